@@ -1,26 +1,41 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"musicbot/internal/blacklist"
 	"musicbot/internal/config"
+	"musicbot/internal/deps"
 	"musicbot/internal/discord"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/health"
+	"musicbot/internal/i18n"
+	"musicbot/internal/janitor"
 	"musicbot/internal/logger"
+	"musicbot/internal/lyrics"
+	"musicbot/internal/metrics"
 	"musicbot/internal/permissions"
+	"musicbot/internal/schedule"
 	"musicbot/internal/shutdown"
 	"musicbot/internal/socket"
 	"musicbot/internal/state"
 )
 
+// version is shown in the footer of rich embeds. Release builds set it with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
 func main() {
 	configPath := flag.String("config", "config.json", "Path to config file")
 	logLevel := flag.Int("log", logger.LevelInfo, "Log level")
+	migrateURLs := flag.Bool("migrate-urls", false, "Normalize stored song URLs, merge duplicates and exit")
+	forceSync := flag.Bool("force-sync", false, "Push every slash command to Discord, even unchanged ones")
 	flag.Parse()
 
 	logger.Setup(*logLevel)
@@ -28,17 +43,41 @@ func main() {
 
 	shutdownManager := shutdown.NewManager()
 
-	if err := runJanitor(); err != nil {
-		logger.Error.Printf("Janitor failed: %v", err)
-	}
-
 	fileConfig, err := config.LoadFromFile(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	if fileConfig.GuildID == "" {
-		log.Fatal("Guild ID is required in config.json")
+	logOptions := logger.Options{
+		Level:      *logLevel,
+		JSON:       fileConfig.LogJSON,
+		File:       fileConfig.LogFile,
+		MaxSizeMB:  fileConfig.LogMaxMB,
+		MaxBackups: fileConfig.LogBackups,
+	}
+	if err := logger.SetupWithOptions(logOptions); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+
+	if err := fileConfig.Validate(); err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	logger.Info.Printf("Database: %s", fileConfig.DBPath)
+	logger.Info.Printf("Download directory: %s", fileConfig.DownloadDir)
+
+	// Missing dependencies are logged and reported by /status and /readyz
+	// rather than stopping the bot, so an operator can fix them and confirm
+	// with /status refresh.
+	var depChecker *deps.Checker
+	if fileConfig.SkipDepCheck {
+		logger.Info.Println("Skipping dependency check (skip_dep_check)")
+	} else {
+		depChecker = deps.New(deps.Config{
+			UDSPath:     fileConfig.UDSPath,
+			DownloadDir: fileConfig.DownloadDir,
+		})
+		depChecker.Run(context.Background())
 	}
 
 	dbManager, err := config.NewDatabaseManager(fileConfig.DBPath)
@@ -47,64 +86,243 @@ func main() {
 	}
 	defer dbManager.Close()
 
+	if *migrateURLs {
+		result, err := dbManager.MigrateSongURLs()
+		if err != nil {
+			log.Fatalf("Failed to migrate song URLs: %v", err)
+		}
+		logger.Info.Printf("Normalized %d song URLs, merged %d duplicate songs", result.Rewritten, result.Merged)
+		return
+	}
+
 	dbConfig, err := dbManager.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load database config: %v", err)
 	}
 
+	guildLocales, err := dbManager.GetGuildLocales()
+	if err != nil {
+		logger.Error.Printf("Failed to load guild languages: %v", err)
+	}
+	for guildID, locale := range guildLocales {
+		if err := i18n.SetGuildLocale(guildID, locale); err != nil {
+			logger.Error.Printf("Ignoring language for guild %s: %v", guildID, err)
+		}
+	}
+
+	guildStyles, err := dbManager.GetStyles()
+	if err != nil {
+		logger.Error.Printf("Failed to load response styles: %v", err)
+	}
+	for guildID, name := range guildStyles {
+		style, ok := render.ParseStyle(name)
+		if !ok {
+			logger.Error.Printf("Ignoring unknown style %q for guild %s", name, guildID)
+			continue
+		}
+		render.SetGuildStyle(guildID, style)
+	}
+	render.SetVersion(version)
+
+	cacheJanitor := janitor.New(dbManager, janitor.Config{
+		MusicDir:      fileConfig.DownloadDir,
+		MaxAge:        time.Duration(fileConfig.CacheMaxAgeDays) * 24 * time.Hour,
+		MaxCacheBytes: int64(fileConfig.MaxCacheGB * (1 << 30)),
+		Interval:      time.Duration(fileConfig.JanitorIntervalMinutes) * time.Minute,
+	})
+
 	botConfig := state.Config{
 		Token:       fileConfig.Token,
 		UDSPath:     fileConfig.UDSPath,
-		IdleChannel: fileConfig.IdleChannel,
+		DownloadDir: fileConfig.DownloadDir,
 		Volume:      dbConfig.Volume,
 		Stream:      dbConfig.Stream,
-		Streams:     dbConfig.Streams,
+		Streams:     fileConfig.StreamOptions(),
+
+		PlaylistWorkers: fileConfig.PlaylistWorkers,
+		IdleDelay:       time.Duration(fileConfig.IdleDelaySeconds) * time.Second,
+		FiltersDisabled: fileConfig.DisableFilters,
+		PurgeOnLeave:    fileConfig.PurgeOnLeave,
+		RadioAutoResume: fileConfig.RadioAutoResume,
+		RequestChannels: fileConfig.RequestChannels,
+	}
+	// idle_delay set with /config wins over the file.
+	if dbConfig.IdleDelay > 0 {
+		botConfig.IdleDelay = dbConfig.IdleDelay
+	}
+
+	idleChannels, err := dbManager.GetIdleChannels()
+	if err != nil {
+		logger.Error.Printf("Failed to load idle channels: %v", err)
+	}
+
+	alwaysOnChannels, err := dbManager.GetAlwaysOnChannels()
+	if err != nil {
+		logger.Error.Printf("Failed to load 24/7 mode: %v", err)
+	}
+
+	filters, err := dbManager.GetFilters()
+	if err != nil {
+		logger.Error.Printf("Failed to load audio filters: %v", err)
+	}
+
+	queueModes, err := dbManager.GetQueueModes()
+	if err != nil {
+		logger.Error.Printf("Failed to load queue modes: %v", err)
+	}
+
+	requestChannels, err := dbManager.GetRequestChannels()
+	if err != nil {
+		logger.Error.Printf("Failed to load request channels: %v", err)
 	}
 
 	stateManager := state.NewManager(botConfig)
+	for _, guild := range fileConfig.Guilds {
+		guildState := stateManager.AddGuild(guild.ID, guild.IdleChannel)
+		guildState.SetAnnounceChannel(guild.AnnounceChannel)
+		guildState.SetAuditChannel(guild.AuditChannel)
+	}
+	// Settings stored with commands win over the file, and cover guilds
+	// that aren't in it at all.
+	for guildID, idleChannel := range idleChannels {
+		stateManager.AddGuild(guildID, idleChannel)
+	}
+	for guildID, channelID := range alwaysOnChannels {
+		stateManager.Guild(guildID).SetAlwaysOnChannel(channelID)
+	}
+	for guildID, filter := range filters {
+		stateManager.Guild(guildID).SetFilter(filter)
+	}
+	for guildID, mode := range queueModes {
+		stateManager.Guild(guildID).SetQueueMode(mode)
+	}
+	for guildID, channelID := range requestChannels {
+		stateManager.Guild(guildID).SetRequestChannel(channelID)
+	}
 
 	shutdownManager.SetStateManager(stateManager)
 
 	socketClient := socket.NewClient(fileConfig.UDSPath)
+
+	var healthServer *health.Server
+	if fileConfig.HealthAddr != "" {
+		checks := health.Checks{
+			ShuttingDown:    shutdownManager.IsShuttingDown,
+			SocketConnected: socketClient.IsConnected,
+			PingDatabase:    dbManager.Ping,
+		}
+		if depChecker != nil {
+			checks.Dependencies = depChecker.Results
+		}
+		healthServer = health.NewServer(fileConfig.HealthAddr, checks)
+		metrics.Enable()
+		healthServer.Handle("/metrics", metrics.Handler())
+		if err := healthServer.Start(); err != nil {
+			log.Fatalf("Failed to start health endpoint: %v", err)
+		}
+		shutdownManager.Register(healthServer)
+	}
+
+	// Registered whether or not the first connect works: the downloader can
+	// still be reached later through /downloader reconnect.
+	shutdownManager.Register(socketClient)
 	if err := socketClient.Connect(); err != nil {
 		logger.Error.Printf("Failed to connect to socket: %v", err)
 		logger.Info.Println("Continuing without socket connection...")
 	} else {
 		logger.Info.Println("Connected to socket")
-		shutdownManager.Register(socketClient)
 	}
 
-	permConfig := permissions.Config{
-		DJRoleName:    fileConfig.DJRoleName,
-		AdminRoleName: fileConfig.AdminRoleName,
+	permissionManager := permissions.NewManager(fileConfig.Permissions())
+	permissionManager.SetOwners(fileConfig.OwnerIDs)
+
+	djOnlyGuilds, err := dbManager.GetDJOnlyGuilds()
+	if err != nil {
+		logger.Error.Printf("Failed to load DJ-only mode: %v", err)
+	}
+	for _, guildID := range djOnlyGuilds {
+		permissionManager.SetDJOnly(guildID, true)
+	}
+
+	roleIDs, err := dbManager.GetRoleIDs()
+	if err != nil {
+		logger.Error.Printf("Failed to load DJ and admin roles: %v", err)
+	}
+	for guildID, roles := range roleIDs {
+		permissionManager.SetRoleID(guildID, permissions.LevelDJ, roles.DJ)
+		permissionManager.SetRoleID(guildID, permissions.LevelAdmin, roles.Admin)
+	}
+
+	lyricsClient := lyrics.NewClient(fileConfig.LyricsURL, dbManager)
+
+	blacklistList, err := blacklist.New(dbManager)
+	if err != nil {
+		log.Fatalf("Failed to load blacklist: %v", err)
 	}
 
-	discordClient, err := discord.NewClient(fileConfig.Token, stateManager, dbManager, socketClient, permConfig)
+	scheduler := schedule.New(dbManager)
+
+	discordClient, err := discord.NewClient(fileConfig.Token, fileConfig.ShardID, fileConfig.ShardCount, stateManager, dbManager, socketClient, cacheJanitor, permissionManager, lyricsClient, blacklistList, scheduler, depChecker)
 	if err != nil {
 		log.Fatalf("Failed to create Discord client: %v", err)
 	}
+	discordClient.SetConfigPath(*configPath, fileConfig)
+
+	if healthServer != nil {
+		healthServer.SetSessionCheck(discordClient.IsSessionOpen)
+	}
+
+	cacheJanitor.SetProtectedSongs(discordClient.GetGuilds().QueuedSongIDs)
+	cacheJanitor.Start()
+	shutdownManager.Register(cacheJanitor)
 
 	if err := discordClient.Connect(); err != nil {
 		log.Fatalf("Failed to connect to Discord: %v", err)
 	}
 
-	shutdownManager.Register(discordClient.GetMusicManager())
-	shutdownManager.Register(discordClient.GetRadioManager())
-	shutdownManager.Register(discordClient.GetVoiceManager())
+	// Components shut down in reverse order: the guilds first, whose music
+	// ends cleanly and flushes its queues while voice, Discord and the
+	// downloader are still up.
 	shutdownManager.Register(discordClient)
+	shutdownManager.Register(discordClient.GetAuditLog())
+	shutdownManager.Register(discordClient.GetGuilds())
+
+	discordClient.GetPresence().Start()
+	shutdownManager.Register(discordClient.GetPresence())
 
-	if err := discordClient.UpdateCommands(); err != nil {
+	discordClient.GetListening().Start()
+	shutdownManager.Register(discordClient.GetListening())
+
+	if err := discordClient.UpdateCommands(*forceSync); err != nil {
 		logger.Error.Printf("Failed to update commands: %v", err)
 	} else {
 		logger.Info.Println("Commands updated successfully")
 	}
 
+	// Guilds go idle as the gateway delivers them, see
+	// Client.handleGuildCreate. The scheduler is started last and after a
+	// pause, so a job that is due right away finds voice and the radio
+	// ready.
 	time.Sleep(2 * time.Second)
 
-	if err := discordClient.StartIdleMode(fileConfig.GuildID); err != nil {
-		logger.Error.Printf("Failed to start idle mode: %v", err)
+	scheduler.SetRunner(discordClient.ScheduleRunner())
+	if err := scheduler.Start(); err != nil {
+		logger.Error.Printf("Failed to start scheduler: %v", err)
+	} else {
+		shutdownManager.Register(scheduler)
 	}
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			logger.Info.Println("SIGHUP received, reloading config...")
+			if _, err := discordClient.ReloadConfig(); err != nil {
+				logger.Error.Printf("Config reload failed: %v", err)
+			}
+		}
+	}()
+
 	logger.Info.Println("Bot is now running. Press Ctrl+C to exit.")
 
 	stop := make(chan os.Signal, 1)
@@ -120,16 +338,3 @@ func main() {
 
 	logger.Info.Println("Shutdown complete.")
 }
-
-func runJanitor() error {
-	logger.Info.Println("Running janitor...")
-
-	cmd := exec.Command("../janitor/janitor", "../shared/musicbot.db", "../shared")
-	err := cmd.Run()
-	if err != nil {
-		return err
-	}
-
-	logger.Info.Println("Janitor completed successfully")
-	return nil
-}
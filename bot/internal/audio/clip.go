@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"layeh.com/gopus"
+)
+
+// PlayPCM plays pcm, interleaved 48kHz stereo samples, on vc by itself, for
+// clips played while nothing else is. It returns once the clip has played or
+// ctx is cancelled.
+func PlayPCM(ctx context.Context, vc *discordgo.VoiceConnection, pcm []int16) error {
+	encoder, err := gopus.NewEncoder(FrameRate, Channels, gopus.Audio)
+	if err != nil {
+		return fmt.Errorf("error creating opus encoder: %w", err)
+	}
+
+	vc.Speaking(true)
+	defer vc.Speaking(false)
+	defer SendSilence(vc)
+
+	sender := NewSender(ctx, vc, "clip")
+	defer sender.Stop()
+
+	samples := FrameSize * Channels
+	frame := make([]int16, samples)
+
+	for len(pcm) > 0 {
+		// The last frame is padded with silence.
+		n := copy(frame, pcm)
+		clear(frame[n:])
+		pcm = pcm[n:]
+
+		opusData, err := encoder.Encode(frame, FrameSize, 1000)
+		if err != nil {
+			return fmt.Errorf("error encoding opus: %w", err)
+		}
+		if err := sender.Send(opusData); err != nil {
+			return nil
+		}
+	}
+
+	sender.Close()
+	return nil
+}
@@ -0,0 +1,70 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// duckFactor is how loud the audio underneath an overlay stays while it
+// plays.
+const duckFactor = 0.5
+
+// ErrOverlayBusy is returned by Overlay.Start while another clip is playing.
+var ErrOverlayBusy = errors.New("a clip is already playing")
+
+// Overlay mixes a short clip of PCM into another stream of frames, ducking
+// the stream while the clip plays. The stream keeps its own pace, so the
+// clip never moves it forward or back.
+type Overlay struct {
+	pcm []int16
+	mu  sync.Mutex
+}
+
+// Start queues pcm, interleaved 48kHz stereo samples, to be mixed into the
+// frames that follow.
+func (o *Overlay) Start(pcm []int16) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pcm) > 0 {
+		return ErrOverlayBusy
+	}
+	o.pcm = pcm
+	return nil
+}
+
+// Active reports whether a clip is waiting to be mixed in.
+func (o *Overlay) Active() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pcm) > 0
+}
+
+// Mix ducks frame and adds the next frame of the clip to it. It does nothing
+// if no clip is playing.
+func (o *Overlay) Mix(frame []int16) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pcm) == 0 {
+		return
+	}
+
+	n := min(len(frame), len(o.pcm))
+	for k := range frame {
+		mixed := float64(frame[k]) * duckFactor
+		if k < n {
+			mixed += float64(o.pcm[k])
+		}
+		frame[k] = int16(max(math.MinInt16, min(math.MaxInt16, mixed)))
+	}
+	o.pcm = o.pcm[n:]
+}
+
+// Cancel cuts off the clip that is playing, if any.
+func (o *Overlay) Cancel() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pcm = nil
+}
@@ -0,0 +1,119 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"layeh.com/gopus"
+)
+
+const (
+	// FrameSamples is how many interleaved samples make up a frame.
+	FrameSamples = FrameSize * Channels
+
+	// frameBytes is a frame as ffmpeg writes it, 16-bit little endian.
+	frameBytes = FrameSamples * 2
+
+	// maxOpusFrame bounds an encoded frame.
+	maxOpusFrame = 1000
+)
+
+// PCMFrame is one frame of interleaved 48kHz stereo samples.
+type PCMFrame [FrameSamples]int16
+
+var framePool = sync.Pool{
+	New: func() any { return new(PCMFrame) },
+}
+
+// GetFrame takes a frame from the pool. Its samples are whatever the last
+// user left in it.
+func GetFrame() *PCMFrame {
+	return framePool.Get().(*PCMFrame)
+}
+
+// PutFrame returns a frame to the pool. The caller must not use it after.
+func PutFrame(frame *PCMFrame) {
+	framePool.Put(frame)
+}
+
+// FrameReader reads the PCM frames ffmpeg writes. It reads into one buffer
+// kept for its whole life, where binary.Read allocates one per call, which
+// at 50 frames a second for each guild playing adds up.
+type FrameReader struct {
+	r   io.Reader
+	buf [frameBytes]byte
+}
+
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r}
+}
+
+// ReadFrame fills pcm with the next frame. Like binary.Read, it returns
+// io.EOF if the input ended between frames and io.ErrUnexpectedEOF if it
+// ended part way into one.
+func (f *FrameReader) ReadFrame(pcm []int16) error {
+	if _, err := io.ReadFull(f.r, f.buf[:]); err != nil {
+		return err
+	}
+	for i := range pcm[:FrameSamples] {
+		pcm[i] = int16(binary.LittleEndian.Uint16(f.buf[2*i:]))
+	}
+	return nil
+}
+
+// Encoder is an opus encoder meant to be kept by a player for its whole
+// life, reset between tracks instead of made anew for each. It is not safe
+// for concurrent use.
+type Encoder struct {
+	encoder *gopus.Encoder
+}
+
+func NewEncoder() (*Encoder, error) {
+	encoder, err := gopus.NewEncoder(FrameRate, Channels, gopus.Audio)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{encoder: encoder}, nil
+}
+
+// Reset clears what the encoder remembers of the audio before, so the start
+// of a track doesn't carry over from the end of the last.
+func (e *Encoder) Reset() {
+	e.encoder.ResetState()
+}
+
+// Encode encodes one frame. gopus allocates the result, which is handed on
+// to the voice connection and can't be reused.
+func (e *Encoder) Encode(pcm []int16) ([]byte, error) {
+	return e.encoder.Encode(pcm, FrameSize, maxOpusFrame)
+}
+
+// EncoderSlot keeps a player's encoder between tracks. Take hands it out,
+// or a new one if it is in use by a track that is still winding down, and
+// Put returns it.
+type EncoderSlot struct {
+	mu      sync.Mutex
+	encoder *Encoder
+}
+
+// Take returns the kept encoder, reset, or a new one.
+func (s *EncoderSlot) Take() (*Encoder, error) {
+	s.mu.Lock()
+	encoder := s.encoder
+	s.encoder = nil
+	s.mu.Unlock()
+
+	if encoder == nil {
+		return NewEncoder()
+	}
+	encoder.Reset()
+	return encoder, nil
+}
+
+// Put keeps encoder for the next track.
+func (s *EncoderSlot) Put(encoder *Encoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder = encoder
+}
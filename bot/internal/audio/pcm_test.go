@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// silence is an endless stream of zero samples, as ffmpeg writes for a
+// silent track.
+type silence struct{}
+
+func (silence) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestReadFrame(t *testing.T) {
+	var input bytes.Buffer
+	want := make([]int16, FrameSamples)
+	for k := range want {
+		want[k] = int16(k - FrameSamples/2)
+	}
+	binary.Write(&input, binary.LittleEndian, want)
+	input.Write([]byte{1, 2})
+
+	frames := NewFrameReader(&input)
+	pcm := make([]int16, FrameSamples)
+	if err := frames.ReadFrame(pcm); err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	for k := range want {
+		if pcm[k] != want[k] {
+			t.Fatalf("sample %d = %d, want %d", k, pcm[k], want[k])
+		}
+	}
+	if err := frames.ReadFrame(pcm); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrame of part of a frame = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if err := NewFrameReader(&bytes.Buffer{}).ReadFrame(pcm); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadFrame at the end = %v, want io.EOF", err)
+	}
+}
+
+func TestReadFrameDoesNotAllocate(t *testing.T) {
+	frames := NewFrameReader(silence{})
+	pcm := GetFrame()
+	defer PutFrame(pcm)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := frames.ReadFrame(pcm[:]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ReadFrame allocates %v times a frame, want 0", allocs)
+	}
+}
+
+func BenchmarkReadFrame(b *testing.B) {
+	frames := NewFrameReader(silence{})
+	pcm := GetFrame()
+	defer PutFrame(pcm)
+
+	b.ReportAllocs()
+	b.SetBytes(frameBytes)
+	for b.Loop() {
+		if err := frames.ReadFrame(pcm[:]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBinaryRead is how frames were read before FrameReader, for
+// comparison.
+func BenchmarkBinaryRead(b *testing.B) {
+	var pcm PCMFrame
+
+	b.ReportAllocs()
+	b.SetBytes(frameBytes)
+	for b.Loop() {
+		if err := binary.Read(silence{}, binary.LittleEndian, &pcm); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFramePath is a frame's way from ffmpeg to Discord: read into a
+// pooled frame, encoded and the frame given back.
+func BenchmarkFramePath(b *testing.B) {
+	frames := NewFrameReader(silence{})
+	var slot EncoderSlot
+	encoder, err := slot.Take()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer slot.Put(encoder)
+
+	b.ReportAllocs()
+	b.SetBytes(frameBytes)
+	for b.Loop() {
+		pcm := GetFrame()
+		if err := frames.ReadFrame(pcm[:]); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := encoder.Encode(pcm[:]); err != nil {
+			b.Fatal(err)
+		}
+		PutFrame(pcm)
+	}
+}
@@ -0,0 +1,177 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Opus frame format sent to Discord: 20ms of 48kHz stereo audio.
+const (
+	FrameSize     = 960
+	Channels      = 2
+	FrameRate     = 48000
+	FrameDuration = time.Second * FrameSize / FrameRate
+)
+
+const (
+	// bufferFrames is how far the encoder may run ahead of playback.
+	bufferFrames = 10
+
+	// maxCatchUp is how many late frames are sent in one tick before the
+	// schedule is reset instead. Sending more would be an audible burst.
+	maxCatchUp = 3
+)
+
+// ErrSenderStopped is returned by Send once the sender has been stopped or
+// closed.
+var ErrSenderStopped = errors.New("audio sender stopped")
+
+// Sender paces opus frames to a voice connection, one per 20ms tick, so
+// playback doesn't depend on how fast the encoder produces them. Frames wait
+// in a small buffer; if Discord can't take a frame on its tick, the frame is
+// dropped and counted rather than blocking the encoder.
+type Sender struct {
+	vc        *discordgo.VoiceConnection
+	source    string
+	frames    chan []byte
+	closing   chan struct{}
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	sent      atomic.Int64
+	dropped   atomic.Int64
+}
+
+// NewSender starts a sender for vc. source labels the dropped frame metric,
+// e.g. "music" or "radio". The sender stops when ctx is cancelled.
+func NewSender(ctx context.Context, vc *discordgo.VoiceConnection, source string) *Sender {
+	s := &Sender{
+		vc:      vc,
+		source:  source,
+		frames:  make(chan []byte, bufferFrames),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	go s.run()
+
+	return s
+}
+
+// Send queues a frame, waiting while the buffer is full. It returns
+// ErrSenderStopped once the sender is stopped or closed.
+func (s *Sender) Send(frame []byte) error {
+	select {
+	case <-s.closing:
+		return ErrSenderStopped
+	case <-s.ctx.Done():
+		return ErrSenderStopped
+	default:
+	}
+
+	select {
+	case s.frames <- frame:
+		return nil
+	case <-s.closing:
+		return ErrSenderStopped
+	case <-s.ctx.Done():
+		return ErrSenderStopped
+	}
+}
+
+// Close waits for the buffered frames to play, then stops the sender. Call it
+// after the last Send; use Stop to discard what is buffered instead. The
+// frame channel is never closed, so a Send racing Close fails rather than
+// panicking.
+func (s *Sender) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	<-s.done
+	s.cancel()
+}
+
+// Stop discards any buffered frames and waits for the sender to finish.
+func (s *Sender) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Played returns how many frames have had their turn, sent or dropped. Each
+// one is FrameDuration of audio.
+func (s *Sender) Played() int64 {
+	return s.sent.Load() + s.dropped.Load()
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	defer func() {
+		if dropped := s.dropped.Load(); dropped > 0 {
+			logger.Info.Printf("Dropped %d of %d %s frames Discord couldn't keep up with", dropped, s.Played(), s.source)
+		}
+	}()
+
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var ticks int64
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			// The ticker skips ticks when this loop falls behind, so work out
+			// from the clock how many frames are due rather than counting
+			// ticks. That keeps playback from drifting.
+			due := int64(now.Sub(start) / FrameDuration)
+			if due-ticks > maxCatchUp {
+				start = now.Add(-FrameDuration)
+				ticks = 0
+				due = 1
+			}
+
+			for ticks < due {
+				ticks++
+
+				select {
+				case frame := <-s.frames:
+					s.deliver(frame)
+				default:
+					// Close comes after the last Send, so an empty buffer
+					// once closing means everything has played.
+					select {
+					case <-s.closing:
+						return
+					default:
+					}
+					// Nothing buffered yet. Restart the schedule so the
+					// frames that arrive late aren't sent in a burst.
+					start = now
+					ticks = 0
+					due = 0
+				}
+			}
+		}
+	}
+}
+
+// deliver hands frame to Discord without waiting, dropping it if Discord
+// isn't ready for it.
+func (s *Sender) deliver(frame []byte) {
+	select {
+	case s.vc.OpusSend <- frame:
+		s.sent.Add(1)
+	default:
+		s.dropped.Add(1)
+		metrics.OpusFrameDropped(s.source)
+	}
+}
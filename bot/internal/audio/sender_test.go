@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSenderCloseDrainsBufferedFrames(t *testing.T) {
+	vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, bufferFrames)}
+	sender := NewSender(context.Background(), vc, "test")
+
+	for range 5 {
+		if err := sender.Send([]byte{1}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	sender.Close()
+
+	if got := sender.Played(); got != 5 {
+		t.Fatalf("Played() = %d, want 5", got)
+	}
+	if got := len(vc.OpusSend); got != 5 {
+		t.Fatalf("Discord got %d frames, want 5", got)
+	}
+}
+
+func TestSenderSendRacingCloseDoesNotPanic(t *testing.T) {
+	for range 100 {
+		vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte)}
+		go func() {
+			for range vc.OpusSend {
+			}
+		}()
+		sender := NewSender(context.Background(), vc, "test")
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					if err := sender.Send([]byte{1}); err != nil {
+						if !errors.Is(err, ErrSenderStopped) {
+							t.Errorf("Send: %v", err)
+						}
+						return
+					}
+				}
+			}()
+		}
+		sender.Close()
+		wg.Wait()
+		close(vc.OpusSend)
+	}
+}
+
+func TestSenderPacesFrames(t *testing.T) {
+	const frames = 10
+	vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, frames)}
+	sender := NewSender(context.Background(), vc, "test")
+
+	start := time.Now()
+	for range frames {
+		if err := sender.Send([]byte{1}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	sender.Close()
+
+	// One frame per tick: the last goes out no sooner than frames-1 ticks
+	// after the first.
+	if elapsed := time.Since(start); elapsed < (frames-1)*FrameDuration {
+		t.Errorf("%d frames sent in %s, want them paced %s apart", frames, elapsed, FrameDuration)
+	}
+	if got := len(vc.OpusSend); got != frames {
+		t.Errorf("Discord got %d frames, want %d", got, frames)
+	}
+}
+
+func TestSenderDropsFramesDiscordDoesNotTake(t *testing.T) {
+	// Nobody reads OpusSend, as when Discord stops taking frames.
+	vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte)}
+	sender := NewSender(context.Background(), vc, "test")
+
+	closed := make(chan struct{})
+	go func() {
+		for range 3 {
+			if err := sender.Send([]byte{1}); err != nil {
+				t.Errorf("Send: %v", err)
+			}
+		}
+		sender.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the sender blocked on a frame Discord never took")
+	}
+	if got := sender.dropped.Load(); got != 3 {
+		t.Errorf("dropped %d frames, want 3", got)
+	}
+	// Dropped frames still had their turn.
+	if got := sender.Played(); got != 3 {
+		t.Errorf("Played() = %d, want 3", got)
+	}
+}
+
+func TestSenderStopDiscardsBufferedFrames(t *testing.T) {
+	vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, bufferFrames)}
+	sender := NewSender(context.Background(), vc, "test")
+	for range bufferFrames {
+		if err := sender.Send([]byte{1}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		sender.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop waited for the buffered frames to play")
+	}
+	if got := sender.Played(); got == bufferFrames {
+		t.Errorf("every buffered frame played, want Stop to discard them")
+	}
+	if err := sender.Send([]byte{1}); !errors.Is(err, ErrSenderStopped) {
+		t.Fatalf("Send after Stop = %v, want ErrSenderStopped", err)
+	}
+}
@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// silenceFrames is how many frames of silence Discord asks for after the last
+// frame of audio, so clients don't interpolate the gap into a click.
+const silenceFrames = 5
+
+// SilenceFrame is an opus frame of silence.
+var SilenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// SendSilence sends the frames of silence that end a stretch of audio. A
+// frame the connection doesn't take within two frame durations is skipped,
+// so a dead connection can't hold up the caller.
+func SendSilence(vc *discordgo.VoiceConnection) {
+	if vc == nil {
+		return
+	}
+
+	for i := 0; i < silenceFrames; i++ {
+		select {
+		case vc.OpusSend <- SilenceFrame:
+		case <-time.After(2 * FrameDuration):
+			return
+		}
+	}
+}
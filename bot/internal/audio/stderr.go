@@ -0,0 +1,39 @@
+package audio
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxStderrTail bounds what is kept of ffmpeg's error output.
+const maxStderrTail = 1024
+
+// StderrTail keeps the end of ffmpeg's error output, which is where it says
+// why it stopped. The zero value is ready to use as ffmpeg's Stderr.
+type StderrTail struct {
+	buf []byte
+	mu  sync.Mutex
+}
+
+func (t *StderrTail) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, b...)
+	if len(t.buf) > maxStderrTail {
+		t.buf = t.buf[len(t.buf)-maxStderrTail:]
+	}
+	return len(b), nil
+}
+
+// String returns what is kept of the output.
+func (t *StderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(string(t.buf))
+}
+
+// LastLine returns the last line ffmpeg wrote, or "".
+func (t *StderrTail) LastLine() string {
+	lines := strings.Split(t.String(), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
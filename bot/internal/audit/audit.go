@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// queueSize is how many records may wait for the database before new ones
+// are dropped.
+const queueSize = 256
+
+// Actions recorded in the audit log.
+const (
+	ActionPlay             = "play"
+	ActionPlaylist         = "playlist"
+	ActionPlayFile         = "playfile"
+	ActionImportQueue      = "import_queue"
+	ActionSkip             = "skip"
+	ActionPrevious         = "previous"
+	ActionRemove           = "remove"
+	ActionClear            = "clear"
+	ActionClearUndo        = "clear_undo"
+	ActionVolume           = "volume"
+	ActionFilter           = "filter"
+	ActionPause            = "pause"
+	ActionResume           = "resume"
+	ActionStop             = "stop"
+	ActionTrim             = "trim"
+	ActionDJOnly           = "djonly"
+	ActionBlacklistAdd     = "blacklist_add"
+	ActionBlacklistRemove  = "blacklist_remove"
+	ActionBlacklistPurge   = "blacklist_purge"
+	ActionRetryFailed      = "retry_failed"
+	ActionIdleChannel      = "idle_channel"
+	ActionAlwaysOn         = "always_on"
+	ActionClipAdd          = "clip_add"
+	ActionClipRemove       = "clip_remove"
+	ActionScheduleAdd      = "schedule_add"
+	ActionScheduleRemove   = "schedule_remove"
+	ActionDownloaderCancel = "downloader_cancel"
+	ActionPresence         = "presence"
+	ActionRepair           = "repair"
+	ActionConfigSet        = "config_set"
+	ActionRole             = "role"
+	ActionSessionStart     = "session_start"
+	ActionSessionStop      = "session_stop"
+	ActionPin              = "pin"
+	ActionUnpin            = "unpin"
+	ActionDJBan            = "djban"
+	ActionDJUnban          = "djunban"
+	ActionCommandDisable   = "command_disable"
+	ActionCommandEnable    = "command_enable"
+	ActionQueueMode        = "queue_mode"
+	ActionRequestChannel   = "request_channel"
+)
+
+// Log records who did what to the music. Records are written by a background
+// worker and mirrored to the guild's audit channel if it has one, so
+// recording never holds up a command and a failed write is only logged.
+type Log struct {
+	dbManager    *config.DatabaseManager
+	session      *discordgo.Session
+	stateManager *state.Manager
+	entries      chan config.AuditEntry
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+func New(dbManager *config.DatabaseManager, session *discordgo.Session, stateManager *state.Manager) *Log {
+	l := &Log{
+		dbManager:    dbManager,
+		session:      session,
+		stateManager: stateManager,
+		entries:      make(chan config.AuditEntry, queueSize),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go l.run()
+
+	return l
+}
+
+// Record queues an action by actorID in guildID. target is the track, value
+// or user acted on, and may be empty.
+func (l *Log) Record(guildID, actorID, action, target string) {
+	if l == nil {
+		return
+	}
+
+	entry := config.AuditEntry{
+		GuildID: guildID,
+		ActorID: actorID,
+		Action:  action,
+		Target:  target,
+		At:      time.Now(),
+	}
+
+	select {
+	case <-l.stop:
+		return
+	default:
+	}
+
+	select {
+	case l.entries <- entry:
+	default:
+		logger.Error.Printf("Audit queue full, dropping %s by %s in guild %s", action, actorID, guildID)
+	}
+}
+
+// Recent returns the latest count entries of guildID, newest first.
+func (l *Log) Recent(guildID string, count int) ([]config.AuditEntry, error) {
+	return l.dbManager.GetAuditEntries(guildID, count)
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+
+	for {
+		select {
+		case entry := <-l.entries:
+			l.write(entry)
+		case <-l.stop:
+			for {
+				select {
+				case entry := <-l.entries:
+					l.write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *Log) write(entry config.AuditEntry) {
+	if err := l.dbManager.AddAuditEntry(entry); err != nil {
+		logger.Error.Printf("Failed to write audit entry %s by %s: %v", entry.Action, entry.ActorID, err)
+	}
+
+	channelID := l.stateManager.Guild(entry.GuildID).GetAuditChannel()
+	if channelID == "" || l.session == nil {
+		return
+	}
+
+	_, err := l.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         FormatLine(entry),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to mirror audit entry to channel %s: %v", channelID, err)
+	}
+}
+
+// FormatLine renders entry as one compact line.
+func FormatLine(entry config.AuditEntry) string {
+	if entry.Target == "" {
+		return i18n.T(entry.GuildID, "audit.line", entry.At.Unix(), entry.ActorID, entry.Action)
+	}
+	return i18n.T(entry.GuildID, "audit.line_target", entry.At.Unix(), entry.ActorID, entry.Action, entry.Target)
+}
+
+// Shutdown writes what is still queued and stops the worker.
+func (l *Log) Shutdown(ctx context.Context) error {
+	close(l.stop)
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Log) Name() string {
+	return "AuditLog"
+}
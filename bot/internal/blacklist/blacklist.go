@@ -0,0 +1,281 @@
+package blacklist
+
+import (
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List holds the blocked URL patterns and users of every guild in memory, so
+// checking a request doesn't touch the database. It is reloaded after each
+// change. Users can also be timed out, which blocks them from queueing for a
+// while only.
+type List struct {
+	dbManager *config.DatabaseManager
+	urls      map[string][]urlRule
+	users     map[string]map[string]config.BlacklistEntry
+	timeouts  map[string]map[string]config.QueueTimeout
+	mu        sync.RWMutex
+}
+
+// urlRule is a blocked URL pattern with its glob compiled. glob is nil for a
+// plain substring pattern.
+type urlRule struct {
+	entry config.BlacklistEntry
+	glob  *regexp.Regexp
+}
+
+func newURLRule(entry config.BlacklistEntry) urlRule {
+	rule := urlRule{entry: entry}
+	if strings.ContainsAny(entry.Value, "*?") {
+		expr := regexp.QuoteMeta(entry.Value)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		rule.glob = regexp.MustCompile("^" + expr + "$")
+	}
+	return rule
+}
+
+func (r urlRule) matches(target, host string) bool {
+	if r.glob == nil {
+		return strings.Contains(target, r.entry.Value)
+	}
+	return r.glob.MatchString(target) || r.glob.MatchString(host)
+}
+
+func New(dbManager *config.DatabaseManager) (*List, error) {
+	l := &List{dbManager: dbManager}
+	if err := l.Load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Load replaces the in-memory lists with what is in the database.
+func (l *List) Load() error {
+	urlEntries, err := l.dbManager.GetBlacklistedURLs()
+	if err != nil {
+		return fmt.Errorf("failed to load URL blacklist: %w", err)
+	}
+	userEntries, err := l.dbManager.GetBlacklistedUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load user blacklist: %w", err)
+	}
+	timeoutEntries, err := l.dbManager.GetQueueTimeouts()
+	if err != nil {
+		return fmt.Errorf("failed to load queue timeouts: %w", err)
+	}
+
+	urls := make(map[string][]urlRule)
+	for _, entry := range urlEntries {
+		urls[entry.GuildID] = append(urls[entry.GuildID], newURLRule(entry))
+	}
+
+	users := make(map[string]map[string]config.BlacklistEntry)
+	for _, entry := range userEntries {
+		if users[entry.GuildID] == nil {
+			users[entry.GuildID] = make(map[string]config.BlacklistEntry)
+		}
+		users[entry.GuildID][entry.Value] = entry
+	}
+
+	timeouts := make(map[string]map[string]config.QueueTimeout)
+	for _, timeout := range timeoutEntries {
+		if timeouts[timeout.GuildID] == nil {
+			timeouts[timeout.GuildID] = make(map[string]config.QueueTimeout)
+		}
+		timeouts[timeout.GuildID][timeout.UserID] = timeout
+	}
+
+	l.mu.Lock()
+	l.urls = urls
+	l.users = users
+	l.timeouts = timeouts
+	l.mu.Unlock()
+	return nil
+}
+
+// AddURL blocks URLs matching pattern in guildID. A pattern with * or ? is a
+// glob matched against the whole URL and against its host; any other pattern
+// blocks URLs that contain it.
+func (l *List) AddURL(guildID, pattern, addedBy string) error {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	err := l.dbManager.AddBlacklistedURL(config.BlacklistEntry{
+		GuildID: guildID,
+		Value:   pattern,
+		AddedBy: addedBy,
+		AddedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return l.Load()
+}
+
+// RemoveURL reports whether pattern was blocked.
+func (l *List) RemoveURL(guildID, pattern string) (bool, error) {
+	removed, err := l.dbManager.RemoveBlacklistedURL(guildID, strings.ToLower(strings.TrimSpace(pattern)))
+	if err != nil || !removed {
+		return removed, err
+	}
+	return true, l.Load()
+}
+
+func (l *List) AddUser(guildID, userID, addedBy string) error {
+	err := l.dbManager.AddBlacklistedUser(config.BlacklistEntry{
+		GuildID: guildID,
+		Value:   userID,
+		AddedBy: addedBy,
+		AddedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return l.Load()
+}
+
+// RemoveUser reports whether userID was blocked.
+func (l *List) RemoveUser(guildID, userID string) (bool, error) {
+	removed, err := l.dbManager.RemoveBlacklistedUser(guildID, userID)
+	if err != nil || !removed {
+		return removed, err
+	}
+	return true, l.Load()
+}
+
+// URLs returns the blocked URL patterns of guildID, oldest first.
+func (l *List) URLs(guildID string) []config.BlacklistEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]config.BlacklistEntry, 0, len(l.urls[guildID]))
+	for _, rule := range l.urls[guildID] {
+		entries = append(entries, rule.entry)
+	}
+	return entries
+}
+
+// Users returns the blocked users of guildID, oldest first.
+func (l *List) Users(guildID string) []config.BlacklistEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]config.BlacklistEntry, 0, len(l.users[guildID]))
+	for _, entry := range l.users[guildID] {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].AddedAt.Before(entries[j].AddedAt)
+	})
+	return entries
+}
+
+func (l *List) IsUserBlocked(guildID, userID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, blocked := l.users[guildID][userID]
+	return blocked
+}
+
+// AddTimeout blocks userID from queueing in guildID for d. A user who is
+// already timed out gets the new timeout instead.
+func (l *List) AddTimeout(guildID, userID, addedBy string, d time.Duration) (config.QueueTimeout, error) {
+	now := time.Now()
+	timeout := config.QueueTimeout{
+		GuildID:   guildID,
+		UserID:    userID,
+		AddedBy:   addedBy,
+		AddedAt:   now,
+		ExpiresAt: now.Add(d),
+	}
+	if err := l.dbManager.AddQueueTimeout(timeout); err != nil {
+		return timeout, err
+	}
+	return timeout, l.Load()
+}
+
+// RemoveTimeout lifts the timeout of userID early. It reports whether there
+// was one.
+func (l *List) RemoveTimeout(guildID, userID string) (bool, error) {
+	_, active := l.TimedOut(guildID, userID)
+	removed, err := l.dbManager.RemoveQueueTimeout(guildID, userID)
+	if err != nil || !removed {
+		return false, err
+	}
+	return active, l.Load()
+}
+
+// Timeouts returns the timeouts running in guildID, the first to end first.
+func (l *List) Timeouts(guildID string) []config.QueueTimeout {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	timeouts := make([]config.QueueTimeout, 0, len(l.timeouts[guildID]))
+	for _, timeout := range l.timeouts[guildID] {
+		if timeout.ExpiresAt.After(now) {
+			timeouts = append(timeouts, timeout)
+		}
+	}
+	sort.Slice(timeouts, func(i, j int) bool {
+		return timeouts[i].ExpiresAt.Before(timeouts[j].ExpiresAt)
+	})
+	return timeouts
+}
+
+// TimedOut reports whether userID is timed out in guildID, and until when. A
+// timeout found to have run out is dropped, here and in the database.
+func (l *List) TimedOut(guildID, userID string) (time.Time, bool) {
+	l.mu.RLock()
+	timeout, ok := l.timeouts[guildID][userID]
+	l.mu.RUnlock()
+
+	if !ok {
+		return time.Time{}, false
+	}
+	if timeout.ExpiresAt.After(time.Now()) {
+		return timeout.ExpiresAt, true
+	}
+
+	l.mu.Lock()
+	if current, ok := l.timeouts[guildID][userID]; ok && current.ExpiresAt.Equal(timeout.ExpiresAt) {
+		delete(l.timeouts[guildID], userID)
+	}
+	l.mu.Unlock()
+
+	if _, err := l.dbManager.DeleteExpiredQueueTimeouts(); err != nil {
+		logger.Error.Printf("Failed to remove expired queue timeouts: %v", err)
+	}
+	return time.Time{}, false
+}
+
+// MatchURL returns the first pattern in guildID that blocks rawURL.
+func (l *List) MatchURL(guildID, rawURL string) (string, bool) {
+	l.mu.RLock()
+	rules := l.urls[guildID]
+	l.mu.RUnlock()
+
+	if len(rules) == 0 {
+		return "", false
+	}
+
+	target := strings.ToLower(strings.TrimSpace(rawURL))
+	host := ""
+	if parsed, err := url.Parse(target); err == nil {
+		host = strings.TrimPrefix(parsed.Hostname(), "www.")
+	}
+
+	for _, rule := range rules {
+		if rule.matches(target, host) {
+			return rule.entry.Value, true
+		}
+	}
+	return "", false
+}
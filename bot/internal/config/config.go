@@ -2,18 +2,111 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"musicbot/internal/permissions"
 	"musicbot/internal/state"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type FileConfig struct {
-	Token         string `json:"token"`
-	UDSPath       string `json:"uds_path"`
-	GuildID       string `json:"guild_id"`
-	IdleChannel   string `json:"idle_channel"`
+	Token   string `json:"token"`
+	UDSPath string `json:"uds_path"`
+
+	// ShardID and ShardCount split the guilds the bot is in over several
+	// processes, each connecting to the gateway as one shard.
+	ShardID    int        `json:"shard_id"`
+	ShardCount ShardCount `json:"shard_count"`
+
+	Guilds []GuildConfig `json:"guilds"`
+
+	// Guilds holds settings for particular guilds. The bot serves every
+	// guild it is in whether listed or not; one that isn't has no idle
+	// channel until /setidlechannel is used.
+	//
+	// GuildID and IdleChannel configure a single guild. They are still
+	// accepted when Guilds is empty.
+	GuildID     string `json:"guild_id"`
+	IdleChannel string `json:"idle_channel"`
+
 	DBPath        string `json:"db_path"`
+	DownloadDir   string `json:"download_dir"`
 	DJRoleName    string `json:"dj_role_name"`
 	AdminRoleName string `json:"admin_role_name"`
+	HealthAddr    string `json:"health_addr"`
+	LogFile       string `json:"log_file"`
+	LogMaxMB      int    `json:"log_max_mb"`
+	LogBackups    int    `json:"log_backups"`
+	LogJSON       bool   `json:"log_json"`
+
+	JanitorIntervalMinutes int     `json:"janitor_interval_minutes"`
+	CacheMaxAgeDays        int     `json:"cache_max_age_days"`
+	MaxCacheGB             float64 `json:"max_cache_gb"`
+
+	// Streams replaces the built-in radio streams when set.
+	Streams []StreamConfig `json:"streams"`
+
+	// LyricsURL is the LRCLIB-compatible lyrics provider.
+	LyricsURL string `json:"lyrics_url"`
+
+	// PlaylistWorkers is how many tracks of a playlist are downloaded at
+	// once.
+	PlaylistWorkers int `json:"playlist_workers"`
+
+	// IdleDelaySeconds is how long the bot waits after the last song before
+	// the radio takes over.
+	IdleDelaySeconds int `json:"idle_delay_seconds"`
+
+	// DisableFilters turns /filter off. A filter runs an extra ffmpeg filter
+	// chain for every song; nightcore and vaporwave resample all audio and
+	// can more than double the CPU ffmpeg takes, which a small host may not
+	// keep up with in real time.
+	DisableFilters bool `json:"disable_filters"`
+
+	// PurgeOnLeave deletes a guild's settings from the database when the bot
+	// is removed from it, so being added back starts from scratch.
+	PurgeOnLeave bool `json:"purge_on_leave"`
+
+	// SkipDepCheck turns off the startup check for ffmpeg, the socket
+	// directory and a writable download directory, e.g. on hosts where
+	// ffmpeg sits behind a wrapper that doesn't answer -version.
+	SkipDepCheck bool `json:"skip_dep_check"`
+
+	// RadioAutoResume moves the radio back to the channel it was playing in
+	// when the bot restarts within an hour, instead of the idle channel.
+	RadioAutoResume bool `json:"radio_autoresume"`
+
+	// RequestChannels lets admins set a text channel with /requestchannel
+	// whose messages are taken as song requests. Reading them needs the
+	// privileged message content intent, which must also be turned on for
+	// the bot in the Discord developer portal; without it Discord refuses
+	// the connection.
+	RequestChannels bool `json:"request_channels"`
+
+	// OwnerIDs are the Discord users who run the bot. Only they may change
+	// the settings /config shares between every guild, such as the volume
+	// and the radio stream.
+	OwnerIDs []string `json:"owner_ids"`
+}
+
+// GuildConfig is one guild the bot serves. Empty role names fall back to the
+// top-level dj_role_name and admin_role_name. AnnounceChannel is an optional
+// text channel for notices such as a failed voice reconnect, and AuditChannel
+// an optional moderation channel that gets a line for each audited action.
+type GuildConfig struct {
+	ID              string `json:"id"`
+	IdleChannel     string `json:"idle_channel"`
+	AnnounceChannel string `json:"announce_channel"`
+	AuditChannel    string `json:"audit_channel"`
+	DJRoleName      string `json:"dj_role_name"`
+	AdminRoleName   string `json:"admin_role_name"`
+}
+
+type StreamConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
 }
 
 func LoadFromFile(path string) (FileConfig, error) {
@@ -37,6 +130,16 @@ func LoadFromFile(path string) (FileConfig, error) {
 		config.DBPath = "bot.db"
 	}
 
+	if config.DownloadDir == "" {
+		config.DownloadDir = "../shared"
+	}
+
+	// Relative paths are relative to the config file, not the working
+	// directory, so the bot can be started from anywhere.
+	baseDir := filepath.Dir(path)
+	config.DBPath = resolvePath(baseDir, config.DBPath)
+	config.DownloadDir = resolvePath(baseDir, config.DownloadDir)
+
 	if config.DJRoleName == "" {
 		config.DJRoleName = "DJ"
 	}
@@ -45,9 +148,232 @@ func LoadFromFile(path string) (FileConfig, error) {
 		config.AdminRoleName = "Admin"
 	}
 
+	if !config.ShardCount.Auto && config.ShardCount.Count == 0 {
+		config.ShardCount.Count = 1
+	}
+
+	if len(config.Guilds) == 0 && config.GuildID != "" {
+		config.Guilds = []GuildConfig{{ID: config.GuildID, IdleChannel: config.IdleChannel}}
+	}
+
+	if config.JanitorIntervalMinutes == 0 {
+		config.JanitorIntervalMinutes = 360
+	}
+
+	if config.CacheMaxAgeDays == 0 {
+		config.CacheMaxAgeDays = 30
+	}
+
+	if config.MaxCacheGB == 0 {
+		config.MaxCacheGB = 10
+	}
+
+	if config.LyricsURL == "" {
+		config.LyricsURL = "https://lrclib.net"
+	}
+
+	if config.PlaylistWorkers <= 0 {
+		config.PlaylistWorkers = 3
+	}
+
+	if config.IdleDelaySeconds <= 0 {
+		config.IdleDelaySeconds = 60
+	}
+
 	return config, nil
 }
 
+// ValidationError reports a config value that can't be used.
+type ValidationError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config %s=%q: %s", e.Key, e.Value, e.Reason)
+}
+
+// Validate checks required keys and prepares the configured paths, creating
+// missing directories and making sure they are writable.
+func (c FileConfig) Validate() error {
+	if c.Token == "" {
+		return &ValidationError{Key: "token", Value: c.Token, Reason: "is required"}
+	}
+
+	if err := c.validateShard(); err != nil {
+		return err
+	}
+
+	if err := c.validateGuilds(); err != nil {
+		return err
+	}
+
+	if err := ensureWritableDir(c.DownloadDir); err != nil {
+		return &ValidationError{Key: "download_dir", Value: c.DownloadDir, Reason: err.Error()}
+	}
+
+	if err := ensureWritableDir(filepath.Dir(c.DBPath)); err != nil {
+		return &ValidationError{Key: "db_path", Value: c.DBPath, Reason: err.Error()}
+	}
+
+	if info, err := os.Stat(c.DBPath); err == nil && info.IsDir() {
+		return &ValidationError{Key: "db_path", Value: c.DBPath, Reason: "is a directory"}
+	}
+
+	return c.validateStreams()
+}
+
+// validateShard checks the shard settings. With "auto" the count isn't
+// known until the bot asks Discord, so shard_id is checked against it then.
+func (c FileConfig) validateShard() error {
+	if !c.ShardCount.Auto && c.ShardCount.Count < 1 {
+		return &ValidationError{Key: "shard_count", Value: c.ShardCount.String(), Reason: "must be at least 1"}
+	}
+	if c.ShardID < 0 || (!c.ShardCount.Auto && c.ShardID >= c.ShardCount.Count) {
+		return &ValidationError{Key: "shard_id", Value: strconv.Itoa(c.ShardID), Reason: "must be below shard_count"}
+	}
+	return nil
+}
+
+func (c FileConfig) validateGuilds() error {
+	seen := make(map[string]bool)
+	for _, guild := range c.Guilds {
+		if guild.ID == "" {
+			return &ValidationError{Key: "guilds", Value: guild.IdleChannel, Reason: "every guild needs an id"}
+		}
+		if seen[guild.ID] {
+			return &ValidationError{Key: "guilds", Value: guild.ID, Reason: "duplicate guild id"}
+		}
+		seen[guild.ID] = true
+	}
+	return nil
+}
+
+// GuildIDs returns the IDs of the configured guilds in config order.
+func (c FileConfig) GuildIDs() []string {
+	ids := make([]string, len(c.Guilds))
+	for i, guild := range c.Guilds {
+		ids[i] = guild.ID
+	}
+	return ids
+}
+
+// Permissions returns the default role names and the overrides of each guild.
+func (c FileConfig) Permissions() (permissions.Config, map[string]permissions.Config) {
+	defaults := permissions.Config{
+		DJRoleName:    c.DJRoleName,
+		AdminRoleName: c.AdminRoleName,
+	}
+
+	guilds := make(map[string]permissions.Config, len(c.Guilds))
+	for _, guild := range c.Guilds {
+		guilds[guild.ID] = permissions.Config{
+			DJRoleName:    guild.DJRoleName,
+			AdminRoleName: guild.AdminRoleName,
+		}
+	}
+	return defaults, guilds
+}
+
+func (c FileConfig) validateStreams() error {
+	seen := make(map[string]bool)
+	for _, stream := range c.Streams {
+		if stream.Name == "" || stream.URL == "" {
+			return &ValidationError{Key: "streams", Value: stream.Name, Reason: "every stream needs a name and a url"}
+		}
+		if seen[stream.Name] {
+			return &ValidationError{Key: "streams", Value: stream.Name, Reason: "duplicate stream name"}
+		}
+		seen[stream.Name] = true
+	}
+	return nil
+}
+
+// StreamOptions returns the configured radio streams, or the built-in ones if
+// none are configured.
+func (c FileConfig) StreamOptions() []state.StreamOption {
+	if len(c.Streams) == 0 {
+		return GetDefaultStreams()
+	}
+
+	streams := make([]state.StreamOption, len(c.Streams))
+	for i, stream := range c.Streams {
+		streams[i] = state.StreamOption{Name: stream.Name, URL: stream.URL}
+	}
+	return streams
+}
+
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory is not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// TrackExtensions are the audio files kept in the download directory: the
+// downloader's mp3s and the formats accepted as uploads.
+var TrackExtensions = []string{".mp3", ".m4a", ".aac", ".ogg", ".opus", ".flac", ".wav", ".webm"}
+
+// IsTrackFile reports whether name has one of the TrackExtensions.
+func IsTrackFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, trackExt := range TrackExtensions {
+		if ext == trackExt {
+			return true
+		}
+	}
+	return false
+}
+
+// ThumbnailExtension is the extension of the thumbnail the downloader saves
+// next to a track, under the track's name.
+const ThumbnailExtension = ".jpg"
+
+// TrackThumbnail returns where the thumbnail of the track at trackPath is
+// saved, whether or not there is one.
+func TrackThumbnail(trackPath string) string {
+	return strings.TrimSuffix(trackPath, filepath.Ext(trackPath)) + ThumbnailExtension
+}
+
+// ResolveTrackPath finds the local file for a track. The downloader may store
+// absolute paths, paths relative to its own working directory, or bare file
+// names, so anything that isn't an existing absolute path is looked up in
+// downloadDir.
+func ResolveTrackPath(downloadDir, filePath string) string {
+	if filePath == "" || downloadDir == "" {
+		return filePath
+	}
+
+	if filepath.IsAbs(filePath) {
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath
+		}
+	} else {
+		joined := filepath.Join(downloadDir, filePath)
+		if _, err := os.Stat(joined); err == nil {
+			return joined
+		}
+	}
+
+	return filepath.Join(downloadDir, filepath.Base(filePath))
+}
+
 func GetDefaultStreams() []state.StreamOption {
 	return []state.StreamOption{
 		{Name: "listen.moe", URL: "https://listen.moe/stream"},
@@ -1,33 +1,130 @@
 package config
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"musicbot/internal/permissions"
 	"musicbot/internal/state"
+	"musicbot/internal/urlnorm"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+const (
+	busyTimeoutMillis = 5000
+	maxReaderConns    = 4
+)
+
+// DatabaseManager keeps two handles on the same SQLite file: a single-connection
+// writer so writes are serialized without "database is locked" errors, and a
+// reader pool that WAL mode lets run alongside the writer.
 type DatabaseManager struct {
-	db *sql.DB
+	path   string
+	writer *sql.DB
+	reader *sql.DB
 }
 
 func NewDatabaseManager(dbPath string) (*DatabaseManager, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	return openDatabase(dbPath, buildDSN(dbPath, "_txlock=immediate"), buildDSN(dbPath, "mode=ro"))
+}
+
+// NewMemoryDatabaseManager opens a database that lives in memory until it is
+// closed, for tests. Managers opened with the same name share a database.
+func NewMemoryDatabaseManager(name string) (*DatabaseManager, error) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_busy_timeout=%d", name, busyTimeoutMillis)
+	return openDatabase(name, dsn+"&_txlock=immediate", dsn)
+}
+
+func openDatabase(dbPath, writerDSN, readerDSN string) (*DatabaseManager, error) {
+	writer, err := sql.Open("sqlite3", writerDSN)
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+
+	reader, err := sql.Open("sqlite3", readerDSN)
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
+	reader.SetMaxOpenConns(maxReaderConns)
+	reader.SetMaxIdleConns(maxReaderConns)
 
-	dm := &DatabaseManager{db: db}
+	dm := &DatabaseManager{path: dbPath, writer: writer, reader: reader}
 	err = dm.initTables()
 	if err != nil {
-		db.Close()
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.ensureColumn("queue", "requested_by", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.ensureColumn("songs", "requester", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	// Items queued before requested_at was tracked keep 0, for unknown.
+	for _, column := range []string{"start_offset", "end_offset", "requested_at"} {
+		err = dm.ensureColumn("queue", column, "INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			dm.Close()
+			return nil, err
+		}
+	}
+
+	// Songs stored before these were tracked keep them NULL.
+	err = dm.ensureColumn("songs", "upload_date", "TEXT")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.ensureColumn("songs", "view_count", "INTEGER")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.ensureColumn("songs", "pinned", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.ensureColumn("queue", "guild_id", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		dm.Close()
+		return nil, err
+	}
+
+	err = dm.migrateQueueGuild()
+	if err != nil {
+		dm.Close()
 		return nil, err
 	}
 
 	return dm, nil
 }
 
+func buildDSN(dbPath, extra string) string {
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&_synchronous=NORMAL", dbPath, busyTimeoutMillis)
+	if extra != "" {
+		dsn += "&" + extra
+	}
+	return dsn
+}
+
 func (dm *DatabaseManager) initTables() error {
 	query := `
 	CREATE TABLE IF NOT EXISTS config (
@@ -53,8 +150,13 @@ func (dm *DatabaseManager) initTables() error {
 	
 	CREATE TABLE IF NOT EXISTS queue (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guild_id TEXT NOT NULL DEFAULT '',
 		song_id INTEGER NOT NULL,
 		position INTEGER NOT NULL,
+		requested_by TEXT NOT NULL DEFAULT '',
+		start_offset INTEGER NOT NULL DEFAULT 0,
+		end_offset INTEGER NOT NULL DEFAULT 0,
+		requested_at INTEGER NOT NULL DEFAULT 0,
 		FOREIGN KEY (song_id) REFERENCES songs (id)
 	);
 	
@@ -63,42 +165,303 @@ func (dm *DatabaseManager) initTables() error {
 		value INTEGER NOT NULL
 	);
 	
+	CREATE TABLE IF NOT EXISTS lyrics (
+		key TEXT PRIMARY KEY,
+		artist TEXT NOT NULL,
+		title TEXT NOT NULL,
+		lyrics TEXT NOT NULL,
+		fetched_at INTEGER NOT NULL
+	);
+	
+	CREATE TABLE IF NOT EXISTS blacklist_urls (
+		guild_id TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		added_by TEXT NOT NULL,
+		added_at INTEGER NOT NULL,
+		PRIMARY KEY (guild_id, pattern)
+	);
+	
+	CREATE TABLE IF NOT EXISTS blacklist_users (
+		guild_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		added_by TEXT NOT NULL,
+		added_at INTEGER NOT NULL,
+		PRIMARY KEY (guild_id, user_id)
+	);
+	
+	CREATE TABLE IF NOT EXISTS queue_timeouts (
+		guild_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		added_by TEXT NOT NULL,
+		added_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		PRIMARY KEY (guild_id, user_id)
+	);
+	
+	CREATE TABLE IF NOT EXISTS disabled_commands (
+		guild_id TEXT NOT NULL,
+		command TEXT NOT NULL,
+		disabled_by TEXT NOT NULL,
+		disabled_at INTEGER NOT NULL,
+		PRIMARY KEY (guild_id, command)
+	);
+	
+	CREATE TABLE IF NOT EXISTS radio_sessions (
+		guild_id TEXT PRIMARY KEY,
+		channel_id TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guild_id TEXT NOT NULL,
+		actor_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_audit_log_guild ON audit_log (guild_id, created_at);
+	
+	CREATE TABLE IF NOT EXISTS download_failures (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guild_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		track INTEGER NOT NULL DEFAULT -1,
+		class TEXT NOT NULL,
+		error TEXT NOT NULL,
+		requested_by TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_download_failures_guild ON download_failures (guild_id, created_at);
+	
+	CREATE TABLE IF NOT EXISTS clips (
+		guild_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		duration INTEGER NOT NULL,
+		added_by TEXT NOT NULL DEFAULT '',
+		added_at INTEGER NOT NULL,
+		PRIMARY KEY (guild_id, name)
+	);
+	
+	CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guild_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		run_at INTEGER NOT NULL,
+		channel_id TEXT NOT NULL,
+		requested_by TEXT NOT NULL,
+		playlist INTEGER NOT NULL DEFAULT 0,
+		preempt INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_schedules_guild ON schedules (guild_id, run_at);
+	
+	CREATE TABLE IF NOT EXISTS search_selections (
+		hash TEXT PRIMARY KEY,
+		owner_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		title TEXT NOT NULL,
+		uploader TEXT,
+		created_at INTEGER NOT NULL
+	);
+	
+	CREATE TABLE IF NOT EXISTS grabs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		guild_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		artist TEXT NOT NULL DEFAULT '',
+		url TEXT NOT NULL,
+		thumbnail_url TEXT NOT NULL DEFAULT '',
+		duration INTEGER NOT NULL DEFAULT 0,
+		is_radio INTEGER NOT NULL DEFAULT 0,
+		grabbed_at INTEGER NOT NULL
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_grabs_user ON grabs (user_id, grabbed_at);
+	
+	CREATE TABLE IF NOT EXISTS listening_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		guild_id TEXT NOT NULL,
+		voice_channel_id TEXT NOT NULL,
+		text_channel_id TEXT NOT NULL,
+		started_by TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		ended_at INTEGER NOT NULL DEFAULT 0,
+		peak_listeners INTEGER NOT NULL DEFAULT 0
+	);
+	
+	CREATE INDEX IF NOT EXISTS idx_listening_sessions_guild ON listening_sessions (guild_id, started_at);
+	
+	CREATE TABLE IF NOT EXISTS listening_session_tracks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		artist TEXT NOT NULL DEFAULT '',
+		url TEXT NOT NULL DEFAULT '',
+		started_at INTEGER NOT NULL,
+		message_id TEXT NOT NULL DEFAULT '',
+		peak_listeners INTEGER NOT NULL DEFAULT 0,
+		reactions INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (session_id) REFERENCES listening_sessions (id)
+	);
+	
+	CREATE TABLE IF NOT EXISTS listening_session_listeners (
+		session_id INTEGER NOT NULL,
+		user_id TEXT NOT NULL,
+		samples INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (session_id, user_id),
+		FOREIGN KEY (session_id) REFERENCES listening_sessions (id)
+	);
+	
 	INSERT OR IGNORE INTO config (key, value) VALUES 
 		('volume', '0.05'),
-		('stream', 'https://listen.moe/stream');
-		
-	INSERT OR IGNORE INTO queue_state (key, value) VALUES 
-		('current_position', '0');
+		('stream', 'https://listen.moe/stream'),
+		('max_queue_length', '100'),
+		('max_user_queued', '25');
 	`
 
-	_, err := dm.db.Exec(query)
+	_, err := dm.writer.Exec(query)
+	return err
+}
+
+// migrateQueueGuild moves a queue saved before queues were kept per guild to
+// the guild it belonged to, which was kept under the music_guild key, along
+// with its position. Without one the rows can't be placed and are dropped.
+func (dm *DatabaseManager) migrateQueueGuild() error {
+	tx, err := dm.writer.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var guildID string
+	err = tx.QueryRow("SELECT value FROM config WHERE key = 'music_guild'").Scan(&guildID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if guildID != "" {
+		if _, err := tx.Exec("UPDATE queue SET guild_id = ? WHERE guild_id = ''", guildID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO queue_state (key, value)
+			SELECT ?, value FROM queue_state WHERE key = 'current_position'
+		`, queuePositionPrefix+guildID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		"DELETE FROM queue WHERE guild_id = ''",
+		"DELETE FROM queue_state WHERE key = 'current_position'",
+		"DELETE FROM config WHERE key = 'music_guild'",
+		"CREATE INDEX IF NOT EXISTS idx_queue_guild ON queue (guild_id, position)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ensureColumn adds a column to a table created by an older version.
+func (dm *DatabaseManager) ensureColumn(table, column, definition string) error {
+	rows, err := dm.writer.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			found = true
+		}
+	}
+	rows.Close()
+
+	if found {
+		return nil
+	}
+
+	_, err = dm.writer.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
+func (dm *DatabaseManager) Path() string {
+	return dm.path
+}
+
+// Ping runs a trivial query through the reader pool to check the database
+// is reachable and not wedged.
+func (dm *DatabaseManager) Ping(ctx context.Context) error {
+	var one int
+	return dm.reader.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+func (dm *DatabaseManager) CountSongs() (int, error) {
+	return dm.CountSongsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) CountSongsCtx(ctx context.Context) (int, error) {
+	var count int
+	err := dm.reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM songs").Scan(&count)
+	return count, err
+}
+
 func (dm *DatabaseManager) LoadConfig() (state.Config, error) {
+	return dm.LoadConfigCtx(context.Background())
+}
+
+// LoadConfigCtx reads the settings kept in the database. Values that don't
+// pass their setting's checks are ignored, and IdleDelay is left zero when
+// config.json decides it.
+func (dm *DatabaseManager) LoadConfigCtx(ctx context.Context) (state.Config, error) {
 	config := state.Config{
 		Streams: GetDefaultStreams(),
 	}
 
-	rows, err := dm.db.Query("SELECT key, value FROM config")
+	values, err := dm.GetSettingsCtx(ctx)
 	if err != nil {
 		return config, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
+	for _, setting := range Settings() {
+		value, ok := values[setting.Key]
+		if !ok {
+			value = setting.Default
+		}
+		if value, err = setting.Parse(value); err != nil {
 			continue
 		}
 
-		switch key {
+		switch setting.Key {
 		case "volume":
-			if v := parseFloat32(value); v > 0 {
-				config.Volume = v
-			}
+			volume, _ := strconv.ParseFloat(value, 32)
+			config.Volume = float32(volume)
 		case "stream":
 			config.Stream = value
+		case "idle_delay":
+			config.IdleDelay, _ = time.ParseDuration(value)
 		}
 	}
 
@@ -106,121 +469,1989 @@ func (dm *DatabaseManager) LoadConfig() (state.Config, error) {
 }
 
 func (dm *DatabaseManager) SaveVolume(volume float32) error {
-	_, err := dm.db.Exec("UPDATE config SET value = ? WHERE key = 'volume'", volume)
+	return dm.SaveVolumeCtx(context.Background(), volume)
+}
+
+func (dm *DatabaseManager) SaveVolumeCtx(ctx context.Context, volume float32) error {
+	_, err := dm.SaveSettingCtx(ctx, "volume", strconv.FormatFloat(float64(volume), 'f', -1, 32))
 	return err
 }
 
 func (dm *DatabaseManager) SaveStream(stream string) error {
-	_, err := dm.db.Exec("UPDATE config SET value = ? WHERE key = 'stream'", stream)
+	return dm.SaveStreamCtx(context.Background(), stream)
+}
+
+func (dm *DatabaseManager) SaveStreamCtx(ctx context.Context, stream string) error {
+	_, err := dm.SaveSettingCtx(ctx, "stream", stream)
 	return err
 }
 
-func (dm *DatabaseManager) GetSongByURL(url string) (*state.Song, error) {
-	var song state.Song
-	var isStreamBool bool // Change type to bool
+// PresenceTemplates are the texts of the bot's status: one while a song
+// plays, one while the radio plays and a list rotated through otherwise.
+// Placeholders such as {title} are filled in by the presence package.
+type PresenceTemplates struct {
+	Playing string
+	Radio   string
+	Idle    []string
+}
+
+func DefaultPresenceTemplates() PresenceTemplates {
+	return PresenceTemplates{
+		Playing: "🎵 {title} - {artist}",
+		Radio:   "Radio Mode | /play for music",
+		Idle: []string{
+			"Use /play",
+			"Serving {guilds} servers",
+			"{uptime} uptime",
+		},
+	}
+}
+
+// GetPresenceTemplates returns the stored templates, with the defaults for
+// any that were never set. The idle templates are stored one per line, since
+// a status can't hold a line break.
+func (dm *DatabaseManager) GetPresenceTemplates() (PresenceTemplates, error) {
+	return dm.GetPresenceTemplatesCtx(context.Background())
+}
 
-	err := dm.db.QueryRow(`
-        SELECT id, title, url, platform, file_path, duration, file_size, thumbnail_url, artist, is_stream
-        FROM songs WHERE url = ?
-    `, url).Scan(&song.ID, &song.Title, &song.URL, &song.Platform, &song.FilePath, &song.Duration, &song.FileSize, &song.ThumbnailURL, &song.Artist, &isStreamBool) // Scan directly into bool
+func (dm *DatabaseManager) GetPresenceTemplatesCtx(ctx context.Context) (PresenceTemplates, error) {
+	templates := DefaultPresenceTemplates()
 
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT key, value FROM config WHERE key IN ('presence_playing', 'presence_radio', 'presence_idle')")
 	if err != nil {
-		return nil, err
+		return templates, err
 	}
+	defer rows.Close()
 
-	song.IsStream = isStreamBool
-	return &song, nil
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		switch key {
+		case "presence_playing":
+			templates.Playing = value
+		case "presence_radio":
+			templates.Radio = value
+		case "presence_idle":
+			templates.Idle = strings.Split(value, "\n")
+		}
+	}
+
+	return templates, rows.Err()
 }
 
-func (dm *DatabaseManager) AddSong(song *state.Song) (int64, error) {
-	result, err := dm.db.Exec(`
-		INSERT INTO songs (title, url, platform, file_path, duration, file_size, thumbnail_url, artist, download_date, is_stream)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, song.Title, song.URL, song.Platform, song.FilePath, song.Duration, song.FileSize, song.ThumbnailURL, song.Artist, time.Now().Unix(), song.IsStream)
+func (dm *DatabaseManager) SavePresenceTemplates(templates PresenceTemplates) error {
+	return dm.SavePresenceTemplatesCtx(context.Background(), templates)
+}
 
+func (dm *DatabaseManager) SavePresenceTemplatesCtx(ctx context.Context, templates PresenceTemplates) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return result.LastInsertId()
-}
+	const upsert = "INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
 
-func (dm *DatabaseManager) AddToQueue(songID int64) error {
-	maxPos := 0
-	err := dm.db.QueryRow("SELECT COALESCE(MAX(position), 0) FROM queue").Scan(&maxPos)
-	if err != nil && err != sql.ErrNoRows {
+	if _, err := tx.ExecContext(ctx, upsert, "presence_playing", templates.Playing); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsert, "presence_radio", templates.Radio); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsert, "presence_idle", strings.Join(templates.Idle, "\n")); err != nil {
 		return err
 	}
 
-	_, err = dm.db.Exec("INSERT INTO queue (song_id, position) VALUES (?, ?)", songID, maxPos+1)
-	return err
+	return tx.Commit()
 }
 
-func (dm *DatabaseManager) GetQueue() ([]state.QueueItem, error) {
-	rows, err := dm.db.Query(`
-		SELECT q.id, q.song_id, q.position, s.title, s.url, s.platform, s.file_path, s.duration, s.file_size, s.thumbnail_url, s.artist, s.is_stream
-		FROM queue q
-		JOIN songs s ON q.song_id = s.id
-		ORDER BY q.position
-	`)
+// QueueLimits caps the total number of upcoming songs and how many of them a
+// single user may have queued or downloading.
+type QueueLimits struct {
+	MaxQueueLength int
+	MaxUserQueued  int
+}
+
+func DefaultQueueLimits() QueueLimits {
+	return QueueLimits{
+		MaxQueueLength: 100,
+		MaxUserQueued:  25,
+	}
+}
+
+func (dm *DatabaseManager) GetQueueLimits() (QueueLimits, error) {
+	return dm.GetQueueLimitsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetQueueLimitsCtx(ctx context.Context) (QueueLimits, error) {
+	limits := DefaultQueueLimits()
+
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT key, value FROM config WHERE key IN ('max_queue_length', 'max_user_queued')")
 	if err != nil {
-		return nil, err
+		return limits, err
 	}
 	defer rows.Close()
 
-	var queue []state.QueueItem
 	for rows.Next() {
-		var item state.QueueItem
-		var song state.Song
-		var isStreamInt int
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
 
-		err := rows.Scan(&item.ID, &item.SongID, &item.Position,
-			&song.Title, &song.URL, &song.Platform, &song.FilePath, &song.Duration, &song.FileSize, &song.ThumbnailURL, &song.Artist, &isStreamInt)
-		if err != nil {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
 			continue
 		}
 
-		song.ID = item.SongID
-		song.IsStream = isStreamInt == 1
-		item.Song = &song
-		queue = append(queue, item)
+		switch key {
+		case "max_queue_length":
+			limits.MaxQueueLength = n
+		case "max_user_queued":
+			limits.MaxUserQueued = n
+		}
 	}
 
-	return queue, nil
-}
-
-func (dm *DatabaseManager) GetCurrentQueuePosition() (int, error) {
-	var position int
-	err := dm.db.QueryRow("SELECT value FROM queue_state WHERE key = 'current_position'").Scan(&position)
-	return position, err
+	return limits, rows.Err()
 }
 
-func (dm *DatabaseManager) SetCurrentQueuePosition(position int) error {
-	_, err := dm.db.Exec("UPDATE queue_state SET value = ? WHERE key = 'current_position'", position)
-	return err
+func (dm *DatabaseManager) SaveQueueLimits(limits QueueLimits) error {
+	return dm.SaveQueueLimitsCtx(context.Background(), limits)
 }
 
-func (dm *DatabaseManager) ClearQueue() error {
-	_, err := dm.db.Exec("DELETE FROM queue")
+func (dm *DatabaseManager) SaveQueueLimitsCtx(ctx context.Context, limits QueueLimits) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	const upsert = "INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
+
+	if _, err := tx.ExecContext(ctx, upsert, "max_queue_length", strconv.Itoa(limits.MaxQueueLength)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsert, "max_user_queued", strconv.Itoa(limits.MaxUserQueued)); err != nil {
+		return err
+	}
 
-	return dm.SetCurrentQueuePosition(0)
+	return tx.Commit()
 }
 
-func (dm *DatabaseManager) RemoveFromQueue(queueID int64) error {
-	_, err := dm.db.Exec("DELETE FROM queue WHERE id = ?", queueID)
-	return err
+// Queue limits a guild set with /setlimit are stored as
+// "max_queue_length:<guildID>" and "max_user_queued:<guildID>". They take
+// precedence over the limits above, which every other guild is held to.
+const (
+	guildMaxQueueLengthPrefix = "max_queue_length:"
+	guildMaxUserQueuedPrefix  = "max_user_queued:"
+)
+
+func (dm *DatabaseManager) GetGuildQueueLimits() (map[string]QueueLimits, error) {
+	return dm.GetGuildQueueLimitsCtx(context.Background())
 }
 
-func (dm *DatabaseManager) Close() error {
-	return dm.db.Close()
+// GetGuildQueueLimitsCtx returns the limits of every guild that set its own.
+// Half a pair that is missing comes from the limits every guild shares.
+func (dm *DatabaseManager) GetGuildQueueLimitsCtx(ctx context.Context) (map[string]QueueLimits, error) {
+	defaults, err := dm.GetQueueLimitsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT key, value FROM config WHERE key LIKE ? OR key LIKE ?",
+		guildMaxQueueLengthPrefix+"%", guildMaxUserQueuedPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guilds := make(map[string]QueueLimits)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			continue
+		}
+
+		if guildID, ok := strings.CutPrefix(key, guildMaxQueueLengthPrefix); ok {
+			limits, found := guilds[guildID]
+			if !found {
+				limits = defaults
+			}
+			limits.MaxQueueLength = n
+			guilds[guildID] = limits
+		} else if guildID, ok := strings.CutPrefix(key, guildMaxUserQueuedPrefix); ok {
+			limits, found := guilds[guildID]
+			if !found {
+				limits = defaults
+			}
+			limits.MaxUserQueued = n
+			guilds[guildID] = limits
+		}
+	}
+
+	return guilds, rows.Err()
 }
 
-func parseFloat32(s string) float32 {
-	if s == "0.05" {
-		return 0.05
+func (dm *DatabaseManager) SaveGuildQueueLimits(guildID string, limits QueueLimits) error {
+	return dm.SaveGuildQueueLimitsCtx(context.Background(), guildID, limits)
+}
+
+func (dm *DatabaseManager) SaveGuildQueueLimitsCtx(ctx context.Context, guildID string, limits QueueLimits) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const upsert = "INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
+
+	if _, err := tx.ExecContext(ctx, upsert, guildMaxQueueLengthPrefix+guildID, strconv.Itoa(limits.MaxQueueLength)); err != nil {
+		return err
 	}
-	return 0
+	if _, err := tx.ExecContext(ctx, upsert, guildMaxUserQueuedPrefix+guildID, strconv.Itoa(limits.MaxUserQueued)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DownloadLimits caps how long and how large a single downloaded track may
+// be. The downloader refuses anything over them, and refuses live streams
+// unless AllowLive is set. AllowLive is chosen per request and never stored,
+// as is Platform, the extractor the downloader is told to use ("" to tell it
+// from the URL). MaxAttempts is how often a download that failed for a
+// passing reason, such as a timeout, is tried before giving up.
+type DownloadLimits struct {
+	MaxDurationSeconds int
+	MaxSizeMB          int
+	AllowLive          bool
+	Platform           string
+	MaxAttempts        int
+}
+
+func DefaultDownloadLimits() DownloadLimits {
+	return DownloadLimits{
+		MaxDurationSeconds: 600,
+		MaxSizeMB:          50,
+		MaxAttempts:        3,
+	}
+}
+
+// Strict reports whether the limits hold tracks to less than the default
+// length, which makes it worth checking a track's length before downloading
+// it.
+func (l DownloadLimits) Strict() bool {
+	return l.MaxDurationSeconds > 0 && l.MaxDurationSeconds < DefaultDownloadLimits().MaxDurationSeconds
+}
+
+// Extended returns the limits for a request a DJ has allowed to run long,
+// such as an hour-long mix. Limits already above the ceiling are kept.
+func (l DownloadLimits) Extended() DownloadLimits {
+	return DownloadLimits{
+		MaxDurationSeconds: max(l.MaxDurationSeconds, 4*60*60),
+		MaxSizeMB:          max(l.MaxSizeMB, 500),
+		AllowLive:          l.AllowLive,
+		Platform:           l.Platform,
+		MaxAttempts:        l.MaxAttempts,
+	}
+}
+
+// Guild download limits are stored as "max_duration:<guildID>",
+// "max_size:<guildID>" and "download_attempts:<guildID>".
+const (
+	guildMaxDurationPrefix = "max_duration:"
+	guildMaxSizePrefix     = "max_size:"
+	guildAttemptsPrefix    = "download_attempts:"
+)
+
+func (dm *DatabaseManager) GetDownloadLimits(guildID string) (DownloadLimits, error) {
+	return dm.GetDownloadLimitsCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetDownloadLimitsCtx(ctx context.Context, guildID string) (DownloadLimits, error) {
+	limits := DefaultDownloadLimits()
+
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT key, value FROM config WHERE key IN (?, ?, ?)",
+		guildMaxDurationPrefix+guildID, guildMaxSizePrefix+guildID, guildAttemptsPrefix+guildID)
+	if err != nil {
+		return limits, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			continue
+		}
+
+		switch key {
+		case guildMaxDurationPrefix + guildID:
+			limits.MaxDurationSeconds = n
+		case guildMaxSizePrefix + guildID:
+			limits.MaxSizeMB = n
+		case guildAttemptsPrefix + guildID:
+			limits.MaxAttempts = n
+		}
+	}
+
+	return limits, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveDownloadLimits(guildID string, limits DownloadLimits) error {
+	return dm.SaveDownloadLimitsCtx(context.Background(), guildID, limits)
+}
+
+func (dm *DatabaseManager) SaveDownloadLimitsCtx(ctx context.Context, guildID string, limits DownloadLimits) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const upsert = "INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
+
+	if _, err := tx.ExecContext(ctx, upsert, guildMaxDurationPrefix+guildID, strconv.Itoa(limits.MaxDurationSeconds)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsert, guildMaxSizePrefix+guildID, strconv.Itoa(limits.MaxSizeMB)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsert, guildAttemptsPrefix+guildID, strconv.Itoa(limits.MaxAttempts)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Guild locales are stored in the config table as "language:<guildID>".
+const guildLocalePrefix = "language:"
+
+func (dm *DatabaseManager) GetGuildLocales() (map[string]string, error) {
+	return dm.GetGuildLocalesCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetGuildLocalesCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildLocalePrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locales := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		locales[strings.TrimPrefix(key, guildLocalePrefix)] = value
+	}
+
+	return locales, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveGuildLocale(guildID, locale string) error {
+	return dm.SaveGuildLocaleCtx(context.Background(), guildID, locale)
+}
+
+func (dm *DatabaseManager) SaveGuildLocaleCtx(ctx context.Context, guildID, locale string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildLocalePrefix+guildID, locale)
+	return err
+}
+
+// Idle channels set with /setidlechannel are stored in the config table as
+// "idle_channel:<guildID>" and take precedence over config.json.
+const guildIdleChannelPrefix = "idle_channel:"
+
+func (dm *DatabaseManager) GetIdleChannels() (map[string]string, error) {
+	return dm.GetIdleChannelsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetIdleChannelsCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildIdleChannelPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		channels[strings.TrimPrefix(key, guildIdleChannelPrefix)] = value
+	}
+
+	return channels, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveIdleChannel(guildID, channelID string) error {
+	return dm.SaveIdleChannelCtx(context.Background(), guildID, channelID)
+}
+
+func (dm *DatabaseManager) SaveIdleChannelCtx(ctx context.Context, guildID, channelID string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildIdleChannelPrefix+guildID, channelID)
+	return err
+}
+
+// DeleteIdleChannel forgets the idle channel set with /setidlechannel, so the
+// one in config.json applies again.
+func (dm *DatabaseManager) DeleteIdleChannel(guildID string) error {
+	return dm.DeleteIdleChannelCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) DeleteIdleChannelCtx(ctx context.Context, guildID string) error {
+	_, err := dm.writer.ExecContext(ctx, "DELETE FROM config WHERE key = ?", guildIdleChannelPrefix+guildID)
+	return err
+}
+
+// 24/7 mode is stored in the config table as "always_on:<guildID>", holding
+// the channel the bot stays in.
+const guildAlwaysOnPrefix = "always_on:"
+
+func (dm *DatabaseManager) GetAlwaysOnChannels() (map[string]string, error) {
+	return dm.GetAlwaysOnChannelsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetAlwaysOnChannelsCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildAlwaysOnPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		channels[strings.TrimPrefix(key, guildAlwaysOnPrefix)] = value
+	}
+
+	return channels, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveAlwaysOnChannel(guildID, channelID string) error {
+	return dm.SaveAlwaysOnChannelCtx(context.Background(), guildID, channelID)
+}
+
+func (dm *DatabaseManager) SaveAlwaysOnChannelCtx(ctx context.Context, guildID, channelID string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildAlwaysOnPrefix+guildID, channelID)
+	return err
+}
+
+// DeleteAlwaysOnChannel turns 24/7 mode off for guildID.
+func (dm *DatabaseManager) DeleteAlwaysOnChannel(guildID string) error {
+	return dm.DeleteAlwaysOnChannelCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) DeleteAlwaysOnChannelCtx(ctx context.Context, guildID string) error {
+	_, err := dm.writer.ExecContext(ctx, "DELETE FROM config WHERE key = ?", guildAlwaysOnPrefix+guildID)
+	return err
+}
+
+// Request channels set with /requestchannel are stored in the config table
+// as "request_channel:<guildID>", holding the text channel.
+const guildRequestChannelPrefix = "request_channel:"
+
+func (dm *DatabaseManager) GetRequestChannels() (map[string]string, error) {
+	return dm.GetRequestChannelsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetRequestChannelsCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildRequestChannelPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		channels[strings.TrimPrefix(key, guildRequestChannelPrefix)] = value
+	}
+
+	return channels, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveRequestChannel(guildID, channelID string) error {
+	return dm.SaveRequestChannelCtx(context.Background(), guildID, channelID)
+}
+
+func (dm *DatabaseManager) SaveRequestChannelCtx(ctx context.Context, guildID, channelID string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildRequestChannelPrefix+guildID, channelID)
+	return err
+}
+
+// DeleteRequestChannel turns the request channel of guildID off.
+func (dm *DatabaseManager) DeleteRequestChannel(guildID string) error {
+	return dm.DeleteRequestChannelCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) DeleteRequestChannelCtx(ctx context.Context, guildID string) error {
+	_, err := dm.writer.ExecContext(ctx, "DELETE FROM config WHERE key = ?", guildRequestChannelPrefix+guildID)
+	return err
+}
+
+// Audio filter presets chosen with /filter are stored in the config table as
+// "filter:<guildID>".
+const guildFilterPrefix = "filter:"
+
+func (dm *DatabaseManager) GetFilters() (map[string]string, error) {
+	return dm.GetFiltersCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetFiltersCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildFilterPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filters := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		filters[strings.TrimPrefix(key, guildFilterPrefix)] = value
+	}
+
+	return filters, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveFilter(guildID, filter string) error {
+	return dm.SaveFilterCtx(context.Background(), guildID, filter)
+}
+
+func (dm *DatabaseManager) SaveFilterCtx(ctx context.Context, guildID, filter string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildFilterPrefix+guildID, filter)
+	return err
+}
+
+// Queue modes chosen with /queuemode are stored in the config table as
+// "queue_mode:<guildID>".
+const guildQueueModePrefix = "queue_mode:"
+
+func (dm *DatabaseManager) GetQueueModes() (map[string]string, error) {
+	return dm.GetQueueModesCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetQueueModesCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildQueueModePrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modes := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		modes[strings.TrimPrefix(key, guildQueueModePrefix)] = value
+	}
+
+	return modes, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveQueueMode(guildID, mode string) error {
+	return dm.SaveQueueModeCtx(context.Background(), guildID, mode)
+}
+
+func (dm *DatabaseManager) SaveQueueModeCtx(ctx context.Context, guildID, mode string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildQueueModePrefix+guildID, mode)
+	return err
+}
+
+// Response styles chosen with /style are stored in the config table as
+// "style:<guildID>".
+const guildStylePrefix = "style:"
+
+func (dm *DatabaseManager) GetStyles() (map[string]string, error) {
+	return dm.GetStylesCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetStylesCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ?", guildStylePrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	styles := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		styles[strings.TrimPrefix(key, guildStylePrefix)] = value
+	}
+
+	return styles, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveStyle(guildID, style string) error {
+	return dm.SaveStyleCtx(context.Background(), guildID, style)
+}
+
+func (dm *DatabaseManager) SaveStyleCtx(ctx context.Context, guildID, style string) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildStylePrefix+guildID, style)
+	return err
+}
+
+// Roles picked with /setdjrole and /setadminrole are stored in the config
+// table as "dj_role:<guildID>" and "admin_role:<guildID>".
+const (
+	guildDJRolePrefix    = "dj_role:"
+	guildAdminRolePrefix = "admin_role:"
+)
+
+func rolePrefix(level permissions.Level) (string, error) {
+	switch level {
+	case permissions.LevelDJ:
+		return guildDJRolePrefix, nil
+	case permissions.LevelAdmin:
+		return guildAdminRolePrefix, nil
+	default:
+		return "", fmt.Errorf("no role is stored for level %s", level)
+	}
+}
+
+// GetRoleIDs returns the roles each guild picked by ID.
+func (dm *DatabaseManager) GetRoleIDs() (map[string]permissions.RoleIDs, error) {
+	return dm.GetRoleIDsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetRoleIDsCtx(ctx context.Context) (map[string]permissions.RoleIDs, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config WHERE key LIKE ? OR key LIKE ?",
+		guildDJRolePrefix+"%", guildAdminRolePrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[string]permissions.RoleIDs)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		if guildID, ok := strings.CutPrefix(key, guildDJRolePrefix); ok {
+			guildRoles := roles[guildID]
+			guildRoles.DJ = value
+			roles[guildID] = guildRoles
+		} else if guildID, ok := strings.CutPrefix(key, guildAdminRolePrefix); ok {
+			guildRoles := roles[guildID]
+			guildRoles.Admin = value
+			roles[guildID] = guildRoles
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+// SaveRoleID stores the role for level in guildID. An empty roleID deletes
+// it, so the role is matched by name again.
+func (dm *DatabaseManager) SaveRoleID(guildID string, level permissions.Level, roleID string) error {
+	return dm.SaveRoleIDCtx(context.Background(), guildID, level, roleID)
+}
+
+func (dm *DatabaseManager) SaveRoleIDCtx(ctx context.Context, guildID string, level permissions.Level, roleID string) error {
+	prefix, err := rolePrefix(level)
+	if err != nil {
+		return err
+	}
+
+	if roleID == "" {
+		_, err = dm.writer.ExecContext(ctx, "DELETE FROM config WHERE key = ?", prefix+guildID)
+		return err
+	}
+	_, err = dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", prefix+guildID, roleID)
+	return err
+}
+
+// DJ-only mode is stored in the config table as "dj_only:<guildID>".
+const guildDJOnlyPrefix = "dj_only:"
+
+// GetDJOnlyGuilds returns the guilds that have DJ-only mode turned on.
+func (dm *DatabaseManager) GetDJOnlyGuilds() ([]string, error) {
+	return dm.GetDJOnlyGuildsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetDJOnlyGuildsCtx(ctx context.Context) ([]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key FROM config WHERE key LIKE ? AND value = '1'", guildDJOnlyPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var guildIDs []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			continue
+		}
+		guildIDs = append(guildIDs, strings.TrimPrefix(key, guildDJOnlyPrefix))
+	}
+
+	return guildIDs, rows.Err()
+}
+
+func (dm *DatabaseManager) SaveDJOnly(guildID string, enabled bool) error {
+	return dm.SaveDJOnlyCtx(context.Background(), guildID, enabled)
+}
+
+func (dm *DatabaseManager) SaveDJOnlyCtx(ctx context.Context, guildID string, enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", guildDJOnlyPrefix+guildID, value)
+	return err
+}
+
+// GetDisabledCommands returns the commands each guild turned off, by guild.
+func (dm *DatabaseManager) GetDisabledCommands() (map[string][]string, error) {
+	return dm.GetDisabledCommandsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetDisabledCommandsCtx(ctx context.Context) (map[string][]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT guild_id, command FROM disabled_commands ORDER BY guild_id, command")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disabled := make(map[string][]string)
+	for rows.Next() {
+		var guildID, command string
+		if err := rows.Scan(&guildID, &command); err != nil {
+			continue
+		}
+		disabled[guildID] = append(disabled[guildID], command)
+	}
+
+	return disabled, rows.Err()
+}
+
+func (dm *DatabaseManager) DisableCommand(guildID, command, disabledBy string) error {
+	return dm.DisableCommandCtx(context.Background(), guildID, command, disabledBy)
+}
+
+func (dm *DatabaseManager) DisableCommandCtx(ctx context.Context, guildID, command, disabledBy string) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO disabled_commands (guild_id, command, disabled_by, disabled_at) VALUES (?, ?, ?, ?)",
+		guildID, command, disabledBy, time.Now().Unix())
+	return err
+}
+
+// EnableCommand turns command back on in guildID and reports whether it was
+// off.
+func (dm *DatabaseManager) EnableCommand(guildID, command string) (bool, error) {
+	return dm.EnableCommandCtx(context.Background(), guildID, command)
+}
+
+func (dm *DatabaseManager) EnableCommandCtx(ctx context.Context, guildID, command string) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx, "DELETE FROM disabled_commands WHERE guild_id = ? AND command = ?", guildID, command)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// guildSettingPrefixes are the config keys that hold a setting of one guild,
+// followed by its ID.
+var guildSettingPrefixes = []string{
+	guildMaxQueueLengthPrefix,
+	guildMaxUserQueuedPrefix,
+	guildMaxDurationPrefix,
+	guildMaxSizePrefix,
+	guildAttemptsPrefix,
+	guildLocalePrefix,
+	guildIdleChannelPrefix,
+	guildAlwaysOnPrefix,
+	guildRequestChannelPrefix,
+	guildFilterPrefix,
+	guildQueueModePrefix,
+	guildStylePrefix,
+	guildDJRolePrefix,
+	guildAdminRolePrefix,
+	guildDJOnlyPrefix,
+}
+
+// PurgeGuild deletes the settings of a guild the bot was removed from: its
+// config keys, queue, blacklists, queue timeouts, disabled commands, clips and
+// schedules. History such as the audit log, failures, grabs and listening
+// sessions is kept.
+func (dm *DatabaseManager) PurgeGuild(guildID string) error {
+	return dm.PurgeGuildCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) PurgeGuildCtx(ctx context.Context, guildID string) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, prefix := range guildSettingPrefixes {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM config WHERE key = ?", prefix+guildID); err != nil {
+			return err
+		}
+	}
+	for _, table := range []string{"blacklist_urls", "blacklist_users", "queue_timeouts", "disabled_commands", "clips", "schedules", "radio_sessions"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE guild_id = ?", table), guildID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM queue WHERE guild_id = ?", guildID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM queue_state WHERE key = ?", queuePositionPrefix+guildID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SearchSelection maps a search button's hash to the result it selects, so
+// buttons keep working after the in-memory search session is gone.
+type SearchSelection struct {
+	Hash     string
+	OwnerID  string
+	URL      string
+	Title    string
+	Uploader string
+}
+
+func (dm *DatabaseManager) SaveSearchSelections(selections []SearchSelection) error {
+	return dm.SaveSearchSelectionsCtx(context.Background(), selections)
+}
+
+func (dm *DatabaseManager) SaveSearchSelectionsCtx(ctx context.Context, selections []SearchSelection) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, sel := range selections {
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO search_selections (hash, owner_id, url, title, uploader, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, sel.Hash, sel.OwnerID, sel.URL, sel.Title, sel.Uploader, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSearchSelection returns the selection for hash if it is younger than
+// maxAge. The janitor deletes old rows, but only when it runs at startup.
+func (dm *DatabaseManager) GetSearchSelection(hash string, maxAge time.Duration) (*SearchSelection, error) {
+	return dm.GetSearchSelectionCtx(context.Background(), hash, maxAge)
+}
+
+func (dm *DatabaseManager) GetSearchSelectionCtx(ctx context.Context, hash string, maxAge time.Duration) (*SearchSelection, error) {
+	sel := SearchSelection{Hash: hash}
+	var uploader sql.NullString
+
+	err := dm.reader.QueryRowContext(ctx, `
+		SELECT owner_id, url, title, uploader FROM search_selections
+		WHERE hash = ? AND created_at >= ?
+	`, hash, time.Now().Add(-maxAge).Unix()).Scan(&sel.OwnerID, &sel.URL, &sel.Title, &uploader)
+	if err != nil {
+		return nil, err
+	}
+
+	sel.Uploader = uploader.String
+	return &sel, nil
+}
+
+// CachedLyrics are lyrics fetched for a track, stored under a normalized
+// artist and title.
+type CachedLyrics struct {
+	Key    string
+	Artist string
+	Title  string
+	Lyrics string
+}
+
+func (dm *DatabaseManager) GetLyrics(key string) (*CachedLyrics, error) {
+	return dm.GetLyricsCtx(context.Background(), key)
+}
+
+func (dm *DatabaseManager) GetLyricsCtx(ctx context.Context, key string) (*CachedLyrics, error) {
+	lyrics := CachedLyrics{Key: key}
+	err := dm.reader.QueryRowContext(ctx,
+		"SELECT artist, title, lyrics FROM lyrics WHERE key = ?", key,
+	).Scan(&lyrics.Artist, &lyrics.Title, &lyrics.Lyrics)
+	if err != nil {
+		return nil, err
+	}
+	return &lyrics, nil
+}
+
+func (dm *DatabaseManager) SaveLyrics(lyrics CachedLyrics) error {
+	return dm.SaveLyricsCtx(context.Background(), lyrics)
+}
+
+func (dm *DatabaseManager) SaveLyricsCtx(ctx context.Context, lyrics CachedLyrics) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO lyrics (key, artist, title, lyrics, fetched_at) VALUES (?, ?, ?, ?, ?)",
+		lyrics.Key, lyrics.Artist, lyrics.Title, lyrics.Lyrics, time.Now().Unix())
+	return err
+}
+
+// BlacklistEntry is a URL pattern or user ID that may not queue music in a
+// guild.
+type BlacklistEntry struct {
+	GuildID string
+	Value   string
+	AddedBy string
+	AddedAt time.Time
+}
+
+func (dm *DatabaseManager) GetBlacklistedURLs() ([]BlacklistEntry, error) {
+	return dm.GetBlacklistedURLsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetBlacklistedURLsCtx(ctx context.Context) ([]BlacklistEntry, error) {
+	return dm.getBlacklist(ctx, "SELECT guild_id, pattern, added_by, added_at FROM blacklist_urls ORDER BY added_at")
+}
+
+func (dm *DatabaseManager) AddBlacklistedURL(entry BlacklistEntry) error {
+	return dm.AddBlacklistedURLCtx(context.Background(), entry)
+}
+
+func (dm *DatabaseManager) AddBlacklistedURLCtx(ctx context.Context, entry BlacklistEntry) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO blacklist_urls (guild_id, pattern, added_by, added_at) VALUES (?, ?, ?, ?)",
+		entry.GuildID, entry.Value, entry.AddedBy, entry.AddedAt.Unix())
+	return err
+}
+
+// RemoveBlacklistedURL reports whether the pattern was on the blacklist.
+func (dm *DatabaseManager) RemoveBlacklistedURL(guildID, pattern string) (bool, error) {
+	return dm.RemoveBlacklistedURLCtx(context.Background(), guildID, pattern)
+}
+
+func (dm *DatabaseManager) RemoveBlacklistedURLCtx(ctx context.Context, guildID, pattern string) (bool, error) {
+	return dm.removeBlacklisted(ctx, "DELETE FROM blacklist_urls WHERE guild_id = ? AND pattern = ?", guildID, pattern)
+}
+
+func (dm *DatabaseManager) GetBlacklistedUsers() ([]BlacklistEntry, error) {
+	return dm.GetBlacklistedUsersCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetBlacklistedUsersCtx(ctx context.Context) ([]BlacklistEntry, error) {
+	return dm.getBlacklist(ctx, "SELECT guild_id, user_id, added_by, added_at FROM blacklist_users ORDER BY added_at")
+}
+
+func (dm *DatabaseManager) AddBlacklistedUser(entry BlacklistEntry) error {
+	return dm.AddBlacklistedUserCtx(context.Background(), entry)
+}
+
+func (dm *DatabaseManager) AddBlacklistedUserCtx(ctx context.Context, entry BlacklistEntry) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO blacklist_users (guild_id, user_id, added_by, added_at) VALUES (?, ?, ?, ?)",
+		entry.GuildID, entry.Value, entry.AddedBy, entry.AddedAt.Unix())
+	return err
+}
+
+// RemoveBlacklistedUser reports whether the user was on the blacklist.
+func (dm *DatabaseManager) RemoveBlacklistedUser(guildID, userID string) (bool, error) {
+	return dm.RemoveBlacklistedUserCtx(context.Background(), guildID, userID)
+}
+
+func (dm *DatabaseManager) RemoveBlacklistedUserCtx(ctx context.Context, guildID, userID string) (bool, error) {
+	return dm.removeBlacklisted(ctx, "DELETE FROM blacklist_users WHERE guild_id = ? AND user_id = ?", guildID, userID)
+}
+
+func (dm *DatabaseManager) getBlacklist(ctx context.Context, query string) ([]BlacklistEntry, error) {
+	rows, err := dm.reader.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []BlacklistEntry
+	for rows.Next() {
+		var entry BlacklistEntry
+		var addedAt int64
+		if err := rows.Scan(&entry.GuildID, &entry.Value, &entry.AddedBy, &addedAt); err != nil {
+			continue
+		}
+		entry.AddedAt = time.Unix(addedAt, 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (dm *DatabaseManager) removeBlacklisted(ctx context.Context, query, guildID, value string) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx, query, guildID, value)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// QueueTimeout bars a user from queueing music in a guild until ExpiresAt.
+type QueueTimeout struct {
+	GuildID   string
+	UserID    string
+	AddedBy   string
+	AddedAt   time.Time
+	ExpiresAt time.Time
+}
+
+// GetQueueTimeouts returns the timeouts that haven't expired yet.
+func (dm *DatabaseManager) GetQueueTimeouts() ([]QueueTimeout, error) {
+	return dm.GetQueueTimeoutsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetQueueTimeoutsCtx(ctx context.Context) ([]QueueTimeout, error) {
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT guild_id, user_id, added_by, added_at, expires_at FROM queue_timeouts WHERE expires_at > ? ORDER BY expires_at",
+		time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timeouts []QueueTimeout
+	for rows.Next() {
+		var timeout QueueTimeout
+		var addedAt, expiresAt int64
+		if err := rows.Scan(&timeout.GuildID, &timeout.UserID, &timeout.AddedBy, &addedAt, &expiresAt); err != nil {
+			continue
+		}
+		timeout.AddedAt = time.Unix(addedAt, 0)
+		timeout.ExpiresAt = time.Unix(expiresAt, 0)
+		timeouts = append(timeouts, timeout)
+	}
+
+	return timeouts, rows.Err()
+}
+
+// AddQueueTimeout stores timeout, replacing any the user already had.
+func (dm *DatabaseManager) AddQueueTimeout(timeout QueueTimeout) error {
+	return dm.AddQueueTimeoutCtx(context.Background(), timeout)
+}
+
+func (dm *DatabaseManager) AddQueueTimeoutCtx(ctx context.Context, timeout QueueTimeout) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO queue_timeouts (guild_id, user_id, added_by, added_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		timeout.GuildID, timeout.UserID, timeout.AddedBy, timeout.AddedAt.Unix(), timeout.ExpiresAt.Unix())
+	return err
+}
+
+// RemoveQueueTimeout reports whether the user had a timeout.
+func (dm *DatabaseManager) RemoveQueueTimeout(guildID, userID string) (bool, error) {
+	return dm.RemoveQueueTimeoutCtx(context.Background(), guildID, userID)
+}
+
+func (dm *DatabaseManager) RemoveQueueTimeoutCtx(ctx context.Context, guildID, userID string) (bool, error) {
+	return dm.removeBlacklisted(ctx, "DELETE FROM queue_timeouts WHERE guild_id = ? AND user_id = ?", guildID, userID)
+}
+
+// DeleteExpiredQueueTimeouts removes the timeouts that have run out and
+// returns how many there were.
+func (dm *DatabaseManager) DeleteExpiredQueueTimeouts() (int64, error) {
+	return dm.DeleteExpiredQueueTimeoutsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) DeleteExpiredQueueTimeoutsCtx(ctx context.Context) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx, "DELETE FROM queue_timeouts WHERE expires_at <= ?", time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// AuditEntry is one recorded music or moderation action.
+type AuditEntry struct {
+	GuildID string
+	ActorID string
+	Action  string
+	Target  string
+	At      time.Time
+}
+
+func (dm *DatabaseManager) AddAuditEntry(entry AuditEntry) error {
+	return dm.AddAuditEntryCtx(context.Background(), entry)
+}
+
+func (dm *DatabaseManager) AddAuditEntryCtx(ctx context.Context, entry AuditEntry) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT INTO audit_log (guild_id, actor_id, action, target, created_at) VALUES (?, ?, ?, ?, ?)",
+		entry.GuildID, entry.ActorID, entry.Action, entry.Target, entry.At.Unix())
+	return err
+}
+
+// GetAuditEntries returns the latest limit entries of guildID, newest first.
+func (dm *DatabaseManager) GetAuditEntries(guildID string, limit int) ([]AuditEntry, error) {
+	return dm.GetAuditEntriesCtx(context.Background(), guildID, limit)
+}
+
+func (dm *DatabaseManager) GetAuditEntriesCtx(ctx context.Context, guildID string, limit int) ([]AuditEntry, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT actor_id, action, target, created_at
+		FROM audit_log
+		WHERE guild_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, guildID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		entry := AuditEntry{GuildID: guildID}
+		var createdAt int64
+		if err := rows.Scan(&entry.ActorID, &entry.Action, &entry.Target, &createdAt); err != nil {
+			continue
+		}
+		entry.At = time.Unix(createdAt, 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// DownloadFailure is a download that failed for good. Track is the index of
+// the track within the playlist at URL, or -1 for a single song.
+type DownloadFailure struct {
+	ID          int64
+	GuildID     string
+	URL         string
+	Track       int
+	Class       string
+	Error       string
+	RequestedBy string
+	At          time.Time
+}
+
+func (dm *DatabaseManager) AddDownloadFailure(failure DownloadFailure) error {
+	return dm.AddDownloadFailureCtx(context.Background(), failure)
+}
+
+func (dm *DatabaseManager) AddDownloadFailureCtx(ctx context.Context, failure DownloadFailure) error {
+	_, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO download_failures (guild_id, url, track, class, error, requested_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, failure.GuildID, failure.URL, failure.Track, failure.Class, failure.Error, failure.RequestedBy, failure.At.Unix())
+	return err
+}
+
+// GetDownloadFailures returns the latest limit failures of guildID, newest
+// first.
+func (dm *DatabaseManager) GetDownloadFailures(guildID string, limit int) ([]DownloadFailure, error) {
+	return dm.GetDownloadFailuresCtx(context.Background(), guildID, limit)
+}
+
+func (dm *DatabaseManager) GetDownloadFailuresCtx(ctx context.Context, guildID string, limit int) ([]DownloadFailure, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT id, url, track, class, error, requested_by, created_at
+		FROM download_failures
+		WHERE guild_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, guildID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []DownloadFailure
+	for rows.Next() {
+		failure := DownloadFailure{GuildID: guildID}
+		var createdAt int64
+		if err := rows.Scan(&failure.ID, &failure.URL, &failure.Track, &failure.Class, &failure.Error, &failure.RequestedBy, &createdAt); err != nil {
+			continue
+		}
+		failure.At = time.Unix(createdAt, 0)
+		failures = append(failures, failure)
+	}
+
+	return failures, rows.Err()
+}
+
+// GetDownloadFailure returns the failure with id if it belongs to guildID.
+func (dm *DatabaseManager) GetDownloadFailure(guildID string, id int64) (*DownloadFailure, error) {
+	return dm.GetDownloadFailureCtx(context.Background(), guildID, id)
+}
+
+func (dm *DatabaseManager) GetDownloadFailureCtx(ctx context.Context, guildID string, id int64) (*DownloadFailure, error) {
+	failure := DownloadFailure{ID: id, GuildID: guildID}
+	var createdAt int64
+	err := dm.reader.QueryRowContext(ctx, `
+		SELECT url, track, class, error, requested_by, created_at
+		FROM download_failures
+		WHERE id = ? AND guild_id = ?
+	`, id, guildID).Scan(&failure.URL, &failure.Track, &failure.Class, &failure.Error, &failure.RequestedBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	failure.At = time.Unix(createdAt, 0)
+	return &failure, nil
+}
+
+// DeleteExpiredDownloadFailures removes failures older than maxAge and
+// returns how many were removed.
+func (dm *DatabaseManager) DeleteExpiredDownloadFailures(maxAge time.Duration) (int64, error) {
+	return dm.DeleteExpiredDownloadFailuresCtx(context.Background(), maxAge)
+}
+
+func (dm *DatabaseManager) DeleteExpiredDownloadFailuresCtx(ctx context.Context, maxAge time.Duration) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx,
+		"DELETE FROM download_failures WHERE created_at < ?", time.Now().Add(-maxAge).Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ErrClipExists is returned by AddClip when the guild already has a clip
+// with that name.
+var ErrClipExists = errors.New("a clip with that name already exists")
+
+// Clip is a short sound a guild can play over the music with /clip. Duration
+// is stored in milliseconds.
+type Clip struct {
+	GuildID  string
+	Name     string
+	FilePath string
+	Duration time.Duration
+	AddedBy  string
+	AddedAt  time.Time
+}
+
+func (dm *DatabaseManager) AddClip(clip Clip) error {
+	return dm.AddClipCtx(context.Background(), clip)
+}
+
+func (dm *DatabaseManager) AddClipCtx(ctx context.Context, clip Clip) error {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT OR IGNORE INTO clips (guild_id, name, file_path, duration, added_by, added_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, clip.GuildID, clip.Name, clip.FilePath, clip.Duration.Milliseconds(), clip.AddedBy, clip.AddedAt.Unix())
+	if err != nil {
+		return err
+	}
+	if added, err := result.RowsAffected(); err == nil && added == 0 {
+		return ErrClipExists
+	}
+	return nil
+}
+
+// GetClip returns the clip of guildID called name, or sql.ErrNoRows.
+func (dm *DatabaseManager) GetClip(guildID, name string) (*Clip, error) {
+	return dm.GetClipCtx(context.Background(), guildID, name)
+}
+
+func (dm *DatabaseManager) GetClipCtx(ctx context.Context, guildID, name string) (*Clip, error) {
+	clip := Clip{GuildID: guildID, Name: name}
+	var duration, addedAt int64
+	err := dm.reader.QueryRowContext(ctx,
+		"SELECT file_path, duration, added_by, added_at FROM clips WHERE guild_id = ? AND name = ?",
+		guildID, name).Scan(&clip.FilePath, &duration, &clip.AddedBy, &addedAt)
+	if err != nil {
+		return nil, err
+	}
+	clip.Duration = time.Duration(duration) * time.Millisecond
+	clip.AddedAt = time.Unix(addedAt, 0)
+	return &clip, nil
+}
+
+// GetClips returns the clips of guildID by name.
+func (dm *DatabaseManager) GetClips(guildID string) ([]Clip, error) {
+	return dm.GetClipsCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetClipsCtx(ctx context.Context, guildID string) ([]Clip, error) {
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT name, file_path, duration, added_by, added_at FROM clips WHERE guild_id = ? ORDER BY name",
+		guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clips []Clip
+	for rows.Next() {
+		clip := Clip{GuildID: guildID}
+		var duration, addedAt int64
+		if err := rows.Scan(&clip.Name, &clip.FilePath, &duration, &clip.AddedBy, &addedAt); err != nil {
+			continue
+		}
+		clip.Duration = time.Duration(duration) * time.Millisecond
+		clip.AddedAt = time.Unix(addedAt, 0)
+		clips = append(clips, clip)
+	}
+
+	return clips, rows.Err()
+}
+
+// DeleteClip removes the clip of guildID called name and reports whether
+// there was one.
+func (dm *DatabaseManager) DeleteClip(guildID, name string) (bool, error) {
+	return dm.DeleteClipCtx(context.Background(), guildID, name)
+}
+
+func (dm *DatabaseManager) DeleteClipCtx(ctx context.Context, guildID, name string) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx, "DELETE FROM clips WHERE guild_id = ? AND name = ?", guildID, name)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// ClipFileInUse reports whether any clip, in any guild, plays filePath.
+func (dm *DatabaseManager) ClipFileInUse(filePath string) (bool, error) {
+	return dm.ClipFileInUseCtx(context.Background(), filePath)
+}
+
+func (dm *DatabaseManager) ClipFileInUseCtx(ctx context.Context, filePath string) (bool, error) {
+	var count int
+	err := dm.reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM clips WHERE file_path = ?", filePath).Scan(&count)
+	return count > 0, err
+}
+
+// Grab is a track a user saved with /grab. Radio grabs keep the stream URL,
+// which can't be queued again.
+type Grab struct {
+	ID           int64
+	UserID       string
+	GuildID      string
+	Title        string
+	Artist       string
+	URL          string
+	ThumbnailURL string
+	Duration     int
+	IsRadio      bool
+	GrabbedAt    time.Time
+}
+
+// AddGrab saves grab and returns it with its ID.
+func (dm *DatabaseManager) AddGrab(grab Grab) (Grab, error) {
+	return dm.AddGrabCtx(context.Background(), grab)
+}
+
+func (dm *DatabaseManager) AddGrabCtx(ctx context.Context, grab Grab) (Grab, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO grabs (user_id, guild_id, title, artist, url, thumbnail_url, duration, is_radio, grabbed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, grab.UserID, grab.GuildID, grab.Title, grab.Artist, grab.URL, grab.ThumbnailURL, grab.Duration, grab.IsRadio, grab.GrabbedAt.Unix())
+	if err != nil {
+		return grab, err
+	}
+	grab.ID, err = result.LastInsertId()
+	return grab, err
+}
+
+// GetGrab returns the grab of userID with id, or sql.ErrNoRows.
+func (dm *DatabaseManager) GetGrab(userID string, id int64) (*Grab, error) {
+	return dm.GetGrabCtx(context.Background(), userID, id)
+}
+
+func (dm *DatabaseManager) GetGrabCtx(ctx context.Context, userID string, id int64) (*Grab, error) {
+	row := dm.reader.QueryRowContext(ctx, `
+		SELECT id, user_id, guild_id, title, artist, url, thumbnail_url, duration, is_radio, grabbed_at
+		FROM grabs WHERE user_id = ? AND id = ?
+	`, userID, id)
+	return scanGrab(row)
+}
+
+// GetGrabs returns the limit latest grabs of userID, newest first.
+func (dm *DatabaseManager) GetGrabs(userID string, limit int) ([]Grab, error) {
+	return dm.GetGrabsCtx(context.Background(), userID, limit)
+}
+
+func (dm *DatabaseManager) GetGrabsCtx(ctx context.Context, userID string, limit int) ([]Grab, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT id, user_id, guild_id, title, artist, url, thumbnail_url, duration, is_radio, grabbed_at
+		FROM grabs WHERE user_id = ? ORDER BY grabbed_at DESC, id DESC LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grabs []Grab
+	for rows.Next() {
+		grab, err := scanGrab(rows)
+		if err != nil {
+			continue
+		}
+		grabs = append(grabs, *grab)
+	}
+
+	return grabs, rows.Err()
+}
+
+func scanGrab(row interface{ Scan(...any) error }) (*Grab, error) {
+	var grab Grab
+	var grabbedAt int64
+	err := row.Scan(&grab.ID, &grab.UserID, &grab.GuildID, &grab.Title, &grab.Artist, &grab.URL,
+		&grab.ThumbnailURL, &grab.Duration, &grab.IsRadio, &grabbedAt)
+	if err != nil {
+		return nil, err
+	}
+	grab.GrabbedAt = time.Unix(grabbedAt, 0)
+	return &grab, nil
+}
+
+// Schedule is playback planned with /schedule: url is queued in ChannelID
+// at RunAt. Preempt skips whatever is playing then instead of queueing
+// behind it.
+type Schedule struct {
+	ID          int64
+	GuildID     string
+	URL         string
+	RunAt       time.Time
+	ChannelID   string
+	RequestedBy string
+	Playlist    bool
+	Preempt     bool
+	CreatedAt   time.Time
+}
+
+// AddSchedule stores schedule and returns its ID.
+func (dm *DatabaseManager) AddSchedule(schedule Schedule) (int64, error) {
+	return dm.AddScheduleCtx(context.Background(), schedule)
+}
+
+func (dm *DatabaseManager) AddScheduleCtx(ctx context.Context, schedule Schedule) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO schedules (guild_id, url, run_at, channel_id, requested_by, playlist, preempt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, schedule.GuildID, schedule.URL, schedule.RunAt.Unix(), schedule.ChannelID, schedule.RequestedBy,
+		schedule.Playlist, schedule.Preempt, schedule.CreatedAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetSchedules returns the schedules of guildID, or of every guild if
+// guildID is empty, soonest first.
+func (dm *DatabaseManager) GetSchedules(guildID string) ([]Schedule, error) {
+	return dm.GetSchedulesCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetSchedulesCtx(ctx context.Context, guildID string) ([]Schedule, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT id, guild_id, url, run_at, channel_id, requested_by, playlist, preempt, created_at
+		FROM schedules WHERE ? = '' OR guild_id = ?
+		ORDER BY run_at, id
+	`, guildID, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var schedule Schedule
+		var runAt, createdAt int64
+		if err := rows.Scan(&schedule.ID, &schedule.GuildID, &schedule.URL, &runAt, &schedule.ChannelID,
+			&schedule.RequestedBy, &schedule.Playlist, &schedule.Preempt, &createdAt); err != nil {
+			continue
+		}
+		schedule.RunAt = time.Unix(runAt, 0)
+		schedule.CreatedAt = time.Unix(createdAt, 0)
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// DeleteSchedule removes schedule id of guildID and reports whether there
+// was one.
+func (dm *DatabaseManager) DeleteSchedule(guildID string, id int64) (bool, error) {
+	return dm.DeleteScheduleCtx(context.Background(), guildID, id)
+}
+
+func (dm *DatabaseManager) DeleteScheduleCtx(ctx context.Context, guildID string, id int64) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx, "DELETE FROM schedules WHERE guild_id = ? AND id = ?", guildID, id)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+func (dm *DatabaseManager) GetSongByURL(url string) (*state.Song, error) {
+	return dm.GetSongByURLCtx(context.Background(), url)
+}
+
+// GetSongByURLCtx looks the song up by its normalized URL, falling back to
+// url as given for rows stored before URLs were normalized.
+func (dm *DatabaseManager) GetSongByURLCtx(ctx context.Context, url string) (*state.Song, error) {
+	var song state.Song
+	var isStreamBool bool
+	var uploadDate sql.NullString
+	var viewCount sql.NullInt64
+
+	normalized := urlnorm.Normalize(url)
+	err := dm.reader.QueryRowContext(ctx, `
+        SELECT id, title, url, platform, file_path, duration, file_size, thumbnail_url, artist, is_stream, upload_date, view_count
+        FROM songs WHERE url IN (?, ?)
+        ORDER BY url = ? DESC LIMIT 1
+    `, normalized, url, normalized).Scan(&song.ID, &song.Title, &song.URL, &song.Platform, &song.FilePath, &song.Duration, &song.FileSize, &song.ThumbnailURL, &song.Artist, &isStreamBool, &uploadDate, &viewCount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	song.IsStream = isStreamBool
+	song.UploadDate, song.ViewCount = uploadDate.String, viewCount.Int64
+	return &song, nil
+}
+
+func (dm *DatabaseManager) AddSong(song *state.Song) (int64, error) {
+	return dm.AddSongCtx(context.Background(), song)
+}
+
+func (dm *DatabaseManager) AddSongCtx(ctx context.Context, song *state.Song) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO songs (title, url, platform, file_path, duration, file_size, thumbnail_url, artist, download_date, is_stream, requester, upload_date, view_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, song.Title, urlnorm.Normalize(song.URL), song.Platform, song.FilePath, song.Duration, song.FileSize, song.ThumbnailURL, song.Artist, time.Now().Unix(), song.IsStream, song.RequesterID,
+		nullString(song.UploadDate), nullInt64(song.ViewCount))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// UpdateSongMetadata stores the upload date and view count the downloader
+// reported for a song it added itself. Unknown values leave the stored ones
+// alone.
+func (dm *DatabaseManager) UpdateSongMetadata(songID int64, uploadDate string, viewCount int64) error {
+	return dm.UpdateSongMetadataCtx(context.Background(), songID, uploadDate, viewCount)
+}
+
+func (dm *DatabaseManager) UpdateSongMetadataCtx(ctx context.Context, songID int64, uploadDate string, viewCount int64) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"UPDATE songs SET upload_date = COALESCE(?, upload_date), view_count = COALESCE(?, view_count) WHERE id = ?",
+		nullString(uploadDate), nullInt64(viewCount), songID)
+	return err
+}
+
+// UpdateSongFile points a song at a file that was downloaded again after the
+// old one went missing.
+func (dm *DatabaseManager) UpdateSongFile(songID int64, filePath string, fileSize int64) error {
+	return dm.UpdateSongFileCtx(context.Background(), songID, filePath, fileSize)
+}
+
+func (dm *DatabaseManager) UpdateSongFileCtx(ctx context.Context, songID int64, filePath string, fileSize int64) error {
+	_, err := dm.writer.ExecContext(ctx, "UPDATE songs SET file_path = ?, file_size = ? WHERE id = ?", filePath, fileSize, songID)
+	return err
+}
+
+// UpdateSongDuration stores the length of a song the downloader couldn't
+// tell, in seconds. Songs that already have a length keep it.
+func (dm *DatabaseManager) UpdateSongDuration(songID int64, duration int) error {
+	return dm.UpdateSongDurationCtx(context.Background(), songID, duration)
+}
+
+func (dm *DatabaseManager) UpdateSongDurationCtx(ctx context.Context, songID int64, duration int) error {
+	_, err := dm.writer.ExecContext(ctx, "UPDATE songs SET duration = ? WHERE id = ? AND COALESCE(duration, 0) <= 0", duration, songID)
+	return err
+}
+
+// MarkSongPlayed bumps a song's play count and last played time, which the
+// janitor uses to pick eviction candidates.
+func (dm *DatabaseManager) MarkSongPlayed(songID int64) error {
+	return dm.MarkSongPlayedCtx(context.Background(), songID)
+}
+
+func (dm *DatabaseManager) MarkSongPlayedCtx(ctx context.Context, songID int64) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"UPDATE songs SET play_count = COALESCE(play_count, 0) + 1, last_played = ? WHERE id = ?",
+		time.Now().Unix(), songID)
+	return err
+}
+
+// CachedSong is the part of a songs row the janitor needs.
+type CachedSong struct {
+	ID           int64
+	Title        string
+	FilePath     string
+	IsStream     bool
+	DownloadDate time.Time
+	LastPlayed   time.Time
+	Pinned       bool
+}
+
+// LastUsed is the later of the download and last play times.
+func (cs CachedSong) LastUsed() time.Time {
+	if cs.LastPlayed.After(cs.DownloadDate) {
+		return cs.LastPlayed
+	}
+	return cs.DownloadDate
+}
+
+func (dm *DatabaseManager) ListCachedSongs() ([]CachedSong, error) {
+	return dm.ListCachedSongsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) ListCachedSongsCtx(ctx context.Context) ([]CachedSong, error) {
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT id, title, file_path, is_stream, download_date, COALESCE(last_played, 0), pinned FROM songs")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []CachedSong
+	for rows.Next() {
+		var song CachedSong
+		var isStreamInt int
+		var downloadDate, lastPlayed int64
+
+		if err := rows.Scan(&song.ID, &song.Title, &song.FilePath, &isStreamInt, &downloadDate, &lastPlayed, &song.Pinned); err != nil {
+			continue
+		}
+
+		song.IsStream = isStreamInt == 1
+		song.DownloadDate = time.Unix(downloadDate, 0)
+		if lastPlayed > 0 {
+			song.LastPlayed = time.Unix(lastPlayed, 0)
+		}
+		songs = append(songs, song)
+	}
+
+	return songs, rows.Err()
+}
+
+// QueuedSongIDs returns the IDs of every song referenced by the persisted queue.
+func (dm *DatabaseManager) QueuedSongIDs() (map[int64]bool, error) {
+	return dm.QueuedSongIDsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) QueuedSongIDsCtx(ctx context.Context) (map[int64]bool, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT DISTINCT song_id FROM queue")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		ids[id] = true
+	}
+
+	return ids, rows.Err()
+}
+
+// DeleteSong removes a song row unless the queue still references it or it
+// is pinned. It reports whether the row was deleted.
+func (dm *DatabaseManager) DeleteSong(songID int64) (bool, error) {
+	return dm.DeleteSongCtx(context.Background(), songID)
+}
+
+func (dm *DatabaseManager) DeleteSongCtx(ctx context.Context, songID int64) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx,
+		"DELETE FROM songs WHERE id = ? AND pinned = 0 AND id NOT IN (SELECT song_id FROM queue)", songID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetSongPinned pins or unpins a song. Pinned songs are never removed from
+// the cache. It reports whether the song changed, which it doesn't if it was
+// already pinned or unpinned.
+func (dm *DatabaseManager) SetSongPinned(songID int64, pinned bool) (bool, error) {
+	return dm.SetSongPinnedCtx(context.Background(), songID, pinned)
+}
+
+func (dm *DatabaseManager) SetSongPinnedCtx(ctx context.Context, songID int64, pinned bool) (bool, error) {
+	result, err := dm.writer.ExecContext(ctx,
+		"UPDATE songs SET pinned = ? WHERE id = ? AND pinned != ?", pinned, songID, pinned)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetPinnedSongs returns the pinned songs by title.
+func (dm *DatabaseManager) GetPinnedSongs() ([]state.Song, error) {
+	return dm.GetPinnedSongsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetPinnedSongsCtx(ctx context.Context) ([]state.Song, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT id, title, url, platform, file_path, COALESCE(duration, 0), COALESCE(file_size, 0), COALESCE(artist, '')
+		FROM songs WHERE pinned = 1 ORDER BY title COLLATE NOCASE, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []state.Song
+	for rows.Next() {
+		var song state.Song
+		if err := rows.Scan(&song.ID, &song.Title, &song.URL, &song.Platform, &song.FilePath, &song.Duration, &song.FileSize, &song.Artist); err != nil {
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	return songs, rows.Err()
+}
+
+// DeleteExpiredSearchSelections removes persisted search buttons older than
+// maxAge and returns how many were removed.
+func (dm *DatabaseManager) DeleteExpiredSearchSelections(maxAge time.Duration) (int64, error) {
+	return dm.DeleteExpiredSearchSelectionsCtx(context.Background(), maxAge)
+}
+
+func (dm *DatabaseManager) DeleteExpiredSearchSelectionsCtx(ctx context.Context, maxAge time.Duration) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx,
+		"DELETE FROM search_selections WHERE created_at < ?", time.Now().Add(-maxAge).Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// queuePositionPrefix keys each guild's playback position in queue_state.
+const queuePositionPrefix = "current_position:"
+
+// Each guild has its own queue, so every queue query is scoped to one guild.
+// Several shards may share the database, each saving the queues of its own
+// guilds.
+
+func (dm *DatabaseManager) AddToQueue(guildID string, songID int64) error {
+	return dm.AddToQueueCtx(context.Background(), guildID, songID)
+}
+
+func (dm *DatabaseManager) AddToQueueCtx(ctx context.Context, guildID string, songID int64) error {
+	_, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO queue (guild_id, song_id, position)
+		SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM queue WHERE guild_id = ?
+	`, guildID, songID, guildID)
+	return err
+}
+
+func (dm *DatabaseManager) GetQueue(guildID string) ([]state.QueueItem, error) {
+	return dm.GetQueueCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetQueueCtx(ctx context.Context, guildID string) ([]state.QueueItem, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT q.id, q.song_id, q.position, q.requested_by, q.requested_at, q.start_offset, q.end_offset, s.title, s.url, s.platform, s.file_path, s.duration, s.file_size, s.thumbnail_url, s.artist, s.is_stream, s.upload_date, s.view_count
+		FROM queue q
+		JOIN songs s ON q.song_id = s.id
+		WHERE q.guild_id = ?
+		ORDER BY q.position
+	`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queue []state.QueueItem
+	for rows.Next() {
+		var item state.QueueItem
+		var song state.Song
+		var isStreamInt int
+		var uploadDate sql.NullString
+		var viewCount sql.NullInt64
+		var requestedAt int64
+
+		err := rows.Scan(&item.ID, &item.SongID, &item.Position, &item.RequestedBy, &requestedAt, &song.StartOffset, &song.EndOffset,
+			&song.Title, &song.URL, &song.Platform, &song.FilePath, &song.Duration, &song.FileSize, &song.ThumbnailURL, &song.Artist, &isStreamInt,
+			&uploadDate, &viewCount)
+		if err != nil {
+			continue
+		}
+
+		song.ID = item.SongID
+		song.IsStream = isStreamInt == 1
+		song.UploadDate, song.ViewCount = uploadDate.String, viewCount.Int64
+		song.RequesterID = item.RequestedBy
+		if requestedAt > 0 {
+			item.RequestedAt = time.Unix(requestedAt, 0)
+			song.RequestedAt = item.RequestedAt
+		}
+		item.Song = &song
+		queue = append(queue, item)
+	}
+
+	return queue, rows.Err()
+}
+
+// QueueEntry is one persisted queue row. RequestedAt is zero when unknown.
+type QueueEntry struct {
+	SongID      int64
+	RequestedBy string
+	RequestedAt time.Time
+	StartOffset int
+	EndOffset   int
+}
+
+func (dm *DatabaseManager) SaveQueue(guildID string, entries []QueueEntry, position int) error {
+	return dm.SaveQueueCtx(context.Background(), guildID, entries, position)
+}
+
+// SaveQueueCtx replaces guildID's persisted queue with the given entries and
+// playback position in a single transaction. Other guilds' queues are left
+// alone.
+func (dm *DatabaseManager) SaveQueueCtx(ctx context.Context, guildID string, entries []QueueEntry, position int) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM queue WHERE guild_id = ?", guildID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO queue (guild_id, song_id, position, requested_by, requested_at, start_offset, end_offset) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, entry := range entries {
+		var requestedAt int64
+		if !entry.RequestedAt.IsZero() {
+			requestedAt = entry.RequestedAt.Unix()
+		}
+		if _, err := stmt.ExecContext(ctx, guildID, entry.SongID, i+1, entry.RequestedBy, requestedAt, entry.StartOffset, entry.EndOffset); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO queue_state (key, value) VALUES (?, ?)", queuePositionPrefix+guildID, position); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCurrentQueuePosition returns the index of the current song in guildID's
+// queue, 0 if it has none saved.
+func (dm *DatabaseManager) GetCurrentQueuePosition(guildID string) (int, error) {
+	return dm.GetCurrentQueuePositionCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetCurrentQueuePositionCtx(ctx context.Context, guildID string) (int, error) {
+	var position int
+	err := dm.reader.QueryRowContext(ctx, "SELECT value FROM queue_state WHERE key = ?", queuePositionPrefix+guildID).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return position, err
+}
+
+func (dm *DatabaseManager) SetCurrentQueuePosition(guildID string, position int) error {
+	return dm.SetCurrentQueuePositionCtx(context.Background(), guildID, position)
+}
+
+func (dm *DatabaseManager) SetCurrentQueuePositionCtx(ctx context.Context, guildID string, position int) error {
+	_, err := dm.writer.ExecContext(ctx, "INSERT OR REPLACE INTO queue_state (key, value) VALUES (?, ?)", queuePositionPrefix+guildID, position)
+	return err
+}
+
+func (dm *DatabaseManager) ClearQueue(guildID string) error {
+	return dm.ClearQueueCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) ClearQueueCtx(ctx context.Context, guildID string) error {
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM queue WHERE guild_id = ?", guildID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM queue_state WHERE key = ?", queuePositionPrefix+guildID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (dm *DatabaseManager) RemoveFromQueue(queueID int64) error {
+	return dm.RemoveFromQueueCtx(context.Background(), queueID)
+}
+
+func (dm *DatabaseManager) RemoveFromQueueCtx(ctx context.Context, queueID int64) error {
+	_, err := dm.writer.ExecContext(ctx, "DELETE FROM queue WHERE id = ?", queueID)
+	return err
+}
+
+func (dm *DatabaseManager) Close() error {
+	readerErr := dm.reader.Close()
+	if err := dm.writer.Close(); err != nil {
+		return err
+	}
+	return readerErr
+}
+
+// nullString stores "" as NULL, for columns where empty means unknown.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullInt64 stores 0 as NULL, for columns where zero means unknown.
+func nullInt64(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
 }
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"musicbot/internal/state"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDatabase opens an in-memory database that is closed with the test.
+func newTestDatabase(t *testing.T) *DatabaseManager {
+	t.Helper()
+	dm, err := NewMemoryDatabaseManager(strings.ReplaceAll(t.Name(), "/", "_"))
+	if err != nil {
+		t.Fatalf("NewMemoryDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	return dm
+}
+
+func addTestSong(t *testing.T, dm *DatabaseManager, url string) int64 {
+	t.Helper()
+	id, err := dm.AddSong(&state.Song{Title: url, URL: url, Platform: "test", FilePath: url + ".mp3"})
+	if err != nil {
+		t.Fatalf("AddSong(%s): %v", url, err)
+	}
+	return id
+}
+
+func queueSongIDs(t *testing.T, dm *DatabaseManager, guildID string) []int64 {
+	t.Helper()
+	items, err := dm.GetQueue(guildID)
+	if err != nil {
+		t.Fatalf("GetQueue(%s): %v", guildID, err)
+	}
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.SongID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQueuesAreKeptPerGuild(t *testing.T) {
+	dm := newTestDatabase(t)
+	a := addTestSong(t, dm, "https://example.com/a")
+	b := addTestSong(t, dm, "https://example.com/b")
+	c := addTestSong(t, dm, "https://example.com/c")
+
+	// Two shards sharing the database, each saving its own guild's queue.
+	if err := dm.SaveQueue("guild1", []QueueEntry{{SongID: a}, {SongID: b}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.SaveQueue("guild2", []QueueEntry{{SongID: c}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.SaveQueue("guild1", []QueueEntry{{SongID: b}, {SongID: a}, {SongID: c}}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := queueSongIDs(t, dm, "guild1"); !equalIDs(got, []int64{b, a, c}) {
+		t.Errorf("guild1 queue = %v, want %v", got, []int64{b, a, c})
+	}
+	if got := queueSongIDs(t, dm, "guild2"); !equalIDs(got, []int64{c}) {
+		t.Errorf("guild2 queue = %v, want %v", got, []int64{c})
+	}
+	if position, _ := dm.GetCurrentQueuePosition("guild1"); position != 2 {
+		t.Errorf("guild1 position = %d, want 2", position)
+	}
+	if position, _ := dm.GetCurrentQueuePosition("guild2"); position != 0 {
+		t.Errorf("guild2 position = %d, want 0", position)
+	}
+
+	if err := dm.ClearQueue("guild2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueSongIDs(t, dm, "guild2"); len(got) != 0 {
+		t.Errorf("guild2 queue after ClearQueue = %v, want empty", got)
+	}
+	if got := queueSongIDs(t, dm, "guild1"); !equalIDs(got, []int64{b, a, c}) {
+		t.Errorf("ClearQueue(guild2) changed guild1's queue to %v", got)
+	}
+
+	if err := dm.PurgeGuild("guild1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueSongIDs(t, dm, "guild1"); len(got) != 0 {
+		t.Errorf("guild1 queue after PurgeGuild = %v, want empty", got)
+	}
+	if position, _ := dm.GetCurrentQueuePosition("guild1"); position != 0 {
+		t.Errorf("guild1 position after PurgeGuild = %d, want 0", position)
+	}
+}
+
+func TestMigrateQueueGuildMovesTheOldQueue(t *testing.T) {
+	dm := newTestDatabase(t)
+	a := addTestSong(t, dm, "https://example.com/a")
+	b := addTestSong(t, dm, "https://example.com/b")
+
+	// A queue saved before queues were kept per guild.
+	for _, query := range []string{
+		"INSERT INTO queue (song_id, position) VALUES (1, 1), (2, 2)",
+		"INSERT INTO queue_state (key, value) VALUES ('current_position', 1)",
+		"INSERT INTO config (key, value) VALUES ('music_guild', 'guild1')",
+	} {
+		if _, err := dm.writer.Exec(query); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+
+	if err := dm.migrateQueueGuild(); err != nil {
+		t.Fatalf("migrateQueueGuild: %v", err)
+	}
+
+	if got := queueSongIDs(t, dm, "guild1"); !equalIDs(got, []int64{a, b}) {
+		t.Errorf("guild1 queue = %v, want %v", got, []int64{a, b})
+	}
+	if position, _ := dm.GetCurrentQueuePosition("guild1"); position != 1 {
+		t.Errorf("guild1 position = %d, want 1", position)
+	}
+
+	var keys int
+	dm.reader.QueryRow("SELECT COUNT(*) FROM config WHERE key = 'music_guild'").Scan(&keys)
+	if keys != 0 {
+		t.Error("music_guild was not removed")
+	}
+}
+
+// TestConcurrentQueueSavesAndReads saves queues while long reads run, as the
+// persister does during playback while stats are looked up, on a database
+// file like the bot's own. None of it may fail with "database is locked".
+func TestConcurrentQueueSavesAndReads(t *testing.T) {
+	dm, err := NewDatabaseManager(filepath.Join(t.TempDir(), "musicbot.db"))
+	if err != nil {
+		t.Fatalf("NewDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+
+	entries := make([]QueueEntry, 200)
+	for i := range entries {
+		entries[i] = QueueEntry{SongID: addTestSong(t, dm, fmt.Sprintf("https://example.com/%d", i))}
+	}
+
+	const workers, rounds = 4, 50
+	errs := make(chan error, 2*workers*rounds)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		guildID := fmt.Sprintf("guild%d", w)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				if err := dm.SaveQueue(guildID, entries[r:], r%10); err != nil {
+					errs <- fmt.Errorf("SaveQueue(%s): %w", guildID, err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				if _, err := dm.ListCachedSongs(); err != nil {
+					errs <- fmt.Errorf("ListCachedSongs: %w", err)
+				}
+				if _, err := dm.GetQueue(guildID); err != nil {
+					errs <- fmt.Errorf("GetQueue(%s): %w", guildID, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	for w := 0; w < workers; w++ {
+		guildID := fmt.Sprintf("guild%d", w)
+		if got := len(queueSongIDs(t, dm, guildID)); got != len(entries)-(rounds-1) {
+			t.Errorf("%s queue holds %d songs, want the last save's %d", guildID, got, len(entries)-(rounds-1))
+		}
+	}
+}
+
+func TestSearchSelectionsExpire(t *testing.T) {
+	dm := newTestDatabase(t)
+	selection := SearchSelection{Hash: "0123456789abcdef", OwnerID: "owner", URL: "https://example.com/a", Title: "A"}
+	if err := dm.SaveSearchSelections([]SearchSelection{selection}); err != nil {
+		t.Fatalf("SaveSearchSelections: %v", err)
+	}
+
+	got, err := dm.GetSearchSelection(selection.Hash, time.Hour)
+	if err != nil || got.URL != selection.URL || got.OwnerID != selection.OwnerID {
+		t.Fatalf("GetSearchSelection = %+v, %v, want %+v", got, err, selection)
+	}
+
+	if removed, err := dm.DeleteExpiredSearchSelections(time.Hour); err != nil || removed != 0 {
+		t.Errorf("DeleteExpiredSearchSelections(1h) removed %d, %v, want nothing", removed, err)
+	}
+	// With a negative age every row counts as expired.
+	if removed, err := dm.DeleteExpiredSearchSelections(-time.Hour); err != nil || removed != 1 {
+		t.Errorf("DeleteExpiredSearchSelections(-1h) removed %d, %v, want 1", removed, err)
+	}
+	if _, err := dm.GetSearchSelection(selection.Hash, time.Hour); err != sql.ErrNoRows {
+		t.Errorf("GetSearchSelection after cleanup: %v, want sql.ErrNoRows", err)
+	}
+}
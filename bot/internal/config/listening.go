@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// ListeningSession is a stretch of listening recorded with /session. Only
+// metadata is kept: the tracks, who was in the voice channel and the
+// reactions to the track messages. EndedAt is zero while it runs.
+type ListeningSession struct {
+	ID             int64
+	GuildID        string
+	VoiceChannelID string
+	TextChannelID  string
+	StartedBy      string
+	StartedAt      time.Time
+	EndedAt        time.Time
+	PeakListeners  int
+}
+
+// ListeningTrack is a track that played during a listening session.
+// MessageID is the message announcing it, whose reactions are counted when
+// the session ends.
+type ListeningTrack struct {
+	ID            int64
+	SessionID     int64
+	Title         string
+	Artist        string
+	URL           string
+	StartedAt     time.Time
+	MessageID     string
+	PeakListeners int
+	Reactions     int
+}
+
+// ListeningListener is someone who was in the voice channel during a
+// listening session, with how many of the samples they were present for.
+type ListeningListener struct {
+	UserID  string
+	Samples int
+}
+
+// AddListeningSession stores a session that just started and returns it
+// with its ID.
+func (dm *DatabaseManager) AddListeningSession(session ListeningSession) (ListeningSession, error) {
+	return dm.AddListeningSessionCtx(context.Background(), session)
+}
+
+func (dm *DatabaseManager) AddListeningSessionCtx(ctx context.Context, session ListeningSession) (ListeningSession, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO listening_sessions (guild_id, voice_channel_id, text_channel_id, started_by, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, session.GuildID, session.VoiceChannelID, session.TextChannelID, session.StartedBy, session.StartedAt.Unix())
+	if err != nil {
+		return session, err
+	}
+	session.ID, err = result.LastInsertId()
+	return session, err
+}
+
+// EndListeningSession records when session id ended and its peak number of
+// listeners.
+func (dm *DatabaseManager) EndListeningSession(id int64, endedAt time.Time, peakListeners int) error {
+	return dm.EndListeningSessionCtx(context.Background(), id, endedAt, peakListeners)
+}
+
+func (dm *DatabaseManager) EndListeningSessionCtx(ctx context.Context, id int64, endedAt time.Time, peakListeners int) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"UPDATE listening_sessions SET ended_at = ?, peak_listeners = ? WHERE id = ?",
+		endedAt.Unix(), peakListeners, id)
+	return err
+}
+
+// EndUnfinishedListeningSessions ends the sessions the bot was recording
+// when it last stopped without shutting down cleanly, at the time of their
+// last track. It returns how many there were.
+func (dm *DatabaseManager) EndUnfinishedListeningSessions() (int64, error) {
+	return dm.EndUnfinishedListeningSessionsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) EndUnfinishedListeningSessionsCtx(ctx context.Context) (int64, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		UPDATE listening_sessions SET ended_at = COALESCE(
+			(SELECT MAX(started_at) FROM listening_session_tracks WHERE session_id = listening_sessions.id),
+			started_at)
+		WHERE ended_at = 0
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetLastListeningSession returns the latest session of guildID that has
+// ended, or sql.ErrNoRows.
+func (dm *DatabaseManager) GetLastListeningSession(guildID string) (*ListeningSession, error) {
+	return dm.GetLastListeningSessionCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetLastListeningSessionCtx(ctx context.Context, guildID string) (*ListeningSession, error) {
+	row := dm.reader.QueryRowContext(ctx, `
+		SELECT id, guild_id, voice_channel_id, text_channel_id, started_by, started_at, ended_at, peak_listeners
+		FROM listening_sessions WHERE guild_id = ? AND ended_at > 0
+		ORDER BY started_at DESC, id DESC LIMIT 1
+	`, guildID)
+
+	var session ListeningSession
+	var startedAt, endedAt int64
+	err := row.Scan(&session.ID, &session.GuildID, &session.VoiceChannelID, &session.TextChannelID,
+		&session.StartedBy, &startedAt, &endedAt, &session.PeakListeners)
+	if err != nil {
+		return nil, err
+	}
+	session.StartedAt = time.Unix(startedAt, 0)
+	session.EndedAt = time.Unix(endedAt, 0)
+	return &session, nil
+}
+
+// AddListeningTrack stores a track that started during a session and
+// returns it with its ID.
+func (dm *DatabaseManager) AddListeningTrack(track ListeningTrack) (ListeningTrack, error) {
+	return dm.AddListeningTrackCtx(context.Background(), track)
+}
+
+func (dm *DatabaseManager) AddListeningTrackCtx(ctx context.Context, track ListeningTrack) (ListeningTrack, error) {
+	result, err := dm.writer.ExecContext(ctx, `
+		INSERT INTO listening_session_tracks (session_id, title, artist, url, started_at, message_id, peak_listeners)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, track.SessionID, track.Title, track.Artist, track.URL, track.StartedAt.Unix(), track.MessageID, track.PeakListeners)
+	if err != nil {
+		return track, err
+	}
+	track.ID, err = result.LastInsertId()
+	return track, err
+}
+
+// UpdateListeningTrack stores the peak listeners and reaction count of
+// track id.
+func (dm *DatabaseManager) UpdateListeningTrack(id int64, peakListeners, reactions int) error {
+	return dm.UpdateListeningTrackCtx(context.Background(), id, peakListeners, reactions)
+}
+
+func (dm *DatabaseManager) UpdateListeningTrackCtx(ctx context.Context, id int64, peakListeners, reactions int) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"UPDATE listening_session_tracks SET peak_listeners = ?, reactions = ? WHERE id = ?",
+		peakListeners, reactions, id)
+	return err
+}
+
+// GetListeningTracks returns the tracks of session id in the order they
+// played.
+func (dm *DatabaseManager) GetListeningTracks(sessionID int64) ([]ListeningTrack, error) {
+	return dm.GetListeningTracksCtx(context.Background(), sessionID)
+}
+
+func (dm *DatabaseManager) GetListeningTracksCtx(ctx context.Context, sessionID int64) ([]ListeningTrack, error) {
+	rows, err := dm.reader.QueryContext(ctx, `
+		SELECT id, session_id, title, artist, url, started_at, message_id, peak_listeners, reactions
+		FROM listening_session_tracks WHERE session_id = ? ORDER BY started_at, id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tracks []ListeningTrack
+	for rows.Next() {
+		var track ListeningTrack
+		var startedAt int64
+		err := rows.Scan(&track.ID, &track.SessionID, &track.Title, &track.Artist, &track.URL,
+			&startedAt, &track.MessageID, &track.PeakListeners, &track.Reactions)
+		if err != nil {
+			return nil, err
+		}
+		track.StartedAt = time.Unix(startedAt, 0)
+		tracks = append(tracks, track)
+	}
+	return tracks, rows.Err()
+}
+
+// AddListeningSample counts one more sample of session id for each of
+// userIDs, the listeners in the voice channel when it was taken.
+func (dm *DatabaseManager) AddListeningSample(sessionID int64, userIDs []string) error {
+	return dm.AddListeningSampleCtx(context.Background(), sessionID, userIDs)
+}
+
+func (dm *DatabaseManager) AddListeningSampleCtx(ctx context.Context, sessionID int64, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, userID := range userIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO listening_session_listeners (session_id, user_id, samples) VALUES (?, ?, 1)
+			ON CONFLICT(session_id, user_id) DO UPDATE SET samples = samples + 1
+		`, sessionID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetListeningListeners returns who was present during session id, the
+// most present first.
+func (dm *DatabaseManager) GetListeningListeners(sessionID int64) ([]ListeningListener, error) {
+	return dm.GetListeningListenersCtx(context.Background(), sessionID)
+}
+
+func (dm *DatabaseManager) GetListeningListenersCtx(ctx context.Context, sessionID int64) ([]ListeningListener, error) {
+	rows, err := dm.reader.QueryContext(ctx,
+		"SELECT user_id, samples FROM listening_session_listeners WHERE session_id = ? ORDER BY samples DESC, user_id",
+		sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var listeners []ListeningListener
+	for rows.Next() {
+		var listener ListeningListener
+		if err := rows.Scan(&listener.UserID, &listener.Samples); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, rows.Err()
+}
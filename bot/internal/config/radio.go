@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RadioSession is where the radio of a guild was last playing. UpdatedAt is
+// when it started there, or when the bot shut down while it played.
+type RadioSession struct {
+	ChannelID string
+	UpdatedAt time.Time
+}
+
+// GetRadioSession returns where the radio of guildID was playing, or nil if
+// it wasn't.
+func (dm *DatabaseManager) GetRadioSession(guildID string) (*RadioSession, error) {
+	return dm.GetRadioSessionCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) GetRadioSessionCtx(ctx context.Context, guildID string) (*RadioSession, error) {
+	var session RadioSession
+	var updatedAt int64
+	err := dm.reader.QueryRowContext(ctx,
+		"SELECT channel_id, updated_at FROM radio_sessions WHERE guild_id = ?", guildID,
+	).Scan(&session.ChannelID, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	session.UpdatedAt = time.Unix(updatedAt, 0)
+	return &session, nil
+}
+
+func (dm *DatabaseManager) SaveRadioSession(guildID, channelID string, updatedAt time.Time) error {
+	return dm.SaveRadioSessionCtx(context.Background(), guildID, channelID, updatedAt)
+}
+
+func (dm *DatabaseManager) SaveRadioSessionCtx(ctx context.Context, guildID, channelID string, updatedAt time.Time) error {
+	_, err := dm.writer.ExecContext(ctx,
+		"INSERT OR REPLACE INTO radio_sessions (guild_id, channel_id, updated_at) VALUES (?, ?, ?)",
+		guildID, channelID, updatedAt.Unix(),
+	)
+	return err
+}
+
+func (dm *DatabaseManager) ClearRadioSession(guildID string) error {
+	return dm.ClearRadioSessionCtx(context.Background(), guildID)
+}
+
+func (dm *DatabaseManager) ClearRadioSessionCtx(ctx context.Context, guildID string) error {
+	_, err := dm.writer.ExecContext(ctx, "DELETE FROM radio_sessions WHERE guild_id = ?", guildID)
+	return err
+}
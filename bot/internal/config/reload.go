@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"musicbot/internal/state"
+	"strings"
+)
+
+// Change is one setting that differs between the running bot and the
+// configuration on disk.
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, displayValue(c.Old), displayValue(c.New))
+}
+
+// ReloadResult lists the changes a reload applied and the ones that need a
+// restart to take effect.
+type ReloadResult struct {
+	Applied  []Change
+	Rejected []Change
+}
+
+func (r *ReloadResult) Apply(key, oldValue, newValue string) {
+	r.Applied = append(r.Applied, Change{Key: key, Old: oldValue, New: newValue})
+}
+
+func (r *ReloadResult) Reject(key, oldValue, newValue string) {
+	r.Rejected = append(r.Rejected, Change{Key: key, Old: oldValue, New: newValue})
+}
+
+func (r *ReloadResult) Empty() bool {
+	return len(r.Applied) == 0 && len(r.Rejected) == 0
+}
+
+func (r *ReloadResult) String() string {
+	if r.Empty() {
+		return "no changes"
+	}
+
+	var parts []string
+	for _, change := range r.Applied {
+		parts = append(parts, "applied "+change.String())
+	}
+	for _, change := range r.Rejected {
+		parts = append(parts, "rejected "+change.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FormatStreams renders a stream list for comparing and displaying it.
+func FormatStreams(streams []state.StreamOption) string {
+	names := make([]string, len(streams))
+	for i, stream := range streams {
+		names[i] = stream.Name + "=" + stream.URL
+	}
+	return strings.Join(names, ", ")
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return "(empty)"
+	}
+	return value
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingType is the kind of value a setting holds.
+type SettingType string
+
+const (
+	SettingNumber   SettingType = "number"
+	SettingInteger  SettingType = "integer"
+	SettingDuration SettingType = "duration"
+	SettingURL      SettingType = "url"
+)
+
+// ErrUnknownSetting is returned for a key that isn't in the registry.
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Setting is a value kept in the config table that can be changed while the
+// bot runs. Settings without a default fall back to config.json.
+type Setting struct {
+	Key         string
+	Type        SettingType
+	Description string
+	Default     string
+
+	// Global settings are shared by every guild, so only the bot's owners
+	// may change them.
+	Global bool
+
+	// parse checks a value and returns it in the form it is stored in.
+	parse func(string) (string, error)
+}
+
+var settings = []Setting{
+	{
+		Key:         "volume",
+		Type:        SettingNumber,
+		Description: "Playback volume, from 0 to 1",
+		Default:     "0.05",
+		Global:      true,
+		parse:       parseVolume,
+	},
+	{
+		Key:         "stream",
+		Type:        SettingURL,
+		Description: "Radio stream played while idle",
+		Default:     "https://listen.moe/stream",
+		Global:      true,
+		parse:       parseStreamURL,
+	},
+	{
+		Key:         "idle_delay",
+		Type:        SettingDuration,
+		Description: "How long after the last song the radio takes over",
+		Global:      true,
+		parse:       parseIdleDelay,
+	},
+	{
+		Key:         "max_queue_length",
+		Type:        SettingInteger,
+		Description: "Most upcoming songs the queue holds, unless a server set its own with /setlimit",
+		Default:     strconv.Itoa(DefaultQueueLimits().MaxQueueLength),
+		Global:      true,
+		parse:       parsePositiveInt,
+	},
+	{
+		Key:         "max_user_queued",
+		Type:        SettingInteger,
+		Description: "Most upcoming songs a single user may have queued, unless a server set its own with /setlimit",
+		Default:     strconv.Itoa(DefaultQueueLimits().MaxUserQueued),
+		Global:      true,
+		parse:       parsePositiveInt,
+	},
+}
+
+// Settings returns the registry in a stable order.
+func Settings() []Setting {
+	return append([]Setting(nil), settings...)
+}
+
+// SettingKeys returns the keys of the registry.
+func SettingKeys() []string {
+	keys := make([]string, len(settings))
+	for i, setting := range settings {
+		keys[i] = setting.Key
+	}
+	return keys
+}
+
+// LookupSetting finds a setting by key.
+func LookupSetting(key string) (Setting, bool) {
+	for _, setting := range settings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Parse checks value against the setting's type and returns it in the form
+// it is stored in.
+func (s Setting) Parse(value string) (string, error) {
+	return s.parse(strings.TrimSpace(value))
+}
+
+// GetSettings returns the stored value of every setting in the registry that
+// has one.
+func (dm *DatabaseManager) GetSettings() (map[string]string, error) {
+	return dm.GetSettingsCtx(context.Background())
+}
+
+func (dm *DatabaseManager) GetSettingsCtx(ctx context.Context) (map[string]string, error) {
+	rows, err := dm.reader.QueryContext(ctx, "SELECT key, value FROM config")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		if _, ok := LookupSetting(key); ok {
+			values[key] = value
+		}
+	}
+	return values, rows.Err()
+}
+
+// SaveSetting validates value and stores it, returning the stored form.
+func (dm *DatabaseManager) SaveSetting(key, value string) (string, error) {
+	return dm.SaveSettingCtx(context.Background(), key, value)
+}
+
+func (dm *DatabaseManager) SaveSettingCtx(ctx context.Context, key, value string) (string, error) {
+	setting, ok := LookupSetting(key)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+
+	value, err := setting.Parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	const upsert = "INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value"
+	if _, err := dm.writer.ExecContext(ctx, upsert, key, value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func parseVolume(value string) (string, error) {
+	volume, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a number", value)
+	}
+	if volume < 0 || volume > 1 {
+		return "", fmt.Errorf("volume must be between 0 and 1")
+	}
+	return strconv.FormatFloat(volume, 'f', -1, 32), nil
+}
+
+func parseStreamURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https URL", value)
+	}
+	return u.String(), nil
+}
+
+// parseIdleDelay takes a duration such as 90s or 2m, or a bare number of
+// seconds.
+func parseIdleDelay(value string) (string, error) {
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return "", fmt.Errorf("%q is not a duration such as 90s or 2m", value)
+		}
+		delay = time.Duration(seconds) * time.Second
+	}
+	if delay <= 0 {
+		return "", fmt.Errorf("idle_delay must be positive")
+	}
+	return delay.String(), nil
+}
+
+func parsePositiveInt(value string) (string, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a whole number", value)
+	}
+	if n <= 0 {
+		return "", fmt.Errorf("must be at least 1")
+	}
+	return strconv.Itoa(n), nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ShardCount is shard_count in the config file: a number, or "auto" for as
+// many shards as Discord recommends for the bot.
+type ShardCount struct {
+	Count int
+	Auto  bool
+}
+
+func (s *ShardCount) UnmarshalJSON(data []byte) error {
+	var auto string
+	if err := json.Unmarshal(data, &auto); err == nil {
+		if auto != "auto" {
+			return fmt.Errorf("shard_count must be a number or \"auto\", got %q", auto)
+		}
+		*s = ShardCount{Auto: true}
+		return nil
+	}
+
+	var count int
+	if err := json.Unmarshal(data, &count); err != nil {
+		return fmt.Errorf("shard_count must be a number or \"auto\": %w", err)
+	}
+	*s = ShardCount{Count: count}
+	return nil
+}
+
+func (s ShardCount) String() string {
+	if s.Auto {
+		return "auto"
+	}
+	return strconv.Itoa(s.Count)
+}
+
+// ShardFor returns the shard that serves guildID out of count, the way
+// Discord assigns them.
+func ShardFor(guildID string, count int) int {
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil || count <= 1 {
+		return 0
+	}
+	return int((id >> 22) % uint64(count))
+}
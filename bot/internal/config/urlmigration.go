@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/urlnorm"
+)
+
+// URLMigration reports what MigrateSongURLs changed.
+type URLMigration struct {
+	Rewritten int
+	Merged    int
+}
+
+type songURLRow struct {
+	id         int64
+	url        string
+	playCount  int64
+	lastPlayed int64
+}
+
+func (dm *DatabaseManager) MigrateSongURLs() (URLMigration, error) {
+	return dm.MigrateSongURLsCtx(context.Background())
+}
+
+// MigrateSongURLsCtx rewrites every song URL to its normalized form. Songs
+// that turn out to be the same track are merged into the oldest row, which
+// keeps the summed play count and the latest play time; queue and playlist
+// entries are moved over to it. Files of merged rows are left for the janitor.
+func (dm *DatabaseManager) MigrateSongURLsCtx(ctx context.Context) (URLMigration, error) {
+	var result URLMigration
+
+	tx, err := dm.writer.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, url, COALESCE(play_count, 0), COALESCE(last_played, 0) FROM songs ORDER BY id")
+	if err != nil {
+		return result, err
+	}
+
+	groups := make(map[string][]songURLRow)
+	var order []string
+	for rows.Next() {
+		var row songURLRow
+		if err := rows.Scan(&row.id, &row.url, &row.playCount, &row.lastPlayed); err != nil {
+			rows.Close()
+			return result, err
+		}
+		normalized := urlnorm.Normalize(row.url)
+		if _, ok := groups[normalized]; !ok {
+			order = append(order, normalized)
+		}
+		groups[normalized] = append(groups[normalized], row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	var hasPlaylists int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'playlist_songs'").Scan(&hasPlaylists)
+	if err != nil {
+		return result, err
+	}
+
+	for _, normalized := range order {
+		group := groups[normalized]
+		keep := group[0]
+		if len(group) == 1 && keep.url == normalized {
+			continue
+		}
+
+		playCount, lastPlayed := keep.playCount, keep.lastPlayed
+		for _, duplicate := range group[1:] {
+			playCount += duplicate.playCount
+			lastPlayed = max(lastPlayed, duplicate.lastPlayed)
+
+			if _, err := tx.ExecContext(ctx, "UPDATE queue SET song_id = ? WHERE song_id = ?", keep.id, duplicate.id); err != nil {
+				return result, fmt.Errorf("failed to move queue entries of song %d: %w", duplicate.id, err)
+			}
+			if hasPlaylists > 0 {
+				if _, err := tx.ExecContext(ctx, "UPDATE OR IGNORE playlist_songs SET song_id = ? WHERE song_id = ?", keep.id, duplicate.id); err != nil {
+					return result, fmt.Errorf("failed to move playlist entries of song %d: %w", duplicate.id, err)
+				}
+				if _, err := tx.ExecContext(ctx, "DELETE FROM playlist_songs WHERE song_id = ?", duplicate.id); err != nil {
+					return result, err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM songs WHERE id = ?", duplicate.id); err != nil {
+				return result, fmt.Errorf("failed to delete song %d: %w", duplicate.id, err)
+			}
+			result.Merged++
+		}
+
+		var lastPlayedValue any
+		if lastPlayed > 0 {
+			lastPlayedValue = lastPlayed
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE songs SET url = ?, play_count = ?, last_played = ? WHERE id = ?",
+			normalized, playCount, lastPlayedValue, keep.id)
+		if err != nil {
+			return result, fmt.Errorf("failed to rewrite URL of song %d: %w", keep.id, err)
+		}
+		result.Rewritten++
+	}
+
+	return result, tx.Commit()
+}
@@ -0,0 +1,165 @@
+package deps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"musicbot/internal/logger"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// programTimeout bounds how long a program gets to answer -version.
+const programTimeout = 5 * time.Second
+
+// Names of the checked dependencies, as shown by /status and /readyz.
+const (
+	FFmpeg      = "ffmpeg"
+	FFprobe     = "ffprobe"
+	SocketDir   = "socket_dir"
+	DownloadDir = "download_dir"
+)
+
+// Names lists the checked dependencies in the order they are checked.
+var Names = []string{FFmpeg, FFprobe, SocketDir, DownloadDir}
+
+type Config struct {
+	// UDSPath is the downloader socket; its directory must exist.
+	UDSPath string
+	// DownloadDir is where the downloader writes audio files.
+	DownloadDir string
+}
+
+// Report is the outcome of one check. Results holds an error for every
+// dependency that failed and nil for the others.
+type Report struct {
+	CheckedAt time.Time
+	Results   map[string]error
+}
+
+// OK reports whether every dependency passed.
+func (r Report) OK() bool {
+	for _, err := range r.Results {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Checker verifies what the bot needs outside the Go binary: ffmpeg and
+// ffprobe for playback and uploads, the downloader socket's directory and a
+// writable download directory. Without them playback fails deep inside the
+// player with an exec error, so they are checked at startup and again on
+// demand with /status refresh.
+type Checker struct {
+	cfg  Config
+	last *Report
+	mu   sync.RWMutex
+}
+
+func New(cfg Config) *Checker {
+	return &Checker{cfg: cfg}
+}
+
+// Run checks every dependency, logs what is missing and keeps the report for
+// Last.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		CheckedAt: time.Now(),
+		Results: map[string]error{
+			FFmpeg:      checkProgram(ctx, FFmpeg),
+			FFprobe:     checkProgram(ctx, FFprobe),
+			SocketDir:   checkSocketDir(c.cfg.UDSPath),
+			DownloadDir: checkDownloadDir(c.cfg.DownloadDir),
+		},
+	}
+
+	for _, name := range Names {
+		if err := report.Results[name]; err != nil {
+			logger.Error.Printf("Dependency check: %v", err)
+		}
+	}
+	if report.OK() {
+		logger.Info.Println("Dependency check passed")
+	}
+
+	c.mu.Lock()
+	c.last = &report
+	c.mu.Unlock()
+
+	return report
+}
+
+// Last returns the report of the latest run, if there was one.
+func (c *Checker) Last() (Report, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.last == nil {
+		return Report{}, false
+	}
+	return *c.last, true
+}
+
+// Results returns the latest result for each dependency, for /readyz.
+// Before the first run every dependency is reported as unchecked.
+func (c *Checker) Results() map[string]error {
+	report, ok := c.Last()
+	if !ok {
+		results := make(map[string]error, len(Names))
+		for _, name := range Names {
+			results[name] = errors.New("not checked yet")
+		}
+		return results
+	}
+	return report.Results
+}
+
+func checkProgram(ctx context.Context, name string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found on PATH: install ffmpeg (it ships %s) or add its directory to PATH", name, name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, programTimeout)
+	defer cancel()
+
+	if err := exec.CommandContext(ctx, path, "-version").Run(); err != nil {
+		return fmt.Errorf("%s at %s does not run (%v): reinstall ffmpeg", name, path, err)
+	}
+	return nil
+}
+
+func checkSocketDir(udsPath string) error {
+	dir := filepath.Dir(udsPath)
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("socket directory %s does not exist: create it or change uds_path", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("socket directory %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory: change uds_path", dir)
+	}
+	return nil
+}
+
+// checkDownloadDir writes and removes a file, since permission bits don't
+// tell the whole story on ACLs and read-only mounts.
+func checkDownloadDir(dir string) error {
+	file, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("download directory %s is not writable (%v): fix its permissions or change download_dir", dir, err)
+	}
+	name := file.Name()
+	file.Close()
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("download directory %s: failed to remove test file: %v", dir, err)
+	}
+	return nil
+}
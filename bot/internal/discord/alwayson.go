@@ -0,0 +1,68 @@
+package discord
+
+import (
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+	"time"
+)
+
+const (
+	// alwaysOnRetryDelay is how long a guild in 24/7 mode waits between
+	// attempts to get back into its channel once reconnecting gave up.
+	alwaysOnRetryDelay = time.Minute
+	// alwaysOnReadyDelay gives discordgo time to restore voice connections
+	// by itself after a gateway reconnect before 24/7 guilds are checked.
+	alwaysOnReadyDelay = 5 * time.Second
+)
+
+// rejoinAlwaysOn keeps trying to put the bot back in its 24/7 channel with
+// the radio on, until it is in voice again, 24/7 mode is turned off or the
+// bot shuts down. The announce channel is told once if the first attempt
+// fails.
+func (e *EventHandler) rejoinAlwaysOn(guild *guilds.Guild) {
+	announced := false
+	for {
+		if e.stateManager.IsShuttingDown() || guild.State.GetAlwaysOnChannel() == "" {
+			return
+		}
+		if guild.Voice.GetVoiceConnection() != nil {
+			return
+		}
+
+		logger.Info.Printf("Rejoining 24/7 channel in guild %s", guild.ID)
+		if err := e.moveToIdle(guild); err == nil {
+			return
+		}
+
+		if !announced {
+			e.announce(guild, "alwayson.rejoin_failed", int(alwaysOnRetryDelay.Seconds()))
+			announced = true
+		}
+		time.Sleep(alwaysOnRetryDelay)
+	}
+}
+
+// restoreAlwaysOn rejoins the 24/7 channel of every guild in 24/7 mode that
+// lost its voice connection over a gateway reconnect.
+func (e *EventHandler) restoreAlwaysOn() {
+	time.Sleep(alwaysOnReadyDelay)
+
+	for _, guild := range e.guilds.All() {
+		if guild.State.GetAlwaysOnChannel() == "" || guild.Voice.GetVoiceConnection() != nil {
+			continue
+		}
+
+		logger.Info.Printf("Voice connection of guild %s was lost over a gateway reconnect", guild.ID)
+		go e.rejoinAlwaysOn(guild)
+	}
+}
+
+// dropAlwaysOn turns 24/7 mode off for a guild whose 24/7 channel is gone,
+// and tells the admins.
+func (e *EventHandler) dropAlwaysOn(guild *guilds.Guild) {
+	if err := e.dbManager.DeleteAlwaysOnChannel(guild.ID); err != nil {
+		logger.Error.Printf("Failed to turn off 24/7 mode of guild %s: %v", guild.ID, err)
+	}
+	guild.State.SetAlwaysOnChannel("")
+	e.announce(guild, "alwayson.deleted", e.adminMention(guild.ID))
+}
@@ -2,105 +2,167 @@ package discord
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"musicbot/internal/discordapi"
+	"sync"
 	"time"
 
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
 	"musicbot/internal/config"
+	"musicbot/internal/deps"
 	"musicbot/internal/discord/commands"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/janitor"
+	"musicbot/internal/listening"
 	"musicbot/internal/logger"
+	"musicbot/internal/lyrics"
+	"musicbot/internal/metrics"
 	"musicbot/internal/music"
 	"musicbot/internal/permissions"
+	"musicbot/internal/presence"
 	"musicbot/internal/radio"
+	"musicbot/internal/schedule"
 	"musicbot/internal/socket"
 	"musicbot/internal/state"
-	"musicbot/internal/voice"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type Client struct {
 	session           *discordgo.Session
+	api               discordapi.Session
 	stateManager      *state.Manager
-	voiceManager      *voice.Manager
-	radioManager      *radio.Manager
-	musicManager      *music.Manager
+	guilds            *guilds.Registry
+	streams           *radio.StreamManager
 	commandRouter     *commands.Router
+	requestChannel    *commands.RequestChannelCommand
 	eventHandler      *EventHandler
 	dbManager         *config.DatabaseManager
 	socketClient      *socket.Client
-	searchCommand     *commands.SearchCommand
 	permissionManager *permissions.Manager
+	janitor           *janitor.Janitor
+	deps              *deps.Checker
+	lyrics            *lyrics.Client
+	blacklist         *blacklist.List
+	audit             *audit.Log
+	scheduler         *schedule.Scheduler
+	presence          *presence.Rotator
+	listening         *listening.Manager
+	shardCount        config.ShardCount
+	configPath        string
+	startConfig       config.FileConfig
+	reloadMu          sync.Mutex
+
+	// available holds the guilds GUILD_CREATE has delivered on this shard.
+	available   map[string]bool
+	availableMu sync.Mutex
 }
 
-func NewClient(token string, stateManager *state.Manager, dbManager *config.DatabaseManager, socketClient *socket.Client, permConfig permissions.Config) (*Client, error) {
+func NewClient(token string, shardID int, shardCount config.ShardCount, stateManager *state.Manager, dbManager *config.DatabaseManager, socketClient *socket.Client, cacheJanitor *janitor.Janitor, permissionManager *permissions.Manager, lyricsClient *lyrics.Client, blacklistList *blacklist.List, scheduler *schedule.Scheduler, depChecker *deps.Checker) (*Client, error) {
 	session, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Discord session: %w", err)
 	}
 
+	// GUILD_CREATE carries the voice states of everyone already connected and
+	// VOICE_STATE_UPDATE keeps them current, so both intents are needed for the
+	// state cache to know who is in voice.
 	session.Identify.Intents = discordgo.IntentsGuildVoiceStates | discordgo.IntentsGuilds
+	// Request channels need the messages themselves, whose content is
+	// privileged.
+	if stateManager.GetConfig().RequestChannels {
+		session.Identify.Intents |= discordgo.IntentsGuildMessages | discordgo.IntentMessageContent
+	}
+	session.ShardID = shardID
+	session.ShardCount = shardCount.Count
+	session.State.TrackVoice = true
+
+	permissionManager.SetRoleLookup(func(guildID, roleID string) (string, bool) {
+		role, err := session.State.Role(guildID, roleID)
+		if err != nil {
+			return "", false
+		}
+		return role.Name, true
+	})
 
-	voiceManager := voice.NewManager(session, stateManager)
-	radioManager := radio.NewManager(stateManager, config.GetDefaultStreams())
-	musicManager := music.NewManager(stateManager, dbManager, radioManager, socketClient)
-	commandRouter := commands.NewRouter(session)
-	eventHandler := NewEventHandler(session, voiceManager, radioManager, musicManager, stateManager)
-	permissionManager := permissions.NewManager(permConfig)
+	api := discordapi.New(session)
+	streams := radio.NewStreamManager(stateManager.GetConfig().Streams)
+	guildRegistry := guilds.NewRegistry(api, stateManager, streams, dbManager, socketClient)
+	eventHandler := NewEventHandler(session, guildRegistry, stateManager, permissionManager, dbManager)
+	commandRouter := commands.NewRouter(api, permissionManager, blacklistList)
+
+	for _, guildID := range stateManager.GuildIDs() {
+		guildRegistry.Get(guildID)
+	}
+
+	presenceTemplates, err := dbManager.GetPresenceTemplates()
+	if err != nil {
+		logger.Error.Printf("Failed to load presence templates, using the defaults: %v", err)
+	}
 
 	client := &Client{
 		session:           session,
+		api:               api,
 		stateManager:      stateManager,
-		voiceManager:      voiceManager,
-		radioManager:      radioManager,
-		musicManager:      musicManager,
+		guilds:            guildRegistry,
+		streams:           streams,
 		commandRouter:     commandRouter,
 		eventHandler:      eventHandler,
 		dbManager:         dbManager,
 		socketClient:      socketClient,
 		permissionManager: permissionManager,
+		janitor:           cacheJanitor,
+		deps:              depChecker,
+		lyrics:            lyricsClient,
+		blacklist:         blacklistList,
+		audit:             audit.New(dbManager, session, stateManager),
+		scheduler:         scheduler,
+		presence:          presence.New(session, guildRegistry, presenceTemplates),
+		listening:         listening.New(session, dbManager),
+		shardCount:        shardCount,
+		available:         make(map[string]bool),
 	}
 
+	client.guilds.SetRadioNotice(client.radioChanged)
 	client.setupMusicManager()
 	client.registerCommands()
+
+	disabledCommands, err := dbManager.GetDisabledCommands()
+	if err != nil {
+		logger.Error.Printf("Failed to load disabled commands: %v", err)
+	}
+	commandRouter.LoadDisabledCommands(disabledCommands)
 	client.registerEventHandlers()
 
 	return client, nil
 }
 
 func (c *Client) setupMusicManager() {
-	c.musicManager.SetVoiceConnectionGetter(c.voiceManager.GetVoiceConnection)
+	c.guilds.SetMusicHook(func(musicManager *music.Manager) {
+		musicManager.SetShutdownNotice(c.announceShutdown)
+		musicManager.SetSkipNotice(c.announceSkip)
+		followTracks(musicManager, c.announceTrack)
+		followTracks(musicManager, countTrack)
+	})
 
 	if c.socketClient != nil {
-		c.socketClient.SetResetPendingHandler(c.musicManager.ResetPendingDownloads)
-		c.socketClient.SetPlaylistStartHandler(c.musicManager.OnPlaylistStart)
-
-		c.socketClient.SetDownloadHandler(func(song *state.Song) {
-			err := c.musicManager.OnDownloadComplete(song)
-			if err != nil {
-				logger.Error.Printf("Failed to handle download completion: %v", err)
-			}
-		})
-
-		c.socketClient.SetPlaylistEventHandler(func(playlistUrl string, song *state.Song) {
-			err := c.musicManager.OnPlaylistItemComplete(playlistUrl, song)
-			if err != nil {
-				logger.Error.Printf("Failed to handle playlist item: %v", err)
-			}
-		})
-
-		c.socketClient.SetPlaylistHandler(func(songs []state.Song) {
-			for _, song := range songs {
-				err := c.musicManager.OnDownloadComplete(&song)
-				if err != nil {
-					logger.Error.Printf("Failed to handle playlist song: %v", err)
-				}
-			}
-		})
+		c.socketClient.SetResetPendingHandler(c.guilds.ResetPendingDownloads)
+		c.socketClient.SetDownloadHandler(c.guilds.OnDownloadComplete)
+		c.socketClient.SetDownloadFailHandler(c.guilds.OnDownloadFailed)
 	}
 }
 
 func (c *Client) Connect() error {
-	logger.Info.Println("Connecting to Discord...")
+	if err := c.resolveShardCount(); err != nil {
+		return err
+	}
+	render.SetShard(c.session.ShardID, c.session.ShardCount)
+
+	logger.Info.Printf("Connecting to Discord as shard %d of %d...", c.session.ShardID+1, c.session.ShardCount)
 
 	err := c.session.Open()
 	if err != nil {
@@ -111,55 +173,190 @@ func (c *Client) Connect() error {
 	return nil
 }
 
+// IsSessionOpen reports whether the gateway session has received READY.
+func (c *Client) IsSessionOpen() bool {
+	return c.session.DataReady
+}
+
 func (c *Client) Disconnect() error {
 	logger.Info.Println("Disconnecting from Discord...")
 	return c.session.Close()
 }
 
-func (c *Client) UpdateCommands() error {
+// UpdateCommands registers the slash commands, only changing what differs
+// from the ones Discord has unless force is set. They are global, so with
+// several shards only the first does it.
+func (c *Client) UpdateCommands(force bool) error {
+	if c.session.ShardID != 0 {
+		logger.Info.Printf("Leaving slash commands to shard 1, this is shard %d", c.session.ShardID+1)
+		return nil
+	}
+
 	logger.Info.Println("Updating slash commands...")
-	return c.commandRouter.UpdateCommands()
+	_, err := c.commandRouter.SyncCommands(force)
+	return err
 }
 
 func (c *Client) StartIdleMode(guildID string) error {
-	logger.Info.Println("Starting idle mode...")
+	logger.Info.Printf("Starting idle mode in guild %s...", guildID)
+
+	guild := c.guilds.Get(guildID)
+	idleChannel := guild.State.GetIdleChannel()
+	if idleChannel == "" {
+		return fmt.Errorf("no idle channel configured for guild %s", guildID)
+	}
 
-	err := c.voiceManager.ReturnToIdle(guildID)
+	if _, err := c.session.Channel(idleChannel); isNotFound(err) {
+		logger.Error.Printf("Idle channel %s of guild %s no longer exists", idleChannel, guildID)
+		if c.eventHandler.replaceIdleChannel(guild) == "" {
+			return fmt.Errorf("idle channel %s of guild %s is gone and no other voice channel is joinable", idleChannel, guildID)
+		}
+	}
+
+	// In 24/7 mode the bot starts out in its 24/7 channel instead.
+	if alwaysOnChannel := guild.State.GetAlwaysOnChannel(); alwaysOnChannel != "" {
+		if _, err := c.session.Channel(alwaysOnChannel); isNotFound(err) {
+			logger.Error.Printf("24/7 channel %s of guild %s no longer exists", alwaysOnChannel, guildID)
+			c.eventHandler.dropAlwaysOn(guild)
+		}
+	}
+
+	err := guild.Voice.ReturnToIdle(guildID)
 	if err != nil {
 		return fmt.Errorf("failed to join idle channel: %w", err)
 	}
 
-	c.stateManager.SetBotState(state.StateIdle)
+	if guild.State.IsInIdleChannel() {
+		guild.State.SetBotState(state.StateIdle)
+	} else {
+		guild.State.SetBotState(state.StateRadio)
+	}
 
 	time.Sleep(500 * time.Millisecond)
 
-	vc := c.voiceManager.GetVoiceConnection()
+	vc := guild.Voice.GetVoiceConnection()
 	if vc != nil {
-		err = c.radioManager.Start(vc)
+		err = guild.Radio.Start(vc)
 		if err != nil {
 			logger.Error.Printf("Failed to start radio: %v", err)
 		}
 	}
 
-	logger.Info.Println("Idle mode started successfully")
+	logger.Info.Printf("Idle mode started in guild %s", guildID)
 	return nil
 }
 
+// followTracks hands the events of a guild's player to handle, one at a time
+// and in order, until the guild's music shuts down.
+func followTracks(musicManager *music.Manager, handle func(music.PlayerEvent)) {
+	events := make(chan music.PlayerEvent)
+	unsubscribe := musicManager.Subscribe(events)
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case event := <-events:
+				handle(event)
+			case <-musicManager.Done():
+				return
+			}
+		}
+	}()
+}
+
+// announceTrack shows a song that started in the presence and posts it to the
+// guild's listening session.
+func (c *Client) announceTrack(event music.PlayerEvent) {
+	if event.Type != music.TrackStarted {
+		return
+	}
+	c.presence.Refresh()
+	c.listening.TrackStarted(event.GuildID, event.Song)
+}
+
+// countTrack counts how songs finish.
+func countTrack(event music.PlayerEvent) {
+	switch event.Type {
+	case music.TrackEnded, music.TrackStopped, music.TrackFailed:
+		metrics.TrackFinished(event.Type.String())
+	}
+}
+
+// shutdownAnnounceTimeout bounds the shutdown notice, so a hanging Discord API
+// call can't use up the shutdown budget.
+const shutdownAnnounceTimeout = 5 * time.Second
+
+// announceShutdown tells the guild music is playing in that the bot is going
+// down and its queue is kept.
+func (c *Client) announceShutdown(ctx context.Context, guildID string, queued int) {
+	channelID := c.guilds.Get(guildID).State.GetAnnounceChannel()
+	if channelID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, shutdownAnnounceTimeout)
+	defer cancel()
+
+	_, err := c.session.ChannelMessageSend(channelID, i18n.T(guildID, "shutdown.announce", queued), discordgo.WithContext(ctx))
+	if err != nil {
+		logger.Error.Printf("Failed to announce shutdown in channel %s: %v", channelID, err)
+	}
+}
+
+// announceSkip tells the guild that a song was skipped because its file is
+// gone and could not be downloaded again, or because it kept breaking off.
+func (c *Client) announceSkip(guildID string, song *state.Song, reason error) {
+	channelID := c.guilds.Get(guildID).State.GetAnnounceChannel()
+	if channelID == "" {
+		return
+	}
+
+	var trackErr *music.TrackError
+	var content string
+	switch {
+	case errors.As(reason, &trackErr):
+		content = i18n.T(guildID, "music.skipped_playback_failed", song.Title, trackErr.Error())
+	case errors.Is(reason, music.ErrFileMissing):
+		content = i18n.T(guildID, "music.skipped_missing", song.Title)
+	default:
+		content = i18n.T(guildID, "music.skipped_redownload_failed", song.Title, reason.Error())
+	}
+	_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to announce skipped song in channel %s: %v", channelID, err)
+	}
+}
+
 func (c *Client) Shutdown(ctx context.Context) error {
 	logger.Info.Println("Shutting down Discord client...")
 
-	c.musicManager.Stop()
-	c.radioManager.Stop()
+	c.guilds.StopAllPlayback()
 
 	time.Sleep(500 * time.Millisecond)
 
-	if err := c.voiceManager.Shutdown(ctx); err != nil {
-		logger.Error.Printf("Error shutting down voice manager: %v", err)
+	for _, guild := range c.guilds.All() {
+		if err := guild.Voice.Shutdown(ctx); err != nil {
+			logger.Error.Printf("Error shutting down voice manager for guild %s: %v", guild.ID, err)
+		}
 	}
 
-	if err := c.session.Close(); err != nil {
-		logger.Error.Printf("Error closing Discord session: %v", err)
-		return err
+	// Closing the gateway connection can hang on a dead network.
+	closed := make(chan error, 1)
+	go func() {
+		closed <- c.session.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			logger.Error.Printf("Error closing Discord session: %v", err)
+			return err
+		}
+	case <-ctx.Done():
+		return fmt.Errorf("closing Discord session: %w", ctx.Err())
 	}
 
 	logger.Info.Println("Discord client shut down successfully")
@@ -170,119 +367,117 @@ func (c *Client) Name() string {
 	return "DiscordClient"
 }
 
-func (c *Client) GetVoiceManager() *voice.Manager {
-	return c.voiceManager
+func (c *Client) GetGuilds() *guilds.Registry {
+	return c.guilds
 }
 
-func (c *Client) GetRadioManager() *radio.Manager {
-	return c.radioManager
+func (c *Client) GetAuditLog() *audit.Log {
+	return c.audit
 }
 
-func (c *Client) GetMusicManager() *music.Manager {
-	return c.musicManager
+// ScheduleRunner returns what carries out /schedule jobs.
+func (c *Client) ScheduleRunner() schedule.Runner {
+	return &scheduleRunner{events: c.eventHandler}
 }
 
-func (c *Client) wrapCommand(cmd commands.Command, level permissions.Level) commands.Command {
-	if level == permissions.LevelUser {
-		return cmd
-	}
-	return commands.NewPermissionWrapper(cmd, level, c.permissionManager)
+// GetPresence returns what keeps the bot's status up to date.
+func (c *Client) GetPresence() *presence.Rotator {
+	return c.presence
+}
+
+// GetListening returns what records listening sessions.
+func (c *Client) GetListening() *listening.Manager {
+	return c.listening
 }
 
 func (c *Client) registerCommands() {
-	c.commandRouter.Register(commands.NewHelpCommand(c.permissionManager))
-	c.commandRouter.Register(commands.NewPingCommand(c.session, c.socketClient))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewJoinCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewLeaveCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewChangeStreamCommand(c.voiceManager, c.radioManager, c.dbManager),
-		permissions.LevelDJ,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewPlayCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewPlaylistCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelDJ,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewQueueCommand(c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewSkipCommand(c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewPauseCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewResumeCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewNowPlayingCommand(c.musicManager, c.radioManager, c.stateManager),
-		permissions.LevelUser,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewClearCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager),
-		permissions.LevelDJ,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewDelMsgCommand(c.session),
-		permissions.LevelAdmin,
-	))
-
-	c.commandRouter.Register(c.wrapCommand(
-		commands.NewVolumeCommand(c.stateManager, c.dbManager),
-		permissions.LevelDJ,
-	))
-
-	c.searchCommand = commands.NewSearchCommand(c.voiceManager, c.radioManager, c.musicManager, c.stateManager, c.socketClient)
-	c.commandRouter.Register(c.wrapCommand(c.searchCommand, permissions.LevelUser))
+	c.commandRouter.Register(commands.NewHelpCommand(c.commandRouter, c.permissionManager))
+	c.commandRouter.Register(commands.NewPingCommand(c.api, c.socketClient))
+	c.commandRouter.Register(commands.NewJoinCommand(c.guilds))
+	c.commandRouter.Register(commands.NewLeaveCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewFollowCommand(c.guilds))
+	c.commandRouter.Register(commands.NewSetIdleChannelCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewAlwaysOnCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewChangeStreamCommand(c.guilds, c.streams, c.dbManager))
+	play := commands.NewPlayCommand(c.guilds, c.permissionManager, c.blacklist, c.audit)
+	c.commandRouter.Register(play)
+	c.requestChannel = commands.NewRequestChannelCommand(c.commandRouter, play, c.guilds, c.stateManager, c.socketClient, c.dbManager, c.audit)
+	c.commandRouter.Register(c.requestChannel)
+	c.commandRouter.Register(commands.NewPlayFileCommand(c.guilds, c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewScheduleCommand(c.scheduler, c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewExportQueueCommand(c.guilds))
+	c.commandRouter.Register(commands.NewImportQueueCommand(c.guilds, c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewClipCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewPlaylistCommand(c.guilds, c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewQueueCommand(c.guilds))
+	c.commandRouter.Register(commands.NewETACommand(c.guilds))
+	c.commandRouter.Register(commands.NewSkipCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewSkipToCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewPreviousCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewHistoryCommand(c.guilds))
+	c.commandRouter.Register(commands.NewRestartCommand(c.guilds))
+	c.commandRouter.Register(commands.NewPauseCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewResumeCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewNowPlayingCommand(c.guilds, c.stateManager))
+	c.commandRouter.Register(commands.NewGrabCommand(c.guilds, c.dbManager))
+	c.commandRouter.Register(commands.NewGrabsCommand(c.guilds, c.dbManager, c.permissionManager, c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewPinCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewUnpinCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewPinsCommand(c.dbManager, c.janitor))
+	c.commandRouter.Register(commands.NewClearCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewRepairCommand(c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewLyricsCommand(c.guilds, c.lyrics))
+	c.commandRouter.Register(commands.NewTrimCommand(c.guilds, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewRemoveCommand(c.guilds, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewDelMsgCommand(c.api))
+	c.commandRouter.Register(commands.NewVolumeCommand(c.stateManager, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewFilterCommand(c.guilds, c.stateManager, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewQueueModeCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewLanguageCommand(c.dbManager))
+	c.commandRouter.Register(commands.NewStyleCommand(c.dbManager))
+	c.commandRouter.Register(commands.NewPresenceCommand(c.presence, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewSessionCommand(c.guilds, c.listening, c.audit))
+	c.commandRouter.Register(commands.NewSetLimitCommand(c.guilds, c.dbManager))
+	c.commandRouter.Register(commands.NewSetMaxDurationCommand(c.guilds, c.dbManager))
+	c.commandRouter.Register(commands.NewSetMaxSizeCommand(c.guilds, c.dbManager))
+	c.commandRouter.Register(commands.NewSetRetriesCommand(c.guilds, c.dbManager))
+	c.commandRouter.Register(commands.NewSetDJRoleCommand(c.permissionManager, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewSetAdminRoleCommand(c.permissionManager, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewDJOnlyCommand(c.permissionManager, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewBlacklistCommand(c.blacklist, c.guilds, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewDJBanCommand(c.blacklist, c.guilds, c.audit))
+	c.commandRouter.Register(commands.NewDJUnbanCommand(c.blacklist, c.audit))
+	c.commandRouter.Register(commands.NewAuditLogCommand(c.audit))
+	c.commandRouter.Register(commands.NewFailuresCommand(c.guilds, c.dbManager, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewRetryFailedCommand(c.guilds, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewReloadConfigCommand(c.ReloadConfig))
+	c.commandRouter.Register(commands.NewSyncCommandsCommand(c.commandRouter.SyncCommands))
+	c.commandRouter.Register(commands.NewCommandsCommand(c.commandRouter, c.dbManager, c.audit))
+	c.commandRouter.Register(commands.NewConfigCommand(c.guilds, c.stateManager, c.dbManager, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewDownloaderCommand(c.socketClient, c.guilds, c.permissionManager, c.audit))
+	c.commandRouter.Register(commands.NewShardInfoCommand())
+	c.commandRouter.Register(commands.NewStatusCommand(c.guilds, c.socketClient, c.dbManager, c.janitor, c.deps, c.permissionManager))
+	c.commandRouter.Register(commands.NewSearchCommand(c.guilds, c.socketClient, c.dbManager, c.blacklist, c.audit))
 }
 
 func (c *Client) registerEventHandlers() {
 	c.session.AddHandler(c.eventHandler.HandleReady)
+	c.session.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+		c.presence.Refresh()
+	})
+	c.session.AddHandler(c.handleGuildCreate)
+	c.session.AddHandler(c.handleGuildDelete)
 	c.session.AddHandler(c.eventHandler.HandleVoiceStateUpdate)
+	c.session.AddHandler(c.eventHandler.HandleGuildRoleDelete)
+	c.session.AddHandler(c.eventHandler.HandleChannelDelete)
+	c.session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		c.requestChannel.HandleMessage(c.api, m)
+	})
 	c.session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		if i.Type == discordgo.InteractionApplicationCommand {
 			c.commandRouter.Handle(i)
 		} else if i.Type == discordgo.InteractionMessageComponent {
-			c.handleMessageComponent(s, i)
+			c.commandRouter.HandleComponent(i)
 		}
 	})
 }
-
-func (c *Client) handleMessageComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	customID := i.MessageComponentData().CustomID
-
-	if len(customID) > 13 && customID[:13] == "search_select" {
-		if c.searchCommand != nil {
-			err := c.searchCommand.HandleSearchSelection(s, i)
-			if err != nil {
-				logger.Error.Printf("Search selection error: %v", err)
-			}
-		}
-	}
-}
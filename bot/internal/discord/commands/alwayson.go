@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"musicbot/internal/voice"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AlwaysOnCommand turns 24/7 mode on or off. In 24/7 mode the bot stays in
+// its 24/7 channel even when everyone leaves, gets back in if it is dropped,
+// and plays the radio whenever the queue is empty.
+type AlwaysOnCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewAlwaysOnCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *AlwaysOnCommand {
+	return &AlwaysOnCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *AlwaysOnCommand) Name() string {
+	return "247"
+}
+
+func (c *AlwaysOnCommand) Description() string {
+	return "Show or change whether the bot stays in a channel around the clock"
+}
+
+func (c *AlwaysOnCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *AlwaysOnCommand) Examples() []string {
+	return []string{
+		"/247 mode:on channel:#lounge",
+		"/247 mode:off",
+	}
+}
+
+func (c *AlwaysOnCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *AlwaysOnCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "mode",
+			Description: "Turn 24/7 mode on or off",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "on", Value: "on"},
+				{Name: "off", Value: "off"},
+			},
+		},
+		{
+			Type:         discordgo.ApplicationCommandOptionChannel,
+			Name:         "channel",
+			Description:  "Voice channel to stay in (defaults to the bot's current channel)",
+			Required:     false,
+			ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+		},
+	}
+}
+
+func (c *AlwaysOnCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	var mode, channelID string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "mode":
+			mode = option.StringValue()
+		case "channel":
+			if channel := discordapi.ChannelOption(s, option); channel != nil {
+				channelID = channel.ID
+			}
+		}
+	}
+
+	switch mode {
+	case "on":
+		return c.enable(s, i, guild, channelID)
+	case "off":
+		return c.disable(s, i, guild)
+	}
+
+	if current := guild.State.GetAlwaysOnChannel(); current != "" {
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.current_on", current))
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, "alwayson.current_off"))
+}
+
+func (c *AlwaysOnCommand) enable(s discordapi.Session, i *discordgo.InteractionCreate, guild *guilds.Guild, channelID string) error {
+	if channelID == "" {
+		channelID = guild.State.GetCurrentChannel()
+	}
+	if channelID == "" {
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.no_channel"))
+	}
+
+	if err := voice.CheckJoin(s, i.GuildID, channelID); err != nil {
+		return c.respond(s, i, joinErrorMessage(i.GuildID, err))
+	}
+
+	if err := c.dbManager.SaveAlwaysOnChannel(i.GuildID, channelID); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save 24/7 mode", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.save_failed"))
+	}
+
+	guild.State.SetAlwaysOnChannel(channelID)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionAlwaysOn, "on "+channelID)
+
+	if guild.State.GetCurrentChannel() == channelID {
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.enabled", channelID))
+	}
+
+	// Music playing elsewhere isn't cut off; the bot comes over once that
+	// channel empties or someone uses /leave.
+	if c.musicPlaying(guild) {
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.enabled_later", channelID))
+	}
+
+	if err := c.returnHome(guild); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to move to the 24/7 channel", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.enabled_move_failed", channelID))
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, "alwayson.enabled", channelID))
+}
+
+// disable turns 24/7 mode off. If nobody is left in the bot's channel, it
+// goes back to the idle channel right away, as it would have if 24/7 mode
+// hadn't kept it there.
+func (c *AlwaysOnCommand) disable(s discordapi.Session, i *discordgo.InteractionCreate, guild *guilds.Guild) error {
+	if err := c.dbManager.DeleteAlwaysOnChannel(i.GuildID); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save 24/7 mode", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "alwayson.save_failed"))
+	}
+
+	guild.State.SetAlwaysOnChannel("")
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionAlwaysOn, "off")
+
+	currentChannel := guild.State.GetCurrentChannel()
+	if currentChannel != "" && !guild.State.IsInIdleChannel() {
+		userCount, err := guild.Voice.GetConnection().CheckChannelUsers(i.GuildID, currentChannel)
+		if err == nil && userCount == 0 {
+			if err := c.returnHome(guild); err != nil {
+				logger.ForCommand(i.GuildID, c.Name()).Error("Failed to return to the idle channel", "error", err)
+			}
+		}
+	}
+
+	return c.respond(s, i, i18n.T(i.GuildID, "alwayson.disabled"))
+}
+
+func (c *AlwaysOnCommand) musicPlaying(guild *guilds.Guild) bool {
+	return guild.State.GetBotState() == state.StateDJ &&
+		(guild.Music.IsPlaying() || guild.Music.IsPaused())
+}
+
+// returnHome stops whatever is playing, takes the bot to its 24/7 channel, or
+// the idle channel if 24/7 mode is off, and starts the radio there.
+func (c *AlwaysOnCommand) returnHome(guild *guilds.Guild) error {
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	var err error
+	guild.Music.ExecuteWithDisabledHandlers(func() {
+		if guild.State.GetBotState() == state.StateDJ {
+			guild.Music.Stop()
+		}
+		guild.Radio.Stop()
+
+		time.Sleep(500 * time.Millisecond)
+
+		err = guild.Voice.ReturnToIdle(guild.ID)
+		if err != nil {
+			return
+		}
+
+		if guild.State.IsInIdleChannel() {
+			guild.State.SetBotState(state.StateIdle)
+		} else {
+			guild.State.SetBotState(state.StateRadio)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+		vc := guild.Voice.GetVoiceConnection()
+		if vc != nil && !guild.Radio.IsPlaying() {
+			guild.Radio.Start(vc)
+		}
+	})
+	return err
+}
+
+func (c *AlwaysOnCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
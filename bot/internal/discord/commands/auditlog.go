@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultAuditCount = 10
+	maxAuditCount     = 25
+)
+
+type AuditLogCommand struct {
+	audit *audit.Log
+}
+
+func NewAuditLogCommand(auditLog *audit.Log) *AuditLogCommand {
+	return &AuditLogCommand{
+		audit: auditLog,
+	}
+}
+
+func (c *AuditLogCommand) Name() string {
+	return "auditlog"
+}
+
+func (c *AuditLogCommand) Description() string {
+	return "Show who recently changed the music"
+}
+
+func (c *AuditLogCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *AuditLogCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *AuditLogCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show the latest audit log entries",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "count",
+					Description: "How many entries to show (default 10)",
+					Required:    false,
+					MinValue:    func() *float64 { v := 1.0; return &v }(),
+					MaxValue:    maxAuditCount,
+				},
+			},
+		},
+	}
+}
+
+func (c *AuditLogCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *AuditLogCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	count := defaultAuditCount
+	for _, option := range i.ApplicationCommandData().Options[0].Options {
+		if option.Name == "count" {
+			count = int(option.IntValue())
+		}
+	}
+
+	entries, err := c.audit.Recent(i.GuildID, count)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read audit log", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "auditlog.failed")),
+		})
+		return err
+	}
+
+	if len(entries) == 0 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "auditlog.empty")),
+		})
+		return err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(audit.FormatLine(entry) + "\n")
+	}
+
+	messages := render.Listing(i.GuildID, i18n.T(i.GuildID, "auditlog.title"), b.String(), render.ColorAccent)
+	return sendChunked(s, i, messages, nil, c.Ephemeral())
+}
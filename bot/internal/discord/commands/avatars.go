@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"sync"
+	"time"
+)
+
+const (
+	// avatarTTL is how long a looked-up avatar is reused, so rendering
+	// doesn't ask Discord for the same user every time.
+	avatarTTL = time.Hour
+
+	// avatarCacheSize bounds the cache; past it expired entries are dropped.
+	avatarCacheSize = 256
+)
+
+type avatarEntry struct {
+	url     string
+	fetched time.Time
+}
+
+// avatarCache resolves the avatar URLs of requesters, from the state cache
+// when the member is in it and from the API otherwise.
+type avatarCache struct {
+	entries map[string]avatarEntry
+	mu      sync.Mutex
+}
+
+func newAvatarCache() *avatarCache {
+	return &avatarCache{entries: make(map[string]avatarEntry)}
+}
+
+// URL returns the avatar of userID as shown in guildID, or "" when it can't
+// be found. Failed lookups are cached too, so a deleted user isn't asked for
+// again on every render.
+func (c *avatarCache) URL(s discordapi.Session, guildID, userID string) string {
+	if userID == "" {
+		return ""
+	}
+
+	key := guildID + ":" + userID
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < avatarTTL {
+		return entry.url
+	}
+
+	url := ""
+	if member, err := s.Cache().Member(guildID, userID); err == nil && member.User != nil {
+		url = member.AvatarURL("64")
+	} else if user, err := s.User(userID); err == nil {
+		url = user.AvatarURL("64")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= avatarCacheSize {
+		for k, e := range c.entries {
+			if time.Since(e.fetched) >= avatarTTL {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < avatarCacheSize {
+		c.entries[key] = avatarEntry{url: url, fetched: time.Now()}
+	}
+	return url
+}
@@ -0,0 +1,351 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	blacklistPurgeID = "blacklist_purge:"
+	blacklistKeepID  = "blacklist_keep:"
+)
+
+type BlacklistCommand struct {
+	blacklist         *blacklist.List
+	guilds            *guilds.Registry
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewBlacklistCommand(blacklistList *blacklist.List, guildRegistry *guilds.Registry, permissionManager *permissions.Manager, auditLog *audit.Log) *BlacklistCommand {
+	return &BlacklistCommand{
+		blacklist:         blacklistList,
+		guilds:            guildRegistry,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *BlacklistCommand) Name() string {
+	return "blacklist"
+}
+
+func (c *BlacklistCommand) Description() string {
+	return "Block URLs or users from queueing music"
+}
+
+func (c *BlacklistCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *BlacklistCommand) Examples() []string {
+	return []string{
+		"/blacklist add url pattern:*.example.com/*",
+		"/blacklist add user user:@someone",
+		"/blacklist list",
+	}
+}
+
+func (c *BlacklistCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *BlacklistCommand) Options() []*discordgo.ApplicationCommandOption {
+	targets := func(verb string) []*discordgo.ApplicationCommandOption {
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "url",
+				Description: verb + " a URL pattern, e.g. example.com or *.example.com/*",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "pattern",
+						Description: "Text the URL contains, or a glob with * and ?",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "user",
+				Description: verb + " a user",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "The user",
+						Required:    true,
+					},
+				},
+			},
+		}
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "add",
+			Description: "Block a URL pattern or a user",
+			Options:     targets("Block"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "remove",
+			Description: "Unblock a URL pattern or a user",
+			Options:     targets("Unblock"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "Show the blocked URL patterns and users",
+		},
+	}
+}
+
+func (c *BlacklistCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *BlacklistCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	group := i.ApplicationCommandData().Options[0]
+	if group.Name == "list" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{c.listEmbed(i.GuildID)},
+		})
+		return err
+	}
+
+	sub := group.Options[0]
+	adminID := i.Member.User.ID
+
+	switch {
+	case group.Name == "add" && sub.Name == "url":
+		pattern := sub.Options[0].StringValue()
+		if err := c.blacklist.AddURL(i.GuildID, pattern, adminID); err != nil {
+			return c.respondFailed(s, i, err)
+		}
+		c.audit.Record(i.GuildID, adminID, audit.ActionBlacklistAdd, strings.ToLower(strings.TrimSpace(pattern)))
+		return c.respond(s, i, i18n.T(i.GuildID, "blacklist.url_added", strings.ToLower(strings.TrimSpace(pattern))))
+
+	case group.Name == "remove" && sub.Name == "url":
+		pattern := sub.Options[0].StringValue()
+		removed, err := c.blacklist.RemoveURL(i.GuildID, pattern)
+		if err != nil {
+			return c.respondFailed(s, i, err)
+		}
+		if !removed {
+			return c.respond(s, i, i18n.T(i.GuildID, "blacklist.url_not_found", pattern))
+		}
+		c.audit.Record(i.GuildID, adminID, audit.ActionBlacklistRemove, pattern)
+		return c.respond(s, i, i18n.T(i.GuildID, "blacklist.url_removed", pattern))
+
+	case group.Name == "add" && sub.Name == "user":
+		user := discordapi.UserOption(s, sub.Options[0])
+		if err := c.blacklist.AddUser(i.GuildID, user.ID, adminID); err != nil {
+			return c.respondFailed(s, i, err)
+		}
+		c.audit.Record(i.GuildID, adminID, audit.ActionBlacklistAdd, "<@"+user.ID+">")
+		return c.respondUserAdded(s, i, user.ID)
+
+	case group.Name == "remove" && sub.Name == "user":
+		user := discordapi.UserOption(s, sub.Options[0])
+		removed, err := c.blacklist.RemoveUser(i.GuildID, user.ID)
+		if err != nil {
+			return c.respondFailed(s, i, err)
+		}
+		if !removed {
+			return c.respond(s, i, i18n.T(i.GuildID, "blacklist.user_not_found", user.ID))
+		}
+		c.audit.Record(i.GuildID, adminID, audit.ActionBlacklistRemove, "<@"+user.ID+">")
+		return c.respond(s, i, i18n.T(i.GuildID, "blacklist.user_removed", user.ID))
+	}
+
+	return nil
+}
+
+// respondUserAdded confirms the block and, if the user still has songs
+// waiting in the queue, offers to remove them.
+func (c *BlacklistCommand) respondUserAdded(s discordapi.Session, i *discordgo.InteractionCreate, userID string) error {
+	queued := c.guilds.Get(i.GuildID).Music.UpcomingCountBy(userID)
+
+	if queued == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "blacklist.user_added", userID))
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "blacklist.user_added_queued", userID, queued)),
+		Components: &[]discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Style:    discordgo.DangerButton,
+						Label:    i18n.T(i.GuildID, "blacklist.purge_button", queued),
+						CustomID: blacklistPurgeID + userID,
+					},
+					discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    i18n.T(i.GuildID, "blacklist.keep_button"),
+						CustomID: blacklistKeepID + userID,
+					},
+				},
+			},
+		},
+	})
+	return err
+}
+
+func (c *BlacklistCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+func (c *BlacklistCommand) respondFailed(s discordapi.Session, i *discordgo.InteractionCreate, err error) error {
+	logger.ForCommand(i.GuildID, c.Name()).Error("Failed to update blacklist", "error", err)
+	return c.respond(s, i, i18n.T(i.GuildID, "blacklist.failed"))
+}
+
+func (c *BlacklistCommand) listEmbed(guildID string) *discordgo.MessageEmbed {
+	var urls, users []string
+	for _, entry := range c.blacklist.URLs(guildID) {
+		urls = append(urls, fmt.Sprintf("`%s`", entry.Value))
+	}
+	for _, entry := range c.blacklist.Users(guildID) {
+		users = append(users, fmt.Sprintf("<@%s>", entry.Value))
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: i18n.T(guildID, "blacklist.title"),
+		Color: 0xED4245,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(guildID, "blacklist.urls"), Value: blacklistField(guildID, urls)},
+			{Name: i18n.T(guildID, "blacklist.users"), Value: blacklistField(guildID, users)},
+		},
+	}
+}
+
+// blacklistField joins entries one per line, cut to fit an embed field.
+func blacklistField(guildID string, entries []string) string {
+	if len(entries) == 0 {
+		return i18n.T(guildID, "blacklist.none")
+	}
+
+	const maxFieldLength = 1024
+	value := ""
+	for k, entry := range entries {
+		more := i18n.T(guildID, "blacklist.more", len(entries)-k)
+		if len(value)+len(entry)+1 > maxFieldLength-len(more) {
+			return value + more
+		}
+		value += entry + "\n"
+	}
+	return value
+}
+
+func (c *BlacklistCommand) ComponentPrefix() string {
+	return "blacklist_"
+}
+
+// HandleComponent answers the purge prompt shown after blocking a user. The
+// router doesn't check permissions for components, so the admin check is
+// repeated here.
+func (c *BlacklistCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if i.Member == nil || i.Member.User == nil {
+		return nil
+	}
+
+	allowed, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, c.RequiredLevel())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, c.RequiredLevel())
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Content: i18n.T(i.GuildID, "permissions.denied", roleName, c.Name()),
+			},
+		})
+	}
+
+	customID := i.MessageComponentData().CustomID
+
+	var content string
+	switch {
+	case strings.HasPrefix(customID, blacklistPurgeID):
+		userID := strings.TrimPrefix(customID, blacklistPurgeID)
+		removed := c.guilds.Get(i.GuildID).Music.RemoveRequestedBy(userID)
+		c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionBlacklistPurge, fmt.Sprintf("<@%s> (%d)", userID, removed))
+		content = i18n.T(i.GuildID, "blacklist.purged", userID, removed)
+	case strings.HasPrefix(customID, blacklistKeepID):
+		content = i18n.T(i.GuildID, "blacklist.user_added", strings.TrimPrefix(customID, blacklistKeepID))
+	default:
+		return nil
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// blacklistReason returns the message explaining why userID may not queue
+// rawURL, if either is blocked or the user is timed out. rawURL may be empty to check only the user.
+func blacklistReason(list *blacklist.List, guildID, userID, rawURL string) (string, bool) {
+	if list == nil {
+		return "", false
+	}
+	if list.IsUserBlocked(guildID, userID) {
+		return i18n.T(guildID, "blacklist.user_blocked"), true
+	}
+	if until, timedOut := list.TimedOut(guildID, userID); timedOut {
+		return i18n.T(guildID, "djban.timed_out", until.Unix()), true
+	}
+	if rawURL != "" {
+		if pattern, blocked := list.MatchURL(guildID, rawURL); blocked {
+			return i18n.T(guildID, "blacklist.url_blocked", pattern), true
+		}
+	}
+	return "", false
+}
+
+// respondBlacklisted answers an interaction that hasn't been responded to yet
+// with an ephemeral explanation of the block.
+func respondBlacklisted(s discordapi.Session, i *discordgo.InteractionCreate, reason string) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Content: reason,
+		},
+	})
+}
+
+// followUpBlacklisted is respondBlacklisted for a command the router has
+// already deferred. A deferred response can't be made ephemeral, so it is
+// removed and the explanation sent as an ephemeral follow-up instead.
+func followUpBlacklisted(s discordapi.Session, i *discordgo.InteractionCreate, reason string) error {
+	if err := s.InteractionResponseDelete(i.Interaction); err != nil {
+		return err
+	}
+	_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: reason,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	return err
+}
@@ -2,23 +2,26 @@ package commands
 
 import (
 	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
 	"musicbot/internal/radio"
-	"musicbot/internal/voice"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type ChangeStreamCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	dbManager    *config.DatabaseManager
+	guilds    *guilds.Registry
+	streams   *radio.StreamManager
+	dbManager *config.DatabaseManager
 }
 
-func NewChangeStreamCommand(voiceManager *voice.Manager, radioManager *radio.Manager, dbManager *config.DatabaseManager) *ChangeStreamCommand {
+func NewChangeStreamCommand(guildRegistry *guilds.Registry, streams *radio.StreamManager, dbManager *config.DatabaseManager) *ChangeStreamCommand {
 	return &ChangeStreamCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		dbManager:    dbManager,
+		guilds:    guildRegistry,
+		streams:   streams,
+		dbManager: dbManager,
 	}
 }
 
@@ -30,11 +33,22 @@ func (c *ChangeStreamCommand) Description() string {
 	return "Change the radio stream"
 }
 
+func (c *ChangeStreamCommand) Category() Category {
+	return CategoryRadio
+}
+
+func (c *ChangeStreamCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
 func (c *ChangeStreamCommand) Options() []*discordgo.ApplicationCommandOption {
-	streamChoices := []*discordgo.ApplicationCommandOptionChoice{
-		{Name: "listen.moe", Value: "listen.moe"},
-		{Name: "listen.moe (kpop)", Value: "listen.moe (kpop)"},
-		{Name: "lofi", Value: "lofi"},
+	// Discord allows at most 25 choices per option.
+	var streamChoices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range c.streams.GetStreamNames() {
+		if len(streamChoices) == 25 {
+			break
+		}
+		streamChoices = append(streamChoices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
 	}
 
 	return []*discordgo.ApplicationCommandOption{
@@ -48,54 +62,41 @@ func (c *ChangeStreamCommand) Options() []*discordgo.ApplicationCommandOption {
 	}
 }
 
-func (c *ChangeStreamCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *ChangeStreamCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
 
 	streamName := i.ApplicationCommandData().Options[0].StringValue()
 
-	if !c.radioManager.IsValidStream(streamName) {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Invalid stream selection."),
+	if !guild.Radio.IsValidStream(streamName) {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "changestream.invalid")),
 		})
 		return err
 	}
 
-	c.radioManager.Stop()
+	guild.Radio.Stop()
 
-	err = c.radioManager.ChangeStream(streamName)
+	err := guild.Radio.ChangeStream(streamName)
 	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Failed to change stream."),
+			Content: stringPtr(i18n.T(i.GuildID, "changestream.failed")),
 		})
 		return err
 	}
 
 	if c.dbManager != nil {
-		streamURL := ""
-		streams := config.GetDefaultStreams()
-		for _, stream := range streams {
-			if stream.Name == streamName {
-				streamURL = stream.URL
-				break
-			}
-		}
-		if streamURL != "" {
-			c.dbManager.SaveStream(streamURL)
+		if stream, err := guild.Radio.GetStream(streamName); err == nil {
+			c.dbManager.SaveStream(stream.URL)
 		}
 	}
 
-	vc := c.voiceManager.GetVoiceConnection()
+	vc := guild.Voice.GetVoiceConnection()
 	if vc != nil {
-		c.radioManager.Start(vc)
+		guild.Radio.Start(vc)
 	}
 
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr("✅ Changed radio stream to " + streamName),
+		Content: stringPtr(i18n.T(i.GuildID, "changestream.changed", streamName)),
 	})
 	return err
 }
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sendChunked edits the deferred response into the first of messages and
+// sends the rest as follow-ups, for listings too long for one message.
+// components go on the first message. The follow-ups of an ephemeral command
+// are ephemeral too.
+func sendChunked(s discordapi.Session, i *discordgo.InteractionCreate, messages []render.Message, components []discordgo.MessageComponent, ephemeral bool) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	edit := messages[0].Edit()
+	if components != nil {
+		edit.Components = &components
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		return err
+	}
+
+	for _, message := range messages[1:] {
+		params := &discordgo.WebhookParams{
+			Content:         message.Content,
+			Embeds:          message.Embeds,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		}
+		if ephemeral {
+			params.Flags = discordgo.MessageFlagsEphemeral
+		}
+		if _, err := s.FollowupMessageCreate(i.Interaction, true, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
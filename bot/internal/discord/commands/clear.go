@@ -1,29 +1,37 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
 	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"musicbot/internal/permissions"
 	"musicbot/internal/state"
 	"musicbot/internal/voice"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// clearConfirmThreshold is how many tracks the queue can hold before /clear
+// asks first.
+const clearConfirmThreshold = 5
+
 type ClearCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
+	audit  *audit.Log
 }
 
-func NewClearCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *ClearCommand {
+func NewClearCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *ClearCommand {
 	return &ClearCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
+		audit:  auditLog,
 	}
 }
 
@@ -35,91 +43,256 @@ func (c *ClearCommand) Description() string {
 	return "Clear the music queue"
 }
 
+func (c *ClearCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ClearCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *ClearCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
 func (c *ClearCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *ClearCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
+func (c *ClearCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	tracks := c.tracks(i.GuildID)
+	if tracks == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.already_empty"), nil)
 	}
 
-	queueItems := c.musicManager.GetQueue()
-	if len(queueItems) == 0 && !c.musicManager.IsPlaying() {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("📭 Queue is already empty."),
-		})
+	if tracks > clearConfirmThreshold {
+		ownerID := i.Member.User.ID
+		edit := render.Confirm(i.GuildID, i18n.T(i.GuildID, "clear.confirm_title"), i18n.T(i.GuildID, "clear.confirm", tracks)).Edit()
+		components := []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Style:    discordgo.DangerButton,
+						Label:    i18n.T(i.GuildID, "clear.confirm_button"),
+						CustomID: c.buttonID("confirm", ownerID),
+					},
+					discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    i18n.T(i.GuildID, "clear.cancel_button"),
+						CustomID: c.buttonID("cancel", ownerID),
+					},
+				},
+			},
+		}
+		edit.Components = &components
+		_, err := s.InteractionResponseEdit(i.Interaction, edit)
 		return err
 	}
 
-	if c.musicManager.HasActiveDownloads() {
-		pendingCount := c.musicManager.GetPendingDownloads()
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr(fmt.Sprintf("⏳ Cannot clear queue while %d songs are downloading. Please wait for downloads to complete.", pendingCount)),
-		})
-		return err
+	return c.clear(s, i)
+}
+
+// tracks is how many songs a clear would take off: the one playing and the
+// ones after it.
+func (c *ClearCommand) tracks(guildID string) int {
+	musicManager := c.guilds.Get(guildID).Music
+	tracks := len(musicManager.GetUpcomingItems())
+	if musicManager.GetCurrentSong() != nil || musicManager.IsPlaying() {
+		tracks++
+	}
+	return tracks
+}
+
+func (c *ClearCommand) clear(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	// Songs and playlists still downloading are stopped rather than waited
+	// for.
+	guild.Music.CancelDownloads()
+
+	if guild.Music.HasActiveDownloads() {
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.downloads_pending", guild.Music.GetPendingDownloads()), nil)
 	}
 
-	c.radioManager.Stop()
-	c.musicManager.Stop()
+	c.guilds.StopPlayback(guild.ID)
 
 	time.Sleep(1 * time.Second)
 
-	err = c.musicManager.ClearQueue()
+	err := guild.Music.ClearQueue()
 	if err != nil {
 		if err.Error() == "cannot clear queue while downloads are in progress" {
-			pendingCount := c.musicManager.GetPendingDownloads()
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("⏳ Cannot clear queue while %d songs are downloading. Please wait for downloads to complete.", pendingCount)),
-			})
-		} else {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to clear queue."),
-			})
+			return c.respond(s, i, i18n.T(i.GuildID, "clear.downloads_pending", guild.Music.GetPendingDownloads()), nil)
 		}
-		return err
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.failed"), nil)
 	}
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionClear, "")
 
 	time.Sleep(500 * time.Millisecond)
 
-	if c.stateManager.IsInIdleChannel() {
-		c.stateManager.SetBotState(state.StateIdle)
+	if guild.State.IsHome() {
+		guild.State.SetBotState(state.StateIdle)
 
 		time.Sleep(500 * time.Millisecond)
 
-		vc := c.voiceManager.GetVoiceConnection()
-		if vc != nil && !c.radioManager.IsPlaying() {
-			c.radioManager.Start(vc)
+		vc := guild.Voice.GetVoiceConnection()
+		if vc != nil && !guild.Radio.IsPlaying() {
+			guild.Radio.Start(vc)
 		}
 
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("🗑️ Queue cleared successfully. Radio will continue playing."),
-		})
-		return err
+		return c.cleared(s, i, i18n.T(i.GuildID, "clear.cleared_radio"))
 	}
 
-	err = c.voiceManager.LeaveToIdle(i.GuildID)
+	err = guild.Voice.LeaveToIdle(i.GuildID)
 	if err != nil {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("🗑️ Queue cleared, but failed to return to idle channel."),
+		return c.cleared(s, i, i18n.T(i.GuildID, "clear.cleared_return_failed"))
+	}
+
+	guild.State.SetBotState(state.StateIdle)
+
+	time.Sleep(500 * time.Millisecond)
+
+	vc := guild.Voice.GetVoiceConnection()
+	if vc != nil && !guild.Radio.IsPlaying() {
+		guild.Radio.Start(vc)
+	}
+
+	return c.cleared(s, i, i18n.T(i.GuildID, "clear.cleared_returned"))
+}
+
+// cleared reports a finished clear with an Undo button, which is taken off
+// again once the cleared songs can no longer be put back.
+func (c *ClearCommand) cleared(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	if !c.guilds.Get(i.GuildID).Music.CanRestoreCleared() {
+		return c.respond(s, i, content, nil)
+	}
+
+	content += "\n" + i18n.T(i.GuildID, "clear.undo_hint", time.Now().Add(music.ClearUndoWindow).Unix())
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.PrimaryButton,
+					Label:    i18n.T(i.GuildID, "clear.undo_button"),
+					CustomID: c.buttonID("undo", i.Member.User.ID),
+				},
+			},
+		},
+	}
+	if err := c.respond(s, i, content, components); err != nil {
+		return err
+	}
+
+	time.AfterFunc(music.ClearUndoWindow, func() {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Components: &[]discordgo.MessageComponent{},
 		})
+		if err != nil {
+			logger.Debug.Printf("Failed to remove clear undo button: %v", err)
+		}
+	})
+	return nil
+}
+
+func (c *ClearCommand) ComponentPrefix() string {
+	return "clear_"
+}
+
+func (c *ClearCommand) buttonID(action, ownerID string) string {
+	return fmt.Sprintf("%s%s_%s", c.ComponentPrefix(), action, ownerID)
+}
+
+// HandleComponent serves the Confirm, Cancel and Undo buttons, which carry
+// the user who ran /clear: clear_<action>_<owner>. Nobody else can press
+// them.
+func (c *ClearCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	action, ownerID, ok := strings.Cut(strings.TrimPrefix(i.MessageComponentData().CustomID, c.ComponentPrefix()), "_")
+	if !ok {
+		return fmt.Errorf("malformed clear button id: %s", i.MessageComponentData().CustomID)
+	}
+
+	if i.Member == nil || i.Member.User == nil || i.Member.User.ID != ownerID {
+		return respondNotOwner(s, i)
+	}
+
+	if action == "cancel" {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i18n.T(i.GuildID, "clear.cancelled"),
+				Embeds:     []*discordgo.MessageEmbed{},
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
 		return err
 	}
 
-	c.stateManager.SetBotState(state.StateIdle)
+	switch action {
+	case "confirm":
+		if c.tracks(i.GuildID) == 0 {
+			return c.respond(s, i, i18n.T(i.GuildID, "clear.already_empty"), nil)
+		}
+		return c.clear(s, i)
+	case "undo":
+		return c.undo(s, i)
+	}
+	return nil
+}
 
-	time.Sleep(500 * time.Millisecond)
+// undo puts the cleared songs back. If the user has gone to another voice
+// channel since, or the bot went back to idle, it joins them first.
+func (c *ClearCommand) undo(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	userID := i.Member.User.ID
+
+	if !guild.Music.CanRestoreCleared() {
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.undo_expired"), nil)
+	}
+
+	userChannelID, err := voice.UserVoiceChannel(s, i.GuildID, userID)
+	if err == nil && userChannelID != guild.State.GetCurrentChannel() && !guild.Music.IsPlaying() {
+		guild.Radio.Stop()
+		if err := guild.Voice.JoinUser(i.GuildID, userID); err != nil {
+			return c.respond(s, i, joinErrorMessage(i.GuildID, err), nil)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
 
-	vc := c.voiceManager.GetVoiceConnection()
-	if vc != nil && !c.radioManager.IsPlaying() {
-		c.radioManager.Start(vc)
+	restored, missing, err := guild.Music.RestoreCleared()
+	if errors.Is(err, music.ErrNothingToRestore) {
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.undo_expired"), nil)
 	}
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to restore the cleared queue", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.undo_failed"), nil)
+	}
+	c.audit.Record(i.GuildID, userID, audit.ActionClearUndo, fmt.Sprintf("%d", restored))
 
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr("🗑️ Queue cleared successfully. Returned to idle channel and resumed radio."),
+	if restored == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "clear.undo_all_missing"), nil)
+	}
+	content := i18n.T(i.GuildID, "clear.restored", restored)
+	if missing > 0 {
+		content += "\n" + i18n.T(i.GuildID, "clear.restored_missing", missing)
+	}
+	return c.respond(s, i, content, nil)
+}
+
+// respond replaces the whole message, so no confirmation embed or buttons
+// stay behind.
+func (c *ClearCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string, components []discordgo.MessageComponent) error {
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Embeds:     &[]*discordgo.MessageEmbed{},
+		Components: &components,
 	})
 	return err
 }
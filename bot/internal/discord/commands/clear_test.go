@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/music"
+	"musicbot/internal/socket/sockettest"
+	"musicbot/internal/state"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// requestNotifier records how a song request ended.
+type requestNotifier struct {
+	queued chan *state.Song
+	failed chan error
+}
+
+func newRequestNotifier() *requestNotifier {
+	return &requestNotifier{queued: make(chan *state.Song, 1), failed: make(chan error, 1)}
+}
+
+func (n *requestNotifier) Queued(song *state.Song, _ music.ETA) { n.queued <- song }
+func (n *requestNotifier) Failed(err error)                     { n.failed <- err }
+
+func TestClearCancelsOnlyItsGuildsDownloads(t *testing.T) {
+	const (
+		otherGuildID = "other"
+		ours         = "https://soundcloud.com/artist/ours"
+		theirs       = "https://soundcloud.com/artist/theirs"
+	)
+
+	env := newTestEnv(t)
+	queueSongs(t, env, 2)
+	if err := env.session.cache.GuildAdd(&discordgo.Guild{ID: otherGuildID}); err != nil {
+		t.Fatal(err)
+	}
+	// Downloads are held until the test answers them.
+	env.downloader.Handle("download_audio", func(sockettest.Request) []sockettest.Response { return nil })
+
+	requested := map[string]*requestNotifier{}
+	for guildID, url := range map[string]string{testGuildID: ours, otherGuildID: theirs} {
+		musicManager := env.guilds.Get(guildID).Music
+		requested[url] = newRequestNotifier()
+		if err := musicManager.RequestSong(url, "user", musicManager.DownloadLimits(guildID), requested[url]); err != nil {
+			t.Fatalf("RequestSong(%s): %v", url, err)
+		}
+	}
+	requestIDs := map[string]string{}
+	for _, request := range env.downloader.Await("download_audio", 2) {
+		requestIDs[request.Params["url"].(string)] = request.ID
+	}
+	// The manager keeps a request's ID once sending it returns, just after
+	// the downloader got it.
+	time.Sleep(100 * time.Millisecond)
+
+	i := commandInteraction("user", "clear")
+	if err := NewClearCommand(env.guilds, env.audit).Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	env.session.awaitContent(t, i, "🗑️")
+
+	cancels := env.downloader.Await("cancel", 1)
+	if len(cancels) != 1 {
+		t.Fatalf("downloader got %d cancel requests, want 1", len(cancels))
+	}
+	ids, _ := cancels[0].Params["ids"].([]interface{})
+	if !slices.Equal(ids, []interface{}{requestIDs[ours]}) {
+		t.Errorf("cancelled %v, want only %s", ids, requestIDs[ours])
+	}
+
+	select {
+	case err := <-requested[ours].failed:
+		if !errors.Is(err, music.ErrDownloadCancelled) {
+			t.Errorf("our request failed with %v, want ErrDownloadCancelled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("our request wasn't told it was cancelled")
+	}
+
+	// The other guild's download still counts when it comes in.
+	env.downloader.Send(sockettest.Success(sockettest.Request{ID: requestIDs[theirs]}, map[string]interface{}{
+		"title":    "theirs",
+		"url":      theirs,
+		"filename": t.TempDir() + "/theirs.mp3",
+		"duration": 60,
+		"platform": "test",
+	}))
+	select {
+	case song := <-requested[theirs].queued:
+		if song.URL != theirs {
+			t.Errorf("queued %s, want %s", song.URL, theirs)
+		}
+	case err := <-requested[theirs].failed:
+		t.Fatalf("the other guild's request failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the other guild's song wasn't queued")
+	}
+	if got := len(env.guilds.Get(testGuildID).Music.GetQueue()); got != 0 {
+		t.Errorf("cleared guild holds %d songs, want 0", got)
+	}
+}
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audio"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ClipCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewClipCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *ClipCommand {
+	return &ClipCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *ClipCommand) Name() string {
+	return "clip"
+}
+
+func (c *ClipCommand) Description() string {
+	return "Play short sounds over the music"
+}
+
+func (c *ClipCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ClipCommand) Examples() []string {
+	return []string{
+		"/clip play name:airhorn",
+		"/clip add name:airhorn file:airhorn.mp3",
+		"/clip list",
+	}
+}
+
+func (c *ClipCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *ClipCommand) Cooldown() time.Duration {
+	return 3 * time.Second
+}
+
+func (c *ClipCommand) Options() []*discordgo.ApplicationCommandOption {
+	name := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "name",
+		Description: "Name of the clip",
+		Required:    true,
+		MaxLength:   music.MaxClipNameLength,
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "play",
+			Description: "Play a clip over whatever is playing",
+			Options:     []*discordgo.ApplicationCommandOption{name},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: fmt.Sprintf("Add a clip of at most %d seconds", int(music.MaxClipLength.Seconds())),
+			Options: []*discordgo.ApplicationCommandOption{
+				name,
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Audio file of the clip",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Remove a clip",
+			Options:     []*discordgo.ApplicationCommandOption{name},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "Show this server's clips",
+		},
+	}
+}
+
+func (c *ClipCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	data := i.ApplicationCommandData()
+	sub := data.Options[0]
+
+	switch sub.Name {
+	case "play":
+		return c.play(s, i, sub.Options[0].StringValue())
+	case "add":
+		attachmentID, _ := sub.Options[1].Value.(string)
+		return c.add(s, i, sub.Options[0].StringValue(), data.Resolved.Attachments[attachmentID])
+	case "remove":
+		return c.remove(s, i, sub.Options[0].StringValue())
+	case "list":
+		return c.list(s, i)
+	}
+	return nil
+}
+
+func (c *ClipCommand) play(s discordapi.Session, i *discordgo.InteractionCreate, name string) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	vc := guild.Voice.GetVoiceConnection()
+	if vc == nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "clip.not_connected"))
+	}
+
+	clip, err := guild.Music.PlayClip(i.GuildID, name, vc, guild.Radio.IsPlaying())
+	if err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, name, err))
+	}
+
+	return c.respond(s, i, i18n.T(i.GuildID, "clip.playing", clip.Name))
+}
+
+func (c *ClipCommand) add(s discordapi.Session, i *discordgo.InteractionCreate, name string, attachment *discordgo.MessageAttachment) error {
+	if attachment == nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "playfile.unsupported", strings.Join(config.TrackExtensions, ", ")))
+	}
+
+	upload := music.Upload{
+		URL:         attachment.URL,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+	}
+
+	userID := i.Member.User.ID
+	clip, err := c.guilds.Get(i.GuildID).Music.AddClip(i.GuildID, name, userID, upload)
+	if err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, name, err))
+	}
+	c.audit.Record(i.GuildID, userID, audit.ActionClipAdd, clip.Name)
+
+	return c.respond(s, i, i18n.T(i.GuildID, "clip.added", clip.Name, formatClipLength(clip.Duration)))
+}
+
+func (c *ClipCommand) remove(s discordapi.Session, i *discordgo.InteractionCreate, name string) error {
+	if err := c.guilds.Get(i.GuildID).Music.RemoveClip(i.GuildID, name); err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, name, err))
+	}
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionClipRemove, name)
+
+	return c.respond(s, i, i18n.T(i.GuildID, "clip.removed", name))
+}
+
+func (c *ClipCommand) list(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	clips, err := c.dbManager.GetClips(i.GuildID)
+	if err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, "", err))
+	}
+	if len(clips) == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "clip.none"))
+	}
+
+	var lines []string
+	for _, clip := range clips {
+		lines = append(lines, fmt.Sprintf("`%s` (%s)", clip.Name, formatClipLength(clip.Duration)))
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       i18n.T(i.GuildID, "clip.title", len(clips)),
+				Description: blacklistField(i.GuildID, lines),
+				Color:       0x5865F2,
+			},
+		},
+	})
+	return err
+}
+
+func (c *ClipCommand) errorMessage(guildID, name string, err error) string {
+	var tooLong *music.ClipTooLongError
+	var tooLarge *music.UploadTooLargeError
+
+	switch {
+	case errors.Is(err, music.ErrClipNotFound):
+		return i18n.T(guildID, "clip.not_found", name)
+	case errors.Is(err, music.ErrInvalidClipName):
+		return i18n.T(guildID, "clip.invalid_name", music.MaxClipNameLength)
+	case errors.Is(err, config.ErrClipExists):
+		return i18n.T(guildID, "clip.exists", name)
+	case errors.Is(err, music.ErrClipOverRadio):
+		return i18n.T(guildID, "clip.radio")
+	case errors.Is(err, audio.ErrOverlayBusy):
+		return i18n.T(guildID, "clip.busy")
+	case errors.Is(err, music.ErrUnsupportedUpload):
+		return i18n.T(guildID, "playfile.unsupported", strings.Join(config.TrackExtensions, ", "))
+	case errors.As(err, &tooLong):
+		return i18n.T(guildID, "clip.too_long", formatClipLength(tooLong.Length), int(music.MaxClipLength.Seconds()))
+	case errors.As(err, &tooLarge):
+		return i18n.T(guildID, "playfile.too_large", tooLarge.LimitMB)
+	}
+
+	logger.ForCommand(guildID, c.Name()).Error("Clip command failed", "error", err)
+	return i18n.T(guildID, "clip.failed")
+}
+
+func (c *ClipCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// formatClipLength shows a clip's length to a tenth of a second.
+func formatClipLength(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ConfigCommand shows and changes the settings kept in the database. They
+// apply right away. Global settings are shared by every server the bot is in,
+// so only the bot's owners may change those.
+type ConfigCommand struct {
+	guilds            *guilds.Registry
+	stateManager      *state.Manager
+	dbManager         *config.DatabaseManager
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewConfigCommand(guildRegistry *guilds.Registry, stateManager *state.Manager, dbManager *config.DatabaseManager, permissionManager *permissions.Manager, auditLog *audit.Log) *ConfigCommand {
+	return &ConfigCommand{
+		guilds:            guildRegistry,
+		stateManager:      stateManager,
+		dbManager:         dbManager,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *ConfigCommand) Name() string {
+	return "config"
+}
+
+func (c *ConfigCommand) Description() string {
+	return "List, show or change the bot's stored settings"
+}
+
+func (c *ConfigCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *ConfigCommand) Examples() []string {
+	return []string{
+		"/config list",
+		"/config get key:idle_delay",
+		"/config set key:volume value:0.08",
+		"/config set key:idle_delay value:2m",
+	}
+}
+
+func (c *ConfigCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *ConfigCommand) Options() []*discordgo.ApplicationCommandOption {
+	key := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "key",
+		Description: "Setting name, as /config list shows it",
+		Required:    true,
+		MaxLength:   32,
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List every setting with its type and current value",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "get",
+			Description: "Show a single setting",
+			Options:     []*discordgo.ApplicationCommandOption{key},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set",
+			Description: "Change a setting",
+			Options: []*discordgo.ApplicationCommandOption{
+				key,
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "value",
+					Description: "New value",
+					Required:    true,
+					MaxLength:   256,
+				},
+			},
+		},
+	}
+}
+
+func (c *ConfigCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	subcommand := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range subcommand.Options {
+		options[option.Name] = option
+	}
+
+	if subcommand.Name == "list" {
+		return c.respond(s, i, c.list(i.GuildID))
+	}
+
+	key := strings.ToLower(strings.TrimSpace(options["key"].StringValue()))
+	setting, ok := config.LookupSetting(key)
+	if !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "config.unknown", key, strings.Join(config.SettingKeys(), "`, `")))
+	}
+
+	switch subcommand.Name {
+	case "get":
+		return c.respond(s, i, c.get(i.GuildID, setting))
+	case "set":
+		return c.set(s, i, setting, options["value"].StringValue())
+	}
+	return nil
+}
+
+func (c *ConfigCommand) list(guildID string) string {
+	var b strings.Builder
+	b.WriteString(i18n.T(guildID, "config.list_title"))
+	for _, setting := range config.Settings() {
+		b.WriteString(i18n.T(guildID, "config.line", setting.Key, setting.Type, c.current(setting.Key), setting.Description))
+	}
+	return b.String()
+}
+
+func (c *ConfigCommand) get(guildID string, setting config.Setting) string {
+	defaultValue := setting.Default
+	if defaultValue == "" {
+		defaultValue = i18n.T(guildID, "config.default_file")
+	}
+	return i18n.T(guildID, "config.get", setting.Key, setting.Description, setting.Type, c.current(setting.Key), defaultValue)
+}
+
+func (c *ConfigCommand) set(s discordapi.Session, i *discordgo.InteractionCreate, setting config.Setting, value string) error {
+	if setting.Global && !c.permissionManager.IsOwner(i.Member.User.ID) {
+		return c.respond(s, i, i18n.T(i.GuildID, "config.owner_only", setting.Key))
+	}
+
+	old := c.current(setting.Key)
+
+	value, err := setting.Parse(value)
+	if err != nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "config.invalid", setting.Key, setting.Type, err.Error()))
+	}
+
+	if _, err := c.dbManager.SaveSetting(setting.Key, value); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save setting", "key", setting.Key, "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "config.save_failed", setting.Key))
+	}
+
+	c.apply(setting.Key, value)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionConfigSet, setting.Key+"="+value)
+
+	return c.respond(s, i, i18n.T(i.GuildID, "config.saved", setting.Key, old, c.current(setting.Key)))
+}
+
+// current is the value the bot is using now, which for idle_delay may come
+// from config.json rather than the database.
+func (c *ConfigCommand) current(key string) string {
+	switch key {
+	case "volume":
+		return strconv.FormatFloat(float64(c.stateManager.GetVolume()), 'f', -1, 32)
+	case "stream":
+		return c.stateManager.GetRadioStream()
+	case "idle_delay":
+		return c.stateManager.GetConfig().IdleDelay.String()
+	case "max_queue_length":
+		return strconv.Itoa(c.guilds.DefaultQueueLimits().MaxQueueLength)
+	case "max_user_queued":
+		return strconv.Itoa(c.guilds.DefaultQueueLimits().MaxUserQueued)
+	}
+	return ""
+}
+
+// apply puts a saved value into effect. value has already passed the
+// setting's checks, so it parses.
+func (c *ConfigCommand) apply(key, value string) {
+	switch key {
+	case "volume":
+		volume, _ := strconv.ParseFloat(value, 32)
+		c.stateManager.SetVolume(float32(volume))
+	case "stream":
+		c.stateManager.SetRadioStream(value)
+		c.restartRadio()
+	case "idle_delay":
+		delay, _ := time.ParseDuration(value)
+		live := c.stateManager.GetConfig()
+		live.IdleDelay = delay
+		c.stateManager.UpdateConfig(live)
+	case "max_queue_length", "max_user_queued":
+		n, _ := strconv.Atoi(value)
+		limits := c.guilds.DefaultQueueLimits()
+		if key == "max_queue_length" {
+			limits.MaxQueueLength = n
+		} else {
+			limits.MaxUserQueued = n
+		}
+		c.guilds.SetDefaultQueueLimits(limits)
+	}
+}
+
+// restartRadio switches every guild the radio plays in over to the new
+// stream.
+func (c *ConfigCommand) restartRadio() {
+	for _, guild := range c.guilds.All() {
+		if !guild.Radio.IsPlaying() {
+			continue
+		}
+		vc := guild.Voice.GetVoiceConnection()
+		if vc == nil {
+			continue
+		}
+		guild.Radio.Stop()
+		if err := guild.Radio.Start(vc); err != nil {
+			logger.ForGuild(guild.ID).Error("Failed to restart the radio on the new stream", "error", err)
+		}
+	}
+}
+
+func (c *ConfigCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
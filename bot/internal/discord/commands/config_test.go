@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"musicbot/internal/permissions"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestConfigGlobalSettingsNeedOwner(t *testing.T) {
+	env := newTestEnv(t)
+	env.state.SetVolume(0.05)
+	permissionManager := permissions.NewManager(permissions.Config{}, nil)
+	permissionManager.SetOwners([]string{"owner"})
+	cmd := NewConfigCommand(env.guilds, env.state, env.db, permissionManager, env.audit)
+
+	set := func(userID, key, value string) *discordgo.InteractionCreate {
+		i := commandInteraction(userID, "config", &discordgo.ApplicationCommandInteractionDataOption{
+			Name:    "set",
+			Type:    discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{stringOption("key", key), stringOption("value", value)},
+		})
+		if err := cmd.Execute(env.session, i); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		return i
+	}
+
+	// A guild's admin can't turn the volume down for every other guild.
+	env.session.awaitContent(t, set("admin", "volume", "0.08"), "only the bot's owners")
+	if volume := env.state.GetVolume(); volume != 0.05 {
+		t.Errorf("volume = %v after an admin's /config set, want it unchanged", volume)
+	}
+	if saved, err := env.db.LoadConfig(); err != nil || saved.Volume == 0.08 {
+		t.Errorf("LoadConfig = %+v, %v, want the volume not saved", saved, err)
+	}
+
+	env.session.awaitContent(t, set("owner", "volume", "0.08"), "changed from `0.05` to `0.08`")
+	if volume := env.state.GetVolume(); volume != 0.08 {
+		t.Errorf("volume = %v after the owner's /config set, want 0.08", volume)
+	}
+}
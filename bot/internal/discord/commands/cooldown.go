@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const cooldownSweepInterval = time.Minute
+
+// CooldownCommand is implemented by commands that a user may only run once
+// per Cooldown. Admins bypass it.
+type CooldownCommand interface {
+	Cooldown() time.Duration
+}
+
+// ConcurrencyLimitedCommand caps how many executions of a command may run at
+// once in a single guild.
+type ConcurrencyLimitedCommand interface {
+	MaxConcurrentPerGuild() int
+}
+
+// BackgroundCommand is a concurrency-limited command whose work carries on
+// after it has answered. The router runs it with Start instead of Execute and
+// keeps its slot in the guild until Start calls done, which it must do once
+// that work is over, or right away if it started none.
+type BackgroundCommand interface {
+	Start(s discordapi.Session, i *discordgo.InteractionCreate, done func()) error
+}
+
+type cooldownKey struct {
+	userID  string
+	command string
+}
+
+type inFlightKey struct {
+	guildID string
+	command string
+}
+
+// cooldownTracker keeps per-user cooldowns and per-guild in-flight counts in
+// memory. Expired cooldowns are swept periodically.
+type cooldownTracker struct {
+	readyAt  map[cooldownKey]time.Time
+	inFlight map[inFlightKey]int
+	mu       sync.Mutex
+}
+
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{
+		readyAt:  make(map[cooldownKey]time.Time),
+		inFlight: make(map[inFlightKey]int),
+	}
+}
+
+// use starts a cooldown for the user if none is active. Otherwise it returns
+// how long the user still has to wait.
+func (t *cooldownTracker) use(userID, command string, cooldown time.Duration, now time.Time) (time.Duration, bool) {
+	key := cooldownKey{userID: userID, command: command}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if readyAt, ok := t.readyAt[key]; ok && now.Before(readyAt) {
+		return readyAt.Sub(now), false
+	}
+
+	t.readyAt[key] = now.Add(cooldown)
+	return 0, true
+}
+
+// acquire reserves an execution slot for the command in the guild.
+func (t *cooldownTracker) acquire(guildID, command string, limit int) bool {
+	key := inFlightKey{guildID: guildID, command: command}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inFlight[key] >= limit {
+		return false
+	}
+
+	t.inFlight[key]++
+	return true
+}
+
+func (t *cooldownTracker) release(guildID, command string) {
+	key := inFlightKey{guildID: guildID, command: command}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inFlight[key] <= 1 {
+		delete(t.inFlight, key)
+		return
+	}
+	t.inFlight[key]--
+}
+
+func (t *cooldownTracker) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, readyAt := range t.readyAt {
+		if !now.Before(readyAt) {
+			delete(t.readyAt, key)
+		}
+	}
+}
+
+func (t *cooldownTracker) run() {
+	ticker := time.NewTicker(cooldownSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		t.sweep(now)
+	}
+}
+
+// formatWait rounds a remaining cooldown up to whole seconds for display.
+func formatWait(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
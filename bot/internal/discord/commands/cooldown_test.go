@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCooldownBoundaries(t *testing.T) {
+	const cooldown = 10 * time.Second
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	readyAt := start.Add(cooldown)
+
+	tracker := newCooldownTracker()
+	if _, ok := tracker.use("user", "playlist", cooldown, start); !ok {
+		t.Fatal("first use refused")
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantOK   bool
+		wantWait time.Duration
+	}{
+		{"straight after", start, false, cooldown},
+		{"a nanosecond early", readyAt.Add(-time.Nanosecond), false, time.Nanosecond},
+		{"exactly when ready", readyAt, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := tracker.use("user", "playlist", cooldown, tt.at)
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Errorf("use = %s, %v, want %s, %v", wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+
+	// Cooldowns are per user and per command.
+	if _, ok := tracker.use("other", "playlist", cooldown, start); !ok {
+		t.Error("another user waits on the first user's cooldown")
+	}
+	if _, ok := tracker.use("user", "repair", cooldown, start); !ok {
+		t.Error("another command waits on the playlist cooldown")
+	}
+}
+
+func TestCooldownSweepBoundary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newCooldownTracker()
+	tracker.use("user", "playlist", time.Minute, start)
+
+	tracker.sweep(start.Add(time.Minute - time.Nanosecond))
+	if len(tracker.readyAt) != 1 {
+		t.Fatal("sweep dropped a cooldown that hasn't run out")
+	}
+	tracker.sweep(start.Add(time.Minute))
+	if len(tracker.readyAt) != 0 {
+		t.Error("sweep kept a cooldown that ran out")
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 1},
+		{time.Nanosecond, 1},
+		{time.Second, 1},
+		{time.Second + time.Nanosecond, 2},
+		{12 * time.Second, 12},
+		{59*time.Second + 500*time.Millisecond, 60},
+	}
+	for _, tt := range tests {
+		if got := formatWait(tt.wait); got != tt.want {
+			t.Errorf("formatWait(%s) = %d, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrencySlots(t *testing.T) {
+	tracker := newCooldownTracker()
+	for n := 1; n <= 2; n++ {
+		if !tracker.acquire("guild", "playlist", 2) {
+			t.Fatalf("slot %d of 2 refused", n)
+		}
+	}
+	if tracker.acquire("guild", "playlist", 2) {
+		t.Fatal("a third slot was given out with a limit of 2")
+	}
+	if !tracker.acquire("other", "playlist", 2) {
+		t.Error("another guild shares the first guild's slots")
+	}
+
+	tracker.release("guild", "playlist")
+	if !tracker.acquire("guild", "playlist", 2) {
+		t.Error("a released slot can't be taken again")
+	}
+}
+
+// backgroundCommand answers at once and holds on to done until the test
+// calls it.
+type backgroundCommand struct {
+	stubCommand
+	done chan func()
+}
+
+func (c *backgroundCommand) MaxConcurrentPerGuild() int { return 1 }
+
+func (c *backgroundCommand) Start(s discordapi.Session, i *discordgo.InteractionCreate, done func()) error {
+	c.done <- done
+	return nil
+}
+
+func TestBackgroundCommandHoldsItsSlot(t *testing.T) {
+	session := newFakeSession(t)
+	router := NewRouter(session, permissions.NewManager(permissions.Config{}, nil), nil)
+	cmd := &backgroundCommand{stubCommand: stubCommand{name: "import"}, done: make(chan func(), 2)}
+	router.Register(cmd)
+	busy := i18n.T(testGuildID, "cooldown.busy", "import")
+
+	first := commandInteraction("a", "import")
+	router.Handle(first)
+	done := <-cmd.done
+
+	// Start has returned, but its work hasn't finished.
+	second := commandInteraction("b", "import")
+	router.Handle(second)
+	session.awaitContent(t, second, busy)
+	if _, reason := router.Admit(testGuildID, "c", "import"); reason != busy {
+		t.Errorf("Admit while busy = %q, want %q", reason, busy)
+	}
+
+	done()
+	done()
+
+	third := commandInteraction("b", "import")
+	router.Handle(third)
+	select {
+	case <-cmd.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command wasn't started again once the first run was done")
+	}
+	if _, reason := router.Admit(testGuildID, "c", "import"); reason != busy {
+		t.Errorf("Admit during the third run = %q, want %q", reason, busy)
+	}
+}
+
+func TestAdmitHoldsSlotUntilReleased(t *testing.T) {
+	session := newFakeSession(t)
+	router := NewRouter(session, permissions.NewManager(permissions.Config{}, nil), nil)
+	router.Register(&backgroundCommand{stubCommand: stubCommand{name: "import"}, done: make(chan func(), 1)})
+
+	release, reason := router.Admit(testGuildID, "a", "import")
+	if reason != "" {
+		t.Fatalf("first Admit = %q, want it let in", reason)
+	}
+	if _, reason := router.Admit(testGuildID, "b", "import"); reason == "" {
+		t.Fatal("second Admit let in while the first holds the only slot")
+	}
+
+	release()
+	second, reason := router.Admit(testGuildID, "b", "import")
+	if reason != "" {
+		t.Fatalf("Admit after release = %q, want it let in", reason)
+	}
+	second()
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
-	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
 	"sync"
 	"time"
 
@@ -9,10 +11,10 @@ import (
 )
 
 type DelMsgCommand struct {
-	session *discordgo.Session
+	session discordapi.Session
 }
 
-func NewDelMsgCommand(session *discordgo.Session) *DelMsgCommand {
+func NewDelMsgCommand(session discordapi.Session) *DelMsgCommand {
 	return &DelMsgCommand{
 		session: session,
 	}
@@ -26,6 +28,18 @@ func (c *DelMsgCommand) Description() string {
 	return "Bulk delete messages in the current channel"
 }
 
+func (c *DelMsgCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *DelMsgCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *DelMsgCommand) RequiredPermissions() int64 {
+	return discordgo.PermissionManageMessages
+}
+
 func (c *DelMsgCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{
 		{
@@ -39,31 +53,25 @@ func (c *DelMsgCommand) Options() []*discordgo.ApplicationCommandOption {
 	}
 }
 
-func (c *DelMsgCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Flags: discordgo.MessageFlagsEphemeral,
-		},
-	})
-	if err != nil {
-		return err
-	}
+func (c *DelMsgCommand) Ephemeral() bool {
+	return true
+}
 
+func (c *DelMsgCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
 	count := int(i.ApplicationCommandData().Options[0].IntValue())
 	channelID := i.ChannelID
 
 	messages, err := s.ChannelMessages(channelID, count, "", "", "")
 	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Failed to fetch messages."),
+			Content: stringPtr(i18n.T(i.GuildID, "delmsg.fetch_failed")),
 		})
 		return err
 	}
 
 	if len(messages) == 0 {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No messages found to delete."),
+			Content: stringPtr(i18n.T(i.GuildID, "delmsg.none")),
 		})
 		return err
 	}
@@ -126,9 +134,9 @@ func (c *DelMsgCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 
 	var responseContent string
 	if deleteErr != nil {
-		responseContent = fmt.Sprintf("⚠️ Partially completed: deleted %d messages, but encountered errors.", deletedCount)
+		responseContent = i18n.T(i.GuildID, "delmsg.partial", deletedCount)
 	} else {
-		responseContent = fmt.Sprintf("✅ Successfully deleted %d messages.", deletedCount)
+		responseContent = i18n.T(i.GuildID, "delmsg.done", deletedCount)
 	}
 
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
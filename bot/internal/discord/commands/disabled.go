@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"slices"
+	"sync"
+)
+
+// alwaysEnabled are the commands a guild can't turn off, so it can always
+// turn the others back on and find out what the bot can do.
+var alwaysEnabled = map[string]bool{
+	"commands": true,
+	"help":     true,
+	"status":   true,
+}
+
+// CanDisable reports whether a guild may turn the command called name off.
+func CanDisable(name string) bool {
+	return !alwaysEnabled[name]
+}
+
+// disabledCommands holds the commands each guild turned off with /commands.
+type disabledCommands struct {
+	guilds map[string]map[string]bool
+	mu     sync.RWMutex
+}
+
+func newDisabledCommands() *disabledCommands {
+	return &disabledCommands{
+		guilds: make(map[string]map[string]bool),
+	}
+}
+
+func (d *disabledCommands) has(guildID, name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.guilds[guildID][name]
+}
+
+func (d *disabledCommands) set(guildID, name string, disabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !disabled {
+		delete(d.guilds[guildID], name)
+		if len(d.guilds[guildID]) == 0 {
+			delete(d.guilds, guildID)
+		}
+		return
+	}
+
+	if d.guilds[guildID] == nil {
+		d.guilds[guildID] = make(map[string]bool)
+	}
+	d.guilds[guildID][name] = true
+}
+
+func (d *disabledCommands) list(guildID string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, 0, len(d.guilds[guildID]))
+	for name := range d.guilds[guildID] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (d *disabledCommands) forget(guildID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.guilds, guildID)
+}
+
+// LoadDisabledCommands sets the commands each guild turned off, as stored in
+// the database.
+func (r *Router) LoadDisabledCommands(disabled map[string][]string) {
+	for guildID, names := range disabled {
+		for _, name := range names {
+			r.SetCommandDisabled(guildID, name, true)
+		}
+	}
+}
+
+// SetCommandDisabled turns the command called name off or back on in
+// guildID. Commands that can't be disabled are left alone.
+func (r *Router) SetCommandDisabled(guildID, name string, disabled bool) {
+	if disabled && !CanDisable(name) {
+		return
+	}
+	r.disabled.set(guildID, name, disabled)
+}
+
+// CommandDisabled reports whether guildID turned the command called name off.
+func (r *Router) CommandDisabled(guildID, name string) bool {
+	return r.disabled.has(guildID, name)
+}
+
+// DisabledCommands returns the commands guildID turned off, by name.
+func (r *Router) DisabledCommands(guildID string) []string {
+	return r.disabled.list(guildID)
+}
+
+// ForgetDisabledCommands turns every command back on in guildID, after its
+// settings were purged.
+func (r *Router) ForgetDisabledCommands(guildID string) {
+	r.disabled.forget(guildID)
+}
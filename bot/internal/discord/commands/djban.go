@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DJBanCommand is the one-shot answer to someone flooding the queue: their
+// queued songs are removed and they can't queue anything for a while. Unlike
+// /blacklist, the block lifts by itself.
+type DJBanCommand struct {
+	blacklist *blacklist.List
+	guilds    *guilds.Registry
+	audit     *audit.Log
+}
+
+func NewDJBanCommand(blacklistList *blacklist.List, guildRegistry *guilds.Registry, auditLog *audit.Log) *DJBanCommand {
+	return &DJBanCommand{
+		blacklist: blacklistList,
+		guilds:    guildRegistry,
+		audit:     auditLog,
+	}
+}
+
+func (c *DJBanCommand) Name() string {
+	return "djban"
+}
+
+func (c *DJBanCommand) Description() string {
+	return "Remove a user's queued songs and stop them queueing for a while"
+}
+
+func (c *DJBanCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *DJBanCommand) Examples() []string {
+	return []string{
+		"/djban add user:@someone duration:1 hour",
+		"/djban list",
+	}
+}
+
+func (c *DJBanCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *DJBanCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Remove the user's queued songs and stop them queueing",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "The user",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "duration",
+					Description: "How long they can't queue",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "10 minutes", Value: 10},
+						{Name: "30 minutes", Value: 30},
+						{Name: "1 hour", Value: 60},
+						{Name: "6 hours", Value: 6 * 60},
+						{Name: "1 day", Value: 24 * 60},
+						{Name: "1 week", Value: 7 * 24 * 60},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "Show who can't queue right now and for how long",
+		},
+	}
+}
+
+func (c *DJBanCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *DJBanCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	sub := i.ApplicationCommandData().Options[0]
+	if sub.Name == "list" {
+		return c.respond(s, i, c.list(i.GuildID))
+	}
+
+	var user *discordgo.User
+	var minutes int64
+	for _, option := range sub.Options {
+		switch option.Name {
+		case "user":
+			user = discordapi.UserOption(s, option)
+		case "duration":
+			minutes = option.IntValue()
+		}
+	}
+	if user == nil || minutes <= 0 {
+		return nil
+	}
+	moderatorID := i.Member.User.ID
+
+	timeout, err := c.blacklist.AddTimeout(i.GuildID, user.ID, moderatorID, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to time out user", "user_id", user.ID, "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "djban.failed"))
+	}
+
+	removed := c.guilds.Get(i.GuildID).Music.RemoveRequestedBy(user.ID)
+
+	c.audit.Record(i.GuildID, moderatorID, audit.ActionDJBan,
+		fmt.Sprintf("<@%s> for %dm, %d removed", user.ID, minutes, removed))
+	return c.respond(s, i, i18n.T(i.GuildID, "djban.added", user.ID, timeout.ExpiresAt.Unix(), removed))
+}
+
+// list describes the running timeouts with the time each has left.
+func (c *DJBanCommand) list(guildID string) string {
+	timeouts := c.blacklist.Timeouts(guildID)
+	if len(timeouts) == 0 {
+		return i18n.T(guildID, "djban.none")
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(guildID, "djban.title", len(timeouts)))
+	for _, timeout := range timeouts {
+		b.WriteString(i18n.T(guildID, "djban.line", timeout.UserID,
+			formatUptime(time.Until(timeout.ExpiresAt).Truncate(time.Second)), timeout.ExpiresAt.Unix(), timeout.AddedBy))
+	}
+	return b.String()
+}
+
+func (c *DJBanCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// DJUnbanCommand lifts a /djban before it runs out.
+type DJUnbanCommand struct {
+	blacklist *blacklist.List
+	audit     *audit.Log
+}
+
+func NewDJUnbanCommand(blacklistList *blacklist.List, auditLog *audit.Log) *DJUnbanCommand {
+	return &DJUnbanCommand{
+		blacklist: blacklistList,
+		audit:     auditLog,
+	}
+}
+
+func (c *DJUnbanCommand) Name() string {
+	return "djunban"
+}
+
+func (c *DJUnbanCommand) Description() string {
+	return "Let a user queue songs again before their /djban runs out"
+}
+
+func (c *DJUnbanCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *DJUnbanCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *DJUnbanCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        "user",
+			Description: "The user",
+			Required:    true,
+		},
+	}
+}
+
+func (c *DJUnbanCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *DJUnbanCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	user := discordapi.UserOption(s, i.ApplicationCommandData().Options[0])
+
+	removed, err := c.blacklist.RemoveTimeout(i.GuildID, user.ID)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to lift timeout", "user_id", user.ID, "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "djban.failed"))
+	}
+	if !removed {
+		return c.respond(s, i, i18n.T(i.GuildID, "djban.not_banned", user.ID))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionDJUnban, "<@"+user.ID+">")
+	return c.respond(s, i, i18n.T(i.GuildID, "djban.removed", user.ID))
+}
+
+func (c *DJUnbanCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
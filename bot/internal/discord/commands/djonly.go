@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type DJOnlyCommand struct {
+	permissionManager *permissions.Manager
+	dbManager         *config.DatabaseManager
+	audit             *audit.Log
+}
+
+func NewDJOnlyCommand(permissionManager *permissions.Manager, dbManager *config.DatabaseManager, auditLog *audit.Log) *DJOnlyCommand {
+	return &DJOnlyCommand{
+		permissionManager: permissionManager,
+		dbManager:         dbManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *DJOnlyCommand) Name() string {
+	return "djonly"
+}
+
+func (c *DJOnlyCommand) Description() string {
+	return "Show or change whether only DJs can control the music"
+}
+
+func (c *DJOnlyCommand) Category() Category {
+	return CategoryModeration
+}
+
+func (c *DJOnlyCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *DJOnlyCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "mode",
+			Description: "Turn DJ-only mode on or off",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "on", Value: "on"},
+				{Name: "off", Value: "off"},
+			},
+		},
+	}
+}
+
+func (c *DJOnlyCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, permissions.LevelDJ)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		key := "djonly.current_off"
+		if c.permissionManager.DJOnly(i.GuildID) {
+			key = "djonly.current_on"
+		}
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, key, roleName)),
+		})
+		return err
+	}
+
+	enabled := options[0].StringValue() == "on"
+
+	err := c.dbManager.SaveDJOnly(i.GuildID, enabled)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "djonly.save_failed")),
+		})
+		return err
+	}
+	c.permissionManager.SetDJOnly(i.GuildID, enabled)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionDJOnly, options[0].StringValue())
+
+	key := "djonly.disabled"
+	if enabled {
+		key = "djonly.enabled"
+	}
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, key, roleName)),
+	})
+	return err
+}
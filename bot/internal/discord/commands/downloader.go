@@ -0,0 +1,264 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/socket"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// pendingRequestsShown bounds the /downloader pending list to what fits
+	// in one message.
+	pendingRequestsShown = 10
+
+	downloaderCancelAllID = "downloader_cancel_all"
+
+	downloaderCancelTimeout = 5 * time.Second
+)
+
+// DownloaderCommand lets admins look into the connection to the downloader
+// and kick it when it got stuck, e.g. after the downloader was restarted.
+type DownloaderCommand struct {
+	socketClient      *socket.Client
+	guilds            *guilds.Registry
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewDownloaderCommand(socketClient *socket.Client, guildRegistry *guilds.Registry, permissionManager *permissions.Manager, auditLog *audit.Log) *DownloaderCommand {
+	return &DownloaderCommand{
+		socketClient:      socketClient,
+		guilds:            guildRegistry,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *DownloaderCommand) Name() string {
+	return "downloader"
+}
+
+func (c *DownloaderCommand) Description() string {
+	return "Check or reconnect the connection to the downloader"
+}
+
+func (c *DownloaderCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *DownloaderCommand) Examples() []string {
+	return []string{
+		"/downloader status",
+		"/downloader reconnect",
+		"/downloader pending",
+	}
+}
+
+func (c *DownloaderCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *DownloaderCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "status",
+			Description: "Show the connection state and a fresh round-trip latency",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "reconnect",
+			Description: "Drop the connection and connect again right away",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "pending",
+			Description: "List the requests the downloader hasn't answered yet",
+		},
+	}
+}
+
+func (c *DownloaderCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if c.socketClient == nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "downloader.disabled"))
+	}
+
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "status":
+		return c.respond(s, i, c.status(i.GuildID))
+	case "reconnect":
+		return c.reconnect(s, i)
+	case "pending":
+		content, components := c.pending(i.GuildID)
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:    &content,
+			Components: &components,
+		})
+		return err
+	}
+	return nil
+}
+
+func (c *DownloaderCommand) status(guildID string) string {
+	lastPong := i18n.T(guildID, "status.never")
+	if last := c.socketClient.LastDownloaderPing(); !last.IsZero() {
+		lastPong = i18n.T(guildID, "status.ago", formatUptime(time.Since(last)))
+	}
+
+	latency := i18n.T(guildID, "downloader.latency_disconnected")
+	if c.socketClient.IsConnected() {
+		started := time.Now()
+		if _, err := c.socketClient.SendPingWithResponse(); err != nil {
+			latency = i18n.T(guildID, "downloader.latency_failed", err.Error())
+		} else {
+			latency = fmt.Sprintf("%dms", time.Since(started).Milliseconds())
+		}
+	}
+
+	return i18n.T(guildID, "downloader.status",
+		c.socketClient.GetDownloaderStatus(), lastPong, latency, len(c.socketClient.PendingRequests()))
+}
+
+func (c *DownloaderCommand) reconnect(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if err := c.socketClient.Reconnect(); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to reconnect to the downloader", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "downloader.reconnect_failed", err.Error()))
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, "downloader.reconnected"))
+}
+
+// pending lists the requests waiting for an answer, with a button to cancel
+// them all.
+func (c *DownloaderCommand) pending(guildID string) (string, []discordgo.MessageComponent) {
+	requests := c.socketClient.PendingRequests()
+	if len(requests) == 0 {
+		return i18n.T(guildID, "downloader.pending_none"), []discordgo.MessageComponent{}
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(guildID, "downloader.pending_title", len(requests)))
+	for idx, request := range requests {
+		if idx == pendingRequestsShown {
+			b.WriteString(i18n.T(guildID, "downloader.pending_more", len(requests)-pendingRequestsShown))
+			break
+		}
+		line := i18n.T(guildID, "downloader.pending_line", request.ID, request.Command, formatUptime(time.Since(request.Started)))
+		if request.URL != "" {
+			line += " - <" + request.URL + ">"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.DangerButton,
+					Label:    i18n.T(guildID, "downloader.cancel_all"),
+					CustomID: downloaderCancelAllID,
+				},
+			},
+		},
+	}
+	return b.String(), components
+}
+
+func (c *DownloaderCommand) ComponentPrefix() string {
+	return downloaderCancelAllID
+}
+
+// HandleComponent cancels every pending request. The router doesn't check
+// permissions for components, so the admin check is repeated here.
+func (c *DownloaderCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if i.Member == nil || i.Member.User == nil || c.socketClient == nil {
+		return nil
+	}
+
+	allowed, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, c.RequiredLevel())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, c.RequiredLevel())
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Content: i18n.T(i.GuildID, "permissions.denied", roleName, c.Name()),
+			},
+		})
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		return err
+	}
+
+	cancelled := c.cancelAll(i.GuildID)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionDownloaderCancel, fmt.Sprintf("%d", cancelled))
+
+	content, components := c.pending(i.GuildID)
+	content = i18n.T(i.GuildID, "downloader.cancelled", cancelled) + "\n\n" + content
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
+	return err
+}
+
+// cancelAll cancels the pending requests and returns how many there were.
+// Song downloads go through the music manager, so the songs waiting on them
+// are let go and their requesters told; whatever is left, such as searches,
+// is cancelled on the socket directly.
+func (c *DownloaderCommand) cancelAll(guildID string) int {
+	cancelled := len(c.cancellable())
+	c.guilds.Get(guildID).Music.CancelDownloads()
+
+	remaining := c.cancellable()
+	if len(remaining) == 0 {
+		return cancelled
+	}
+
+	ids := make([]string, 0, len(remaining))
+	for _, request := range remaining {
+		ids = append(ids, request.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), downloaderCancelTimeout)
+	defer cancel()
+	if err := c.socketClient.CancelRequest(ctx, ids...); err != nil {
+		logger.ForCommand(guildID, c.Name()).Error("Downloader did not acknowledge the cancellation", "error", err)
+	}
+	return cancelled
+}
+
+// cancellable is the pending requests other than pings and cancellations,
+// which answer quickly on their own.
+func (c *DownloaderCommand) cancellable() []socket.PendingRequest {
+	var requests []socket.PendingRequest
+	for _, request := range c.socketClient.PendingRequests() {
+		if request.Command != "ping" && request.Command != "cancel" {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (c *DownloaderCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
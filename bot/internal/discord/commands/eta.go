@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ETACommand struct {
+	guilds *guilds.Registry
+}
+
+func NewETACommand(guildRegistry *guilds.Registry) *ETACommand {
+	return &ETACommand{
+		guilds: guildRegistry,
+	}
+}
+
+func (c *ETACommand) Name() string {
+	return "eta"
+}
+
+func (c *ETACommand) Description() string {
+	return "Show when a song in the queue is expected to play"
+}
+
+func (c *ETACommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ETACommand) Examples() []string {
+	return []string{
+		"/eta",
+		"/eta position:5",
+	}
+}
+
+func (c *ETACommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "position",
+			Description: "Position in the queue (defaults to your next queued song)",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+		},
+	}
+}
+
+func (c *ETACommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(c.message(i)),
+	})
+	return err
+}
+
+func (c *ETACommand) message(i *discordgo.InteractionCreate) string {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	upcoming := len(musicManager.GetUpcomingItems())
+	if upcoming == 0 {
+		return i18n.T(i.GuildID, "eta.empty")
+	}
+
+	position := 0
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		position = int(options[0].IntValue())
+	} else {
+		position = c.ownNextPosition(i.GuildID, i.Member.User.ID)
+		if position == 0 {
+			return i18n.T(i.GuildID, "eta.none_yours")
+		}
+	}
+
+	eta, err := musicManager.ETA(position)
+	if errors.Is(err, music.ErrOutOfRange) {
+		return i18n.T(i.GuildID, "eta.out_of_range", upcoming)
+	}
+	if err != nil {
+		return i18n.T(i.GuildID, "eta.failed")
+	}
+
+	if !eta.Known {
+		return i18n.T(i.GuildID, "eta.unknown", eta.Position)
+	}
+	return etaLine(i.GuildID, eta)
+}
+
+// ownNextPosition is the queue position of the user's next song, or 0.
+func (c *ETACommand) ownNextPosition(guildID, userID string) int {
+	for idx, item := range c.guilds.Get(guildID).Music.GetUpcomingItems() {
+		if item.RequestedBy == userID {
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+// etaLine describes an ETA both as a rough wait and as a Discord timestamp,
+// which every reader sees counting down in their own time zone. Songs of
+// unknown length before it make the wait a lower bound, which is said so.
+func etaLine(guildID string, eta music.ETA) string {
+	if eta.Unknown > 0 {
+		return i18n.T(guildID, "eta.line_at_least", eta.Position, aboutDuration(guildID, eta.Wait), eta.At(time.Now()).Unix(), eta.Unknown)
+	}
+	return i18n.T(guildID, "eta.line", eta.Position, aboutDuration(guildID, eta.Wait), eta.At(time.Now()).Unix())
+}
+
+// aboutDuration rounds d to whole minutes for display.
+func aboutDuration(guildID string, d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	switch {
+	case minutes < 1:
+		return i18n.T(guildID, "eta.under_minute")
+	case minutes == 1:
+		return i18n.T(guildID, "eta.minute")
+	case minutes < 60:
+		return i18n.T(guildID, "eta.minutes", minutes)
+	default:
+		return i18n.T(guildID, "eta.hours", minutes/60, minutes%60)
+	}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ExportQueueCommand struct {
+	guilds *guilds.Registry
+}
+
+func NewExportQueueCommand(guildRegistry *guilds.Registry) *ExportQueueCommand {
+	return &ExportQueueCommand{
+		guilds: guildRegistry,
+	}
+}
+
+func (c *ExportQueueCommand) Name() string {
+	return "exportqueue"
+}
+
+func (c *ExportQueueCommand) Description() string {
+	return "Save the queue as a file that /importqueue can load"
+}
+
+func (c *ExportQueueCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ExportQueueCommand) Cooldown() time.Duration {
+	return 10 * time.Second
+}
+
+func (c *ExportQueueCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *ExportQueueCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	data, exported, skipped := c.guilds.Get(i.GuildID).Music.ExportQueue()
+	if exported == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "exportqueue.empty")),
+		})
+		return err
+	}
+
+	content := i18n.T(i.GuildID, "exportqueue.done", exported)
+	if skipped > 0 {
+		content += i18n.T(i.GuildID, "exportqueue.skipped_uploads", skipped)
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+		Files: []*discordgo.File{
+			{
+				Name:        fmt.Sprintf("queue-%s.txt", time.Now().Format("2006-01-02")),
+				ContentType: "text/plain",
+				Reader:      bytes.NewReader(data),
+			},
+		},
+	})
+	return err
+}
@@ -0,0 +1,307 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultFailureCount = 10
+	maxFailureCount     = 25
+
+	// maxFailureReason is how much of the downloader's error each line shows.
+	maxFailureReason = 150
+
+	failuresRetryID = "failures_retry:"
+)
+
+type FailuresCommand struct {
+	guilds            *guilds.Registry
+	dbManager         *config.DatabaseManager
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewFailuresCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, permissionManager *permissions.Manager, auditLog *audit.Log) *FailuresCommand {
+	return &FailuresCommand{
+		guilds:            guildRegistry,
+		dbManager:         dbManager,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *FailuresCommand) Name() string {
+	return "failures"
+}
+
+func (c *FailuresCommand) Description() string {
+	return "Show the latest downloads that failed"
+}
+
+func (c *FailuresCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *FailuresCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *FailuresCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "count",
+			Description: "How many failures to show (default 10)",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    maxFailureCount,
+		},
+	}
+}
+
+func (c *FailuresCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *FailuresCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	count := defaultFailureCount
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "count" {
+			count = int(option.IntValue())
+		}
+	}
+
+	failures, err := c.dbManager.GetDownloadFailures(i.GuildID, count)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read download failures", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.failed")),
+		})
+		return err
+	}
+
+	if len(failures) == 0 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.empty")),
+		})
+		return err
+	}
+
+	var b strings.Builder
+	var buttons []discordgo.MessageComponent
+	for k, failure := range failures {
+		b.WriteString(failureLine(i.GuildID, k+1, failure) + "\n")
+
+		// Tracks of a playlist can't be requested on their own.
+		if failure.Track < 0 {
+			buttons = append(buttons, discordgo.Button{
+				Style:    discordgo.SecondaryButton,
+				Label:    i18n.T(i.GuildID, "failures.retry_button", k+1),
+				CustomID: failuresRetryID + strconv.FormatInt(failure.ID, 10),
+			})
+		}
+	}
+
+	var rows []discordgo.MessageComponent
+	for len(buttons) > 0 {
+		n := min(len(buttons), 5)
+		rows = append(rows, discordgo.ActionsRow{Components: buttons[:n]})
+		buttons = buttons[n:]
+	}
+
+	messages := render.Listing(i.GuildID, i18n.T(i.GuildID, "failures.title"), b.String(), render.ColorError)
+	return sendChunked(s, i, messages, rows, c.Ephemeral())
+}
+
+// failureLine describes the failure shown at position index.
+func failureLine(guildID string, index int, failure config.DownloadFailure) string {
+	target := failure.URL
+	if failure.Track >= 0 {
+		target = i18n.T(guildID, "failures.track", failure.Track+1, failure.URL)
+	}
+
+	requester := i18n.T(guildID, "failures.unknown_user")
+	if failure.RequestedBy != "" {
+		requester = "<@" + failure.RequestedBy + ">"
+	}
+
+	reason := strings.TrimSpace(failure.Error)
+	if len([]rune(reason)) > maxFailureReason {
+		reason = string([]rune(reason)[:maxFailureReason-1]) + "…"
+	}
+
+	return i18n.T(guildID, "failures.line", index, failure.At.Unix(), failure.Class, target, requester, reason)
+}
+
+func (c *FailuresCommand) ComponentPrefix() string {
+	return "failures_"
+}
+
+// HandleComponent retries the failure behind a button of the list. The router
+// doesn't check permissions for components, so the admin check is repeated
+// here.
+func (c *FailuresCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if i.Member == nil || i.Member.User == nil {
+		return nil
+	}
+
+	customID := i.MessageComponentData().CustomID
+	if !strings.HasPrefix(customID, failuresRetryID) {
+		return nil
+	}
+
+	allowed, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, c.RequiredLevel())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, c.RequiredLevel())
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Content: i18n.T(i.GuildID, "permissions.denied", roleName, c.Name()),
+			},
+		})
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(customID, failuresRetryID), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid failure button %q: %w", customID, err)
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		return err
+	}
+
+	failure, err := c.dbManager.GetDownloadFailure(i.GuildID, id)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read download failure", "error", err, "id", id)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.not_found")),
+		})
+		return err
+	}
+
+	return retryFailure(s, i, c.guilds, c.audit, *failure)
+}
+
+type RetryFailedCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewRetryFailedCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *RetryFailedCommand {
+	return &RetryFailedCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *RetryFailedCommand) Name() string {
+	return "retryfailed"
+}
+
+func (c *RetryFailedCommand) Description() string {
+	return "Try a failed download again and queue it"
+}
+
+func (c *RetryFailedCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *RetryFailedCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *RetryFailedCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "index",
+			Description: "Number of the failure in /failures",
+			Required:    true,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    maxFailureCount,
+		},
+	}
+}
+
+func (c *RetryFailedCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	index := int(i.ApplicationCommandData().Options[0].IntValue())
+
+	failures, err := c.dbManager.GetDownloadFailures(i.GuildID, index)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read download failures", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.failed")),
+		})
+		return err
+	}
+	if index > len(failures) {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.not_found")),
+		})
+		return err
+	}
+
+	return retryFailure(s, i, c.guilds, c.audit, failures[index-1])
+}
+
+// retryFailure requests the song of failure again on behalf of the user who
+// asked, reporting the outcome through the deferred response of i.
+func retryFailure(s discordapi.Session, i *discordgo.InteractionCreate, guildRegistry *guilds.Registry, auditLog *audit.Log, failure config.DownloadFailure) error {
+	if failure.Track >= 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.playlist_track")),
+		})
+		return err
+	}
+
+	guild := guildRegistry.Get(i.GuildID)
+	if guild.State.GetCurrentChannel() == "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "failures.not_connected")),
+		})
+		return err
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "play.downloading", failure.URL)),
+	})
+	if err != nil {
+		return err
+	}
+
+	userID := i.Member.User.ID
+	limits := guild.Music.DownloadLimits(i.GuildID)
+
+	go func() {
+		followUp := newRequestFollowUp(s, i)
+		err := guild.Music.RequestSong(failure.URL, userID, limits, followUp)
+		if err != nil {
+			followUp.Finish(requestErrorMessage(i.GuildID, err))
+			return
+		}
+		auditLog.Record(i.GuildID, userID, audit.ActionRetryFailed, failure.URL)
+	}()
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type FilterCommand struct {
+	guilds       *guilds.Registry
+	stateManager *state.Manager
+	dbManager    *config.DatabaseManager
+	audit        *audit.Log
+}
+
+func NewFilterCommand(guildRegistry *guilds.Registry, stateManager *state.Manager, dbManager *config.DatabaseManager, auditLog *audit.Log) *FilterCommand {
+	return &FilterCommand{
+		guilds:       guildRegistry,
+		stateManager: stateManager,
+		dbManager:    dbManager,
+		audit:        auditLog,
+	}
+}
+
+func (c *FilterCommand) Name() string {
+	return "filter"
+}
+
+func (c *FilterCommand) Description() string {
+	return "Show or change the audio filter songs play with"
+}
+
+func (c *FilterCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *FilterCommand) Examples() []string {
+	return []string{
+		"/filter preset:nightcore",
+		"/filter preset:off",
+	}
+}
+
+func (c *FilterCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *FilterCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *FilterCommand) Options() []*discordgo.ApplicationCommandOption {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(music.Filters))
+	for _, filter := range music.Filters {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(filter), Value: string(filter)})
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "preset",
+			Description: "Filter preset (radio and live streams always play unfiltered)",
+			Required:    false,
+			Choices:     choices,
+		},
+	}
+}
+
+func (c *FilterCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if c.stateManager.GetConfig().FiltersDisabled {
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.disabled"))
+	}
+
+	guild := c.guilds.Get(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		current, _ := music.ParseFilter(guild.State.GetFilter())
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.current", current))
+	}
+
+	filter, ok := music.ParseFilter(options[0].StringValue())
+	if !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.unknown"))
+	}
+
+	if err := c.dbManager.SaveFilter(i.GuildID, string(filter)); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save filter", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.save_failed"))
+	}
+
+	guild.State.SetFilter(string(filter))
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionFilter, string(filter))
+
+	if err := guild.Music.ApplyFilter(); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to apply filter to the current song", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.set_next", filter))
+	}
+
+	if filter == music.FilterOff {
+		return c.respond(s, i, i18n.T(i.GuildID, "filter.off"))
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, "filter.set", filter))
+}
+
+func (c *FilterCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
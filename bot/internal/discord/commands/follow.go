@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type FollowCommand struct {
+	guilds *guilds.Registry
+}
+
+func NewFollowCommand(guildRegistry *guilds.Registry) *FollowCommand {
+	return &FollowCommand{
+		guilds: guildRegistry,
+	}
+}
+
+func (c *FollowCommand) Name() string {
+	return "follow"
+}
+
+func (c *FollowCommand) Description() string {
+	return "Toggle whether the bot moves with you between voice channels"
+}
+
+func (c *FollowCommand) Category() Category {
+	return CategoryVoice
+}
+
+func (c *FollowCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *FollowCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *FollowCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	userID := i.Member.User.ID
+
+	if guild.State.GetFollowedUser() == userID {
+		guild.State.SetFollowedUser("")
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "follow.disabled")),
+		})
+		return err
+	}
+
+	// Following someone else ends when another DJ takes over.
+	guild.State.SetFollowedUser(userID)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "follow.enabled")),
+	})
+	return err
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music"
+	"musicbot/internal/state"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// playlistProgressInterval spaces out progress edits so a fast playlist
+// doesn't run into Discord's rate limits.
+const playlistProgressInterval = 3 * time.Second
+
+// skipReasonKeys names each music.SkipReason in the playlist summary.
+var skipReasonKeys = [...]string{
+	music.SkipUnavailable:   "playlist.skip_unavailable",
+	music.SkipPrivate:       "playlist.skip_private",
+	music.SkipRegionBlocked: "playlist.skip_region_blocked",
+	music.SkipAgeRestricted: "playlist.skip_age_restricted",
+	music.SkipTooLong:       "playlist.skip_too_long",
+	music.SkipTooLarge:      "playlist.skip_too_large",
+	music.SkipFailed:        "playlist.skip_failed",
+}
+
+// requestFollowUp tells the requester how their song or playlist request
+// ended, through the progress reporter of the interaction they asked in.
+type requestFollowUp struct {
+	*progressReporter
+	lastProgress time.Time
+}
+
+func newRequestFollowUp(s discordapi.Session, i *discordgo.InteractionCreate) *requestFollowUp {
+	return &requestFollowUp{progressReporter: newProgressReporter(s, i)}
+}
+
+func (f *requestFollowUp) Queued(song *state.Song, eta music.ETA) {
+	content := i18n.T(f.guildID, "play.queued", song.Title)
+	switch {
+	case !eta.Known:
+		content += "\n" + i18n.T(f.guildID, "eta.unknown", eta.Position)
+	case eta.Wait > 0 || eta.Unknown > 0:
+		content += "\n" + etaLine(f.guildID, eta)
+	}
+	f.Finish(content)
+}
+
+func (f *requestFollowUp) Failed(err error) {
+	f.Finish(requestErrorMessage(f.guildID, err))
+}
+
+func (f *requestFollowUp) PlaylistProgress(done, failed, total int) {
+	if done < total && time.Since(f.lastProgress) < playlistProgressInterval {
+		return
+	}
+	f.lastProgress = time.Now()
+	f.Update(i18n.T(f.guildID, "playlist.progress", done, total, failed))
+}
+
+func (f *requestFollowUp) PlaylistDone(added, duplicates int, skipped music.Skipped) {
+	content := i18n.T(f.guildID, "playlist.done", added)
+	if duplicates > 0 {
+		content = i18n.T(f.guildID, "playlist.done_duplicates", added, duplicates)
+	}
+	if total := skipped.Total(); total > 0 {
+		content += i18n.T(f.guildID, "playlist.done_skipped", total, skippedSummary(f.guildID, skipped))
+	}
+	f.Finish(content)
+}
+
+// skippedSummary lists why tracks were skipped, e.g. "2 unavailable, 1 too
+// long".
+func skippedSummary(guildID string, skipped music.Skipped) string {
+	var parts []string
+	for reason, count := range skipped {
+		if count > 0 {
+			parts = append(parts, i18n.T(guildID, skipReasonKeys[reason], count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
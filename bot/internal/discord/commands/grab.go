@@ -0,0 +1,390 @@
+package commands
+
+import (
+	"database/sql"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// grabsShown is how many of a user's latest grabs /grabs lists.
+	grabsShown = 10
+
+	// maxEmbedTitle is the longest title Discord accepts on an embed.
+	maxEmbedTitle = 256
+
+	grabsQueueID = "grabs_queue:"
+)
+
+// GrabCommand saves what is playing for the user who asks: it is sent to
+// them in a DM and kept in their grabs, which /grabs lists.
+type GrabCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+}
+
+func NewGrabCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) *GrabCommand {
+	return &GrabCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+	}
+}
+
+func (c *GrabCommand) Name() string {
+	return "grab"
+}
+
+func (c *GrabCommand) Description() string {
+	return "Save the current track and get it sent to you in a DM"
+}
+
+func (c *GrabCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *GrabCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *GrabCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *GrabCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	grab, ok := c.currentTrack(i.GuildID)
+	if !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "grab.nothing_playing"))
+	}
+	grab.UserID = i.Member.User.ID
+	grab.GuildID = i.GuildID
+	grab.GrabbedAt = time.Now()
+
+	if _, err := c.dbManager.AddGrab(grab); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save grab", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "grab.save_failed"))
+	}
+
+	embed := grabEmbed(i.GuildID, grab)
+
+	channel, err := s.UserChannelCreate(grab.UserID)
+	if err == nil {
+		_, err = s.ChannelMessageSendEmbed(channel.ID, embed)
+	}
+	if err != nil {
+		// Most likely the user doesn't accept DMs from server members, so
+		// they get the embed here instead.
+		logger.ForCommand(i.GuildID, c.Name()).Debug("Failed to DM grab", "user_id", grab.UserID, "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "grab.dm_failed")),
+			Embeds:  &[]*discordgo.MessageEmbed{embed},
+		})
+		return err
+	}
+
+	return c.respond(s, i, i18n.T(i.GuildID, "grab.sent"))
+}
+
+// currentTrack describes what the guild is playing: the current song in DJ
+// mode, otherwise the radio stream's announced title or, failing that, its
+// name.
+func (c *GrabCommand) currentTrack(guildID string) (config.Grab, bool) {
+	guild := c.guilds.Get(guildID)
+
+	if guild.State.GetBotState() == state.StateDJ {
+		song := guild.Music.GetCurrentSong()
+		if song == nil {
+			return config.Grab{}, false
+		}
+		return config.Grab{
+			Title:        song.Title,
+			Artist:       song.Artist,
+			URL:          song.URL,
+			ThumbnailURL: song.ThumbnailURL,
+			Duration:     song.Duration,
+		}, true
+	}
+
+	if !guild.Radio.IsPlaying() {
+		return config.Grab{}, false
+	}
+
+	grab := config.Grab{
+		Title:   guild.Radio.StreamTitle(),
+		Artist:  guild.Radio.StreamName(),
+		URL:     guild.State.GetRadioStream(),
+		IsRadio: true,
+	}
+	if grab.Title == "" {
+		grab.Title = grab.Artist
+		grab.Artist = ""
+	}
+	return grab, true
+}
+
+func (c *GrabCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// grabEmbed shows a saved track the way /grab sends it.
+func grabEmbed(guildID string, grab config.Grab) *discordgo.MessageEmbed {
+	title := grab.Title
+	if len([]rune(title)) > maxEmbedTitle {
+		title = string([]rune(title)[:maxEmbedTitle-1]) + "…"
+	}
+
+	duration := i18n.T(guildID, "common.unknown_duration")
+	if grab.IsRadio {
+		duration = i18n.T(guildID, "common.live")
+	} else if grab.Duration > 0 {
+		duration = formatTimestamp(grab.Duration)
+	}
+
+	artist := grab.Artist
+	if artist == "" {
+		artist = i18n.T(guildID, "grab.unknown_artist")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		URL:   grab.URL,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(guildID, "grab.artist"), Value: artist, Inline: true},
+			{Name: i18n.T(guildID, "grab.duration"), Value: duration, Inline: true},
+			{Name: i18n.T(guildID, "grab.link"), Value: grab.URL},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: i18n.T(guildID, "grab.footer"),
+		},
+		Color:     0x5865F2,
+		Timestamp: grab.GrabbedAt.Format(time.RFC3339),
+	}
+	if grab.ThumbnailURL != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: grab.ThumbnailURL}
+	}
+	return embed
+}
+
+// GrabsCommand lists the latest tracks the user grabbed, with buttons to
+// queue them again.
+type GrabsCommand struct {
+	guilds            *guilds.Registry
+	dbManager         *config.DatabaseManager
+	permissionManager *permissions.Manager
+	blacklist         *blacklist.List
+	audit             *audit.Log
+}
+
+func NewGrabsCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, permissionManager *permissions.Manager, blacklistList *blacklist.List, auditLog *audit.Log) *GrabsCommand {
+	return &GrabsCommand{
+		guilds:            guildRegistry,
+		dbManager:         dbManager,
+		permissionManager: permissionManager,
+		blacklist:         blacklistList,
+		audit:             auditLog,
+	}
+}
+
+func (c *GrabsCommand) Name() string {
+	return "grabs"
+}
+
+func (c *GrabsCommand) Description() string {
+	return "Show the tracks you saved with /grab"
+}
+
+func (c *GrabsCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *GrabsCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *GrabsCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *GrabsCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	grabs, err := c.dbManager.GetGrabs(i.Member.User.ID, grabsShown)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read grabs", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "grabs.failed")),
+		})
+		return err
+	}
+
+	if len(grabs) == 0 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "grabs.empty")),
+		})
+		return err
+	}
+
+	// Lines are cut once the embed description would go over its limit.
+	const maxDescriptionLength = 4096
+	description := ""
+	var buttons []discordgo.MessageComponent
+	for k, grab := range grabs {
+		line := grabLine(i.GuildID, k+1, grab) + "\n"
+		if len(description)+len(line) > maxDescriptionLength {
+			break
+		}
+		description += line
+
+		// A radio stream plays whatever is on now, not what was grabbed.
+		if !grab.IsRadio {
+			buttons = append(buttons, discordgo.Button{
+				Style:    discordgo.SecondaryButton,
+				Label:    i18n.T(i.GuildID, "grabs.queue_button", k+1),
+				CustomID: grabsQueueID + strconv.FormatInt(grab.ID, 10),
+			})
+		}
+	}
+
+	var rows []discordgo.MessageComponent
+	for len(buttons) > 0 {
+		n := min(len(buttons), 5)
+		rows = append(rows, discordgo.ActionsRow{Components: buttons[:n]})
+		buttons = buttons[n:]
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       i18n.T(i.GuildID, "grabs.title"),
+				Description: description,
+				Color:       0x5865F2,
+			},
+		},
+		Components: &rows,
+	})
+	return err
+}
+
+// grabLine describes the grab shown at position index.
+func grabLine(guildID string, index int, grab config.Grab) string {
+	title := grab.Title
+	if len([]rune(title)) > maxEmbedTitle {
+		title = string([]rune(title)[:maxEmbedTitle-1]) + "…"
+	}
+	if grab.Artist != "" {
+		title += " - " + grab.Artist
+	}
+
+	line := i18n.T(guildID, "grabs.line", index, title, grab.URL, grab.GrabbedAt.Unix())
+	if grab.IsRadio {
+		line += i18n.T(guildID, "grabs.flag_radio")
+	}
+	return line
+}
+
+func (c *GrabsCommand) ComponentPrefix() string {
+	return "grabs_"
+}
+
+// HandleComponent queues the grab behind a button of the list in the guild
+// the button is pressed in. The router doesn't check permissions for
+// components, so DJ-only mode is enforced here as it is for /play.
+func (c *GrabsCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	if i.Member == nil || i.Member.User == nil {
+		return nil
+	}
+
+	customID := i.MessageComponentData().CustomID
+	if !strings.HasPrefix(customID, grabsQueueID) {
+		return nil
+	}
+	userID := i.Member.User.ID
+
+	if c.permissionManager.DJOnly(i.GuildID) {
+		allowed, err := c.permissionManager.HasPermission(s, i.GuildID, userID, permissions.LevelDJ)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, permissions.LevelDJ)
+			return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Flags:   discordgo.MessageFlagsEphemeral,
+					Content: i18n.T(i.GuildID, "permissions.dj_only", roleName, c.Name()),
+				},
+			})
+		}
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(customID, grabsQueueID), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid grab button %q: %w", customID, err)
+	}
+
+	grab, err := c.dbManager.GetGrab(userID, id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to read grab", "error", err, "id", id)
+		}
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Content: i18n.T(i.GuildID, "grabs.not_found"),
+			},
+		})
+	}
+
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, userID, grab.URL); blocked {
+		return respondBlacklisted(s, i, reason)
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		return err
+	}
+
+	if message := joinUserForRequest(s, c.guilds, c.guilds.Get(i.GuildID), userID); message != "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(message),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "play.downloading", grab.URL)),
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		limits := musicManager.DownloadLimits(i.GuildID)
+		followUp := newRequestFollowUp(s, i)
+		err := musicManager.RequestSong(grab.URL, userID, limits, followUp)
+		if err != nil {
+			followUp.Finish(requestErrorMessage(i.GuildID, err))
+			return
+		}
+		c.audit.Record(i.GuildID, userID, audit.ActionPlay, grab.URL)
+	}()
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/radio"
+	"musicbot/internal/socket"
+	"musicbot/internal/socket/sockettest"
+	"musicbot/internal/state"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	testGuildID   = "guild"
+	testChannelID = "text"
+	testVoiceID   = "voice"
+)
+
+// fakeSession stands in for Discord. It keeps every response the commands
+// send and answers lookups from its cache, which holds the test guild and
+// whoever was put in voice. Calls the fake doesn't implement panic through
+// the nil embedded Session.
+type fakeSession struct {
+	discordapi.Session
+
+	cache *discordgo.State
+
+	// delay is how long every call to Discord takes.
+	delay time.Duration
+
+	mu        sync.Mutex
+	responses []response
+	changed   chan struct{}
+}
+
+// response is an interaction response, an edit of one, or a channel
+// message, reduced to what tests look at.
+type response struct {
+	interactionID string
+	kind          string
+	content       string
+	components    []discordgo.MessageComponent
+	ephemeral     bool
+}
+
+func newFakeSession(t *testing.T) *fakeSession {
+	t.Helper()
+	cache := discordgo.NewState()
+	cache.User = &discordgo.User{ID: "bot"}
+	if err := cache.GuildAdd(&discordgo.Guild{ID: testGuildID}); err != nil {
+		t.Fatal(err)
+	}
+	return &fakeSession{cache: cache, changed: make(chan struct{})}
+}
+
+// joinVoice puts userID in channelID as far as the cache knows.
+func (s *fakeSession) joinVoice(t *testing.T, userID, channelID string) {
+	t.Helper()
+	guild, err := s.cache.Guild(testGuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.cache.Lock()
+	guild.VoiceStates = append(guild.VoiceStates, &discordgo.VoiceState{GuildID: testGuildID, UserID: userID, ChannelID: channelID})
+	s.cache.Unlock()
+}
+
+func (s *fakeSession) record(r response) {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, r)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// sent returns what was sent for the interaction so far, oldest first.
+func (s *fakeSession) sent(i *discordgo.InteractionCreate) []response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sent []response
+	for _, r := range s.responses {
+		if r.interactionID == i.ID {
+			sent = append(sent, r)
+		}
+	}
+	return sent
+}
+
+// await waits until something sent for the interaction satisfies match and
+// returns it.
+func (s *fakeSession) await(t *testing.T, i *discordgo.InteractionCreate, what string, match func(response) bool) response {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		sent := s.sent(i)
+		for _, r := range sent {
+			if match(r) {
+				return r
+			}
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			var contents []string
+			for _, r := range sent {
+				contents = append(contents, fmt.Sprintf("%s %q", r.kind, r.content))
+			}
+			t.Fatalf("no %s among: %s", what, strings.Join(contents, ", "))
+			return response{}
+		}
+	}
+}
+
+// awaitContent waits until the interaction gets a response containing want.
+func (s *fakeSession) awaitContent(t *testing.T, i *discordgo.InteractionCreate, want string) response {
+	t.Helper()
+	return s.await(t, i, fmt.Sprintf("%q", want), func(r response) bool {
+		return strings.Contains(r.content, want)
+	})
+}
+
+// messageText joins a message's content and the descriptions of its embeds,
+// so a test reads the same text whichever style a guild renders in.
+func messageText(content string, embeds []*discordgo.MessageEmbed) string {
+	text := content
+	for _, e := range embeds {
+		text += e.Title + "\n" + e.Description
+	}
+	return text
+}
+
+func (s *fakeSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	r := response{interactionID: interaction.ID, kind: "respond"}
+	if resp.Data != nil {
+		r.content = messageText(resp.Data.Content, resp.Data.Embeds)
+		r.components = resp.Data.Components
+		r.ephemeral = resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+	}
+	s.record(r)
+	return nil
+}
+
+func (s *fakeSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r := response{interactionID: interaction.ID, kind: "edit"}
+	if newresp.Content != nil {
+		r.content = *newresp.Content
+	}
+	if newresp.Embeds != nil {
+		r.content = messageText(r.content, *newresp.Embeds)
+	}
+	if newresp.Components != nil {
+		r.components = *newresp.Components
+	}
+	s.record(r)
+	return &discordgo.Message{ID: "response", ChannelID: testChannelID}, nil
+}
+
+func (s *fakeSession) FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.record(response{
+		interactionID: interaction.ID,
+		kind:          "followup",
+		content:       messageText(data.Content, data.Embeds),
+		components:    data.Components,
+		ephemeral:     data.Flags&discordgo.MessageFlagsEphemeral != 0,
+	})
+	return &discordgo.Message{ID: "followup", ChannelID: testChannelID}, nil
+}
+
+func (s *fakeSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.record(response{kind: "message", content: messageText(data.Content, data.Embeds), components: data.Components})
+	return &discordgo.Message{ID: "message", ChannelID: channelID}, nil
+}
+
+// RequestWithBucketID only serves voice state lookups, and knows no one
+// beyond the cache.
+func (s *fakeSession) RequestWithBucketID(method, urlStr string, data interface{}, bucketID string, options ...discordgo.RequestOption) ([]byte, error) {
+	time.Sleep(s.delay)
+	return nil, &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}
+}
+
+func (s *fakeSession) GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	time.Sleep(s.delay)
+	return &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: userID}}, nil
+}
+
+func (s *fakeSession) Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error) {
+	time.Sleep(s.delay)
+	return s.cache.Guild(guildID)
+}
+
+func (s *fakeSession) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	time.Sleep(s.delay)
+	return discordgo.PermissionAll, nil
+}
+
+func (s *fakeSession) Cache() *discordgo.State {
+	return s.cache
+}
+
+func (s *fakeSession) Shard() (id, count int) {
+	return 0, 1
+}
+
+// testEnv is a guild registry wired to a fake Discord, a fake downloader and
+// an in-memory database, as the client wires the real ones.
+type testEnv struct {
+	session    *fakeSession
+	downloader *sockettest.Server
+	socket     *socket.Client
+	db         *config.DatabaseManager
+	state      *state.Manager
+	guilds     *guilds.Registry
+	blacklist  *blacklist.List
+	audit      *audit.Log
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := config.NewMemoryDatabaseManager(strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()))
+	if err != nil {
+		t.Fatalf("NewMemoryDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return newTestEnvWithDB(t, db)
+}
+
+// newTestEnvWithDB starts the bot over db, which may hold what an earlier
+// run left behind.
+func newTestEnvWithDB(t *testing.T, db *config.DatabaseManager) *testEnv {
+	t.Helper()
+	downloader := sockettest.NewServer(t)
+	client := socket.NewClient(downloader.Path())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("connecting to the fake downloader: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+
+	blacklistList, err := blacklist.New(db)
+	if err != nil {
+		t.Fatalf("blacklist.New: %v", err)
+	}
+
+	session := newFakeSession(t)
+	stateManager := state.NewManager(state.Config{})
+	registry := guilds.NewRegistry(session, stateManager, radio.NewStreamManager(nil), db, client)
+	client.SetDownloadHandler(registry.OnDownloadComplete)
+	client.SetDownloadFailHandler(registry.OnDownloadFailed)
+	t.Cleanup(func() { registry.Shutdown(context.Background()) })
+
+	return &testEnv{
+		session:    session,
+		downloader: downloader,
+		socket:     client,
+		db:         db,
+		state:      stateManager,
+		guilds:     registry,
+		blacklist:  blacklistList,
+		audit:      audit.New(db, nil, stateManager),
+	}
+}
+
+// downloads answers every download with a song made from the requested URL,
+// saving its file in the test's temp directory.
+func (e *testEnv) downloads(t *testing.T) {
+	dir := t.TempDir()
+	e.downloader.Handle("download_audio", func(request sockettest.Request) []sockettest.Response {
+		url, _ := request.Params["url"].(string)
+		title := url[strings.LastIndex(url, "/")+1:]
+		return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+			"title":    title,
+			"url":      url,
+			"filename": dir + "/" + title + ".mp3",
+			"duration": 60,
+			"platform": "test",
+		})}
+	})
+}
+
+var interactionIDs atomic.Int64
+
+func newInteraction(userID string) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		ID:        fmt.Sprintf("interaction%d", interactionIDs.Add(1)),
+		GuildID:   testGuildID,
+		ChannelID: testChannelID,
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+	}
+}
+
+// commandInteraction is userID running the slash command name.
+func commandInteraction(userID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	interaction := newInteraction(userID)
+	interaction.Type = discordgo.InteractionApplicationCommand
+	interaction.Data = discordgo.ApplicationCommandInteractionData{Name: name, Options: options}
+	return &discordgo.InteractionCreate{Interaction: interaction}
+}
+
+// buttonInteraction is userID pressing the button customID.
+func buttonInteraction(userID, customID string) *discordgo.InteractionCreate {
+	interaction := newInteraction(userID)
+	interaction.Type = discordgo.InteractionMessageComponent
+	interaction.Data = discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent}
+	interaction.Message = &discordgo.Message{ID: "response", ChannelID: testChannelID}
+	return &discordgo.InteractionCreate{Interaction: interaction}
+}
+
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionString, Value: value}
+}
+
+// intOption holds value as a float64, which is how Discord's JSON decodes.
+func intOption(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+}
+
+// buttons returns the buttons of a message, row by row.
+func buttons(components []discordgo.MessageComponent) []discordgo.Button {
+	var found []discordgo.Button
+	for _, component := range components {
+		row, ok := component.(discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if button, ok := c.(discordgo.Button); ok {
+				found = append(found, button)
+			}
+		}
+	}
+	return found
+}
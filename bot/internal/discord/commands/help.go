@@ -2,108 +2,72 @@ package commands
 
 import (
 	"fmt"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
 	"musicbot/internal/permissions"
-	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-type HelpCommand struct {
-	permissionManager *permissions.Manager
-	commandRegistry   map[string]HelpCommandInfo
+const (
+	// helpPageSize keeps a page of /help within a plain message's 2000
+	// characters.
+	helpPageSize = 12
+	helpPageTTL  = 10 * time.Minute
+)
+
+// Category groups commands in /help.
+type Category string
+
+const (
+	CategoryMusic      Category = "music"
+	CategoryVoice      Category = "voice"
+	CategoryRadio      Category = "radio"
+	CategoryModeration Category = "moderation"
+	CategoryAdmin      Category = "admin"
+	CategoryUtility    Category = "utility"
+)
+
+// Categories lists the categories in the order /help shows them.
+var Categories = []Category{CategoryMusic, CategoryVoice, CategoryRadio, CategoryModeration, CategoryAdmin, CategoryUtility}
+
+// CategorizedCommand is implemented by commands that /help lists under a
+// category. Commands without one are listed under utility.
+type CategorizedCommand interface {
+	Category() Category
 }
 
-type HelpCommandInfo struct {
-	Description   string
-	RequiredLevel permissions.Level
-	Category      string
+// ExampleCommand is implemented by commands that show usage examples in
+// /help.
+type ExampleCommand interface {
+	Examples() []string
 }
 
-func NewHelpCommand(permissionManager *permissions.Manager) *HelpCommand {
-	cmd := &HelpCommand{
-		permissionManager: permissionManager,
-		commandRegistry:   make(map[string]HelpCommandInfo),
+func commandCategory(cmd Command) Category {
+	if cc, ok := cmd.(CategorizedCommand); ok {
+		return cc.Category()
 	}
+	return CategoryUtility
+}
 
-	cmd.registerCommands()
-	return cmd
+// HelpCommand lists the commands registered with the router, so it never
+// falls out of step with what the bot can actually do.
+type HelpCommand struct {
+	router            *Router
+	permissionManager *permissions.Manager
 }
 
-func (c *HelpCommand) registerCommands() {
-	c.commandRegistry = map[string]HelpCommandInfo{
-		"help": {
-			Description:   "Show this help message",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Utility",
-		},
-		"ping": {
-			Description:   "Check bot latency and response time",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Utility",
-		},
-		"join": {
-			Description:   "Join your voice channel",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Voice",
-		},
-		"leave": {
-			Description:   "Leave current voice channel and return to idle",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Voice",
-		},
-		"play": {
-			Description:   "Play a song from URL",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"playlist": {
-			Description:   "Play a playlist from URL",
-			RequiredLevel: permissions.LevelDJ,
-			Category:      "Music",
-		},
-		"search": {
-			Description:   "Search for songs to play",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"queue": {
-			Description:   "Show the current music queue",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"nowplaying": {
-			Description:   "Show what's currently playing",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"skip": {
-			Description:   "Skip the current song",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"pause": {
-			Description:   "Pause music and switch to idle mode",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"resume": {
-			Description:   "Resume paused music",
-			RequiredLevel: permissions.LevelUser,
-			Category:      "Music",
-		},
-		"clear": {
-			Description:   "Clear the music queue",
-			RequiredLevel: permissions.LevelDJ,
-			Category:      "Music",
-		},
-		"changestream": {
-			Description:   "Change the radio stream",
-			RequiredLevel: permissions.LevelDJ,
-			Category:      "Radio",
-		},
+func NewHelpCommand(router *Router, permissionManager *permissions.Manager) *HelpCommand {
+	return &HelpCommand{
+		router:            router,
+		permissionManager: permissionManager,
 	}
 }
 
@@ -115,103 +79,261 @@ func (c *HelpCommand) Description() string {
 	return "Show available commands and their descriptions"
 }
 
+func (c *HelpCommand) Category() Category {
+	return CategoryUtility
+}
+
+func (c *HelpCommand) Examples() []string {
+	return []string{
+		"/help",
+		"/help category:Music",
+		"/help command:play",
+	}
+}
+
 func (c *HelpCommand) Options() []*discordgo.ApplicationCommandOption {
+	caser := cases.Title(language.Und)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(Categories))
+	for _, category := range Categories {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: caser.String(string(category)), Value: string(category)})
+	}
+
 	return []*discordgo.ApplicationCommandOption{
 		{
 			Type:        discordgo.ApplicationCommandOptionString,
 			Name:        "category",
-			Description: "Show commands from a specific category",
+			Description: "Start at a specific category",
 			Required:    false,
-			Choices: []*discordgo.ApplicationCommandOptionChoice{
-				{Name: "All", Value: "all"},
-				{Name: "Utility", Value: "utility"},
-				{Name: "Voice", Value: "voice"},
-				{Name: "Music", Value: "music"},
-				{Name: "Radio", Value: "radio"},
-			},
+			Choices:     choices,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "command",
+			Description: "Show the options, permissions and examples of a command",
+			Required:    false,
+			MaxLength:   32,
 		},
 	}
 }
 
-func (c *HelpCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: c.generateHelpMessage(s, i),
-		},
-	})
-	return err
-}
+func (c *HelpCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	var category Category
+	var commandName string
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "category":
+			category = Category(option.StringValue())
+		case "command":
+			commandName = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(option.StringValue())), "/")
+		}
+	}
 
-func (c *HelpCommand) generateHelpMessage(s *discordgo.Session, i *discordgo.InteractionCreate) string {
-	options := i.ApplicationCommandData().Options
-	selectedCategory := "all"
-	if len(options) > 0 {
-		selectedCategory = strings.ToLower(options[0].StringValue())
+	if commandName != "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, c.commandDetails(i.GuildID, commandName).Edit())
+		return err
 	}
 
-	userID := i.Member.User.ID
-	guildID := i.GuildID
+	ownerID := i.Member.User.ID
+	issued := time.Now().Unix()
+	pages := c.pages(s, i.GuildID, ownerID)
 
-	message := "🤖 **Music Bot Commands**\n\n"
+	page := 0
+	for idx, p := range pages {
+		if p.category == category {
+			page = idx
+			break
+		}
+	}
 
-	categories := c.getCategories(selectedCategory)
+	message, components := c.renderPage(i.GuildID, pages, ownerID, page, issued)
 
-	for _, category := range categories {
-		commands := c.getCommandsForCategory(category, s, guildID, userID)
-		if len(commands) == 0 {
-			continue
-		}
+	edit := message.Edit()
+	if len(components) > 0 {
+		edit.Components = &components
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil || len(components) == 0 {
+		return err
+	}
 
-		message += fmt.Sprintf("**%s Commands:**\n", category)
+	time.AfterFunc(helpPageTTL, func() {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Components: &[]discordgo.MessageComponent{},
+		})
+		if err != nil {
+			logger.Debug.Printf("Failed to remove help buttons: %v", err)
+		}
+	})
 
-		for _, cmdName := range commands {
-			cmdInfo := c.commandRegistry[cmdName]
-			permissionText := ""
+	return nil
+}
 
-			if cmdInfo.RequiredLevel != permissions.LevelUser {
-				roleName := c.permissionManager.GetRequiredRoleName(cmdInfo.RequiredLevel)
-				permissionText = fmt.Sprintf(" *(%s only)*", roleName)
-			}
+func (c *HelpCommand) ComponentPrefix() string {
+	return "help_page_"
+}
 
-			message += fmt.Sprintf("• `/%s` - %s%s\n", cmdName, cmdInfo.Description, permissionText)
-		}
-		message += "\n"
+// HandleComponent serves the Previous/Next buttons. The custom ID carries
+// everything needed to render the page: help_page_<owner>_<page>_<issued>.
+func (c *HelpCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, c.ComponentPrefix()), "_")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed help page id: %s", i.MessageComponentData().CustomID)
 	}
 
-	message += "💡 **Tips:**\n"
-	message += "• Use `/nowplaying` to see what's currently playing\n"
-	message += "• The bot automatically switches between radio and music modes\n"
-	message += "• When no music is queued, radio will resume automatically"
+	ownerID := parts[0]
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	issued, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return err
+	}
 
-	return message
-}
+	if i.Member == nil || i.Member.User.ID != ownerID {
+		return respondNotOwner(s, i)
+	}
 
-func (c *HelpCommand) getCategories(selectedCategory string) []string {
-	if selectedCategory != "all" {
-		caser := cases.Title(language.Und)
-		return []string{caser.String(selectedCategory)}
+	if time.Since(time.Unix(issued, 0)) > helpPageTTL {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content,
+				Components: []discordgo.MessageComponent{},
+			},
+		})
 	}
 
-	return []string{"Utility", "Voice", "Music", "Radio"}
+	message, components := c.renderPage(i.GuildID, c.pages(s, i.GuildID, ownerID), ownerID, page, issued)
+
+	data := message.Data()
+	data.Components = components
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
 }
 
-func (c *HelpCommand) getCommandsForCategory(category string, s *discordgo.Session, guildID, userID string) []string {
-	var commands []string
+// helpPage is a page of /help: a category, or part of one if it has more
+// than helpPageSize commands the user can run.
+type helpPage struct {
+	category Category
+	entries  []render.HelpEntry
+}
 
-	for cmdName, cmdInfo := range c.commandRegistry {
-		if cmdInfo.Category != category {
-			continue
+// pages splits the commands the user can run into pages by category.
+func (c *HelpCommand) pages(s discordapi.Session, guildID, userID string) []helpPage {
+	allowed := map[permissions.Level]bool{permissions.LevelUser: true}
+	for _, level := range []permissions.Level{permissions.LevelDJ, permissions.LevelAdmin} {
+		hasPermission, err := c.permissionManager.HasPermission(s, guildID, userID, level)
+		if err != nil {
+			logger.ForCommand(guildID, c.Name()).Debug("Permission check for help failed", "level", level, "error", err)
 		}
+		allowed[level] = hasPermission
+	}
 
-		hasPermission, err := c.permissionManager.HasPermission(s, guildID, userID, cmdInfo.RequiredLevel)
-		if err != nil || !hasPermission {
+	byCategory := make(map[Category][]render.HelpEntry)
+	for _, cmd := range c.router.Commands() {
+		level, _ := c.router.commandLevel(cmd, guildID)
+		if !allowed[level] || c.router.CommandDisabled(guildID, cmd.Name()) {
 			continue
 		}
 
-		commands = append(commands, cmdName)
+		entry := render.HelpEntry{Name: cmd.Name(), Description: cmd.Description()}
+		if level != permissions.LevelUser {
+			entry.Role = c.permissionManager.GetRequiredRoleName(guildID, level)
+		}
+		category := commandCategory(cmd)
+		byCategory[category] = append(byCategory[category], entry)
+	}
+
+	var pages []helpPage
+	for _, category := range Categories {
+		entries := byCategory[category]
+		for start := 0; start < len(entries); start += helpPageSize {
+			end := min(start+helpPageSize, len(entries))
+			pages = append(pages, helpPage{category: category, entries: entries[start:end]})
+		}
+	}
+	return pages
+}
+
+func (c *HelpCommand) renderPage(guildID string, pages []helpPage, ownerID string, page int, issued int64) (render.Message, []discordgo.MessageComponent) {
+	if len(pages) == 0 {
+		return render.Text(guildID, i18n.T(guildID, "help.none")), nil
+	}
+	if page >= len(pages) {
+		page = len(pages) - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	message := render.Help(guildID, render.HelpPage{
+		Category: i18n.T(guildID, "help.category."+string(pages[page].category)),
+		Entries:  pages[page].entries,
+		Page:     page,
+		Pages:    len(pages),
+	})
+
+	if len(pages) == 1 {
+		return message, nil
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    i18n.T(guildID, "queue.previous"),
+					CustomID: c.pageID(ownerID, page-1, issued),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    i18n.T(guildID, "queue.next"),
+					CustomID: c.pageID(ownerID, page+1, issued),
+					Disabled: page == len(pages)-1,
+				},
+			},
+		},
+	}
+
+	return message, components
+}
+
+func (c *HelpCommand) pageID(ownerID string, page int, issued int64) string {
+	return fmt.Sprintf("%s%s_%d_%d", c.ComponentPrefix(), ownerID, page, issued)
+}
+
+// commandDetails describes a command using the same interfaces the router
+// enforces, so the permissions and cooldown shown are the ones that apply.
+func (c *HelpCommand) commandDetails(guildID, name string) render.Message {
+	cmd, ok := c.router.Command(name)
+	if !ok {
+		return render.Text(guildID, i18n.T(guildID, "help.unknown_command", name))
+	}
+
+	details := render.CommandDetails{
+		Name:        cmd.Name(),
+		Description: cmd.Description(),
+		Options:     cmd.Options(),
+	}
+
+	if level := requiredLevel(cmd); level != permissions.LevelUser {
+		details.Role = c.permissionManager.GetRequiredRoleName(guildID, level)
+	} else if controlsMusic(cmd) {
+		details.DJOnlyRole = c.permissionManager.GetRequiredRoleName(guildID, permissions.LevelDJ)
+	}
+	if cc, ok := cmd.(CooldownCommand); ok && cc.Cooldown() > 0 {
+		details.Cooldown = cc.Cooldown()
+	}
+	if limited, ok := cmd.(ConcurrencyLimitedCommand); ok {
+		details.MaxConcurrent = limited.MaxConcurrentPerGuild()
+	}
+	if ec, ok := cmd.(ExampleCommand); ok {
+		details.Examples = ec.Examples()
 	}
 
-	sort.Strings(commands)
-	return commands
+	return render.Command(guildID, details)
 }
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/state"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type PreviousCommand struct {
+	guilds *guilds.Registry
+	audit  *audit.Log
+}
+
+func NewPreviousCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *PreviousCommand {
+	return &PreviousCommand{
+		guilds: guildRegistry,
+		audit:  auditLog,
+	}
+}
+
+func (c *PreviousCommand) Name() string {
+	return "previous"
+}
+
+func (c *PreviousCommand) Description() string {
+	return "Go back to the song that played before"
+}
+
+func (c *PreviousCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PreviousCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *PreviousCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *PreviousCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	if guild.State.GetBotState() != state.StateDJ {
+		return c.respond(s, i, i18n.T(i.GuildID, "skip.not_playing"))
+	}
+
+	song, err := guild.Music.PlayPrevious()
+	switch {
+	case errors.Is(err, music.ErrNoHistory):
+		return c.respond(s, i, i18n.T(i.GuildID, "previous.empty"))
+	case errors.Is(err, music.ErrPreviousGone):
+		return c.respond(s, i, i18n.T(i.GuildID, "previous.gone"))
+	case err != nil:
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to play the previous song", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "previous.failed"))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionPrevious, song.Title)
+	return c.respond(s, i, i18n.T(i.GuildID, "previous.playing", song.Title, song.Artist))
+}
+
+func (c *PreviousCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+type HistoryCommand struct {
+	guilds *guilds.Registry
+}
+
+func NewHistoryCommand(guildRegistry *guilds.Registry) *HistoryCommand {
+	return &HistoryCommand{
+		guilds: guildRegistry,
+	}
+}
+
+func (c *HistoryCommand) Name() string {
+	return "history"
+}
+
+func (c *HistoryCommand) Description() string {
+	return "Show the songs that played last"
+}
+
+func (c *HistoryCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *HistoryCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *HistoryCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	entries := c.guilds.Get(i.GuildID).Music.History()
+	if len(entries) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "history.empty")),
+		})
+		return err
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, render.History(i.GuildID, entries).Edit())
+	return err
+}
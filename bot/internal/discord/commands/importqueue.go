@@ -0,0 +1,286 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// importFetchTimeout bounds downloading the queue file itself.
+	importFetchTimeout = 30 * time.Second
+	// importTrackTimeout gives up on a track whose download never reports
+	// back, so one lost request doesn't stall the rest of the import.
+	importTrackTimeout = 10 * time.Minute
+	// importMalformedShown is how many malformed lines the summary quotes.
+	importMalformedShown = 10
+)
+
+type ImportQueueCommand struct {
+	guilds    *guilds.Registry
+	blacklist *blacklist.List
+	audit     *audit.Log
+}
+
+func NewImportQueueCommand(guildRegistry *guilds.Registry, blacklistList *blacklist.List, auditLog *audit.Log) *ImportQueueCommand {
+	return &ImportQueueCommand{
+		guilds:    guildRegistry,
+		blacklist: blacklistList,
+		audit:     auditLog,
+	}
+}
+
+func (c *ImportQueueCommand) Name() string {
+	return "importqueue"
+}
+
+func (c *ImportQueueCommand) Description() string {
+	return "Queue the tracks of a file from /exportqueue or a list of URLs"
+}
+
+func (c *ImportQueueCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ImportQueueCommand) QueuesSongs() bool {
+	return true
+}
+
+func (c *ImportQueueCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *ImportQueueCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *ImportQueueCommand) Cooldown() time.Duration {
+	return 30 * time.Second
+}
+
+func (c *ImportQueueCommand) MaxConcurrentPerGuild() int {
+	return 1
+}
+
+func (c *ImportQueueCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionAttachment,
+			Name:        "file",
+			Description: fmt.Sprintf("Text file with one URL per line, at most %d lines", music.MaxQueueFileLines),
+			Required:    true,
+		},
+	}
+}
+
+func (c *ImportQueueCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return c.Start(s, i, func() {})
+}
+
+// Start checks the queue file and imports it in the background, calling done
+// once every track has been tried.
+func (c *ImportQueueCommand) Start(s discordapi.Session, i *discordgo.InteractionCreate, done func()) error {
+	started := false
+	defer func() {
+		if !started {
+			done()
+		}
+	}()
+
+	userID := i.Member.User.ID
+
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, userID, ""); blocked {
+		return followUpBlacklisted(s, i, reason)
+	}
+
+	data := i.ApplicationCommandData()
+	attachmentID, _ := data.Options[0].Value.(string)
+	attachment := data.Resolved.Attachments[attachmentID]
+	if attachment == nil || attachment.Size > music.MaxQueueFileSize {
+		return c.respond(s, i, i18n.T(i.GuildID, "importqueue.too_large", music.MaxQueueFileSize>>10))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), importFetchTimeout)
+	defer cancel()
+
+	content, err := music.FetchQueueFile(ctx, attachment.URL)
+	if err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, err))
+	}
+	file, err := music.ParseQueueFile(content)
+	if err != nil {
+		return c.respond(s, i, c.errorMessage(i.GuildID, err))
+	}
+	if len(file.URLs) == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "importqueue.no_urls")+c.malformedSummary(i.GuildID, file.Malformed))
+	}
+
+	if message := joinUserForRequest(s, c.guilds, c.guilds.Get(i.GuildID), userID); message != "" {
+		return c.respond(s, i, message)
+	}
+
+	if err := c.respond(s, i, i18n.T(i.GuildID, "importqueue.starting", len(file.URLs))); err != nil {
+		return err
+	}
+
+	c.audit.Record(i.GuildID, userID, audit.ActionImportQueue, fmt.Sprintf("%d tracks", len(file.URLs)))
+
+	started = true
+	go func() {
+		defer done()
+		c.importTracks(newProgressReporter(s, i), file, userID, c.guilds.Get(i.GuildID).Music.DownloadLimits(i.GuildID))
+	}()
+	return nil
+}
+
+// importTracks requests the file's tracks one at a time, so they are queued
+// in the file's order, and reports how the import went once all are through.
+// Tracks that are blocked, already queued or fail to download are counted and
+// skipped; only a full queue stops the import.
+func (c *ImportQueueCommand) importTracks(progress *progressReporter, file *music.QueueFile, userID string, limits config.DownloadLimits) {
+	guildID := progress.guildID
+	musicManager := c.guilds.Get(guildID).Music
+	total := len(file.URLs)
+
+	var added, duplicates, blocked, failed int
+	stopped := ""
+	lastProgress := time.Now()
+
+	for k, url := range file.URLs {
+		if _, isBlocked := blacklistReason(c.blacklist, guildID, "", url); isBlocked {
+			blocked++
+			continue
+		}
+		if musicManager.FindQueued(url) != nil || musicManager.IsDownloading(url) {
+			duplicates++
+			continue
+		}
+
+		track := &importTrack{done: make(chan error, 1)}
+		err := musicManager.RequestSong(url, userID, limits, track)
+		if err == nil {
+			select {
+			case err = <-track.done:
+			case <-time.After(importTrackTimeout):
+				err = fmt.Errorf("no answer from the downloader for %s", url)
+			}
+		}
+
+		var duplicateErr *music.DuplicateError
+		var limitErr *music.LimitError
+		switch {
+		case err == nil:
+			added++
+		case errors.As(err, &duplicateErr):
+			duplicates++
+		case errors.As(err, &limitErr), errors.Is(err, music.ErrDownloadCancelled):
+			stopped = requestErrorMessage(guildID, err) + i18n.T(guildID, "importqueue.stopped", total-k)
+		default:
+			logger.ForCommand(guildID, c.Name()).Debug("Imported track failed", "url", url, "error", err)
+			failed++
+		}
+		if stopped != "" {
+			break
+		}
+
+		if time.Since(lastProgress) >= playlistProgressInterval {
+			lastProgress = time.Now()
+			progress.Update(i18n.T(guildID, "importqueue.progress", k+1, total, added))
+		}
+	}
+
+	summary := i18n.T(guildID, "importqueue.done", added, total)
+	if duplicates+file.Repeats > 0 {
+		summary += i18n.T(guildID, "importqueue.duplicates", duplicates+file.Repeats)
+	}
+	if blocked > 0 {
+		summary += i18n.T(guildID, "importqueue.blocked", blocked)
+	}
+	if failed > 0 {
+		summary += i18n.T(guildID, "importqueue.failed", failed)
+	}
+	if stopped != "" {
+		summary += "\n" + stopped
+	}
+	summary += c.malformedSummary(guildID, file.Malformed)
+
+	progress.Finish(summary)
+}
+
+// malformedSummary lists the lines that weren't URLs, the first few by line
+// number and text.
+func (c *ImportQueueCommand) malformedSummary(guildID string, malformed []music.MalformedLine) string {
+	if len(malformed) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for _, line := range malformed[:min(len(malformed), importMalformedShown)] {
+		text := line.Text
+		if runes := []rune(text); len(runes) > 60 {
+			text = string(runes[:57]) + "..."
+		}
+		lines = append(lines, i18n.T(guildID, "importqueue.malformed_line", line.Number, strings.ReplaceAll(text, "`", "'")))
+	}
+	if extra := len(malformed) - importMalformedShown; extra > 0 {
+		lines = append(lines, i18n.T(guildID, "importqueue.malformed_more", extra))
+	}
+
+	return i18n.T(guildID, "importqueue.malformed", len(malformed)) + "\n" + strings.Join(lines, "\n")
+}
+
+func (c *ImportQueueCommand) errorMessage(guildID string, err error) string {
+	switch {
+	case errors.Is(err, music.ErrQueueFileTooLarge):
+		return i18n.T(guildID, "importqueue.too_large", music.MaxQueueFileSize>>10)
+	case errors.Is(err, music.ErrQueueFileTooLong):
+		return i18n.T(guildID, "importqueue.too_long", music.MaxQueueFileLines)
+	case errors.Is(err, music.ErrQueueFileEmpty):
+		return i18n.T(guildID, "importqueue.no_urls")
+	}
+
+	logger.ForCommand(guildID, c.Name()).Error("Failed to read queue file", "error", err)
+	return i18n.T(guildID, "importqueue.fetch_failed")
+}
+
+func (c *ImportQueueCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// importTrack hears back about one imported track's download. Only the
+// first answer counts.
+type importTrack struct {
+	done chan error
+}
+
+func (t *importTrack) Queued(*state.Song, music.ETA) {
+	t.finish(nil)
+}
+
+func (t *importTrack) Failed(err error) {
+	t.finish(err)
+}
+
+func (t *importTrack) finish(err error) {
+	select {
+	case t.done <- err:
+	default:
+	}
+}
@@ -1,8 +1,10 @@
 package commands
 
 import (
-	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"errors"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/state"
 	"musicbot/internal/voice"
 	"time"
@@ -11,18 +13,12 @@ import (
 )
 
 type JoinCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
 }
 
-func NewJoinCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *JoinCommand {
+func NewJoinCommand(guildRegistry *guilds.Registry) *JoinCommand {
 	return &JoinCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
 	}
 }
 
@@ -34,72 +30,71 @@ func (c *JoinCommand) Description() string {
 	return "Join your voice channel"
 }
 
+func (c *JoinCommand) Category() Category {
+	return CategoryVoice
+}
+
 func (c *JoinCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *JoinCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *JoinCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
 
-	userVS, err := s.State.VoiceState(i.GuildID, i.Member.User.ID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
+	userChannelID, err := voice.UserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.not_in_voice")),
 		})
 		return err
 	}
 
-	if c.voiceManager.IsConnectedTo(userVS.ChannelID) {
+	if guild.Voice.IsConnectedTo(userChannelID) {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("✅ Already in your voice channel."),
+			Content: stringPtr(i18n.T(i.GuildID, "join.already_here")),
 		})
 		return err
 	}
 
-	currentState := c.stateManager.GetBotState()
+	currentState := guild.State.GetBotState()
 
-	c.stateManager.SetManualOperationActive(true)
-	defer c.stateManager.SetManualOperationActive(false)
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
 
-	c.musicManager.ExecuteWithDisabledHandlers(func() {
+	guild.Music.ExecuteWithDisabledHandlers(func() {
 		if currentState == state.StateDJ {
-			c.musicManager.Stop()
+			guild.Music.Stop()
 		} else {
-			c.radioManager.Stop()
+			guild.Radio.Stop()
 		}
 
 		time.Sleep(500 * time.Millisecond)
 
-		err = c.voiceManager.JoinUser(i.GuildID, i.Member.User.ID)
+		err = guild.Voice.JoinUser(i.GuildID, i.Member.User.ID)
 		if err != nil {
 			return
 		}
 
 		time.Sleep(500 * time.Millisecond)
 
-		if c.stateManager.IsInIdleChannel() {
-			c.stateManager.SetBotState(state.StateIdle)
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil && !c.radioManager.IsPlaying() {
-				c.radioManager.Start(vc)
+		if guild.State.IsInIdleChannel() {
+			guild.State.SetBotState(state.StateIdle)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		} else {
-			if currentState == state.StateDJ && c.musicManager.GetCurrentSong() != nil && !c.musicManager.IsPaused() {
-				c.stateManager.SetBotState(state.StateDJ)
-				vc := c.voiceManager.GetVoiceConnection()
+			if currentState == state.StateDJ && guild.Music.GetCurrentSong() != nil && !guild.Music.IsPaused() {
+				guild.State.SetBotState(state.StateDJ)
+				vc := guild.Voice.GetVoiceConnection()
 				if vc != nil {
-					c.musicManager.Start(vc)
+					guild.Music.Start(vc)
 				}
 			} else {
-				c.stateManager.SetBotState(state.StateRadio)
-				vc := c.voiceManager.GetVoiceConnection()
-				if vc != nil && !c.radioManager.IsPlaying() {
-					c.radioManager.Start(vc)
+				guild.State.SetBotState(state.StateRadio)
+				vc := guild.Voice.GetVoiceConnection()
+				if vc != nil && !guild.Radio.IsPlaying() {
+					guild.Radio.Start(vc)
 				}
 			}
 		}
@@ -107,24 +102,24 @@ func (c *JoinCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCrea
 
 	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Failed to join your voice channel."),
+			Content: stringPtr(joinErrorMessage(i.GuildID, err)),
 		})
 		return err
 	}
 
-	if c.stateManager.IsInIdleChannel() {
+	if guild.State.IsInIdleChannel() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("✅ Joined your voice channel and started radio."),
+			Content: stringPtr(i18n.T(i.GuildID, "join.joined_radio")),
 		})
 	} else {
-		currentState := c.stateManager.GetBotState()
+		currentState := guild.State.GetBotState()
 		if currentState == state.StateDJ {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("✅ Joined your voice channel and resumed music."),
+				Content: stringPtr(i18n.T(i.GuildID, "join.joined_music")),
 			})
 		} else {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("✅ Joined your voice channel and started radio."),
+				Content: stringPtr(i18n.T(i.GuildID, "join.joined_radio")),
 			})
 		}
 	}
@@ -132,6 +127,81 @@ func (c *JoinCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCrea
 	return err
 }
 
+// joinErrorMessage turns an error from joining the user's voice channel into
+// the message shown to them, naming the permission or limit in the way.
+func joinErrorMessage(guildID string, err error) string {
+	var channelErr *voice.ChannelError
+	if !errors.As(err, &channelErr) {
+		return i18n.T(guildID, "common.join_failed")
+	}
+
+	switch {
+	case errors.Is(err, voice.ErrMissingConnect):
+		return i18n.T(guildID, "common.join_missing_connect", channelErr.ChannelID)
+	case errors.Is(err, voice.ErrMissingSpeak):
+		return i18n.T(guildID, "common.join_missing_speak", channelErr.ChannelID)
+	case errors.Is(err, voice.ErrChannelFull):
+		return i18n.T(guildID, "common.join_channel_full", channelErr.ChannelID)
+	}
+	return i18n.T(guildID, "common.join_failed")
+}
+
+// joinForRequest brings the bot to the user's channel so a song they request
+// can play there, and returns the message to show them if it can't. What the
+// bot is doing is read once up front; leaving and joining take a while, so it
+// is checked again afterwards in case another command moved the bot meanwhile.
+func joinForRequest(registry *guilds.Registry, guild *guilds.Guild, userID, userChannelID string) string {
+	currentChannelID := guild.State.GetCurrentChannel()
+	if currentChannelID == userChannelID {
+		return ""
+	}
+
+	currentBotState := guild.State.GetBotState()
+	if currentChannelID != "" {
+		if currentBotState == state.StateDJ && guild.Music.IsPlaying() {
+			return i18n.T(guild.ID, "common.busy_other_channel")
+		}
+
+		registry.StopPlayback(guild.ID)
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	err := guild.Voice.JoinUser(guild.ID, userID)
+	if guild.State.GetCurrentChannel() != userChannelID {
+		if err == nil {
+			return i18n.T(guild.ID, "common.busy_other_channel")
+		}
+		return joinErrorMessage(guild.ID, err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	if currentChannelID != "" && currentBotState == state.StateRadio && guild.State.GetBotState() == state.StateRadio && !guild.Radio.IsPlaying() {
+		vc := guild.Voice.GetVoiceConnection()
+		if vc != nil {
+			guild.Radio.Start(vc)
+		}
+	}
+	return ""
+}
+
+// joinUserForRequest makes the checks a request for songs starts with, then
+// takes the bot to userID's voice channel with joinForRequest. It returns the
+// message to show the user if the request can't go ahead, or "".
+func joinUserForRequest(s discordapi.Session, registry *guilds.Registry, guild *guilds.Guild, userID string) string {
+	if _, err := guild.Music.RemainingCapacity(userID); err != nil {
+		return requestErrorMessage(guild.ID, err)
+	}
+
+	userChannelID, err := voice.UserVoiceChannel(s, guild.ID, userID)
+	if err != nil {
+		return i18n.T(guild.ID, "common.not_in_voice")
+	}
+
+	return joinForRequest(registry, guild, userID, userChannelID)
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
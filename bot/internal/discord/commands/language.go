@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type LanguageCommand struct {
+	dbManager *config.DatabaseManager
+}
+
+func NewLanguageCommand(dbManager *config.DatabaseManager) *LanguageCommand {
+	return &LanguageCommand{
+		dbManager: dbManager,
+	}
+}
+
+func (c *LanguageCommand) Name() string {
+	return "language"
+}
+
+func (c *LanguageCommand) Description() string {
+	return "Show or change the bot's language for this server"
+}
+
+func (c *LanguageCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *LanguageCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *LanguageCommand) Options() []*discordgo.ApplicationCommandOption {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, locale := range i18n.Locales() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  i18n.LocaleName(locale),
+			Value: locale,
+		})
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "language",
+			Description: "Language to use for bot responses",
+			Required:    false,
+			Choices:     choices,
+		},
+	}
+}
+
+func (c *LanguageCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		current := i18n.GetGuildLocale(i.GuildID)
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "language.current", i18n.LocaleName(current))),
+		})
+		return err
+	}
+
+	locale := options[0].StringValue()
+	if !i18n.IsSupported(locale) {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "language.unsupported", locale)),
+		})
+		return err
+	}
+
+	if err := c.dbManager.SaveGuildLocale(i.GuildID, locale); err != nil {
+		logger.Error.Printf("Failed to save language for guild %s: %v", i.GuildID, err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "language.save_failed")),
+		})
+		return err
+	}
+
+	i18n.SetGuildLocale(i.GuildID, locale)
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "language.set", i18n.LocaleName(locale))),
+	})
+	return err
+}
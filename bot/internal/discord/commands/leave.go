@@ -1,28 +1,25 @@
 package commands
 
 import (
-	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/state"
-	"musicbot/internal/voice"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type LeaveCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
+	audit  *audit.Log
 }
 
-func NewLeaveCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *LeaveCommand {
+func NewLeaveCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *LeaveCommand {
 	return &LeaveCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
+		audit:  auditLog,
 	}
 }
 
@@ -34,70 +31,76 @@ func (c *LeaveCommand) Description() string {
 	return "Leave current voice channel"
 }
 
+func (c *LeaveCommand) Category() Category {
+	return CategoryVoice
+}
+
 func (c *LeaveCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *LeaveCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *LeaveCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	var err error
+
+	currentState := guild.State.GetBotState()
+
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
 
-	currentState := c.stateManager.GetBotState()
+	// Following the DJ would only drag the bot back out of idle.
+	guild.State.SetFollowedUser("")
 
-	c.stateManager.SetManualOperationActive(true)
-	defer c.stateManager.SetManualOperationActive(false)
+	guild.Music.CancelDownloads()
 
-	c.musicManager.ExecuteWithDisabledHandlers(func() {
+	guild.Music.ExecuteWithDisabledHandlers(func() {
 		if currentState == state.StateDJ {
-			c.musicManager.Stop()
+			c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionStop, "")
+			guild.Music.Stop()
 		} else {
-			c.radioManager.Stop()
+			guild.Radio.Stop()
 		}
 
 		time.Sleep(500 * time.Millisecond)
 
-		if c.stateManager.IsInIdleChannel() {
-			c.stateManager.SetBotState(state.StateIdle)
+		if guild.State.IsHome() {
+			guild.State.SetBotState(state.StateIdle)
 
 			time.Sleep(500 * time.Millisecond)
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil && !c.radioManager.IsPlaying() {
-				c.radioManager.Start(vc)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		} else {
-			err = c.voiceManager.LeaveToIdle(i.GuildID)
+			err = guild.Voice.LeaveToIdle(i.GuildID)
 			if err != nil {
 				return
 			}
 
-			c.stateManager.SetBotState(state.StateIdle)
+			guild.State.SetBotState(state.StateIdle)
 
 			time.Sleep(500 * time.Millisecond)
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil && !c.radioManager.IsPlaying() {
-				c.radioManager.Start(vc)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		}
 	})
 
 	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Failed to return to idle channel."),
+			Content: stringPtr(i18n.T(i.GuildID, "leave.failed")),
 		})
 		return err
 	}
 
-	if c.stateManager.IsInIdleChannel() {
+	if guild.State.IsHome() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("✅ This is the idle channel. Radio will continue playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "leave.idle_channel")),
 		})
 	} else {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("✅ Returned to idle channel and resumed radio."),
+			Content: stringPtr(i18n.T(i.GuildID, "leave.returned")),
 		})
 	}
 
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"context"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/lyrics"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxEmbedDescription is Discord's limit on an embed description, counted in
+// characters.
+const maxEmbedDescription = 4096
+
+type LyricsCommand struct {
+	guilds *guilds.Registry
+	lyrics *lyrics.Client
+}
+
+func NewLyricsCommand(guildRegistry *guilds.Registry, lyricsClient *lyrics.Client) *LyricsCommand {
+	return &LyricsCommand{
+		guilds: guildRegistry,
+		lyrics: lyricsClient,
+	}
+}
+
+func (c *LyricsCommand) Name() string {
+	return "lyrics"
+}
+
+func (c *LyricsCommand) Description() string {
+	return "Show the lyrics of the current song or a search"
+}
+
+func (c *LyricsCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *LyricsCommand) Examples() []string {
+	return []string{
+		"/lyrics",
+		"/lyrics query:bohemian rhapsody",
+	}
+}
+
+func (c *LyricsCommand) Cooldown() time.Duration {
+	return 5 * time.Second
+}
+
+func (c *LyricsCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "query",
+			Description: "Song to look up instead of the current one",
+			Required:    false,
+		},
+	}
+}
+
+func (c *LyricsCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	var artist, title string
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		title = options[0].StringValue()
+	} else {
+		currentSong := c.guilds.Get(i.GuildID).Music.GetCurrentSong()
+		if currentSong == nil {
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "common.no_song_playing")),
+			})
+			return err
+		}
+
+		title = currentSong.Title
+		// The downloader falls back to "Unknown" when a video has no artist.
+		if currentSong.Artist != "Unknown" {
+			artist = currentSong.Artist
+		}
+	}
+
+	found, err := c.lyrics.Find(context.Background(), artist, title)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "lyrics.not_found", title)),
+		})
+		return err
+	}
+
+	pages := splitLyrics(found.Text, maxEmbedDescription)
+	for idx, page := range pages {
+		embed := &discordgo.MessageEmbed{
+			Description: page,
+			Color:       0x5865F2,
+		}
+		if idx == 0 {
+			embed.Title = i18n.T(i.GuildID, "lyrics.title", found.Title, found.Artist)
+		}
+		if len(pages) > 1 {
+			embed.Footer = &discordgo.MessageEmbedFooter{
+				Text: i18n.T(i.GuildID, "lyrics.page", idx+1, len(pages)),
+			}
+		}
+
+		// An embed's description and a message's embeds share one length
+		// limit, so each page goes in its own message.
+		if idx == 0 {
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Embeds: &[]*discordgo.MessageEmbed{embed},
+			})
+		} else {
+			_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Embeds: []*discordgo.MessageEmbed{embed},
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitLyrics breaks text into pages of at most limit characters, splitting
+// between lines where it can.
+func splitLyrics(text string, limit int) []string {
+	var pages []string
+	var page []rune
+
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+
+		if len(page) > 0 && len(page)+1+len(runes) > limit {
+			pages = append(pages, string(page))
+			page = nil
+		}
+
+		// A single line longer than a page is cut wherever it has to be.
+		for len(runes) > limit {
+			pages = append(pages, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+
+		if len(page) > 0 {
+			page = append(page, '\n')
+		}
+		page = append(page, runes...)
+	}
+
+	if len(page) > 0 {
+		pages = append(pages, string(page))
+	}
+	return pages
+}
@@ -1,25 +1,29 @@
 package commands
 
 import (
-	"fmt"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/music"
-	"musicbot/internal/radio"
 	"musicbot/internal/state"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type NowPlayingCommand struct {
-	musicManager *music.Manager
-	radioManager *radio.Manager
+	guilds       *guilds.Registry
 	stateManager *state.Manager
+	avatars      *avatarCache
+	thumbnails   *thumbnailCache
 }
 
-func NewNowPlayingCommand(musicManager *music.Manager, radioManager *radio.Manager, stateManager *state.Manager) *NowPlayingCommand {
+func NewNowPlayingCommand(guildRegistry *guilds.Registry, stateManager *state.Manager) *NowPlayingCommand {
 	return &NowPlayingCommand{
-		musicManager: musicManager,
-		radioManager: radioManager,
+		guilds:       guildRegistry,
 		stateManager: stateManager,
+		avatars:      newAvatarCache(),
+		thumbnails:   newThumbnailCache(),
 	}
 }
 
@@ -31,69 +35,65 @@ func (c *NowPlayingCommand) Description() string {
 	return "Show what's currently playing"
 }
 
+func (c *NowPlayingCommand) Category() Category {
+	return CategoryMusic
+}
+
 func (c *NowPlayingCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *NowPlayingCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	message := c.generateNowPlayingMessage()
-
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: message,
-		},
-	})
+func (c *NowPlayingCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, c.render(s, i.GuildID).Edit())
 	return err
 }
 
-func (c *NowPlayingCommand) generateNowPlayingMessage() string {
-	currentState := c.stateManager.GetBotState()
+func (c *NowPlayingCommand) render(s discordapi.Session, guildID string) render.Message {
+	musicManager := c.guilds.Get(guildID).Music
+	guild := c.guilds.Get(guildID)
+	currentState := guild.State.GetBotState()
 
 	switch currentState {
 	case state.StateDJ:
-		currentSong := c.musicManager.GetCurrentSong()
+		currentSong := musicManager.GetCurrentSong()
 		if currentSong == nil {
-			return "🎵 **DJ Mode** - No song currently playing"
+			return render.Text(guildID, i18n.T(guildID, "nowplaying.dj_no_song"))
 		}
 
-		duration := c.formatDuration(currentSong.Duration)
-		message := fmt.Sprintf("🎧 **Now Playing:**\n**%s** - %s\n⏱️ Duration: %s",
-			currentSong.Title, currentSong.Artist, duration)
-
-		upcoming := c.musicManager.GetUpcoming(3)
-		if len(upcoming) > 0 {
-			message += "\n\n📋 **Up Next:**\n"
-			for i, song := range upcoming {
-				songDuration := c.formatDuration(song.Duration)
-				message += fmt.Sprintf("**%d.** %s - %s (%s)\n",
-					i+1, song.Title, song.Artist, songDuration)
-			}
+		np := render.NowPlaying{
+			Song:     currentSong,
+			Upcoming: musicManager.GetUpcoming(3),
 		}
-
-		return message
+		if render.GuildStyle(guildID) != render.StylePlain {
+			np.RequesterAvatar = c.avatars.URL(s, guildID, currentSong.RequesterID)
+			np.Thumbnail, np.ThumbnailFile = c.thumbnails.Thumbnail(currentSong, c.stateManager.GetConfig().DownloadDir)
+		}
+		if filter := musicManager.ActiveFilter(); filter != music.FilterOff {
+			np.Filter = string(filter)
+		}
+		return render.NowPlayingSong(guildID, np)
 
 	case state.StateRadio:
-		streamName := c.getStreamName()
+		streamName := c.getStreamName(guild.State)
 		if streamName != "" {
-			return fmt.Sprintf("📻 **Radio Mode** - Playing: %s", streamName)
+			return render.Text(guildID, i18n.T(guildID, "nowplaying.radio_named", streamName))
 		}
-		return "📻 **Radio Mode** - Playing radio stream"
+		return render.Text(guildID, i18n.T(guildID, "nowplaying.radio"))
 
 	case state.StateIdle:
-		streamName := c.getStreamName()
+		streamName := c.getStreamName(guild.State)
 		if streamName != "" {
-			return fmt.Sprintf("😴 **Idle Mode** - Playing: %s", streamName)
+			return render.Text(guildID, i18n.T(guildID, "nowplaying.idle_named", streamName))
 		}
-		return "😴 **Idle Mode** - Playing radio stream"
+		return render.Text(guildID, i18n.T(guildID, "nowplaying.idle"))
 
 	default:
-		return "❓ **Unknown State** - Not sure what's playing"
+		return render.Text(guildID, i18n.T(guildID, "nowplaying.unknown"))
 	}
 }
 
-func (c *NowPlayingCommand) getStreamName() string {
-	currentStreamURL := c.stateManager.GetRadioStream()
+func (c *NowPlayingCommand) getStreamName(guildState *state.Guild) string {
+	currentStreamURL := guildState.GetRadioStream()
 
 	streamNames := map[string]string{
 		"https://listen.moe/stream":                      "listen.moe",
@@ -107,13 +107,3 @@ func (c *NowPlayingCommand) getStreamName() string {
 
 	return ""
 }
-
-func (c *NowPlayingCommand) formatDuration(seconds int) string {
-	if seconds <= 0 {
-		return "Unknown"
-	}
-
-	minutes := seconds / 60
-	secs := seconds % 60
-	return fmt.Sprintf("%d:%02d", minutes, secs)
-}
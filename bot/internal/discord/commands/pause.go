@@ -1,28 +1,25 @@
 package commands
 
 import (
-	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/state"
-	"musicbot/internal/voice"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type PauseCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
+	audit  *audit.Log
 }
 
-func NewPauseCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *PauseCommand {
+func NewPauseCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *PauseCommand {
 	return &PauseCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
+		audit:  auditLog,
 	}
 }
 
@@ -34,95 +31,101 @@ func (c *PauseCommand) Description() string {
 	return "Pause music and switch to idle mode"
 }
 
+func (c *PauseCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PauseCommand) ControlsMusic() bool {
+	return true
+}
+
 func (c *PauseCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *PauseCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *PauseCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	var err error
 
-	currentState := c.stateManager.GetBotState()
+	currentState := guild.State.GetBotState()
 
 	if currentState != state.StateDJ {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No music is currently playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "pause.no_music")),
 		})
 		return err
 	}
 
-	if !c.musicManager.IsPlaying() {
+	if !guild.Music.IsPlaying() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No song is currently playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.no_song_playing")),
 		})
 		return err
 	}
 
-	if c.musicManager.IsPaused() {
+	if guild.Music.IsPaused() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Music is already paused."),
+			Content: stringPtr(i18n.T(i.GuildID, "pause.already_paused")),
 		})
 		return err
 	}
 
-	currentSong := c.musicManager.GetCurrentSong()
+	currentSong := guild.Music.GetCurrentSong()
 	if currentSong == nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No song is currently playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.no_song_playing")),
 		})
 		return err
 	}
 
-	c.stateManager.SetManualOperationActive(true)
-	defer c.stateManager.SetManualOperationActive(false)
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
 
-	c.musicManager.ExecuteWithDisabledHandlers(func() {
-		err = c.musicManager.Pause()
+	guild.Music.ExecuteWithDisabledHandlers(func() {
+		err = guild.Music.Pause()
 		if err != nil {
 			return
 		}
 
 		time.Sleep(500 * time.Millisecond)
 
-		if c.stateManager.IsInIdleChannel() {
-			c.stateManager.SetBotState(state.StateIdle)
+		if guild.State.IsHome() {
+			guild.State.SetBotState(state.StateIdle)
 
 			time.Sleep(500 * time.Millisecond)
 
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil && !c.radioManager.IsPlaying() {
-				c.radioManager.Start(vc)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		} else {
-			err = c.voiceManager.LeaveToIdle(i.GuildID)
+			err = guild.Voice.LeaveToIdle(i.GuildID)
 			if err != nil {
 				return
 			}
 
-			c.stateManager.SetBotState(state.StateIdle)
+			guild.State.SetBotState(state.StateIdle)
 
 			time.Sleep(500 * time.Millisecond)
 
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil && !c.radioManager.IsPlaying() {
-				c.radioManager.Start(vc)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		}
 	})
 
 	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Failed to pause music."),
+			Content: stringPtr(i18n.T(i.GuildID, "pause.failed")),
 		})
 		return err
 	}
 
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionPause, currentSong.Title)
+
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr("⏸️ Music paused. Use `/resume` to continue playing."),
+		Content: stringPtr(i18n.T(i.GuildID, "pause.paused")),
 	})
 	return err
 }
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/janitor"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// pinsShown bounds the /pins list. Titles are long enough that it can still
+// take more than one message.
+const pinsShown = 20
+
+func pinOptions(action string) []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "url",
+			Description: "URL of the track to " + action + " (defaults to the current track)",
+			Required:    false,
+		},
+	}
+}
+
+// pinTarget finds the song a /pin or /unpin is about: the one at the url
+// option, or else the one playing. It returns the message to show instead
+// when there is none.
+func pinTarget(i *discordgo.InteractionCreate, guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) (*state.Song, string, error) {
+	url := ""
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		url = strings.TrimSpace(options[0].StringValue())
+	} else {
+		current := guildRegistry.Get(i.GuildID).Music.GetCurrentSong()
+		if current == nil {
+			return nil, i18n.T(i.GuildID, "pin.nothing_playing"), nil
+		}
+		if current.IsStream {
+			return nil, i18n.T(i.GuildID, "pin.stream"), nil
+		}
+		url = current.URL
+	}
+
+	song, err := dbManager.GetSongByURL(url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, i18n.T(i.GuildID, "pin.unknown"), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if song.IsStream {
+		return nil, i18n.T(i.GuildID, "pin.stream"), nil
+	}
+	return song, "", nil
+}
+
+// PinCommand keeps a track in the cache for good, such as a server anthem
+// that has to start right away. The cache is shared, so a pin holds for
+// every server.
+type PinCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewPinCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *PinCommand {
+	return &PinCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *PinCommand) Name() string {
+	return "pin"
+}
+
+func (c *PinCommand) Description() string {
+	return "Keep a track in the cache so it never has to be downloaded again"
+}
+
+func (c *PinCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PinCommand) Examples() []string {
+	return []string{
+		"/pin",
+		"/pin url:https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+	}
+}
+
+func (c *PinCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *PinCommand) Options() []*discordgo.ApplicationCommandOption {
+	return pinOptions("pin")
+}
+
+func (c *PinCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	song, message, err := pinTarget(i, c.guilds, c.dbManager)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to look up the track to pin", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.failed"))
+	}
+	if song == nil {
+		return c.respond(s, i, message)
+	}
+
+	changed, err := c.dbManager.SetSongPinned(song.ID, true)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to pin track", "song_id", song.ID, "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.failed"))
+	}
+	if changed {
+		c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionPin, song.URL)
+	}
+
+	content := i18n.T(i.GuildID, "pin.pinned", song.Title)
+	if !changed {
+		content = i18n.T(i.GuildID, "pin.already_pinned", song.Title)
+	}
+
+	// A pinned track is only useful while its file is there, so a missing
+	// one is downloaded again right away.
+	if c.guilds.Get(i.GuildID).Music.FileMissing(song) {
+		content += "\n" + c.redownload(i.GuildID, song)
+	}
+	return c.respond(s, i, content)
+}
+
+// redownload fetches the missing file of song and says how that went.
+func (c *PinCommand) redownload(guildID string, song *state.Song) string {
+	musicManager := c.guilds.Get(guildID).Music
+	if !music.Redownloadable(song) {
+		return i18n.T(guildID, "pin.missing_upload")
+	}
+
+	err := musicManager.RepairSong(song.URL, musicManager.DownloadLimits(guildID), nil)
+	if err != nil && !errors.Is(err, music.ErrRepairPending) {
+		logger.ForCommand(guildID, c.Name()).Error("Failed to download pinned track again", "url", song.URL, "error", err)
+		return i18n.T(guildID, "pin.redownload_failed", err.Error())
+	}
+	return i18n.T(guildID, "pin.redownloading")
+}
+
+func (c *PinCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// UnpinCommand lets the cache remove a pinned track again once it is old or
+// the cache is full.
+type UnpinCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewUnpinCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *UnpinCommand {
+	return &UnpinCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *UnpinCommand) Name() string {
+	return "unpin"
+}
+
+func (c *UnpinCommand) Description() string {
+	return "Let a pinned track be removed from the cache again"
+}
+
+func (c *UnpinCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *UnpinCommand) Examples() []string {
+	return []string{
+		"/unpin",
+		"/unpin url:https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+	}
+}
+
+func (c *UnpinCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *UnpinCommand) Options() []*discordgo.ApplicationCommandOption {
+	return pinOptions("unpin")
+}
+
+func (c *UnpinCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	song, message, err := pinTarget(i, c.guilds, c.dbManager)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to look up the track to unpin", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.failed"))
+	}
+	if song == nil {
+		return c.respond(s, i, message)
+	}
+
+	changed, err := c.dbManager.SetSongPinned(song.ID, false)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to unpin track", "song_id", song.ID, "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.failed"))
+	}
+	if !changed {
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.not_pinned", song.Title))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionUnpin, song.URL)
+	return c.respond(s, i, i18n.T(i.GuildID, "pin.unpinned", song.Title))
+}
+
+func (c *UnpinCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// PinsCommand lists the pinned tracks and how much of the cache they take.
+type PinsCommand struct {
+	dbManager *config.DatabaseManager
+	janitor   *janitor.Janitor
+}
+
+func NewPinsCommand(dbManager *config.DatabaseManager, cacheJanitor *janitor.Janitor) *PinsCommand {
+	return &PinsCommand{
+		dbManager: dbManager,
+		janitor:   cacheJanitor,
+	}
+}
+
+func (c *PinsCommand) Name() string {
+	return "pins"
+}
+
+func (c *PinsCommand) Description() string {
+	return "List the tracks pinned in the cache"
+}
+
+func (c *PinsCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PinsCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *PinsCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	songs, err := c.dbManager.GetPinnedSongs()
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to list pinned tracks", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "pin.failed"))
+	}
+	if len(songs) == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "pins.none"))
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(i.GuildID, "pins.title", len(songs)))
+
+	// The total counts every pinned file, listed or not.
+	var total int64
+	for idx := range songs {
+		song := &songs[idx]
+		size, ok := c.janitor.FileSize(song.FilePath)
+		total += size
+
+		if idx >= pinsShown {
+			continue
+		}
+		sizeText := i18n.T(i.GuildID, "pins.missing")
+		if ok {
+			sizeText = janitor.FormatBytes(size)
+		}
+		b.WriteString(i18n.T(i.GuildID, "pins.line", idx+1, song.Title, sizeText))
+	}
+	if len(songs) > pinsShown {
+		b.WriteString(i18n.T(i.GuildID, "pins.more", len(songs)-pinsShown))
+	}
+
+	b.WriteString("\n" + i18n.T(i.GuildID, "pins.total", janitor.FormatBytes(total)))
+	if budget := c.janitor.MaxCacheBytes(); budget > 0 {
+		b.WriteString(i18n.T(i.GuildID, "pins.budget", janitor.FormatBytes(budget)))
+		if total > budget/2 {
+			b.WriteString("\n" + i18n.T(i.GuildID, "pins.over_half"))
+		}
+	}
+
+	return sendChunked(s, i, render.Chunks(b.String()), nil, false)
+}
+
+func (c *PinsCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
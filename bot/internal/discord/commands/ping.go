@@ -2,6 +2,8 @@ package commands
 
 import (
 	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
 	"time"
 
 	"musicbot/internal/socket"
@@ -10,11 +12,11 @@ import (
 )
 
 type PingCommand struct {
-	session      *discordgo.Session
+	session      discordapi.Session
 	socketClient *socket.Client
 }
 
-func NewPingCommand(session *discordgo.Session, socketClient *socket.Client) *PingCommand {
+func NewPingCommand(session discordapi.Session, socketClient *socket.Client) *PingCommand {
 	return &PingCommand{
 		session:      session,
 		socketClient: socketClient,
@@ -29,23 +31,23 @@ func (c *PingCommand) Description() string {
 	return "Check bot latency and response time, and downloader status"
 }
 
+func (c *PingCommand) Category() Category {
+	return CategoryUtility
+}
+
 func (c *PingCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *PingCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	startTime := time.Now()
-
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
+func (c *PingCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	// The router has deferred the response by now, so the response time is
+	// counted from when Discord created the interaction.
+	responseTime := time.Duration(0)
+	if created, err := discordgo.SnowflakeTimestamp(i.ID); err == nil {
+		responseTime = time.Since(created)
 	}
-
-	responseTime := time.Since(startTime)
 	wsLatency := s.HeartbeatLatency()
-	botStatus := c.getLatencyStatus(wsLatency)
+	botStatus := c.getLatencyStatus(i.GuildID, wsLatency)
 
 	downloaderStatus := c.socketClient.GetDownloaderStatus()
 	downloaderPingLatency := "N/A"
@@ -65,53 +67,33 @@ func (c *PingCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCrea
 		downloaderError = "Not connected to downloader service"
 	}
 
-	var content string
+	content := i18n.T(i.GuildID, "ping.pong",
+		wsLatency.Milliseconds(),
+		botStatus,
+		responseTime.Milliseconds(),
+		downloaderStatus,
+		downloaderPingLatency,
+	)
 	if downloaderError != "" {
-		content = fmt.Sprintf("🏓 **Pong!**\n\n"+
-			"📡 **WebSocket Latency:** %dms %s\n"+
-			"⚡ **Bot Response Time:** %dms\n"+
-			"🤖 **Bot Status:** Online and Ready\n"+
-			"⬇️ **Downloader Status:** %s\n"+
-			"📶 **Downloader Ping:** %s\n"+
-			"❌ **Downloader Error:** %s",
-			wsLatency.Milliseconds(),
-			botStatus,
-			responseTime.Milliseconds(),
-			downloaderStatus,
-			downloaderPingLatency,
-			downloaderError,
-		)
-	} else {
-		content = fmt.Sprintf("🏓 **Pong!**\n\n"+
-			"📡 **WebSocket Latency:** %dms %s\n"+
-			"⚡ **Bot Response Time:** %dms\n"+
-			"🤖 **Bot Status:** Online and Ready\n"+
-			"⬇️ **Downloader Status:** %s\n"+
-			"📶 **Downloader Ping:** %s",
-			wsLatency.Milliseconds(),
-			botStatus,
-			responseTime.Milliseconds(),
-			downloaderStatus,
-			downloaderPingLatency,
-		)
+		content += i18n.T(i.GuildID, "ping.downloader_error", downloaderError)
 	}
 
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &content,
 	})
 	return err
 }
 
-func (c *PingCommand) getLatencyStatus(latency time.Duration) string {
+func (c *PingCommand) getLatencyStatus(guildID string, latency time.Duration) string {
 	ms := latency.Milliseconds()
 
 	if ms < 100 {
-		return "🟢 (Excellent)"
+		return i18n.T(guildID, "ping.latency_excellent")
 	} else if ms < 200 {
-		return "🟡 (Good)"
+		return i18n.T(guildID, "ping.latency_good")
 	} else if ms < 500 {
-		return "🟠 (Fair)"
+		return i18n.T(guildID, "ping.latency_fair")
 	} else {
-		return "🔴 (Poor)"
+		return i18n.T(guildID, "ping.latency_poor")
 	}
 }
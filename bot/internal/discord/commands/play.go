@@ -1,29 +1,41 @@
 package commands
 
 import (
-	"fmt"
+	"context"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
 	"musicbot/internal/music"
-	"musicbot/internal/radio"
-	"musicbot/internal/state"
+	"musicbot/internal/permissions"
+	"musicbot/internal/socket"
+	"musicbot/internal/urlnorm"
 	"musicbot/internal/voice"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// durationProbeTimeout bounds how long /play waits to learn a track's length
+// before downloading it.
+const durationProbeTimeout = 10 * time.Second
+
 type PlayCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds            *guilds.Registry
+	permissionManager *permissions.Manager
+	blacklist         *blacklist.List
+	audit             *audit.Log
 }
 
-func NewPlayCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *PlayCommand {
+func NewPlayCommand(guildRegistry *guilds.Registry, permissionManager *permissions.Manager, blacklistList *blacklist.List, auditLog *audit.Log) *PlayCommand {
 	return &PlayCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds:            guildRegistry,
+		permissionManager: permissionManager,
+		blacklist:         blacklistList,
+		audit:             auditLog,
 	}
 }
 
@@ -35,6 +47,31 @@ func (c *PlayCommand) Description() string {
 	return "Play a song from URL"
 }
 
+func (c *PlayCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PlayCommand) QueuesSongs() bool {
+	return true
+}
+
+func (c *PlayCommand) Examples() []string {
+	return []string{
+		"/play url:https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"/play url:https://soundcloud.com/artist/track force:True",
+		"/play url:https://artist.bandcamp.com/track/song platform:Bandcamp",
+		"/play url:https://www.youtube.com/watch?v=jfKfPfyJRdk live:True",
+	}
+}
+
+func (c *PlayCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *PlayCommand) Cooldown() time.Duration {
+	return 5 * time.Second
+}
+
 func (c *PlayCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{
 		{
@@ -43,88 +80,163 @@ func (c *PlayCommand) Options() []*discordgo.ApplicationCommandOption {
 			Description: "URL of the song to play",
 			Required:    true,
 		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "allow_long",
+			Description: "Raise the length and size limits for this song (DJ only)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "live",
+			Description: "Allow a live stream, played until it ends or is skipped (DJ only)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "force",
+			Description: "Add the song even if it is already playing or queued",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "platform",
+			Description: "Where the song is from (told from the URL by default)",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "SoundCloud", Value: socket.PlatformSoundCloud},
+				{Name: "YouTube", Value: socket.PlatformYouTube},
+				{Name: "YouTube Music", Value: socket.PlatformYouTubeMusic},
+				{Name: "Bandcamp", Value: socket.PlatformBandcamp},
+			},
+		},
 	}
 }
 
-func (c *PlayCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *PlayCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
 
 	url := i.ApplicationCommandData().Options[0].StringValue()
 	userID := i.Member.User.ID
 
-	userVS, err := s.State.VoiceState(i.GuildID, userID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
-		})
-		return err
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, userID, url); blocked {
+		return followUpBlacklisted(s, i, reason)
 	}
-
-	userChannelID := userVS.ChannelID
-	currentChannelID := c.stateManager.GetCurrentChannel()
-
-	if currentChannelID != "" && currentChannelID != userChannelID {
-		currentBotState := c.stateManager.GetBotState()
-
-		if currentBotState == state.StateDJ && c.musicManager.IsPlaying() {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Bot is currently playing music in another channel."),
-			})
-			return err
+	// The platform is told before normalizing, which turns YouTube Music
+	// links into plain YouTube ones.
+	limits := guild.Music.DownloadLimits(i.GuildID)
+	limits.Platform = socket.DetectPlatform(url)
+	url = urlnorm.Normalize(url)
+
+	force := false
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "platform" {
+			limits.Platform = option.StringValue()
+			continue
+		}
+		if option.Type != discordgo.ApplicationCommandOptionBoolean || !option.BoolValue() {
+			continue
 		}
 
-		c.radioManager.Stop()
-		c.musicManager.Stop()
-
-		time.Sleep(500 * time.Millisecond)
+		if option.Name == "force" {
+			force = true
+			continue
+		}
 
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
-		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
+		if !c.isDJ(s, i) {
+			roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, permissions.LevelDJ)
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "play.dj_option_denied", roleName, option.Name)),
 			})
 			return err
 		}
 
-		time.Sleep(500 * time.Millisecond)
-
-		if currentBotState == state.StateRadio && !c.radioManager.IsPlaying() {
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil {
-				c.radioManager.Start(vc)
-			}
-		}
-	} else if currentChannelID == "" {
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
-		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
-			})
-			return err
+		switch option.Name {
+		case "allow_long":
+			limits = limits.Extended()
+		case "live":
+			limits.AllowLive = true
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
 
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr(fmt.Sprintf("🎵 Downloading song from: %s\n⏳ This may take a moment...", url)),
+	if message := c.prepare(s, guild, userID, url, limits); message != "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(message),
+		})
+		return err
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "play.downloading", url)),
 	})
 	if err != nil {
 		return err
 	}
 
-	go func() {
-		err := c.musicManager.RequestSong(url, userID)
-		if err != nil {
-			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("❌ Failed to request song: %v", err)),
-			})
+	go c.request(i.GuildID, userID, url, force, limits, newRequestFollowUp(s, i))
+	return nil
+}
+
+// prepare makes the checks that come before a download of url for userID
+// and takes the bot to the user's voice channel. It returns why the request
+// is turned away, or "" if it may go ahead. Request channels go through it
+// as well, so a pasted link is held to the same rules as /play.
+func (c *PlayCommand) prepare(s discordapi.Session, guild *guilds.Guild, userID, url string, limits config.DownloadLimits) string {
+	if _, err := guild.Music.RemainingCapacity(userID); err != nil {
+		return requestErrorMessage(guild.ID, err)
+	}
+
+	if limits.Strict() {
+		if err := c.checkDuration(guild.ID, url, limits); err != nil {
+			return requestErrorMessage(guild.ID, err)
 		}
-	}()
+	}
 
-	return nil
+	userChannelID, err := voice.UserVoiceChannel(s, guild.ID, userID)
+	if err != nil {
+		return i18n.T(guild.ID, "common.not_in_voice")
+	}
+
+	return joinForRequest(c.guilds, guild, userID, userChannelID)
+}
+
+// request downloads and queues url for userID, telling notifier how it
+// went. With force the song is queued even if it already is.
+func (c *PlayCommand) request(guildID, userID, url string, force bool, limits config.DownloadLimits, notifier music.RequestNotifier) {
+	musicManager := c.guilds.Get(guildID).Music
+	request := musicManager.RequestSong
+	if force {
+		request = musicManager.RequestSongAllowDuplicate
+	}
+
+	if err := request(url, userID, limits, notifier); err != nil {
+		notifier.Failed(err)
+		return
+	}
+	c.audit.Record(guildID, userID, audit.ActionPlay, url)
+}
+
+// checkDuration refuses a track over a strict duration limit before it is
+// downloaded. The probe is best effort: when it fails, the downloader still
+// enforces the limit.
+func (c *PlayCommand) checkDuration(guildID, url string, limits config.DownloadLimits) error {
+	ctx, cancel := context.WithTimeout(context.Background(), durationProbeTimeout)
+	defer cancel()
+
+	seconds, err := c.guilds.Get(guildID).Music.ProbeDuration(ctx, url)
+	if err != nil {
+		logger.ForCommand(guildID, c.Name()).Debug("Failed to probe the track's length", "url", url, "error", err)
+		return nil
+	}
+	return music.CheckDuration(seconds, limits)
+}
+
+// isDJ reports whether the user may use the DJ-only options.
+func (c *PlayCommand) isDJ(s discordapi.Session, i *discordgo.InteractionCreate) bool {
+	isDJ, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, permissions.LevelDJ)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Permission check for DJ option failed", "error", err)
+		return false
+	}
+	return isDJ
 }
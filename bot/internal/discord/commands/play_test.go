@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+	"musicbot/internal/socket/sockettest"
+	"testing"
+)
+
+func TestPlayCommand(t *testing.T) {
+	const url = "https://soundcloud.com/artist/song"
+
+	tests := []struct {
+		name     string
+		inVoice  bool
+		download sockettest.Handler
+		want     string
+		queued   bool
+	}{
+		{
+			name:    "queues the song",
+			inVoice: true,
+			download: func(request sockettest.Request) []sockettest.Response {
+				return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+					"title":    "Song",
+					"url":      url,
+					"filename": "/tmp/song.mp3",
+					"duration": 200,
+				})}
+			},
+			want:   i18n.T(testGuildID, "play.queued", "Song"),
+			queued: true,
+		},
+		{
+			name: "user not in voice",
+			want: i18n.T(testGuildID, "common.not_in_voice"),
+		},
+		{
+			name:    "download fails",
+			inVoice: true,
+			download: func(request sockettest.Request) []sockettest.Response {
+				return []sockettest.Response{sockettest.Failure(request, "ERROR: This video is private")}
+			},
+			want: i18n.T(testGuildID, "download.private"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.download != nil {
+				env.downloader.Handle("download_audio", tt.download)
+			}
+			if tt.inVoice {
+				env.session.joinVoice(t, "user", testVoiceID)
+				// The bot is there already, so it doesn't have to join.
+				env.guilds.Get(testGuildID).State.SetCurrentChannel(testVoiceID)
+			}
+
+			cmd := NewPlayCommand(env.guilds, permissions.NewManager(permissions.Config{}, nil), env.blacklist, env.audit)
+			i := commandInteraction("user", "play", stringOption("url", url))
+			if err := cmd.Execute(env.session, i); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			env.session.awaitContent(t, i, tt.want)
+
+			requests := env.downloader.Requests("download_audio")
+			if !tt.inVoice {
+				if len(requests) != 0 {
+					t.Errorf("sent %d downloads for a user who isn't in voice", len(requests))
+				}
+				return
+			}
+			if len(requests) != 1 || requests[0].Params["url"] != url {
+				t.Fatalf("download requests = %v, want one for %s", requests, url)
+			}
+
+			queue := env.guilds.Get(testGuildID).Music.GetQueue()
+			if tt.queued != (len(queue) == 1) {
+				t.Errorf("queue holds %d songs, want queued = %v", len(queue), tt.queued)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type PlayFileCommand struct {
+	guilds    *guilds.Registry
+	blacklist *blacklist.List
+	audit     *audit.Log
+}
+
+func NewPlayFileCommand(guildRegistry *guilds.Registry, blacklistList *blacklist.List, auditLog *audit.Log) *PlayFileCommand {
+	return &PlayFileCommand{
+		guilds:    guildRegistry,
+		blacklist: blacklistList,
+		audit:     auditLog,
+	}
+}
+
+func (c *PlayFileCommand) Name() string {
+	return "playfile"
+}
+
+func (c *PlayFileCommand) Description() string {
+	return "Play an audio file you upload"
+}
+
+func (c *PlayFileCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PlayFileCommand) QueuesSongs() bool {
+	return true
+}
+
+func (c *PlayFileCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *PlayFileCommand) Cooldown() time.Duration {
+	return 5 * time.Second
+}
+
+func (c *PlayFileCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionAttachment,
+			Name:        "file",
+			Description: "Audio file to play",
+			Required:    true,
+		},
+	}
+}
+
+func (c *PlayFileCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, i.Member.User.ID, ""); blocked {
+		return followUpBlacklisted(s, i, reason)
+	}
+
+	data := i.ApplicationCommandData()
+	attachmentID, _ := data.Options[0].Value.(string)
+	attachment := data.Resolved.Attachments[attachmentID]
+	if attachment == nil {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "playfile.unsupported", strings.Join(config.TrackExtensions, ", "))),
+		})
+		return err
+	}
+
+	upload := music.Upload{
+		URL:         attachment.URL,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+	}
+	limits := musicManager.DownloadLimits(i.GuildID)
+
+	// Check the file before joining anyone's channel for it.
+	if err := upload.Validate(limits); err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(c.errorMessage(i.GuildID, err)),
+		})
+		return err
+	}
+
+	userID := i.Member.User.ID
+
+	if message := joinUserForRequest(s, c.guilds, c.guilds.Get(i.GuildID), userID); message != "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(message),
+		})
+		return err
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "playfile.uploading", attachment.Filename)),
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		song, err := musicManager.QueueUpload(upload, userID, i.Member.User.Username, limits)
+		if err != nil {
+			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(c.errorMessage(i.GuildID, err)),
+			})
+			return
+		}
+		c.audit.Record(i.GuildID, userID, audit.ActionPlayFile, song.Title)
+
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "playfile.queued", song.Title)),
+		})
+	}()
+
+	return nil
+}
+
+func (c *PlayFileCommand) errorMessage(guildID string, err error) string {
+	if errors.Is(err, music.ErrUnsupportedUpload) {
+		return i18n.T(guildID, "playfile.unsupported", strings.Join(config.TrackExtensions, ", "))
+	}
+
+	var tooLarge *music.UploadTooLargeError
+	if errors.As(err, &tooLarge) {
+		return i18n.T(guildID, "playfile.too_large", tooLarge.LimitMB)
+	}
+
+	return requestErrorMessage(guildID, err)
+}
@@ -1,10 +1,14 @@
 package commands
 
 import (
-	"fmt"
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/music"
-	"musicbot/internal/radio"
-	"musicbot/internal/state"
+	"musicbot/internal/permissions"
 	"musicbot/internal/voice"
 	"time"
 
@@ -12,18 +16,16 @@ import (
 )
 
 type PlaylistCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds    *guilds.Registry
+	blacklist *blacklist.List
+	audit     *audit.Log
 }
 
-func NewPlaylistCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *PlaylistCommand {
+func NewPlaylistCommand(guildRegistry *guilds.Registry, blacklistList *blacklist.List, auditLog *audit.Log) *PlaylistCommand {
 	return &PlaylistCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds:    guildRegistry,
+		blacklist: blacklistList,
+		audit:     auditLog,
 	}
 }
 
@@ -35,6 +37,37 @@ func (c *PlaylistCommand) Description() string {
 	return "Play a playlist from URL"
 }
 
+func (c *PlaylistCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *PlaylistCommand) QueuesSongs() bool {
+	return true
+}
+
+func (c *PlaylistCommand) Examples() []string {
+	return []string{
+		"/playlist url:https://www.youtube.com/playlist?list=PL...",
+		"/playlist url:https://soundcloud.com/artist/sets/album limit:10",
+	}
+}
+
+func (c *PlaylistCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *PlaylistCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *PlaylistCommand) Cooldown() time.Duration {
+	return 60 * time.Second
+}
+
+func (c *PlaylistCommand) MaxConcurrentPerGuild() int {
+	return 1
+}
+
 func (c *PlaylistCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{
 		{
@@ -54,18 +87,30 @@ func (c *PlaylistCommand) Options() []*discordgo.ApplicationCommandOption {
 	}
 }
 
-func (c *PlaylistCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *PlaylistCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return c.Start(s, i, func() {})
+}
+
+// Start requests the playlist and calls done once the download has finished,
+// failed or been cancelled.
+func (c *PlaylistCommand) Start(s discordapi.Session, i *discordgo.InteractionCreate, done func()) error {
+	started := false
+	defer func() {
+		if !started {
+			done()
+		}
+	}()
+
+	guild := c.guilds.Get(i.GuildID)
 
 	options := i.ApplicationCommandData().Options
 	url := options[0].StringValue()
 	userID := i.Member.User.ID
 
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, userID, url); blocked {
+		return followUpBlacklisted(s, i, reason)
+	}
+
 	limit := 20
 	if len(options) > 1 && options[1].IntValue() > 0 {
 		providedLimit := int(options[1].IntValue())
@@ -76,74 +121,79 @@ func (c *PlaylistCommand) Execute(s *discordgo.Session, i *discordgo.Interaction
 		}
 	}
 
-	userVS, err := s.State.VoiceState(i.GuildID, userID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
+	remaining, err := guild.Music.RemainingCapacity(userID)
+	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
+			Content: stringPtr(requestErrorMessage(i.GuildID, err)),
 		})
 		return err
 	}
 
-	userChannelID := userVS.ChannelID
-	currentChannelID := c.stateManager.GetCurrentChannel()
-
-	if currentChannelID != "" && currentChannelID != userChannelID {
-		currentBotState := c.stateManager.GetBotState()
-
-		if currentBotState == state.StateDJ && c.musicManager.IsPlaying() {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Bot is currently playing music in another channel."),
-			})
-			return err
-		}
-
-		c.radioManager.Stop()
-		c.musicManager.Stop()
-
-		time.Sleep(500 * time.Millisecond)
+	requestedLimit := limit
+	if limit > remaining {
+		limit = remaining
+	}
 
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
-		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
-			})
-			return err
-		}
+	userChannelID, err := voice.UserVoiceChannel(s, i.GuildID, userID)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "common.not_in_voice")),
+		})
+		return err
+	}
 
-		time.Sleep(500 * time.Millisecond)
+	if message := joinForRequest(c.guilds, guild, userID, userChannelID); message != "" {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(message),
+		})
+		return err
+	}
 
-		if currentBotState == state.StateRadio && !c.radioManager.IsPlaying() {
-			vc := c.voiceManager.GetVoiceConnection()
-			if vc != nil {
-				c.radioManager.Start(vc)
-			}
-		}
-	} else if currentChannelID == "" {
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
-		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
-			})
-			return err
-		}
-		time.Sleep(500 * time.Millisecond)
+	content := i18n.T(i.GuildID, "playlist.starting", url, limit)
+	if limit < requestedLimit {
+		content += i18n.T(i.GuildID, "playlist.clamped", requestedLimit, limit)
 	}
 
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr(fmt.Sprintf("📜 Starting playlist download from: %s\n⏳ Downloading up to %d songs. Songs will be added to queue as they download...", url, limit)),
+		Content: stringPtr(content),
 	})
 	if err != nil {
 		return err
 	}
 
+	started = true
 	go func() {
-		err := c.musicManager.RequestPlaylist(url, userID, limit)
+		limits := guild.Music.DownloadLimits(i.GuildID)
+		followUp := newRequestFollowUp(s, i)
+		err := guild.Music.RequestPlaylist(url, userID, limit, limits, &playlistDoneNotifier{followUp, done})
 		if err != nil {
-			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("❌ Failed to request playlist: %v", err)),
-			})
+			content := i18n.T(i.GuildID, "playlist.request_failed", err)
+			var limitErr *music.LimitError
+			if errors.As(err, &limitErr) || errors.Is(err, music.ErrPlaylistInProgress) || errors.Is(err, music.ErrPlaylistCancelled) {
+				content = requestErrorMessage(i.GuildID, err)
+			}
+			followUp.Finish(content)
+			done()
+			return
 		}
+		c.audit.Record(i.GuildID, userID, audit.ActionPlaylist, url)
 	}()
 
 	return nil
 }
+
+// playlistDoneNotifier calls done once the playlist it reports on is over.
+type playlistDoneNotifier struct {
+	music.PlaylistNotifier
+	done func()
+}
+
+func (n *playlistDoneNotifier) PlaylistDone(added, duplicates int, skipped music.Skipped) {
+	n.PlaylistNotifier.PlaylistDone(added, duplicates, skipped)
+	n.done()
+}
+
+func (n *playlistDoneNotifier) Failed(err error) {
+	n.PlaylistNotifier.Failed(err)
+	n.done()
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music/musictest"
+	"musicbot/internal/socket"
+	"musicbot/internal/socket/sockettest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPlaylistDoesNotBlockGuild runs /playlist against a Discord that takes
+// a second to answer and checks that /queue in the same guild keeps
+// answering straight away until it is done.
+func TestPlaylistDoesNotBlockGuild(t *testing.T) {
+	const maxBlocked = 100 * time.Millisecond
+
+	env := newTestEnv(t)
+	queueSongs(t, env, 3)
+	env.session.delay = time.Second
+	env.session.joinVoice(t, "owner", testVoiceID)
+	env.guilds.Get(testGuildID).State.SetCurrentChannel(testVoiceID)
+
+	playlist := NewPlaylistCommand(env.guilds, env.blacklist, env.audit)
+	done := make(chan error, 1)
+	go func() {
+		done <- playlist.Execute(env.session, commandInteraction("owner", "playlist", stringOption("url", "https://soundcloud.com/artist/sets/mix")))
+	}()
+
+	// /queue answers through a Discord of its own that isn't slow, so any
+	// time it takes is time spent waiting on the guild.
+	fast := newFakeSession(t)
+	queue := NewQueueCommand(env.guilds)
+	runs := 0
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("playlist Execute: %v", err)
+			}
+			if runs < 5 {
+				t.Fatalf("/queue ran %d times while /playlist was busy, want it to keep running", runs)
+			}
+			return
+		default:
+		}
+
+		start := time.Now()
+		if err := queue.Execute(fast, commandInteraction("listener", "queue")); err != nil {
+			t.Fatalf("queue Execute: %v", err)
+		}
+		if took := time.Since(start); took > maxBlocked {
+			t.Fatalf("/queue took %s while /playlist was busy, want at most %s", took, maxBlocked)
+		}
+		runs++
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestPlaylistDoneOnlyWhenOver checks that /playlist reports its work done
+// once the download is over, not when it answers, and that asking for the
+// same playlist meanwhile is turned down with a message.
+func TestPlaylistDoneOnlyWhenOver(t *testing.T) {
+	env := newTestEnv(t)
+	env.session.joinVoice(t, "owner", testVoiceID)
+	env.guilds.Get(testGuildID).State.SetCurrentChannel(testVoiceID)
+
+	answer := make(chan struct{})
+	env.downloader.Handle("get_playlist_info", func(request sockettest.Request) []sockettest.Response {
+		<-answer
+		return []sockettest.Response{sockettest.Failure(request, "playlist is private")}
+	})
+
+	playlist := NewPlaylistCommand(env.guilds, env.blacklist, env.audit)
+	url := stringOption("url", "https://soundcloud.com/artist/sets/mix")
+
+	first := commandInteraction("owner", "playlist", url)
+	firstDone := make(chan struct{})
+	if err := playlist.Start(env.session, first, func() { close(firstDone) }); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	env.downloader.Await("get_playlist_info", 1)
+
+	second := commandInteraction("owner", "playlist", url)
+	secondDone := make(chan struct{})
+	if err := playlist.Start(env.session, second, func() { close(secondDone) }); err != nil {
+		t.Fatalf("second Start: %v", err)
+	}
+	env.session.awaitContent(t, second, i18n.T(testGuildID, "playlist.in_progress"))
+	select {
+	case <-secondDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the turned down request was never done")
+	}
+
+	select {
+	case <-firstDone:
+		t.Fatal("done before the playlist download was over")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(answer)
+	select {
+	case <-firstDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not done after the playlist download failed")
+	}
+}
+
+// TestPlaylistKeepsTracksThatDownloaded runs /playlist on a playlist where
+// some tracks download and the rest fail for different reasons, and checks
+// that the ones that downloaded are queued in playlist order and the rest
+// are counted by why they were skipped.
+func TestPlaylistKeepsTracksThatDownloaded(t *testing.T) {
+	env := newTestEnv(t)
+	env.session.joinVoice(t, "owner", testVoiceID)
+	guild := env.guilds.Get(testGuildID)
+	guild.State.SetCurrentChannel(testVoiceID)
+
+	failures := map[int]map[string]interface{}{
+		1: {"status": "error", "code": socket.CodePrivate, "message": "Private video"},
+		3: {"status": "error", "message": "Duration 7200s exceeds limit of 3600s"},
+		4: {"status": "error", "code": socket.CodeUnavailable, "message": "Video unavailable"},
+		5: {"status": "error", "code": socket.CodeRegionBlocked, "message": "Not available in your country"},
+	}
+	const tracks = 7
+
+	env.downloader.Handle("get_playlist_info", func(request sockettest.Request) []sockettest.Response {
+		return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+			"playlist_title": "Mix", "total_tracks": tracks, "is_playlist": true,
+		})}
+	})
+	env.downloader.Handle("download_playlist_item", func(request sockettest.Request) []sockettest.Response {
+		index := int(request.Params["index"].(float64))
+		if failure, ok := failures[index]; ok {
+			return []sockettest.Response{sockettest.Success(request, failure)}
+		}
+		song := musictest.Song(t, fmt.Sprintf("track-%d", index))
+		return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+			"title": song.Title, "url": song.URL, "platform": song.Platform, "filename": song.FilePath, "duration": song.Duration,
+		})}
+	})
+
+	playlist := NewPlaylistCommand(env.guilds, env.blacklist, env.audit)
+	i := commandInteraction("owner", "playlist", stringOption("url", "https://soundcloud.com/artist/sets/mix"))
+	done := make(chan struct{})
+	if err := playlist.Start(env.session, i, func() { close(done) }); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the playlist download never finished")
+	}
+
+	skipped := "1 unavailable, 1 private, 1 region-locked, 1 too long"
+	env.session.awaitContent(t, i, i18n.T(testGuildID, "playlist.done", tracks-len(failures))+
+		i18n.T(testGuildID, "playlist.done_skipped", len(failures), skipped))
+
+	var queued []string
+	for _, item := range guild.Music.GetQueue() {
+		queued = append(queued, item.Song.Title)
+	}
+	if got := strings.Join(queued, " "); got != "track-0 track-2 track-6" {
+		t.Errorf("queued %s, want track-0 track-2 track-6", got)
+	}
+
+	// Tracks the downloader recognised as impossible aren't tried again.
+	if n := len(env.downloader.Requests("download_playlist_item")); n != tracks {
+		t.Errorf("downloader was asked for %d tracks, want each of the %d once", n, tracks)
+	}
+}
@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/presence"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxIdleTemplates bounds the idle rotation, which /presence lists in full.
+const maxIdleTemplates = 10
+
+// PresenceCommand shows and changes the templates of the bot's status. The
+// status is shared by every server the bot is in.
+type PresenceCommand struct {
+	presence  *presence.Rotator
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewPresenceCommand(presenceRotator *presence.Rotator, dbManager *config.DatabaseManager, auditLog *audit.Log) *PresenceCommand {
+	return &PresenceCommand{
+		presence:  presenceRotator,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *PresenceCommand) Name() string {
+	return "presence"
+}
+
+func (c *PresenceCommand) Description() string {
+	return "Show, preview or change the bot's status"
+}
+
+func (c *PresenceCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *PresenceCommand) Examples() []string {
+	return []string{
+		"/presence show",
+		"/presence preview template:🎵 {title} | {queue} queued",
+		"/presence set kind:playing template:🎵 {title} - {artist}",
+		"/presence idle-add template:{uptime} uptime",
+		"/presence idle-remove position:2",
+	}
+}
+
+func (c *PresenceCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *PresenceCommand) Options() []*discordgo.ApplicationCommandOption {
+	template := func(description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "template",
+			Description: description,
+			Required:    true,
+			MaxLength:   128,
+		}
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show the status templates and what they look like now",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "preview",
+			Description: "Show what a template would look like now, without saving it",
+			Options:     []*discordgo.ApplicationCommandOption{template("Template to preview")},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set",
+			Description: "Set the status shown while a song or the radio plays",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "kind",
+					Description: "When the status is shown",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "While a song plays", Value: string(presence.KindPlaying)},
+						{Name: "While the radio plays", Value: string(presence.KindRadio)},
+					},
+				},
+				template("Status text, with placeholders such as {title}"),
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "idle-add",
+			Description: "Add a status to rotate through while nothing plays",
+			Options:     []*discordgo.ApplicationCommandOption{template("Status text, with placeholders such as {uptime}")},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "idle-remove",
+			Description: "Remove a status from the idle rotation",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "position",
+					Description: "Position in the list /presence show gives",
+					Required:    true,
+					MinValue:    func() *float64 { v := 1.0; return &v }(),
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "reset",
+			Description: "Go back to the default status templates",
+		},
+	}
+}
+
+func (c *PresenceCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	subcommand := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, option := range subcommand.Options {
+		options[option.Name] = option
+	}
+
+	templates := c.presence.Templates()
+	switch subcommand.Name {
+	case "show":
+		return c.respond(s, i, c.show(i.GuildID, templates))
+	case "preview":
+		return c.respond(s, i, i18n.T(i.GuildID, "presence.preview", c.presence.Render(options["template"].StringValue())))
+	case "set":
+		template := options["template"].StringValue()
+		if presence.Kind(options["kind"].StringValue()) == presence.KindRadio {
+			templates.Radio = template
+		} else {
+			templates.Playing = template
+		}
+	case "idle-add":
+		if len(templates.Idle) >= maxIdleTemplates {
+			return c.respond(s, i, i18n.T(i.GuildID, "presence.idle_full", maxIdleTemplates))
+		}
+		templates.Idle = append(templates.Idle, options["template"].StringValue())
+	case "idle-remove":
+		position := int(options["position"].IntValue())
+		if position > len(templates.Idle) {
+			return c.respond(s, i, i18n.T(i.GuildID, "presence.idle_out_of_range", len(templates.Idle)))
+		}
+		if len(templates.Idle) == 1 {
+			return c.respond(s, i, i18n.T(i.GuildID, "presence.idle_last"))
+		}
+		templates.Idle = append(templates.Idle[:position-1], templates.Idle[position:]...)
+	case "reset":
+		templates = config.DefaultPresenceTemplates()
+	default:
+		return nil
+	}
+
+	if err := c.dbManager.SavePresenceTemplates(templates); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save presence templates", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "presence.save_failed"))
+	}
+	c.presence.SetTemplates(templates)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionPresence, subcommand.Name)
+
+	return c.respond(s, i, i18n.T(i.GuildID, "presence.saved")+"\n\n"+c.show(i.GuildID, templates))
+}
+
+// show lists the templates, each with what it would show right now.
+func (c *PresenceCommand) show(guildID string, templates config.PresenceTemplates) string {
+	line := func(template string) string {
+		return i18n.T(guildID, "presence.line", template, c.presence.Render(template))
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(guildID, "presence.title"))
+	b.WriteString(i18n.T(guildID, "presence.playing") + line(templates.Playing))
+	b.WriteString(i18n.T(guildID, "presence.radio") + line(templates.Radio))
+	b.WriteString(i18n.T(guildID, "presence.idle", int(presence.RotateInterval.Minutes())))
+	for idx, template := range templates.Idle {
+		b.WriteString(fmt.Sprintf("**%d.** ", idx+1) + line(template))
+	}
+	b.WriteString(i18n.T(guildID, "presence.placeholders", strings.Join(presence.Placeholders, " ")))
+	if !c.presence.ShowsPlayback() {
+		b.WriteString(i18n.T(guildID, "presence.shared"))
+	}
+	return b.String()
+}
+
+func (c *PresenceCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
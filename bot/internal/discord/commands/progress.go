@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionWindow is how long an interaction's response can still be
+// edited, less a margin so an edit isn't sent just as the token expires.
+const interactionWindow = 14 * time.Minute
+
+// progressReporter keeps the user informed during a long request. It edits
+// the interaction response while the token is valid. Once the token has run
+// out, or Discord refuses it, it posts one message in the channel and edits
+// that message for the remaining updates.
+type progressReporter struct {
+	session     discordapi.Session
+	interaction *discordgo.Interaction
+	guildID     string
+	channelID   string
+	userID      string
+	startedAt   time.Time
+
+	mu        sync.Mutex
+	expired   bool
+	messageID string
+}
+
+func newProgressReporter(s discordapi.Session, i *discordgo.InteractionCreate) *progressReporter {
+	return &progressReporter{
+		session:     s,
+		interaction: i.Interaction,
+		guildID:     i.GuildID,
+		channelID:   i.ChannelID,
+		userID:      i.Member.User.ID,
+		startedAt:   time.Now(),
+	}
+}
+
+// Update shows content as the current state of the request.
+func (p *progressReporter) Update(content string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A progress update that fails for another reason is simply skipped.
+	if p.editResponse(content) || !p.expired {
+		return
+	}
+
+	if p.messageID != "" {
+		_, err := p.session.ChannelMessageEdit(p.channelID, p.messageID, content)
+		if err == nil {
+			return
+		}
+		logger.Debug.Printf("Failed to edit progress message %s: %v", p.messageID, err)
+	}
+
+	message, err := p.session.ChannelMessageSendComplex(p.channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to send progress message in channel %s: %v", p.channelID, err)
+		return
+	}
+	p.messageID = message.ID
+}
+
+// Finish shows how the request ended. When the response can no longer be
+// edited the outcome is sent as a new message mentioning the requester, so
+// they are notified, and the progress message is removed.
+func (p *progressReporter) Finish(content string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.editResponse(content) {
+		return
+	}
+
+	_, err := p.session.ChannelMessageSendComplex(p.channelID, &discordgo.MessageSend{
+		Content: i18n.T(p.guildID, "play.followup", p.userID, content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Users: []string{p.userID},
+		},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to send request follow-up in channel %s: %v", p.channelID, err)
+		return
+	}
+
+	if p.messageID != "" {
+		if err := p.session.ChannelMessageDelete(p.channelID, p.messageID); err != nil {
+			logger.Debug.Printf("Failed to delete progress message %s: %v", p.messageID, err)
+		}
+		p.messageID = ""
+	}
+}
+
+// editResponse edits the interaction response and reports whether it worked.
+// An expired or rejected token switches the reporter to channel messages for
+// good. The caller holds p.mu.
+func (p *progressReporter) editResponse(content string) bool {
+	if p.expired {
+		return false
+	}
+	if time.Since(p.startedAt) >= interactionWindow {
+		p.expired = true
+		return false
+	}
+
+	_, err := p.session.InteractionResponseEdit(p.interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	if err == nil {
+		return true
+	}
+
+	if tokenExpired(err) {
+		logger.Info.Printf("Interaction token in guild %s expired, reporting progress in the channel", p.guildID)
+		p.expired = true
+	} else {
+		logger.Debug.Printf("Failed to edit interaction response: %v", err)
+	}
+	return false
+}
+
+// tokenExpired reports whether err means the interaction token can't be used
+// anymore.
+func tokenExpired(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) {
+		return false
+	}
+	if restErr.Response != nil && restErr.Response.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if restErr.Message == nil {
+		return false
+	}
+	switch restErr.Message.Code {
+	case discordgo.ErrCodeInvalidWebhookTokenProvided,
+		discordgo.ErrCodeUnknownWebhook,
+		discordgo.ErrCodeInteractionHasAlreadyBeenAcknowledged:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/i18n"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// progressSession records the calls a progress reporter makes. Editing the
+// interaction response fails with editErr when it is set.
+type progressSession struct {
+	*fakeSession
+	editErr error
+
+	mu       sync.Mutex
+	calls    []string
+	mentions [][]string
+	messages int
+}
+
+func (s *progressSession) call(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf(format, args...))
+}
+
+func (s *progressSession) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if s.editErr != nil {
+		s.call("edit response failed")
+		return nil, s.editErr
+	}
+	s.call("edit response: %s", *newresp.Content)
+	return &discordgo.Message{ID: "response", ChannelID: testChannelID}, nil
+}
+
+func (s *progressSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	s.messages++
+	id := fmt.Sprintf("message%d", s.messages)
+	s.mentions = append(s.mentions, data.AllowedMentions.Users)
+	s.mu.Unlock()
+	s.call("send %s: %s", id, data.Content)
+	return &discordgo.Message{ID: id, ChannelID: channelID}, nil
+}
+
+func (s *progressSession) ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.call("edit %s: %s", messageID, content)
+	return &discordgo.Message{ID: messageID, ChannelID: channelID}, nil
+}
+
+func (s *progressSession) ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error {
+	s.call("delete %s", messageID)
+	return nil
+}
+
+func (s *progressSession) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.calls)
+}
+
+// expiredToken is how Discord refuses an edit once the token has run out.
+var expiredToken = &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusUnauthorized}}
+
+func TestProgressReporterEditsTheResponse(t *testing.T) {
+	session := &progressSession{fakeSession: newFakeSession(t)}
+	progress := newProgressReporter(session, commandInteraction("user", "playlist"))
+
+	progress.Update("1/2")
+	progress.Finish("done")
+
+	want := []string{"edit response: 1/2", "edit response: done"}
+	if got := session.recorded(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestProgressReporterMovesToTheChannel(t *testing.T) {
+	session := &progressSession{fakeSession: newFakeSession(t), editErr: expiredToken}
+	progress := newProgressReporter(session, commandInteraction("user", "playlist"))
+
+	progress.Update("1/3")
+	progress.Update("2/3")
+	progress.Finish("done")
+
+	// The response isn't tried again once the token is known to be gone.
+	want := []string{
+		"edit response failed",
+		"send message1: 1/3",
+		"edit message1: 2/3",
+		"send message2: " + i18n.T(testGuildID, "play.followup", "user", "done"),
+		"delete message1",
+	}
+	if got := session.recorded(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	// Progress doesn't ping anyone; the outcome pings the requester.
+	if !slices.Equal(session.mentions[0], nil) || !slices.Equal(session.mentions[1], []string{"user"}) {
+		t.Errorf("mentions = %q, want none and then the requester", session.mentions)
+	}
+}
+
+func TestProgressReporterAfterTheWindow(t *testing.T) {
+	session := &progressSession{fakeSession: newFakeSession(t)}
+	progress := newProgressReporter(session, commandInteraction("user", "playlist"))
+	progress.startedAt = time.Now().Add(-interactionWindow)
+
+	progress.Update("1/2")
+	progress.Finish("done")
+
+	// The old token isn't even tried.
+	want := []string{
+		"send message1: 1/2",
+		"send message2: " + i18n.T(testGuildID, "play.followup", "user", "done"),
+		"delete message1",
+	}
+	if got := session.recorded(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestProgressReporterSkipsFailedUpdates(t *testing.T) {
+	session := &progressSession{fakeSession: newFakeSession(t), editErr: errors.New("connection reset")}
+	progress := newProgressReporter(session, commandInteraction("user", "playlist"))
+
+	progress.Update("1/2")
+	session.editErr = nil
+	progress.Update("2/2")
+
+	// A passing failure isn't an expired token, so nothing moves to the
+	// channel.
+	want := []string{"edit response failed", "edit response: 2/2"}
+	if got := session.recorded(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	withCode := func(code int) error {
+		return &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: &discordgo.APIErrorMessage{Code: code}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unauthorized", expiredToken, true},
+		{"invalid token", withCode(discordgo.ErrCodeInvalidWebhookTokenProvided), true},
+		{"unknown webhook", withCode(discordgo.ErrCodeUnknownWebhook), true},
+		{"already acknowledged", withCode(discordgo.ErrCodeInteractionHasAlreadyBeenAcknowledged), true},
+		{"wrapped", fmt.Errorf("editing: %w", expiredToken), true},
+		{"other API error", withCode(discordgo.ErrCodeUnknownChannel), false},
+		{"no message", &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, false},
+		{"not a REST error", errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := tokenExpired(tt.err); got != tt.want {
+			t.Errorf("%s: tokenExpired = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,21 +2,31 @@ package commands
 
 import (
 	"fmt"
-	"musicbot/internal/music"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
 	"musicbot/internal/state"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+const (
+	queuePageSize = 10
+	queuePageTTL  = 10 * time.Minute
+)
+
 type QueueCommand struct {
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
 }
 
-func NewQueueCommand(musicManager *music.Manager, stateManager *state.Manager) *QueueCommand {
+func NewQueueCommand(guildRegistry *guilds.Registry) *QueueCommand {
 	return &QueueCommand{
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
 	}
 }
 
@@ -28,70 +38,187 @@ func (c *QueueCommand) Description() string {
 	return "Show the current music queue"
 }
 
+func (c *QueueCommand) Category() Category {
+	return CategoryMusic
+}
+
 func (c *QueueCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *QueueCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: c.generateQueueMessage(),
-		},
+func (c *QueueCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	ownerID := interactionUserID(i)
+	issued := time.Now().Unix()
+	message, components := c.renderPage(i.GuildID, ownerID, 0, issued)
+
+	edit := message.Edit()
+	if len(components) > 0 {
+		edit.Components = &components
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	if err != nil || len(components) == 0 {
+		return err
+	}
+
+	time.AfterFunc(queuePageTTL, func() {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Components: &[]discordgo.MessageComponent{},
+		})
+		if err != nil {
+			logger.Debug.Printf("Failed to remove queue buttons: %v", err)
+		}
 	})
-	return err
+
+	return nil
 }
 
-func (c *QueueCommand) generateQueueMessage() string {
-	currentSong := c.musicManager.GetCurrentSong()
-	upcoming := c.musicManager.GetUpcoming(10)   // Show 10 upcoming songs
-	totalQueueItems := c.musicManager.GetQueue() // Get all queue items to count total
+func (c *QueueCommand) ComponentPrefix() string {
+	return "queue_page_"
+}
 
-	totalSongs := len(totalQueueItems)
+// HandleComponent serves the Previous/Next buttons. The custom ID carries
+// everything needed to render the page: queue_page_<owner>_<page>_<issued>.
+func (c *QueueCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, c.ComponentPrefix()), "_")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed queue page id: %s", i.MessageComponentData().CustomID)
+	}
 
-	if currentSong == nil && totalSongs == 0 {
-		return "📭 Queue is empty. Use `/play` to add songs!"
+	ownerID := parts[0]
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	issued, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return err
 	}
 
-	message := "🎵 **Music Queue**\n\n"
+	if userID := interactionUserID(i); userID == "" || userID != ownerID {
+		return respondNotOwner(s, i)
+	}
 
-	if currentSong != nil {
-		duration := c.formatDuration(currentSong.Duration)
-		message += fmt.Sprintf("🎧 **Now Playing:**\n**%s** - %s (%s)\n\n",
-			currentSong.Title, currentSong.Artist, duration)
+	if time.Since(time.Unix(issued, 0)) > queuePageTTL {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content,
+				Components: []discordgo.MessageComponent{},
+			},
+		})
 	}
 
-	if len(upcoming) > 0 {
-		message += "📋 **Up Next:**\n"
-		for i, song := range upcoming {
-			duration := c.formatDuration(song.Duration)
-			message += fmt.Sprintf("**%d.** %s - %s (%s)\n",
-				i+1, song.Title, song.Artist, duration)
+	message, components := c.renderPage(i.GuildID, ownerID, page, issued)
+
+	data := message.Data()
+	data.Components = components
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: data,
+	})
+}
+
+func (c *QueueCommand) renderPage(guildID, ownerID string, page int, issued int64) (render.Message, []discordgo.MessageComponent) {
+	musicManager := c.guilds.Get(guildID).Music
+	currentSong := musicManager.GetCurrentSong()
+	upcoming := musicManager.GetUpcoming(len(musicManager.GetQueue()))
+	fair := musicManager.FairQueue()
+
+	if currentSong == nil && len(upcoming) == 0 {
+		return render.Text(guildID, i18n.T(guildID, "queue.empty")), nil
+	}
+
+	totalPages := (len(upcoming) + queuePageSize - 1) / queuePageSize
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	trackCount := len(upcoming)
+	totalSeconds, unknownCount := 0, 0
+	addLength := func(song *state.Song) {
+		switch {
+		case song.IsStream:
+			// Live streams have no length to add.
+		case song.PlayLength() <= 0:
+			unknownCount++
+		default:
+			totalSeconds += song.PlayLength()
 		}
+	}
 
-		// Show if there are more songs beyond the displayed ones
-		if totalSongs > len(upcoming) {
-			remainingSongs := totalSongs - len(upcoming)
-			if currentSong != nil {
-				remainingSongs-- // Account for the currently playing song
-			}
-			if remainingSongs > 0 {
-				message += fmt.Sprintf("\n*... and %d more songs*\n", remainingSongs)
+	if currentSong != nil {
+		trackCount++
+		addLength(currentSong)
+	}
+	for idx := range upcoming {
+		addLength(&upcoming[idx])
+	}
+
+	start := page * queuePageSize
+	end := start + queuePageSize
+	if end > len(upcoming) {
+		end = len(upcoming)
+	}
+
+	// Songs whose files were evicted while they waited are flagged, so they
+	// can be repaired before their turn comes.
+	missing := make([]bool, end-start)
+	missingCount := 0
+	for idx := range upcoming {
+		if musicManager.FileMissing(&upcoming[idx]) {
+			missingCount++
+			if idx >= start && idx < end {
+				missing[idx-start] = true
 			}
 		}
 	}
 
-	message += fmt.Sprintf("\n📊 **Total songs in queue:** %d", totalSongs)
+	message := render.Queue(guildID, render.QueuePage{
+		Current:      currentSong,
+		Upcoming:     upcoming[start:end],
+		Offset:       start,
+		Missing:      missing,
+		MissingCount: missingCount,
+		Fair:         fair,
+		Page:         page,
+		Pages:        totalPages,
+		TrackCount:   trackCount,
+		TotalSeconds: totalSeconds,
+		UnknownCount: unknownCount,
+	})
 
-	return message
-}
+	if totalPages == 1 {
+		return message, nil
+	}
 
-func (c *QueueCommand) formatDuration(seconds int) string {
-	if seconds <= 0 {
-		return "Unknown"
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    i18n.T(guildID, "queue.previous"),
+					CustomID: c.pageID(ownerID, page-1, issued),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    i18n.T(guildID, "queue.next"),
+					CustomID: c.pageID(ownerID, page+1, issued),
+					Disabled: page == totalPages-1,
+				},
+			},
+		},
 	}
 
-	minutes := seconds / 60
-	secs := seconds % 60
-	return fmt.Sprintf("%d:%02d", minutes, secs)
+	return message, components
+}
+
+func (c *QueueCommand) pageID(ownerID string, page int, issued int64) string {
+	return fmt.Sprintf("%s%s_%d_%d", c.ComponentPrefix(), ownerID, page, issued)
 }
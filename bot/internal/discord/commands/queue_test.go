@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music/musictest"
+	"musicbot/internal/state"
+	"strings"
+	"testing"
+	"time"
+)
+
+// queueSongs leaves n songs, song-01 onwards, queued for the test guild.
+// The first is the one that was playing, so the queue lists the others.
+func queueSongs(t *testing.T, env *testEnv, n int) {
+	t.Helper()
+	songs := make([]*state.Song, n)
+	for k := range songs {
+		songs[k] = musictest.Song(t, fmt.Sprintf("song-%02d", k+1))
+	}
+	musictest.SaveQueue(t, env.db, testGuildID, songs...)
+}
+
+func TestQueueCommandEmpty(t *testing.T) {
+	env := newTestEnv(t)
+	cmd := NewQueueCommand(env.guilds)
+
+	i := commandInteraction("user", "queue")
+	if err := cmd.Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	r := env.session.awaitContent(t, i, i18n.T(testGuildID, "queue.empty"))
+	if len(r.components) != 0 {
+		t.Errorf("empty queue has %d components, want none", len(r.components))
+	}
+}
+
+func TestQueueCommandPages(t *testing.T) {
+	env := newTestEnv(t)
+	queueSongs(t, env, 25)
+	cmd := NewQueueCommand(env.guilds)
+
+	i := commandInteraction("owner", "queue")
+	if err := cmd.Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	first := env.session.awaitContent(t, i, "song-02")
+	if strings.Contains(first.content, "song-12") {
+		t.Errorf("first page shows song-12:\n%s", first.content)
+	}
+
+	pageButtons := buttons(first.components)
+	if len(pageButtons) != 2 {
+		t.Fatalf("got %d buttons, want Previous and Next", len(pageButtons))
+	}
+	previous, next := pageButtons[0], pageButtons[1]
+	if !previous.Disabled || next.Disabled {
+		t.Errorf("on the first page Previous disabled = %v, Next disabled = %v", previous.Disabled, next.Disabled)
+	}
+	if !strings.HasPrefix(next.CustomID, "queue_page_owner_1_") {
+		t.Errorf("Next button id = %q", next.CustomID)
+	}
+
+	t.Run("next page", func(t *testing.T) {
+		press := buttonInteraction("owner", next.CustomID)
+		if err := cmd.HandleComponent(env.session, press); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		second := env.session.awaitContent(t, press, "song-12")
+		if strings.Contains(second.content, "song-02") || strings.Contains(second.content, "song-22") {
+			t.Errorf("second page shows songs of other pages:\n%s", second.content)
+		}
+		if pageButtons := buttons(second.components); len(pageButtons) != 2 || pageButtons[0].Disabled || pageButtons[1].Disabled {
+			t.Errorf("on a middle page both buttons should be enabled: %+v", pageButtons)
+		}
+	})
+
+	t.Run("someone else's buttons", func(t *testing.T) {
+		press := buttonInteraction("stranger", next.CustomID)
+		if err := cmd.HandleComponent(env.session, press); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		r := env.session.awaitContent(t, press, i18n.T(testGuildID, "common.not_your_buttons"))
+		if !r.ephemeral {
+			t.Error("the refusal isn't ephemeral")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		issued := time.Now().Add(-queuePageTTL - time.Minute).Unix()
+		press := buttonInteraction("owner", cmd.pageID("owner", 1, issued))
+		press.Message.Content = "old page"
+		if err := cmd.HandleComponent(env.session, press); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		r := env.session.awaitContent(t, press, "old page")
+		if r.components == nil || len(r.components) != 0 {
+			t.Errorf("expired page keeps components %v, want them removed", r.components)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// QueueModeCommand picks how the queue chooses the next song: in the order
+// songs were queued, or with requesters taking turns.
+type QueueModeCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewQueueModeCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *QueueModeCommand {
+	return &QueueModeCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *QueueModeCommand) Name() string {
+	return "queuemode"
+}
+
+func (c *QueueModeCommand) Description() string {
+	return "Show or change whether requesters take turns in the queue"
+}
+
+func (c *QueueModeCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *QueueModeCommand) Examples() []string {
+	return []string{
+		"/queuemode mode:fair",
+		"/queuemode mode:fifo",
+	}
+}
+
+func (c *QueueModeCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *QueueModeCommand) Options() []*discordgo.ApplicationCommandOption {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(music.QueueModes))
+	for _, mode := range music.QueueModes {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(mode), Value: string(mode)})
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "mode",
+			Description: "fifo plays songs in the order they were queued; fair lets requesters take turns",
+			Required:    false,
+			Choices:     choices,
+		},
+	}
+}
+
+func (c *QueueModeCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		current, _ := music.ParseQueueMode(guild.State.GetQueueMode())
+		return c.respond(s, i, i18n.T(i.GuildID, "queuemode.current", current))
+	}
+
+	mode, ok := music.ParseQueueMode(options[0].StringValue())
+	if !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "queuemode.unknown"))
+	}
+
+	if err := c.dbManager.SaveQueueMode(i.GuildID, string(mode)); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save queue mode", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "queuemode.save_failed"))
+	}
+
+	guild.State.SetQueueMode(string(mode))
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionQueueMode, string(mode))
+
+	guild.Music.ApplyQueueMode()
+
+	if mode == music.QueueFair {
+		return c.respond(s, i, i18n.T(i.GuildID, "queuemode.set_fair"))
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, "queuemode.set_fifo"))
+}
+
+func (c *QueueModeCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
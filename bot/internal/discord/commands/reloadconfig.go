@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ReloadConfigCommand struct {
+	reload func() (*config.ReloadResult, error)
+}
+
+func NewReloadConfigCommand(reload func() (*config.ReloadResult, error)) *ReloadConfigCommand {
+	return &ReloadConfigCommand{
+		reload: reload,
+	}
+}
+
+func (c *ReloadConfigCommand) Name() string {
+	return "reloadconfig"
+}
+
+func (c *ReloadConfigCommand) Description() string {
+	return "Reload the configuration without restarting"
+}
+
+func (c *ReloadConfigCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *ReloadConfigCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *ReloadConfigCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *ReloadConfigCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *ReloadConfigCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	result, err := c.reload()
+	if err != nil {
+		logger.Error.Printf("Config reload failed: %v", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "reloadconfig.failed", err)),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(reloadMessage(i.GuildID, result)),
+	})
+	return err
+}
+
+func reloadMessage(guildID string, result *config.ReloadResult) string {
+	if result.Empty() {
+		return i18n.T(guildID, "reloadconfig.unchanged")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(guildID, "reloadconfig.done"))
+
+	if len(result.Applied) > 0 {
+		sb.WriteString("\n\n" + i18n.T(guildID, "reloadconfig.applied"))
+		for _, change := range result.Applied {
+			sb.WriteString("\n• `" + change.String() + "`")
+		}
+	}
+
+	if len(result.Rejected) > 0 {
+		sb.WriteString("\n\n" + i18n.T(guildID, "reloadconfig.rejected"))
+		for _, change := range result.Rejected {
+			sb.WriteString("\n• `" + change.String() + "`")
+		}
+	}
+
+	// Long stream lists could push the message past Discord's limit.
+	content := []rune(sb.String())
+	if len(content) > 2000 {
+		return string(content[:1997]) + "..."
+	}
+	return string(content)
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxRemovedListed is how many removed titles the reply names.
+const maxRemovedListed = 10
+
+type RemoveCommand struct {
+	guilds            *guilds.Registry
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewRemoveCommand(guildRegistry *guilds.Registry, permissionManager *permissions.Manager, auditLog *audit.Log) *RemoveCommand {
+	return &RemoveCommand{
+		guilds:            guildRegistry,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *RemoveCommand) Name() string {
+	return "remove"
+}
+
+func (c *RemoveCommand) Description() string {
+	return "Remove songs from the queue"
+}
+
+func (c *RemoveCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *RemoveCommand) Examples() []string {
+	return []string{
+		"/remove position:3",
+		"/remove position:2 to:5",
+		"/remove user:@someone",
+	}
+}
+
+func (c *RemoveCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *RemoveCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "position",
+			Description: "Position in the queue, or the start of a range",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "to",
+			Description: "Last position of the range to remove",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Name:        "user",
+			Description: "Only remove songs this user queued",
+			Required:    false,
+		},
+	}
+}
+
+func (c *RemoveCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	userID := i.Member.User.ID
+
+	from, to := 0, 0
+	requestedBy := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "position":
+			from = int(option.IntValue())
+		case "to":
+			to = int(option.IntValue())
+		case "user":
+			requestedBy = option.UserValue(nil).ID
+		}
+	}
+
+	if from == 0 && requestedBy == "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.nothing")),
+		})
+		return err
+	}
+
+	if to > 0 && from == 0 {
+		from = 1
+	}
+	if to > 0 && to < from {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.bad_range", from, to)),
+		})
+		return err
+	}
+
+	if len(musicManager.GetUpcomingItems()) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.none")),
+		})
+		return err
+	}
+
+	// Only DJs may remove what other people queued.
+	owner := ""
+	if !c.isDJ(s, i) {
+		owner = userID
+	}
+
+	removed, err := musicManager.RemoveUpcoming(from, to, requestedBy, owner)
+	switch {
+	case errors.Is(err, music.ErrOutOfRange):
+		last := to
+		if last == 0 {
+			last = from
+		}
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.not_found", last)),
+		})
+		return err
+	case errors.Is(err, music.ErrNotOwner):
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, permissions.LevelDJ)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.not_yours", roleName)),
+		})
+		return err
+	case err != nil:
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to remove songs", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.failed")),
+		})
+		return err
+	}
+
+	if len(removed) == 0 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "remove.none")),
+		})
+		return err
+	}
+
+	var lines []string
+	for k, item := range removed {
+		if k == maxRemovedListed {
+			lines = append(lines, i18n.T(i.GuildID, "remove.more", len(removed)-maxRemovedListed))
+			break
+		}
+		title := fmt.Sprintf("#%d", item.SongID)
+		if item.Song != nil {
+			title = item.Song.Title
+		}
+		lines = append(lines, "• "+title)
+	}
+
+	target := fmt.Sprintf("%d songs", len(removed))
+	if len(removed) == 1 && removed[0].Song != nil {
+		target = removed[0].Song.Title
+	}
+	c.audit.Record(i.GuildID, userID, audit.ActionRemove, target)
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "remove.removed", len(removed)) + "\n" + strings.Join(lines, "\n")),
+	})
+	return err
+}
+
+// isDJ reports whether the user may remove songs other people queued.
+func (c *RemoveCommand) isDJ(s discordapi.Session, i *discordgo.InteractionCreate) bool {
+	isDJ, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, permissions.LevelDJ)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Permission check for remove failed", "error", err)
+		return false
+	}
+	return isDJ
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// RepairCommand downloads the files of queued songs again after the janitor
+// evicted them, and drops the songs that can't be brought back.
+type RepairCommand struct {
+	guilds *guilds.Registry
+	audit  *audit.Log
+}
+
+func NewRepairCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *RepairCommand {
+	return &RepairCommand{
+		guilds: guildRegistry,
+		audit:  auditLog,
+	}
+}
+
+func (c *RepairCommand) Name() string {
+	return "repair"
+}
+
+func (c *RepairCommand) Description() string {
+	return "Download missing files of queued songs again, or remove the songs"
+}
+
+func (c *RepairCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *RepairCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *RepairCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *RepairCommand) Cooldown() time.Duration {
+	return 30 * time.Second
+}
+
+func (c *RepairCommand) MaxConcurrentPerGuild() int {
+	return 1
+}
+
+func (c *RepairCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *RepairCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return c.Start(s, i, func() {})
+}
+
+// Start begins repairing the queue and calls done once every missing song
+// has been tried.
+func (c *RepairCommand) Start(s discordapi.Session, i *discordgo.InteractionCreate, done func()) error {
+	started := false
+	defer func() {
+		if !started {
+			done()
+		}
+	}()
+
+	musicManager := c.guilds.Get(i.GuildID).Music
+	missing := musicManager.MissingUpcoming()
+	if len(missing) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "repair.nothing")),
+		})
+		return err
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "repair.starting", len(missing))),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionRepair, fmt.Sprintf("%d songs", len(missing)))
+
+	started = true
+	go func() {
+		defer done()
+		c.repairSongs(newProgressReporter(s, i), missing, musicManager.DownloadLimits(i.GuildID))
+	}()
+	return nil
+}
+
+// repairSongs downloads the missing songs one at a time, like an import, and
+// sums up how it went. A song that can't be downloaded again is removed from
+// the queue, every time it was queued.
+func (c *RepairCommand) repairSongs(progress *progressReporter, missing []state.Song, limits config.DownloadLimits) {
+	guildID := progress.guildID
+	musicManager := c.guilds.Get(guildID).Music
+	total := len(missing)
+
+	var repaired, pending, removed int
+	lastProgress := time.Now()
+
+	for k := range missing {
+		song := &missing[k]
+		var err error = music.ErrFileMissing
+		if music.Redownloadable(song) {
+			track := &importTrack{done: make(chan error, 1)}
+			err = musicManager.RepairSong(song.URL, limits, track)
+			if err == nil {
+				select {
+				case err = <-track.done:
+				case <-time.After(importTrackTimeout):
+					err = fmt.Errorf("no answer from the downloader for %s", song.URL)
+				}
+			}
+		}
+
+		switch {
+		case err == nil:
+			repaired++
+		case errors.Is(err, music.ErrRepairPending):
+			pending++
+		default:
+			logger.ForCommand(guildID, c.Name()).Debug("Could not repair queued song", "url", song.URL, "error", err)
+			removed += musicManager.RemoveMissing(song.URL)
+		}
+
+		if time.Since(lastProgress) >= playlistProgressInterval {
+			lastProgress = time.Now()
+			progress.Update(i18n.T(guildID, "repair.progress", k+1, total, repaired))
+		}
+	}
+
+	summary := i18n.T(guildID, "repair.done", repaired, total)
+	if pending > 0 {
+		summary += i18n.T(guildID, "repair.pending", pending)
+	}
+	if removed > 0 {
+		summary += i18n.T(guildID, "repair.removed", removed)
+	}
+	progress.Finish(summary)
+}
@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/socket"
+	"musicbot/internal/state"
+	"musicbot/internal/urlnorm"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// requestSearchPrefix marks a message in a request channel as a search
+	// rather than a link.
+	requestSearchPrefix = "?"
+
+	// requestSearchPlatform is searched for requests, the same default as
+	// /search.
+	requestSearchPlatform = "soundcloud"
+	requestSearchTimeout  = 2 * time.Minute
+
+	// requestCleanupDelay is how long chatter in a request channel, and the
+	// bot's explanations of failed requests, stay before they are deleted.
+	requestCleanupDelay = 10 * time.Second
+
+	reactionQueued  = "✅"
+	reactionFailed  = "❌"
+	reactionWorking = "⏳"
+)
+
+// RequestChannelCommand sets a text channel whose messages are song
+// requests: a link is played like /play, and a message starting with "?" is
+// searched and its first result played. HandleMessage takes the messages.
+type RequestChannelCommand struct {
+	router       *Router
+	play         *PlayCommand
+	guilds       *guilds.Registry
+	stateManager *state.Manager
+	socketClient *socket.Client
+	dbManager    *config.DatabaseManager
+	audit        *audit.Log
+}
+
+func NewRequestChannelCommand(router *Router, play *PlayCommand, guildRegistry *guilds.Registry, stateManager *state.Manager, socketClient *socket.Client, dbManager *config.DatabaseManager, auditLog *audit.Log) *RequestChannelCommand {
+	return &RequestChannelCommand{
+		router:       router,
+		play:         play,
+		guilds:       guildRegistry,
+		stateManager: stateManager,
+		socketClient: socketClient,
+		dbManager:    dbManager,
+		audit:        auditLog,
+	}
+}
+
+func (c *RequestChannelCommand) Name() string {
+	return "requestchannel"
+}
+
+func (c *RequestChannelCommand) Description() string {
+	return "Set a text channel where posted links and ?searches are played"
+}
+
+func (c *RequestChannelCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *RequestChannelCommand) Examples() []string {
+	return []string{
+		"/requestchannel set channel:#song-requests",
+		"/requestchannel unset",
+	}
+}
+
+func (c *RequestChannelCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *RequestChannelCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set",
+			Description: "Take song requests from messages in a text channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "Text channel to take requests from",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "unset",
+			Description: "Stop taking song requests from messages",
+		},
+	}
+}
+
+func (c *RequestChannelCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	subcommand := i.ApplicationCommandData().Options[0]
+	userID := i.Member.User.ID
+
+	switch subcommand.Name {
+	case "set":
+		if !c.stateManager.GetConfig().RequestChannels {
+			return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.disabled"))
+		}
+
+		channel := discordapi.ChannelOption(s, subcommand.Options[0])
+		if channel == nil {
+			return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.save_failed"))
+		}
+		if err := c.dbManager.SaveRequestChannel(i.GuildID, channel.ID); err != nil {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save request channel", "error", err)
+			return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.save_failed"))
+		}
+		guild.State.SetRequestChannel(channel.ID)
+		c.audit.Record(i.GuildID, userID, audit.ActionRequestChannel, channel.ID)
+		return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.set", channel.ID))
+	case "unset":
+		if guild.State.GetRequestChannel() == "" {
+			return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.not_set"))
+		}
+		if err := c.dbManager.DeleteRequestChannel(i.GuildID); err != nil {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to delete request channel", "error", err)
+			return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.save_failed"))
+		}
+		guild.State.SetRequestChannel("")
+		c.audit.Record(i.GuildID, userID, audit.ActionRequestChannel, "")
+		return c.respond(s, i, i18n.T(i.GuildID, "requestchannel.unset"))
+	}
+	return nil
+}
+
+func (c *RequestChannelCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+// HandleMessage takes a message posted in a guild's request channel as a
+// song request. Requests go through the checks /play makes, with /play's
+// cooldown, and the message gets a ✅ or ❌ once it is settled. Messages
+// that are neither a link nor a search are deleted after a while, to keep
+// the channel to requests. Bots and webhooks are ignored.
+func (c *RequestChannelCommand) HandleMessage(s discordapi.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" || m.Author == nil || m.Author.Bot || m.WebhookID != "" {
+		return
+	}
+	if !c.stateManager.GetConfig().RequestChannels || c.stateManager.IsShuttingDown() {
+		return
+	}
+
+	guild := c.guilds.Get(m.GuildID)
+	if channelID := guild.State.GetRequestChannel(); channelID == "" || channelID != m.ChannelID {
+		return
+	}
+
+	request := &messageRequest{session: s, message: m.Message}
+	rawURL, query := parseRequest(m.Content)
+	if rawURL == "" && query == "" {
+		request.react(reactionFailed)
+		request.deleteLater(m.ID)
+		return
+	}
+
+	userID := m.Author.ID
+	if reason, blocked := blacklistReason(c.play.blacklist, m.GuildID, userID, rawURL); blocked {
+		request.reject(reason)
+		return
+	}
+	release, reason := c.router.Admit(m.GuildID, userID, c.play.Name())
+	defer release()
+	if reason != "" {
+		request.reject(reason)
+		return
+	}
+
+	request.react(reactionWorking)
+
+	if query != "" {
+		found, reason := c.search(m.GuildID, query)
+		if reason != "" {
+			request.reject(reason)
+			return
+		}
+		if reason, blocked := blacklistReason(c.play.blacklist, m.GuildID, userID, found); blocked {
+			request.reject(reason)
+			return
+		}
+		rawURL = found
+	}
+
+	// As with /play, the platform is told before normalizing.
+	limits := c.play.guilds.Get(m.GuildID).Music.DownloadLimits(m.GuildID)
+	limits.Platform = socket.DetectPlatform(rawURL)
+	rawURL = urlnorm.Normalize(rawURL)
+
+	if reason := c.play.prepare(s, guild, userID, rawURL, limits); reason != "" {
+		request.reject(reason)
+		return
+	}
+
+	c.play.request(m.GuildID, userID, rawURL, false, limits, request)
+}
+
+// search returns the URL of the first result for query, or why there is
+// none.
+func (c *RequestChannelCommand) search(guildID, query string) (string, string) {
+	if c.socketClient == nil || !c.socketClient.IsConnected() {
+		return "", i18n.T(guildID, "search.unavailable")
+	}
+
+	results, err := c.socketClient.Search(query, requestSearchPlatform, 1, requestSearchTimeout)
+	if errors.Is(err, socket.ErrRequestTimeout) {
+		return "", i18n.T(guildID, "search.timeout")
+	}
+	if err != nil {
+		return "", i18n.T(guildID, "search.failed", err)
+	}
+	if len(results) == 0 || results[0].URL == "" {
+		return "", i18n.T(guildID, "search.no_results")
+	}
+	return results[0].URL, ""
+}
+
+// parseRequest reads a message in a request channel: a search if it starts
+// with "?", otherwise the first http(s) link in it. Both are empty when it is
+// neither.
+func parseRequest(content string) (rawURL, query string) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, requestSearchPrefix) {
+		return "", strings.TrimSpace(strings.TrimPrefix(content, requestSearchPrefix))
+	}
+
+	for _, field := range strings.Fields(content) {
+		// Discord users wrap links in <> to hide the preview.
+		field = strings.TrimSuffix(strings.TrimPrefix(field, "<"), ">")
+		parsed, err := url.Parse(field)
+		if err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" {
+			return field, ""
+		}
+	}
+	return "", ""
+}
+
+// messageRequest reports how a request from a request channel went, with a
+// reaction on its message and, when it failed, a reply saying why that is
+// deleted after a while.
+type messageRequest struct {
+	session discordapi.Session
+	message *discordgo.Message
+}
+
+func (r *messageRequest) Queued(*state.Song, music.ETA) {
+	r.unreact(reactionWorking)
+	r.react(reactionQueued)
+}
+
+func (r *messageRequest) Failed(err error) {
+	r.reject(requestErrorMessage(r.message.GuildID, err))
+}
+
+func (r *messageRequest) reject(reason string) {
+	r.unreact(reactionWorking)
+	r.react(reactionFailed)
+
+	reply, err := r.session.ChannelMessageSendReply(r.message.ChannelID, reason, r.message.Reference())
+	if err != nil {
+		logger.Error.Printf("Failed to explain a rejected request in channel %s: %v", r.message.ChannelID, err)
+		return
+	}
+	r.deleteLater(reply.ID)
+}
+
+func (r *messageRequest) react(emoji string) {
+	if err := r.session.MessageReactionAdd(r.message.ChannelID, r.message.ID, emoji); err != nil {
+		logger.Debug.Printf("Failed to react to request %s: %v", r.message.ID, err)
+	}
+}
+
+func (r *messageRequest) unreact(emoji string) {
+	if err := r.session.MessageReactionRemove(r.message.ChannelID, r.message.ID, emoji, "@me"); err != nil {
+		logger.Debug.Printf("Failed to remove reaction from request %s: %v", r.message.ID, err)
+	}
+}
+
+// deleteLater deletes messageID from the request channel after
+// requestCleanupDelay. Deleting another user's message needs Manage
+// Messages; without it the message stays.
+func (r *messageRequest) deleteLater(messageID string) {
+	channelID := r.message.ChannelID
+	time.AfterFunc(requestCleanupDelay, func() {
+		if err := r.session.ChannelMessageDelete(channelID, messageID); err != nil {
+			logger.Debug.Printf("Failed to delete message %s from request channel %s: %v", messageID, channelID, err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type RestartCommand struct {
+	guilds *guilds.Registry
+}
+
+func NewRestartCommand(guildRegistry *guilds.Registry) *RestartCommand {
+	return &RestartCommand{
+		guilds: guildRegistry,
+	}
+}
+
+func (c *RestartCommand) Name() string {
+	return "restart"
+}
+
+func (c *RestartCommand) Description() string {
+	return "Restart the queue from the current song"
+}
+
+func (c *RestartCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *RestartCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *RestartCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *RestartCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *RestartCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	if guild.State.GetCurrentChannel() == "" || guild.State.IsInIdleChannel() {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "restart.not_in_music")),
+		})
+		return err
+	}
+
+	count, err := guild.Music.RestartQueue()
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "restart.failed", err)),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "restart.restarted", count)),
+	})
+	return err
+}
@@ -1,8 +1,11 @@
 package commands
 
 import (
-	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"errors"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/state"
 	"musicbot/internal/voice"
 	"time"
@@ -11,18 +14,14 @@ import (
 )
 
 type ResumeCommand struct {
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
+	audit  *audit.Log
 }
 
-func NewResumeCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *ResumeCommand {
+func NewResumeCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *ResumeCommand {
 	return &ResumeCommand{
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
+		audit:  auditLog,
 	}
 }
 
@@ -34,56 +33,58 @@ func (c *ResumeCommand) Description() string {
 	return "Resume paused music in your voice channel"
 }
 
+func (c *ResumeCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ResumeCommand) ControlsMusic() bool {
+	return true
+}
+
 func (c *ResumeCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *ResumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *ResumeCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
 
-	currentSong := c.musicManager.GetCurrentSong()
-	queueItems := c.musicManager.GetQueue()
+	currentSong := guild.Music.GetCurrentSong()
+	queueItems := guild.Music.GetQueue()
 
 	if currentSong == nil && len(queueItems) == 0 {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No music queue available to resume."),
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "resume.no_queue")),
 		})
 		return err
 	}
 
-	if c.musicManager.IsPlaying() {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Music is already playing."),
+	if guild.Music.IsPlaying() {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "resume.already_playing")),
 		})
 		return err
 	}
 
-	userVS, err := s.State.VoiceState(i.GuildID, i.Member.User.ID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
+	userChannelID, err := voice.UserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.not_in_voice")),
 		})
 		return err
 	}
 
-	userChannelID := userVS.ChannelID
-	currentChannelID := c.stateManager.GetCurrentChannel()
+	currentChannelID := guild.State.GetCurrentChannel()
 
-	c.stateManager.SetManualOperationActive(true)
-	defer c.stateManager.SetManualOperationActive(false)
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
 
-	c.musicManager.ExecuteWithDisabledHandlers(func() {
-		c.radioManager.Stop()
+	guild.Music.ExecuteWithDisabledHandlers(func() {
+		guild.Radio.Stop()
 
 		if currentChannelID != userChannelID {
 			time.Sleep(500 * time.Millisecond)
 
-			err = c.voiceManager.JoinUser(i.GuildID, i.Member.User.ID)
+			err = guild.Voice.JoinUser(i.GuildID, i.Member.User.ID)
 			if err != nil {
 				return
 			}
@@ -91,14 +92,14 @@ func (c *ResumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 			time.Sleep(500 * time.Millisecond)
 		}
 
-		c.stateManager.SetBotState(state.StateDJ)
+		guild.State.SetBotState(state.StateDJ)
 
-		if c.musicManager.IsPaused() {
-			err = c.musicManager.Resume()
+		if guild.Music.IsPaused() {
+			err = guild.Music.Resume()
 		} else {
-			vc := c.voiceManager.GetVoiceConnection()
+			vc := guild.Voice.GetVoiceConnection()
 			if vc != nil {
-				err = c.musicManager.Start(vc)
+				err = guild.Music.Start(vc)
 			}
 		}
 	})
@@ -106,22 +107,28 @@ func (c *ResumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 	if err != nil {
 		if err.Error() == "user not in voice channel" {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ You need to be in a voice channel."),
+				Content: stringPtr(i18n.T(i.GuildID, "common.not_in_voice")),
+			})
+		} else if errors.As(err, new(*voice.ChannelError)) {
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(joinErrorMessage(i.GuildID, err)),
 			})
 		} else if err.Error() == "already in user's channel" {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to resume music."),
+				Content: stringPtr(i18n.T(i.GuildID, "resume.failed")),
 			})
 		} else {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to resume music."),
+				Content: stringPtr(i18n.T(i.GuildID, "resume.failed")),
 			})
 		}
 		return err
 	}
 
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionResume, "")
+
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr("▶️ Music resumed!"),
+		Content: stringPtr(i18n.T(i.GuildID, "resume.resumed")),
 	})
 	return err
 }
@@ -1,7 +1,15 @@
 package commands
 
 import (
+	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
 	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
+	"musicbot/internal/permissions"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,29 +20,165 @@ type Command interface {
 	Name() string
 	Description() string
 	Options() []*discordgo.ApplicationCommandOption
-	Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error
+	Execute(s discordapi.Session, i *discordgo.InteractionCreate) error
+}
+
+// PermissionedCommand is implemented by commands that need more than the
+// default user level. The router enforces it before Execute is called.
+type PermissionedCommand interface {
+	RequiredLevel() permissions.Level
+}
+
+// DiscordPermissionCommand is implemented by commands that need a Discord
+// permission rather than a bot role. They are registered with it as their
+// default member permissions, so Discord hides them from members without it.
+type DiscordPermissionCommand interface {
+	RequiredPermissions() int64
+}
+
+// MusicControlCommand is implemented by commands that change what is playing
+// or queued. While a guild is in DJ-only mode the router limits them to the DJ
+// role.
+type MusicControlCommand interface {
+	ControlsMusic() bool
+}
+
+// QueueingCommand is implemented by commands that add songs to the queue.
+// The router turns users with a queue timeout away from them and from their
+// components, such as the search result buttons.
+type QueueingCommand interface {
+	QueuesSongs() bool
+}
+
+// QueueTimeouts tells whether a user is barred from queueing in a guild, and
+// until when.
+type QueueTimeouts interface {
+	TimedOut(guildID, userID string) (time.Time, bool)
+}
+
+// InstantCommand is implemented by commands that answer straight away
+// without waiting on the database, the player or Discord. The router defers
+// every other command before Execute, so Discord's 3 second deadline is met
+// however long the command then takes, and their Execute edits the deferred
+// response rather than responding.
+type InstantCommand interface {
+	RespondsInstantly() bool
+}
+
+// EphemeralCommand is implemented by commands whose answer only the user who
+// ran them sees. The router defers them as ephemeral.
+type EphemeralCommand interface {
+	Ephemeral() bool
+}
+
+// ComponentHandler handles message components (buttons, selects) whose
+// custom ID starts with ComponentPrefix.
+type ComponentHandler interface {
+	ComponentPrefix() string
+	HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error
 }
 
 type Router struct {
-	commands   map[string]Command
-	session    *discordgo.Session
-	versioning *Versioning
-	mu         sync.RWMutex
+	commands          map[string]Command
+	componentHandlers map[string]ComponentHandler
+	session           discordapi.Session
+	versioning        *Versioning
+	syncMu            sync.Mutex // one command sync at a time
+	permissionManager *permissions.Manager
+	timeouts          QueueTimeouts
+	cooldowns         *cooldownTracker
+	disabled          *disabledCommands
+	mu                sync.RWMutex
 }
 
-func NewRouter(session *discordgo.Session) *Router {
-	return &Router{
-		commands:   make(map[string]Command),
-		session:    session,
-		versioning: NewVersioning(""),
-		mu:         sync.RWMutex{},
+func NewRouter(session discordapi.Session, permissionManager *permissions.Manager, timeouts QueueTimeouts) *Router {
+	r := &Router{
+		commands:          make(map[string]Command),
+		componentHandlers: make(map[string]ComponentHandler),
+		session:           session,
+		versioning:        NewVersioning(""),
+		permissionManager: permissionManager,
+		timeouts:          timeouts,
+		cooldowns:         newCooldownTracker(),
+		disabled:          newDisabledCommands(),
+		mu:                sync.RWMutex{},
 	}
+
+	go r.cooldowns.run()
+	return r
 }
 
 func (r *Router) Register(cmd Command) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.commands[cmd.Name()] = cmd
+
+	if handler, ok := cmd.(ComponentHandler); ok {
+		r.componentHandlers[handler.ComponentPrefix()] = handler
+	}
+}
+
+// Commands returns the registered commands sorted by name.
+func (r *Router) Commands() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(a, b int) bool {
+		return commands[a].Name() < commands[b].Name()
+	})
+	return commands
+}
+
+// Command returns the registered command called name.
+func (r *Router) Command(name string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+func (r *Router) HandleComponent(i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	customID := i.MessageComponentData().CustomID
+
+	r.mu.RLock()
+	var handler ComponentHandler
+	for prefix, h := range r.componentHandlers {
+		if strings.HasPrefix(customID, prefix) {
+			handler = h
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if handler == nil {
+		logger.Debug.Printf("No component handler for %s", customID)
+		return
+	}
+
+	// A button is the command it came from, so it goes through the same
+	// checks: the command's level, raised to DJ by DJ-only mode if it
+	// controls music, and queue timeouts.
+	if cmd, ok := handler.(Command); ok {
+		if r.CommandDisabled(i.GuildID, cmd.Name()) {
+			r.respondEphemeral(i, i18n.T(i.GuildID, "commands.disabled_here", cmd.Name()))
+			return
+		}
+		if !r.checkPermission(cmd, i) || r.timedOut(cmd, i) {
+			return
+		}
+	}
+
+	if err := handler.HandleComponent(r.session, i); err != nil {
+		logger.ForGuild(i.GuildID).Error("Component failed", "custom_id", customID, "user_id", interactionUserID(i), "error", err)
+	}
 }
 
 func (r *Router) Handle(i *discordgo.InteractionCreate) {
@@ -44,6 +188,13 @@ func (r *Router) Handle(i *discordgo.InteractionCreate) {
 
 	cmdName := i.ApplicationCommandData().Name
 
+	deferred := false
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.commandPanicked(cmdName, i, deferred, recovered)
+		}
+	}()
+
 	r.mu.RLock()
 	cmd, exists := r.commands[cmdName]
 	r.mu.RUnlock()
@@ -53,12 +204,349 @@ func (r *Router) Handle(i *discordgo.InteractionCreate) {
 		return
 	}
 
-	if err := cmd.Execute(r.session, i); err != nil {
-		logger.Error.Printf("Command %s failed: %v", cmdName, err)
+	if r.CommandDisabled(i.GuildID, cmdName) {
+		r.respondEphemeral(i, i18n.T(i.GuildID, "commands.disabled_here", cmdName))
+		metrics.CommandHandled(cmdName, metrics.StatusDenied)
+		return
+	}
+
+	if !r.checkPermission(cmd, i) || r.timedOut(cmd, i) {
+		metrics.CommandHandled(cmdName, metrics.StatusDenied)
+		return
+	}
+
+	release, ok := r.acquireSlot(cmd, i.GuildID)
+	if !ok {
+		r.respondEphemeral(i, i18n.T(i.GuildID, "cooldown.busy", cmdName))
+		metrics.CommandHandled(cmdName, metrics.StatusThrottled)
+		return
+	}
+	// A background command holds its slot until its work is done, unless
+	// starting it failed.
+	background := false
+	defer func() {
+		if !background {
+			release()
+		}
+	}()
+
+	if !r.checkCooldown(cmd, i) {
+		metrics.CommandHandled(cmdName, metrics.StatusThrottled)
+		return
+	}
+
+	log := logger.ForCommand(i.GuildID, cmdName)
+	log.Debug("Handling command", "user_id", interactionUserID(i))
+
+	if !respondsInstantly(cmd) {
+		if err := r.deferResponse(cmd, i); err != nil {
+			log.Error("Failed to defer the response", "error", err)
+			metrics.CommandHandled(cmdName, metrics.StatusError)
+			return
+		}
+		deferred = true
+	}
+
+	var err error
+	if bg, ok := cmd.(BackgroundCommand); ok {
+		err = bg.Start(r.session, i, release)
+		background = err == nil
+	} else {
+		err = cmd.Execute(r.session, i)
+	}
+	if err != nil {
+		log.Error("Command failed", "error", err)
+		metrics.CommandHandled(cmdName, metrics.StatusError)
+		return
+	}
+
+	metrics.CommandHandled(cmdName, metrics.StatusOK)
+}
+
+// acquireSlot takes one of the guild's slots for a concurrency-limited
+// command. It returns false if they are all taken, and otherwise a func that
+// gives the slot back, which is safe to call more than once.
+func (r *Router) acquireSlot(cmd Command, guildID string) (func(), bool) {
+	limited, ok := cmd.(ConcurrencyLimitedCommand)
+	if !ok {
+		return func() {}, true
+	}
+	if !r.cooldowns.acquire(guildID, cmd.Name(), limited.MaxConcurrentPerGuild()) {
+		return nil, false
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { r.cooldowns.release(guildID, cmd.Name()) })
+	}, true
+}
+
+// Admit makes the checks Handle makes before running cmdName, for a request
+// that doesn't arrive as an interaction, such as a link pasted in a request
+// channel. It starts the user's cooldown and returns why they are turned
+// away, or "" if they may go ahead. A user who is let in holds one of the
+// command's slots in the guild until they call release.
+func (r *Router) Admit(guildID, userID, cmdName string) (release func(), reason string) {
+	release = func() {}
+
+	r.mu.RLock()
+	cmd, exists := r.commands[cmdName]
+	r.mu.RUnlock()
+
+	if !exists {
+		return release, i18n.T(guildID, "commands.unknown", cmdName)
+	}
+	if r.CommandDisabled(guildID, cmdName) {
+		return release, i18n.T(guildID, "commands.disabled_here", cmdName)
+	}
+
+	if level, djOnly := r.commandLevel(cmd, guildID); level != permissions.LevelUser {
+		hasPermission, err := r.permissionManager.HasPermission(r.session, guildID, userID, level)
+		if err != nil {
+			logger.ForCommand(guildID, cmdName).Error("Permission check failed", "error", err)
+			return release, i18n.T(guildID, "permissions.check_failed")
+		}
+		if !hasPermission {
+			roleName := r.permissionManager.GetRequiredRoleName(guildID, level)
+			if djOnly {
+				return release, i18n.T(guildID, "permissions.dj_only", roleName, cmdName)
+			}
+			return release, i18n.T(guildID, "permissions.denied", roleName, cmdName)
+		}
+	}
+
+	if r.timeouts != nil && queuesSongs(cmd) {
+		if until, ok := r.timeouts.TimedOut(guildID, userID); ok {
+			return release, i18n.T(guildID, "djban.timed_out", until.Unix())
+		}
+	}
+
+	slot, ok := r.acquireSlot(cmd, guildID)
+	if !ok {
+		return release, i18n.T(guildID, "cooldown.busy", cmdName)
+	}
+
+	if cc, ok := cmd.(CooldownCommand); ok && cc.Cooldown() > 0 {
+		wait, ok := r.cooldowns.use(userID, cmdName, cc.Cooldown(), time.Now())
+		if !ok {
+			isAdmin, err := r.permissionManager.HasPermission(r.session, guildID, userID, permissions.LevelAdmin)
+			if err != nil || !isAdmin {
+				slot()
+				return release, i18n.T(guildID, "cooldown.wait", formatWait(wait))
+			}
+		}
+	}
+
+	return slot, ""
+}
+
+// deferResponse acknowledges a command before it runs, showing the user that
+// the bot is thinking until Execute edits in its answer.
+func (r *Router) deferResponse(cmd Command, i *discordgo.InteractionCreate) error {
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}
+	if ec, ok := cmd.(EphemeralCommand); ok && ec.Ephemeral() {
+		response.Data = &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		}
+	}
+	return r.session.InteractionRespond(i.Interaction, response)
+}
+
+// commandPanicked logs a command that panicked along with its stack, and
+// tells the user it failed instead of leaving them waiting on the deferred
+// response.
+func (r *Router) commandPanicked(cmdName string, i *discordgo.InteractionCreate, deferred bool, recovered any) {
+	logger.ForCommand(i.GuildID, cmdName).Error("Command panicked",
+		"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+	metrics.CommandHandled(cmdName, metrics.StatusError)
+
+	content := i18n.T(i.GuildID, "common.command_failed")
+	if !deferred {
+		r.respondEphemeral(i, content)
+		return
+	}
+	_, err := r.session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to report a failed command: %v", err)
+	}
+}
+
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+func requiredLevel(cmd Command) permissions.Level {
+	if pc, ok := cmd.(PermissionedCommand); ok {
+		return pc.RequiredLevel()
+	}
+	return permissions.LevelUser
+}
+
+func respondsInstantly(cmd Command) bool {
+	ic, ok := cmd.(InstantCommand)
+	return ok && ic.RespondsInstantly()
+}
+
+func queuesSongs(cmd Command) bool {
+	qc, ok := cmd.(QueueingCommand)
+	return ok && qc.QueuesSongs()
+}
+
+func controlsMusic(cmd Command) bool {
+	mc, ok := cmd.(MusicControlCommand)
+	return ok && mc.ControlsMusic()
+}
+
+// commandLevel is the level needed to run cmd in the guild right now, and
+// whether it was raised to DJ by DJ-only mode.
+func (r *Router) commandLevel(cmd Command, guildID string) (permissions.Level, bool) {
+	level := requiredLevel(cmd)
+	if level < permissions.LevelDJ && controlsMusic(cmd) && r.permissionManager.DJOnly(guildID) {
+		return permissions.LevelDJ, true
 	}
+	return level, false
 }
 
-func (r *Router) UpdateCommands() error {
+func (r *Router) checkPermission(cmd Command, i *discordgo.InteractionCreate) bool {
+	level, djOnly := r.commandLevel(cmd, i.GuildID)
+
+	if level == permissions.LevelUser {
+		return true
+	}
+
+	if i.Member == nil || i.Member.User == nil {
+		r.respondDenied(i, i18n.T(i.GuildID, "permissions.server_only"))
+		return false
+	}
+
+	hasPermission, err := r.permissionManager.HasPermission(r.session, i.GuildID, i.Member.User.ID, level)
+	if err != nil {
+		logger.ForCommand(i.GuildID, cmd.Name()).Error("Permission check failed", "error", err)
+		r.respondDenied(i, i18n.T(i.GuildID, "permissions.check_failed"))
+		return false
+	}
+
+	if !hasPermission {
+		roleName := r.permissionManager.GetRequiredRoleName(i.GuildID, level)
+		if djOnly {
+			r.respondDenied(i, i18n.T(i.GuildID, "permissions.dj_only", roleName, cmd.Name()))
+		} else {
+			r.respondDenied(i, i18n.T(i.GuildID, "permissions.denied", roleName, cmd.Name()))
+		}
+		return false
+	}
+
+	return true
+}
+
+// timedOut turns the user away from cmd if it queues songs and they have a
+// queue timeout. It reports whether they were turned away.
+func (r *Router) timedOut(cmd Command, i *discordgo.InteractionCreate) bool {
+	if r.timeouts == nil || !queuesSongs(cmd) {
+		return false
+	}
+
+	until, ok := r.timeouts.TimedOut(i.GuildID, interactionUserID(i))
+	if !ok {
+		return false
+	}
+	r.respondDenied(i, i18n.T(i.GuildID, "djban.timed_out", until.Unix()))
+	return true
+}
+
+// checkCooldown starts the user's cooldown for cmd, or tells them how long to
+// wait if one is still running. Admins are never throttled.
+func (r *Router) checkCooldown(cmd Command, i *discordgo.InteractionCreate) bool {
+	cc, ok := cmd.(CooldownCommand)
+	if !ok || cc.Cooldown() <= 0 {
+		return true
+	}
+
+	userID := interactionUserID(i)
+	if userID == "" {
+		return true
+	}
+
+	wait, ok := r.cooldowns.use(userID, cmd.Name(), cc.Cooldown(), time.Now())
+	if ok {
+		return true
+	}
+
+	if i.Member != nil {
+		isAdmin, err := r.permissionManager.HasPermission(r.session, i.GuildID, userID, permissions.LevelAdmin)
+		if err != nil {
+			logger.ForCommand(i.GuildID, cmd.Name()).Debug("Admin check for cooldown failed", "error", err)
+		} else if isAdmin {
+			return true
+		}
+	}
+
+	r.respondEphemeral(i, i18n.T(i.GuildID, "cooldown.wait", formatWait(wait)))
+	return false
+}
+
+func (r *Router) respondEphemeral(i *discordgo.InteractionCreate, content string) {
+	err := r.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Content: content,
+		},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to send ephemeral response: %v", err)
+	}
+}
+
+func (r *Router) respondDenied(i *discordgo.InteractionCreate, description string) {
+	err := r.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       i18n.T(i.GuildID, "permissions.denied_title"),
+					Description: description,
+					Color:       0xED4245,
+				},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to send permission denial: %v", err)
+	}
+}
+
+// respondNotOwner rejects a component interaction from someone other than the
+// user who ran the command that created it.
+func respondNotOwner(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Content: i18n.T(i.GuildID, "common.not_your_buttons"),
+		},
+	})
+}
+
+// SyncCommands brings the slash commands Discord has in line with the
+// registered ones, creating, editing and deleting only what differs. With
+// force every command is pushed again. A command Discord refuses is logged
+// and counted as failed rather than stopping the sync.
+func (r *Router) SyncCommands(force bool) (ChangeSummary, error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
 	logger.Info.Println("Checking for command changes...")
 
 	r.mu.RLock()
@@ -68,26 +556,27 @@ func (r *Router) UpdateCommands() error {
 	}
 	r.mu.RUnlock()
 
-	existing, err := r.session.ApplicationCommands(r.session.State.User.ID, "")
+	existing, err := r.session.ApplicationCommands(r.session.Cache().User.ID, "")
 	if err != nil {
-		return err
+		return ChangeSummary{}, err
 	}
 
-	changeSummary := r.versioning.GetChangeSummary(commands, existing)
+	changeSummary := r.versioning.GetChangeSummary(commands, existing, force)
 	changeSummary.LogSummary()
 
 	if !changeSummary.HasChanges() {
-		logger.Info.Println("All commands are up to date")
-		return nil
+		logger.Info.Printf("All commands are up to date (%s)", changeSummary)
+		return changeSummary, nil
 	}
 
 	logger.Info.Println("Applying command changes...")
 
 	for _, cmdID := range changeSummary.ToDelete {
 		logger.Info.Printf("Deleting command ID: %s", cmdID)
-		err := r.session.ApplicationCommandDelete(r.session.State.User.ID, "", cmdID)
+		err := r.session.ApplicationCommandDelete(r.session.Cache().User.ID, "", cmdID)
 		if err != nil {
 			logger.Error.Printf("Failed to delete command %s: %v", cmdID, err)
+			changeSummary.Failed++
 		}
 		time.Sleep(200 * time.Millisecond)
 	}
@@ -95,15 +584,10 @@ func (r *Router) UpdateCommands() error {
 	for cmdID, cmd := range changeSummary.ToUpdate {
 		logger.Info.Printf("Updating command: %s", cmd.Name())
 
-		commandDef := &discordgo.ApplicationCommand{
-			Name:        cmd.Name(),
-			Description: cmd.Description(),
-			Options:     cmd.Options(),
-		}
-
-		updatedCmd, err := r.session.ApplicationCommandEdit(r.session.State.User.ID, "", cmdID, commandDef)
+		updatedCmd, err := r.session.ApplicationCommandEdit(r.session.Cache().User.ID, "", cmdID, commandDefinition(cmd))
 		if err != nil {
 			logger.Error.Printf("Failed to update command %s: %v", cmd.Name(), err)
+			changeSummary.Failed++
 			continue
 		}
 
@@ -118,15 +602,10 @@ func (r *Router) UpdateCommands() error {
 	for _, cmd := range changeSummary.ToCreate {
 		logger.Info.Printf("Creating command: %s", cmd.Name())
 
-		commandDef := &discordgo.ApplicationCommand{
-			Name:        cmd.Name(),
-			Description: cmd.Description(),
-			Options:     cmd.Options(),
-		}
-
-		createdCmd, err := r.session.ApplicationCommandCreate(r.session.State.User.ID, "", commandDef)
+		createdCmd, err := r.session.ApplicationCommandCreate(r.session.Cache().User.ID, "", commandDefinition(cmd))
 		if err != nil {
 			logger.Error.Printf("Failed to create command %s: %v", cmd.Name(), err)
+			changeSummary.Failed++
 			continue
 		}
 
@@ -143,6 +622,6 @@ func (r *Router) UpdateCommands() error {
 		logger.Error.Printf("Failed to save command registry: %v", err)
 	}
 
-	logger.Info.Println("Command update completed successfully")
-	return nil
+	logger.Info.Printf("Command sync completed: %s", changeSummary)
+	return changeSummary, nil
 }
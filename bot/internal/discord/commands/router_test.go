@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// slowCommand takes as long as the test holds release before it answers,
+// standing in for a command that takes longer than Discord waits for an
+// acknowledgement.
+type slowCommand struct {
+	stubCommand
+	release   chan struct{}
+	ephemeral bool
+	instant   bool
+	panics    bool
+}
+
+func (c *slowCommand) Ephemeral() bool         { return c.ephemeral }
+func (c *slowCommand) RespondsInstantly() bool { return c.instant }
+
+func (c *slowCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	<-c.release
+	if c.panics {
+		panic("slow command broke")
+	}
+	if c.instant {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "done"},
+		})
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: stringPtr("done")})
+	return err
+}
+
+func TestSlowCommandsAreAcknowledgedFirst(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        slowCommand
+		acked      bool
+		wantKinds  []string
+		answer     string
+		wantHidden bool
+	}{
+		{"slow command", slowCommand{}, true, []string{"respond", "edit"}, "done", false},
+		{"ephemeral command", slowCommand{ephemeral: true}, true, []string{"respond", "edit"}, "done", true},
+		{"panicking command", slowCommand{panics: true}, true, []string{"respond", "edit"}, i18n.T(testGuildID, "common.command_failed"), false},
+		{"instant command", slowCommand{instant: true}, false, []string{"respond"}, "done", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession(t)
+			router := NewRouter(session, permissions.NewManager(permissions.Config{}, nil), nil)
+			cmd := tt.cmd
+			cmd.stubCommand = stubCommand{name: "slow"}
+			cmd.release = make(chan struct{})
+			router.Register(&cmd)
+
+			i := commandInteraction("user", "slow")
+			handled := make(chan struct{})
+			go func() {
+				defer close(handled)
+				router.Handle(i)
+			}()
+
+			// Discord gives up on an interaction that isn't acknowledged
+			// within 3 seconds. The command is held for as long as the test
+			// likes, so a 2.5 second command is acknowledged while it runs,
+			// not after.
+			if tt.acked {
+				ack := session.await(t, i, "acknowledgement", func(r response) bool { return r.kind == "respond" })
+				if ack.content != "" || ack.ephemeral != tt.wantHidden {
+					t.Errorf("acknowledged with %q, ephemeral %v; want a deferred response, ephemeral %v", ack.content, ack.ephemeral, tt.wantHidden)
+				}
+			} else {
+				select {
+				case <-handled:
+					t.Fatal("Handle returned before the command ran")
+				case <-time.After(50 * time.Millisecond):
+				}
+				if sent := session.sent(i); len(sent) != 0 {
+					t.Fatalf("an instant command was acknowledged for it: %v", sent)
+				}
+			}
+
+			close(cmd.release)
+			session.awaitContent(t, i, tt.answer)
+			<-handled
+
+			var kinds []string
+			for _, r := range session.sent(i) {
+				kinds = append(kinds, r.kind)
+			}
+			if len(kinds) != len(tt.wantKinds) {
+				t.Fatalf("sent %v, want %v", kinds, tt.wantKinds)
+			}
+			for k := range kinds {
+				if kinds[k] != tt.wantKinds[k] {
+					t.Fatalf("sent %v, want %v", kinds, tt.wantKinds)
+				}
+			}
+		})
+	}
+}
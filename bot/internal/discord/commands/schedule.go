@@ -0,0 +1,263 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/schedule"
+	"musicbot/internal/urlnorm"
+	"musicbot/internal/voice"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxSchedulesPerGuild bounds how many jobs a guild can have pending.
+const maxSchedulesPerGuild = 25
+
+type ScheduleCommand struct {
+	scheduler *schedule.Scheduler
+	blacklist *blacklist.List
+	audit     *audit.Log
+}
+
+func NewScheduleCommand(scheduler *schedule.Scheduler, blacklistList *blacklist.List, auditLog *audit.Log) *ScheduleCommand {
+	return &ScheduleCommand{
+		scheduler: scheduler,
+		blacklist: blacklistList,
+		audit:     auditLog,
+	}
+}
+
+func (c *ScheduleCommand) Name() string {
+	return "schedule"
+}
+
+func (c *ScheduleCommand) Description() string {
+	return "Start a track or playlist at a given time"
+}
+
+func (c *ScheduleCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *ScheduleCommand) Examples() []string {
+	return []string{
+		"/schedule add url:https://www.youtube.com/watch?v=dQw4w9WgXcQ time:in 2h channel:#music",
+		"/schedule add url:https://www.youtube.com/playlist?list=PL... time:2025-06-01T20:00+02:00 channel:#music playlist:True",
+		"/schedule remove id:3",
+	}
+}
+
+func (c *ScheduleCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *ScheduleCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Schedule a track or playlist",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "url",
+					Description: "URL of the track or playlist",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "time",
+					Description: "When to start, e.g. 2025-06-01T20:00+02:00 (UTC if no offset) or in 2h",
+					Required:    true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel",
+					Description:  "Voice channel to play in",
+					Required:     true,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "playlist",
+					Description: "The URL is a playlist",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "preempt",
+					Description: "Skip what is playing at that time instead of queueing behind it (tracks only)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "Show the scheduled tracks and playlists",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Cancel a scheduled track or playlist",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "id",
+					Description: "ID shown by /schedule list",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+func (c *ScheduleCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	sub := i.ApplicationCommandData().Options[0]
+	switch sub.Name {
+	case "add":
+		return c.add(s, i, sub.Options)
+	case "list":
+		return c.list(s, i)
+	case "remove":
+		return c.remove(s, i, sub.Options[0].IntValue())
+	}
+	return nil
+}
+
+func (c *ScheduleCommand) add(s discordapi.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) error {
+	job := config.Schedule{
+		GuildID:     i.GuildID,
+		RequestedBy: i.Member.User.ID,
+	}
+	var timeInput string
+	for _, option := range options {
+		switch option.Name {
+		case "url":
+			job.URL = strings.TrimSpace(option.StringValue())
+		case "time":
+			timeInput = option.StringValue()
+		case "channel":
+			if channel := discordapi.ChannelOption(s, option); channel != nil {
+				job.ChannelID = channel.ID
+			}
+		case "playlist":
+			job.Playlist = option.BoolValue()
+		case "preempt":
+			job.Preempt = option.BoolValue()
+		}
+	}
+
+	if parsed, err := url.Parse(job.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.bad_url"))
+	}
+	if !job.Playlist {
+		job.URL = urlnorm.Normalize(job.URL)
+	}
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, job.RequestedBy, job.URL); blocked {
+		return c.respond(s, i, reason)
+	}
+	if job.Playlist && job.Preempt {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.preempt_playlist"))
+	}
+
+	runAt, err := schedule.ParseTime(timeInput, time.Now())
+	if err != nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.bad_time"))
+	}
+	job.RunAt = runAt
+
+	if err := voice.CheckJoin(s, i.GuildID, job.ChannelID); err != nil {
+		return c.respond(s, i, joinErrorMessage(i.GuildID, err))
+	}
+
+	pending, err := c.scheduler.List(i.GuildID)
+	if err != nil {
+		return c.respondFailed(s, i, err)
+	}
+	if len(pending) >= maxSchedulesPerGuild {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.too_many", maxSchedulesPerGuild))
+	}
+
+	job, err = c.scheduler.Add(job)
+	switch {
+	case errors.Is(err, schedule.ErrInPast):
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.in_past"))
+	case errors.Is(err, schedule.ErrTooFar):
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.too_far", int(schedule.MaxAhead.Hours()/24)))
+	case err != nil:
+		return c.respondFailed(s, i, err)
+	}
+
+	c.audit.Record(i.GuildID, job.RequestedBy, audit.ActionScheduleAdd, fmt.Sprintf("#%d %s", job.ID, job.URL))
+	return c.respond(s, i, i18n.T(i.GuildID, "schedule.added", job.ID, job.URL, job.RunAt.Unix(), job.ChannelID))
+}
+
+func (c *ScheduleCommand) list(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	jobs, err := c.scheduler.List(i.GuildID)
+	if err != nil {
+		return c.respondFailed(s, i, err)
+	}
+	if len(jobs) == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.none"))
+	}
+
+	var lines []string
+	for _, job := range jobs {
+		line := i18n.T(i.GuildID, "schedule.line", job.ID, job.RunAt.Unix(), job.URL, job.ChannelID, job.RequestedBy)
+		if job.Playlist {
+			line += i18n.T(i.GuildID, "schedule.flag_playlist")
+		}
+		if job.Preempt {
+			line += i18n.T(i.GuildID, "schedule.flag_preempt")
+		}
+		lines = append(lines, line)
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       i18n.T(i.GuildID, "schedule.title", len(jobs)),
+				Description: blacklistField(i.GuildID, lines),
+				Color:       0x5865F2,
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+func (c *ScheduleCommand) remove(s discordapi.Session, i *discordgo.InteractionCreate, id int64) error {
+	removed, err := c.scheduler.Remove(i.GuildID, id)
+	if err != nil {
+		return c.respondFailed(s, i, err)
+	}
+	if !removed {
+		return c.respond(s, i, i18n.T(i.GuildID, "schedule.not_found", id))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionScheduleRemove, fmt.Sprintf("#%d", id))
+	return c.respond(s, i, i18n.T(i.GuildID, "schedule.removed", id))
+}
+
+func (c *ScheduleCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
+
+func (c *ScheduleCommand) respondFailed(s discordapi.Session, i *discordgo.InteractionCreate, err error) error {
+	logger.ForCommand(i.GuildID, c.Name()).Error("Failed to update schedules", "error", err)
+	return c.respond(s, i, i18n.T(i.GuildID, "schedule.failed_command"))
+}
@@ -1,43 +1,50 @@
 package commands
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/blacklist"
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
 	"musicbot/internal/music"
-	"musicbot/internal/radio"
 	"musicbot/internal/socket"
-	"musicbot/internal/state"
+	"musicbot/internal/urlnorm"
 	"musicbot/internal/voice"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type SearchCommand struct {
-	voiceManager  *voice.Manager
-	radioManager  *radio.Manager
-	musicManager  *music.Manager
-	stateManager  *state.Manager
-	socketClient  *socket.Client
-	searchResults map[string][]socket.SearchResult
-	searchMutex   sync.RWMutex
+	guilds       *guilds.Registry
+	socketClient *socket.Client
+	dbManager    *config.DatabaseManager
+	blacklist    *blacklist.List
+	sessions     *searchSessionStore
+	audit        *audit.Log
 }
 
-func NewSearchCommand(voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager, socketClient *socket.Client) *SearchCommand {
+func NewSearchCommand(guildRegistry *guilds.Registry, socketClient *socket.Client, dbManager *config.DatabaseManager, blacklistList *blacklist.List, auditLog *audit.Log) *SearchCommand {
 	cmd := &SearchCommand{
-		voiceManager:  voiceManager,
-		radioManager:  radioManager,
-		musicManager:  musicManager,
-		stateManager:  stateManager,
-		socketClient:  socketClient,
-		searchResults: make(map[string][]socket.SearchResult),
+		guilds:       guildRegistry,
+		socketClient: socketClient,
+		dbManager:    dbManager,
+		blacklist:    blacklistList,
+		sessions:     newSearchSessionStore(),
+		audit:        auditLog,
 	}
 
-	if socketClient != nil {
-		socketClient.SetSearchHandler(cmd.handleSearchResults)
-	}
+	go cmd.sessions.run()
 
 	return cmd
 }
@@ -50,6 +57,29 @@ func (c *SearchCommand) Description() string {
 	return "Search for songs to play"
 }
 
+func (c *SearchCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *SearchCommand) QueuesSongs() bool {
+	return true
+}
+
+func (c *SearchCommand) Examples() []string {
+	return []string{
+		"/search query:never gonna give you up",
+		"/search query:lofi beats platform:SoundCloud",
+	}
+}
+
+func (c *SearchCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *SearchCommand) Cooldown() time.Duration {
+	return 10 * time.Second
+}
+
 func (c *SearchCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{
 		{
@@ -72,12 +102,9 @@ func (c *SearchCommand) Options() []*discordgo.ApplicationCommandOption {
 	}
 }
 
-func (c *SearchCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
+func (c *SearchCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if reason, blocked := blacklistReason(c.blacklist, i.GuildID, i.Member.User.ID, ""); blocked {
+		return followUpBlacklisted(s, i, reason)
 	}
 
 	options := i.ApplicationCommandData().Options
@@ -89,17 +116,23 @@ func (c *SearchCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 		platform = options[1].StringValue()
 	}
 
-	userVS, err := s.State.VoiceState(i.GuildID, userID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
+	return c.startSearch(s, i, userID, query, platform)
+}
+
+// startSearch runs a search for an already deferred interaction and stores
+// the results in a session keyed by that interaction's ID.
+func (c *SearchCommand) startSearch(s discordapi.Session, i *discordgo.InteractionCreate, userID, query, platform string) error {
+	_, err := voice.UserVoiceChannel(s, i.GuildID, userID)
+	if err != nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.not_in_voice")),
 		})
 		return err
 	}
 
 	if c.socketClient == nil || !c.socketClient.IsConnected() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Search service is not available."),
+			Content: stringPtr(i18n.T(i.GuildID, "search.unavailable")),
 		})
 		return err
 	}
@@ -113,121 +146,229 @@ func (c *SearchCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr(fmt.Sprintf("🔍 Searching %s for: %s\n⏳ Please wait...", platformName, query)),
+		Content: stringPtr(i18n.T(i.GuildID, "search.searching", platformName, query)),
 	})
 	if err != nil {
 		return err
 	}
 
-	searchKey := fmt.Sprintf("%s-%s", userID, i.Interaction.ID)
+	searchKey := i.Interaction.ID
+	c.sessions.create(searchKey, userID, query, platform)
 
-	go func() {
-		err := c.socketClient.SendSearchRequest(query, platform, 5)
-		if err != nil {
-			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("❌ Failed to search: %v", err)),
-			})
-		}
-	}()
-
-	go c.waitForSearchResults(s, i, searchKey, 2*time.Minute)
+	go c.runSearch(s, i, searchKey, query, platform)
 
 	return nil
 }
 
-func (c *SearchCommand) waitForSearchResults(s *discordgo.Session, i *discordgo.InteractionCreate, searchKey string, timeout time.Duration) {
-	c.searchMutex.Lock()
-	c.searchResults[searchKey] = nil
-	c.searchMutex.Unlock()
-
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		c.searchMutex.RLock()
-		results := c.searchResults[searchKey]
-		c.searchMutex.RUnlock()
-
-		if results != nil {
-			c.showSearchResults(s, i, results, searchKey)
-			return
+func (c *SearchCommand) runSearch(s discordapi.Session, i *discordgo.InteractionCreate, searchKey, query, platform string) {
+	results, err := c.socketClient.Search(query, platform, maxSearchResults, 2*time.Minute)
+	if err != nil {
+		content := i18n.T(i.GuildID, "search.failed", err)
+		if errors.Is(err, socket.ErrRequestTimeout) {
+			content = i18n.T(i.GuildID, "search.timeout")
 		}
 
-		time.Sleep(1 * time.Second)
-	}
+		_, editErr := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+		if editErr != nil {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to report search failure", "error", editErr)
+		}
 
-	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr("⏱️ Search is taking longer than expected. Please try again with a different search term."),
-	})
-	if err != nil {
-		fmt.Printf("Failed to edit interaction: %v\n", err)
+		c.sessions.expire(searchKey)
+		return
 	}
 
-	c.searchMutex.Lock()
-	delete(c.searchResults, searchKey)
-	c.searchMutex.Unlock()
+	c.sessions.setResults(searchKey, results)
+	c.showSearchResults(s, i, results, searchKey)
 }
 
-func (c *SearchCommand) handleSearchResults(results []socket.SearchResult) {
-	c.searchMutex.Lock()
-	defer c.searchMutex.Unlock()
-
-	for key := range c.searchResults {
-		c.searchResults[key] = results
-	}
-}
-
-func (c *SearchCommand) showSearchResults(s *discordgo.Session, i *discordgo.InteractionCreate, results []socket.SearchResult, searchKey string) {
+func (c *SearchCommand) showSearchResults(s discordapi.Session, i *discordgo.InteractionCreate, results []socket.SearchResult, searchKey string) {
 	if len(results) == 0 {
 		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No results found."),
+			Content: stringPtr(i18n.T(i.GuildID, "search.no_results")),
 		})
 		if err != nil {
-			fmt.Printf("Failed to edit interaction: %v\n", err)
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to report empty search", "error", err)
 		}
 		return
 	}
 
-	content := "🎵 Search Results:\n\n"
-	var components []discordgo.MessageComponent
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
 
-	buttons := make([]discordgo.MessageComponent, 0)
+	ownerID := i.Member.User.ID
+	selectButtons := make([]discordgo.MessageComponent, 0)
+	nextButtons := make([]discordgo.MessageComponent, 0)
+	selections := make([]config.SearchSelection, 0)
 
 	for idx, result := range results {
-		if idx >= 5 {
-			break
-		}
-
-		duration := c.formatDuration(result.Duration)
-		content += fmt.Sprintf("**%d.** %s - %s (%s)\n", idx+1, result.Title, result.Uploader, duration)
+		hash := searchSelectionHash(ownerID, result.URL)
+		selections = append(selections, config.SearchSelection{
+			Hash:     hash,
+			OwnerID:  ownerID,
+			URL:      result.URL,
+			Title:    result.Title,
+			Uploader: result.Uploader,
+		})
 
-		button := discordgo.Button{
+		selectButtons = append(selectButtons, discordgo.Button{
 			Style:    discordgo.PrimaryButton,
 			Label:    strconv.Itoa(idx + 1),
-			CustomID: fmt.Sprintf("search_select_%s_%d", searchKey, idx),
+			CustomID: FormatSearchButtonID(SearchButton{Action: searchActionSelect, SearchKey: searchKey, Index: idx, Hash: hash}),
+		})
+		nextButtons = append(nextButtons, discordgo.Button{
+			Style:    discordgo.SecondaryButton,
+			Label:    i18n.T(i.GuildID, "search.play_next_button", idx+1),
+			CustomID: FormatSearchButtonID(SearchButton{Action: searchActionNext, SearchKey: searchKey, Index: idx, Hash: hash}),
+		})
+	}
+
+	if c.dbManager != nil {
+		if err := c.dbManager.SaveSearchSelections(selections); err != nil {
+			logger.Error.Printf("Failed to persist search selections: %v", err)
 		}
-		buttons = append(buttons, button)
 	}
 
-	actionRow := discordgo.ActionsRow{
-		Components: buttons,
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: selectButtons},
+		discordgo.ActionsRow{Components: nextButtons},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SuccessButton,
+					Label:    i18n.T(i.GuildID, "search.queue_all_button"),
+					CustomID: FormatSearchButtonID(SearchButton{Action: searchActionAll, SearchKey: searchKey}),
+				},
+			},
+		},
 	}
-	components = append(components, actionRow)
 
-	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content:    &content,
-		Components: &components,
-	})
+	edit := render.SearchResults(i.GuildID, results).Edit()
+	edit.Components = &components
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
 	if err != nil {
-		fmt.Printf("Failed to edit interaction: %v\n", err)
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to show search results", "error", err)
 	}
+}
 
-	go c.cleanupSearchResults(searchKey, 5*time.Minute)
+const (
+	searchActionSelect = "select"
+	searchActionNext   = "next"
+	searchActionAll    = "all"
+	searchActionRetry  = "retry"
+
+	maxSearchResults = 5
+)
+
+// SearchButton is the data carried in a search button's custom ID. Hash is
+// set on per-result buttons and points at a persisted SearchSelection.
+type SearchButton struct {
+	Action    string
+	SearchKey string
+	Index     int
+	Hash      string
 }
 
-func (c *SearchCommand) HandleSearchSelection(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+// FormatSearchButtonID builds search_<action>_<searchKey>_<index>[_<hash>].
+// Search keys are interaction IDs and hashes are 16 hex characters, so the
+// result stays well under Discord's 100 character limit.
+func FormatSearchButtonID(button SearchButton) string {
+	id := fmt.Sprintf("search_%s_%s_%d", button.Action, button.SearchKey, button.Index)
+	if button.Hash != "" {
+		id += "_" + button.Hash
+	}
+	return id
+}
+
+// ParseSearchButton reverses FormatSearchButtonID. Buttons posted before
+// actions and hashes existed were search_select_<key>_<index>, which parses
+// unchanged.
+func ParseSearchButton(customID string) (SearchButton, error) {
+	parts := strings.Split(customID, "_")
+	if (len(parts) != 4 && len(parts) != 5) || parts[0] != "search" {
+		return SearchButton{}, fmt.Errorf("invalid search button id: %s", customID)
+	}
+
+	button := SearchButton{
+		Action:    parts[1],
+		SearchKey: parts[2],
+	}
+
+	switch button.Action {
+	case searchActionSelect, searchActionNext, searchActionAll, searchActionRetry:
+	default:
+		return SearchButton{}, fmt.Errorf("unknown search action: %s", button.Action)
+	}
+
+	index, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return SearchButton{}, fmt.Errorf("invalid search index: %w", err)
+	}
+	button.Index = index
+
+	if len(parts) == 5 {
+		button.Hash = parts[4]
+	}
+
+	return button, nil
+}
+
+// searchSelectionHash identifies one result for one user. It is stored with
+// the result so the button still resolves after a restart.
+func searchSelectionHash(ownerID, url string) string {
+	sum := sha256.Sum256([]byte(ownerID + "\x00" + url))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c *SearchCommand) ComponentPrefix() string {
+	return "search_"
+}
+
+func (c *SearchCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return c.HandleSearchSelection(s, i)
+}
+
+func (c *SearchCommand) HandleSearchSelection(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	musicManager := c.guilds.Get(i.GuildID).Music
 	customID := i.MessageComponentData().CustomID
 	userID := i.Member.User.ID
 
+	button, parseErr := ParseSearchButton(customID)
+	searchKey := button.SearchKey
+
+	session, exists := c.sessions.get(searchKey)
+	results := session.results
+
+	// After a restart the session is gone, but per-result buttons can still
+	// be resolved from the persisted selection.
+	var persisted *config.SearchSelection
+	if parseErr == nil && !session.hasResults() && button.Hash != "" && c.dbManager != nil {
+		sel, err := c.dbManager.GetSearchSelection(button.Hash, searchQueryRetention)
+		if err != nil && err != sql.ErrNoRows {
+			logger.Error.Printf("Failed to load search selection %s: %v", button.Hash, err)
+		}
+		persisted = sel
+	}
+
+	if parseErr == nil {
+		if (exists && session.ownerID != userID) || (persisted != nil && persisted.OwnerID != userID) {
+			return respondNotOwner(s, i)
+		}
+
+		// A queue-all press checks each result as it goes instead.
+		selectedURL := ""
+		if persisted != nil {
+			selectedURL = persisted.URL
+		} else if button.Action != searchActionAll && button.Index >= 0 && button.Index < len(results) {
+			selectedURL = results[button.Index].URL
+		}
+		if reason, blocked := blacklistReason(c.blacklist, i.GuildID, userID, selectedURL); blocked {
+			return respondBlacklisted(s, i, reason)
+		}
+	}
+
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
@@ -235,121 +376,175 @@ func (c *SearchCommand) HandleSearchSelection(s *discordgo.Session, i *discordgo
 		return err
 	}
 
-	parts := strings.Split(customID, "_")
-	if len(parts) < 4 || parts[0] != "search" || parts[1] != "select" {
+	if parseErr != nil {
+		logger.Debug.Printf("Rejected search button: %v", parseErr)
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Invalid selection format."),
+			Content: stringPtr(i18n.T(i.GuildID, "search.invalid_selection")),
 		})
 		return err
 	}
 
-	searchKey := strings.Join(parts[2:len(parts)-1], "_")
-	selectedIndexStr := parts[len(parts)-1]
-
-	selectedIndex, err := strconv.Atoi(selectedIndexStr)
-	if err != nil {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Invalid selection index."),
-		})
-		return err
+	if button.Action == searchActionRetry && exists {
+		return c.startSearch(s, i, userID, session.query, session.platform)
 	}
 
-	c.searchMutex.RLock()
-	results, exists := c.searchResults[searchKey]
-	c.searchMutex.RUnlock()
-
-	if !exists || results == nil || selectedIndex >= len(results) {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Search results expired."),
-		})
-		return err
+	selectedIndex := button.Index
+	if persisted != nil {
+		results = []socket.SearchResult{{
+			Title:    persisted.Title,
+			URL:      persisted.URL,
+			Uploader: persisted.Uploader,
+		}}
+		selectedIndex = 0
 	}
 
-	selectedResult := results[selectedIndex]
+	if results == nil || selectedIndex < 0 || selectedIndex >= len(results) {
+		return c.respondExpired(s, i, searchKey, exists)
+	}
 
-	userVS, err := s.State.VoiceState(i.GuildID, userID)
-	if err != nil || userVS == nil || userVS.ChannelID == "" {
-		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ You need to be in a voice channel."),
+	if message := joinUserForRequest(s, c.guilds, c.guilds.Get(i.GuildID), userID); message != "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(message),
 		})
 		return err
 	}
 
-	userChannelID := userVS.ChannelID
-	currentChannelID := c.stateManager.GetCurrentChannel()
+	c.sessions.expire(searchKey)
 
-	if currentChannelID != "" && currentChannelID != userChannelID {
-		currentBotState := c.stateManager.GetBotState()
+	switch button.Action {
+	case searchActionAll:
+		go c.queueAll(s, i, results, userID)
+		return nil
 
-		if currentBotState == state.StateDJ && c.musicManager.IsPlaying() {
+	case searchActionNext:
+		selectedResult := results[selectedIndex]
+		selectedResult.URL = urlnorm.Normalize(selectedResult.URL)
+		limits := musicManager.DownloadLimits(i.GuildID)
+		if err := music.CheckDuration(selectedResult.Duration, limits); err != nil {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Bot is currently playing music in another channel."),
+				Content: stringPtr(requestErrorMessage(i.GuildID, err)),
 			})
 			return err
 		}
 
-		c.radioManager.Stop()
-		c.musicManager.Stop()
-
-		time.Sleep(500 * time.Millisecond)
-
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "search.downloading_next", selectedResult.Title, selectedResult.Uploader)),
+		})
 		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
-			})
 			return err
 		}
 
-		time.Sleep(500 * time.Millisecond)
-	} else if currentChannelID == "" {
-		err = c.voiceManager.JoinUser(i.GuildID, userID)
-		if err != nil {
-			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr("❌ Failed to join your voice channel."),
-			})
-			return err
-		}
-		time.Sleep(500 * time.Millisecond)
+		go func() {
+			followUp := newRequestFollowUp(s, i)
+			err := musicManager.RequestSongNext(selectedResult.URL, userID, limits, followUp)
+			if err != nil {
+				followUp.Finish(requestErrorMessage(i.GuildID, err))
+				return
+			}
+			c.audit.Record(i.GuildID, userID, audit.ActionPlay, selectedResult.URL)
+		}()
+		return nil
+	}
+
+	selectedResult := results[selectedIndex]
+	selectedResult.URL = urlnorm.Normalize(selectedResult.URL)
+	// The results carry each track's length, so one over the limit is
+	// refused here instead of after the download.
+	limits := musicManager.DownloadLimits(i.GuildID)
+	if err := music.CheckDuration(selectedResult.Duration, limits); err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(requestErrorMessage(i.GuildID, err)),
+		})
+		return err
 	}
 
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr(fmt.Sprintf("🎵 Downloading: %s - %s", selectedResult.Title, selectedResult.Uploader)),
+		Content: stringPtr(i18n.T(i.GuildID, "search.downloading", selectedResult.Title, selectedResult.Uploader)),
 	})
 	if err != nil {
 		return err
 	}
 
 	go func() {
-		err := c.musicManager.RequestSong(selectedResult.URL, userID)
+		followUp := newRequestFollowUp(s, i)
+		err := musicManager.RequestSong(selectedResult.URL, userID, limits, followUp)
 		if err != nil {
-			s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("❌ Failed to request song: %v", err)),
-			})
+			followUp.Finish(requestErrorMessage(i.GuildID, err))
+			return
 		}
+		c.audit.Record(i.GuildID, userID, audit.ActionPlay, selectedResult.URL)
 	}()
 
-	c.searchMutex.Lock()
-	delete(c.searchResults, searchKey)
-	c.searchMutex.Unlock()
-
 	return nil
 }
 
-func (c *SearchCommand) formatDuration(seconds int) string {
-	if seconds <= 0 {
-		return "Unknown"
+// respondExpired tells the user the results are gone. If the session's query
+// is still known, it offers a button to run the same search again.
+func (c *SearchCommand) respondExpired(s discordapi.Session, i *discordgo.InteractionCreate, searchKey string, canRetry bool) error {
+	edit := &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "search.expired")),
 	}
 
-	minutes := seconds / 60
-	secs := seconds % 60
-	return fmt.Sprintf("%d:%02d", minutes, secs)
+	if canRetry {
+		edit.Components = &[]discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    i18n.T(i.GuildID, "search.retry_button"),
+						CustomID: FormatSearchButtonID(SearchButton{Action: searchActionRetry, SearchKey: searchKey}),
+					},
+				},
+			},
+		}
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	return err
 }
 
-func (c *SearchCommand) cleanupSearchResults(searchKey string, after time.Duration) {
-	time.Sleep(after)
+// queueAll requests every listed result in order, editing the response as it
+// goes. Long runs move to a channel message once the token expires.
+func (c *SearchCommand) queueAll(s discordapi.Session, i *discordgo.InteractionCreate, results []socket.SearchResult, userID string) {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+
+	limits := musicManager.DownloadLimits(i.GuildID)
+
+	progress := newProgressReporter(s, i)
+
+	queued := 0
+	for idx, result := range results {
+		progress.Update(i18n.T(i.GuildID, "search.queue_all_progress", idx+1, len(results), result.Title, result.Uploader))
+
+		if pattern, blocked := c.blacklist.MatchURL(i.GuildID, result.URL); blocked {
+			logger.Info.Printf("Skipping blacklisted search result %s (matches %s)", result.URL, pattern)
+			continue
+		}
+		if err := music.CheckDuration(result.Duration, limits); err != nil {
+			logger.Info.Printf("Skipping search result over the duration limit: %s (%v)", result.URL, err)
+			continue
+		}
+
+		if err := musicManager.RequestSong(urlnorm.Normalize(result.URL), userID, limits, nil); err != nil {
+			var limitErr *music.LimitError
+			if errors.As(err, &limitErr) {
+				logger.Info.Printf("Stopping queue-all at the queue limit: %v", err)
+				break
+			}
+			var duplicateErr *music.DuplicateError
+			if errors.As(err, &duplicateErr) {
+				logger.Info.Printf("Skipping search result already in the queue: %v", err)
+				continue
+			}
+			logger.Error.Printf("Failed to request search result %s: %v", result.URL, err)
+			continue
+		}
+		c.audit.Record(i.GuildID, userID, audit.ActionPlay, result.URL)
+		queued++
+	}
 
-	c.searchMutex.Lock()
-	delete(c.searchResults, searchKey)
-	c.searchMutex.Unlock()
+	progress.Finish(i18n.T(i.GuildID, "search.queue_all_done", queued, len(results)))
 }
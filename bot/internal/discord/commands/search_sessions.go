@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"musicbot/internal/socket"
+	"sync"
+	"time"
+)
+
+const (
+	searchSessionTTL     = 15 * time.Minute
+	searchQueryRetention = 24 * time.Hour
+	searchSweepInterval  = time.Minute
+)
+
+// searchSession holds the results of one /search invocation, keyed by the
+// interaction that created it. After the results expire the query is kept a
+// while longer so stale buttons can offer to run the search again.
+type searchSession struct {
+	ownerID  string
+	query    string
+	platform string
+	results  []socket.SearchResult
+	created  time.Time
+}
+
+func (s searchSession) hasResults() bool {
+	return s.results != nil
+}
+
+type searchSessionStore struct {
+	sessions map[string]*searchSession
+	mu       sync.Mutex
+}
+
+func newSearchSessionStore() *searchSessionStore {
+	return &searchSessionStore{
+		sessions: make(map[string]*searchSession),
+	}
+}
+
+func (st *searchSessionStore) create(key, ownerID, query, platform string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.sessions[key] = &searchSession{
+		ownerID:  ownerID,
+		query:    query,
+		platform: platform,
+		created:  time.Now(),
+	}
+}
+
+func (st *searchSessionStore) setResults(key string, results []socket.SearchResult) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if session, ok := st.sessions[key]; ok {
+		session.results = results
+	}
+}
+
+func (st *searchSessionStore) get(key string) (searchSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.sessions[key]
+	if !ok {
+		return searchSession{}, false
+	}
+	return *session, true
+}
+
+// expire drops a session's results but keeps its query for "Search again".
+func (st *searchSessionStore) expire(key string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if session, ok := st.sessions[key]; ok {
+		session.results = nil
+	}
+}
+
+func (st *searchSessionStore) sweep(now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for key, session := range st.sessions {
+		age := now.Sub(session.created)
+		if age > searchQueryRetention {
+			delete(st.sessions, key)
+		} else if age > searchSessionTTL {
+			session.results = nil
+		}
+	}
+}
+
+func (st *searchSessionStore) run() {
+	ticker := time.NewTicker(searchSweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		st.sweep(now)
+	}
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"musicbot/internal/socket"
+	"testing"
+	"time"
+)
+
+func TestSearchSessionsAreKeptPerInteraction(t *testing.T) {
+	store := newSearchSessionStore()
+	store.create("first", "owner", "lofi", "soundcloud")
+	store.setResults("first", []socket.SearchResult{{Title: "lofi 1"}})
+	store.create("second", "owner", "jazz", "soundcloud")
+	store.setResults("second", []socket.SearchResult{{Title: "jazz 1"}})
+
+	for key, want := range map[string]string{"first": "lofi 1", "second": "jazz 1"} {
+		session, ok := store.get(key)
+		if !ok || len(session.results) != 1 || session.results[0].Title != want {
+			t.Errorf("session %s = %+v, want its own result %q", key, session, want)
+		}
+	}
+}
+
+func TestSearchSessionsExpire(t *testing.T) {
+	store := newSearchSessionStore()
+	store.create("key", "owner", "lofi", "soundcloud")
+	store.setResults("key", []socket.SearchResult{{Title: "lofi 1"}})
+	session, _ := store.get("key")
+	created := session.created
+
+	store.sweep(created.Add(searchSessionTTL - time.Minute))
+	if session, _ := store.get("key"); !session.hasResults() {
+		t.Fatal("results were dropped before the TTL")
+	}
+
+	store.sweep(created.Add(searchSessionTTL + time.Minute))
+	session, ok := store.get("key")
+	if !ok || session.hasResults() {
+		t.Fatalf("after the TTL the session is %+v (kept %v), want its results dropped", session, ok)
+	}
+	if session.query != "lofi" {
+		t.Errorf("query = %q, want it kept to search again", session.query)
+	}
+
+	store.sweep(created.Add(searchQueryRetention + time.Minute))
+	if _, ok := store.get("key"); ok {
+		t.Error("the session outlived the query retention")
+	}
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/socket/sockettest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// searchResults answers every search with three tracks.
+func searchResults(request sockettest.Request) []sockettest.Response {
+	results := make([]interface{}, 3)
+	for k := range results {
+		results[k] = map[string]interface{}{
+			"title":    fmt.Sprintf("Track %d", k+1),
+			"url":      fmt.Sprintf("https://soundcloud.com/artist/track-%d", k+1),
+			"duration": 180,
+			"uploader": "Artist",
+			"platform": "soundcloud",
+		}
+	}
+	return []sockettest.Response{sockettest.Success(request, map[string]interface{}{"results": results})}
+}
+
+// newSearchEnv is a guild where "owner" listens with the bot, and a search
+// command whose downloader finds three tracks and downloads any of them.
+func newSearchEnv(t *testing.T) (*testEnv, *SearchCommand) {
+	env := newTestEnv(t)
+	env.downloader.Handle("search", searchResults)
+	env.downloads(t)
+	env.session.joinVoice(t, "owner", testVoiceID)
+	env.guilds.Get(testGuildID).State.SetCurrentChannel(testVoiceID)
+	return env, NewSearchCommand(env.guilds, env.socket, env.db, env.blacklist, env.audit)
+}
+
+// search runs /search for owner and returns the buttons of its results.
+func search(t *testing.T, env *testEnv, cmd *SearchCommand) []discordgo.Button {
+	t.Helper()
+	i := commandInteraction("owner", "search", stringOption("query", "lofi"))
+	if err := cmd.Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	r := env.session.await(t, i, "search results", func(r response) bool {
+		return len(r.components) > 0
+	})
+	return buttons(r.components)
+}
+
+func TestSearchButtons(t *testing.T) {
+	env, cmd := newSearchEnv(t)
+	resultButtons := search(t, env, cmd)
+	if len(resultButtons) != 7 {
+		t.Fatalf("got %d buttons, want a select and a play-next for each of 3 results and queue-all", len(resultButtons))
+	}
+	second, all := resultButtons[1], resultButtons[6]
+
+	press := func(t *testing.T, userID, customID string) *discordgo.InteractionCreate {
+		t.Helper()
+		i := buttonInteraction(userID, customID)
+		if err := cmd.HandleComponent(env.session, i); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		return i
+	}
+
+	t.Run("someone else picks", func(t *testing.T) {
+		i := press(t, "stranger", second.CustomID)
+		if r := env.session.awaitContent(t, i, i18n.T(testGuildID, "common.not_your_buttons")); !r.ephemeral {
+			t.Error("the refusal isn't ephemeral")
+		}
+	})
+
+	t.Run("owner picks", func(t *testing.T) {
+		i := press(t, "owner", second.CustomID)
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "search.downloading", "Track 2", "Artist"))
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "play.queued", "track-2"))
+
+		requests := env.downloader.Requests("download_audio")
+		if len(requests) != 1 || requests[0].Params["url"] != "https://soundcloud.com/artist/track-2" {
+			t.Errorf("download requests = %v, want one for track-2", requests)
+		}
+	})
+
+	t.Run("results are used up", func(t *testing.T) {
+		i := press(t, "owner", all.CustomID)
+		r := env.session.awaitContent(t, i, i18n.T(testGuildID, "search.expired"))
+		retry := buttons(r.components)
+		if len(retry) != 1 {
+			t.Fatalf("got %d buttons, want one to search again", len(retry))
+		}
+
+		i = press(t, "owner", retry[0].CustomID)
+		env.session.await(t, i, "search results", func(r response) bool {
+			return len(r.components) > 0
+		})
+		if n := len(env.downloader.Requests("search")); n != 1 {
+			t.Errorf("downloader searched %d times, want the repeat answered from the cache", n)
+		}
+	})
+}
+
+func TestSearchNeedsVoice(t *testing.T) {
+	env, cmd := newSearchEnv(t)
+
+	i := commandInteraction("listener", "search", stringOption("query", "lofi"))
+	if err := cmd.Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	env.session.awaitContent(t, i, i18n.T(testGuildID, "common.not_in_voice"))
+	if n := len(env.downloader.Requests("search")); n != 0 {
+		t.Errorf("downloader searched %d times for a user who isn't in voice", n)
+	}
+}
+
+// queryResults answers a search with two tracks named after the query.
+func queryResults(request sockettest.Request) []sockettest.Response {
+	query, _ := request.Params["query"].(string)
+	results := make([]interface{}, 2)
+	for k := range results {
+		results[k] = map[string]interface{}{
+			"title":    fmt.Sprintf("%s %d", query, k+1),
+			"url":      fmt.Sprintf("https://soundcloud.com/artist/%s-%d", query, k+1),
+			"duration": 180,
+			"uploader": "Artist",
+			"platform": "soundcloud",
+		}
+	}
+	return []sockettest.Response{sockettest.Success(request, map[string]interface{}{"results": results})}
+}
+
+func TestSearchTwiceKeepsBothResults(t *testing.T) {
+	env, cmd := newSearchEnv(t)
+	env.downloader.Handle("search", queryResults)
+
+	var first []discordgo.Button
+	for _, query := range []string{"lofi", "jazz"} {
+		i := commandInteraction("owner", "search", stringOption("query", query))
+		if err := cmd.Execute(env.session, i); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		r := env.session.awaitContent(t, i, query+" 1")
+		if first == nil {
+			first = buttons(r.components)
+		}
+	}
+
+	i := buttonInteraction("owner", first[0].CustomID)
+	if err := cmd.HandleComponent(env.session, i); err != nil {
+		t.Fatalf("HandleComponent: %v", err)
+	}
+	env.session.awaitContent(t, i, i18n.T(testGuildID, "search.downloading", "lofi 1", "Artist"))
+}
+
+func TestSearchExpiredOffersToSearchAgain(t *testing.T) {
+	env, cmd := newSearchEnv(t)
+	env.downloader.Handle("search", queryResults)
+	all := search(t, env, cmd)[4]
+
+	cmd.sessions.sweep(time.Now().Add(searchSessionTTL + time.Minute))
+
+	i := buttonInteraction("owner", all.CustomID)
+	if err := cmd.HandleComponent(env.session, i); err != nil {
+		t.Fatalf("HandleComponent: %v", err)
+	}
+	retry := buttons(env.session.awaitContent(t, i, i18n.T(testGuildID, "search.expired")).components)
+	if len(retry) != 1 {
+		t.Fatalf("got %d buttons, want one to search again", len(retry))
+	}
+
+	i = buttonInteraction("owner", retry[0].CustomID)
+	if err := cmd.HandleComponent(env.session, i); err != nil {
+		t.Fatalf("HandleComponent: %v", err)
+	}
+	env.session.awaitContent(t, i, "lofi 1")
+}
+
+func TestSearchButtonsSurviveRestart(t *testing.T) {
+	env, cmd := newSearchEnv(t)
+	resultButtons := search(t, env, cmd)
+
+	// A restart loses every session kept in memory.
+	cmd.sessions = newSearchSessionStore()
+
+	t.Run("someone else picks", func(t *testing.T) {
+		i := buttonInteraction("stranger", resultButtons[1].CustomID)
+		if err := cmd.HandleComponent(env.session, i); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "common.not_your_buttons"))
+	})
+
+	t.Run("owner picks", func(t *testing.T) {
+		i := buttonInteraction("owner", resultButtons[1].CustomID)
+		if err := cmd.HandleComponent(env.session, i); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "search.downloading", "Track 2", "Artist"))
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "play.queued", "track-2"))
+	})
+
+	t.Run("queue all", func(t *testing.T) {
+		// Queue-all has no result of its own to persist.
+		i := buttonInteraction("owner", resultButtons[6].CustomID)
+		if err := cmd.HandleComponent(env.session, i); err != nil {
+			t.Fatalf("HandleComponent: %v", err)
+		}
+		env.session.awaitContent(t, i, i18n.T(testGuildID, "search.expired"))
+	})
+}
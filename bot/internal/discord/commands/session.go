@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/listening"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SessionCommand records a listening session: which tracks played, who was
+// in the voice channel and the reactions to the tracks. It is opt-in and
+// announced in the channel when it starts; no audio is recorded.
+type SessionCommand struct {
+	guilds    *guilds.Registry
+	listening *listening.Manager
+	audit     *audit.Log
+}
+
+func NewSessionCommand(guildRegistry *guilds.Registry, listeningManager *listening.Manager, auditLog *audit.Log) *SessionCommand {
+	return &SessionCommand{
+		guilds:    guildRegistry,
+		listening: listeningManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *SessionCommand) Name() string {
+	return "session"
+}
+
+func (c *SessionCommand) Description() string {
+	return "Record who listened to which tracks in a listening session"
+}
+
+func (c *SessionCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SessionCommand) Examples() []string {
+	return []string{
+		"/session start",
+		"/session stop",
+		"/session last",
+	}
+}
+
+func (c *SessionCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SessionCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "start",
+			Description: "Start recording a session in the bot's voice channel, posting the tracks here",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "stop",
+			Description: "Stop the session and show its summary",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "last",
+			Description: "Show the summary of the last session",
+		},
+	}
+}
+
+func (c *SessionCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "start":
+		return c.start(s, i)
+	case "stop":
+		return c.stop(s, i)
+	case "last":
+		return c.last(s, i)
+	}
+	return nil
+}
+
+// start begins a session and announces it, which is the listeners' notice
+// that they are being recorded.
+func (c *SessionCommand) start(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	voiceChannelID := c.guilds.Get(i.GuildID).State.GetCurrentChannel()
+	if voiceChannelID == "" {
+		return c.respond(s, i, i18n.T(i.GuildID, "session.not_connected"))
+	}
+
+	err := c.listening.Begin(i.GuildID, voiceChannelID, i.ChannelID, i.Member.User.ID)
+	if errors.Is(err, listening.ErrActive) {
+		return c.respond(s, i, i18n.T(i.GuildID, "session.already_running"))
+	}
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to start a listening session", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "session.failed"))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionSessionStart, voiceChannelID)
+	return c.respond(s, i, i18n.T(i.GuildID, "session.started", voiceChannelID, i.Member.User.ID))
+}
+
+func (c *SessionCommand) stop(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	summary, err := c.listening.End(i.GuildID)
+	if errors.Is(err, listening.ErrNotActive) {
+		return c.respond(s, i, i18n.T(i.GuildID, "session.not_running"))
+	}
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to stop the listening session", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "session.failed"))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionSessionStop, fmt.Sprintf("%d", len(summary.Tracks)))
+	return c.showSummary(s, i, summary)
+}
+
+func (c *SessionCommand) last(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	summary, err := c.listening.Last(i.GuildID)
+	if errors.Is(err, listening.ErrNoSession) {
+		return c.respond(s, i, i18n.T(i.GuildID, "session.none"))
+	}
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to load the last listening session", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "session.failed"))
+	}
+	return c.showSummary(s, i, summary)
+}
+
+// showSummary shows summary with its track list attached as a CSV file.
+func (c *SessionCommand) showSummary(s discordapi.Session, i *discordgo.InteractionCreate, summary listening.Summary) error {
+	edit := render.SessionSummary(i.GuildID, summary).Edit()
+	edit.Files = []*discordgo.File{
+		{
+			Name:        fmt.Sprintf("session-%s.csv", summary.Session.StartedAt.Format("2006-01-02-1504")),
+			ContentType: "text/csv",
+			Reader:      bytes.NewReader(summary.CSV()),
+		},
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, edit)
+	return err
+}
+
+func (c *SessionCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
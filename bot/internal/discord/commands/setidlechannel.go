@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"musicbot/internal/voice"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SetIdleChannelCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewSetIdleChannelCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager, auditLog *audit.Log) *SetIdleChannelCommand {
+	return &SetIdleChannelCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *SetIdleChannelCommand) Name() string {
+	return "setidlechannel"
+}
+
+func (c *SetIdleChannelCommand) Description() string {
+	return "Show or change the voice channel the bot idles in with the radio"
+}
+
+func (c *SetIdleChannelCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetIdleChannelCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetIdleChannelCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:         discordgo.ApplicationCommandOptionChannel,
+			Name:         "channel",
+			Description:  "Voice channel to idle in",
+			Required:     false,
+			ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+		},
+	}
+}
+
+func (c *SetIdleChannelCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		key, args := "setidlechannel.current_none", []interface{}{}
+		if current := guild.State.GetIdleChannel(); current != "" {
+			key, args = "setidlechannel.current", []interface{}{current}
+		}
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, key, args...)),
+		})
+		return err
+	}
+
+	channel := discordapi.ChannelOption(s, options[0])
+	if channel == nil || channel.Type != discordgo.ChannelTypeGuildVoice {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setidlechannel.not_voice")),
+		})
+		return err
+	}
+
+	if err := voice.CheckJoin(s, i.GuildID, channel.ID); err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(joinErrorMessage(i.GuildID, err)),
+		})
+		return err
+	}
+
+	if err := c.dbManager.SaveIdleChannel(i.GuildID, channel.ID); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save idle channel", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setidlechannel.save_failed")),
+		})
+		return err
+	}
+
+	// A bot idling in the old channel, or stuck without one, moves over.
+	// In 24/7 mode it stays in the 24/7 channel.
+	currentChannel := guild.State.GetCurrentChannel()
+	move := guild.State.GetAlwaysOnChannel() == "" && (currentChannel == "" ||
+		(guild.State.IsInIdleChannel() && guild.State.GetBotState() == state.StateIdle))
+
+	guild.State.SetIdleChannel(channel.ID)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionIdleChannel, channel.ID)
+
+	if move && currentChannel != channel.ID {
+		c.moveToIdle(guild)
+	}
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "setidlechannel.set", channel.ID)),
+	})
+	return err
+}
+
+// moveToIdle takes the bot to the new idle channel and restarts the radio.
+func (c *SetIdleChannelCommand) moveToIdle(guild *guilds.Guild) {
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	guild.Radio.Stop()
+
+	time.Sleep(500 * time.Millisecond)
+
+	if err := guild.Voice.ReturnToIdle(guild.ID); err != nil {
+		logger.ForCommand(guild.ID, c.Name()).Error("Failed to move to the new idle channel", "error", err)
+		return
+	}
+
+	guild.State.SetBotState(state.StateIdle)
+
+	time.Sleep(500 * time.Millisecond)
+
+	vc := guild.Voice.GetVoiceConnection()
+	if vc != nil && !guild.Radio.IsPlaying() {
+		guild.Radio.Start(vc)
+	}
+}
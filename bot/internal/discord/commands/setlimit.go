@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SetLimitCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+}
+
+func NewSetLimitCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) *SetLimitCommand {
+	return &SetLimitCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+	}
+}
+
+func (c *SetLimitCommand) Name() string {
+	return "setlimit"
+}
+
+func (c *SetLimitCommand) Description() string {
+	return "Show or change the queue size limits"
+}
+
+func (c *SetLimitCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetLimitCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetLimitCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "queue",
+			Description: "Maximum number of upcoming songs in the queue",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    1000,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "per_user",
+			Description: "Maximum number of songs one user may have queued",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    1000,
+		},
+	}
+}
+
+func (c *SetLimitCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	var err error
+	limits := c.guilds.QueueLimits(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setlimit.current", limits.MaxQueueLength, limits.MaxUserQueued)),
+		})
+		return err
+	}
+
+	for _, option := range options {
+		switch option.Name {
+		case "queue":
+			limits.MaxQueueLength = int(option.IntValue())
+		case "per_user":
+			limits.MaxUserQueued = int(option.IntValue())
+		}
+	}
+
+	if c.dbManager != nil {
+		err = c.dbManager.SaveGuildQueueLimits(i.GuildID, limits)
+		if err != nil {
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "setlimit.save_failed")),
+			})
+			return err
+		}
+	}
+
+	c.guilds.SetQueueLimits(i.GuildID, limits)
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "setlimit.set", limits.MaxQueueLength, limits.MaxUserQueued)),
+	})
+	return err
+}
+
+// requestErrorMessage turns an error from a song or playlist request into the
+// message shown to the user, explaining queue limits when one was hit.
+func requestErrorMessage(guildID string, err error) string {
+	if errors.Is(err, music.ErrPlaylistCancelled) {
+		return i18n.T(guildID, "playlist.cancelled")
+	}
+
+	if errors.Is(err, music.ErrPlaylistInProgress) {
+		return i18n.T(guildID, "playlist.in_progress")
+	}
+
+	if errors.Is(err, music.ErrDownloadCancelled) {
+		return i18n.T(guildID, "download.cancelled")
+	}
+
+	var duplicateErr *music.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		if duplicateErr.Position == 0 {
+			return i18n.T(guildID, "play.duplicate_playing", duplicateErr.Title)
+		}
+		return i18n.T(guildID, "play.duplicate", duplicateErr.Title, duplicateErr.Position)
+	}
+
+	var limitErr *music.LimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.PerUser {
+			return i18n.T(guildID, "limits.user_full", limitErr.Limit)
+		}
+		return i18n.T(guildID, "limits.queue_full", limitErr.Limit)
+	}
+
+	var failedErr *music.DownloadError
+	if errors.As(err, &failedErr) {
+		switch {
+		case failedErr.Temporary:
+			return i18n.T(guildID, "download.temporary", failedErr.Attempts)
+		case errors.Is(err, music.ErrPrivate):
+			return i18n.T(guildID, "download.private")
+		case errors.Is(err, music.ErrRegionBlocked):
+			return i18n.T(guildID, "download.region_blocked")
+		case errors.Is(err, music.ErrAgeRestricted):
+			return i18n.T(guildID, "download.age_restricted")
+		case failedErr.Unavailable:
+			return i18n.T(guildID, "download.unavailable")
+		}
+		return i18n.T(guildID, "download.failed", failedErr.Reason)
+	}
+
+	var downloadErr *music.DownloadLimitError
+	if errors.As(err, &downloadErr) {
+		limits := downloadErr.Limits
+
+		var content string
+		switch {
+		case downloadErr.TooLarge:
+			content = i18n.T(guildID, "limits.download_too_large", limits.MaxSizeMB)
+		case downloadErr.Duration > 0:
+			content = i18n.T(guildID, "limits.download_too_long_length", render.Duration(guildID, downloadErr.Duration), limits.MaxDurationSeconds/60)
+		default:
+			content = i18n.T(guildID, "limits.download_too_long", limits.MaxDurationSeconds/60)
+		}
+
+		// Requests made with allow_long are already at the ceiling.
+		if limits != limits.Extended() {
+			content += i18n.T(guildID, "limits.download_override")
+		}
+		return content
+	}
+
+	return i18n.T(guildID, "common.request_failed", err)
+}
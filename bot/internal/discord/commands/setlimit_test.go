@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/guilds"
+	"musicbot/internal/radio"
+	"musicbot/internal/state"
+	"testing"
+)
+
+func TestSetLimitOnlyChangesItsGuild(t *testing.T) {
+	env := newTestEnv(t)
+	const otherGuildID = "other"
+	defaults := env.guilds.DefaultQueueLimits()
+
+	cmd := NewSetLimitCommand(env.guilds, env.db)
+	i := commandInteraction("admin", "setlimit", intOption("queue", 5), intOption("per_user", 2))
+	if err := cmd.Execute(env.session, i); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	env.session.awaitContent(t, i, "**5** songs in total, **2** per user")
+
+	want := config.QueueLimits{MaxQueueLength: 5, MaxUserQueued: 2}
+	if got := env.guilds.Get(testGuildID).Music.GetQueueLimits(); got != want {
+		t.Errorf("limits of the guild that ran /setlimit = %+v, want %+v", got, want)
+	}
+	if got := env.guilds.Get(otherGuildID).Music.GetQueueLimits(); got != defaults {
+		t.Errorf("limits of another guild = %+v, want the defaults %+v", got, defaults)
+	}
+
+	// Changing the defaults leaves a guild's own limits alone.
+	env.guilds.SetDefaultQueueLimits(config.QueueLimits{MaxQueueLength: 50, MaxUserQueued: 10})
+	if got := env.guilds.QueueLimits(testGuildID); got != want {
+		t.Errorf("limits after the defaults changed = %+v, want %+v", got, want)
+	}
+	if got := env.guilds.Get(otherGuildID).Music.GetQueueLimits(); got.MaxQueueLength != 50 {
+		t.Errorf("limits of another guild after the defaults changed = %+v, want the new defaults", got)
+	}
+
+	// A restart loads each guild's limits back.
+	restarted := guilds.NewRegistry(env.session, state.NewManager(state.Config{}), radio.NewStreamManager(nil), env.db, env.socket)
+	if got := restarted.QueueLimits(testGuildID); got != want {
+		t.Errorf("limits after a restart = %+v, want %+v", got, want)
+	}
+	if got := restarted.QueueLimits(otherGuildID); got != defaults {
+		t.Errorf("limits of another guild after a restart = %+v, want %+v", got, defaults)
+	}
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SetMaxDurationCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+}
+
+func NewSetMaxDurationCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) *SetMaxDurationCommand {
+	return &SetMaxDurationCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+	}
+}
+
+func (c *SetMaxDurationCommand) Name() string {
+	return "setmaxduration"
+}
+
+func (c *SetMaxDurationCommand) Description() string {
+	return "Show or change the longest song that can be downloaded"
+}
+
+func (c *SetMaxDurationCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetMaxDurationCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetMaxDurationCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "minutes",
+			Description: "Maximum song length in minutes",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    240,
+		},
+	}
+}
+
+func (c *SetMaxDurationCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	limits := c.guilds.Get(i.GuildID).Music.DownloadLimits(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setmaxduration.current", limits.MaxDurationSeconds/60)),
+		})
+		return err
+	}
+
+	limits.MaxDurationSeconds = int(options[0].IntValue()) * 60
+
+	err := c.dbManager.SaveDownloadLimits(i.GuildID, limits)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setmaxduration.save_failed")),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "setmaxduration.set", limits.MaxDurationSeconds/60)),
+	})
+	return err
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SetMaxSizeCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+}
+
+func NewSetMaxSizeCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) *SetMaxSizeCommand {
+	return &SetMaxSizeCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+	}
+}
+
+func (c *SetMaxSizeCommand) Name() string {
+	return "setmaxsize"
+}
+
+func (c *SetMaxSizeCommand) Description() string {
+	return "Show or change the largest song that can be downloaded"
+}
+
+func (c *SetMaxSizeCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetMaxSizeCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetMaxSizeCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "megabytes",
+			Description: "Maximum song size in MB",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    500,
+		},
+	}
+}
+
+func (c *SetMaxSizeCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	limits := c.guilds.Get(i.GuildID).Music.DownloadLimits(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setmaxsize.current", limits.MaxSizeMB)),
+		})
+		return err
+	}
+
+	limits.MaxSizeMB = int(options[0].IntValue())
+
+	err := c.dbManager.SaveDownloadLimits(i.GuildID, limits)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setmaxsize.save_failed")),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "setmaxsize.set", limits.MaxSizeMB)),
+	})
+	return err
+}
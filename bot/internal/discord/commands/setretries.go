@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SetRetriesCommand struct {
+	guilds    *guilds.Registry
+	dbManager *config.DatabaseManager
+}
+
+func NewSetRetriesCommand(guildRegistry *guilds.Registry, dbManager *config.DatabaseManager) *SetRetriesCommand {
+	return &SetRetriesCommand{
+		guilds:    guildRegistry,
+		dbManager: dbManager,
+	}
+}
+
+func (c *SetRetriesCommand) Name() string {
+	return "setretries"
+}
+
+func (c *SetRetriesCommand) Description() string {
+	return "Show or change how often a failed download is tried"
+}
+
+func (c *SetRetriesCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetRetriesCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetRetriesCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "attempts",
+			Description: "Attempts per download (1 turns retries off)",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+			MaxValue:    5,
+		},
+	}
+}
+
+func (c *SetRetriesCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	limits := c.guilds.Get(i.GuildID).Music.DownloadLimits(i.GuildID)
+	options := i.ApplicationCommandData().Options
+
+	if len(options) == 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setretries.current", limits.MaxAttempts)),
+		})
+		return err
+	}
+
+	limits.MaxAttempts = int(options[0].IntValue())
+
+	err := c.dbManager.SaveDownloadLimits(i.GuildID, limits)
+	if err != nil {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "setretries.save_failed")),
+		})
+		return err
+	}
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "setretries.set", limits.MaxAttempts)),
+	})
+	return err
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SetRoleCommand picks the role that grants a permission level in a guild.
+// The role is stored by ID, so renaming it keeps it working; until a guild
+// picks one, roles are matched by the name in config.json. It backs both
+// /setdjrole and /setadminrole.
+type SetRoleCommand struct {
+	level             permissions.Level
+	permissionManager *permissions.Manager
+	dbManager         *config.DatabaseManager
+	audit             *audit.Log
+}
+
+func NewSetDJRoleCommand(permissionManager *permissions.Manager, dbManager *config.DatabaseManager, auditLog *audit.Log) *SetRoleCommand {
+	return &SetRoleCommand{
+		level:             permissions.LevelDJ,
+		permissionManager: permissionManager,
+		dbManager:         dbManager,
+		audit:             auditLog,
+	}
+}
+
+func NewSetAdminRoleCommand(permissionManager *permissions.Manager, dbManager *config.DatabaseManager, auditLog *audit.Log) *SetRoleCommand {
+	return &SetRoleCommand{
+		level:             permissions.LevelAdmin,
+		permissionManager: permissionManager,
+		dbManager:         dbManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *SetRoleCommand) Name() string {
+	if c.level == permissions.LevelAdmin {
+		return "setadminrole"
+	}
+	return "setdjrole"
+}
+
+func (c *SetRoleCommand) Description() string {
+	if c.level == permissions.LevelAdmin {
+		return "Show or change the role that may use admin commands"
+	}
+	return "Show or change the role that may control the music"
+}
+
+func (c *SetRoleCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SetRoleCommand) Examples() []string {
+	return []string{
+		"/" + c.Name(),
+		"/" + c.Name() + " role:@" + c.level.String(),
+		"/" + c.Name() + " reset:True",
+	}
+}
+
+func (c *SetRoleCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SetRoleCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionRole,
+			Name:        "role",
+			Description: "Role to use",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "reset",
+			Description: "Go back to matching the role by the name in the config",
+			Required:    false,
+		},
+	}
+}
+
+func (c *SetRoleCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	var role *discordgo.Role
+	reset := false
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "role":
+			role = discordapi.RoleOption(s, option, i.GuildID)
+		case "reset":
+			reset = option.BoolValue()
+		}
+	}
+
+	level := c.level.String()
+	if role == nil && !reset {
+		return c.respond(s, i, c.current(i.GuildID))
+	}
+
+	roleID := ""
+	if !reset {
+		// @everyone shares its ID with the guild.
+		if role.ID == i.GuildID {
+			return c.respond(s, i, i18n.T(i.GuildID, "setrole.everyone", level))
+		}
+		roleID = role.ID
+	}
+
+	if err := c.dbManager.SaveRoleID(i.GuildID, c.level, roleID); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save role", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "setrole.save_failed", level))
+	}
+	c.permissionManager.SetRoleID(i.GuildID, c.level, roleID)
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionRole, level+"="+roleID)
+
+	if reset {
+		return c.respond(s, i, i18n.T(i.GuildID, "setrole.reset", level, c.permissionManager.GetRequiredRoleName(i.GuildID, c.level)))
+	}
+	content := i18n.T(i.GuildID, "setrole.set", level, role.Name)
+	if c.level == permissions.LevelAdmin && !slices.Contains(i.Member.Roles, roleID) {
+		content += "\n" + i18n.T(i.GuildID, "setrole.admin_not_yours")
+	}
+	return c.respond(s, i, content)
+}
+
+func (c *SetRoleCommand) current(guildID string) string {
+	level := c.level.String()
+	if name, ok := c.permissionManager.RoleName(guildID, c.permissionManager.RoleIDs(guildID).Get(c.level)); ok {
+		return i18n.T(guildID, "setrole.current", level, name)
+	}
+	return i18n.T(guildID, "setrole.current_name", level, c.permissionManager.GetRequiredRoleName(guildID, c.level))
+}
+
+func (c *SetRoleCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ShardInfoCommand struct{}
+
+func NewShardInfoCommand() *ShardInfoCommand {
+	return &ShardInfoCommand{}
+}
+
+func (c *ShardInfoCommand) Name() string {
+	return "shardinfo"
+}
+
+func (c *ShardInfoCommand) Description() string {
+	return "Show which shard serves this server, its latency and how many servers it has"
+}
+
+func (c *ShardInfoCommand) Category() Category {
+	return CategoryUtility
+}
+
+// RespondsInstantly skips the deferral: the command only reads the session.
+func (c *ShardInfoCommand) RespondsInstantly() bool {
+	return true
+}
+
+func (c *ShardInfoCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{}
+}
+
+func (c *ShardInfoCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	shardID, shardCount := s.Shard()
+	count := max(shardCount, 1)
+	guildShard := config.ShardFor(i.GuildID, count)
+
+	cache := s.Cache()
+	cache.RLock()
+	guildCount := len(cache.Guilds)
+	cache.RUnlock()
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: i18n.T(i.GuildID, "shardinfo.info", guildShard+1, count, s.HeartbeatLatency().Milliseconds(), shardID+1, guildCount),
+		},
+	})
+}
@@ -1,21 +1,24 @@
 package commands
 
 import (
-	"musicbot/internal/music"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/state"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type SkipCommand struct {
-	musicManager *music.Manager
-	stateManager *state.Manager
+	guilds *guilds.Registry
+	audit  *audit.Log
 }
 
-func NewSkipCommand(musicManager *music.Manager, stateManager *state.Manager) *SkipCommand {
+func NewSkipCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *SkipCommand {
 	return &SkipCommand{
-		musicManager: musicManager,
-		stateManager: stateManager,
+		guilds: guildRegistry,
+		audit:  auditLog,
 	}
 }
 
@@ -27,52 +30,57 @@ func (c *SkipCommand) Description() string {
 	return "Skip the current song"
 }
 
+func (c *SkipCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *SkipCommand) ControlsMusic() bool {
+	return true
+}
+
 func (c *SkipCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{}
 }
 
-func (c *SkipCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
+func (c *SkipCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+	var err error
 
-	if c.stateManager.GetBotState() != state.StateDJ {
+	if guild.State.GetBotState() != state.StateDJ {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ Not currently playing music."),
+			Content: stringPtr(i18n.T(i.GuildID, "skip.not_playing")),
 		})
 		return err
 	}
 
-	currentSong := c.musicManager.GetCurrentSong()
+	currentSong := guild.Music.GetCurrentSong()
 	if currentSong == nil {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No song is currently playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.no_song_playing")),
 		})
 		return err
 	}
 
-	if !c.musicManager.IsPlaying() {
+	if !guild.Music.IsPlaying() {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("❌ No song is currently playing."),
+			Content: stringPtr(i18n.T(i.GuildID, "common.no_song_playing")),
 		})
 		return err
 	}
 
-	upcoming := c.musicManager.GetUpcoming(1)
+	upcoming := guild.Music.GetUpcoming(1)
 	if len(upcoming) == 0 {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("⏭️ Skipped current song. No more songs in queue."),
+			Content: stringPtr(i18n.T(i.GuildID, "skip.skipped_last")),
 		})
 	} else {
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr("⏭️ Skipped to next song."),
+			Content: stringPtr(i18n.T(i.GuildID, "skip.skipped")),
 		})
 	}
 
-	c.musicManager.Stop()
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionSkip, currentSong.Title)
+	guild.Music.Stop()
 
 	return err
 }
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type SkipToCommand struct {
+	guilds *guilds.Registry
+	audit  *audit.Log
+}
+
+func NewSkipToCommand(guildRegistry *guilds.Registry, auditLog *audit.Log) *SkipToCommand {
+	return &SkipToCommand{
+		guilds: guildRegistry,
+		audit:  auditLog,
+	}
+}
+
+func (c *SkipToCommand) Name() string {
+	return "skipto"
+}
+
+func (c *SkipToCommand) Description() string {
+	return "Skip ahead to a song further down the queue"
+}
+
+func (c *SkipToCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *SkipToCommand) Examples() []string {
+	return []string{
+		"/skipto position:4",
+		"/skipto position:4 keep_skipped:True",
+	}
+}
+
+func (c *SkipToCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *SkipToCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
+func (c *SkipToCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "position",
+			Description: "Position in the queue to skip to (1 is the next song)",
+			Required:    true,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "keep_skipped",
+			Description: "Move the skipped songs to the end of the queue instead of dropping them",
+			Required:    false,
+		},
+	}
+}
+
+func (c *SkipToCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	guild := c.guilds.Get(i.GuildID)
+
+	var position int
+	var keepSkipped bool
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "position":
+			position = int(option.IntValue())
+		case "keep_skipped":
+			keepSkipped = option.BoolValue()
+		}
+	}
+
+	if guild.State.GetBotState() != state.StateDJ {
+		return c.respond(s, i, i18n.T(i.GuildID, "skip.not_playing"))
+	}
+
+	if guild.Music.GetCurrentSong() == nil {
+		return c.respond(s, i, i18n.T(i.GuildID, "common.no_song_playing"))
+	}
+
+	song, err := guild.Music.SkipTo(position, keepSkipped)
+	if errors.Is(err, music.ErrOutOfRange) {
+		upcoming := len(guild.Music.GetUpcomingItems())
+		if upcoming == 0 {
+			return c.respond(s, i, i18n.T(i.GuildID, "skipto.nothing_queued"))
+		}
+		return c.respond(s, i, i18n.T(i.GuildID, "skipto.out_of_range", position, upcoming))
+	}
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to skip ahead", "error", err, "position", position)
+		return c.respond(s, i, i18n.T(i.GuildID, "skipto.failed"))
+	}
+
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionSkip, fmt.Sprintf("to %d: %s", position, song.Title))
+
+	key := "skipto.skipped"
+	if keepSkipped {
+		key = "skipto.skipped_kept"
+	}
+	return c.respond(s, i, i18n.T(i.GuildID, key, song.Title, song.Artist, position-1))
+}
+
+func (c *SkipToCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
@@ -0,0 +1,316 @@
+package commands
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/deps"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/janitor"
+	"musicbot/internal/permissions"
+	"musicbot/internal/socket"
+	"musicbot/internal/state"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const statusRefreshID = "status_refresh"
+
+type StatusCommand struct {
+	guilds            *guilds.Registry
+	socketClient      *socket.Client
+	dbManager         *config.DatabaseManager
+	janitor           *janitor.Janitor
+	deps              *deps.Checker
+	permissionManager *permissions.Manager
+	startedAt         time.Time
+}
+
+func NewStatusCommand(guildRegistry *guilds.Registry, socketClient *socket.Client, dbManager *config.DatabaseManager, cacheJanitor *janitor.Janitor, depChecker *deps.Checker, permissionManager *permissions.Manager) *StatusCommand {
+	return &StatusCommand{
+		guilds:            guildRegistry,
+		socketClient:      socketClient,
+		dbManager:         dbManager,
+		janitor:           cacheJanitor,
+		deps:              depChecker,
+		permissionManager: permissionManager,
+		startedAt:         time.Now(),
+	}
+}
+
+func (c *StatusCommand) Name() string {
+	return "status"
+}
+
+func (c *StatusCommand) Description() string {
+	return "Show downloader, player and runtime status"
+}
+
+func (c *StatusCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *StatusCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *StatusCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "refresh",
+			Description: "Check ffmpeg, the socket directory and the download directory again first",
+			Required:    false,
+		},
+	}
+}
+
+func (c *StatusCommand) Examples() []string {
+	return []string{"/status", "/status refresh:true"}
+}
+
+func (c *StatusCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) > 0 && options[0].BoolValue() && c.deps != nil {
+		c.deps.Run(context.Background())
+	}
+
+	embeds := []*discordgo.MessageEmbed{c.buildEmbed(i.GuildID)}
+	components := c.buildComponents(i.GuildID)
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	return err
+}
+
+func (c *StatusCommand) ComponentPrefix() string {
+	return statusRefreshID
+}
+
+// HandleComponent re-renders the status embed in place. The router doesn't
+// check permissions for components, so the admin check is repeated here.
+func (c *StatusCommand) HandleComponent(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	if i.Member == nil || i.Member.User == nil {
+		return nil
+	}
+
+	allowed, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, c.RequiredLevel())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, c.RequiredLevel())
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Content: i18n.T(i.GuildID, "permissions.denied", roleName, c.Name()),
+			},
+		})
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{c.buildEmbed(i.GuildID)},
+			Components: c.buildComponents(i.GuildID),
+		},
+	})
+}
+
+func (c *StatusCommand) buildComponents(guildID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    i18n.T(guildID, "status.refresh"),
+					CustomID: statusRefreshID,
+				},
+			},
+		},
+	}
+}
+
+func (c *StatusCommand) buildEmbed(guildID string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title: i18n.T(guildID, "status.title"),
+		Color: 0x5865F2,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(guildID, "status.downloader"), Value: c.downloaderField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.pending"), Value: i18n.T(guildID, "status.pending_value", c.guilds.Get(guildID).Music.GetPendingDownloads()), Inline: true},
+			{Name: i18n.T(guildID, "status.mode"), Value: c.modeField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.dj_only"), Value: c.djOnlyField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.always_on"), Value: c.alwaysOnField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.roles"), Value: c.rolesField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.player"), Value: c.playerField(guildID), Inline: false},
+			{Name: i18n.T(guildID, "status.radio"), Value: c.radioField(guildID), Inline: false},
+			{Name: i18n.T(guildID, "status.database"), Value: c.databaseField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.search_cache"), Value: c.searchCacheField(guildID), Inline: true},
+			{Name: i18n.T(guildID, "status.janitor"), Value: c.janitorField(guildID), Inline: false},
+			{Name: i18n.T(guildID, "status.dependencies"), Value: c.dependenciesField(guildID), Inline: false},
+			{Name: i18n.T(guildID, "status.uptime"), Value: formatUptime(time.Since(c.startedAt)), Inline: true},
+			{Name: i18n.T(guildID, "status.runtime"), Value: c.runtimeField(guildID), Inline: true},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+func (c *StatusCommand) downloaderField(guildID string) string {
+	if c.socketClient == nil {
+		return i18n.T(guildID, "status.downloader_disabled")
+	}
+
+	lastPong := i18n.T(guildID, "status.never")
+	if last := c.socketClient.LastDownloaderPing(); !last.IsZero() {
+		lastPong = i18n.T(guildID, "status.ago", formatUptime(time.Since(last)))
+	}
+
+	return i18n.T(guildID, "status.downloader_value", c.socketClient.GetDownloaderStatus(), lastPong)
+}
+
+func (c *StatusCommand) modeField(guildID string) string {
+	switch c.guilds.Get(guildID).State.GetBotState() {
+	case state.StateDJ:
+		return i18n.T(guildID, "status.mode_dj")
+	case state.StateRadio:
+		return i18n.T(guildID, "status.mode_radio")
+	case state.StateIdle:
+		return i18n.T(guildID, "status.mode_idle")
+	default:
+		return i18n.T(guildID, "status.mode_transitioning")
+	}
+}
+
+func (c *StatusCommand) djOnlyField(guildID string) string {
+	if !c.permissionManager.DJOnly(guildID) {
+		return i18n.T(guildID, "status.dj_only_off")
+	}
+	return i18n.T(guildID, "status.dj_only_on", c.permissionManager.GetRequiredRoleName(guildID, permissions.LevelDJ))
+}
+
+// rolesField shows the DJ and admin roles picked by ID, or that a level is
+// still matched by name.
+func (c *StatusCommand) rolesField(guildID string) string {
+	roleIDs := c.permissionManager.RoleIDs(guildID)
+	lines := make([]string, 0, 2)
+	for _, level := range []permissions.Level{permissions.LevelDJ, permissions.LevelAdmin} {
+		role, ok := c.permissionManager.RoleName(guildID, roleIDs.Get(level))
+		if !ok {
+			role = i18n.T(guildID, "status.role_not_configured", c.permissionManager.GetRequiredRoleName(guildID, level))
+		}
+		lines = append(lines, i18n.T(guildID, "status.role_line", level, role))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *StatusCommand) alwaysOnField(guildID string) string {
+	channelID := c.guilds.Get(guildID).State.GetAlwaysOnChannel()
+	if channelID == "" {
+		return i18n.T(guildID, "status.always_on_off")
+	}
+	return i18n.T(guildID, "status.always_on_on", channelID)
+}
+
+func (c *StatusCommand) playerField(guildID string) string {
+	musicManager := c.guilds.Get(guildID).Music
+	playerState := i18n.T(guildID, "status.player_stopped")
+	if musicManager.IsPaused() {
+		playerState = i18n.T(guildID, "status.player_paused")
+	} else if musicManager.IsPlaying() {
+		playerState = i18n.T(guildID, "status.player_playing")
+	}
+
+	song := musicManager.GetCurrentSong()
+	if song == nil {
+		return playerState
+	}
+	return i18n.T(guildID, "status.player_track", playerState, song.Title, song.Artist)
+}
+
+func (c *StatusCommand) radioField(guildID string) string {
+	guild := c.guilds.Get(guildID)
+	stream := guild.State.GetRadioStream()
+	underruns := i18n.T(guildID, "status.radio_underruns", guild.Radio.Underruns())
+	if guild.Radio.IsPlaying() {
+		return i18n.T(guildID, "status.radio_playing", stream) + underruns
+	}
+	return i18n.T(guildID, "status.radio_stopped", stream) + underruns
+}
+
+func (c *StatusCommand) databaseField(guildID string) string {
+	count, err := c.dbManager.CountSongs()
+	if err != nil {
+		return i18n.T(guildID, "status.database_error", c.dbManager.Path())
+	}
+	return i18n.T(guildID, "status.database_value", c.dbManager.Path(), count)
+}
+
+func (c *StatusCommand) searchCacheField(guildID string) string {
+	if c.socketClient == nil {
+		return i18n.T(guildID, "status.downloader_disabled")
+	}
+	stats := c.socketClient.SearchCacheStats()
+	return i18n.T(guildID, "status.search_cache_value", stats.Hits, stats.Misses, stats.Entries)
+}
+
+func (c *StatusCommand) janitorField(guildID string) string {
+	if c.janitor == nil {
+		return i18n.T(guildID, "status.janitor_disabled")
+	}
+
+	summary, ok := c.janitor.LastSummary()
+	if !ok {
+		return i18n.T(guildID, "status.janitor_pending")
+	}
+
+	ago := i18n.T(guildID, "status.ago", formatUptime(time.Since(summary.StartedAt)))
+	if summary.Err != nil {
+		return i18n.T(guildID, "status.janitor_failed", ago, summary.Err)
+	}
+
+	removed := summary.OrphanedEntries + summary.OrphanedFiles + summary.ExpiredSongs + summary.EvictedSongs
+	return i18n.T(guildID, "status.janitor_value", ago, removed,
+		janitor.FormatBytes(summary.FreedBytes), janitor.FormatBytes(summary.CacheBytes))
+}
+
+func (c *StatusCommand) dependenciesField(guildID string) string {
+	if c.deps == nil {
+		return i18n.T(guildID, "status.dependencies_skipped")
+	}
+
+	report, ok := c.deps.Last()
+	if !ok {
+		return i18n.T(guildID, "status.dependencies_pending")
+	}
+
+	ago := i18n.T(guildID, "status.ago", formatUptime(time.Since(report.CheckedAt)))
+	if report.OK() {
+		return i18n.T(guildID, "status.dependencies_ok", ago)
+	}
+
+	lines := []string{i18n.T(guildID, "status.dependencies_failed", ago)}
+	for _, name := range deps.Names {
+		if err := report.Results[name]; err != nil {
+			lines = append(lines, i18n.T(guildID, "status.dependency_line", err))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *StatusCommand) runtimeField(guildID string) string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	heapMiB := float64(mem.HeapAlloc) / (1024 * 1024)
+	return i18n.T(guildID, "status.runtime_value", runtime.NumGoroutine(), heapMiB)
+}
+
+func formatUptime(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// StyleCommand picks how the queue, now playing, search results and
+// history look in the guild.
+type StyleCommand struct {
+	dbManager *config.DatabaseManager
+}
+
+func NewStyleCommand(dbManager *config.DatabaseManager) *StyleCommand {
+	return &StyleCommand{
+		dbManager: dbManager,
+	}
+}
+
+func (c *StyleCommand) Name() string {
+	return "style"
+}
+
+func (c *StyleCommand) Description() string {
+	return "Show or change how the bot's listings look in this server"
+}
+
+func (c *StyleCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *StyleCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *StyleCommand) Options() []*discordgo.ApplicationCommandOption {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(render.Styles))
+	for _, style := range render.Styles {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(style), Value: string(style)})
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "style",
+			Description: "Rich embeds, or plain text that works well with screen readers",
+			Required:    false,
+			Choices:     choices,
+		},
+	}
+}
+
+func (c *StyleCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return c.respond(s, i, i18n.T(i.GuildID, "style.current", render.GuildStyle(i.GuildID)))
+	}
+
+	style, ok := render.ParseStyle(options[0].StringValue())
+	if !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "style.unknown"))
+	}
+
+	if err := c.dbManager.SaveStyle(i.GuildID, string(style)); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to save style", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "style.save_failed"))
+	}
+
+	render.SetGuildStyle(i.GuildID, style)
+	return c.respond(s, i, i18n.T(i.GuildID, "style.set", style))
+}
+
+func (c *StyleCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
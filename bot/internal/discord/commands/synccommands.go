@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SyncCommandsCommand runs the startup command sync by hand, for when a
+// command is missing or out of date in the client.
+type SyncCommandsCommand struct {
+	sync func(force bool) (ChangeSummary, error)
+}
+
+func NewSyncCommandsCommand(sync func(force bool) (ChangeSummary, error)) *SyncCommandsCommand {
+	return &SyncCommandsCommand{
+		sync: sync,
+	}
+}
+
+func (c *SyncCommandsCommand) Name() string {
+	return "synccommands"
+}
+
+func (c *SyncCommandsCommand) Description() string {
+	return "Update the slash commands Discord shows to match the bot"
+}
+
+func (c *SyncCommandsCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *SyncCommandsCommand) Examples() []string {
+	return []string{
+		"/synccommands",
+		"/synccommands force:True",
+	}
+}
+
+func (c *SyncCommandsCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *SyncCommandsCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "force",
+			Description: "Push every command again, even unchanged ones",
+			Required:    false,
+		},
+	}
+}
+
+func (c *SyncCommandsCommand) Ephemeral() bool {
+	return true
+}
+
+func (c *SyncCommandsCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	force := false
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		force = options[0].BoolValue()
+	}
+
+	summary, err := c.sync(force)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Command sync failed", "error", err)
+		return c.respond(s, i, i18n.T(i.GuildID, "synccommands.failed", err))
+	}
+
+	content := i18n.T(i.GuildID, "synccommands.done",
+		len(summary.ToCreate), len(summary.ToUpdate), len(summary.ToDelete), summary.Unchanged)
+	if summary.Failed > 0 {
+		content += "\n" + i18n.T(i.GuildID, "synccommands.some_failed", summary.Failed)
+	}
+	return c.respond(s, i, content)
+}
+
+func (c *SyncCommandsCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/state"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// thumbnailCheckTimeout bounds the HEAD request that checks whether a
+	// thumbnail URL still loads.
+	thumbnailCheckTimeout = 2 * time.Second
+
+	// thumbnailTTL is how long the result of a check is reused.
+	thumbnailTTL = time.Hour
+
+	// thumbnailCacheSize bounds the cache; past it expired entries are
+	// dropped.
+	thumbnailCacheSize = 256
+
+	// maxThumbnailFile is the largest local thumbnail that is attached.
+	maxThumbnailFile = 2 << 20
+)
+
+type thumbnailEntry struct {
+	alive   bool
+	checked time.Time
+}
+
+// thumbnailCache picks the image a song embed shows. Thumbnail URLs of old
+// downloads often stop working, since YouTube rotates its image URLs, so
+// each URL is checked with a HEAD request before it is used. A dead one is
+// replaced by the thumbnail the downloader saved next to the song, attached
+// to the message, or left out rather than shown broken.
+type thumbnailCache struct {
+	client  *http.Client
+	entries map[string]thumbnailEntry
+	mu      sync.Mutex
+}
+
+func newThumbnailCache() *thumbnailCache {
+	return &thumbnailCache{
+		client:  &http.Client{Timeout: thumbnailCheckTimeout},
+		entries: make(map[string]thumbnailEntry),
+	}
+}
+
+// Thumbnail returns the image to show for song: its thumbnail URL while
+// that loads, otherwise an attachment:// URL with the local file to attach,
+// or "" and nil when there is neither.
+func (c *thumbnailCache) Thumbnail(song *state.Song, downloadDir string) (string, *discordgo.File) {
+	if song.ThumbnailURL != "" && c.alive(song.ThumbnailURL) {
+		return song.ThumbnailURL, nil
+	}
+
+	file := localThumbnail(song, downloadDir)
+	if file == nil {
+		return "", nil
+	}
+	return "attachment://" + file.Name, file
+}
+
+// alive reports whether rawURL answered a HEAD request with a success.
+// Failed checks are cached too, so a dead URL isn't tried on every render.
+func (c *thumbnailCache) alive(rawURL string) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[rawURL]
+	c.mu.Unlock()
+	if ok && time.Since(entry.checked) < thumbnailTTL {
+		return entry.alive
+	}
+
+	alive := c.check(rawURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= thumbnailCacheSize {
+		for k, e := range c.entries {
+			if time.Since(e.checked) >= thumbnailTTL {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < thumbnailCacheSize {
+		c.entries[rawURL] = thumbnailEntry{alive: alive, checked: time.Now()}
+	}
+	return alive
+}
+
+func (c *thumbnailCache) check(rawURL string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), thumbnailCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// localThumbnail reads the thumbnail saved next to song, or returns nil when
+// there is none.
+func localThumbnail(song *state.Song, downloadDir string) *discordgo.File {
+	if song.ThumbnailPath == "" {
+		return nil
+	}
+
+	path := config.ResolveTrackPath(downloadDir, song.ThumbnailPath)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxThumbnailFile {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	return &discordgo.File{
+		Name:        "thumbnail" + filepath.Ext(path),
+		ContentType: "image/jpeg",
+		Reader:      bytes.NewReader(data),
+	}
+}
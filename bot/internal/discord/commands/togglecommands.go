@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"musicbot/internal/audit"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandsCommand turns commands off and back on in a guild, for servers
+// that don't use some features. The router refuses disabled commands.
+type CommandsCommand struct {
+	router    *Router
+	dbManager *config.DatabaseManager
+	audit     *audit.Log
+}
+
+func NewCommandsCommand(router *Router, dbManager *config.DatabaseManager, auditLog *audit.Log) *CommandsCommand {
+	return &CommandsCommand{
+		router:    router,
+		dbManager: dbManager,
+		audit:     auditLog,
+	}
+}
+
+func (c *CommandsCommand) Name() string {
+	return "commands"
+}
+
+func (c *CommandsCommand) Description() string {
+	return "Turn commands off or back on in this server"
+}
+
+func (c *CommandsCommand) Category() Category {
+	return CategoryAdmin
+}
+
+func (c *CommandsCommand) Examples() []string {
+	return []string{
+		"/commands disable name:playlist",
+		"/commands enable name:playlist",
+		"/commands list",
+	}
+}
+
+func (c *CommandsCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelAdmin
+}
+
+func (c *CommandsCommand) Options() []*discordgo.ApplicationCommandOption {
+	name := func(description string) []*discordgo.ApplicationCommandOption {
+		return []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: description,
+				Required:    true,
+				MaxLength:   32,
+			},
+		}
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "disable",
+			Description: "Turn a command off in this server",
+			Options:     name("Command to turn off, e.g. playlist"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "enable",
+			Description: "Turn a disabled command back on",
+			Options:     name("Command to turn back on"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List the commands turned off in this server",
+		},
+	}
+}
+
+func (c *CommandsCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	subcommand := i.ApplicationCommandData().Options[0]
+	if subcommand.Name == "list" {
+		return c.respond(s, i, c.list(i.GuildID))
+	}
+
+	name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(subcommand.Options[0].StringValue())), "/")
+	if _, ok := c.router.Command(name); !ok {
+		return c.respond(s, i, i18n.T(i.GuildID, "commands.unknown", name))
+	}
+
+	userID := i.Member.User.ID
+	switch subcommand.Name {
+	case "disable":
+		if !CanDisable(name) {
+			return c.respond(s, i, i18n.T(i.GuildID, "commands.protected", name))
+		}
+		if c.router.CommandDisabled(i.GuildID, name) {
+			return c.respond(s, i, i18n.T(i.GuildID, "commands.already_disabled", name))
+		}
+		if err := c.dbManager.DisableCommand(i.GuildID, name, userID); err != nil {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to disable command", "command", name, "error", err)
+			return c.respond(s, i, i18n.T(i.GuildID, "commands.save_failed"))
+		}
+		c.router.SetCommandDisabled(i.GuildID, name, true)
+		c.audit.Record(i.GuildID, userID, audit.ActionCommandDisable, name)
+		return c.respond(s, i, i18n.T(i.GuildID, "commands.disabled", name))
+	case "enable":
+		enabled, err := c.dbManager.EnableCommand(i.GuildID, name)
+		if err != nil {
+			logger.ForCommand(i.GuildID, c.Name()).Error("Failed to enable command", "command", name, "error", err)
+			return c.respond(s, i, i18n.T(i.GuildID, "commands.save_failed"))
+		}
+		c.router.SetCommandDisabled(i.GuildID, name, false)
+		if !enabled {
+			return c.respond(s, i, i18n.T(i.GuildID, "commands.not_disabled", name))
+		}
+		c.audit.Record(i.GuildID, userID, audit.ActionCommandEnable, name)
+		return c.respond(s, i, i18n.T(i.GuildID, "commands.enabled", name))
+	}
+	return nil
+}
+
+func (c *CommandsCommand) list(guildID string) string {
+	names := c.router.DisabledCommands(guildID)
+	if len(names) == 0 {
+		return i18n.T(guildID, "commands.list_none")
+	}
+	for idx, name := range names {
+		names[idx] = "`/" + name + "`"
+	}
+	return i18n.T(guildID, "commands.list", strings.Join(names, ", "))
+}
+
+func (c *CommandsCommand) respond(s discordapi.Session, i *discordgo.InteractionCreate, content string) error {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+	return err
+}
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"fmt"
+	"musicbot/internal/audit"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type TrimCommand struct {
+	guilds            *guilds.Registry
+	permissionManager *permissions.Manager
+	audit             *audit.Log
+}
+
+func NewTrimCommand(guildRegistry *guilds.Registry, permissionManager *permissions.Manager, auditLog *audit.Log) *TrimCommand {
+	return &TrimCommand{
+		guilds:            guildRegistry,
+		permissionManager: permissionManager,
+		audit:             auditLog,
+	}
+}
+
+func (c *TrimCommand) Name() string {
+	return "trim"
+}
+
+func (c *TrimCommand) Description() string {
+	return "Skip the intro or outro of a queued song"
+}
+
+func (c *TrimCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *TrimCommand) Examples() []string {
+	return []string{
+		"/trim start:0:45",
+		"/trim start:0:10 end:3:50 position:2",
+	}
+}
+
+func (c *TrimCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *TrimCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "start",
+			Description: "Where the song starts, e.g. 0:45 (0 plays from the beginning)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "end",
+			Description: "Where the song stops, e.g. 3:50 (0 plays to the end)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "position",
+			Description: "Position in the queue (defaults to your latest queued song)",
+			Required:    false,
+			MinValue:    func() *float64 { v := 1.0; return &v }(),
+		},
+	}
+}
+
+func (c *TrimCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	musicManager := c.guilds.Get(i.GuildID).Music
+	userID := i.Member.User.ID
+
+	var startText, endText string
+	position := 0
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "start":
+			startText = option.StringValue()
+		case "end":
+			endText = option.StringValue()
+		case "position":
+			position = int(option.IntValue())
+		}
+	}
+
+	if startText == "" && endText == "" {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "trim.nothing")),
+		})
+		return err
+	}
+
+	items := musicManager.GetUpcomingItems()
+
+	index := -1
+	if position > 0 {
+		if position > len(items) {
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "trim.not_found", position)),
+			})
+			return err
+		}
+		index = position - 1
+	} else {
+		for k := len(items) - 1; k >= 0; k-- {
+			if items[k].RequestedBy == userID {
+				index = k
+				break
+			}
+		}
+		if index < 0 {
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "trim.no_song")),
+			})
+			return err
+		}
+	}
+
+	item := items[index]
+	song := item.Song
+
+	if item.RequestedBy != userID && !c.isDJ(s, i) {
+		roleName := c.permissionManager.GetRequiredRoleName(i.GuildID, permissions.LevelDJ)
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "trim.not_yours", roleName)),
+		})
+		return err
+	}
+
+	if song == nil || song.IsStream || song.Duration <= 0 {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "trim.no_duration")),
+		})
+		return err
+	}
+
+	start, end := song.StartOffset, song.EndOffset
+	for _, field := range []struct {
+		text  string
+		value *int
+	}{{startText, &start}, {endText, &end}} {
+		if field.text == "" {
+			continue
+		}
+		seconds, ok := parseTimestamp(field.text)
+		if !ok {
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: stringPtr(i18n.T(i.GuildID, "trim.invalid_time", field.text)),
+			})
+			return err
+		}
+		*field.value = seconds
+	}
+
+	// An end at the very end of the song is the same as no end.
+	if end == song.Duration {
+		end = 0
+	}
+
+	if start >= song.Duration || end > song.Duration || (end > 0 && start >= end) {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "trim.out_of_range", formatTimestamp(song.Duration))),
+		})
+		return err
+	}
+
+	if err := musicManager.TrimUpcoming(index, item.SongID, start, end); err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Failed to trim song", "error", err)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: stringPtr(i18n.T(i.GuildID, "trim.failed")),
+		})
+		return err
+	}
+
+	trimmed := *song
+	trimmed.StartOffset, trimmed.EndOffset = start, end
+	stop := end
+	if stop == 0 {
+		stop = song.Duration
+	}
+
+	c.audit.Record(i.GuildID, userID, audit.ActionTrim,
+		fmt.Sprintf("%s (%s-%s)", song.Title, formatTimestamp(start), formatTimestamp(stop)))
+
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(i18n.T(i.GuildID, "trim.trimmed",
+			song.Title, formatTimestamp(start), formatTimestamp(stop), formatTimestamp(trimmed.PlayLength()))),
+	})
+	return err
+}
+
+// isDJ reports whether the user may trim songs other people queued.
+func (c *TrimCommand) isDJ(s discordapi.Session, i *discordgo.InteractionCreate) bool {
+	isDJ, err := c.permissionManager.HasPermission(s, i.GuildID, i.Member.User.ID, permissions.LevelDJ)
+	if err != nil {
+		logger.ForCommand(i.GuildID, c.Name()).Error("Permission check for trim failed", "error", err)
+		return false
+	}
+	return isDJ
+}
+
+// parseTimestamp reads seconds, m:ss or h:mm:ss into a number of seconds.
+func parseTimestamp(text string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	seconds := 0
+	for k, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 || (k > 0 && value >= 60) {
+			return 0, false
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, true
+}
+
+// formatTimestamp formats seconds as m:ss, or h:mm:ss from an hour up.
+func formatTimestamp(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
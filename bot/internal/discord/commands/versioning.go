@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"musicbot/internal/logger"
 
@@ -99,13 +100,7 @@ func (v *Versioning) saveRegistry() error {
 }
 
 func (v *Versioning) calculateCommandHash(cmd Command) (string, error) {
-	commandDef := &discordgo.ApplicationCommand{
-		Name:        cmd.Name(),
-		Description: cmd.Description(),
-		Options:     cmd.Options(),
-	}
-
-	data, err := json.Marshal(commandDef)
+	data, err := json.Marshal(commandDefinition(cmd))
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal command: %w", err)
 	}
@@ -114,20 +109,6 @@ func (v *Versioning) calculateCommandHash(cmd Command) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
-func (v *Versioning) HasCommandChanged(cmd Command) (bool, error) {
-	currentHash, err := v.calculateCommandHash(cmd)
-	if err != nil {
-		return true, err
-	}
-
-	cmdName := cmd.Name()
-	if storedInfo, exists := v.registry.Commands[cmdName]; exists {
-		return storedInfo.Hash != currentHash, nil
-	}
-
-	return true, nil
-}
-
 func (v *Versioning) UpdateCommandHash(cmd Command, commandID string) error {
 	hash, err := v.calculateCommandHash(cmd)
 	if err != nil {
@@ -153,7 +134,10 @@ func (v *Versioning) GetStoredCommandID(cmdName string) string {
 	return ""
 }
 
-func (v *Versioning) GetChangeSummary(commands []Command, existingCommands []*discordgo.ApplicationCommand) ChangeSummary {
+// GetChangeSummary compares commands with the ones Discord has, option by
+// option, so a lost or stale hash file never causes a needless update. With
+// force every existing command is updated.
+func (v *Versioning) GetChangeSummary(commands []Command, existingCommands []*discordgo.ApplicationCommand, force bool) ChangeSummary {
 	summary := ChangeSummary{
 		ToCreate: make([]Command, 0),
 		ToUpdate: make(map[string]Command),
@@ -176,16 +160,15 @@ func (v *Versioning) GetChangeSummary(commands []Command, existingCommands []*di
 
 		if existingCmd, exists := existingCmds[cmdName]; exists {
 			// Command exists, check if it changed
-			changed, err := v.HasCommandChanged(cmd)
-			if err != nil {
-				logger.Error.Printf("Error checking if command %s changed: %v", cmdName, err)
-				// If we can't determine if it changed, update it to be safe
-				summary.ToUpdate[existingCmd.ID] = cmd
-			} else if changed {
+			if force || !sameCommand(commandDefinition(cmd), existingCmd) {
 				logger.Debug.Printf("Command %s has changes", cmdName)
 				summary.ToUpdate[existingCmd.ID] = cmd
 			} else {
 				logger.Debug.Printf("Command %s is unchanged", cmdName)
+				summary.Unchanged++
+				if err := v.UpdateCommandHash(cmd, existingCmd.ID); err != nil {
+					logger.Error.Printf("Failed to store hash for command %s: %v", cmdName, err)
+				}
 			}
 		} else {
 			// New command
@@ -211,16 +194,31 @@ func (v *Versioning) Save() error {
 	return v.saveRegistry()
 }
 
+// ChangeSummary is what a sync has to do. Unchanged counts the commands
+// left alone and Failed the changes Discord refused.
 type ChangeSummary struct {
-	ToCreate []Command
-	ToUpdate map[string]Command
-	ToDelete []string
+	ToCreate  []Command
+	ToUpdate  map[string]Command
+	ToDelete  []string
+	Unchanged int
+	Failed    int
 }
 
 func (cs ChangeSummary) HasChanges() bool {
 	return len(cs.ToCreate) > 0 || len(cs.ToUpdate) > 0 || len(cs.ToDelete) > 0
 }
 
+// String sums the changes up for the log, e.g. "2 updated, 1 removed, 9
+// unchanged".
+func (cs ChangeSummary) String() string {
+	summary := fmt.Sprintf("%d created, %d updated, %d removed, %d unchanged",
+		len(cs.ToCreate), len(cs.ToUpdate), len(cs.ToDelete), cs.Unchanged)
+	if cs.Failed > 0 {
+		summary += fmt.Sprintf(", %d failed", cs.Failed)
+	}
+	return summary
+}
+
 func (cs ChangeSummary) LogSummary() {
 	if !cs.HasChanges() {
 		logger.Info.Println("No command changes detected")
@@ -246,3 +244,76 @@ func (cs ChangeSummary) LogSummary() {
 		logger.Info.Printf("  - Deleting %d commands", len(cs.ToDelete))
 	}
 }
+
+// commandDefinition is what Discord is told about cmd. Every command works
+// on a guild, so none is offered in DMs. Commands needing a Discord
+// permission carry it as their default member permissions; commands gated by
+// a bot role can't be expressed that way, so the router checks the role when
+// they run.
+func commandDefinition(cmd Command) *discordgo.ApplicationCommand {
+	def := &discordgo.ApplicationCommand{
+		Name:         cmd.Name(),
+		Description:  cmd.Description(),
+		Options:      cmd.Options(),
+		DMPermission: new(bool),
+	}
+
+	if pc, ok := cmd.(DiscordPermissionCommand); ok {
+		perms := pc.RequiredPermissions()
+		def.DefaultMemberPermissions = &perms
+	}
+
+	return def
+}
+
+// sameCommand reports whether the command Discord has matches def in
+// everything the bot sets: the description, the permissions and the options,
+// down to their choices, bounds and subcommands.
+func sameCommand(def, existing *discordgo.ApplicationCommand) bool {
+	return def.Description == existing.Description &&
+		samePointer(def.DefaultMemberPermissions, existing.DefaultMemberPermissions) &&
+		dmAllowed(def) == dmAllowed(existing) &&
+		sameOptions(def.Options, existing.Options)
+}
+
+// dmAllowed is cmd's DM permission, which Discord treats as true when unset.
+func dmAllowed(cmd *discordgo.ApplicationCommand) bool {
+	return cmd.DMPermission == nil || *cmd.DMPermission
+}
+
+func sameOptions(a, b []*discordgo.ApplicationCommandOption) bool {
+	return slices.EqualFunc(a, b, sameOption)
+}
+
+func sameOption(a, b *discordgo.ApplicationCommandOption) bool {
+	return a.Type == b.Type &&
+		a.Name == b.Name &&
+		a.Description == b.Description &&
+		a.Required == b.Required &&
+		a.Autocomplete == b.Autocomplete &&
+		samePointer(a.MinValue, b.MinValue) &&
+		a.MaxValue == b.MaxValue &&
+		samePointer(a.MinLength, b.MinLength) &&
+		a.MaxLength == b.MaxLength &&
+		slices.Equal(a.ChannelTypes, b.ChannelTypes) &&
+		slices.EqualFunc(a.Choices, b.Choices, sameChoice) &&
+		sameOptions(a.Options, b.Options)
+}
+
+// sameChoice compares choice values as JSON, since the values Discord sends
+// back are float64 where the bot's are often int.
+func sameChoice(a, b *discordgo.ApplicationCommandOptionChoice) bool {
+	if a.Name != b.Name {
+		return false
+	}
+	valueA, errA := json.Marshal(a.Value)
+	valueB, errB := json.Marshal(b.Value)
+	return errA == nil && errB == nil && string(valueA) == string(valueB)
+}
+
+func samePointer[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"encoding/json"
+	"musicbot/internal/discordapi"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// stubCommand is a command that is only ever registered, never run.
+type stubCommand struct {
+	name        string
+	description string
+	options     []*discordgo.ApplicationCommandOption
+}
+
+func (c *stubCommand) Name() string        { return c.name }
+func (c *stubCommand) Description() string { return c.description }
+func (c *stubCommand) Options() []*discordgo.ApplicationCommandOption {
+	return c.options
+}
+func (c *stubCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	return nil
+}
+
+// sampleOptions has a bit of every option structure the bot registers:
+// subcommands, a subcommand group, choices, bounds and channel types.
+func sampleOptions() []*discordgo.ApplicationCommandOption {
+	minCount, minLength := 1.0, 3
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Add songs",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "url", Description: "Song URL", Required: true, MinLength: &minLength, MaxLength: 200},
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "count", Description: "How many", MinValue: &minCount, MaxValue: 50},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "mode",
+			Description: "Set the mode",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "mode",
+					Description: "Mode",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Off", Value: 0},
+						{Name: "On", Value: 1},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+			Name:        "channel",
+			Description: "Channel settings",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "set",
+					Description: "Set the channel",
+					Options: []*discordgo.ApplicationCommandOption{
+						{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Channel", ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// fromDiscord is def as Discord sends it back: through JSON, so choice
+// values come back as float64, and with an ID.
+func fromDiscord(t *testing.T, def *discordgo.ApplicationCommand) *discordgo.ApplicationCommand {
+	t.Helper()
+	data, err := json.Marshal(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var existing discordgo.ApplicationCommand
+	if err := json.Unmarshal(data, &existing); err != nil {
+		t.Fatal(err)
+	}
+	existing.ID = "id-" + def.Name
+	return &existing
+}
+
+func TestSameCommand(t *testing.T) {
+	cmd := &stubCommand{name: "sample", description: "A sample", options: sampleOptions()}
+
+	tests := []struct {
+		name   string
+		change func(existing *discordgo.ApplicationCommand)
+		same   bool
+	}{
+		{"unchanged", func(*discordgo.ApplicationCommand) {}, true},
+		{"allowed in DMs", func(e *discordgo.ApplicationCommand) {
+			allowed := true
+			e.DMPermission = &allowed
+		}, false},
+		{"DM permission unset", func(e *discordgo.ApplicationCommand) {
+			e.DMPermission = nil
+		}, false},
+		{"description", func(e *discordgo.ApplicationCommand) {
+			e.Description = "An old sample"
+		}, false},
+		{"choice renamed", func(e *discordgo.ApplicationCommand) {
+			e.Options[1].Options[0].Choices[1].Name = "Enabled"
+		}, false},
+		{"choice value", func(e *discordgo.ApplicationCommand) {
+			e.Options[1].Options[0].Choices[1].Value = 2
+		}, false},
+		{"choice removed", func(e *discordgo.ApplicationCommand) {
+			e.Options[1].Options[0].Choices = e.Options[1].Options[0].Choices[:1]
+		}, false},
+		{"min value", func(e *discordgo.ApplicationCommand) {
+			minCount := 0.0
+			e.Options[0].Options[1].MinValue = &minCount
+		}, false},
+		{"min value removed", func(e *discordgo.ApplicationCommand) {
+			e.Options[0].Options[1].MinValue = nil
+		}, false},
+		{"max value", func(e *discordgo.ApplicationCommand) {
+			e.Options[0].Options[1].MaxValue = 25
+		}, false},
+		{"min length", func(e *discordgo.ApplicationCommand) {
+			minLength := 1
+			e.Options[0].Options[0].MinLength = &minLength
+		}, false},
+		{"required", func(e *discordgo.ApplicationCommand) {
+			e.Options[0].Options[0].Required = false
+		}, false},
+		{"subcommand removed", func(e *discordgo.ApplicationCommand) {
+			e.Options = e.Options[:2]
+		}, false},
+		{"subcommand renamed", func(e *discordgo.ApplicationCommand) {
+			e.Options[0].Name = "append"
+		}, false},
+		{"options reordered", func(e *discordgo.ApplicationCommand) {
+			e.Options[0], e.Options[1] = e.Options[1], e.Options[0]
+		}, false},
+		{"channel types in a group", func(e *discordgo.ApplicationCommand) {
+			e.Options[2].Options[0].Options[0].ChannelTypes = []discordgo.ChannelType{discordgo.ChannelTypeGuildStageVoice}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := commandDefinition(cmd)
+			existing := fromDiscord(t, def)
+			tt.change(existing)
+			if got := sameCommand(def, existing); got != tt.same {
+				t.Errorf("sameCommand = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestCommandDefinition(t *testing.T) {
+	manageMessages := int64(discordgo.PermissionManageMessages)
+	tests := []struct {
+		name  string
+		cmd   Command
+		perms *int64
+	}{
+		{"user command", NewPlayCommand(nil, nil, nil, nil), nil},
+		{"read-only command", NewQueueCommand(nil), nil},
+		{"DJ command", NewPlaylistCommand(nil, nil, nil), nil},
+		{"admin command", NewConfigCommand(nil, nil, nil, nil, nil), nil},
+		{"Discord permission", NewDelMsgCommand(nil), &manageMessages},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := commandDefinition(tt.cmd)
+			if def.Name != tt.cmd.Name() || def.Description != tt.cmd.Description() {
+				t.Errorf("definition = %q %q, want the command's name and description", def.Name, def.Description)
+			}
+			// Commands read i.Member, which a DM doesn't have.
+			if def.DMPermission == nil || *def.DMPermission {
+				t.Errorf("DMPermission = %v, want false", def.DMPermission)
+			}
+			if !samePointer(def.DefaultMemberPermissions, tt.perms) {
+				t.Errorf("DefaultMemberPermissions = %v, want %v", def.DefaultMemberPermissions, tt.perms)
+			}
+
+			// Discord's copy, with its ID, matches the definition.
+			if !sameCommand(def, fromDiscord(t, def)) {
+				t.Error("the definition doesn't match itself after a round trip through JSON")
+			}
+		})
+	}
+}
+
+func TestGetChangeSummary(t *testing.T) {
+	unchanged := &stubCommand{name: "queue", description: "Show the queue", options: sampleOptions()}
+	changed := &stubCommand{name: "play", description: "Play a song", options: sampleOptions()}
+	created := &stubCommand{name: "style", description: "Pick a style"}
+
+	oldPlay := commandDefinition(changed)
+	oldPlay.Options = sampleOptions()[:1]
+	existing := []*discordgo.ApplicationCommand{
+		fromDiscord(t, commandDefinition(unchanged)),
+		fromDiscord(t, oldPlay),
+		fromDiscord(t, &discordgo.ApplicationCommand{Name: "removed", Description: "Gone"}),
+	}
+	commands := []Command{unchanged, changed, created}
+
+	v := NewVersioning(filepath.Join(t.TempDir(), CommandHashFile))
+	summary := v.GetChangeSummary(commands, existing, false)
+	if len(summary.ToCreate) != 1 || summary.ToCreate[0] != created {
+		t.Errorf("ToCreate = %v, want style", summary.ToCreate)
+	}
+	if len(summary.ToUpdate) != 1 || summary.ToUpdate["id-play"] != changed {
+		t.Errorf("ToUpdate = %v, want play", summary.ToUpdate)
+	}
+	if !slices.Equal(summary.ToDelete, []string{"id-removed"}) {
+		t.Errorf("ToDelete = %v, want removed", summary.ToDelete)
+	}
+	if want := "1 created, 1 updated, 1 removed, 1 unchanged"; summary.String() != want {
+		t.Errorf("summary = %q, want %q", summary.String(), want)
+	}
+
+	forced := v.GetChangeSummary(commands, existing, true)
+	if len(forced.ToUpdate) != 2 || forced.Unchanged != 0 {
+		t.Errorf("forced sync updates %d and leaves %d unchanged, want every existing command updated", len(forced.ToUpdate), forced.Unchanged)
+	}
+}
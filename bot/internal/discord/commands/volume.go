@@ -1,9 +1,13 @@
 package commands
 
 import (
-	"fmt"
+	"musicbot/internal/audit"
 	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/i18n"
+	"musicbot/internal/permissions"
 	"musicbot/internal/state"
+	"strconv"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -11,12 +15,14 @@ import (
 type VolumeCommand struct {
 	stateManager *state.Manager
 	dbManager    *config.DatabaseManager
+	audit        *audit.Log
 }
 
-func NewVolumeCommand(stateManager *state.Manager, dbManager *config.DatabaseManager) *VolumeCommand {
+func NewVolumeCommand(stateManager *state.Manager, dbManager *config.DatabaseManager, auditLog *audit.Log) *VolumeCommand {
 	return &VolumeCommand{
 		stateManager: stateManager,
 		dbManager:    dbManager,
+		audit:        auditLog,
 	}
 }
 
@@ -28,6 +34,24 @@ func (c *VolumeCommand) Description() string {
 	return "Set the playback volume"
 }
 
+func (c *VolumeCommand) Category() Category {
+	return CategoryMusic
+}
+
+func (c *VolumeCommand) Examples() []string {
+	return []string{
+		"/volume level:50",
+	}
+}
+
+func (c *VolumeCommand) ControlsMusic() bool {
+	return true
+}
+
+func (c *VolumeCommand) RequiredLevel() permissions.Level {
+	return permissions.LevelDJ
+}
+
 func (c *VolumeCommand) Options() []*discordgo.ApplicationCommandOption {
 	return []*discordgo.ApplicationCommandOption{
 		{
@@ -41,14 +65,8 @@ func (c *VolumeCommand) Options() []*discordgo.ApplicationCommandOption {
 	}
 }
 
-func (c *VolumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) error {
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		return err
-	}
-
+func (c *VolumeCommand) Execute(s discordapi.Session, i *discordgo.InteractionCreate) error {
+	var err error
 	options := i.ApplicationCommandData().Options
 
 	if len(options) == 0 {
@@ -56,7 +74,7 @@ func (c *VolumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 		percentage := int(currentVolume * 1000)
 
 		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-			Content: stringPtr(fmt.Sprintf("🔊 Current volume: %d%%", percentage)),
+			Content: stringPtr(i18n.T(i.GuildID, "volume.current", percentage)),
 		})
 		return err
 	}
@@ -76,14 +94,16 @@ func (c *VolumeCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCr
 		err = c.dbManager.SaveVolume(volumeFloat)
 		if err != nil {
 			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-				Content: stringPtr(fmt.Sprintf("🔊 Volume set to %d%% but failed to save to database.", level)),
+				Content: stringPtr(i18n.T(i.GuildID, "volume.save_failed", level)),
 			})
 			return err
 		}
 	}
 
+	c.audit.Record(i.GuildID, i.Member.User.ID, audit.ActionVolume, strconv.Itoa(level))
+
 	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: stringPtr(fmt.Sprintf("🔊 Volume set to %d%%", level)),
+		Content: stringPtr(i18n.T(i.GuildID, "volume.set", level)),
 	})
 	return err
 }
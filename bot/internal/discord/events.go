@@ -1,37 +1,91 @@
 package discord
 
 import (
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/guilds"
+	"musicbot/internal/i18n"
 	"musicbot/internal/logger"
-	"musicbot/internal/music"
-	"musicbot/internal/radio"
+	"musicbot/internal/metrics"
+	"musicbot/internal/permissions"
 	"musicbot/internal/state"
 	"musicbot/internal/voice"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type EventHandler struct {
-	session      *discordgo.Session
-	voiceManager *voice.Manager
-	radioManager *radio.Manager
-	musicManager *music.Manager
-	stateManager *state.Manager
+	session           *discordgo.Session
+	guilds            *guilds.Registry
+	stateManager      *state.Manager
+	permissionManager *permissions.Manager
+	dbManager         *config.DatabaseManager
+	followTimers      map[string]*time.Timer
+	followMu          sync.Mutex
+	readySeen         atomic.Bool
 }
 
-func NewEventHandler(session *discordgo.Session, voiceManager *voice.Manager, radioManager *radio.Manager, musicManager *music.Manager, stateManager *state.Manager) *EventHandler {
+func NewEventHandler(session *discordgo.Session, guildRegistry *guilds.Registry, stateManager *state.Manager, permissionManager *permissions.Manager, dbManager *config.DatabaseManager) *EventHandler {
 	return &EventHandler{
-		session:      session,
-		voiceManager: voiceManager,
-		radioManager: radioManager,
-		musicManager: musicManager,
-		stateManager: stateManager,
+		session:           session,
+		guilds:            guildRegistry,
+		stateManager:      stateManager,
+		permissionManager: permissionManager,
+		dbManager:         dbManager,
+		followTimers:      make(map[string]*time.Timer),
 	}
 }
 
 func (e *EventHandler) HandleReady(s *discordgo.Session, r *discordgo.Ready) {
 	logger.Info.Printf("Bot ready as %s", r.User.Username)
-	s.UpdateGameStatus(0, "Radio Mode | /play for music")
+
+	// Ready only comes again after the gateway reconnected with a new
+	// session, which voice connections don't always survive.
+	if e.readySeen.Swap(true) {
+		go e.restoreAlwaysOn()
+	}
+}
+
+// HandleGuildRoleDelete forgets a deleted DJ or admin role picked by ID and
+// tells the guild, and turns DJ-only mode off when the DJ role is gone,
+// since nobody but admins could control the music otherwise. The state cache
+// has already dropped the role, so the check is whether a DJ role is left.
+func (e *EventHandler) HandleGuildRoleDelete(s *discordgo.Session, r *discordgo.GuildRoleDelete) {
+	roleIDs := e.permissionManager.RoleIDs(r.GuildID)
+	for _, level := range []permissions.Level{permissions.LevelDJ, permissions.LevelAdmin} {
+		if roleIDs.Get(level) != r.RoleID {
+			continue
+		}
+		e.permissionManager.SetRoleID(r.GuildID, level, "")
+		if err := e.dbManager.SaveRoleID(r.GuildID, level, ""); err != nil {
+			logger.Error.Printf("Failed to forget the deleted %s role of guild %s: %v", level, r.GuildID, err)
+		}
+		logger.Info.Printf("%s role deleted in guild %s, matching by name again", level, r.GuildID)
+		e.announce(e.guilds.Get(r.GuildID), "roles.deleted", level.String(), e.permissionManager.GetRequiredRoleName(r.GuildID, level))
+	}
+
+	if !e.permissionManager.DJOnly(r.GuildID) {
+		return
+	}
+
+	guild, err := s.State.Guild(r.GuildID)
+	if err != nil {
+		logger.Error.Printf("Failed to look up guild %s after role delete: %v", r.GuildID, err)
+		return
+	}
+
+	if e.permissionManager.HasDJRole(guild) {
+		return
+	}
+
+	e.permissionManager.SetDJOnly(r.GuildID, false)
+	if err := e.dbManager.SaveDJOnly(r.GuildID, false); err != nil {
+		logger.Error.Printf("Failed to save DJ-only mode for guild %s: %v", r.GuildID, err)
+	}
+	logger.Info.Printf("DJ role deleted in guild %s, DJ-only mode turned off", r.GuildID)
 }
 
 func (e *EventHandler) HandleVoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
@@ -40,66 +94,54 @@ func (e *EventHandler) HandleVoiceStateUpdate(s *discordgo.Session, v *discordgo
 		return
 	}
 
+	guild := e.guilds.Get(v.GuildID)
 	botID := s.State.User.ID
 
 	if v.UserID == botID {
-		e.handleBotVoiceUpdate(v)
+		e.handleBotVoiceUpdate(guild, v)
 		return
 	}
 
-	e.handleUserVoiceUpdate(v)
+	e.handleUserVoiceUpdate(guild, v)
+}
+
+func (e *EventHandler) updateVoiceMetrics() {
+	metrics.SetVoiceConnections(e.guilds.ConnectedCount())
 }
 
-func (e *EventHandler) handleBotVoiceUpdate(v *discordgo.VoiceStateUpdate) {
+func (e *EventHandler) handleBotVoiceUpdate(guild *guilds.Guild, v *discordgo.VoiceStateUpdate) {
 	if v.ChannelID == "" {
-		logger.Info.Println("Bot disconnected from voice")
+		logger.Info.Printf("Bot disconnected from voice in guild %s", guild.ID)
 
-		if e.stateManager.IsShuttingDown() {
-			logger.Debug.Println("Bot disconnect expected during shutdown")
-			e.voiceManager.HandleDisconnect(v.GuildID)
+		expected := guild.Voice.HandleDisconnect(v.GuildID)
+		e.updateVoiceMetrics()
+		if expected {
 			return
 		}
 
-		e.musicManager.Stop()
-		e.radioManager.Stop()
-
-		go func() {
-			time.Sleep(500 * time.Millisecond)
-
-			if err := e.voiceManager.HandleDisconnect(v.GuildID); err != nil {
-				logger.Error.Printf("Failed to handle disconnect: %v", err)
-				return
-			}
+		position, resume := guild.Music.Suspend()
+		guild.Radio.Stop()
 
-			if !e.stateManager.IsShuttingDown() {
-				time.Sleep(500 * time.Millisecond)
-				e.stateManager.SetBotState(state.StateIdle)
-
-				time.Sleep(500 * time.Millisecond)
-				vc := e.voiceManager.GetVoiceConnection()
-				if vc != nil && !e.radioManager.IsPlaying() {
-					e.radioManager.Start(vc)
-				}
-			}
-		}()
+		go e.recoverVoice(guild, resume, position)
 		return
 	}
 
-	e.stateManager.SetCurrentChannel(v.ChannelID)
+	guild.State.SetCurrentChannel(v.ChannelID)
+	e.updateVoiceMetrics()
 
-	currentState := e.stateManager.GetBotState()
-	if e.stateManager.IsInIdleChannel() {
+	currentState := guild.State.GetBotState()
+	if guild.State.IsInIdleChannel() {
 		if currentState == state.StateDJ {
-			e.musicManager.Stop()
+			guild.Music.Stop()
 		}
-		e.stateManager.SetBotState(state.StateIdle)
+		guild.State.SetBotState(state.StateIdle)
 	} else {
 		switch currentState {
 		case state.StateIdle:
-			if e.musicManager.IsPlaying() {
-				e.stateManager.SetBotState(state.StateDJ)
+			if guild.Music.IsPlaying() {
+				guild.State.SetBotState(state.StateDJ)
 			} else {
-				e.stateManager.SetBotState(state.StateRadio)
+				guild.State.SetBotState(state.StateRadio)
 			}
 		case state.StateRadio:
 		case state.StateDJ:
@@ -107,8 +149,80 @@ func (e *EventHandler) handleBotVoiceUpdate(v *discordgo.VoiceStateUpdate) {
 	}
 }
 
-func (e *EventHandler) handleUserVoiceUpdate(v *discordgo.VoiceStateUpdate) {
-	currentChannel := e.stateManager.GetCurrentChannel()
+// recoverVoice rejoins the channel Discord dropped the bot from and picks up
+// where playback left off. If the bot can't get back in, the guild's announce
+// channel is told once.
+func (e *EventHandler) recoverVoice(guild *guilds.Guild, resume bool, position time.Duration) {
+	time.Sleep(500 * time.Millisecond)
+
+	err := guild.Voice.Reconnect(guild.ID)
+	e.updateVoiceMetrics()
+	if errors.Is(err, voice.ErrReconnectAborted) {
+		logger.Info.Printf("Voice recovery in guild %s cancelled", guild.ID)
+		return
+	}
+	if err != nil {
+		logger.Error.Printf("Failed to recover voice in guild %s: %v", guild.ID, err)
+		if guild.State.GetAlwaysOnChannel() != "" {
+			e.rejoinAlwaysOn(guild)
+			return
+		}
+		e.announce(guild, "voice.reconnect_failed")
+		return
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	e.resumePlayback(guild, resume, position)
+}
+
+// resumePlayback picks up a song suspended at position after the bot changed
+// voice connections, or starts the radio if there is nothing to resume.
+func (e *EventHandler) resumePlayback(guild *guilds.Guild, resume bool, position time.Duration) {
+	vc := guild.Voice.GetVoiceConnection()
+	if vc == nil || e.stateManager.IsShuttingDown() {
+		return
+	}
+
+	if resume {
+		err := guild.Music.ResumeAt(vc, position)
+		if err == nil {
+			return
+		}
+		logger.Error.Printf("Failed to resume music in guild %s: %v", guild.ID, err)
+		if guild.State.IsInIdleChannel() {
+			guild.State.SetBotState(state.StateIdle)
+		} else {
+			guild.State.SetBotState(state.StateRadio)
+		}
+	}
+
+	if guild.State.GetBotState() != state.StateDJ && !guild.Radio.IsPlaying() {
+		guild.Radio.Start(vc)
+	}
+}
+
+// announce posts a notice to the guild's announce channel, if it has one.
+func (e *EventHandler) announce(guild *guilds.Guild, key string, args ...interface{}) {
+	channelID := guild.State.GetAnnounceChannel()
+	if channelID == "" {
+		return
+	}
+
+	if _, err := e.session.ChannelMessageSend(channelID, i18n.T(guild.ID, key, args...)); err != nil {
+		logger.Error.Printf("Failed to send announcement to channel %s: %v", channelID, err)
+	}
+}
+
+func (e *EventHandler) handleUserVoiceUpdate(guild *guilds.Guild, v *discordgo.VoiceStateUpdate) {
+	if followed := guild.State.GetFollowedUser(); followed != "" && v.UserID == followed {
+		// A followed DJ moving on is followed rather than treated as
+		// leaving the bot behind.
+		if e.followUser(guild, v) {
+			return
+		}
+	}
+
+	currentChannel := guild.State.GetCurrentChannel()
 	if currentChannel == "" || v.ChannelID == currentChannel {
 		return
 	}
@@ -116,7 +230,7 @@ func (e *EventHandler) handleUserVoiceUpdate(v *discordgo.VoiceStateUpdate) {
 	if v.BeforeUpdate != nil && v.BeforeUpdate.ChannelID == currentChannel {
 		go func() {
 			if !e.stateManager.IsShuttingDown() {
-				if err := e.handleUserLeft(v.GuildID, currentChannel); err != nil {
+				if err := e.handleUserLeft(guild, currentChannel); err != nil {
 					logger.Error.Printf("Failed to handle user left: %v", err)
 				}
 			}
@@ -124,36 +238,41 @@ func (e *EventHandler) handleUserVoiceUpdate(v *discordgo.VoiceStateUpdate) {
 	}
 }
 
-func (e *EventHandler) handleUserLeft(guildID, channelID string) error {
+func (e *EventHandler) handleUserLeft(guild *guilds.Guild, channelID string) error {
 	if e.stateManager.IsShuttingDown() {
 		logger.Debug.Println("Ignoring user left event during shutdown")
 		return nil
 	}
 
-	if e.stateManager.IsInIdleChannel() {
+	if guild.State.GetAlwaysOnChannel() != "" && guild.State.IsHome() {
+		logger.Info.Println("24/7 mode is on, staying in the channel")
+		return nil
+	}
+
+	if guild.State.IsInIdleChannel() {
 		logger.Info.Println("Already in idle channel, no action needed for voice operations")
 
-		e.stateManager.SetManualOperationActive(true)
-		defer e.stateManager.SetManualOperationActive(false)
+		guild.State.SetManualOperationActive(true)
+		defer guild.State.SetManualOperationActive(false)
 
-		e.musicManager.ExecuteWithDisabledHandlers(func() {
-			currentState := e.stateManager.GetBotState()
+		guild.Music.ExecuteWithDisabledHandlers(func() {
+			currentState := guild.State.GetBotState()
 			if currentState == state.StateDJ {
-				e.musicManager.Stop()
+				guild.Music.Stop()
 				time.Sleep(500 * time.Millisecond)
-				e.stateManager.SetBotState(state.StateIdle)
+				guild.State.SetBotState(state.StateIdle)
 
 				time.Sleep(500 * time.Millisecond)
-				vc := e.voiceManager.GetVoiceConnection()
-				if vc != nil && !e.radioManager.IsPlaying() {
-					e.radioManager.Start(vc)
+				vc := guild.Voice.GetVoiceConnection()
+				if vc != nil && !guild.Radio.IsPlaying() {
+					guild.Radio.Start(vc)
 				}
 			}
 		})
 		return nil
 	}
 
-	userCount, err := e.voiceManager.GetConnection().CheckChannelUsers(guildID, channelID)
+	userCount, err := guild.Voice.GetConnection().CheckChannelUsers(guild.ID, channelID)
 	if err != nil {
 		logger.Error.Printf("Error checking channel users: %v", err)
 		return err
@@ -164,30 +283,30 @@ func (e *EventHandler) handleUserLeft(guildID, channelID string) error {
 	if userCount == 0 {
 		logger.Info.Println("Channel is empty, stopping music and returning to idle")
 
-		e.stateManager.SetManualOperationActive(true)
-		defer e.stateManager.SetManualOperationActive(false)
+		guild.State.SetManualOperationActive(true)
+		defer guild.State.SetManualOperationActive(false)
 
-		e.musicManager.ExecuteWithDisabledHandlers(func() {
-			currentState := e.stateManager.GetBotState()
+		guild.Music.ExecuteWithDisabledHandlers(func() {
+			currentState := guild.State.GetBotState()
 			if currentState == state.StateDJ {
-				e.musicManager.Stop()
+				guild.Music.Stop()
 			}
 
-			e.radioManager.Stop()
+			guild.Radio.Stop()
 
 			time.Sleep(500 * time.Millisecond)
 
-			err = e.voiceManager.ReturnToIdle(guildID)
+			err = guild.Voice.ReturnToIdle(guild.ID)
 			if err != nil {
 				return
 			}
 
-			e.stateManager.SetBotState(state.StateIdle)
+			guild.State.SetBotState(state.StateIdle)
 
 			time.Sleep(500 * time.Millisecond)
-			vc := e.voiceManager.GetVoiceConnection()
-			if vc != nil && !e.radioManager.IsPlaying() {
-				e.radioManager.Start(vc)
+			vc := guild.Voice.GetVoiceConnection()
+			if vc != nil && !guild.Radio.IsPlaying() {
+				guild.Radio.Start(vc)
 			}
 		})
 	}
@@ -0,0 +1,117 @@
+package discord
+
+import (
+	"errors"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+	"musicbot/internal/voice"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// followDelay is how long a followed DJ has to stay in a channel before the
+// bot moves after them, so hopping through channels doesn't thrash the voice
+// connection.
+const followDelay = 2 * time.Second
+
+// followUser handles a voice update of the DJ the bot follows. It reports
+// whether the update was taken care of; a DJ who disconnected turns follow
+// mode off and is otherwise handled like any user leaving.
+func (e *EventHandler) followUser(guild *guilds.Guild, v *discordgo.VoiceStateUpdate) bool {
+	if v.ChannelID == "" {
+		e.stopFollowTimer(guild.ID)
+		guild.State.SetFollowedUser("")
+		logger.Info.Printf("Followed user %s left voice in guild %s, follow mode off", v.UserID, guild.ID)
+		e.announce(guild, "follow.stopped_disconnect", v.UserID)
+		return false
+	}
+
+	e.followMu.Lock()
+	defer e.followMu.Unlock()
+
+	if timer := e.followTimers[guild.ID]; timer != nil {
+		timer.Stop()
+		delete(e.followTimers, guild.ID)
+	}
+
+	if v.ChannelID == guild.State.GetCurrentChannel() {
+		return true
+	}
+
+	userID, channelID := v.UserID, v.ChannelID
+	e.followTimers[guild.ID] = time.AfterFunc(followDelay, func() {
+		e.followMu.Lock()
+		delete(e.followTimers, guild.ID)
+		e.followMu.Unlock()
+
+		e.moveToFollowed(guild, userID, channelID)
+	})
+	return true
+}
+
+func (e *EventHandler) stopFollowTimer(guildID string) {
+	e.followMu.Lock()
+	defer e.followMu.Unlock()
+
+	if timer := e.followTimers[guildID]; timer != nil {
+		timer.Stop()
+		delete(e.followTimers, guildID)
+	}
+}
+
+// moveToFollowed moves the bot into channelID once the followed DJ has stayed
+// there for followDelay, carrying on with whatever was playing.
+func (e *EventHandler) moveToFollowed(guild *guilds.Guild, userID, channelID string) {
+	if e.stateManager.IsShuttingDown() || guild.State.GetFollowedUser() != userID {
+		return
+	}
+
+	// A later move or disconnect has its own update to act on.
+	current, err := voice.UserVoiceChannel(discordapi.New(e.session), guild.ID, userID)
+	if err != nil || current != channelID {
+		return
+	}
+
+	currentChannel := guild.State.GetCurrentChannel()
+	if currentChannel == "" || currentChannel == channelID {
+		return
+	}
+
+	if err := voice.CheckJoin(discordapi.New(e.session), guild.ID, channelID); err != nil {
+		logger.Info.Printf("Not following user %s in guild %s: %v", userID, guild.ID, err)
+		if errors.Is(err, voice.ErrChannelFull) {
+			e.announce(guild, "follow.channel_full", channelID)
+		} else {
+			e.announce(guild, "follow.no_permission", channelID)
+		}
+
+		// The channel the bot stays in may be empty now.
+		if err := e.handleUserLeft(guild, currentChannel); err != nil {
+			logger.Error.Printf("Failed to handle user left: %v", err)
+		}
+		return
+	}
+
+	logger.Info.Printf("Following user %s into channel %s in guild %s", userID, channelID, guild.ID)
+
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	guild.Music.ExecuteWithDisabledHandlers(func() {
+		position, resume := guild.Music.Suspend()
+		guild.Radio.Stop()
+
+		err := guild.Voice.MoveTo(guild.ID, channelID)
+		if errors.Is(err, voice.ErrManagerClosed) {
+			return
+		}
+		if err != nil {
+			logger.Error.Printf("Failed to follow user %s into channel %s: %v", userID, channelID, err)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+		e.resumePlayback(guild, resume, position)
+	})
+}
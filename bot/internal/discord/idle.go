@@ -0,0 +1,136 @@
+package discord
+
+import (
+	"errors"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+	"musicbot/internal/state"
+	"musicbot/internal/voice"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// HandleChannelDelete moves the bot to a stand-in idle channel when the
+// guild's idle channel is deleted, and tells the admins to pick a new one.
+// Deleting the 24/7 channel turns 24/7 mode off. Renaming the channel needs
+// nothing, since it keeps its ID.
+func (e *EventHandler) HandleChannelDelete(s *discordgo.Session, c *discordgo.ChannelDelete) {
+	if c.GuildID == "" || e.stateManager.IsShuttingDown() {
+		return
+	}
+
+	guild := e.guilds.Get(c.GuildID)
+	if c.ID == guild.State.GetRequestChannel() {
+		logger.Info.Printf("Request channel %s of guild %s was deleted", c.ID, c.GuildID)
+		e.dropRequestChannel(guild)
+	}
+
+	alwaysOnDeleted := c.ID == guild.State.GetAlwaysOnChannel()
+	idleDeleted := c.ID == guild.State.GetIdleChannel()
+	if !alwaysOnDeleted && !idleDeleted {
+		return
+	}
+
+	// The bot is dropped from a deleted channel, so it was in it if it is
+	// still or no longer connected.
+	currentChannel := guild.State.GetCurrentChannel()
+	wasInChannel := currentChannel == c.ID || currentChannel == ""
+
+	if alwaysOnDeleted {
+		logger.Info.Printf("24/7 channel %s of guild %s was deleted", c.ID, c.GuildID)
+		e.dropAlwaysOn(guild)
+	}
+
+	if idleDeleted {
+		logger.Info.Printf("Idle channel %s of guild %s was deleted", c.ID, c.GuildID)
+		if e.replaceIdleChannel(guild) == "" {
+			return
+		}
+	}
+
+	if wasInChannel {
+		go e.moveToIdle(guild)
+	}
+}
+
+// replaceIdleChannel forgets the guild's idle channel, which no longer
+// exists, and idles in its AFK channel or the first joinable voice channel
+// until an admin sets a new one. The announce channel is told either way. It
+// returns the stand-in channel, or "" if there is none.
+func (e *EventHandler) replaceIdleChannel(guild *guilds.Guild) string {
+	if err := e.dbManager.DeleteIdleChannel(guild.ID); err != nil {
+		logger.Error.Printf("Failed to clear idle channel of guild %s: %v", guild.ID, err)
+	}
+
+	admins := e.adminMention(guild.ID)
+
+	fallback, err := voice.FallbackChannel(discordapi.New(e.session), guild.ID)
+	guild.State.SetIdleChannel(fallback)
+	if err != nil {
+		logger.Error.Printf("No voice channel to idle in for guild %s: %v", guild.ID, err)
+		e.announce(guild, "idle.deleted_no_fallback", admins)
+		return ""
+	}
+
+	logger.Info.Printf("Idling in channel %s of guild %s until a new idle channel is set", fallback, guild.ID)
+	e.announce(guild, "idle.deleted_fallback", admins, fallback)
+	return fallback
+}
+
+// moveToIdle takes the bot to the guild's idle channel, or its 24/7 channel
+// in 24/7 mode, and starts the radio.
+func (e *EventHandler) moveToIdle(guild *guilds.Guild) error {
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	guild.Radio.Stop()
+
+	if err := guild.Voice.ReturnToIdle(guild.ID); err != nil {
+		logger.Error.Printf("Failed to move to idle channel in guild %s: %v", guild.ID, err)
+		return err
+	}
+	e.updateVoiceMetrics()
+
+	if guild.State.IsInIdleChannel() {
+		guild.State.SetBotState(state.StateIdle)
+	} else {
+		guild.State.SetBotState(state.StateRadio)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	vc := guild.Voice.GetVoiceConnection()
+	if vc != nil && !guild.Radio.IsPlaying() {
+		guild.Radio.Start(vc)
+	}
+	return nil
+}
+
+// adminMention mentions the guild's admin role, or names it if the role
+// can't be found.
+func (e *EventHandler) adminMention(guildID string) string {
+	name := e.permissionManager.GetRequiredRoleName(guildID, permissions.LevelAdmin)
+
+	guild, err := e.session.State.Guild(guildID)
+	if err != nil {
+		return name
+	}
+
+	for _, role := range guild.Roles {
+		if strings.EqualFold(role.Name, name) {
+			return "<@&" + role.ID + ">"
+		}
+	}
+	return name
+}
+
+// isNotFound reports whether err is Discord saying the resource doesn't exist.
+func isNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,115 @@
+package discord
+
+import (
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"musicbot/internal/voice"
+	"time"
+)
+
+// radioResumeWindow is how recently the radio must have been playing for a
+// restart to take it back to its channel.
+const radioResumeWindow = time.Hour
+
+// radioChanged keeps track of the channel each guild's radio plays in, for
+// radio_autoresume. A stop during shutdown keeps the channel and marks the
+// radio as playing until then; any other stop, such as music taking over or
+// an admin moving the bot, forgets it.
+func (c *Client) radioChanged(guildID, channelID string, playing bool) {
+	if !playing && !c.stateManager.IsShuttingDown() {
+		if err := c.dbManager.ClearRadioSession(guildID); err != nil {
+			logger.Error.Printf("Failed to clear the radio session of guild %s: %v", guildID, err)
+		}
+		return
+	}
+
+	if err := c.dbManager.SaveRadioSession(guildID, channelID, time.Now()); err != nil {
+		logger.Error.Printf("Failed to save the radio session of guild %s: %v", guildID, err)
+	}
+}
+
+// lastRadioSession returns where the radio of guildID was playing before the
+// restart, if radio_autoresume is on and that was recent enough. It has to
+// be read before idle mode starts the radio again and overwrites it.
+func (c *Client) lastRadioSession(guildID string) *config.RadioSession {
+	if !c.stateManager.GetConfig().RadioAutoResume {
+		return nil
+	}
+
+	session, err := c.dbManager.GetRadioSession(guildID)
+	if err != nil {
+		logger.Error.Printf("Failed to load the radio session of guild %s: %v", guildID, err)
+		return nil
+	}
+	if session == nil || time.Since(session.UpdatedAt) > radioResumeWindow {
+		return nil
+	}
+	return session
+}
+
+// resumeRadio moves the radio from the idle channel back to the channel it
+// was playing in before the restart. The bot stays put in 24/7 mode, while
+// music plays, or when nobody is left in that channel to listen.
+func (c *Client) resumeRadio(guildID string, session *config.RadioSession) {
+	if session == nil || c.stateManager.IsShuttingDown() {
+		return
+	}
+
+	guild := c.guilds.Get(guildID)
+	if guild.State.GetAlwaysOnChannel() != "" || guild.State.GetBotState() == state.StateDJ {
+		return
+	}
+	if guild.Voice.IsConnectedTo(session.ChannelID) || !c.hasListeners(guildID, session.ChannelID) {
+		return
+	}
+	if err := voice.CheckJoin(c.api, guildID, session.ChannelID); err != nil {
+		logger.Info.Printf("Not resuming the radio in channel %s of guild %s: %v", session.ChannelID, guildID, err)
+		return
+	}
+
+	logger.Info.Printf("Resuming the radio in channel %s of guild %s", session.ChannelID, guildID)
+
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	guild.Music.ExecuteWithDisabledHandlers(func() {
+		guild.Radio.Stop()
+
+		err := guild.Voice.MoveTo(guildID, session.ChannelID)
+		if errors.Is(err, voice.ErrManagerClosed) {
+			return
+		}
+		if err != nil {
+			logger.Error.Printf("Failed to resume the radio in channel %s of guild %s: %v", session.ChannelID, guildID, err)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+
+		if guild.State.IsInIdleChannel() {
+			guild.State.SetBotState(state.StateIdle)
+		} else {
+			guild.State.SetBotState(state.StateRadio)
+		}
+		if vc := guild.Voice.GetVoiceConnection(); vc != nil && !guild.Radio.IsPlaying() {
+			guild.Radio.Start(vc)
+		}
+	})
+}
+
+// hasListeners reports whether anyone other than the bot is in channelID.
+func (c *Client) hasListeners(guildID, channelID string) bool {
+	guild, err := c.session.State.Guild(guildID)
+	if err != nil {
+		return false
+	}
+
+	botID := c.session.State.User.ID
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID == channelID && vs.UserID != botID {
+			return true
+		}
+	}
+	return false
+}
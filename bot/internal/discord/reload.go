@@ -0,0 +1,297 @@
+package discord
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/permissions"
+)
+
+// SetConfigPath tells the client where to re-read the config file from when
+// reloading. loaded is the config the bot started with, which settings that
+// need a restart are compared against.
+func (c *Client) SetConfigPath(path string, loaded config.FileConfig) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	c.configPath = path
+	c.startConfig = loaded
+}
+
+// ReloadConfig re-reads the config file and the database config and applies
+// the settings that can change while the bot is running. Settings that need a
+// restart are reported as rejected and left as they are.
+func (c *Client) ReloadConfig() (*config.ReloadResult, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	if c.configPath == "" {
+		return nil, fmt.Errorf("config path is not set")
+	}
+
+	fileConfig, err := config.LoadFromFile(c.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := fileConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	dbConfig, err := c.dbManager.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load database config: %w", err)
+	}
+
+	limits, err := c.dbManager.GetQueueLimits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queue limits: %w", err)
+	}
+
+	idleChannels, err := c.dbManager.GetIdleChannels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load idle channels: %w", err)
+	}
+
+	result := &config.ReloadResult{}
+	live := c.stateManager.GetConfig()
+
+	if fileConfig.Token != live.Token {
+		result.Reject("token", "(hidden)", "(hidden)")
+	}
+	if fileConfig.UDSPath != live.UDSPath {
+		result.Reject("uds_path", live.UDSPath, fileConfig.UDSPath)
+	}
+	if fileConfig.ShardID != c.session.ShardID {
+		result.Reject("shard_id", strconv.Itoa(c.session.ShardID), strconv.Itoa(fileConfig.ShardID))
+	}
+	if fileConfig.ShardCount != c.shardCount {
+		result.Reject("shard_count", c.shardCount.String(), fileConfig.ShardCount.String())
+	}
+	if fileConfig.DownloadDir != live.DownloadDir {
+		result.Reject("download_dir", live.DownloadDir, fileConfig.DownloadDir)
+	}
+	// The gateway intents are fixed when the bot connects.
+	if fileConfig.RequestChannels != live.RequestChannels {
+		result.Reject("request_channels", strconv.FormatBool(live.RequestChannels), strconv.FormatBool(fileConfig.RequestChannels))
+	}
+
+	rejectRestartOnly(c.startConfig, fileConfig, result)
+	c.reloadRoles(fileConfig, result)
+
+	if owners := c.permissionManager.Owners(); !sameOwners(owners, fileConfig.OwnerIDs) {
+		c.permissionManager.SetOwners(fileConfig.OwnerIDs)
+		result.Apply("owner_ids", strings.Join(owners, ","), strings.Join(c.permissionManager.Owners(), ","))
+	}
+	c.reloadGuilds(fileConfig, idleChannels, result)
+
+	streams := fileConfig.StreamOptions()
+	if oldStreams, newStreams := config.FormatStreams(live.Streams), config.FormatStreams(streams); oldStreams != newStreams {
+		live = c.stateManager.GetConfig()
+		live.Streams = streams
+		c.stateManager.UpdateConfig(live)
+		c.streams.SetStreams(streams)
+		result.Apply("streams", oldStreams, newStreams)
+
+		if err := c.UpdateCommands(false); err != nil {
+			logger.Error.Printf("Failed to update stream choices: %v", err)
+		}
+	}
+
+	// idle_delay set with /config wins over the file.
+	idleDelay := time.Duration(fileConfig.IdleDelaySeconds) * time.Second
+	if dbConfig.IdleDelay > 0 {
+		idleDelay = dbConfig.IdleDelay
+	}
+	if idleDelay != live.IdleDelay {
+		old := live.IdleDelay
+		live = c.stateManager.GetConfig()
+		live.IdleDelay = idleDelay
+		c.stateManager.UpdateConfig(live)
+		result.Apply("idle_delay_seconds", strconv.Itoa(int(old.Seconds())), strconv.Itoa(int(idleDelay.Seconds())))
+	}
+
+	// Songs already playing keep their filter; the change applies from the
+	// next one.
+	if fileConfig.DisableFilters != live.FiltersDisabled {
+		old := live.FiltersDisabled
+		live = c.stateManager.GetConfig()
+		live.FiltersDisabled = fileConfig.DisableFilters
+		c.stateManager.UpdateConfig(live)
+		result.Apply("disable_filters", strconv.FormatBool(old), strconv.FormatBool(fileConfig.DisableFilters))
+	}
+
+	if fileConfig.PurgeOnLeave != live.PurgeOnLeave {
+		old := live.PurgeOnLeave
+		live = c.stateManager.GetConfig()
+		live.PurgeOnLeave = fileConfig.PurgeOnLeave
+		c.stateManager.UpdateConfig(live)
+		result.Apply("purge_on_leave", strconv.FormatBool(old), strconv.FormatBool(fileConfig.PurgeOnLeave))
+	}
+
+	if fileConfig.RadioAutoResume != live.RadioAutoResume {
+		old := live.RadioAutoResume
+		live = c.stateManager.GetConfig()
+		live.RadioAutoResume = fileConfig.RadioAutoResume
+		c.stateManager.UpdateConfig(live)
+		result.Apply("radio_autoresume", strconv.FormatBool(old), strconv.FormatBool(fileConfig.RadioAutoResume))
+	}
+
+	if oldURL := c.lyrics.BaseURL(); c.lyrics.SetBaseURL(fileConfig.LyricsURL) {
+		result.Apply("lyrics_url", oldURL, c.lyrics.BaseURL())
+	}
+
+	if volume := c.stateManager.GetVolume(); dbConfig.Volume > 0 && dbConfig.Volume != volume {
+		c.stateManager.SetVolume(dbConfig.Volume)
+		result.Apply("volume", formatVolume(volume), formatVolume(dbConfig.Volume))
+	}
+
+	if stream := c.stateManager.GetRadioStream(); dbConfig.Stream != "" && dbConfig.Stream != stream {
+		c.stateManager.SetRadioStream(dbConfig.Stream)
+		result.Apply("stream", stream, dbConfig.Stream)
+	}
+
+	if current := c.guilds.DefaultQueueLimits(); limits != current {
+		c.guilds.SetDefaultQueueLimits(limits)
+		if limits.MaxQueueLength != current.MaxQueueLength {
+			result.Apply("max_queue_length", strconv.Itoa(current.MaxQueueLength), strconv.Itoa(limits.MaxQueueLength))
+		}
+		if limits.MaxUserQueued != current.MaxUserQueued {
+			result.Apply("max_user_queued", strconv.Itoa(current.MaxUserQueued), strconv.Itoa(limits.MaxUserQueued))
+		}
+	}
+
+	logger.Info.Printf("Config reloaded: %s", result)
+	return result, nil
+}
+
+// rejectRestartOnly rejects the settings that are only read at startup and
+// differ between the config the bot is running with and the reloaded one.
+func rejectRestartOnly(running, reloaded config.FileConfig, result *config.ReloadResult) {
+	rejectString := func(key, current, updated string) {
+		if updated != current {
+			result.Reject(key, current, updated)
+		}
+	}
+	rejectInt := func(key string, current, updated int) {
+		rejectString(key, strconv.Itoa(current), strconv.Itoa(updated))
+	}
+	rejectBool := func(key string, current, updated bool) {
+		rejectString(key, strconv.FormatBool(current), strconv.FormatBool(updated))
+	}
+
+	// The database and health server are opened once.
+	rejectString("db_path", running.DBPath, reloaded.DBPath)
+	rejectString("health_addr", running.HealthAddr, reloaded.HealthAddr)
+
+	// Logging is set up before anything else.
+	rejectString("log_file", running.LogFile, reloaded.LogFile)
+	rejectInt("log_max_mb", running.LogMaxMB, reloaded.LogMaxMB)
+	rejectInt("log_backups", running.LogBackups, reloaded.LogBackups)
+	rejectBool("log_json", running.LogJSON, reloaded.LogJSON)
+
+	// The janitor and the downloader's workers are configured when they
+	// start.
+	rejectInt("janitor_interval_minutes", running.JanitorIntervalMinutes, reloaded.JanitorIntervalMinutes)
+	rejectInt("cache_max_age_days", running.CacheMaxAgeDays, reloaded.CacheMaxAgeDays)
+	rejectString("max_cache_gb", strconv.FormatFloat(running.MaxCacheGB, 'g', -1, 64), strconv.FormatFloat(reloaded.MaxCacheGB, 'g', -1, 64))
+	rejectInt("playlist_workers", running.PlaylistWorkers, reloaded.PlaylistWorkers)
+
+	// The dependency check only runs at startup.
+	rejectBool("skip_dep_check", running.SkipDepCheck, reloaded.SkipDepCheck)
+}
+
+// sameOwners reports whether owners, sorted, are the same users as userIDs.
+func sameOwners(owners, userIDs []string) bool {
+	sorted := slices.Clone(userIDs)
+	slices.Sort(sorted)
+	return slices.Equal(owners, slices.Compact(sorted))
+}
+
+// reloadGuilds applies idle, announce and audit channel changes. Guilds that get their first idle
+// channel are sent to it if this shard serves them. An idle channel set with /setidlechannel wins
+// over the one in the file.
+func (c *Client) reloadGuilds(fileConfig config.FileConfig, idleChannels map[string]string, result *config.ReloadResult) {
+	for _, guild := range fileConfig.Guilds {
+		guildState := c.stateManager.Guild(guild.ID)
+
+		if announce := guildState.GetAnnounceChannel(); guild.AnnounceChannel != announce {
+			guildState.SetAnnounceChannel(guild.AnnounceChannel)
+			result.Apply(guildKey(guild.ID, "announce_channel"), announce, guild.AnnounceChannel)
+		}
+
+		if audit := guildState.GetAuditChannel(); guild.AuditChannel != audit {
+			guildState.SetAuditChannel(guild.AuditChannel)
+			result.Apply(guildKey(guild.ID, "audit_channel"), audit, guild.AuditChannel)
+		}
+
+		idleChannel := guild.IdleChannel
+		if stored, ok := idleChannels[guild.ID]; ok {
+			idleChannel = stored
+		}
+
+		current := guildState.GetIdleChannel()
+		if idleChannel == current {
+			continue
+		}
+
+		guildState.SetIdleChannel(idleChannel)
+		result.Apply(guildKey(guild.ID, "idle_channel"), current, idleChannel)
+
+		// A guild on another shard, or one the bot hasn't been added to
+		// yet, goes idle when the gateway delivers it.
+		if current == "" && c.guildAvailable(guild.ID) && !guildState.IsConnected() {
+			go func(guildID string) {
+				if err := c.StartIdleMode(guildID); err != nil {
+					logger.Error.Printf("Failed to start idle mode in guild %s: %v", guildID, err)
+				}
+			}(guild.ID)
+		}
+	}
+}
+
+func (c *Client) reloadRoles(fileConfig config.FileConfig, result *config.ReloadResult) {
+	guildIDs := fileConfig.GuildIDs()
+
+	before := make(map[string]permissions.Config, len(guildIDs)+1)
+	before[""] = c.permissionManager.Config("")
+	for _, id := range guildIDs {
+		before[id] = c.permissionManager.Config(id)
+	}
+
+	c.permissionManager.UpdateConfig(fileConfig.Permissions())
+
+	defaults := c.permissionManager.Config("")
+	applyRole := func(key, current, updated string) {
+		if updated != current {
+			result.Apply(key, current, updated)
+		}
+	}
+
+	applyRole("dj_role_name", before[""].DJRoleName, defaults.DJRoleName)
+	applyRole("admin_role_name", before[""].AdminRoleName, defaults.AdminRoleName)
+
+	// Guilds that follow the defaults are already covered above.
+	for _, id := range guildIDs {
+		current, updated := before[id], c.permissionManager.Config(id)
+		if current.DJRoleName != before[""].DJRoleName || updated.DJRoleName != defaults.DJRoleName {
+			applyRole(guildKey(id, "dj_role_name"), current.DJRoleName, updated.DJRoleName)
+		}
+		if current.AdminRoleName != before[""].AdminRoleName || updated.AdminRoleName != defaults.AdminRoleName {
+			applyRole(guildKey(id, "admin_role_name"), current.AdminRoleName, updated.AdminRoleName)
+		}
+	}
+}
+
+// guildKey names a per-guild setting in reload results, e.g.
+// "guilds.123.idle_channel".
+func guildKey(guildID, key string) string {
+	return "guilds." + guildID + "." + key
+}
+
+func formatVolume(volume float32) string {
+	return strconv.FormatFloat(float64(volume), 'f', 2, 32)
+}
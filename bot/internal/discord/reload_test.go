@@ -0,0 +1,81 @@
+package discord
+
+import (
+	"musicbot/internal/config"
+	"testing"
+)
+
+func TestRejectRestartOnly(t *testing.T) {
+	running := config.FileConfig{
+		DBPath:                 "bot.db",
+		HealthAddr:             ":8080",
+		LogFile:                "bot.log",
+		LogMaxMB:               10,
+		LogBackups:             3,
+		JanitorIntervalMinutes: 60,
+		CacheMaxAgeDays:        30,
+		MaxCacheGB:             5,
+		PlaylistWorkers:        3,
+	}
+
+	tests := []struct {
+		key    string
+		change func(c *config.FileConfig)
+		old    string
+		new    string
+	}{
+		{"db_path", func(c *config.FileConfig) { c.DBPath = "other.db" }, "bot.db", "other.db"},
+		{"health_addr", func(c *config.FileConfig) { c.HealthAddr = "" }, ":8080", ""},
+		{"log_file", func(c *config.FileConfig) { c.LogFile = "other.log" }, "bot.log", "other.log"},
+		{"log_max_mb", func(c *config.FileConfig) { c.LogMaxMB = 20 }, "10", "20"},
+		{"log_backups", func(c *config.FileConfig) { c.LogBackups = 0 }, "3", "0"},
+		{"log_json", func(c *config.FileConfig) { c.LogJSON = true }, "false", "true"},
+		{"janitor_interval_minutes", func(c *config.FileConfig) { c.JanitorIntervalMinutes = 30 }, "60", "30"},
+		{"cache_max_age_days", func(c *config.FileConfig) { c.CacheMaxAgeDays = 7 }, "30", "7"},
+		{"max_cache_gb", func(c *config.FileConfig) { c.MaxCacheGB = 2.5 }, "5", "2.5"},
+		{"playlist_workers", func(c *config.FileConfig) { c.PlaylistWorkers = 5 }, "3", "5"},
+		{"skip_dep_check", func(c *config.FileConfig) { c.SkipDepCheck = true }, "false", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			reloaded := running
+			tt.change(&reloaded)
+
+			result := &config.ReloadResult{}
+			rejectRestartOnly(running, reloaded, result)
+			if len(result.Applied) != 0 || len(result.Rejected) != 1 {
+				t.Fatalf("result = %s, want only %s rejected", result, tt.key)
+			}
+			want := config.Change{Key: tt.key, Old: tt.old, New: tt.new}
+			if got := result.Rejected[0]; got != want {
+				t.Errorf("rejected %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	result := &config.ReloadResult{}
+	rejectRestartOnly(running, running, result)
+	if !result.Empty() {
+		t.Errorf("reloading the same config = %s, want no changes", result)
+	}
+}
+
+func TestSameOwners(t *testing.T) {
+	tests := []struct {
+		owners  []string
+		userIDs []string
+		want    bool
+	}{
+		{nil, nil, true},
+		{[]string{"1", "2"}, []string{"2", "1"}, true},
+		{[]string{"1"}, []string{"1", "1"}, true},
+		{[]string{"1"}, []string{"1", "2"}, false},
+		{[]string{"1", "2"}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := sameOwners(tt.owners, tt.userIDs); got != tt.want {
+			t.Errorf("sameOwners(%v, %v) = %v, want %v", tt.owners, tt.userIDs, got, tt.want)
+		}
+	}
+}
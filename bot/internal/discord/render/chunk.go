@@ -0,0 +1,161 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxEmbedDescription = 4096
+
+	codeFence = "```"
+)
+
+// Chunk splits text into pieces of at most limit characters, breaking
+// between lines where it can. A line longer than limit is broken up itself.
+// A code block that is open at a break is closed at the end of the piece and
+// opened again, with the same language, at the start of the next.
+func Chunk(text string, limit int) []string {
+	c := chunker{limit: limit}
+	for _, line := range strings.Split(text, "\n") {
+		c.addLine(line)
+	}
+	c.flush(false)
+	return c.chunks
+}
+
+// Chunks renders text as plain messages that each fit Discord's content
+// limit, for listings that can outgrow one message.
+func Chunks(text string) []Message {
+	var messages []Message
+	for _, chunk := range Chunk(text, maxContent) {
+		messages = append(messages, Message{Content: chunk})
+	}
+	return messages
+}
+
+// Listing renders a titled list that may not fit one message. In rich style
+// the body is spread over embeds of up to 4096 characters, the first one
+// carrying the title; in plain style over messages of up to 2000.
+func Listing(guildID, title, body string, color int) []Message {
+	if GuildStyle(guildID) == StylePlain {
+		return Chunks(plain("**" + title + "**\n" + body))
+	}
+
+	var messages []Message
+	for idx, chunk := range Chunk(body, maxEmbedDescription) {
+		e := &discordgo.MessageEmbed{Description: chunk, Color: color}
+		if idx == 0 {
+			e.Title = title
+		}
+		messages = append(messages, embed(guildID, e))
+	}
+	return messages
+}
+
+type chunker struct {
+	limit  int
+	chunks []string
+	lines  []string
+	size   int
+
+	// fence is the line that opened the code block the piece is in, or "",
+	// and opened is the index in lines of that line.
+	fence  string
+	opened int
+}
+
+func (c *chunker) addLine(line string) {
+	for {
+		next := nextFence(c.fence, line)
+		if c.size+c.separator()+utf8.RuneCountInString(line)+closingSize(next) <= c.limit {
+			if c.fence == "" && next != "" {
+				c.opened = len(c.lines)
+			}
+			c.size += c.separator() + utf8.RuneCountInString(line)
+			c.lines = append(c.lines, line)
+			c.fence = next
+			return
+		}
+
+		if !c.fresh() {
+			c.flush(true)
+			continue
+		}
+
+		// The line doesn't fit even on its own: send what fits of it.
+		runes := []rune(line)
+		room := min(max(c.limit-c.size-c.separator()-closingSize(c.fence), 1), len(runes))
+		c.size += c.separator() + room
+		c.lines = append(c.lines, string(runes[:room]))
+		c.flush(true)
+		if room >= len(runes) {
+			return
+		}
+		line = string(runes[room:])
+	}
+}
+
+// fresh reports whether the piece holds nothing but the reopened code fence.
+func (c *chunker) fresh() bool {
+	return len(c.lines) == 0 || (c.fence != "" && len(c.lines) == 1)
+}
+
+func (c *chunker) separator() int {
+	if len(c.lines) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// flush ends the piece, closing an open code block, and with reopen starts
+// the next one inside the same block.
+func (c *chunker) flush(reopen bool) {
+	if len(c.lines) == 0 {
+		return
+	}
+
+	lines, closing := c.lines, c.fence != ""
+	if reopen && closing && c.opened == len(lines)-1 {
+		// The block was opened by the last line: leave it to the next
+		// piece rather than end this one with an empty block.
+		lines, closing = lines[:len(lines)-1], false
+	}
+	text := strings.Join(lines, "\n")
+	if closing {
+		text += "\n" + codeFence
+	}
+	c.chunks = append(c.chunks, text)
+	c.lines, c.size = nil, 0
+
+	if reopen && c.fence != "" {
+		c.lines = []string{c.fence}
+		c.size = utf8.RuneCountInString(c.fence)
+		c.opened = 0
+	}
+}
+
+// nextFence is the open code block after line, given the one before it.
+func nextFence(fence, line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.Count(trimmed, codeFence)%2 == 0 {
+		return fence
+	}
+	if fence != "" {
+		return ""
+	}
+	if strings.HasPrefix(trimmed, codeFence) && !strings.Contains(trimmed[len(codeFence):], " ") {
+		return trimmed
+	}
+	return codeFence
+}
+
+// closingSize is what closing the code block fence takes, if one is open.
+func closingSize(fence string) int {
+	if fence == "" {
+		return 0
+	}
+	return len("\n" + codeFence)
+}
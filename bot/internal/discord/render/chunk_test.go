@@ -0,0 +1,101 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits exactly", "aaaa\nbbbb", 9, []string{"aaaa\nbbbb"}},
+		{"one over", "aaaa\nbbbb", 8, []string{"aaaa", "bbbb"}},
+		{"counts characters, not bytes", "ééé\nééé", 7, []string{"ééé\nééé"}},
+		{"long line", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"long line after others", "ab\ncdefgh", 4, []string{"ab", "cdef", "gh"}},
+		{
+			"code block straddling the limit",
+			"intro\n```go\nline1\nline2\n```\nafter", 15,
+			[]string{"intro", "```go\nline1\n```", "```go\nline2\n```", "after"},
+		},
+		{
+			"closing fence just fits",
+			"```\nline1\n```", 13,
+			[]string{"```\nline1\n```"},
+		},
+		{
+			"closing fence one over",
+			"```\nline1\n```", 12,
+			[]string{"```\nline\n```", "```\n1\n```"},
+		},
+		{
+			"long line in a code block",
+			"```\nabcdefghijkl\n```", 14,
+			[]string{"```\nabcdef\n```", "```\nghijkl\n```"},
+		},
+		{
+			"fence with text after it is not a language",
+			"```not a language\nline1\nline2\n```", 27,
+			[]string{"```not a language\nline1\n```", "```\nline2\n```"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Chunk(tt.text, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("Chunk(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestChunkKeepsLimitsAndFences splits a listing with code blocks at every
+// limit around its line lengths and checks that each piece fits and has its
+// code blocks closed, and that together they hold all of the text in order.
+func TestChunkKeepsLimitsAndFences(t *testing.T) {
+	var lines []string
+	for n := 1; n <= 30; n++ {
+		lines = append(lines, fmt.Sprintf("%d. %s", n, strings.Repeat("x", n%7)))
+		if n%10 == 0 {
+			lines = append(lines, "```diff", "+ added", "- removed", "```")
+		}
+	}
+	text := strings.Join(lines, "\n")
+
+	for limit := 20; limit <= 60; limit++ {
+		var got []string
+		for _, chunk := range Chunk(text, limit) {
+			if n := utf8.RuneCountInString(chunk); n > limit {
+				t.Fatalf("limit %d: piece of %d characters:\n%s", limit, n, chunk)
+			}
+			if strings.Count(chunk, codeFence)%2 != 0 {
+				t.Fatalf("limit %d: piece leaves a code block open:\n%s", limit, chunk)
+			}
+			got = append(got, strings.Split(chunk, "\n")...)
+		}
+
+		// Long lines are broken up and fences added at the breaks, so only
+		// the rest of the text is compared.
+		if got, want := withoutFences(got), withoutFences(lines); got != want {
+			t.Fatalf("limit %d: pieces hold %q, want %q", limit, got, want)
+		}
+	}
+}
+
+// withoutFences joins lines, leaving out code fences and line breaks.
+func withoutFences(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if !strings.HasPrefix(line, codeFence) {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
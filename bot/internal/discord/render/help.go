@@ -0,0 +1,154 @@
+package render
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	maxEmbedFieldValue = 1024
+	maxContent         = 2000
+)
+
+// HelpEntry is a command listed by /help.
+type HelpEntry struct {
+	Name        string
+	Description string
+
+	// Role is the role needed to run the command, or "" if anyone can.
+	Role string
+}
+
+// HelpPage is one page of /help, listing commands of a single category.
+type HelpPage struct {
+	Category string
+	Entries  []HelpEntry
+	Page     int
+	Pages    int
+}
+
+// Help renders a page of the command list. Page is counted from 0; the tips
+// are shown on the first page only.
+func Help(guildID string, page HelpPage) Message {
+	body := ""
+	for _, entry := range page.Entries {
+		role := ""
+		if entry.Role != "" {
+			role = i18n.T(guildID, "help.role_only", entry.Role)
+		}
+		body += i18n.T(guildID, "help.line", entry.Name, entry.Description, role)
+	}
+	if page.Page == 0 {
+		body += "\n" + i18n.T(guildID, "help.tips")
+	}
+	body += "\n\n" + i18n.T(guildID, "help.page", page.Page+1, page.Pages)
+
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain(i18n.T(guildID, "help.header") + i18n.T(guildID, "help.category", page.Category) + body)}
+	}
+
+	return embed(guildID, &discordgo.MessageEmbed{
+		Title:       i18n.T(guildID, "render.help_title", page.Category),
+		Description: body,
+	})
+}
+
+// CommandDetails is everything /help shows about a single command.
+type CommandDetails struct {
+	Name        string
+	Description string
+	Options     []*discordgo.ApplicationCommandOption
+
+	// Role is the role needed to run the command, or "" if anyone can.
+	// DJOnlyRole is the role needed while DJ-only mode is on, for commands
+	// that mode restricts.
+	Role       string
+	DJOnlyRole string
+
+	Cooldown      time.Duration
+	MaxConcurrent int
+	Examples      []string
+}
+
+// Command renders the details of a command.
+func Command(guildID string, details CommandDetails) Message {
+	options := strings.Join(optionLines(guildID, "/"+details.Name+" ", details.Options), "\n")
+	if options == "" {
+		options = i18n.T(guildID, "help.no_options")
+	}
+
+	access := i18n.T(guildID, "help.access_everyone")
+	if details.Role != "" {
+		access = i18n.T(guildID, "help.access_role", details.Role)
+	}
+	if details.DJOnlyRole != "" {
+		access += "\n" + i18n.T(guildID, "help.access_dj_only", details.DJOnlyRole)
+	}
+
+	var limits []string
+	if details.Cooldown > 0 {
+		limits = append(limits, i18n.T(guildID, "help.cooldown", int(details.Cooldown.Seconds())))
+	}
+	if details.MaxConcurrent > 0 {
+		limits = append(limits, i18n.T(guildID, "help.max_concurrent", details.MaxConcurrent))
+	}
+
+	examples := make([]string, 0, len(details.Examples))
+	for _, example := range details.Examples {
+		examples = append(examples, "`"+example+"`")
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: i18n.T(guildID, "help.field_options"), Value: options},
+		{Name: i18n.T(guildID, "help.field_access"), Value: access},
+	}
+	if len(limits) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: i18n.T(guildID, "help.field_limits"), Value: strings.Join(limits, "\n")})
+	}
+	if len(examples) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: i18n.T(guildID, "help.field_examples"), Value: strings.Join(examples, "\n")})
+	}
+
+	if GuildStyle(guildID) == StylePlain {
+		text := fmt.Sprintf("**/%s** - %s", details.Name, details.Description)
+		for _, field := range fields {
+			text += fmt.Sprintf("\n\n**%s**\n%s", field.Name, field.Value)
+		}
+		return Message{Content: truncate(plain(text), maxContent)}
+	}
+
+	for _, field := range fields {
+		field.Value = truncate(field.Value, maxEmbedFieldValue)
+	}
+	return embed(guildID, &discordgo.MessageEmbed{
+		Title:       "/" + details.Name,
+		Description: details.Description,
+		Fields:      fields,
+	})
+}
+
+// optionLines describes options one per line. Subcommands are written out
+// in full, prefix included, followed by their own options.
+func optionLines(guildID, prefix string, options []*discordgo.ApplicationCommandOption) []string {
+	var lines []string
+	for _, option := range options {
+		switch option.Type {
+		case discordgo.ApplicationCommandOptionSubCommandGroup:
+			lines = append(lines, optionLines(guildID, prefix+option.Name+" ", option.Options)...)
+		case discordgo.ApplicationCommandOptionSubCommand:
+			lines = append(lines, fmt.Sprintf("**%s%s** - %s", prefix, option.Name, option.Description))
+			lines = append(lines, optionLines(guildID, prefix+option.Name+" ", option.Options)...)
+		default:
+			line := fmt.Sprintf("• `%s` - %s", option.Name, option.Description)
+			if option.Required {
+				line += i18n.T(guildID, "help.required")
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
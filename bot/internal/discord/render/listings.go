@@ -0,0 +1,58 @@
+package render
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/music"
+	"musicbot/internal/socket"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SearchResults renders the numbered results of a search. The buttons to
+// pick one are added by the caller.
+func SearchResults(guildID string, results []socket.SearchResult) Message {
+	body := ""
+	for idx, result := range results {
+		body += fmt.Sprintf("**%d.** %s - %s (%s)\n", idx+1, result.Title, result.Uploader, Duration(guildID, result.Duration))
+	}
+
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain(i18n.T(guildID, "search.results_header") + body)}
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:       i18n.T(guildID, "render.search_title"),
+		Description: body,
+	}
+	if len(results) > 0 && link(results[0].Thumbnail) != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: results[0].Thumbnail}
+	}
+	return embed(guildID, e)
+}
+
+// History renders the songs that played last, most recent first.
+func History(guildID string, entries []music.HistoryEntry) Message {
+	body := ""
+	for idx, entry := range entries {
+		body += i18n.T(guildID, "history.line",
+			idx+1, entry.Song.Title, entry.Song.Artist, entry.FinishedAt.Unix(), requestedBy(guildID, &entry.Song))
+		if entry.Skipped {
+			body += i18n.T(guildID, "history.flag_skipped")
+		}
+		body += "\n"
+	}
+
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain(i18n.T(guildID, "history.title") + "\n\n" + body + "\n" + i18n.T(guildID, "history.footer"))}
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:       i18n.T(guildID, "history.title"),
+		Description: body + "\n" + i18n.T(guildID, "history.footer"),
+	}
+	if len(entries) > 0 && entries[0].Song.ThumbnailURL != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: entries[0].Song.ThumbnailURL}
+	}
+	return embed(guildID, e)
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"musicbot/internal/i18n"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Colors used by rich embeds.
+const (
+	ColorAccent  = 0x5865F2
+	ColorSuccess = 0x57F287
+	ColorWarning = 0xFEE75C
+	ColorError   = 0xED4245
+)
+
+var (
+	version    = "dev"
+	shardID    = 0
+	shardCount = 1
+	infoMu     sync.RWMutex
+)
+
+// SetVersion sets the bot version shown in the footer of rich embeds.
+func SetVersion(v string) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	version = v
+}
+
+// SetShard sets the shard shown in the footer of rich embeds.
+func SetShard(id, count int) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	shardID, shardCount = id, max(count, 1)
+}
+
+func footer(guildID string) *discordgo.MessageEmbedFooter {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return &discordgo.MessageEmbedFooter{
+		Text: i18n.T(guildID, "render.footer", version, shardID+1, shardCount),
+	}
+}
+
+// Message is a rendered response: an embed in rich style, text in plain.
+// Files are attached for the embeds to show with attachment:// URLs.
+type Message struct {
+	Content string
+	Embeds  []*discordgo.MessageEmbed
+	Files   []*discordgo.File
+}
+
+// Data is the message as an interaction response. Mentions in it never ping.
+func (m Message) Data() *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Content:         m.Content,
+		Embeds:          m.Embeds,
+		Files:           m.Files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+}
+
+// Edit is the message as an edit of an interaction response. It replaces
+// both the content and the embeds, so switching styles leaves nothing behind.
+func (m Message) Edit() *discordgo.WebhookEdit {
+	embeds := m.Embeds
+	if embeds == nil {
+		embeds = []*discordgo.MessageEmbed{}
+	}
+	return &discordgo.WebhookEdit{
+		Content:         &m.Content,
+		Embeds:          &embeds,
+		Files:           m.Files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+}
+
+// Text renders a short notice, such as an empty queue.
+func Text(guildID, text string) Message {
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain(text)}
+	}
+	return embed(guildID, &discordgo.MessageEmbed{Description: text})
+}
+
+// Confirm renders a question that the buttons added by the caller answer,
+// such as whether to clear a long queue.
+func Confirm(guildID, title, text string) Message {
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain("**" + title + "**\n" + text)}
+	}
+	return embed(guildID, &discordgo.MessageEmbed{Title: title, Description: text, Color: ColorWarning})
+}
+
+// embed finishes e with the accent color and footer every rich message has.
+func embed(guildID string, e *discordgo.MessageEmbed) Message {
+	if e.Color == 0 {
+		e.Color = ColorAccent
+	}
+	e.Footer = footer(guildID)
+	return Message{Embeds: []*discordgo.MessageEmbed{e}}
+}
+
+// plain drops the decorative emoji from text, which screen readers would
+// otherwise read out by name, and tidies the spaces they leave behind.
+func plain(text string) string {
+	var b strings.Builder
+	var last rune
+	dropped := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.So, r):
+			dropped = true
+		case r == '\uFE0F', r == '\u200D', r == '\u20E3':
+		case r >= 0x1F3FB && r <= 0x1F3FF:
+		case r == ' ' && dropped && last == '(':
+			// "(🔴 LIVE)" becomes "(LIVE)" rather than "( LIVE)".
+		default:
+			b.WriteRune(r)
+			last, dropped = r, false
+		}
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for k, line := range lines {
+		lines[k] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// link returns rawURL if an embed can link to it, or "".
+func link(rawURL string) string {
+	if strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, "http://") {
+		return rawURL
+	}
+	return ""
+}
+
+// truncate cuts text to at most limit characters.
+func truncate(text string, limit int) string {
+	if len([]rune(text)) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/state"
+	"net/url"
+	"strings"
+)
+
+// platformNames maps the platforms the downloader reports, which are the
+// site's origin, to how they are shown.
+var platformNames = map[string]string{
+	"youtube.com":       "YouTube",
+	"music.youtube.com": "YouTube Music",
+	"soundcloud.com":    "SoundCloud",
+	"open.spotify.com":  "Spotify",
+	"bandcamp.com":      "Bandcamp",
+	"twitch.tv":         "Twitch",
+	"vimeo.com":         "Vimeo",
+	"dailymotion.com":   "Dailymotion",
+}
+
+// platformName is the display name of a song's platform, or "" for uploads
+// and songs stored without one. Unknown sites are shown by host.
+func platformName(platform string) string {
+	if platform == "" || platform == "upload" {
+		return ""
+	}
+
+	host := platform
+	if u, err := url.Parse(platform); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+
+	if name, ok := platformNames[host]; ok {
+		return name
+	}
+	return host
+}
+
+// songMetadata is the platform, upload year and view count of a song, such
+// as "YouTube • uploaded 2019 • 12M views". Whatever isn't known is left
+// out, so songs stored before these were tracked show just the platform.
+func songMetadata(guildID string, song *state.Song) string {
+	var parts []string
+	if name := platformName(song.Platform); name != "" {
+		parts = append(parts, name)
+	}
+	if len(song.UploadDate) >= 4 {
+		parts = append(parts, i18n.T(guildID, "render.uploaded", song.UploadDate[:4]))
+	}
+	if song.ViewCount > 0 {
+		parts = append(parts, i18n.T(guildID, "render.views", compactCount(song.ViewCount)))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// compactCount shortens n the way the platforms do: 950, 1.2K, 12M.
+func compactCount(n int64) string {
+	units := []struct {
+		size   int64
+		suffix string
+	}{
+		{1_000_000_000, "B"},
+		{1_000_000, "M"},
+		{1_000, "K"},
+	}
+	for _, unit := range units {
+		if n < unit.size {
+			continue
+		}
+		if n < 10*unit.size {
+			return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n/(unit.size/10))/10), ".0") + unit.suffix
+		}
+		return fmt.Sprintf("%d%s", n/unit.size, unit.suffix)
+	}
+	return fmt.Sprintf("%d", n)
+}
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"musicbot/internal/i18n"
+	"musicbot/internal/state"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxEmbedTitle is the longest title Discord accepts on an embed.
+const maxEmbedTitle = 256
+
+// NowPlaying is what /nowplaying shows while music plays.
+type NowPlaying struct {
+	Song *state.Song
+
+	// Filter is the audio filter the song plays with, or "" for none.
+	Filter   string
+	Upcoming []state.Song
+
+	// RequesterAvatar is the avatar URL of whoever queued Song, or "".
+	RequesterAvatar string
+
+	// Thumbnail is the image to show, either a URL or an attachment://
+	// reference to ThumbnailFile. "" shows none.
+	Thumbnail     string
+	ThumbnailFile *discordgo.File
+}
+
+// NowPlayingSong renders the song playing and the few after it.
+func NowPlayingSong(guildID string, np NowPlaying) Message {
+	song := np.Song
+
+	metadata := songMetadata(guildID, song)
+
+	upNext := ""
+	for idx := range np.Upcoming {
+		upNext += songLine(guildID, idx+1, &np.Upcoming[idx])
+	}
+
+	if GuildStyle(guildID) == StylePlain {
+		text := i18n.T(guildID, "nowplaying.playing",
+			song.Title, song.Artist, songDuration(guildID, song), requestedBy(guildID, song))
+		if metadata != "" {
+			text += "\n" + metadata
+		}
+		if np.Filter != "" {
+			text += i18n.T(guildID, "nowplaying.filter", np.Filter)
+		}
+		if upNext != "" {
+			text += i18n.T(guildID, "nowplaying.up_next") + upNext
+		}
+		return Message{Content: plain(text)}
+	}
+
+	e := &discordgo.MessageEmbed{
+		Author:      &discordgo.MessageEmbedAuthor{Name: i18n.T(guildID, "render.now_playing"), IconURL: np.RequesterAvatar},
+		Title:       truncate(song.Title, maxEmbedTitle),
+		URL:         link(song.URL),
+		Description: strings.TrimSpace(song.Artist + "\n" + metadata),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(guildID, "render.duration"), Value: songDuration(guildID, song), Inline: true},
+		},
+	}
+	if song.RequesterID != "" {
+		requester := "<@" + song.RequesterID + ">"
+		if !song.RequestedAt.IsZero() {
+			requester += "\n" + i18n.T(guildID, "render.requested_at", song.RequestedAt.Unix())
+		}
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name: i18n.T(guildID, "render.requested_by"), Value: requester, Inline: true,
+		})
+	}
+	if np.Filter != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name: i18n.T(guildID, "render.filter"), Value: np.Filter, Inline: true,
+		})
+	}
+	if upNext != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name: i18n.T(guildID, "render.up_next"), Value: truncate(upNext, 1024),
+		})
+	}
+	if np.Thumbnail == "" {
+		return embed(guildID, e)
+	}
+
+	e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: np.Thumbnail}
+	message := embed(guildID, e)
+	if np.ThumbnailFile != nil {
+		message.Files = []*discordgo.File{np.ThumbnailFile}
+	}
+	return message
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"musicbot/internal/i18n"
+	"musicbot/internal/state"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// QueuePage is one page of /queue.
+type QueuePage struct {
+	Current *state.Song
+
+	// Upcoming holds the songs on this page, the first of which is number
+	// Offset+1 in the queue.
+	Upcoming []state.Song
+	Offset   int
+
+	// Missing flags the songs of Upcoming whose files are gone, and
+	// MissingCount counts them in the whole queue.
+	Missing      []bool
+	MissingCount int
+
+	// Fair marks a queue where requesters take turns, so the numbers
+	// follow the turns rather than the order songs were queued in.
+	Fair bool
+
+	Page       int
+	Pages      int
+	TrackCount int
+
+	// TotalSeconds adds up the songs of known length; UnknownCount counts
+	// the others, live streams aside.
+	TotalSeconds int
+	UnknownCount int
+}
+
+// Queue renders a page of the queue. Page is counted from 0.
+func Queue(guildID string, page QueuePage) Message {
+	line := linkedSongLine
+	if GuildStyle(guildID) == StylePlain {
+		line = songLine
+	}
+
+	body := ""
+	if page.Current != nil {
+		body += i18n.T(guildID, "queue.now_playing",
+			page.Current.Title, page.Current.Artist, songDuration(guildID, page.Current), requestedBy(guildID, page.Current))
+	}
+	if len(page.Upcoming) > 0 {
+		if page.Fair {
+			body += i18n.T(guildID, "queue.up_next_fair")
+		} else {
+			body += i18n.T(guildID, "queue.up_next")
+		}
+		for idx := range page.Upcoming {
+			entry := line(guildID, page.Offset+idx+1, &page.Upcoming[idx])
+			if idx < len(page.Missing) && page.Missing[idx] {
+				entry = strings.TrimSuffix(entry, "\n") + i18n.T(guildID, "queue.missing_file") + "\n"
+			}
+			body += entry
+		}
+	}
+	body += i18n.T(guildID, "queue.footer", page.Page+1, page.Pages, page.TrackCount, Duration(guildID, page.TotalSeconds))
+	if page.UnknownCount > 0 {
+		body += i18n.T(guildID, "queue.unknown_length", page.UnknownCount)
+	}
+	if page.MissingCount > 0 {
+		body += "\n" + i18n.T(guildID, "queue.missing_hint", page.MissingCount)
+	}
+
+	if GuildStyle(guildID) == StylePlain {
+		return Message{Content: plain(i18n.T(guildID, "queue.header") + body)}
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:       i18n.T(guildID, "render.queue_title"),
+		Description: body,
+	}
+	if page.Current != nil && page.Current.ThumbnailURL != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: page.Current.ThumbnailURL}
+	}
+	return embed(guildID, e)
+}
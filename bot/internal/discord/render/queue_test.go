@@ -0,0 +1,111 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"musicbot/internal/state"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// sampleQueue is a page of a queue with a bit of everything: a song playing
+// with its thumbnail, a linked song with metadata, a live stream, a song of
+// unknown length and one whose file is gone.
+func sampleQueue() QueuePage {
+	requested := time.Date(2024, 3, 1, 20, 15, 0, 0, time.UTC)
+	return QueuePage{
+		Current: &state.Song{
+			Title:        "Intro",
+			Artist:       "Opening Act",
+			URL:          "https://soundcloud.com/opening-act/intro",
+			Duration:     95,
+			ThumbnailURL: "https://i1.sndcdn.com/artworks-intro.jpg",
+			RequesterID:  "111",
+			RequestedAt:  requested,
+		},
+		Upcoming: []state.Song{
+			{
+				Title:       "Song [Remastered]",
+				Artist:      "Band",
+				URL:         "https://www.youtube.com/watch?v=abc(1)",
+				Platform:    "youtube.com",
+				Duration:    245,
+				UploadDate:  "2019-06-01",
+				ViewCount:   12_400_000,
+				RequesterID: "222",
+				RequestedAt: requested.Add(time.Minute),
+			},
+			{Title: "Radio", Artist: "Station", URL: "https://twitch.tv/station", IsStream: true, RequesterID: "111"},
+			{Title: "Upload", Artist: "Someone", FilePath: "upload.mp3"},
+			{Title: "Gone", Artist: "Band", URL: "https://soundcloud.com/band/gone", Duration: 3725},
+		},
+		Offset:       10,
+		Missing:      []bool{false, false, false, true},
+		MissingCount: 1,
+		Page:         1,
+		Pages:        3,
+		TrackCount:   25,
+		TotalSeconds: 5400,
+		UnknownCount: 1,
+	}
+}
+
+// golden compares got with testdata/name, or rewrites it with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from %s:\n%s", t.Name(), path, got)
+	}
+}
+
+func TestQueueGolden(t *testing.T) {
+	SetVersion("1.2.3")
+	SetShard(0, 2)
+	t.Cleanup(func() {
+		SetVersion("dev")
+		SetShard(0, 1)
+	})
+
+	t.Run("rich", func(t *testing.T) {
+		const guildID = "queue-rich"
+		SetGuildStyle(guildID, StyleRich)
+		message := Queue(guildID, sampleQueue())
+		if message.Content != "" {
+			t.Errorf("rich queue has content %q, want only an embed", message.Content)
+		}
+		var got bytes.Buffer
+		encoder := json.NewEncoder(&got)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(message.Embeds); err != nil {
+			t.Fatal(err)
+		}
+		golden(t, "queue_rich.golden", got.Bytes())
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		const guildID = "queue-plain"
+		SetGuildStyle(guildID, StylePlain)
+		message := Queue(guildID, sampleQueue())
+		if len(message.Embeds) != 0 {
+			t.Errorf("plain queue has %d embeds, want none", len(message.Embeds))
+		}
+		golden(t, "queue_plain.golden", []byte(message.Content+"\n"))
+	})
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/listening"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sessionListenersShown bounds the listeners named in a session summary.
+const sessionListenersShown = 10
+
+// SessionSummary renders what was recorded of a listening session. The full
+// track list goes in the CSV attached next to it, so only as many tracks are
+// listed as fit.
+func SessionSummary(guildID string, summary listening.Summary) Message {
+	session := summary.Session
+
+	overview := i18n.T(guildID, "session.summary_overview",
+		session.VoiceChannelID, session.StartedBy, session.StartedAt.Unix(),
+		Duration(guildID, int(summary.Duration().Seconds())),
+		len(summary.Tracks), session.PeakListeners, len(summary.Listeners))
+
+	var tracks []string
+	for idx, track := range summary.Tracks {
+		tracks = append(tracks, i18n.T(guildID, "session.summary_track",
+			idx+1, track.StartedAt.Unix(), track.Title, track.PeakListeners, track.Reactions))
+	}
+	trackList := strings.Join(tracks, "\n")
+	if trackList == "" {
+		trackList = i18n.T(guildID, "session.summary_no_tracks")
+	}
+
+	var listeners []string
+	for idx, listener := range summary.Listeners {
+		if idx == sessionListenersShown {
+			listeners = append(listeners, i18n.T(guildID, "session.summary_more_listeners", len(summary.Listeners)-sessionListenersShown))
+			break
+		}
+		listeners = append(listeners, i18n.T(guildID, "session.summary_listener", listener.UserID, listener.Samples))
+	}
+	listenerList := strings.Join(listeners, "\n")
+	if listenerList == "" {
+		listenerList = i18n.T(guildID, "session.summary_no_listeners")
+	}
+
+	title := i18n.T(guildID, "session.summary_title")
+	tracksName := i18n.T(guildID, "session.summary_tracks")
+	listenersName := i18n.T(guildID, "session.summary_listeners")
+
+	if GuildStyle(guildID) == StylePlain {
+		text := fmt.Sprintf("**%s**\n%s\n\n**%s**\n%s\n\n**%s**\n%s",
+			title, overview, tracksName, trackList, listenersName, listenerList)
+		return Message{Content: truncate(plain(text), maxContent)}
+	}
+
+	return embed(guildID, &discordgo.MessageEmbed{
+		Title:       title,
+		Description: overview,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: tracksName, Value: truncate(trackList, maxEmbedFieldValue)},
+			{Name: listenersName, Value: truncate(listenerList, maxEmbedFieldValue)},
+		},
+	})
+}
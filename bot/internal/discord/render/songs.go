@@ -0,0 +1,68 @@
+package render
+
+import (
+	"fmt"
+	"musicbot/internal/i18n"
+	"musicbot/internal/state"
+	"strings"
+)
+
+// Duration formats seconds as m:ss, or h:mm:ss from an hour up.
+func Duration(guildID string, seconds int) string {
+	if seconds <= 0 {
+		return i18n.T(guildID, "common.unknown_duration")
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// songDuration formats a song's length, or marks it live for a stream. A
+// song the downloader couldn't tell the length of shows a placeholder
+// rather than a length of zero.
+func songDuration(guildID string, song *state.Song) string {
+	if song.IsStream {
+		return i18n.T(guildID, "common.live")
+	}
+	if song.PlayLength() <= 0 {
+		return i18n.T(guildID, "common.unknown_length")
+	}
+	return Duration(guildID, song.PlayLength())
+}
+
+// requestedBy mentions whoever queued song and, as a Discord timestamp that
+// keeps itself up to date, when. It is empty for songs queued before
+// requesters were recorded. Messages that use it must not ping.
+func requestedBy(guildID string, song *state.Song) string {
+	if song.RequesterID == "" {
+		return ""
+	}
+	if song.RequestedAt.IsZero() {
+		return i18n.T(guildID, "queue.requested_by", song.RequesterID)
+	}
+	return i18n.T(guildID, "queue.requested_by_at", song.RequestedAt.Unix(), song.RequesterID)
+}
+
+// songLine is one numbered song of a listing.
+func songLine(guildID string, number int, song *state.Song) string {
+	return fmt.Sprintf("**%d.** %s - %s (%s)%s\n",
+		number, song.Title, song.Artist, songDuration(guildID, song), requestedBy(guildID, song))
+}
+
+// linkedSongLine is songLine with the title linked to the song, showing its
+// metadata when hovered. Only embeds render the link.
+func linkedSongLine(guildID string, number int, song *state.Song) string {
+	target := link(song.URL)
+	if target == "" {
+		return songLine(guildID, number, song)
+	}
+
+	title := strings.NewReplacer("[", "(", "]", ")").Replace(song.Title)
+	target = strings.NewReplacer("(", "%28", ")", "%29").Replace(target)
+	if metadata := songMetadata(guildID, song); metadata != "" {
+		target += ` "` + strings.ReplaceAll(metadata, `"`, "'") + `"`
+	}
+	return fmt.Sprintf("**%d.** [%s](%s) - %s (%s)%s\n",
+		number, title, target, song.Artist, songDuration(guildID, song), requestedBy(guildID, song))
+}
@@ -0,0 +1,49 @@
+// Package render turns the bot's listings into messages in the style each
+// guild chose with /style: rich embeds with thumbnails and color accents, or
+// plain minimal text that reads well in a screen reader.
+package render
+
+import "sync"
+
+type Style string
+
+const (
+	StyleRich  Style = "rich"
+	StylePlain Style = "plain"
+
+	DefaultStyle = StyleRich
+)
+
+// Styles lists the styles in the order /style offers them.
+var Styles = []Style{StyleRich, StylePlain}
+
+// ParseStyle returns the style called name.
+func ParseStyle(name string) (Style, bool) {
+	for _, style := range Styles {
+		if string(style) == name {
+			return style, true
+		}
+	}
+	return DefaultStyle, false
+}
+
+var (
+	guildStyles = make(map[string]Style)
+	mu          sync.RWMutex
+)
+
+// GuildStyle returns the style guildID renders in.
+func GuildStyle(guildID string) Style {
+	mu.RLock()
+	defer mu.RUnlock()
+	if style, ok := guildStyles[guildID]; ok {
+		return style
+	}
+	return DefaultStyle
+}
+
+func SetGuildStyle(guildID string, style Style) {
+	mu.Lock()
+	defer mu.Unlock()
+	guildStyles[guildID] = style
+}
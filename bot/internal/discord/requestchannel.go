@@ -0,0 +1,37 @@
+package discord
+
+import (
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// checkRequestChannel turns the request channel of g off if it no longer
+// exists, as when it was deleted while the bot was down.
+func (c *Client) checkRequestChannel(g *discordgo.GuildCreate) {
+	guild := c.guilds.Get(g.ID)
+	channelID := guild.State.GetRequestChannel()
+	if channelID == "" {
+		return
+	}
+
+	for _, channel := range g.Channels {
+		if channel.ID == channelID {
+			return
+		}
+	}
+
+	logger.Info.Printf("Request channel %s of guild %s no longer exists", channelID, g.ID)
+	c.eventHandler.dropRequestChannel(guild)
+}
+
+// dropRequestChannel turns off the request channel of a guild, which was
+// deleted, and tells the announce channel.
+func (e *EventHandler) dropRequestChannel(guild *guilds.Guild) {
+	if err := e.dbManager.DeleteRequestChannel(guild.ID); err != nil {
+		logger.Error.Printf("Failed to clear request channel of guild %s: %v", guild.ID, err)
+	}
+	guild.State.SetRequestChannel("")
+	e.announce(guild, "requestchannel.deleted", e.adminMention(guild.ID))
+}
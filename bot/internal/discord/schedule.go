@@ -0,0 +1,139 @@
+package discord
+
+import (
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/state"
+	"time"
+)
+
+// scheduledPlaylistLimit is how many tracks of a scheduled playlist are
+// queued, the same default /playlist uses.
+const scheduledPlaylistLimit = 20
+
+// scheduleRunner carries out /schedule jobs for the scheduler, announcing
+// in the guild's announce channel how each one went.
+type scheduleRunner struct {
+	events *EventHandler
+}
+
+func (r *scheduleRunner) Prefetch(job config.Schedule) {
+	e := r.events
+	if e.stateManager.IsShuttingDown() {
+		return
+	}
+
+	musicManager := e.guilds.Get(job.GuildID).Music
+	if err := musicManager.Prefetch(job.URL, musicManager.DownloadLimits(job.GuildID)); err != nil {
+		logger.Error.Printf("Failed to prefetch %s for schedule %d: %v", job.URL, job.ID, err)
+	}
+}
+
+// Run queues the job's URL. If the guild's music is playing, the job queues
+// behind it, or with Preempt replaces the current song. Otherwise the bot
+// moves to the job's channel first.
+func (r *scheduleRunner) Run(job config.Schedule) {
+	e := r.events
+	if e.stateManager.IsShuttingDown() {
+		return
+	}
+
+	guild := e.guilds.Get(job.GuildID)
+	playing := guild.State.GetBotState() == state.StateDJ &&
+		(guild.Music.IsPlaying() || guild.Music.IsPaused())
+
+	if !playing {
+		if err := r.moveTo(guild, job.ChannelID); err != nil {
+			logger.Error.Printf("Schedule %d failed to join channel %s: %v", job.ID, job.ChannelID, err)
+			e.announce(guild, "schedule.join_failed", job.URL, job.ChannelID)
+			return
+		}
+	}
+
+	limits := guild.Music.DownloadLimits(job.GuildID)
+	notifier := &scheduleNotifier{events: e, guild: guild, job: job}
+
+	var err error
+	switch {
+	case job.Playlist:
+		limit := scheduledPlaylistLimit
+		if remaining, capErr := guild.Music.RemainingCapacity(job.RequestedBy); capErr == nil {
+			limit = min(limit, remaining)
+		}
+		err = guild.Music.RequestPlaylist(job.URL, job.RequestedBy, limit, limits, notifier)
+	case playing && job.Preempt:
+		notifier.skipCurrent = true
+		err = guild.Music.RequestSongNext(job.URL, job.RequestedBy, limits, notifier)
+	default:
+		err = guild.Music.RequestSong(job.URL, job.RequestedBy, limits, notifier)
+	}
+	if err != nil {
+		notifier.Failed(err)
+		return
+	}
+
+	switch {
+	case !playing:
+		e.announce(guild, "schedule.starting", job.URL, job.RequestedBy, job.ChannelID)
+	case job.Preempt && !job.Playlist:
+		e.announce(guild, "schedule.preempting", job.URL, job.RequestedBy)
+	default:
+		e.announce(guild, "schedule.queued_behind", job.URL, job.RequestedBy)
+	}
+}
+
+// moveTo takes the bot to channelID, stopping the radio on the way.
+func (r *scheduleRunner) moveTo(guild *guilds.Guild, channelID string) error {
+	if guild.State.GetCurrentChannel() == channelID {
+		return nil
+	}
+
+	guild.State.SetManualOperationActive(true)
+	defer guild.State.SetManualOperationActive(false)
+
+	r.events.guilds.StopPlayback(guild.ID)
+
+	time.Sleep(500 * time.Millisecond)
+
+	if err := guild.Voice.MoveTo(guild.ID, channelID); err != nil {
+		return err
+	}
+	r.events.updateVoiceMetrics()
+
+	time.Sleep(500 * time.Millisecond)
+	return nil
+}
+
+// scheduleNotifier announces how a scheduled request ended. With
+// skipCurrent set, the song playing is skipped once the scheduled one is
+// queued after it.
+type scheduleNotifier struct {
+	events      *EventHandler
+	guild       *guilds.Guild
+	job         config.Schedule
+	skipCurrent bool
+}
+
+func (n *scheduleNotifier) Queued(*state.Song, music.ETA) {
+	if n.skipCurrent {
+		n.guild.Music.Stop()
+	}
+}
+
+func (n *scheduleNotifier) Failed(err error) {
+	var duplicateErr *music.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		return
+	}
+	logger.Error.Printf("Schedule %d failed: %v", n.job.ID, err)
+	n.events.announce(n.guild, "schedule.failed", n.job.URL, err)
+}
+
+func (n *scheduleNotifier) PlaylistProgress(done, failed, total int) {}
+
+func (n *scheduleNotifier) PlaylistDone(added, duplicates int, skipped music.Skipped) {
+	logger.Info.Printf("Schedule %d queued %d tracks (%d duplicates, %d failed)", n.job.ID, added, duplicates, skipped.Total())
+}
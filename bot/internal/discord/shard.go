@@ -0,0 +1,194 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"musicbot/internal/config"
+	"musicbot/internal/discord/render"
+	"musicbot/internal/i18n"
+	"musicbot/internal/listening"
+	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// guildRemoveTimeout bounds disconnecting from a guild the bot was removed
+// from.
+const guildRemoveTimeout = 10 * time.Second
+
+// resolveShardCount asks Discord how many shards to use when shard_count is
+// "auto".
+func (c *Client) resolveShardCount() error {
+	if !c.shardCount.Auto {
+		return nil
+	}
+
+	gateway, err := c.session.GatewayBot()
+	if err != nil {
+		return fmt.Errorf("failed to get the recommended shard count: %w", err)
+	}
+	if c.session.ShardID >= gateway.Shards {
+		return &config.ValidationError{
+			Key:    "shard_id",
+			Value:  fmt.Sprint(c.session.ShardID),
+			Reason: fmt.Sprintf("Discord recommends %d shards", gateway.Shards),
+		}
+	}
+
+	logger.Info.Printf("Discord recommends %d shards", gateway.Shards)
+	c.session.ShardCount = gateway.Shards
+	return nil
+}
+
+// handleGuildCreate sets a guild up when the gateway delivers it, which is
+// how a shard learns the guilds it serves: at startup, when the bot is added
+// to a guild, and when a guild comes back after an outage. Guilds with an
+// idle channel go to it.
+func (c *Client) handleGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.Unavailable {
+		return
+	}
+
+	c.availableMu.Lock()
+	seen := c.available[g.ID]
+	c.available[g.ID] = true
+	c.availableMu.Unlock()
+	if seen {
+		return
+	}
+
+	guild := c.guilds.Get(g.ID)
+	c.checkRequestChannel(g)
+	metrics.SetQueueLengthFunc(g.ID, func() int {
+		return len(guild.Music.GetUpcomingItems())
+	})
+
+	if c.stateManager.IsShuttingDown() || guild.State.IsConnected() {
+		return
+	}
+	if guild.State.GetIdleChannel() == "" {
+		logger.Debug.Printf("Guild %s has no idle channel, not joining voice", g.ID)
+		return
+	}
+
+	go func() {
+		session := c.lastRadioSession(g.ID)
+		if err := c.StartIdleMode(g.ID); err != nil {
+			logger.Error.Printf("Failed to start idle mode in guild %s: %v", g.ID, err)
+			return
+		}
+		c.resumeRadio(g.ID, session)
+	}()
+}
+
+// handleGuildDelete forgets a guild the bot left or that became unavailable,
+// so it is set up again if it comes back. A guild that removed the bot is
+// also cleaned up; one that is only unavailable keeps playing once it's back.
+func (c *Client) handleGuildDelete(s *discordgo.Session, g *discordgo.GuildDelete) {
+	c.availableMu.Lock()
+	delete(c.available, g.ID)
+	c.availableMu.Unlock()
+
+	if g.Unavailable || c.stateManager.IsShuttingDown() {
+		return
+	}
+	go c.removeGuild(g.ID)
+}
+
+// removeGuild lets go of everything held for a guild the bot was removed
+// from: its music, voice connection, radio, listening session and follow
+// timer. With purge_on_leave its settings are deleted as well, so adding the
+// bot back starts from scratch.
+func (c *Client) removeGuild(guildID string) {
+	logger.Info.Printf("Removed from guild %s, cleaning up", guildID)
+
+	c.eventHandler.stopFollowTimer(guildID)
+
+	if _, err := c.listening.End(guildID); err != nil && !errors.Is(err, listening.ErrNotActive) {
+		logger.Error.Printf("Failed to end the listening session of removed guild %s: %v", guildID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), guildRemoveTimeout)
+	defer cancel()
+	if err := c.guilds.Remove(ctx, guildID); err != nil {
+		logger.Error.Printf("Failed to shut down voice in removed guild %s: %v", guildID, err)
+	}
+	metrics.RemoveQueueLengthFunc(guildID)
+	c.presence.Refresh()
+
+	if err := c.dbManager.ClearRadioSession(guildID); err != nil {
+		logger.Error.Printf("Failed to clear the radio session of removed guild %s: %v", guildID, err)
+	}
+
+	if c.stateManager.GetConfig().PurgeOnLeave {
+		c.purgeGuild(guildID)
+	}
+}
+
+// purgeGuild deletes the settings of guildID and puts the ones held in
+// memory back to their defaults. The idle channel goes back to the one in
+// the config file.
+func (c *Client) purgeGuild(guildID string) {
+	jobs, err := c.scheduler.List(guildID)
+	if err != nil {
+		logger.Error.Printf("Failed to list the schedules of removed guild %s: %v", guildID, err)
+	}
+	for _, job := range jobs {
+		if _, err := c.scheduler.Remove(guildID, job.ID); err != nil {
+			logger.Error.Printf("Failed to remove schedule %d of removed guild %s: %v", job.ID, guildID, err)
+		}
+	}
+
+	if err := c.dbManager.PurgeGuild(guildID); err != nil {
+		logger.Error.Printf("Failed to purge the settings of removed guild %s: %v", guildID, err)
+		return
+	}
+	if err := c.blacklist.Load(); err != nil {
+		logger.Error.Printf("Failed to reload the blacklist: %v", err)
+	}
+
+	c.permissionManager.ForgetGuild(guildID)
+	c.guilds.ForgetQueueLimits(guildID)
+	c.commandRouter.ForgetDisabledCommands(guildID)
+	i18n.SetGuildLocale(guildID, i18n.DefaultLocale)
+	render.SetGuildStyle(guildID, render.DefaultStyle)
+
+	guildState := c.stateManager.Guild(guildID)
+	guildState.SetIdleChannel(c.fileIdleChannel(guildID))
+	guildState.SetAlwaysOnChannel("")
+	guildState.SetFilter("")
+	guildState.SetQueueMode("")
+	guildState.SetRequestChannel("")
+
+	logger.Info.Printf("Purged the settings of removed guild %s", guildID)
+}
+
+// fileIdleChannel returns the idle channel config.json gives guildID, or "".
+func (c *Client) fileIdleChannel(guildID string) string {
+	if c.configPath == "" {
+		return ""
+	}
+
+	fileConfig, err := config.LoadFromFile(c.configPath)
+	if err != nil {
+		logger.Error.Printf("Failed to read the idle channel of guild %s from the config file: %v", guildID, err)
+		return ""
+	}
+	for _, guild := range fileConfig.Guilds {
+		if guild.ID == guildID {
+			return guild.IdleChannel
+		}
+	}
+	return ""
+}
+
+// guildAvailable reports whether guildID has been delivered on this shard.
+func (c *Client) guildAvailable(guildID string) bool {
+	c.availableMu.Lock()
+	defer c.availableMu.Unlock()
+	return c.available[guildID]
+}
@@ -0,0 +1,114 @@
+// Package discordapi describes the part of a Discord session the bot calls
+// when it handles commands and voice, so tests can stand in for Discord.
+package discordapi
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is what commands, voice and permission checks need from Discord.
+// The methods are those of *discordgo.Session; New adapts one.
+type Session interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+
+	ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, options ...discordgo.RequestOption) ([]*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendReply(channelID string, content string, reference *discordgo.MessageReference, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+	ChannelMessagesBulkDelete(channelID string, messages []string, options ...discordgo.RequestOption) error
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error
+	MessageReactionRemove(channelID, messageID, emojiID, userID string, options ...discordgo.RequestOption) error
+
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error)
+	Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error)
+	GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	GuildRoles(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Role, error)
+	RequestWithBucketID(method, urlStr string, data interface{}, bucketID string, options ...discordgo.RequestOption) ([]byte, error)
+
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID string, guildID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID, guildID, cmdID string, options ...discordgo.RequestOption) error
+
+	ChannelVoiceJoin(guildID, channelID string, mute, deaf bool) (*discordgo.VoiceConnection, error)
+	// ChannelVoiceLeave disconnects vc, a connection ChannelVoiceJoin made.
+	ChannelVoiceLeave(vc *discordgo.VoiceConnection) error
+	HeartbeatLatency() time.Duration
+
+	// Cache is the state the gateway keeps: guilds, channels, voice states
+	// and the bot's own user.
+	Cache() *discordgo.State
+	// Shard returns the shard this session is and how many there are.
+	Shard() (id, count int)
+}
+
+type session struct {
+	*discordgo.Session
+}
+
+// New returns s as a Session.
+func New(s *discordgo.Session) Session {
+	return session{s}
+}
+
+func (s session) Cache() *discordgo.State {
+	return s.State
+}
+
+func (s session) Shard() (id, count int) {
+	return s.ShardID, s.ShardCount
+}
+
+func (s session) ChannelVoiceLeave(vc *discordgo.VoiceConnection) error {
+	return vc.Disconnect()
+}
+
+// UserOption returns the user option names, with everything Discord knows
+// about them when it can be fetched. It is UserValue for a Session.
+func UserOption(s Session, option *discordgo.ApplicationCommandInteractionDataOption) *discordgo.User {
+	user := option.UserValue(nil)
+	if fetched, err := s.User(user.ID); err == nil {
+		return fetched
+	}
+	return user
+}
+
+// ChannelOption returns the channel option names, from the cache or else
+// from Discord. It is ChannelValue for a Session.
+func ChannelOption(s Session, option *discordgo.ApplicationCommandInteractionDataOption) *discordgo.Channel {
+	channel := option.ChannelValue(nil)
+	if cached, err := s.Cache().Channel(channel.ID); err == nil {
+		return cached
+	}
+	if fetched, err := s.Channel(channel.ID); err == nil {
+		return fetched
+	}
+	return channel
+}
+
+// RoleOption returns the role option names in guildID, from the cache or
+// else from Discord. It is RoleValue for a Session.
+func RoleOption(s Session, option *discordgo.ApplicationCommandInteractionDataOption, guildID string) *discordgo.Role {
+	role := option.RoleValue(nil, "")
+	if cached, err := s.Cache().Role(guildID, role.ID); err == nil {
+		return cached
+	}
+	if roles, err := s.GuildRoles(guildID); err == nil {
+		for _, r := range roles {
+			if r.ID == role.ID {
+				return r
+			}
+		}
+	}
+	return role
+}
@@ -0,0 +1,365 @@
+package guilds
+
+import (
+	"context"
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/logger"
+	"musicbot/internal/music"
+	"musicbot/internal/radio"
+	"musicbot/internal/socket"
+	"musicbot/internal/state"
+	"musicbot/internal/voice"
+	"sort"
+	"sync"
+)
+
+// Guild bundles the voice connection, radio and music of one guild with its
+// state.
+type Guild struct {
+	ID    string
+	State *state.Guild
+	Voice *voice.Manager
+	Radio *radio.Manager
+	Music *music.Manager
+}
+
+// Registry creates and holds the per-guild managers. Guilds missing from the
+// config are created on first use, without an idle channel.
+type Registry struct {
+	session      discordapi.Session
+	stateManager *state.Manager
+	streams      *radio.StreamManager
+	dbManager    *config.DatabaseManager
+	socketClient *socket.Client
+	guilds       map[string]*Guild
+	limits       config.QueueLimits
+	guildLimits  map[string]config.QueueLimits
+	radioNotice  func(guildID, channelID string, playing bool)
+	musicHook    func(*music.Manager)
+	mu           sync.Mutex
+}
+
+func NewRegistry(session discordapi.Session, stateManager *state.Manager, streams *radio.StreamManager, dbManager *config.DatabaseManager, socketClient *socket.Client) *Registry {
+	limits, err := dbManager.GetQueueLimits()
+	if err != nil {
+		logger.Error.Printf("Failed to load queue limits, using defaults: %v", err)
+		limits = config.DefaultQueueLimits()
+	}
+	logger.Info.Printf("Queue limits: %d total, %d per user", limits.MaxQueueLength, limits.MaxUserQueued)
+
+	guildLimits, err := dbManager.GetGuildQueueLimits()
+	if err != nil {
+		logger.Error.Printf("Failed to load the queue limits guilds set: %v", err)
+		guildLimits = make(map[string]config.QueueLimits)
+	}
+
+	return &Registry{
+		session:      session,
+		stateManager: stateManager,
+		streams:      streams,
+		dbManager:    dbManager,
+		socketClient: socketClient,
+		guilds:       make(map[string]*Guild),
+		limits:       limits,
+		guildLimits:  guildLimits,
+	}
+}
+
+func (r *Registry) Get(guildID string) *Guild {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if guild, ok := r.guilds[guildID]; ok {
+		return guild
+	}
+
+	guildState := r.stateManager.Guild(guildID)
+	voiceManager := voice.NewManager(r.session, guildState)
+	radioManager := radio.NewManager(guildState, r.streams)
+	r.setRadioNotice(guildID, radioManager)
+	musicManager := music.NewManager(r.stateManager, r.dbManager, r.socketClient, &music.Session{
+		State:           guildState,
+		Radio:           radioManager,
+		VoiceConnection: voiceManager.GetVoiceConnection,
+	})
+	musicManager.SetQueueLimits(r.queueLimitsLocked(guildID))
+	if r.musicHook != nil {
+		r.musicHook(musicManager)
+	}
+
+	guild := &Guild{
+		ID:    guildID,
+		State: guildState,
+		Voice: voiceManager,
+		Radio: radioManager,
+		Music: musicManager,
+	}
+	r.guilds[guildID] = guild
+	return guild
+}
+
+// SetRadioNotice installs a callback told whenever the radio of any guild,
+// existing or created later, starts or stops.
+func (r *Registry) SetRadioNotice(notice func(guildID, channelID string, playing bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.radioNotice = notice
+	for guildID, guild := range r.guilds {
+		r.setRadioNotice(guildID, guild.Radio)
+	}
+}
+
+// setRadioNotice must be called with mu held.
+func (r *Registry) setRadioNotice(guildID string, radioManager *radio.Manager) {
+	if r.radioNotice == nil {
+		return
+	}
+	notice := r.radioNotice
+	radioManager.SetNotice(func(channelID string, playing bool) {
+		notice(guildID, channelID, playing)
+	})
+}
+
+// SetMusicHook installs a function that sets up the music manager of every
+// guild, existing or created later, e.g. with its notices.
+func (r *Registry) SetMusicHook(hook func(*music.Manager)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.musicHook = hook
+	for _, guild := range r.guilds {
+		hook(guild.Music)
+	}
+}
+
+// StopPlayback stops the radio and the song playing in guildID. Every other
+// guild plays on.
+func (r *Registry) StopPlayback(guildID string) {
+	guild := r.Get(guildID)
+	guild.Radio.Stop()
+	guild.Music.Stop()
+}
+
+// StopAllPlayback stops the radio and music of every guild. It is only for
+// shutting down; anything done on behalf of one guild uses StopPlayback.
+func (r *Registry) StopAllPlayback() {
+	for _, guild := range r.All() {
+		guild.Music.Stop()
+		guild.Radio.Stop()
+	}
+}
+
+// QueueLimits returns the limits guildID's queue is held to: its own if it
+// set any, otherwise the defaults.
+func (r *Registry) QueueLimits(guildID string) config.QueueLimits {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queueLimitsLocked(guildID)
+}
+
+// queueLimitsLocked must be called with mu held.
+func (r *Registry) queueLimitsLocked(guildID string) config.QueueLimits {
+	if limits, ok := r.guildLimits[guildID]; ok {
+		return limits
+	}
+	return r.limits
+}
+
+// DefaultQueueLimits returns the limits of every guild that hasn't set its
+// own.
+func (r *Registry) DefaultQueueLimits() config.QueueLimits {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limits
+}
+
+// SetQueueLimits applies limits to guildID alone. Every other guild keeps
+// its own.
+func (r *Registry) SetQueueLimits(guildID string, limits config.QueueLimits) {
+	r.mu.Lock()
+	r.guildLimits[guildID] = limits
+	r.mu.Unlock()
+
+	r.Get(guildID).Music.SetQueueLimits(limits)
+	logger.ForGuild(guildID).Info("Queue limits changed", "total", limits.MaxQueueLength, "per_user", limits.MaxUserQueued)
+}
+
+// ForgetQueueLimits puts guildID back on the defaults, after its settings
+// were purged.
+func (r *Registry) ForgetQueueLimits(guildID string) {
+	r.mu.Lock()
+	delete(r.guildLimits, guildID)
+	guild, ok := r.guilds[guildID]
+	limits := r.limits
+	r.mu.Unlock()
+
+	if ok {
+		guild.Music.SetQueueLimits(limits)
+	}
+}
+
+// SetDefaultQueueLimits applies changed defaults to the music of every guild
+// without limits of its own, existing or created later.
+func (r *Registry) SetDefaultQueueLimits(limits config.QueueLimits) {
+	r.mu.Lock()
+	r.limits = limits
+	var affected []*Guild
+	for guildID, guild := range r.guilds {
+		if _, ok := r.guildLimits[guildID]; !ok {
+			affected = append(affected, guild)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, guild := range affected {
+		guild.Music.SetQueueLimits(limits)
+	}
+	logger.Info.Printf("Queue limits: %d total, %d per user", limits.MaxQueueLength, limits.MaxUserQueued)
+}
+
+// awaiting returns the music manager waiting on a download of url, or nil if
+// no guild asked for it.
+func (r *Registry) awaiting(url string) *music.Manager {
+	for _, guild := range r.All() {
+		if guild.Music.Awaits(url) {
+			return guild.Music
+		}
+	}
+	return nil
+}
+
+// OnDownloadComplete hands a finished download to the guild that requested
+// it. A download nobody waits on anymore, e.g. of a removed guild, is dropped.
+func (r *Registry) OnDownloadComplete(song *state.Song) {
+	if song == nil {
+		return
+	}
+
+	musicManager := r.awaiting(song.URL)
+	if musicManager == nil {
+		logger.Info.Printf("Dropping download of %s, no guild is waiting for it", song.URL)
+		return
+	}
+	if err := musicManager.OnDownloadComplete(song); err != nil {
+		logger.Error.Printf("Failed to handle download completion: %v", err)
+	}
+}
+
+// OnDownloadFailed hands a failed download to the guild that requested it.
+func (r *Registry) OnDownloadFailed(url, reason string) {
+	musicManager := r.awaiting(url)
+	if musicManager == nil {
+		logger.Info.Printf("Dropping failed download of %s, no guild is waiting for it: %s", url, reason)
+		return
+	}
+	musicManager.OnDownloadFailed(url, reason)
+}
+
+// ResetPendingDownloads forgets the downloads in flight of every guild,
+// e.g. after the downloader reconnected.
+func (r *Registry) ResetPendingDownloads() {
+	for _, guild := range r.All() {
+		guild.Music.ResetPendingDownloads()
+	}
+}
+
+// QueuedSongIDs returns the IDs of the songs every guild has queued, playing
+// or in its history, so the cache janitor keeps their files.
+func (r *Registry) QueuedSongIDs() []int64 {
+	var ids []int64
+	for _, guild := range r.All() {
+		ids = append(ids, guild.Music.QueuedSongIDs()...)
+	}
+	return ids
+}
+
+// Remove shuts down the music, voice connection and radio of a guild the bot
+// was removed from and forgets them, so the guild starts over if the bot is
+// added back. Its state keeps its settings.
+func (r *Registry) Remove(ctx context.Context, guildID string) error {
+	r.mu.Lock()
+	guild, ok := r.guilds[guildID]
+	delete(r.guilds, guildID)
+	r.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	guild.Music.Release()
+
+	var errs []error
+	if err := guild.Radio.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := guild.Voice.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	guild.State.Reset()
+	return errors.Join(errs...)
+}
+
+// All returns every guild created so far, ordered by ID.
+func (r *Registry) All() []*Guild {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	guilds := make([]*Guild, 0, len(r.guilds))
+	for _, guild := range r.guilds {
+		guilds = append(guilds, guild)
+	}
+	sort.Slice(guilds, func(a, b int) bool {
+		return guilds[a].ID < guilds[b].ID
+	})
+	return guilds
+}
+
+// ConnectedCount returns how many guilds have a voice connection.
+func (r *Registry) ConnectedCount() int {
+	count := 0
+	for _, guild := range r.All() {
+		if guild.State.IsConnected() {
+			count++
+		}
+	}
+	return count
+}
+
+// Shutdown stops the music of every guild first, all at once, so songs end
+// with silence and queues are saved while the voice connections are still up,
+// and then their radios and voice connections.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	guilds := r.All()
+	errs := make([]error, 0, len(guilds))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, guild := range guilds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := guild.Music.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, guild := range guilds {
+		logger.Info.Printf("Shutting down guild %s...", guild.ID)
+		if err := guild.Radio.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if err := guild.Voice.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) Name() string {
+	return "Guilds"
+}
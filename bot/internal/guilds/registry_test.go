@@ -0,0 +1,248 @@
+package guilds
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/music/musictest"
+	"musicbot/internal/radio"
+	"musicbot/internal/state"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestRegistry creates a registry whose guilds start out with the given
+// queues. It is shut down with the test.
+func newTestRegistry(t *testing.T, queues map[string]*state.Song) *Registry {
+	t.Helper()
+	return newTestRegistryOn(t, discordapi.New(&discordgo.Session{State: discordgo.NewState()}), queues)
+}
+
+// newTestRegistryOn is newTestRegistry talking to Discord through session.
+func newTestRegistryOn(t *testing.T, session discordapi.Session, queues map[string]*state.Song) *Registry {
+	t.Helper()
+	dm, err := config.NewMemoryDatabaseManager(strings.ReplaceAll(t.Name(), "/", "_"))
+	if err != nil {
+		t.Fatalf("NewMemoryDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	for guildID, song := range queues {
+		musictest.SaveQueue(t, dm, guildID, song)
+	}
+
+	r := NewRegistry(session, state.NewManager(state.Config{}), radio.NewStreamManager(nil), dm, nil)
+	t.Cleanup(func() { r.Shutdown(context.Background()) })
+	return r
+}
+
+// waitPlaying waits until guild's music plays song.
+func waitPlaying(t *testing.T, guild *Guild, title string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if song := guild.Music.GetCurrentSong(); guild.Music.IsPlaying() && song != nil && song.Title == title {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s isn't playing %s", guild.ID, title)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStopPlaybackOnlyStopsItsGuild(t *testing.T) {
+	musictest.FakeFFmpeg(t)
+	vcA, vcB := musictest.VoiceConnection(t), musictest.VoiceConnection(t)
+	r := newTestRegistry(t, map[string]*state.Song{
+		"guildA": musictest.Song(t, "songA"),
+		"guildB": musictest.Song(t, "songB"),
+	})
+
+	a, b := r.Get("guildA"), r.Get("guildB")
+	if err := a.Music.Start(vcA); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Music.Start(vcB); err != nil {
+		t.Fatal(err)
+	}
+	waitPlaying(t, a, "songA")
+	waitPlaying(t, b, "songB")
+	// Give both songs time to start sending audio.
+	time.Sleep(100 * time.Millisecond)
+
+	r.StopPlayback("guildA")
+	if a.Music.IsPlaying() {
+		t.Error("guildA still plays after StopPlayback")
+	}
+	time.Sleep(100 * time.Millisecond)
+	waitPlaying(t, b, "songB")
+
+	r.StopAllPlayback()
+	if a.Music.IsPlaying() || b.Music.IsPlaying() {
+		t.Errorf("playing after StopAllPlayback: guildA %v, guildB %v", a.Music.IsPlaying(), b.Music.IsPlaying())
+	}
+}
+
+func TestRadioMusicSwitchesDoNotLeak(t *testing.T) {
+	const switches = 100
+
+	running := musictest.TrackFFmpeg(t)
+	vc := musictest.VoiceConnection(t)
+	r := newTestRegistry(t, map[string]*state.Song{"guild": musictest.Song(t, "song")})
+	guild := r.Get("guild")
+	// The radio only plays while connected. ffmpeg fetches HLS streams
+	// itself, so the fake one plays it.
+	guild.State.SetConnected(true)
+	guild.State.SetRadioStream("http://radio.invalid/live.m3u8")
+
+	// Each switch waits for the new producer's ffmpeg, so every one of them
+	// has a process to clean up.
+	waitFFmpeg := func(n int, what string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for running() != 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("switch %d: %d ffmpeg processes run for %s, want 1", n, running(), what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	before := runtime.NumGoroutine()
+	for n := range switches {
+		if err := guild.Radio.Start(vc); err != nil {
+			t.Fatalf("switch %d: starting the radio: %v", n, err)
+		}
+		waitFFmpeg(n, "the radio")
+
+		// Starting the song stops the radio first.
+		if err := guild.Music.Start(vc); err != nil {
+			t.Fatalf("switch %d: starting music: %v", n, err)
+		}
+		if guild.Radio.IsPlaying() {
+			t.Fatalf("switch %d: the radio still plays under the song", n)
+		}
+		waitFFmpeg(n, "the song")
+
+		if _, ok := guild.Music.Suspend(); !ok {
+			t.Fatalf("switch %d: no song was playing", n)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before || running() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("after %d switches %d goroutines are left of %d and %d ffmpeg processes run", switches, runtime.NumGoroutine(), before, running())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// voiceSession hands out voice connections that are ready straight away and
+// take the frames sent to them until they are left.
+type voiceSession struct {
+	discordapi.Session
+
+	cache *discordgo.State
+
+	mu   sync.Mutex
+	open map[*discordgo.VoiceConnection]chan struct{}
+}
+
+func newVoiceSession() *voiceSession {
+	cache := discordgo.NewState()
+	cache.User = &discordgo.User{ID: "bot"}
+	return &voiceSession{cache: cache, open: make(map[*discordgo.VoiceConnection]chan struct{})}
+}
+
+func (s *voiceSession) Cache() *discordgo.State {
+	return s.cache
+}
+
+func (s *voiceSession) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	return discordgo.PermissionAll, nil
+}
+
+func (s *voiceSession) ChannelVoiceJoin(guildID, channelID string, mute, deaf bool) (*discordgo.VoiceConnection, error) {
+	vc := &discordgo.VoiceConnection{GuildID: guildID, ChannelID: channelID, Ready: true, OpusSend: make(chan []byte, 2)}
+	left := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-vc.OpusSend:
+			case <-left:
+				return
+			}
+		}
+	}()
+
+	s.mu.Lock()
+	s.open[vc] = left
+	s.mu.Unlock()
+	return vc, nil
+}
+
+func (s *voiceSession) ChannelVoiceLeave(vc *discordgo.VoiceConnection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if left, ok := s.open[vc]; ok {
+		close(left)
+		delete(s.open, vc)
+	}
+	return nil
+}
+
+func (s *voiceSession) openConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.open)
+}
+
+func TestRemovedGuildReleasesPlayback(t *testing.T) {
+	running := musictest.TrackFFmpeg(t)
+	session := newVoiceSession()
+	r := newTestRegistryOn(t, session, map[string]*state.Song{"guild": musictest.Song(t, "song")})
+
+	before := runtime.NumGoroutine()
+	guild := r.Get("guild")
+	if err := guild.Voice.MoveTo("guild", "voice"); err != nil {
+		t.Fatalf("joining voice: %v", err)
+	}
+	if err := guild.Music.Start(guild.Voice.GetVoiceConnection()); err != nil {
+		t.Fatalf("starting music: %v", err)
+	}
+	waitPlaying(t, guild, "song")
+	deadline := time.Now().Add(5 * time.Second)
+	for running() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d ffmpeg processes run for the song, want 1", running())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The Discord client removes a guild that kicked the bot.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Remove(ctx, "guild"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if n := session.openConnections(); n != 0 {
+		t.Errorf("%d voice connections are still open after the guild was removed", n)
+	}
+	if guild.Music.IsPlaying() {
+		t.Error("the song still plays after the guild was removed")
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before || running() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("after the guild was removed %d goroutines are left of %d and %d ffmpeg processes run", runtime.NumGoroutine(), before, running())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"musicbot/internal/logger"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const dependencyTimeout = 1 * time.Second
+
+// Checks are the probes behind /healthz and /readyz. Any nil check is
+// reported as failing, except Dependencies, which is left out of /readyz when
+// the dependency check is skipped.
+type Checks struct {
+	ShuttingDown    func() bool
+	SocketConnected func() bool
+	PingDatabase    func(ctx context.Context) error
+	// Dependencies returns the latest result for each external dependency,
+	// nil for those that passed.
+	Dependencies func() map[string]error
+}
+
+// Server serves liveness and readiness endpoints for systemd and container
+// orchestrators. It starts before Discord connects, so the session check is
+// installed later with SetSessionCheck.
+type Server struct {
+	addr         string
+	checks       Checks
+	httpServer   *http.Server
+	mux          *http.ServeMux
+	sessionCheck func() bool
+	mu           sync.RWMutex
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func NewServer(addr string, checks Checks) *Server {
+	s := &Server{
+		addr:   addr,
+		checks: checks,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+
+	s.mux = mux
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return s
+}
+
+func (s *Server) SetSessionCheck(check func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionCheck = check
+}
+
+// Handle mounts an extra endpoint, such as /metrics, on the same listener.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start binds the listener synchronously so a bad address fails startup, then
+// serves in the background.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	logger.Info.Printf("Health endpoint listening on %s", listener.Addr())
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error.Printf("Health server stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) Name() string {
+	return "HealthServer"
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	checks := make(map[string]string)
+
+	s.mu.RLock()
+	sessionCheck := s.sessionCheck
+	s.mu.RUnlock()
+
+	if sessionCheck != nil && sessionCheck() {
+		checks["discord"] = "ok"
+	} else {
+		checks["discord"] = "session not open"
+	}
+
+	if s.checks.ShuttingDown != nil && !s.checks.ShuttingDown() {
+		checks["shutdown"] = "ok"
+	} else {
+		checks["shutdown"] = "shutting down"
+	}
+
+	writeResponse(w, checks)
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	checks := make(map[string]string)
+
+	if s.checks.SocketConnected != nil && s.checks.SocketConnected() {
+		checks["downloader"] = "ok"
+	} else {
+		checks["downloader"] = "not connected"
+	}
+
+	if s.checks.PingDatabase == nil {
+		checks["database"] = "no check configured"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), dependencyTimeout)
+		err := s.checks.PingDatabase(ctx)
+		cancel()
+
+		if err != nil {
+			checks["database"] = err.Error()
+		} else {
+			checks["database"] = "ok"
+		}
+	}
+
+	if s.checks.Dependencies != nil {
+		for name, err := range s.checks.Dependencies() {
+			if err != nil {
+				checks[name] = err.Error()
+			} else {
+				checks[name] = "ok"
+			}
+		}
+	}
+
+	writeResponse(w, checks)
+}
+
+func writeResponse(w http.ResponseWriter, checks map[string]string) {
+	body := response{Status: "ok", Checks: checks}
+	status := http.StatusOK
+
+	for _, result := range checks {
+		if result != "ok" {
+			body.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Debug.Printf("Failed to write health response: %v", err)
+	}
+}
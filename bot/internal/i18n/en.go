@@ -0,0 +1,635 @@
+package i18n
+
+var english = Bundle{
+	"language.name": "English",
+
+	"common.not_in_voice":         "❌ You need to be in a voice channel.",
+	"common.join_failed":          "❌ Failed to join your voice channel.",
+	"common.join_missing_connect": "❌ I need the Connect permission in <#%s>.",
+	"common.join_missing_speak":   "❌ I need the Speak permission in <#%s>.",
+	"common.join_channel_full":    "❌ <#%s> is full.",
+	"common.busy_other_channel":   "❌ Bot is currently playing music in another channel.",
+	"common.no_song_playing":      "❌ No song is currently playing.",
+	"common.unknown_duration":     "Unknown",
+	"common.unknown_length":       "--:--",
+	"common.live":                 "🔴 LIVE",
+	"common.request_failed":       "❌ Failed to request song: %v",
+	"common.command_failed":       "❌ Something went wrong while running that command.",
+	"download.temporary":          "⚠️ The song is temporarily unavailable (tried %d times). Please try again later.",
+	"download.unavailable":        "❌ The video can't be downloaded: it is private, region-locked or removed.",
+	"download.private":            "❌ The video can't be downloaded: it is private.",
+	"download.region_blocked":     "❌ The video can't be downloaded: it is blocked in this region.",
+	"download.age_restricted":     "❌ The video can't be downloaded: it is age-restricted.",
+	"download.failed":             "❌ Failed to download the song: %s",
+	"download.cancelled":          "⏹️ The download was stopped.",
+	"common.not_your_buttons":     "❌ Only the person who ran this command can use these buttons.",
+
+	"permissions.server_only":  "❌ This command can only be used in a server.",
+	"permissions.check_failed": "❌ Could not verify your permissions. Please try again.",
+	"permissions.denied_title": "🚫 Permission denied",
+	"permissions.denied":       "You need the **%s** role to use `/%s`.",
+	"permissions.dj_only":      "🔒 DJ-only mode is on. You need the **%s** role to use `/%s`.",
+
+	"cooldown.wait": "⏳ Slow down — try again in %ds.",
+	"cooldown.busy": "⏳ `/%s` is already running in this server. Try again when it finishes.",
+
+	"limits.queue_full":               "❌ Queue is full (%d tracks). Try again once some songs have played.",
+	"limits.user_full":                "❌ You already have %d tracks queued. Wait for some of them to play first.",
+	"limits.download_too_long":        "❌ That track is longer than this server's limit of %d minutes.",
+	"limits.download_too_long_length": "❌ That track is %s long, over this server's limit of %d minutes.",
+	"limits.download_too_large":       "❌ That track is larger than this server's limit of %d MB.",
+	"limits.download_override":        "\nDJs can queue it anyway with `allow_long` on /play, and admins can change the limit with /setmaxduration or /setmaxsize.",
+
+	"play.downloading":       "🎵 Downloading song from: %s\n⏳ This may take a moment, you'll hear back when it's queued.",
+	"play.dj_option_denied":  "🔒 Only members with the **%s** role can use `%s`.",
+	"play.queued":            "✅ Added to the queue: **%s**",
+	"play.followup":          "<@%s> %s",
+	"play.duplicate":         "🔁 **%s** is already in the queue at position %d. Use `/play` with `force` to add it again.",
+	"play.duplicate_playing": "🔁 **%s** is already playing. Use `/play` with `force` to add it again.",
+
+	"playfile.uploading":   "📎 Adding **%s** to the queue...",
+	"playfile.queued":      "📎 Queued **%s**.",
+	"playfile.unsupported": "❌ That isn't an audio file I can play. Accepted types: %s",
+	"playfile.too_large":   "❌ That file is larger than this server's limit of %d MB.",
+
+	"exportqueue.done":            "💾 Exported %d tracks. Load them again with `/importqueue`.",
+	"exportqueue.skipped_uploads": "\n⚠️ %d uploaded files were left out, since they can't be fetched again by URL.",
+	"exportqueue.empty":           "❌ There is nothing in the queue to export.",
+
+	"importqueue.starting":       "📥 Importing %d tracks. They are added to the queue as they download...",
+	"importqueue.progress":       "📥 Importing: %d/%d done, %d added",
+	"importqueue.done":           "📥 Imported %d of %d tracks.",
+	"importqueue.duplicates":     "\n⏭️ %d were already in the queue.",
+	"importqueue.blocked":        "\n🚫 %d are blocked on this server.",
+	"importqueue.failed":         "\n⚠️ %d couldn't be downloaded.",
+	"importqueue.stopped":        "\n⏹️ The import stopped with %d tracks left.",
+	"importqueue.malformed":      "\n⚠️ %d lines aren't URLs:",
+	"importqueue.malformed_line": "Line %d: `%s`",
+	"importqueue.malformed_more": "…and %d more",
+	"importqueue.no_urls":        "❌ That file has no URLs in it.",
+	"importqueue.too_large":      "❌ Queue files can be at most %d KB.",
+	"importqueue.too_long":       "❌ Queue files can have at most %d lines.",
+	"importqueue.fetch_failed":   "❌ Failed to read that file.",
+
+	"repair.nothing":  "✅ Every queued song has its file.",
+	"repair.starting": "🔧 Downloading %d missing songs again...",
+	"repair.progress": "🔧 Repairing: %d/%d done, %d repaired",
+	"repair.done":     "🔧 Repaired %d of %d missing songs.",
+	"repair.pending":  "\n⏳ %d were already being downloaded again.",
+	"repair.removed":  "\n🗑️ Removed %d queued songs that couldn't be downloaded again.",
+
+	"playlist.starting":            "📜 Starting playlist download from: %s\n⏳ Downloading up to %d songs. Songs will be added to queue as they download...",
+	"playlist.request_failed":      "❌ Failed to request playlist: %v",
+	"playlist.clamped":             "\n⚠️ You asked for %d songs but only %d fit within the queue limits.",
+	"playlist.done":                "📜 Added %d tracks to the queue.",
+	"playlist.done_duplicates":     "📜 Added %d tracks to the queue (%d duplicates skipped).",
+	"playlist.done_skipped":        "\n⚠️ %d skipped: %s.",
+	"playlist.skip_unavailable":    "%d unavailable",
+	"playlist.skip_private":        "%d private",
+	"playlist.skip_region_blocked": "%d region-locked",
+	"playlist.skip_age_restricted": "%d age-restricted",
+	"playlist.skip_too_long":       "%d too long",
+	"playlist.skip_too_large":      "%d too large",
+	"playlist.skip_failed":         "%d failed to download",
+	"playlist.progress":            "📥 Downloading playlist: %d/%d done, %d failed",
+	"playlist.cancelled":           "⏹️ The playlist download was stopped.",
+	"playlist.in_progress":         "⏳ That playlist is already downloading.",
+
+	"search.unavailable":        "❌ Search service is not available.",
+	"search.searching":          "🔍 Searching %s for: %s\n⏳ Please wait...",
+	"search.failed":             "❌ Failed to search: %v",
+	"search.timeout":            "⏱️ Search is taking longer than expected. Please try again with a different search term.",
+	"search.no_results":         "❌ No results found.",
+	"search.results_header":     "🎵 Search Results:\n\n",
+	"search.invalid_selection":  "❌ Invalid selection format.",
+	"search.expired":            "❌ Search results expired.",
+	"search.retry_button":       "🔁 Search again",
+	"search.downloading":        "🎵 Downloading: %s - %s",
+	"search.downloading_next":   "⏭️ Downloading to play next: %s - %s",
+	"search.play_next_button":   "⏭ %d",
+	"search.queue_all_button":   "Queue all results",
+	"search.queue_all_progress": "📥 Queueing search results (%d/%d): %s - %s",
+	"search.queue_all_done":     "📥 Requested %d of %d search results. Songs will be added to queue as they download...",
+
+	"queue.empty":           "📭 Queue is empty. Use `/play` to add songs!",
+	"queue.header":          "🎵 **Music Queue**\n\n",
+	"queue.now_playing":     "🎧 **Now Playing:**\n**%s** - %s (%s)%s\n\n",
+	"queue.up_next":         "📋 **Up Next:**\n",
+	"queue.up_next_fair":    "📋 **Up Next** (requesters take turns):\n",
+	"queue.footer":          "\n📄 Page %d/%d • %d tracks • %s total",
+	"queue.unknown_length":  " (+%d tracks of unknown length)",
+	"queue.previous":        "◀ Previous",
+	"queue.next":            "Next ▶",
+	"queue.requested_by":    " • <@%s>",
+	"queue.requested_by_at": " • requested <t:%d:R> by <@%s>",
+	"queue.missing_file":    " ⚠️ *file missing*",
+	"queue.missing_hint":    "⚠️ %d tracks have no file and would be skipped. Use /repair to download them again.",
+
+	"skip.not_playing":  "❌ Not currently playing music.",
+	"skip.skipped_last": "⏭️ Skipped current song. No more songs in queue.",
+	"skip.skipped":      "⏭️ Skipped to next song.",
+
+	"eta.line":          "⏱️ Position %d starts in about %s (<t:%d:R>).",
+	"eta.line_at_least": "⏱️ Position %d starts in %s at the earliest (<t:%d:R>). %d song(s) before it have no known length, so it may well be later.",
+	"eta.unknown":       "⏱️ A live stream plays before position %d, so there's no telling when it starts.",
+	"eta.empty":         "📭 Nothing is queued.",
+	"eta.none_yours":    "❌ You have no songs in the queue. Give a position to check another one.",
+	"eta.out_of_range":  "❌ Pick a position between 1 and %d.",
+	"eta.failed":        "❌ Failed to work out when that song plays.",
+	"eta.under_minute":  "less than a minute",
+	"eta.minute":        "1 minute",
+	"eta.minutes":       "%d minutes",
+	"eta.hours":         "%d h %d min",
+
+	"skipto.skipped":        "⏭️ Now playing **%s** - %s\nSkipped the previous song and %d queued before it.",
+	"skipto.skipped_kept":   "⏭️ Now playing **%s** - %s\nMoved the previous song and %d queued before it to the end of the queue.",
+	"skipto.out_of_range":   "❌ There is no song at position %d. Pick a position from 1 to %d.",
+	"skipto.nothing_queued": "❌ There are no songs queued after the current one.",
+	"skipto.failed":         "❌ Failed to skip ahead.",
+
+	"previous.playing": "⏮️ Back to **%s** - %s",
+	"previous.empty":   "❌ No songs have played yet to go back to.",
+	"previous.gone":    "❌ The previous song is no longer downloaded. Queue it again with `/play`.",
+	"previous.failed":  "❌ Failed to go back to the previous song.",
+
+	"history.title":        "🕘 Recently Played",
+	"history.line":         "`%d.` **%s** - %s <t:%d:R>%s",
+	"history.flag_skipped": " ⏭️",
+	"history.footer":       "Use /previous to go back to the latest one.",
+	"history.empty":        "📭 No songs have played yet.",
+
+	"clear.already_empty":         "📭 Queue is already empty.",
+	"clear.downloads_pending":     "⏳ Cannot clear queue while %d songs are downloading. Please wait for downloads to complete.",
+	"clear.failed":                "❌ Failed to clear queue.",
+	"clear.cleared_radio":         "🗑️ Queue cleared successfully. Radio will continue playing.",
+	"clear.cleared_return_failed": "🗑️ Queue cleared, but failed to return to idle channel.",
+	"clear.cleared_returned":      "🗑️ Queue cleared successfully. Returned to idle channel and resumed radio.",
+	"clear.confirm_title":         "Clear the queue?",
+	"clear.confirm":               "This removes %d tracks from the queue.",
+	"clear.confirm_button":        "Clear",
+	"clear.cancel_button":         "Cancel",
+	"clear.cancelled":             "👍 Queue left as it was.",
+	"clear.undo_hint":             "↩️ Changed your mind? You can undo this until <t:%d:T>.",
+	"clear.undo_button":           "Undo",
+	"clear.undo_expired":          "⌛ The cleared queue can no longer be restored.",
+	"clear.undo_failed":           "❌ Failed to restore the queue.",
+	"clear.undo_all_missing":      "❌ None of the cleared songs could be restored, their files are gone.",
+	"clear.restored":              "↩️ Restored %d songs to the queue.",
+	"clear.restored_missing":      "⚠️ %d songs were skipped because their files are gone or the queue is full.",
+
+	"volume.current":     "🔊 Current volume: %d%%",
+	"volume.save_failed": "🔊 Volume set to %d%% but failed to save to database.",
+	"volume.set":         "🔊 Volume set to %d%%",
+
+	"filter.current":     "🎛️ Current filter: **%s**",
+	"filter.set":         "🎛️ Filter set to **%s**. Radio and live streams still play unfiltered.",
+	"filter.set_next":    "🎛️ Filter set to **%s**. It applies from the next song.",
+	"filter.off":         "🎛️ Filter turned off.",
+	"filter.unknown":     "❌ Unknown filter.",
+	"filter.save_failed": "❌ Failed to save the filter.",
+	"filter.disabled":    "❌ Audio filters are disabled on this bot.",
+
+	"nowplaying.dj_no_song":  "🎵 **DJ Mode** - No song currently playing",
+	"nowplaying.playing":     "🎧 **Now Playing:**\n**%s** - %s\n⏱️ Duration: %s%s",
+	"nowplaying.filter":      "\n🎛️ Filter: %s",
+	"nowplaying.up_next":     "\n\n📋 **Up Next:**\n",
+	"nowplaying.radio_named": "📻 **Radio Mode** - Playing: %s",
+	"nowplaying.radio":       "📻 **Radio Mode** - Playing radio stream",
+	"nowplaying.idle_named":  "😴 **Idle Mode** - Playing: %s",
+	"nowplaying.idle":        "😴 **Idle Mode** - Playing radio stream",
+	"nowplaying.unknown":     "❓ **Unknown State** - Not sure what's playing",
+
+	"grab.nothing_playing": "❌ Nothing is playing to grab.",
+	"grab.save_failed":     "❌ Failed to save the track.",
+	"grab.sent":            "📬 Saved and sent to your DMs. Use `/grabs` to see your saved tracks.",
+	"grab.dm_failed":       "📌 Saved, but I couldn't DM you, so here it is. Use `/grabs` to see your saved tracks.",
+	"grab.artist":          "Artist",
+	"grab.unknown_artist":  "Unknown",
+	"grab.duration":        "Duration",
+	"grab.link":            "Link",
+	"grab.footer":          "Grabbed",
+
+	"grabs.title":        "📌 Your Grabs",
+	"grabs.line":         "`%d.` [%s](%s) <t:%d:R>",
+	"grabs.flag_radio":   " 📻",
+	"grabs.queue_button": "Queue %d",
+	"grabs.empty":        "📭 You haven't grabbed anything yet. Use `/grab` while something plays.",
+	"grabs.failed":       "❌ Failed to read your grabs.",
+	"grabs.not_found":    "❌ That grab no longer exists.",
+
+	"pin.nothing_playing":   "❌ Nothing is playing. Give the URL of the track to pin.",
+	"pin.stream":            "❌ Live streams have no file to keep.",
+	"pin.unknown":           "❌ That track isn't in the cache. Play it once, then pin it.",
+	"pin.failed":            "❌ Something went wrong with the pinned tracks.",
+	"pin.pinned":            "📌 Pinned **%s**. It stays in the cache until it is unpinned.",
+	"pin.already_pinned":    "📌 **%s** is already pinned.",
+	"pin.missing_upload":    "⚠️ Its file is missing, and an uploaded file can't be downloaded again.",
+	"pin.redownloading":     "⏳ Its file is missing, so it is being downloaded again.",
+	"pin.redownload_failed": "⚠️ Its file is missing and could not be downloaded again: %s",
+	"pin.unpinned":          "Unpinned **%s**. The cache may remove it again once it is old or the cache is full.",
+	"pin.not_pinned":        "**%s** isn't pinned.",
+	"pins.none":             "No tracks are pinned.",
+	"pins.title":            "📌 **Pinned tracks** (%d)\n",
+	"pins.line":             "`%d.` %s - %s\n",
+	"pins.missing":          "file missing",
+	"pins.more":             "...and %d more\n",
+	"pins.total":            "Pinned files take %s",
+	"pins.budget":           " of the %s cache.",
+	"pins.over_half":        "⚠️ Pinned tracks take more than half the cache, which leaves little room for everything else.",
+
+	"pause.no_music":       "❌ No music is currently playing.",
+	"pause.already_paused": "❌ Music is already paused.",
+	"pause.failed":         "❌ Failed to pause music.",
+	"pause.paused":         "⏸️ Music paused. Use `/resume` to continue playing.",
+
+	"resume.no_queue":        "❌ No music queue available to resume.",
+	"resume.already_playing": "❌ Music is already playing.",
+	"resume.failed":          "❌ Failed to resume music.",
+	"resume.resumed":         "▶️ Music resumed!",
+
+	"join.already_here": "✅ Already in your voice channel.",
+	"join.joined_radio": "✅ Joined your voice channel and started radio.",
+	"join.joined_music": "✅ Joined your voice channel and resumed music.",
+
+	"leave.failed":       "❌ Failed to return to idle channel.",
+	"leave.idle_channel": "✅ This is the idle channel. Radio will continue playing.",
+	"leave.returned":     "✅ Returned to idle channel and resumed radio.",
+
+	"follow.enabled":            "👣 Following you — I'll move with you between voice channels. Use /follow again to stop.",
+	"follow.disabled":           "✅ Stopped following you.",
+	"follow.stopped_disconnect": "👣 <@%s> left voice, so I'm staying here and no longer following.",
+	"follow.no_permission":      "⚠️ Can't follow into <#%s>: I need permission to connect and speak there.",
+	"follow.channel_full":       "⚠️ Can't follow into <#%s>: the channel is full.",
+
+	"voice.reconnect_failed": "⚠️ Lost the voice connection and couldn't reconnect. Use /join or /play to bring me back.",
+
+	"setidlechannel.current":      "📻 The idle channel is <#%s>.",
+	"setidlechannel.current_none": "📻 No idle channel is set.",
+	"setidlechannel.not_voice":    "❌ The idle channel has to be a voice channel.",
+	"setidlechannel.save_failed":  "❌ Failed to save the idle channel.",
+	"setidlechannel.set":          "✅ Idle channel set to <#%s>.",
+
+	"setrole.current":         "👥 The %s role is **%s**.",
+	"setrole.current_name":    "👥 No %s role is set. Roles named **%s** are used.",
+	"setrole.everyone":        "❌ @everyone can't be the %s role.",
+	"setrole.save_failed":     "❌ Failed to save the %s role.",
+	"setrole.set":             "✅ %s role set to **%s**.",
+	"setrole.reset":           "✅ %s role reset. Roles named **%s** are used again.",
+	"setrole.admin_not_yours": "⚠️ You don't have this role, so you can no longer use admin commands yourself.",
+	"roles.deleted":           "⚠️ The %s role was deleted. Roles named **%s** are used until a new one is set.",
+
+	"clip.playing":       "🔊 Playing **%s**.",
+	"clip.added":         "✅ Added clip **%s** (%s).",
+	"clip.removed":       "🗑️ Removed clip **%s**.",
+	"clip.not_found":     "❌ There is no clip called **%s**.",
+	"clip.exists":        "❌ There is already a clip called **%s**.",
+	"clip.invalid_name":  "❌ Clip names can only use letters, digits, - and _, and be at most %d characters long.",
+	"clip.too_long":      "❌ That clip is %s long. Clips can be at most %d seconds.",
+	"clip.radio":         "❌ Clips can't be played over the radio.",
+	"clip.busy":          "❌ A clip is already playing.",
+	"clip.not_connected": "❌ I'm not in a voice channel.",
+	"clip.none":          "No clips yet. Add one with `/clip add`.",
+	"clip.title":         "🔊 Clips (%d)",
+	"clip.failed":        "❌ Something went wrong with that clip.",
+
+	"schedule.added":            "⏰ Scheduled #%d: %s at <t:%d:F> in <#%s>.",
+	"schedule.removed":          "🗑️ Cancelled schedule #%d.",
+	"schedule.not_found":        "❌ There is no schedule #%d.",
+	"schedule.none":             "Nothing is scheduled.",
+	"schedule.title":            "⏰ Scheduled (%d)",
+	"schedule.line":             "`#%d` <t:%d:F> %s in <#%s> by <@%s>",
+	"schedule.flag_playlist":    " · playlist",
+	"schedule.flag_preempt":     " · preempts",
+	"schedule.bad_url":          "❌ That isn't a valid http(s) URL.",
+	"schedule.bad_time":         "❌ I don't understand that time. Use an ISO time such as `2025-06-01T20:00+02:00` (UTC if no offset) or a delay such as `in 2h`.",
+	"schedule.in_past":          "❌ That time has already passed.",
+	"schedule.too_far":          "❌ Playback can be scheduled at most %d days ahead.",
+	"schedule.too_many":         "❌ This server already has %d scheduled tracks and playlists.",
+	"schedule.preempt_playlist": "❌ Only tracks can preempt what is playing; playlists queue behind it.",
+	"schedule.failed_command":   "❌ Failed to update the schedule.",
+	"schedule.starting":         "⏰ Starting scheduled %s, requested by <@%s>, in <#%s>.",
+	"schedule.queued_behind":    "⏰ Scheduled %s by <@%s> is queued behind the current music.",
+	"schedule.preempting":       "⏰ Scheduled %s by <@%s> is taking over from the current song.",
+	"schedule.join_failed":      "⏰ Scheduled %s couldn't start: I couldn't join <#%s>.",
+	"schedule.failed":           "⏰ Scheduled %s failed: %v",
+
+	"idle.deleted_fallback":    "⚠️ %s The idle channel was deleted. I'm idling in <#%s> for now — use /setidlechannel to pick a new one.",
+	"idle.deleted_no_fallback": "⚠️ %s The idle channel was deleted and I can't join any other voice channel. Use /setidlechannel to pick a new one.",
+
+	"shutdown.announce": "🔧 Shutting down for maintenance — queue is saved (%d tracks).",
+
+	"music.skipped_missing":           "⏭️ Skipped **%s**: its file is missing and can't be downloaded again.",
+	"music.skipped_redownload_failed": "⏭️ Skipped **%s**: its file is missing and downloading it again failed (%s).",
+	"music.skipped_playback_failed":   "⏭️ Skipped **%s**: playback broke off twice (%s). It is listed in /failures.",
+
+	"changestream.invalid": "❌ Invalid stream selection.",
+	"changestream.failed":  "❌ Failed to change stream.",
+	"changestream.changed": "✅ Changed radio stream to %s",
+
+	"restart.not_in_music": "❌ Not currently in a music channel. Use `/resume` to start the queue.",
+	"restart.failed":       "❌ Failed to restart queue: %v",
+	"restart.restarted":    "🔁 Restarted the queue with %d songs.",
+
+	"delmsg.fetch_failed": "❌ Failed to fetch messages.",
+	"delmsg.none":         "❌ No messages found to delete.",
+	"delmsg.partial":      "⚠️ Partially completed: deleted %d messages, but encountered errors.",
+	"delmsg.done":         "✅ Successfully deleted %d messages.",
+
+	"ping.pong":              "🏓 **Pong!**\n\n📡 **WebSocket Latency:** %dms %s\n⚡ **Bot Response Time:** %dms\n🤖 **Bot Status:** Online and Ready\n⬇️ **Downloader Status:** %s\n📶 **Downloader Ping:** %s",
+	"ping.downloader_error":  "\n❌ **Downloader Error:** %s",
+	"ping.latency_excellent": "🟢 (Excellent)",
+	"ping.latency_good":      "🟡 (Good)",
+	"ping.latency_fair":      "🟠 (Fair)",
+	"ping.latency_poor":      "🔴 (Poor)",
+
+	"shardinfo.info": "🧩 This server is served by **shard %d of %d**\n📡 **Gateway latency:** %dms\n🏠 **Servers on shard %d:** %d",
+
+	"help.header":              "🤖 **Music Bot Commands**\n\n",
+	"help.category":            "**%s Commands:**\n",
+	"help.role_only":           " *(%s only)*",
+	"help.tips":                "💡 **Tips:**\n• Use `/nowplaying` to see what's currently playing\n• The bot automatically switches between radio and music modes\n• When no music is queued, radio will resume automatically",
+	"help.category.utility":    "Utility",
+	"help.category.voice":      "Voice",
+	"help.category.music":      "Music",
+	"help.category.radio":      "Radio",
+	"help.category.moderation": "Moderation",
+	"help.category.admin":      "Admin",
+	"help.line":                "• `/%s` - %s%s\n",
+	"help.page":                "Page %d/%d • Use `/help command:<name>` for details",
+	"help.none":                "❌ There are no commands you can use here.",
+	"help.unknown_command":     "❌ There is no command called `/%s`.",
+	"help.no_options":          "None",
+	"help.required":            " *(required)*",
+	"help.access_everyone":     "Everyone",
+	"help.access_role":         "%s role",
+	"help.access_dj_only":      "%s role while DJ-only mode is on",
+	"help.cooldown":            "Once every %d seconds per user (admins are exempt)",
+	"help.max_concurrent":      "At most %d running at once per server",
+	"help.field_options":       "Options",
+	"help.field_access":        "Who can use it",
+	"help.field_limits":        "Limits",
+	"help.field_examples":      "Examples",
+
+	"downloader.disabled":             "❌ No downloader is configured.",
+	"downloader.status":               "⬇️ **Downloader:** %s\n📡 **Last pong:** %s\n📶 **Latency:** %s\n⏳ **Pending requests:** %d",
+	"downloader.latency_disconnected": "Disconnected",
+	"downloader.latency_failed":       "Failed (%s)",
+	"downloader.reconnected":          "✅ Reconnected to the downloader.",
+	"downloader.reconnect_failed":     "❌ Failed to reconnect to the downloader: %s",
+	"downloader.pending_none":         "✅ No requests are waiting for the downloader.",
+	"downloader.pending_title":        "⏳ **%d requests waiting for the downloader:**\n",
+	"downloader.pending_line":         "• `%s` %s, %s",
+	"downloader.pending_more":         "…and %d more\n",
+	"downloader.cancel_all":           "Cancel all",
+	"downloader.cancelled":            "🛑 Cancelled %d requests.",
+
+	"session.not_connected":          "❌ I'm not in a voice channel. Start the music first, then the session.",
+	"session.already_running":        "❌ A listening session is already running. Stop it with `/session stop` first.",
+	"session.not_running":            "❌ No listening session is running.",
+	"session.none":                   "No listening session has been recorded here yet.",
+	"session.failed":                 "❌ Something went wrong with the listening session.",
+	"session.started":                "🔴 **Listening session started** in <#%s> by <@%s>.\nFrom now on I note which tracks play, who is in the voice channel (checked once a minute) and the reactions to the tracks I post here. **No audio is recorded.** An admin ends it with `/session stop`.",
+	"session.track":                  "🎶 Now playing: **%s**",
+	"session.track_artist":           "🎶 Now playing: **%s** by %s",
+	"session.summary_title":          "📋 Listening session",
+	"session.summary_overview":       "In <#%s>, started by <@%s> <t:%d:f>\nLasted %s · %d tracks · peak of %d listeners · %d people present",
+	"session.summary_tracks":         "Tracks",
+	"session.summary_track":          "`%d.` <t:%d:t> %s - 👥 %d · %d reactions",
+	"session.summary_no_tracks":      "No tracks played.",
+	"session.summary_listeners":      "Listeners",
+	"session.summary_listener":       "<@%s> - %d min",
+	"session.summary_more_listeners": "...and %d more",
+	"session.summary_no_listeners":   "Nobody was in the voice channel.",
+
+	"status.title":                "📊 Bot Status",
+	"status.refresh":              "🔄 Refresh",
+	"status.downloader":           "Downloader",
+	"status.downloader_value":     "%s\nLast pong: %s",
+	"status.downloader_disabled":  "Not configured",
+	"status.never":                "never",
+	"status.ago":                  "%s ago",
+	"status.pending":              "Pending downloads",
+	"status.pending_value":        "%d",
+	"status.mode":                 "Mode",
+	"status.mode_dj":              "🎵 DJ",
+	"status.mode_radio":           "📻 Radio",
+	"status.mode_idle":            "😴 Idle",
+	"status.mode_transitioning":   "🔄 Transitioning",
+	"status.player":               "Player",
+	"status.player_playing":       "▶️ Playing",
+	"status.player_paused":        "⏸️ Paused",
+	"status.player_stopped":       "⏹️ Stopped",
+	"status.player_track":         "%s\n**%s** - %s",
+	"status.radio":                "Radio",
+	"status.radio_playing":        "📻 Playing\n%s",
+	"status.radio_stopped":        "⏹️ Stopped\n%s",
+	"status.radio_underruns":      "\nBuffer underruns: %d",
+	"status.database":             "Database",
+	"status.database_value":       "`%s`\n%d songs",
+	"status.database_error":       "`%s`\nSong count unavailable",
+	"status.search_cache":         "Search cache",
+	"status.search_cache_value":   "%d hits, %d misses\n%d searches cached",
+	"status.uptime":               "Uptime",
+	"status.runtime":              "Runtime",
+	"status.runtime_value":        "%d goroutines\n%.1f MiB heap",
+	"status.janitor":              "Janitor",
+	"status.janitor_value":        "Last run %s: removed %d files or entries, freed %s. Cache size: %s",
+	"status.janitor_failed":       "Last run %s failed: %v",
+	"status.janitor_pending":      "No run yet",
+	"status.janitor_disabled":     "Not configured",
+	"status.dependencies":         "Dependencies",
+	"status.dependencies_ok":      "✅ ffmpeg, socket directory and download directory OK (checked %s)",
+	"status.dependencies_failed":  "⚠️ Problems found (checked %s). Fix them and run `/status refresh:true`:",
+	"status.dependency_line":      "• %v",
+	"status.dependencies_pending": "Not checked yet",
+	"status.dependencies_skipped": "Not checked (skip_dep_check)",
+	"status.dj_only":              "DJ-only mode",
+	"status.dj_only_on":           "🔒 On (%s)",
+	"status.dj_only_off":          "🔓 Off",
+	"status.always_on":            "24/7 mode",
+	"status.always_on_on":         "🔁 On (<#%s>)",
+	"status.always_on_off":        "Off",
+	"status.roles":                "Roles",
+	"status.role_line":            "%s: %s",
+	"status.role_not_configured":  "not configured (matching **%s** by name)",
+
+	"lyrics.title":     "%s — %s",
+	"lyrics.page":      "Page %d of %d",
+	"lyrics.not_found": "❌ Couldn't find lyrics for **%s**.",
+
+	"trim.nothing":      "❌ Give a `start`, an `end` or both.",
+	"trim.no_song":      "❌ You have no upcoming song to trim.",
+	"trim.not_found":    "❌ There is no song at position %d of the queue.",
+	"trim.not_yours":    "❌ Only the requester or members with the **%s** role can trim this song.",
+	"trim.no_duration":  "❌ That song has no known length, so it can't be trimmed.",
+	"trim.invalid_time": "❌ `%s` isn't a time. Use seconds, m:ss or h:mm:ss.",
+	"trim.out_of_range": "❌ The song is %s long. The start must be before the end and both must fit in the song.",
+	"trim.failed":       "❌ Failed to trim the song.",
+	"trim.trimmed":      "✂️ **%s** will play from %s to %s (%s).",
+
+	"remove.nothing":   "❌ Give a `position`, a `user` or both.",
+	"remove.bad_range": "❌ %d to %d isn't a range. The end must come after the start.",
+	"remove.not_found": "❌ The queue doesn't reach position %d.",
+	"remove.not_yours": "❌ You can only remove your own songs. Removing other people's needs the **%s** role.",
+	"remove.none":      "❌ No matching songs are waiting in the queue.",
+	"remove.failed":    "❌ Failed to remove the songs.",
+	"remove.removed":   "🗑️ Removed %d from the queue:",
+	"remove.more":      "…and %d more",
+
+	"language.current":     "🌐 Current language: %s",
+	"language.set":         "🌐 Language set to %s.",
+	"language.unsupported": "❌ Unsupported language: %s",
+	"language.save_failed": "❌ Failed to save language setting.",
+
+	"requestchannel.set":         "📨 <#%s> is now the request channel. Links posted there are played, and messages starting with `?` are searched.",
+	"requestchannel.unset":       "📨 The request channel is off.",
+	"requestchannel.not_set":     "❌ There is no request channel.",
+	"requestchannel.disabled":    "❌ Request channels are turned off on this bot. The bot owner can turn them on with `request_channels` in the config file, after enabling the message content intent in the Discord developer portal.",
+	"requestchannel.save_failed": "❌ Failed to save the request channel.",
+	"requestchannel.deleted":     "⚠️ %s The request channel was deleted, so requests by message are off. Use /requestchannel set to pick a new one.",
+
+	"queuemode.current":     "🔀 Queue mode: **%s**",
+	"queuemode.set_fifo":    "🔀 Queue mode set to **fifo**: songs play in the order they were queued.",
+	"queuemode.set_fair":    "🔀 Queue mode set to **fair**: requesters take turns, each in the order they queued.",
+	"queuemode.unknown":     "❌ Unknown queue mode.",
+	"queuemode.save_failed": "❌ Failed to save the queue mode.",
+
+	"style.current":     "🎨 Current style: **%s**",
+	"style.set":         "🎨 Style set to **%s**.",
+	"style.unknown":     "❌ Unknown style.",
+	"style.save_failed": "❌ Failed to save the style.",
+
+	"presence.title":             "🪪 **Status templates**\n",
+	"presence.playing":           "**While a song plays:** ",
+	"presence.radio":             "**While the radio plays:** ",
+	"presence.idle":              "**While nothing plays**, one every %d minutes:\n",
+	"presence.line":              "`%s` → %s\n",
+	"presence.placeholders":      "\nPlaceholders: %s",
+	"presence.shared":            "\nThe bot is in more than one server, so only the idle statuses are shown.",
+	"presence.preview":           "🪪 Right now this shows as: **%s**",
+	"presence.saved":             "✅ Status templates saved.",
+	"presence.save_failed":       "❌ Failed to save the status templates.",
+	"presence.idle_full":         "❌ The idle rotation holds at most %d statuses.",
+	"presence.idle_out_of_range": "❌ There are only %d idle statuses.",
+	"presence.idle_last":         "❌ The last idle status can't be removed. Change it with `/presence reset` or add another first.",
+
+	"config.list_title":   "⚙️ **Stored settings**\nChange one with `/config set`.\n\n",
+	"config.line":         "• `%s` (%s) = `%s`\n  %s\n",
+	"config.get":          "⚙️ **%s**\n%s\n\n**Type:** %s\n**Current value:** `%s`\n**Default:** %s",
+	"config.default_file": "from config.json",
+	"config.unknown":      "❌ There is no setting `%s`. Valid settings: `%s`",
+	"config.invalid":      "❌ Invalid value for `%s` (%s): %s",
+	"config.save_failed":  "❌ Failed to save `%s`. Please try again.",
+	"config.saved":        "✅ `%s` changed from `%s` to `%s`.",
+	"config.owner_only":   "❌ `%s` is shared by every server the bot is in, so only the bot's owners can change it.",
+
+	"render.footer":       "Musicbot %s • Shard %d/%d",
+	"render.queue_title":  "🎵 Music Queue",
+	"render.search_title": "🔍 Search Results",
+	"render.now_playing":  "🎧 Now Playing",
+	"render.duration":     "Duration",
+	"render.requested_by": "Requested by",
+	"render.requested_at": "<t:%d:R>",
+	"render.filter":       "Filter",
+	"render.help_title":   "📖 %s Commands",
+	"render.up_next":      "Up Next",
+	"render.uploaded":     "uploaded %s",
+	"render.views":        "%s views",
+
+	"setlimit.current":     "📏 Queue limits: **%d** songs in total, **%d** per user.",
+	"setlimit.set":         "✅ Queue limits set to **%d** songs in total, **%d** per user.",
+	"setlimit.save_failed": "❌ Failed to save the queue limits.",
+
+	"setmaxduration.current":     "📏 Songs can be up to **%d** minutes long.",
+	"setmaxduration.set":         "✅ Songs can now be up to **%d** minutes long.",
+	"setmaxduration.save_failed": "❌ Failed to save the duration limit.",
+
+	"setmaxsize.current":     "📏 Songs can be up to **%d** MB.",
+	"setmaxsize.set":         "✅ Songs can now be up to **%d** MB.",
+	"setmaxsize.save_failed": "❌ Failed to save the size limit.",
+
+	"setretries.current":     "🔁 Downloads are tried up to **%d** times.",
+	"setretries.set":         "✅ Downloads are now tried up to **%d** times.",
+	"setretries.save_failed": "❌ Failed to save the retry setting.",
+
+	"djonly.current_on":  "🔒 DJ-only mode is on. Only members with the **%s** role can control the music.",
+	"djonly.current_off": "🔓 DJ-only mode is off. Everyone can control the music; DJ-only mode would limit it to the **%s** role.",
+	"djonly.enabled":     "🔒 DJ-only mode is on. Only members with the **%s** role can control the music now.",
+	"djonly.disabled":    "🔓 DJ-only mode is off. Everyone can control the music again.",
+	"djonly.save_failed": "❌ Failed to save DJ-only mode.",
+
+	"alwayson.current_on":          "🔁 24/7 mode is on. I stay in <#%s> around the clock.",
+	"alwayson.current_off":         "💤 24/7 mode is off. I go back to the idle channel when everyone leaves.",
+	"alwayson.no_channel":          "❌ I'm not in a voice channel. Pick one with the `channel` option.",
+	"alwayson.enabled":             "🔁 24/7 mode is on. I'll stay in <#%s>, even when it's empty, and play the radio when the queue runs out.",
+	"alwayson.enabled_later":       "🔁 24/7 mode is on. I'll move to <#%s> once my current channel empties or someone uses /leave.",
+	"alwayson.enabled_move_failed": "⚠️ 24/7 mode is on, but I couldn't join <#%s> yet. I'll go there once my current channel empties or someone uses /leave.",
+	"alwayson.disabled":            "💤 24/7 mode is off. I'll go back to the idle channel when everyone leaves.",
+	"alwayson.save_failed":         "❌ Failed to save 24/7 mode.",
+	"alwayson.deleted":             "⚠️ %s The 24/7 channel was deleted, so 24/7 mode is off. Use /247 to turn it on again.",
+	"alwayson.rejoin_failed":       "⚠️ Lost the voice connection and couldn't get back into the 24/7 channel. I'll keep trying every %d seconds.",
+
+	"blacklist.url_added":         "🚫 URLs matching `%s` can no longer be queued.",
+	"blacklist.url_removed":       "✅ URLs matching `%s` can be queued again.",
+	"blacklist.url_not_found":     "❌ `%s` isn't on the blacklist.",
+	"blacklist.user_added":        "🚫 <@%s> can no longer queue music.",
+	"blacklist.user_added_queued": "🚫 <@%s> can no longer queue music. They still have %d songs in the queue.",
+	"blacklist.user_removed":      "✅ <@%s> can queue music again.",
+	"blacklist.user_not_found":    "❌ <@%s> isn't on the blacklist.",
+	"blacklist.purge_button":      "Remove their %d songs",
+	"blacklist.keep_button":       "Keep them",
+	"blacklist.purged":            "🚫 <@%s> can no longer queue music. Removed %d of their songs from the queue.",
+	"blacklist.failed":            "❌ Failed to update the blacklist.",
+	"blacklist.title":             "🚫 Blacklist",
+	"blacklist.urls":              "URL patterns",
+	"blacklist.users":             "Users",
+	"blacklist.none":              "None",
+	"blacklist.more":              "…and %d more",
+	"blacklist.user_blocked":      "🚫 You aren't allowed to queue music on this server.",
+	"blacklist.url_blocked":       "🚫 That link is blocked on this server (matches `%s`).",
+
+	"djban.timed_out":  "⏳ You can't queue music on this server right now. Your timeout ends <t:%d:R>.",
+	"djban.failed":     "❌ Something went wrong with the timeout.",
+	"djban.added":      "⏳ <@%s> can't queue music until <t:%d:f>. Removed %d of their queued songs.",
+	"djban.none":       "Nobody is timed out from queueing.",
+	"djban.title":      "⏳ **Timed out from queueing** (%d)\n",
+	"djban.line":       "<@%s> - %s left, until <t:%d:f> (by <@%s>)\n",
+	"djban.not_banned": "<@%s> isn't timed out.",
+	"djban.removed":    "✅ <@%s> can queue music again.",
+
+	"audit.line":        "<t:%d:f> <@%s> `%s`",
+	"audit.line_target": "<t:%d:f> <@%s> `%s` %s",
+	"auditlog.title":    "📜 Audit log",
+	"auditlog.empty":    "📜 Nothing has been recorded yet.",
+	"auditlog.failed":   "❌ Failed to read the audit log.",
+
+	"failures.title":          "⚠️ Failed downloads",
+	"failures.line":           "`%d.` <t:%d:R> `%s` %s by %s\n> %s",
+	"failures.track":          "track %d of %s",
+	"failures.unknown_user":   "unknown",
+	"failures.retry_button":   "Retry %d",
+	"failures.empty":          "✅ No downloads have failed in the last 30 days.",
+	"failures.failed":         "❌ Failed to read the download failures.",
+	"failures.not_found":      "❌ There is no such failure. Use /failures to see the list.",
+	"failures.playlist_track": "❌ Tracks of a playlist can't be retried on their own. Request the playlist again instead.",
+	"failures.not_connected":  "❌ I'm not in a voice channel. Use /join first.",
+
+	"reloadconfig.unchanged":    "✅ Config reloaded, nothing changed.",
+	"reloadconfig.done":         "🔄 Config reloaded.",
+	"reloadconfig.applied":      "**Applied:**",
+	"reloadconfig.rejected":     "**Not applied, restart required:**",
+	"reloadconfig.failed":       "❌ Failed to reload config: %v",
+	"synccommands.done":         "✅ Slash commands synced: %d created, %d updated, %d removed, %d unchanged.",
+	"synccommands.some_failed":  "⚠️ %d changes failed, see the log.",
+	"synccommands.failed":       "❌ Failed to sync slash commands: %v",
+	"commands.disabled_here":    "🚫 /%s is disabled in this server.",
+	"commands.unknown":          "❌ There is no /%s command.",
+	"commands.protected":        "❌ /%s can't be disabled.",
+	"commands.already_disabled": "/%s is already disabled.",
+	"commands.disabled":         "🚫 /%s is now disabled in this server.",
+	"commands.not_disabled":     "/%s isn't disabled.",
+	"commands.enabled":          "✅ /%s is enabled again.",
+	"commands.save_failed":      "❌ Failed to save the change.",
+	"commands.list_none":        "Every command is enabled in this server.",
+	"commands.list":             "🚫 **Disabled in this server:** %s",
+}
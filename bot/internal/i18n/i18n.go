@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"fmt"
+	"musicbot/internal/logger"
+	"sort"
+	"sync"
+)
+
+const DefaultLocale = "en"
+
+// Bundle maps message keys to fmt format strings for one locale.
+type Bundle map[string]string
+
+var (
+	bundles = map[string]Bundle{
+		"en": english,
+		"no": norwegian,
+	}
+	guildLocales  = make(map[string]string)
+	missingLogged = make(map[string]bool)
+	mu            sync.RWMutex
+)
+
+// T translates key for the guild's configured locale.
+func T(guildID, key string, args ...interface{}) string {
+	return Translate(GetGuildLocale(guildID), key, args...)
+}
+
+// Translate looks key up in the given locale, falling back to English. Each
+// missing key is logged once per locale.
+func Translate(locale, key string, args ...interface{}) string {
+	format, ok := lookup(locale, key)
+	if !ok {
+		logMissing(locale, key)
+		format, ok = lookup(DefaultLocale, key)
+		if !ok {
+			logMissing(DefaultLocale, key)
+			return key
+		}
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func lookup(locale, key string) (string, bool) {
+	bundle, ok := bundles[locale]
+	if !ok {
+		return "", false
+	}
+	format, ok := bundle[key]
+	return format, ok
+}
+
+func logMissing(locale, key string) {
+	id := locale + ":" + key
+
+	mu.Lock()
+	defer mu.Unlock()
+	if missingLogged[id] {
+		return
+	}
+	missingLogged[id] = true
+	logger.Error.Printf("Missing translation for %q in locale %q", key, locale)
+}
+
+func GetGuildLocale(guildID string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if locale, ok := guildLocales[guildID]; ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+func SetGuildLocale(guildID, locale string) error {
+	if !IsSupported(locale) {
+		return fmt.Errorf("unsupported locale: %s", locale)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	guildLocales[guildID] = locale
+	return nil
+}
+
+func IsSupported(locale string) bool {
+	_, ok := bundles[locale]
+	return ok
+}
+
+// Locales returns the supported locale codes in a stable order.
+func Locales() []string {
+	locales := make([]string, 0, len(bundles))
+	for locale := range bundles {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// LocaleName returns the locale's own name for itself, e.g. "Norsk".
+func LocaleName(locale string) string {
+	return Translate(locale, "language.name")
+}
@@ -0,0 +1,38 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// formatVerb matches a fmt verb with its flags, width and precision.
+var formatVerb = regexp.MustCompile(`%[-+# 0]*(\[\d+\])?\d*(\.\d+)?[a-zA-Z%]`)
+
+func TestBundlesMatchEnglish(t *testing.T) {
+	for locale, bundle := range bundles {
+		if locale == DefaultLocale {
+			continue
+		}
+		t.Run(locale, func(t *testing.T) {
+			for key, format := range english {
+				translated, ok := bundle[key]
+				if !ok {
+					t.Errorf("%s is missing", key)
+					continue
+				}
+				// The same arguments are passed whatever the locale, so the
+				// verbs have to line up.
+				want, got := formatVerb.FindAllString(format, -1), formatVerb.FindAllString(translated, -1)
+				if !slices.Equal(got, want) {
+					t.Errorf("%s has verbs %v, want %v as in English", key, got, want)
+				}
+			}
+			for key := range bundle {
+				if _, ok := english[key]; !ok {
+					t.Errorf("%s isn't in English", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,635 @@
+package i18n
+
+var norwegian = Bundle{
+	"language.name": "Norsk",
+
+	"common.not_in_voice":         "❌ Du må være i en talekanal.",
+	"common.join_failed":          "❌ Klarte ikke å bli med i talekanalen din.",
+	"common.join_missing_connect": "❌ Jeg trenger tillatelsen Koble til i <#%s>.",
+	"common.join_missing_speak":   "❌ Jeg trenger tillatelsen Snakke i <#%s>.",
+	"common.join_channel_full":    "❌ <#%s> er full.",
+	"common.busy_other_channel":   "❌ Boten spiller allerede musikk i en annen kanal.",
+	"common.no_song_playing":      "❌ Ingen sang spilles akkurat nå.",
+	"common.unknown_duration":     "Ukjent",
+	"common.unknown_length":       "--:--",
+	"common.live":                 "🔴 DIREKTE",
+	"common.request_failed":       "❌ Klarte ikke å be om sangen: %v",
+	"common.command_failed":       "❌ Noe gikk galt da kommandoen ble kjørt.",
+	"download.temporary":          "⚠️ Sangen er midlertidig utilgjengelig (prøvde %d ganger). Prøv igjen senere.",
+	"download.unavailable":        "❌ Videoen kan ikke lastes ned: den er privat, regionlåst eller fjernet.",
+	"download.private":            "❌ Videoen kan ikke lastes ned: den er privat.",
+	"download.region_blocked":     "❌ Videoen kan ikke lastes ned: den er sperret i denne regionen.",
+	"download.age_restricted":     "❌ Videoen kan ikke lastes ned: den er aldersbegrenset.",
+	"download.failed":             "❌ Klarte ikke å laste ned sangen: %s",
+	"download.cancelled":          "⏹️ Nedlastingen ble stoppet.",
+	"common.not_your_buttons":     "❌ Bare den som kjørte denne kommandoen kan bruke disse knappene.",
+
+	"permissions.server_only":  "❌ Denne kommandoen kan bare brukes på en server.",
+	"permissions.check_failed": "❌ Klarte ikke å sjekke tillatelsene dine. Prøv igjen.",
+	"permissions.denied_title": "🚫 Ingen tilgang",
+	"permissions.denied":       "Du trenger rollen **%s** for å bruke `/%s`.",
+	"permissions.dj_only":      "🔒 Bare DJ-er kan styre musikken nå. Du trenger rollen **%s** for å bruke `/%s`.",
+
+	"cooldown.wait": "⏳ Ta det med ro — prøv igjen om %ds.",
+	"cooldown.busy": "⏳ `/%s` kjører allerede på denne serveren. Prøv igjen når den er ferdig.",
+
+	"limits.queue_full":               "❌ Køen er full (%d sanger). Prøv igjen når noen sanger er spilt.",
+	"limits.user_full":                "❌ Du har allerede %d sanger i køen. Vent til noen av dem er spilt først.",
+	"limits.download_too_long":        "❌ Sangen er lengre enn serverens grense på %d minutter.",
+	"limits.download_too_long_length": "❌ Sangen er %s lang, over serverens grense på %d minutter.",
+	"limits.download_too_large":       "❌ Sangen er større enn serverens grense på %d MB.",
+	"limits.download_override":        "\nDJ-er kan legge den til likevel med `allow_long` på /play, og administratorer kan endre grensen med /setmaxduration eller /setmaxsize.",
+
+	"play.downloading":       "🎵 Laster ned sang fra: %s\n⏳ Dette kan ta litt tid, du får beskjed når den er i køen.",
+	"play.dj_option_denied":  "🔒 Bare medlemmer med rollen **%s** kan bruke `%s`.",
+	"play.queued":            "✅ Lagt til i køen: **%s**",
+	"play.followup":          "<@%s> %s",
+	"play.duplicate":         "🔁 **%s** er allerede i køen på plass %d. Bruk `/play` med `force` for å legge den til igjen.",
+	"play.duplicate_playing": "🔁 **%s** spilles allerede. Bruk `/play` med `force` for å legge den til igjen.",
+
+	"playfile.uploading":   "📎 Legger **%s** til i køen...",
+	"playfile.queued":      "📎 La til **%s** i køen.",
+	"playfile.unsupported": "❌ Det er ikke en lydfil jeg kan spille. Godtatte typer: %s",
+	"playfile.too_large":   "❌ Filen er større enn serverens grense på %d MB.",
+
+	"exportqueue.done":            "💾 Eksporterte %d spor. Last dem inn igjen med `/importqueue`.",
+	"exportqueue.skipped_uploads": "\n⚠️ %d opplastede filer ble utelatt, siden de ikke kan hentes igjen med URL.",
+	"exportqueue.empty":           "❌ Det er ingenting i køen å eksportere.",
+
+	"importqueue.starting":       "📥 Importerer %d spor. De legges i køen etter hvert som de lastes ned...",
+	"importqueue.progress":       "📥 Importerer: %d/%d ferdig, %d lagt til",
+	"importqueue.done":           "📥 Importerte %d av %d spor.",
+	"importqueue.duplicates":     "\n⏭️ %d lå allerede i køen.",
+	"importqueue.blocked":        "\n🚫 %d er blokkert på denne serveren.",
+	"importqueue.failed":         "\n⚠️ %d kunne ikke lastes ned.",
+	"importqueue.stopped":        "\n⏹️ Importen stoppet med %d spor igjen.",
+	"importqueue.malformed":      "\n⚠️ %d linjer er ikke URL-er:",
+	"importqueue.malformed_line": "Linje %d: `%s`",
+	"importqueue.malformed_more": "…og %d til",
+	"importqueue.no_urls":        "❌ Den filen inneholder ingen URL-er.",
+	"importqueue.too_large":      "❌ Køfiler kan være på høyst %d KB.",
+	"importqueue.too_long":       "❌ Køfiler kan ha høyst %d linjer.",
+	"importqueue.fetch_failed":   "❌ Klarte ikke å lese den filen.",
+
+	"repair.nothing":  "✅ Alle sangene i køen har filen sin.",
+	"repair.starting": "🔧 Laster ned %d manglende sanger på nytt...",
+	"repair.progress": "🔧 Reparerer: %d/%d ferdig, %d reparert",
+	"repair.done":     "🔧 Reparerte %d av %d manglende sanger.",
+	"repair.pending":  "\n⏳ %d ble allerede lastet ned på nytt.",
+	"repair.removed":  "\n🗑️ Fjernet %d sanger fra køen som ikke kunne lastes ned på nytt.",
+
+	"playlist.starting":            "📜 Starter nedlasting av spilleliste fra: %s\n⏳ Laster ned opptil %d sanger. Sangene legges i køen etter hvert som de lastes ned...",
+	"playlist.request_failed":      "❌ Klarte ikke å be om spillelisten: %v",
+	"playlist.clamped":             "\n⚠️ Du ba om %d sanger, men bare %d får plass innenfor kø-grensene.",
+	"playlist.done":                "📜 La til %d sanger i køen.",
+	"playlist.done_duplicates":     "📜 La til %d sanger i køen (%d duplikater hoppet over).",
+	"playlist.done_skipped":        "\n⚠️ %d hoppet over: %s.",
+	"playlist.skip_unavailable":    "%d utilgjengelige",
+	"playlist.skip_private":        "%d private",
+	"playlist.skip_region_blocked": "%d regionsperret",
+	"playlist.skip_age_restricted": "%d aldersbegrenset",
+	"playlist.skip_too_long":       "%d for lange",
+	"playlist.skip_too_large":      "%d for store",
+	"playlist.skip_failed":         "%d kunne ikke lastes ned",
+	"playlist.progress":            "📥 Laster ned spilleliste: %d/%d ferdig, %d feilet",
+	"playlist.cancelled":           "⏹️ Nedlastingen av spillelisten ble stoppet.",
+	"playlist.in_progress":         "⏳ Den spillelisten lastes allerede ned.",
+
+	"search.unavailable":        "❌ Søketjenesten er ikke tilgjengelig.",
+	"search.searching":          "🔍 Søker på %s etter: %s\n⏳ Vent litt...",
+	"search.failed":             "❌ Søket feilet: %v",
+	"search.timeout":            "⏱️ Søket tar lengre tid enn forventet. Prøv igjen med et annet søkeord.",
+	"search.no_results":         "❌ Fant ingen resultater.",
+	"search.results_header":     "🎵 Søkeresultater:\n\n",
+	"search.invalid_selection":  "❌ Ugyldig valg.",
+	"search.expired":            "❌ Søkeresultatene har utløpt.",
+	"search.retry_button":       "🔁 Søk igjen",
+	"search.downloading":        "🎵 Laster ned: %s - %s",
+	"search.downloading_next":   "⏭️ Laster ned for å spille neste: %s - %s",
+	"search.play_next_button":   "⏭ %d",
+	"search.queue_all_button":   "Legg alle i køen",
+	"search.queue_all_progress": "📥 Legger søkeresultater i køen (%d/%d): %s - %s",
+	"search.queue_all_done":     "📥 Ba om %d av %d søkeresultater. Sangene legges i køen etter hvert som de lastes ned...",
+
+	"queue.empty":           "📭 Køen er tom. Bruk `/play` for å legge til sanger!",
+	"queue.header":          "🎵 **Musikkø**\n\n",
+	"queue.now_playing":     "🎧 **Spilles nå:**\n**%s** - %s (%s)%s\n\n",
+	"queue.up_next":         "📋 **Neste:**\n",
+	"queue.up_next_fair":    "📋 **Neste** (de som ønsker bytter på):\n",
+	"queue.footer":          "\n📄 Side %d/%d • %d sanger • %s totalt",
+	"queue.unknown_length":  " (+%d sanger med ukjent lengde)",
+	"queue.previous":        "◀ Forrige",
+	"queue.next":            "Neste ▶",
+	"queue.requested_by":    " • <@%s>",
+	"queue.requested_by_at": " • ønsket <t:%d:R> av <@%s>",
+	"queue.missing_file":    " ⚠️ *fil mangler*",
+	"queue.missing_hint":    "⚠️ %d spor mangler filen og ville blitt hoppet over. Bruk /repair for å laste dem ned på nytt.",
+
+	"skip.not_playing":  "❌ Spiller ikke musikk akkurat nå.",
+	"skip.skipped_last": "⏭️ Hoppet over sangen. Det er ingen flere sanger i køen.",
+	"skip.skipped":      "⏭️ Hoppet til neste sang.",
+
+	"eta.line":          "⏱️ Posisjon %d starter om omtrent %s (<t:%d:R>).",
+	"eta.line_at_least": "⏱️ Posisjon %d starter tidligst om %s (<t:%d:R>). %d sang(er) før den har ukjent lengde, så det kan godt bli senere.",
+	"eta.unknown":       "⏱️ En direktesending spilles før posisjon %d, så det er umulig å si når den starter.",
+	"eta.empty":         "📭 Ingenting står i kø.",
+	"eta.none_yours":    "❌ Du har ingen sanger i køen. Oppgi en posisjon for å sjekke en annen.",
+	"eta.out_of_range":  "❌ Velg en posisjon mellom 1 og %d.",
+	"eta.failed":        "❌ Klarte ikke å finne ut når sangen spilles.",
+	"eta.under_minute":  "under ett minutt",
+	"eta.minute":        "1 minutt",
+	"eta.minutes":       "%d minutter",
+	"eta.hours":         "%d t %d min",
+
+	"skipto.skipped":        "⏭️ Spiller nå **%s** - %s\nHoppet over den forrige sangen og %d i køen før denne.",
+	"skipto.skipped_kept":   "⏭️ Spiller nå **%s** - %s\nFlyttet den forrige sangen og %d i køen før denne til slutten av køen.",
+	"skipto.out_of_range":   "❌ Det er ingen sang på plass %d. Velg en plass fra 1 til %d.",
+	"skipto.nothing_queued": "❌ Det er ingen sanger i køen etter den som spilles.",
+	"skipto.failed":         "❌ Kunne ikke hoppe fremover.",
+
+	"previous.playing": "⏮️ Tilbake til **%s** - %s",
+	"previous.empty":   "❌ Ingen sanger har spilt ennå som kan gås tilbake til.",
+	"previous.gone":    "❌ Den forrige sangen er ikke lastet ned lenger. Legg den til igjen med `/play`.",
+	"previous.failed":  "❌ Kunne ikke gå tilbake til den forrige sangen.",
+
+	"history.title":        "🕘 Nylig spilt",
+	"history.line":         "`%d.` **%s** - %s <t:%d:R>%s",
+	"history.flag_skipped": " ⏭️",
+	"history.footer":       "Bruk /previous for å gå tilbake til den siste.",
+	"history.empty":        "📭 Ingen sanger har spilt ennå.",
+
+	"clear.already_empty":         "📭 Køen er allerede tom.",
+	"clear.downloads_pending":     "⏳ Kan ikke tømme køen mens %d sanger lastes ned. Vent til nedlastingene er ferdige.",
+	"clear.failed":                "❌ Klarte ikke å tømme køen.",
+	"clear.cleared_radio":         "🗑️ Køen er tømt. Radioen fortsetter å spille.",
+	"clear.cleared_return_failed": "🗑️ Køen er tømt, men klarte ikke å gå tilbake til ventekanalen.",
+	"clear.cleared_returned":      "🗑️ Køen er tømt. Gikk tilbake til ventekanalen og startet radioen igjen.",
+	"clear.confirm_title":         "Tømme køen?",
+	"clear.confirm":               "Dette fjerner %d spor fra køen.",
+	"clear.confirm_button":        "Tøm",
+	"clear.cancel_button":         "Avbryt",
+	"clear.cancelled":             "👍 Køen ble latt være.",
+	"clear.undo_hint":             "↩️ Ombestemt deg? Du kan angre frem til <t:%d:T>.",
+	"clear.undo_button":           "Angre",
+	"clear.undo_expired":          "⌛ Den tømte køen kan ikke lenger gjenopprettes.",
+	"clear.undo_failed":           "❌ Klarte ikke å gjenopprette køen.",
+	"clear.undo_all_missing":      "❌ Ingen av de fjernede sangene kunne gjenopprettes, filene er borte.",
+	"clear.restored":              "↩️ La %d sanger tilbake i køen.",
+	"clear.restored_missing":      "⚠️ %d sanger ble hoppet over fordi filene er borte eller køen er full.",
+
+	"volume.current":     "🔊 Nåværende volum: %d%%",
+	"volume.save_failed": "🔊 Volumet er satt til %d%%, men kunne ikke lagres i databasen.",
+	"volume.set":         "🔊 Volumet er satt til %d%%",
+
+	"filter.current":     "🎛️ Nåværende filter: **%s**",
+	"filter.set":         "🎛️ Filteret er satt til **%s**. Radio og direktesendinger spilles fortsatt uten filter.",
+	"filter.set_next":    "🎛️ Filteret er satt til **%s**. Det gjelder fra neste sang.",
+	"filter.off":         "🎛️ Filteret er slått av.",
+	"filter.unknown":     "❌ Ukjent filter.",
+	"filter.save_failed": "❌ Klarte ikke å lagre filteret.",
+	"filter.disabled":    "❌ Lydfiltre er slått av på denne boten.",
+
+	"nowplaying.dj_no_song":  "🎵 **DJ-modus** - Ingen sang spilles akkurat nå",
+	"nowplaying.playing":     "🎧 **Spilles nå:**\n**%s** - %s\n⏱️ Lengde: %s%s",
+	"nowplaying.filter":      "\n🎛️ Filter: %s",
+	"nowplaying.up_next":     "\n\n📋 **Neste:**\n",
+	"nowplaying.radio_named": "📻 **Radiomodus** - Spiller: %s",
+	"nowplaying.radio":       "📻 **Radiomodus** - Spiller radiostrøm",
+	"nowplaying.idle_named":  "😴 **Hvilemodus** - Spiller: %s",
+	"nowplaying.idle":        "😴 **Hvilemodus** - Spiller radiostrøm",
+	"nowplaying.unknown":     "❓ **Ukjent tilstand** - Usikker på hva som spilles",
+
+	"grab.nothing_playing": "❌ Ingenting spilles som kan lagres.",
+	"grab.save_failed":     "❌ Kunne ikke lagre sporet.",
+	"grab.sent":            "📬 Lagret og sendt på DM. Bruk `/grabs` for å se de lagrede sporene dine.",
+	"grab.dm_failed":       "📌 Lagret, men jeg kunne ikke sende deg DM, så her er det. Bruk `/grabs` for å se de lagrede sporene dine.",
+	"grab.artist":          "Artist",
+	"grab.unknown_artist":  "Ukjent",
+	"grab.duration":        "Varighet",
+	"grab.link":            "Lenke",
+	"grab.footer":          "Lagret",
+
+	"grabs.title":        "📌 Dine lagrede spor",
+	"grabs.line":         "`%d.` [%s](%s) <t:%d:R>",
+	"grabs.flag_radio":   " 📻",
+	"grabs.queue_button": "Legg til %d",
+	"grabs.empty":        "📭 Du har ikke lagret noe ennå. Bruk `/grab` mens noe spilles.",
+	"grabs.failed":       "❌ Kunne ikke lese de lagrede sporene dine.",
+	"grabs.not_found":    "❌ Det lagrede sporet finnes ikke lenger.",
+
+	"pin.nothing_playing":   "❌ Ingenting spilles. Oppgi URL-en til sporet som skal festes.",
+	"pin.stream":            "❌ Direktesendinger har ingen fil å beholde.",
+	"pin.unknown":           "❌ Det sporet er ikke i hurtigbufferen. Spill det én gang, og fest det så.",
+	"pin.failed":            "❌ Noe gikk galt med de festede sporene.",
+	"pin.pinned":            "📌 Festet **%s**. Det blir i hurtigbufferen til det løsnes.",
+	"pin.already_pinned":    "📌 **%s** er allerede festet.",
+	"pin.missing_upload":    "⚠️ Filen mangler, og en opplastet fil kan ikke lastes ned igjen.",
+	"pin.redownloading":     "⏳ Filen mangler, så den lastes ned igjen.",
+	"pin.redownload_failed": "⚠️ Filen mangler og kunne ikke lastes ned igjen: %s",
+	"pin.unpinned":          "Løsnet **%s**. Hurtigbufferen kan fjerne det igjen når det er gammelt eller bufferen er full.",
+	"pin.not_pinned":        "**%s** er ikke festet.",
+	"pins.none":             "Ingen spor er festet.",
+	"pins.title":            "📌 **Festede spor** (%d)\n",
+	"pins.line":             "`%d.` %s - %s\n",
+	"pins.missing":          "filen mangler",
+	"pins.more":             "...og %d til\n",
+	"pins.total":            "Festede filer tar %s",
+	"pins.budget":           " av hurtigbufferen på %s.",
+	"pins.over_half":        "⚠️ Festede spor tar mer enn halve hurtigbufferen, noe som gir lite plass til alt annet.",
+
+	"pause.no_music":       "❌ Ingen musikk spilles akkurat nå.",
+	"pause.already_paused": "❌ Musikken er allerede satt på pause.",
+	"pause.failed":         "❌ Klarte ikke å sette musikken på pause.",
+	"pause.paused":         "⏸️ Musikken er satt på pause. Bruk `/resume` for å fortsette.",
+
+	"resume.no_queue":        "❌ Det finnes ingen musikkø å fortsette.",
+	"resume.already_playing": "❌ Musikken spiller allerede.",
+	"resume.failed":          "❌ Klarte ikke å fortsette musikken.",
+	"resume.resumed":         "▶️ Musikken fortsetter!",
+
+	"join.already_here": "✅ Er allerede i talekanalen din.",
+	"join.joined_radio": "✅ Ble med i talekanalen din og startet radioen.",
+	"join.joined_music": "✅ Ble med i talekanalen din og fortsatte musikken.",
+
+	"leave.failed":       "❌ Klarte ikke å gå tilbake til ventekanalen.",
+	"leave.idle_channel": "✅ Dette er ventekanalen. Radioen fortsetter å spille.",
+	"leave.returned":     "✅ Gikk tilbake til ventekanalen og startet radioen igjen.",
+
+	"follow.enabled":            "👣 Følger deg — jeg flytter meg med deg mellom talekanalene. Bruk /follow igjen for å slutte.",
+	"follow.disabled":           "✅ Sluttet å følge deg.",
+	"follow.stopped_disconnect": "👣 <@%s> forlot talekanalen, så jeg blir her og følger ikke lenger.",
+	"follow.no_permission":      "⚠️ Kan ikke følge inn i <#%s>: jeg trenger tillatelse til å koble til og snakke der.",
+	"follow.channel_full":       "⚠️ Kan ikke følge inn i <#%s>: kanalen er full.",
+
+	"voice.reconnect_failed": "⚠️ Mistet forbindelsen til talekanalen og klarte ikke å koble til igjen. Bruk /join eller /play for å hente meg tilbake.",
+
+	"setidlechannel.current":      "📻 Ventekanalen er <#%s>.",
+	"setidlechannel.current_none": "📻 Ingen ventekanal er satt.",
+	"setidlechannel.not_voice":    "❌ Ventekanalen må være en talekanal.",
+	"setidlechannel.save_failed":  "❌ Klarte ikke å lagre ventekanalen.",
+	"setidlechannel.set":          "✅ Ventekanalen er satt til <#%s>.",
+
+	"setrole.current":         "👥 %s-rollen er **%s**.",
+	"setrole.current_name":    "👥 Ingen %s-rolle er satt. Roller som heter **%s** brukes.",
+	"setrole.everyone":        "❌ @everyone kan ikke være %s-rollen.",
+	"setrole.save_failed":     "❌ Kunne ikke lagre %s-rollen.",
+	"setrole.set":             "✅ %s-rollen er satt til **%s**.",
+	"setrole.reset":           "✅ %s-rollen er tilbakestilt. Roller som heter **%s** brukes igjen.",
+	"setrole.admin_not_yours": "⚠️ Du har ikke denne rollen, så du kan ikke lenger bruke admin-kommandoer selv.",
+	"roles.deleted":           "⚠️ %s-rollen ble slettet. Roller som heter **%s** brukes til en ny er satt.",
+
+	"clip.playing":       "🔊 Spiller **%s**.",
+	"clip.added":         "✅ La til klippet **%s** (%s).",
+	"clip.removed":       "🗑️ Fjernet klippet **%s**.",
+	"clip.not_found":     "❌ Det finnes ikke noe klipp som heter **%s**.",
+	"clip.exists":        "❌ Det finnes allerede et klipp som heter **%s**.",
+	"clip.invalid_name":  "❌ Klippnavn kan bare inneholde bokstaver, tall, - og _, og være på høyst %d tegn.",
+	"clip.too_long":      "❌ Det klippet er %s langt. Klipp kan være på høyst %d sekunder.",
+	"clip.radio":         "❌ Klipp kan ikke spilles over radioen.",
+	"clip.busy":          "❌ Et klipp spilles allerede.",
+	"clip.not_connected": "❌ Jeg er ikke i en talekanal.",
+	"clip.none":          "Ingen klipp ennå. Legg til et med `/clip add`.",
+	"clip.title":         "🔊 Klipp (%d)",
+	"clip.failed":        "❌ Noe gikk galt med det klippet.",
+
+	"schedule.added":            "⏰ Planla #%d: %s <t:%d:F> i <#%s>.",
+	"schedule.removed":          "🗑️ Avlyste planlagt avspilling #%d.",
+	"schedule.not_found":        "❌ Det finnes ingen planlagt avspilling #%d.",
+	"schedule.none":             "Ingenting er planlagt.",
+	"schedule.title":            "⏰ Planlagt (%d)",
+	"schedule.line":             "`#%d` <t:%d:F> %s i <#%s> av <@%s>",
+	"schedule.flag_playlist":    " · spilleliste",
+	"schedule.flag_preempt":     " · avbryter",
+	"schedule.bad_url":          "❌ Det er ikke en gyldig http(s)-URL.",
+	"schedule.bad_time":         "❌ Jeg forstår ikke det tidspunktet. Bruk et ISO-tidspunkt som `2025-06-01T20:00+02:00` (UTC uten forskyvning) eller en forsinkelse som `in 2h`.",
+	"schedule.in_past":          "❌ Det tidspunktet har allerede vært.",
+	"schedule.too_far":          "❌ Avspilling kan planlegges høyst %d dager frem i tid.",
+	"schedule.too_many":         "❌ Denne serveren har allerede %d planlagte spor og spillelister.",
+	"schedule.preempt_playlist": "❌ Bare spor kan avbryte det som spilles; spillelister legges i kø bak.",
+	"schedule.failed_command":   "❌ Klarte ikke å oppdatere planen.",
+	"schedule.starting":         "⏰ Starter planlagt %s, ønsket av <@%s>, i <#%s>.",
+	"schedule.queued_behind":    "⏰ Planlagt %s av <@%s> er lagt i kø bak musikken som spilles.",
+	"schedule.preempting":       "⏰ Planlagt %s av <@%s> tar over for sangen som spilles.",
+	"schedule.join_failed":      "⏰ Planlagt %s kunne ikke starte: jeg kom ikke inn i <#%s>.",
+	"schedule.failed":           "⏰ Planlagt %s mislyktes: %v",
+
+	"idle.deleted_fallback":    "⚠️ %s Ventekanalen ble slettet. Jeg venter i <#%s> inntil videre — bruk /setidlechannel for å velge en ny.",
+	"idle.deleted_no_fallback": "⚠️ %s Ventekanalen ble slettet, og jeg kan ikke bli med i noen annen talekanal. Bruk /setidlechannel for å velge en ny.",
+
+	"shutdown.announce": "🔧 Slår meg av for vedlikehold — køen er lagret (%d spor).",
+
+	"music.skipped_missing":           "⏭️ Hoppet over **%s**: filen mangler og kan ikke lastes ned på nytt.",
+	"music.skipped_redownload_failed": "⏭️ Hoppet over **%s**: filen mangler, og nedlastingen på nytt mislyktes (%s).",
+	"music.skipped_playback_failed":   "⏭️ Hoppet over **%s**: avspillingen brøt av to ganger (%s). Den står i /failures.",
+
+	"changestream.invalid": "❌ Ugyldig strøm.",
+	"changestream.failed":  "❌ Klarte ikke å bytte strøm.",
+	"changestream.changed": "✅ Byttet radiostrøm til %s",
+
+	"restart.not_in_music": "❌ Er ikke i en musikkanal. Bruk `/resume` for å starte køen.",
+	"restart.failed":       "❌ Klarte ikke å starte køen på nytt: %v",
+	"restart.restarted":    "🔁 Startet køen på nytt med %d sanger.",
+
+	"delmsg.fetch_failed": "❌ Klarte ikke å hente meldinger.",
+	"delmsg.none":         "❌ Fant ingen meldinger å slette.",
+	"delmsg.partial":      "⚠️ Delvis fullført: slettet %d meldinger, men det oppstod feil.",
+	"delmsg.done":         "✅ Slettet %d meldinger.",
+
+	"ping.pong":              "🏓 **Pong!**\n\n📡 **WebSocket-forsinkelse:** %dms %s\n⚡ **Responstid:** %dms\n🤖 **Botstatus:** Tilkoblet og klar\n⬇️ **Nedlasterstatus:** %s\n📶 **Nedlasterping:** %s",
+	"ping.downloader_error":  "\n❌ **Nedlasterfeil:** %s",
+	"ping.latency_excellent": "🟢 (Utmerket)",
+	"ping.latency_good":      "🟡 (God)",
+	"ping.latency_fair":      "🟠 (Middels)",
+	"ping.latency_poor":      "🔴 (Dårlig)",
+
+	"shardinfo.info": "🧩 Denne serveren betjenes av **shard %d av %d**\n📡 **Gateway-forsinkelse:** %dms\n🏠 **Servere på shard %d:** %d",
+
+	"help.header":              "🤖 **Musikkbot-kommandoer**\n\n",
+	"help.category":            "**%s-kommandoer:**\n",
+	"help.role_only":           " *(kun %s)*",
+	"help.tips":                "💡 **Tips:**\n• Bruk `/nowplaying` for å se hva som spilles\n• Boten bytter automatisk mellom radio- og musikkmodus\n• Når køen er tom, starter radioen automatisk igjen",
+	"help.category.utility":    "Verktøy",
+	"help.category.voice":      "Tale",
+	"help.category.music":      "Musikk",
+	"help.category.radio":      "Radio",
+	"help.category.moderation": "Moderering",
+	"help.category.admin":      "Administrasjon",
+	"help.line":                "• `/%s` - %s%s\n",
+	"help.page":                "Side %d/%d • Bruk `/help command:<navn>` for detaljer",
+	"help.none":                "❌ Det finnes ingen kommandoer du kan bruke her.",
+	"help.unknown_command":     "❌ Det finnes ingen kommando som heter `/%s`.",
+	"help.no_options":          "Ingen",
+	"help.required":            " *(påkrevd)*",
+	"help.access_everyone":     "Alle",
+	"help.access_role":         "%s-rollen",
+	"help.access_dj_only":      "%s-rollen når DJ-modus er på",
+	"help.cooldown":            "Én gang hvert %d. sekund per bruker (administratorer er unntatt)",
+	"help.max_concurrent":      "Maks %d samtidig per server",
+	"help.field_options":       "Valg",
+	"help.field_access":        "Hvem kan bruke den",
+	"help.field_limits":        "Begrensninger",
+	"help.field_examples":      "Eksempler",
+
+	"downloader.disabled":             "❌ Ingen nedlaster er satt opp.",
+	"downloader.status":               "⬇️ **Nedlaster:** %s\n📡 **Siste pong:** %s\n📶 **Forsinkelse:** %s\n⏳ **Ventende forespørsler:** %d",
+	"downloader.latency_disconnected": "Frakoblet",
+	"downloader.latency_failed":       "Mislyktes (%s)",
+	"downloader.reconnected":          "✅ Koblet til nedlasteren på nytt.",
+	"downloader.reconnect_failed":     "❌ Klarte ikke å koble til nedlasteren på nytt: %s",
+	"downloader.pending_none":         "✅ Ingen forespørsler venter på nedlasteren.",
+	"downloader.pending_title":        "⏳ **%d forespørsler venter på nedlasteren:**\n",
+	"downloader.pending_line":         "• `%s` %s, %s",
+	"downloader.pending_more":         "…og %d til\n",
+	"downloader.cancel_all":           "Avbryt alle",
+	"downloader.cancelled":            "🛑 Avbrøt %d forespørsler.",
+
+	"session.not_connected":          "❌ Jeg er ikke i en talekanal. Start musikken først, så økten.",
+	"session.already_running":        "❌ En lytteøkt pågår allerede. Stopp den med `/session stop` først.",
+	"session.not_running":            "❌ Ingen lytteøkt pågår.",
+	"session.none":                   "Ingen lytteøkt er registrert her ennå.",
+	"session.failed":                 "❌ Noe gikk galt med lytteøkten.",
+	"session.started":                "🔴 **Lytteøkt startet** i <#%s> av <@%s>.\nFra nå av noterer jeg hvilke spor som spilles, hvem som er i talekanalen (sjekkes hvert minutt) og reaksjonene på sporene jeg poster her. **Ingen lyd tas opp.** En admin avslutter den med `/session stop`.",
+	"session.track":                  "🎶 Spilles nå: **%s**",
+	"session.track_artist":           "🎶 Spilles nå: **%s** av %s",
+	"session.summary_title":          "📋 Lytteøkt",
+	"session.summary_overview":       "I <#%s>, startet av <@%s> <t:%d:f>\nVarte %s · %d spor · topp på %d lyttere · %d personer til stede",
+	"session.summary_tracks":         "Spor",
+	"session.summary_track":          "`%d.` <t:%d:t> %s - 👥 %d · %d reaksjoner",
+	"session.summary_no_tracks":      "Ingen spor ble spilt.",
+	"session.summary_listeners":      "Lyttere",
+	"session.summary_listener":       "<@%s> - %d min",
+	"session.summary_more_listeners": "...og %d til",
+	"session.summary_no_listeners":   "Ingen var i talekanalen.",
+
+	"status.title":                "📊 Botstatus",
+	"status.refresh":              "🔄 Oppdater",
+	"status.downloader":           "Nedlaster",
+	"status.downloader_value":     "%s\nSiste pong: %s",
+	"status.downloader_disabled":  "Ikke konfigurert",
+	"status.never":                "aldri",
+	"status.ago":                  "for %s siden",
+	"status.pending":              "Ventende nedlastinger",
+	"status.pending_value":        "%d",
+	"status.mode":                 "Modus",
+	"status.mode_dj":              "🎵 DJ",
+	"status.mode_radio":           "📻 Radio",
+	"status.mode_idle":            "😴 Hvile",
+	"status.mode_transitioning":   "🔄 Bytter modus",
+	"status.player":               "Spiller",
+	"status.player_playing":       "▶️ Spiller",
+	"status.player_paused":        "⏸️ Pauset",
+	"status.player_stopped":       "⏹️ Stoppet",
+	"status.player_track":         "%s\n**%s** - %s",
+	"status.radio":                "Radio",
+	"status.radio_playing":        "📻 Spiller\n%s",
+	"status.radio_stopped":        "⏹️ Stoppet\n%s",
+	"status.radio_underruns":      "\nBuffer-tømminger: %d",
+	"status.database":             "Database",
+	"status.database_value":       "`%s`\n%d sanger",
+	"status.database_error":       "`%s`\nAntall sanger utilgjengelig",
+	"status.search_cache":         "Søkebuffer",
+	"status.search_cache_value":   "%d treff, %d bom\n%d søk bufret",
+	"status.uptime":               "Oppetid",
+	"status.runtime":              "Kjøretid",
+	"status.runtime_value":        "%d goroutiner\n%.1f MiB heap",
+	"status.janitor":              "Opprydding",
+	"status.janitor_value":        "Siste kjøring %s: fjernet %d filer eller oppføringer, frigjorde %s. Hurtigbufferstørrelse: %s",
+	"status.janitor_failed":       "Siste kjøring %s feilet: %v",
+	"status.janitor_pending":      "Ikke kjørt ennå",
+	"status.janitor_disabled":     "Ikke konfigurert",
+	"status.dependencies":         "Avhengigheter",
+	"status.dependencies_ok":      "✅ ffmpeg, socketmappe og nedlastingsmappe OK (sjekket %s)",
+	"status.dependencies_failed":  "⚠️ Fant problemer (sjekket %s). Rett dem og kjør `/status refresh:true`:",
+	"status.dependency_line":      "• %v",
+	"status.dependencies_pending": "Ikke sjekket ennå",
+	"status.dependencies_skipped": "Ikke sjekket (skip_dep_check)",
+	"status.dj_only":              "Kun DJ",
+	"status.dj_only_on":           "🔒 På (%s)",
+	"status.dj_only_off":          "🔓 Av",
+	"status.always_on":            "24/7-modus",
+	"status.always_on_on":         "🔁 På (<#%s>)",
+	"status.always_on_off":        "Av",
+	"status.roles":                "Roller",
+	"status.role_line":            "%s: %s",
+	"status.role_not_configured":  "ikke satt (bruker **%s** etter navn)",
+
+	"lyrics.title":     "%s — %s",
+	"lyrics.page":      "Side %d av %d",
+	"lyrics.not_found": "❌ Fant ingen sangtekst for **%s**.",
+
+	"trim.nothing":      "❌ Oppgi `start`, `end` eller begge.",
+	"trim.no_song":      "❌ Du har ingen kommende sang å klippe.",
+	"trim.not_found":    "❌ Det er ingen sang på plass %d i køen.",
+	"trim.not_yours":    "❌ Bare den som la til sangen eller medlemmer med rollen **%s** kan klippe den.",
+	"trim.no_duration":  "❌ Sangen har ingen kjent lengde, så den kan ikke klippes.",
+	"trim.invalid_time": "❌ `%s` er ikke et tidspunkt. Bruk sekunder, m:ss eller t:mm:ss.",
+	"trim.out_of_range": "❌ Sangen er %s lang. Starten må være før slutten, og begge må være innenfor sangen.",
+	"trim.failed":       "❌ Klarte ikke å klippe sangen.",
+	"trim.trimmed":      "✂️ **%s** spilles fra %s til %s (%s).",
+
+	"remove.nothing":   "❌ Oppgi `position`, `user` eller begge.",
+	"remove.bad_range": "❌ %d til %d er ikke et område. Slutten må komme etter starten.",
+	"remove.not_found": "❌ Køen når ikke til plass %d.",
+	"remove.not_yours": "❌ Du kan bare fjerne dine egne sanger. For å fjerne andres trenger du rollen **%s**.",
+	"remove.none":      "❌ Ingen passende sanger venter i køen.",
+	"remove.failed":    "❌ Klarte ikke å fjerne sangene.",
+	"remove.removed":   "🗑️ Fjernet %d fra køen:",
+	"remove.more":      "…og %d til",
+
+	"language.current":     "🌐 Nåværende språk: %s",
+	"language.set":         "🌐 Språket er satt til %s.",
+	"language.unsupported": "❌ Språket støttes ikke: %s",
+	"language.save_failed": "❌ Klarte ikke å lagre språkvalget.",
+
+	"requestchannel.set":         "📨 <#%s> er nå ønskekanalen. Lenker som postes der spilles, og meldinger som starter med `?` blir søkt etter.",
+	"requestchannel.unset":       "📨 Ønskekanalen er slått av.",
+	"requestchannel.not_set":     "❌ Det er ingen ønskekanal.",
+	"requestchannel.disabled":    "❌ Ønskekanaler er slått av på denne boten. Eieren av boten kan slå dem på med `request_channels` i konfigurasjonsfilen, etter å ha slått på message content intent i Discords utviklerportal.",
+	"requestchannel.save_failed": "❌ Klarte ikke å lagre ønskekanalen.",
+	"requestchannel.deleted":     "⚠️ %s Ønskekanalen ble slettet, så ønsker via meldinger er slått av. Bruk /requestchannel set for å velge en ny.",
+
+	"queuemode.current":     "🔀 Kømodus: **%s**",
+	"queuemode.set_fifo":    "🔀 Kømodus satt til **fifo**: sangene spilles i den rekkefølgen de ble lagt til.",
+	"queuemode.set_fair":    "🔀 Kømodus satt til **fair**: de som ønsker sanger bytter på, hver i sin egen rekkefølge.",
+	"queuemode.unknown":     "❌ Ukjent kømodus.",
+	"queuemode.save_failed": "❌ Klarte ikke å lagre kømodusen.",
+
+	"style.current":     "🎨 Nåværende stil: **%s**",
+	"style.set":         "🎨 Stilen er satt til **%s**.",
+	"style.unknown":     "❌ Ukjent stil.",
+	"style.save_failed": "❌ Klarte ikke å lagre stilen.",
+
+	"presence.title":             "🪪 **Statusmaler**\n",
+	"presence.playing":           "**Mens en sang spilles:** ",
+	"presence.radio":             "**Mens radioen spilles:** ",
+	"presence.idle":              "**Mens ingenting spilles**, én hvert %d. minutt:\n",
+	"presence.line":              "`%s` → %s\n",
+	"presence.placeholders":      "\nPlassholdere: %s",
+	"presence.shared":            "\nBoten er på mer enn én server, så bare inaktiv-statusene vises.",
+	"presence.preview":           "🪪 Akkurat nå vises dette som: **%s**",
+	"presence.saved":             "✅ Statusmalene er lagret.",
+	"presence.save_failed":       "❌ Klarte ikke å lagre statusmalene.",
+	"presence.idle_full":         "❌ Rotasjonen kan ha maks %d statuser.",
+	"presence.idle_out_of_range": "❌ Det er bare %d inaktiv-statuser.",
+	"presence.idle_last":         "❌ Den siste inaktiv-statusen kan ikke fjernes. Bruk `/presence reset` eller legg til en annen først.",
+
+	"config.list_title":   "⚙️ **Lagrede innstillinger**\nEndre en med `/config set`.\n\n",
+	"config.line":         "• `%s` (%s) = `%s`\n  %s\n",
+	"config.get":          "⚙️ **%s**\n%s\n\n**Type:** %s\n**Nåværende verdi:** `%s`\n**Standard:** %s",
+	"config.default_file": "fra config.json",
+	"config.unknown":      "❌ Det finnes ingen innstilling `%s`. Gyldige innstillinger: `%s`",
+	"config.invalid":      "❌ Ugyldig verdi for `%s` (%s): %s",
+	"config.save_failed":  "❌ Kunne ikke lagre `%s`. Prøv igjen.",
+	"config.saved":        "✅ `%s` endret fra `%s` til `%s`.",
+	"config.owner_only":   "❌ `%s` deles av alle serverne boten er på, så bare botens eiere kan endre den.",
+
+	"render.footer":       "Musicbot %s • Shard %d/%d",
+	"render.queue_title":  "🎵 Musikkø",
+	"render.search_title": "🔍 Søkeresultater",
+	"render.now_playing":  "🎧 Spilles nå",
+	"render.duration":     "Varighet",
+	"render.requested_by": "Ønsket av",
+	"render.requested_at": "<t:%d:R>",
+	"render.filter":       "Filter",
+	"render.help_title":   "📖 %s-kommandoer",
+	"render.up_next":      "Neste",
+	"render.uploaded":     "lastet opp %s",
+	"render.views":        "%s visninger",
+
+	"setlimit.current":     "📏 Kø-grenser: **%d** sanger totalt, **%d** per bruker.",
+	"setlimit.set":         "✅ Kø-grensene er satt til **%d** sanger totalt, **%d** per bruker.",
+	"setlimit.save_failed": "❌ Klarte ikke å lagre kø-grensene.",
+
+	"setmaxduration.current":     "📏 Sanger kan være opptil **%d** minutter lange.",
+	"setmaxduration.set":         "✅ Sanger kan nå være opptil **%d** minutter lange.",
+	"setmaxduration.save_failed": "❌ Klarte ikke å lagre lengdegrensen.",
+
+	"setmaxsize.current":     "📏 Sanger kan være opptil **%d** MB.",
+	"setmaxsize.set":         "✅ Sanger kan nå være opptil **%d** MB.",
+	"setmaxsize.save_failed": "❌ Klarte ikke å lagre størrelsesgrensen.",
+
+	"setretries.current":     "🔁 Nedlastinger prøves opptil **%d** ganger.",
+	"setretries.set":         "✅ Nedlastinger prøves nå opptil **%d** ganger.",
+	"setretries.save_failed": "❌ Klarte ikke å lagre innstillingen for nye forsøk.",
+
+	"djonly.current_on":  "🔒 Bare DJ-er kan styre musikken. Det krever rollen **%s**.",
+	"djonly.current_off": "🔓 Alle kan styre musikken. Med DJ-modus på kreves rollen **%s**.",
+	"djonly.enabled":     "🔒 Bare medlemmer med rollen **%s** kan styre musikken nå.",
+	"djonly.disabled":    "🔓 Alle kan styre musikken igjen.",
+	"djonly.save_failed": "❌ Klarte ikke å lagre DJ-modus.",
+
+	"alwayson.current_on":          "🔁 24/7-modus er på. Jeg blir i <#%s> hele døgnet.",
+	"alwayson.current_off":         "💤 24/7-modus er av. Jeg går tilbake til ventekanalen når alle forlater kanalen.",
+	"alwayson.no_channel":          "❌ Jeg er ikke i en talekanal. Velg en med `channel`-valget.",
+	"alwayson.enabled":             "🔁 24/7-modus er på. Jeg blir i <#%s>, også når den er tom, og spiller radio når køen er tom.",
+	"alwayson.enabled_later":       "🔁 24/7-modus er på. Jeg flytter til <#%s> når kanalen jeg er i blir tom eller noen bruker /leave.",
+	"alwayson.enabled_move_failed": "⚠️ 24/7-modus er på, men jeg kom ikke inn i <#%s> ennå. Jeg går dit når kanalen jeg er i blir tom eller noen bruker /leave.",
+	"alwayson.disabled":            "💤 24/7-modus er av. Jeg går tilbake til ventekanalen når alle forlater kanalen.",
+	"alwayson.save_failed":         "❌ Klarte ikke å lagre 24/7-modus.",
+	"alwayson.deleted":             "⚠️ %s 24/7-kanalen ble slettet, så 24/7-modus er slått av. Bruk /247 for å slå den på igjen.",
+	"alwayson.rejoin_failed":       "⚠️ Mistet forbindelsen og kom ikke tilbake i 24/7-kanalen. Jeg prøver igjen hvert %d. sekund.",
+
+	"blacklist.url_added":         "🚫 Lenker som passer med `%s` kan ikke lenger legges i køen.",
+	"blacklist.url_removed":       "✅ Lenker som passer med `%s` kan legges i køen igjen.",
+	"blacklist.url_not_found":     "❌ `%s` står ikke på svartelisten.",
+	"blacklist.user_added":        "🚫 <@%s> kan ikke lenger legge til musikk.",
+	"blacklist.user_added_queued": "🚫 <@%s> kan ikke lenger legge til musikk. De har fortsatt %d sanger i køen.",
+	"blacklist.user_removed":      "✅ <@%s> kan legge til musikk igjen.",
+	"blacklist.user_not_found":    "❌ <@%s> står ikke på svartelisten.",
+	"blacklist.purge_button":      "Fjern de %d sangene",
+	"blacklist.keep_button":       "Behold dem",
+	"blacklist.purged":            "🚫 <@%s> kan ikke lenger legge til musikk. Fjernet %d av sangene deres fra køen.",
+	"blacklist.failed":            "❌ Klarte ikke å oppdatere svartelisten.",
+	"blacklist.title":             "🚫 Svarteliste",
+	"blacklist.urls":              "Lenkemønstre",
+	"blacklist.users":             "Brukere",
+	"blacklist.none":              "Ingen",
+	"blacklist.more":              "…og %d til",
+	"blacklist.user_blocked":      "🚫 Du har ikke lov til å legge til musikk på denne serveren.",
+	"blacklist.url_blocked":       "🚫 Den lenken er blokkert på denne serveren (passer med `%s`).",
+
+	"djban.timed_out":  "⏳ Du kan ikke legge til musikk på denne serveren akkurat nå. Utestengingen din slutter <t:%d:R>.",
+	"djban.failed":     "❌ Noe gikk galt med utestengingen.",
+	"djban.added":      "⏳ <@%s> kan ikke legge til musikk før <t:%d:f>. Fjernet %d av sangene deres fra køen.",
+	"djban.none":       "Ingen er utestengt fra køen.",
+	"djban.title":      "⏳ **Utestengt fra køen** (%d)\n",
+	"djban.line":       "<@%s> - %s igjen, til <t:%d:f> (av <@%s>)\n",
+	"djban.not_banned": "<@%s> er ikke utestengt.",
+	"djban.removed":    "✅ <@%s> kan legge til musikk igjen.",
+
+	"audit.line":        "<t:%d:f> <@%s> `%s`",
+	"audit.line_target": "<t:%d:f> <@%s> `%s` %s",
+	"auditlog.title":    "📜 Revisjonslogg",
+	"auditlog.empty":    "📜 Ingenting er registrert ennå.",
+	"auditlog.failed":   "❌ Klarte ikke å lese revisjonsloggen.",
+
+	"failures.title":          "⚠️ Mislykkede nedlastinger",
+	"failures.line":           "`%d.` <t:%d:R> `%s` %s av %s\n> %s",
+	"failures.track":          "spor %d av %s",
+	"failures.unknown_user":   "ukjent",
+	"failures.retry_button":   "Prøv %d igjen",
+	"failures.empty":          "✅ Ingen nedlastinger har mislyktes de siste 30 dagene.",
+	"failures.failed":         "❌ Klarte ikke å lese de mislykkede nedlastingene.",
+	"failures.not_found":      "❌ Den feilen finnes ikke. Bruk /failures for å se listen.",
+	"failures.playlist_track": "❌ Spor fra en spilleliste kan ikke prøves alene. Be om spillelisten på nytt i stedet.",
+	"failures.not_connected":  "❌ Jeg er ikke i en talekanal. Bruk /join først.",
+
+	"reloadconfig.unchanged":    "✅ Konfigurasjonen er lastet inn på nytt, ingenting endret.",
+	"reloadconfig.done":         "🔄 Konfigurasjonen er lastet inn på nytt.",
+	"reloadconfig.applied":      "**Tatt i bruk:**",
+	"reloadconfig.rejected":     "**Ikke tatt i bruk, krever omstart:**",
+	"reloadconfig.failed":       "❌ Klarte ikke å laste inn konfigurasjonen på nytt: %v",
+	"synccommands.done":         "✅ Skråstrekkommandoer synkronisert: %d opprettet, %d oppdatert, %d fjernet, %d uendret.",
+	"synccommands.some_failed":  "⚠️ %d endringer feilet, se loggen.",
+	"synccommands.failed":       "❌ Kunne ikke synkronisere skråstrekkommandoer: %v",
+	"commands.disabled_here":    "🚫 /%s er slått av på denne serveren.",
+	"commands.unknown":          "❌ Det finnes ingen /%s-kommando.",
+	"commands.protected":        "❌ /%s kan ikke slås av.",
+	"commands.already_disabled": "/%s er allerede slått av.",
+	"commands.disabled":         "🚫 /%s er nå slått av på denne serveren.",
+	"commands.not_disabled":     "/%s er ikke slått av.",
+	"commands.enabled":          "✅ /%s er slått på igjen.",
+	"commands.save_failed":      "❌ Klarte ikke å lagre endringen.",
+	"commands.list_none":        "Alle kommandoer er slått på på denne serveren.",
+	"commands.list":             "🚫 **Slått av på denne serveren:** %s",
+}
@@ -0,0 +1,17 @@
+// Package ids generates the IDs that correlate downloader requests with their
+// responses.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// New returns a random ID starting with prefix and an underscore, e.g.
+// "dl_3f0c…". The random part is 128 bits from crypto/rand, so IDs don't
+// collide no matter how many are made at once.
+func New(prefix string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return prefix + "_" + hex.EncodeToString(b)
+}
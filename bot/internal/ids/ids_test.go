@@ -0,0 +1,44 @@
+package ids
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewIsUnique(t *testing.T) {
+	const workers, perWorker = 8, 125000
+
+	// The IDs are made in a burst from several goroutines at once, which is
+	// when time-seeded IDs collided.
+	made := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range made {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			made[w] = make([]string, perWorker)
+			for k := range made[w] {
+				made[w][k] = New("dl")
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]struct{}, workers*perWorker)
+	for _, batch := range made {
+		for _, id := range batch {
+			if _, ok := seen[id]; ok {
+				t.Fatalf("%s was made twice", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestNewPrefix(t *testing.T) {
+	id := New("srch")
+	if !strings.HasPrefix(id, "srch_") || len(id) != len("srch_")+32 {
+		t.Errorf("New(\"srch\") = %q, want srch_ and 32 hex digits", id)
+	}
+}
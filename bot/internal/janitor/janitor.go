@@ -0,0 +1,458 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchSelectionTTL matches how long the search command honours persisted
+// buttons.
+const searchSelectionTTL = 24 * time.Hour
+
+// downloadFailureTTL is how long failed downloads are kept for /failures.
+const downloadFailureTTL = 30 * 24 * time.Hour
+
+// orphanGracePeriod keeps files the downloader wrote recently out of the
+// orphan sweep: a download in flight writes its audio and thumbnail before
+// the song is saved, and would otherwise be removed from under it.
+const orphanGracePeriod = time.Hour
+
+type Config struct {
+	// MusicDir is where the downloader writes audio files.
+	MusicDir string
+	// MaxAge removes songs not played (or downloaded) within it. Zero disables.
+	MaxAge time.Duration
+	// MaxCacheBytes evicts least recently used songs above it. Zero disables.
+	MaxCacheBytes int64
+	// Interval between runs after the one at startup.
+	Interval time.Duration
+}
+
+// Summary describes one janitor run.
+type Summary struct {
+	StartedAt         time.Time
+	Duration          time.Duration
+	OrphanedEntries   int
+	OrphanedFiles     int
+	ExpiredSongs      int
+	EvictedSongs      int
+	ExpiredSelections int64
+	ExpiredFailures   int64
+	ExpiredTimeouts   int64
+	FreedBytes        int64
+	CacheBytes        int64
+	Err               error
+}
+
+// Janitor keeps the download directory and the songs table in step and
+// bounds the size of the cache. Songs in the queue and pinned songs are
+// never removed.
+type Janitor struct {
+	dbManager *config.DatabaseManager
+	cfg       Config
+	protected func() []int64
+	last      *Summary
+	runMu     sync.Mutex
+	mu        sync.RWMutex
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+func New(dbManager *config.DatabaseManager, cfg Config) *Janitor {
+	return &Janitor{
+		dbManager: dbManager,
+		cfg:       cfg,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// SetProtectedSongs installs a callback returning song IDs that are in use but
+// may not have been persisted yet, e.g. the in-memory queue.
+func (j *Janitor) SetProtectedSongs(fn func() []int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.protected = fn
+}
+
+// Start runs the janitor in the background, once immediately and then every
+// Interval.
+func (j *Janitor) Start() {
+	go func() {
+		defer close(j.done)
+
+		j.RunOnce(context.Background())
+		if j.cfg.Interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(j.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				j.RunOnce(context.Background())
+			}
+		}
+	}()
+}
+
+func (j *Janitor) LastSummary() (Summary, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if j.last == nil {
+		return Summary{}, false
+	}
+	return *j.last, true
+}
+
+func (j *Janitor) Shutdown(ctx context.Context) error {
+	j.stopOnce.Do(func() { close(j.stop) })
+
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *Janitor) Name() string {
+	return "Janitor"
+}
+
+// MaxCacheBytes is the size the cache is kept under, or 0 if it isn't bounded.
+func (j *Janitor) MaxCacheBytes() int64 {
+	return j.cfg.MaxCacheBytes
+}
+
+// FileSize returns the size of the file at filePath, relative to the music
+// directory or absolute, and false if it is missing.
+func (j *Janitor) FileSize(filePath string) (int64, bool) {
+	_, size, ok := j.resolve(filePath)
+	return size, ok
+}
+
+// RunOnce performs a full cleanup pass and records its summary.
+func (j *Janitor) RunOnce(ctx context.Context) Summary {
+	j.runMu.Lock()
+	defer j.runMu.Unlock()
+
+	summary := Summary{StartedAt: time.Now()}
+	summary.Err = j.run(ctx, &summary)
+	summary.Duration = time.Since(summary.StartedAt)
+
+	if summary.Err != nil {
+		logger.Error.Printf("Janitor run failed: %v", summary.Err)
+	} else {
+		logger.Info.Printf("Janitor finished in %v: %d orphaned entries, %d orphaned files, %d expired, %d evicted, %s freed, cache now %s",
+			summary.Duration.Round(time.Millisecond), summary.OrphanedEntries, summary.OrphanedFiles,
+			summary.ExpiredSongs, summary.EvictedSongs, FormatBytes(summary.FreedBytes), FormatBytes(summary.CacheBytes))
+	}
+
+	j.mu.Lock()
+	j.last = &summary
+	j.mu.Unlock()
+
+	return summary
+}
+
+// cachedFile is a song whose audio file exists on disk.
+type cachedFile struct {
+	song config.CachedSong
+	path string
+	size int64
+}
+
+func (j *Janitor) run(ctx context.Context, summary *Summary) error {
+	info, err := os.Stat(j.cfg.MusicDir)
+	if err != nil {
+		return fmt.Errorf("music directory %s is not accessible: %w", j.cfg.MusicDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("music directory %s is not a directory", j.cfg.MusicDir)
+	}
+
+	protected, err := j.protectedSongs(ctx)
+	if err != nil {
+		return err
+	}
+
+	songs, err := j.dbManager.ListCachedSongsCtx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list songs: %w", err)
+	}
+
+	referenced := make(map[string]bool)
+	var files []cachedFile
+
+	for _, song := range songs {
+		if song.IsStream {
+			continue
+		}
+		if song.Pinned {
+			protected[song.ID] = true
+		}
+
+		path, size, ok := j.resolve(song.FilePath)
+		if ok {
+			referenced[path] = true
+			files = append(files, cachedFile{song: song, path: path, size: size})
+			continue
+		}
+
+		if protected[song.ID] {
+			continue
+		}
+
+		logger.Info.Printf("Janitor: removing entry with missing file: %s (ID: %d)", song.Title, song.ID)
+		if j.deleteSong(ctx, song.ID) {
+			summary.OrphanedEntries++
+		}
+	}
+
+	orphaned, freed := j.removeOrphanedFiles(referenced)
+	summary.OrphanedFiles = orphaned
+	summary.FreedBytes += freed
+
+	files = j.expire(ctx, files, protected, summary)
+	files = j.evict(ctx, files, protected, summary)
+
+	for _, f := range files {
+		summary.CacheBytes += f.size
+	}
+
+	removed, err := j.dbManager.DeleteExpiredSearchSelectionsCtx(ctx, searchSelectionTTL)
+	if err != nil {
+		logger.Error.Printf("Janitor: failed to clean search selections: %v", err)
+	}
+	summary.ExpiredSelections = removed
+
+	removed, err = j.dbManager.DeleteExpiredDownloadFailuresCtx(ctx, downloadFailureTTL)
+	if err != nil {
+		logger.Error.Printf("Janitor: failed to clean download failures: %v", err)
+	}
+	summary.ExpiredFailures = removed
+
+	removed, err = j.dbManager.DeleteExpiredQueueTimeoutsCtx(ctx)
+	if err != nil {
+		logger.Error.Printf("Janitor: failed to clean queue timeouts: %v", err)
+	}
+	summary.ExpiredTimeouts = removed
+
+	return nil
+}
+
+func (j *Janitor) protectedSongs(ctx context.Context) (map[int64]bool, error) {
+	protected, err := j.dbManager.QueuedSongIDsCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	j.mu.RLock()
+	fn := j.protected
+	j.mu.RUnlock()
+
+	if fn != nil {
+		for _, id := range fn() {
+			protected[id] = true
+		}
+	}
+
+	return protected, nil
+}
+
+// resolve finds a song's file and returns its cleaned path and size.
+func (j *Janitor) resolve(filePath string) (string, int64, bool) {
+	if filePath == "" {
+		return "", 0, false
+	}
+
+	path := config.ResolveTrackPath(j.cfg.MusicDir, filePath)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", 0, false
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path, info.Size(), true
+}
+
+func (j *Janitor) removeOrphanedFiles(referenced map[string]bool) (int, int64) {
+	entries, err := os.ReadDir(j.cfg.MusicDir)
+	if err != nil {
+		logger.Error.Printf("Janitor: failed to read music directory: %v", err)
+		return 0, 0
+	}
+
+	removed := 0
+	var freed int64
+
+	// Thumbnails are kept as long as a referenced track has the same name.
+	thumbnails := make(map[string]bool, len(referenced))
+	for path := range referenced {
+		thumbnails[config.TrackThumbnail(path)] = true
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		isThumbnail := strings.EqualFold(filepath.Ext(entry.Name()), config.ThumbnailExtension)
+		if !isThumbnail && !config.IsTrackFile(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(j.cfg.MusicDir, entry.Name())
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if referenced[path] || (isThumbnail && thumbnails[path]) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < orphanGracePeriod {
+			continue
+		}
+
+		size := info.Size()
+		logger.Info.Printf("Janitor: removing orphaned file: %s", path)
+		if err := os.Remove(path); err != nil {
+			logger.Error.Printf("Janitor: failed to remove %s: %v", path, err)
+			continue
+		}
+
+		removed++
+		freed += size
+	}
+
+	return removed, freed
+}
+
+// expire removes songs that have not been used within MaxAge and returns the
+// files that remain.
+func (j *Janitor) expire(ctx context.Context, files []cachedFile, protected map[int64]bool, summary *Summary) []cachedFile {
+	if j.cfg.MaxAge <= 0 {
+		return files
+	}
+
+	cutoff := time.Now().Add(-j.cfg.MaxAge)
+	kept := files[:0]
+
+	for _, f := range files {
+		if protected[f.song.ID] || f.song.LastUsed().After(cutoff) {
+			kept = append(kept, f)
+			continue
+		}
+
+		logger.Info.Printf("Janitor: removing song unused since %s: %s", f.song.LastUsed().Format(time.DateOnly), f.song.Title)
+		if j.removeSong(ctx, f) {
+			summary.ExpiredSongs++
+			summary.FreedBytes += f.size
+		} else {
+			kept = append(kept, f)
+		}
+	}
+
+	return kept
+}
+
+// evict removes least recently used songs until the cache fits MaxCacheBytes.
+func (j *Janitor) evict(ctx context.Context, files []cachedFile, protected map[int64]bool, summary *Summary) []cachedFile {
+	if j.cfg.MaxCacheBytes <= 0 {
+		return files
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	if total <= j.cfg.MaxCacheBytes {
+		return files
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].song.LastUsed().Before(files[b].song.LastUsed())
+	})
+
+	kept := files[:0]
+	for _, f := range files {
+		if total <= j.cfg.MaxCacheBytes || protected[f.song.ID] {
+			kept = append(kept, f)
+			continue
+		}
+
+		logger.Info.Printf("Janitor: evicting %s (%s) to stay under the cache limit", f.song.Title, FormatBytes(f.size))
+		if j.removeSong(ctx, f) {
+			total -= f.size
+			summary.EvictedSongs++
+			summary.FreedBytes += f.size
+		} else {
+			kept = append(kept, f)
+		}
+	}
+
+	if total > j.cfg.MaxCacheBytes {
+		logger.Info.Printf("Janitor: cache is %s, over the %s limit, but the rest is queued or pinned",
+			FormatBytes(total), FormatBytes(j.cfg.MaxCacheBytes))
+	}
+
+	return kept
+}
+
+// removeSong deletes the database row first so a queued song is never left
+// without its file; the row delete refuses songs the queue references.
+func (j *Janitor) removeSong(ctx context.Context, f cachedFile) bool {
+	if !j.deleteSong(ctx, f.song.ID) {
+		return false
+	}
+
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		logger.Error.Printf("Janitor: failed to remove %s: %v", f.path, err)
+	}
+	thumbnail := config.TrackThumbnail(f.path)
+	if err := os.Remove(thumbnail); err != nil && !os.IsNotExist(err) {
+		logger.Error.Printf("Janitor: failed to remove %s: %v", thumbnail, err)
+	}
+	return true
+}
+
+func (j *Janitor) deleteSong(ctx context.Context, songID int64) bool {
+	deleted, err := j.dbManager.DeleteSongCtx(ctx, songID)
+	if err != nil {
+		logger.Error.Printf("Janitor: failed to delete song %d: %v", songID, err)
+		return false
+	}
+	return deleted
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
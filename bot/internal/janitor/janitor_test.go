@@ -0,0 +1,52 @@
+package janitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, dir, name string, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRemoveOrphanedFilesSparesRecentFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := 2 * orphanGracePeriod
+
+	referenced := writeFile(t, dir, "kept.mp3", old)
+	referencedThumbnail := writeFile(t, dir, "kept.jpg", old)
+	orphan := writeFile(t, dir, "orphan.mp3", old)
+	orphanThumbnail := writeFile(t, dir, "orphan.jpg", old)
+	// A download still in flight: written, but not saved as a song yet.
+	downloading := writeFile(t, dir, "downloading.mp3", time.Minute)
+	downloadingThumbnail := writeFile(t, dir, "downloading.jpg", time.Minute)
+	other := writeFile(t, dir, "notes.txt", old)
+
+	j := &Janitor{cfg: Config{MusicDir: dir}}
+	removed, freed := j.removeOrphanedFiles(map[string]bool{referenced: true})
+
+	if removed != 2 || freed != 2 {
+		t.Fatalf("removed %d files (%d bytes), want 2 (2 bytes)", removed, freed)
+	}
+	for _, path := range []string{orphan, orphanThumbnail} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{referenced, referencedThumbnail, downloading, downloadingThumbnail, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", filepath.Base(path), err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package listening
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/i18n"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SampleInterval is how often the voice channel of a running session is
+// checked for who is listening.
+const SampleInterval = time.Minute
+
+var (
+	ErrActive    = errors.New("a listening session is already running")
+	ErrNotActive = errors.New("no listening session is running")
+	ErrNoSession = errors.New("no listening session was recorded yet")
+)
+
+// recording is a session that is running.
+type recording struct {
+	session config.ListeningSession
+	tracks  []config.ListeningTrack
+}
+
+// Manager records listening sessions, at most one per guild. Nothing of the
+// audio is kept: only the tracks that played, who was in the voice channel
+// and how many reactions the track messages got.
+type Manager struct {
+	session   *discordgo.Session
+	dbManager *config.DatabaseManager
+
+	active map[string]*recording
+	mu     sync.Mutex
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func New(session *discordgo.Session, dbManager *config.DatabaseManager) *Manager {
+	return &Manager{
+		session:   session,
+		dbManager: dbManager,
+		active:    make(map[string]*recording),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start closes the sessions left running by an unclean stop and samples the
+// running sessions every SampleInterval in the background.
+func (m *Manager) Start() {
+	if ended, err := m.dbManager.EndUnfinishedListeningSessions(); err != nil {
+		logger.Error.Printf("Failed to end unfinished listening sessions: %v", err)
+	} else if ended > 0 {
+		logger.Info.Printf("Ended %d listening sessions left running by the last stop", ended)
+	}
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(SampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.sampleAll()
+			}
+		}
+	}()
+}
+
+// Active reports whether a session is running in guildID.
+func (m *Manager) Active(guildID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.active[guildID]
+	return ok
+}
+
+// Begin starts a session in guildID for voiceChannelID. Tracks are posted to
+// textChannelID, where their reactions are counted.
+func (m *Manager) Begin(guildID, voiceChannelID, textChannelID, startedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.active[guildID]; ok {
+		return ErrActive
+	}
+
+	session, err := m.dbManager.AddListeningSession(config.ListeningSession{
+		GuildID:        guildID,
+		VoiceChannelID: voiceChannelID,
+		TextChannelID:  textChannelID,
+		StartedBy:      startedBy,
+		StartedAt:      time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	rec := &recording{session: session}
+	m.active[guildID] = rec
+
+	listeners := m.listeners(guildID, voiceChannelID)
+	m.record(rec, listeners)
+	if err := m.dbManager.AddListeningSample(session.ID, listeners); err != nil {
+		logger.Error.Printf("Failed to store listening session sample: %v", err)
+	}
+	return nil
+}
+
+// End stops the session in guildID and returns its summary.
+func (m *Manager) End(guildID string) (Summary, error) {
+	return m.end(context.Background(), guildID)
+}
+
+func (m *Manager) end(ctx context.Context, guildID string) (Summary, error) {
+	m.mu.Lock()
+	rec, ok := m.active[guildID]
+	delete(m.active, guildID)
+	m.mu.Unlock()
+
+	if !ok {
+		return Summary{}, ErrNotActive
+	}
+
+	rec.session.EndedAt = time.Now()
+	for idx := range rec.tracks {
+		track := &rec.tracks[idx]
+		track.Reactions = m.reactions(ctx, rec.session.TextChannelID, track.MessageID)
+		if err := m.dbManager.UpdateListeningTrackCtx(ctx, track.ID, track.PeakListeners, track.Reactions); err != nil {
+			logger.Error.Printf("Failed to store listening track %d: %v", track.ID, err)
+		}
+	}
+
+	err := m.dbManager.EndListeningSessionCtx(ctx, rec.session.ID, rec.session.EndedAt, rec.session.PeakListeners)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	listeners, err := m.dbManager.GetListeningListenersCtx(ctx, rec.session.ID)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{Session: rec.session, Tracks: rec.tracks, Listeners: listeners}, nil
+}
+
+// Last returns the summary of the latest session in guildID that has ended.
+func (m *Manager) Last(guildID string) (Summary, error) {
+	session, err := m.dbManager.GetLastListeningSession(guildID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Summary{}, ErrNoSession
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+
+	tracks, err := m.dbManager.GetListeningTracks(session.ID)
+	if err != nil {
+		return Summary{}, err
+	}
+	listeners, err := m.dbManager.GetListeningListeners(session.ID)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{Session: *session, Tracks: tracks, Listeners: listeners}, nil
+}
+
+// TrackStarted posts song to the text channel of the session running in
+// guildID, if there is one, and adds it to the session.
+func (m *Manager) TrackStarted(guildID string, song *state.Song) {
+	m.mu.Lock()
+	rec, ok := m.active[guildID]
+	var textChannelID, voiceChannelID string
+	var sessionID int64
+	if ok {
+		textChannelID = rec.session.TextChannelID
+		voiceChannelID = rec.session.VoiceChannelID
+		sessionID = rec.session.ID
+	}
+	m.mu.Unlock()
+
+	if !ok || song == nil {
+		return
+	}
+
+	content := i18n.T(guildID, "session.track", song.Title)
+	if song.Artist != "" {
+		content = i18n.T(guildID, "session.track_artist", song.Title, song.Artist)
+	}
+	message, err := m.session.ChannelMessageSendComplex(textChannelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	messageID := ""
+	if err != nil {
+		logger.Error.Printf("Failed to post listening session track in channel %s: %v", textChannelID, err)
+	} else {
+		messageID = message.ID
+	}
+
+	listeners := len(m.listeners(guildID, voiceChannelID))
+	track, err := m.dbManager.AddListeningTrack(config.ListeningTrack{
+		SessionID:     sessionID,
+		Title:         song.Title,
+		Artist:        song.Artist,
+		URL:           song.URL,
+		StartedAt:     time.Now(),
+		MessageID:     messageID,
+		PeakListeners: listeners,
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to store listening session track: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The session may have been stopped while the message was being sent.
+	if current, ok := m.active[guildID]; ok && current == rec {
+		rec.tracks = append(rec.tracks, track)
+		rec.session.PeakListeners = max(rec.session.PeakListeners, listeners)
+	}
+}
+
+// Shutdown ends the running sessions, so they can be read back with
+// /session last.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+
+	m.mu.Lock()
+	guildIDs := make([]string, 0, len(m.active))
+	for guildID := range m.active {
+		guildIDs = append(guildIDs, guildID)
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, guildID := range guildIDs {
+		if _, err := m.end(ctx, guildID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+func (m *Manager) Name() string {
+	return "ListeningSessions"
+}
+
+// sampleAll samples every running session.
+func (m *Manager) sampleAll() {
+	m.mu.Lock()
+	recordings := make([]*recording, 0, len(m.active))
+	for _, rec := range m.active {
+		recordings = append(recordings, rec)
+	}
+	m.mu.Unlock()
+
+	for _, rec := range recordings {
+		listeners := m.listeners(rec.session.GuildID, rec.session.VoiceChannelID)
+
+		m.mu.Lock()
+		running := m.active[rec.session.GuildID] == rec
+		if running {
+			m.record(rec, listeners)
+		}
+		m.mu.Unlock()
+		if !running {
+			continue
+		}
+
+		if err := m.dbManager.AddListeningSample(rec.session.ID, listeners); err != nil {
+			logger.Error.Printf("Failed to store listening session sample: %v", err)
+		}
+	}
+}
+
+// record raises the peaks of rec and its current track to the listeners of
+// a sample. m.mu must be held.
+func (m *Manager) record(rec *recording, listeners []string) {
+	count := len(listeners)
+	rec.session.PeakListeners = max(rec.session.PeakListeners, count)
+	if len(rec.tracks) > 0 {
+		current := &rec.tracks[len(rec.tracks)-1]
+		current.PeakListeners = max(current.PeakListeners, count)
+	}
+}
+
+// listeners returns who, other than the bot, is in channelID.
+func (m *Manager) listeners(guildID, channelID string) []string {
+	guild, err := m.session.State.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	botID := m.session.State.User.ID
+	var userIDs []string
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID == channelID && vs.UserID != botID {
+			userIDs = append(userIDs, vs.UserID)
+		}
+	}
+	return userIDs
+}
+
+// reactions counts the reactions to messageID, or 0 when it is gone.
+func (m *Manager) reactions(ctx context.Context, channelID, messageID string) int {
+	if messageID == "" {
+		return 0
+	}
+
+	message, err := m.session.ChannelMessage(channelID, messageID, discordgo.WithContext(ctx))
+	if err != nil {
+		logger.Error.Printf("Failed to count the reactions to message %s: %v", messageID, err)
+		return 0
+	}
+
+	count := 0
+	for _, reaction := range message.Reactions {
+		count += reaction.Count
+	}
+	return count
+}
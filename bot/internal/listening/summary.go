@@ -0,0 +1,44 @@
+package listening
+
+import (
+	"bytes"
+	"encoding/csv"
+	"musicbot/internal/config"
+	"strconv"
+	"time"
+)
+
+// Summary is what was recorded of a listening session.
+type Summary struct {
+	Session   config.ListeningSession
+	Tracks    []config.ListeningTrack
+	Listeners []config.ListeningListener
+}
+
+// Duration is how long the session ran.
+func (s Summary) Duration() time.Duration {
+	return s.Session.EndedAt.Sub(s.Session.StartedAt)
+}
+
+// CSV lists the tracks of the session, one row each, with when they started
+// and how many listened at most.
+func (s Summary) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"position", "started_at", "title", "artist", "url", "peak_listeners", "reactions"})
+	for idx, track := range s.Tracks {
+		w.Write([]string{
+			strconv.Itoa(idx + 1),
+			track.StartedAt.UTC().Format(time.RFC3339),
+			track.Title,
+			track.Artist,
+			track.URL,
+			strconv.Itoa(track.PeakListeners),
+			strconv.Itoa(track.Reactions),
+		})
+	}
+
+	w.Flush()
+	return buf.Bytes()
+}
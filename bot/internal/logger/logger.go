@@ -3,6 +3,7 @@ package logger
 import (
 	"io"
 	"log"
+	"log/slog"
 	"os"
 )
 
@@ -12,24 +13,108 @@ const (
 	LevelDebug
 )
 
+// Structured field keys shared across packages.
+const (
+	KeyGuildID   = "guild_id"
+	KeyCommand   = "command"
+	KeyRequestID = "request_id"
+)
+
+// Error, Info and Debug are kept so existing logger.Info.Printf call sites
+// keep working; they write through the same slog handler as L().
 var (
 	Error *log.Logger
 	Info  *log.Logger
 	Debug *log.Logger
+
+	base *slog.Logger
 )
 
+type Options struct {
+	Level int
+	JSON  bool
+
+	// File enables file output with size-based rotation instead of stdout.
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+}
+
+// Until Setup is called, errors are logged to stdout. That lets packages be
+// used without main setting logging up, as their tests do.
+func init() {
+	Setup(LevelError)
+}
+
 func Setup(level int) {
-	Error = log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+	if err := SetupWithOptions(Options{Level: level}); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+}
 
-	if level >= LevelInfo {
-		Info = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
-	} else {
-		Info = log.New(io.Discard, "", 0)
+func SetupWithOptions(opts Options) error {
+	var out io.Writer = os.Stdout
+	if opts.File != "" {
+		file, err := newRotatingFile(opts.File, opts.MaxSizeMB, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		out = file
 	}
 
-	if level >= LevelDebug {
-		Debug = log.New(os.Stdout, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+	handlerOpts := &slog.HandlerOptions{
+		Level:     toSlogLevel(opts.Level),
+		AddSource: opts.Level >= LevelDebug,
+	}
+
+	var handler slog.Handler
+	if opts.JSON {
+		handler = slog.NewJSONHandler(out, handlerOpts)
 	} else {
-		Debug = log.New(io.Discard, "", 0)
+		handler = slog.NewTextHandler(out, handlerOpts)
 	}
+
+	base = slog.New(handler)
+	slog.SetDefault(base)
+
+	Error = slog.NewLogLogger(handler, slog.LevelError)
+	Info = slog.NewLogLogger(handler, slog.LevelInfo)
+	Debug = slog.NewLogLogger(handler, slog.LevelDebug)
+
+	return nil
+}
+
+func toSlogLevel(level int) slog.Level {
+	switch {
+	case level >= LevelDebug:
+		return slog.LevelDebug
+	case level >= LevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelError
+	}
+}
+
+// L returns the structured logger.
+func L() *slog.Logger {
+	if base == nil {
+		return slog.Default()
+	}
+	return base
+}
+
+func With(args ...any) *slog.Logger {
+	return L().With(args...)
+}
+
+func ForGuild(guildID string) *slog.Logger {
+	return L().With(KeyGuildID, guildID)
+}
+
+func ForCommand(guildID, command string) *slog.Logger {
+	return L().With(KeyGuildID, guildID, KeyCommand, command)
+}
+
+func ForRequest(requestID string) *slog.Logger {
+	return L().With(KeyRequestID, requestID)
 }
@@ -0,0 +1,180 @@
+package lyrics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// requestTimeout bounds one lookup at the provider.
+const requestTimeout = 10 * time.Second
+
+// ErrNotFound is returned when the provider has no lyrics for a track, and
+// also when it couldn't be asked.
+var ErrNotFound = errors.New("lyrics not found")
+
+type Lyrics struct {
+	Artist string
+	Title  string
+	Text   string
+}
+
+// Client looks up lyrics from an LRCLIB-compatible provider and caches what
+// it finds, so asking again for the same track doesn't hit the network.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	dbManager  *config.DatabaseManager
+	mu         sync.RWMutex
+}
+
+func NewClient(baseURL string, dbManager *config.DatabaseManager) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+		dbManager:  dbManager,
+	}
+}
+
+func (c *Client) BaseURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.baseURL
+}
+
+// SetBaseURL switches provider and reports whether the URL changed. Cached
+// lyrics are kept.
+func (c *Client) SetBaseURL(baseURL string) bool {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.baseURL == baseURL {
+		return false
+	}
+	c.baseURL = baseURL
+	return true
+}
+
+// Find returns the lyrics for title by artist. Without an artist the title is
+// used as a free text search, which suits video titles like "Artist - Song".
+func (c *Client) Find(ctx context.Context, artist, title string) (*Lyrics, error) {
+	key := cacheKey(artist, title)
+
+	cached, err := c.dbManager.GetLyricsCtx(ctx, key)
+	if err == nil {
+		return &Lyrics{Artist: cached.Artist, Title: cached.Title, Text: cached.Lyrics}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.Error.Printf("Failed to read cached lyrics: %v", err)
+	}
+
+	found, err := c.search(ctx, artist, title)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			logger.Error.Printf("Lyrics lookup for %q failed: %v", title, err)
+		}
+		return nil, ErrNotFound
+	}
+
+	err = c.dbManager.SaveLyricsCtx(ctx, config.CachedLyrics{
+		Key:    key,
+		Artist: found.Artist,
+		Title:  found.Title,
+		Lyrics: found.Text,
+	})
+	if err != nil {
+		logger.Error.Printf("Failed to cache lyrics: %v", err)
+	}
+
+	return found, nil
+}
+
+type searchResult struct {
+	TrackName    string `json:"trackName"`
+	ArtistName   string `json:"artistName"`
+	PlainLyrics  string `json:"plainLyrics"`
+	Instrumental bool   `json:"instrumental"`
+}
+
+func (c *Client) search(ctx context.Context, artist, title string) (*Lyrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := url.Values{}
+	if artist != "" {
+		params.Set("artist_name", artist)
+		params.Set("track_name", title)
+	} else {
+		params.Set("q", title)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL()+"/api/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Discord-s-Final-Musican (https://github.com/BlankTuber/Discord-s-Final-Musican)")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider returned %s", resp.Status)
+	}
+
+	var results []searchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, result := range results {
+		if result.Instrumental || strings.TrimSpace(result.PlainLyrics) == "" {
+			continue
+		}
+		return &Lyrics{
+			Artist: result.ArtistName,
+			Title:  result.TrackName,
+			Text:   strings.TrimSpace(result.PlainLyrics),
+		}, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// cacheKey normalizes artist and title so the same track is found again
+// regardless of case, punctuation or spacing.
+func cacheKey(artist, title string) string {
+	return normalize(artist) + "|" + normalize(title)
+}
+
+func normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics are only collected after Enable is called, so the instrumented code
+// paths cost a single atomic load when no endpoint is configured.
+var enabled atomic.Bool
+
+var (
+	commandsTotal = newCounterVec("commands_total",
+		"Slash commands handled, by command and outcome.", "command", "status")
+	downloadsTotal = newCounterVec("downloads_total",
+		"Downloader requests completed, by result.", "result")
+	downloadRetries = newCounterVec("downloads_retries_total",
+		"Downloads tried again after a passing failure, by error class.", "class")
+	tracksTotal = newCounterVec("tracks_total",
+		"Songs that finished playing, by how they ended.", "result")
+	downloadDuration = newHistogram("download_duration_seconds",
+		"Time from sending a download request to its response.",
+		[]float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300})
+	queueLength = newGaugeFuncVec("queue_length",
+		"Songs waiting in the queue after the current one.", "guild")
+	voiceConnections = newGauge("voice_connections",
+		"Open voice connections.")
+	prebufferBytes = newGauge("prebuffer_bytes",
+		"Memory held by the pre-encoded opening of the next song.")
+	socketReconnects = newCounterVec("socket_reconnects_total",
+		"Successful reconnections to the downloader socket.")
+	radioStreamErrors = newCounterVec("radio_stream_errors_total",
+		"Radio stream errors, by classification.", "type")
+	radioUnderruns = newCounterVec("radio_buffer_underruns_total",
+		"Times the radio's buffer ran dry and silence was played until the stream caught up.")
+	opusFramesDropped = newCounterVec("opus_frames_dropped_total",
+		"Opus frames dropped because Discord couldn't take them in time, by source.", "source")
+)
+
+var collectors = []collector{
+	commandsTotal,
+	downloadsTotal,
+	downloadRetries,
+	downloadDuration,
+	tracksTotal,
+	queueLength,
+	voiceConnections,
+	prebufferBytes,
+	socketReconnects,
+	radioStreamErrors,
+	radioUnderruns,
+	opusFramesDropped,
+}
+
+// Command statuses recorded in commands_total.
+const (
+	StatusOK        = "ok"
+	StatusError     = "error"
+	StatusDenied    = "denied"
+	StatusThrottled = "throttled"
+)
+
+// Download results recorded in downloads_total.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+func Enable() {
+	enabled.Store(true)
+}
+
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Handler serves all metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var buf bytes.Buffer
+		for _, c := range collectors {
+			c.write(&buf)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Write(buf.Bytes())
+	})
+}
+
+func CommandHandled(command, status string) {
+	if !Enabled() {
+		return
+	}
+	commandsTotal.Inc(command, status)
+}
+
+func DownloadFinished(result string, elapsed time.Duration) {
+	if !Enabled() {
+		return
+	}
+	downloadsTotal.Inc(result)
+	downloadDuration.Observe(elapsed.Seconds())
+}
+
+func DownloadRetried(class string) {
+	if !Enabled() {
+		return
+	}
+	downloadRetries.Inc(class)
+}
+
+// TrackFinished counts a song that ended, was stopped or broke off.
+func TrackFinished(result string) {
+	if !Enabled() {
+		return
+	}
+	tracksTotal.Inc(result)
+}
+
+// SetQueueLengthFunc registers the callback read for queue_length{guild} on
+// every scrape.
+func SetQueueLengthFunc(guildID string, fn func() int) {
+	if !Enabled() {
+		return
+	}
+	queueLength.Set(func() float64 { return float64(fn()) }, guildID)
+}
+
+// RemoveQueueLengthFunc stops reporting queue_length for guildID.
+func RemoveQueueLengthFunc(guildID string) {
+	if !Enabled() {
+		return
+	}
+	queueLength.Delete(guildID)
+}
+
+func SetVoiceConnections(count int) {
+	if !Enabled() {
+		return
+	}
+	voiceConnections.Set(float64(count))
+}
+
+func SetPrebufferBytes(bytes int) {
+	if !Enabled() {
+		return
+	}
+	prebufferBytes.Set(float64(bytes))
+}
+
+func SocketReconnected() {
+	if !Enabled() {
+		return
+	}
+	socketReconnects.Inc()
+}
+
+func RadioStreamError(errorType string) {
+	if !Enabled() {
+		return
+	}
+	radioStreamErrors.Inc(errorType)
+}
+
+func RadioBufferUnderrun() {
+	if !Enabled() {
+		return
+	}
+	radioUnderruns.Inc()
+}
+
+func OpusFrameDropped(source string) {
+	if !Enabled() {
+		return
+	}
+	opusFramesDropped.Inc(source)
+}
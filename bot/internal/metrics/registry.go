@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelSeparator joins label values into a map key. It cannot appear in
+// command names, guild IDs or error types.
+const labelSeparator = "\xff"
+
+type collector interface {
+	write(w io.Writer)
+}
+
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+	mu     sync.Mutex
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+func (c *counterVec) Inc(labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key), formatFloat(c.values[key]))
+	}
+}
+
+type gauge struct {
+	name  string
+	help  string
+	value float64
+	mu    sync.Mutex
+}
+
+func newGauge(name, help string) *gauge {
+	return &gauge{name: name, help: help}
+}
+
+func (g *gauge) Set(value float64) {
+	g.mu.Lock()
+	g.value = value
+	g.mu.Unlock()
+}
+
+func (g *gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+}
+
+// gaugeFuncVec is a labelled gauge whose values are read at scrape time, for
+// state that already lives elsewhere (e.g. the queue).
+type gaugeFuncVec struct {
+	name   string
+	help   string
+	labels []string
+	funcs  map[string]func() float64
+	mu     sync.Mutex
+}
+
+func newGaugeFuncVec(name, help string, labels ...string) *gaugeFuncVec {
+	return &gaugeFuncVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		funcs:  make(map[string]func() float64),
+	}
+}
+
+func (g *gaugeFuncVec) Set(fn func() float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	g.mu.Lock()
+	g.funcs[key] = fn
+	g.mu.Unlock()
+}
+
+func (g *gaugeFuncVec) Delete(labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	g.mu.Lock()
+	delete(g.funcs, key)
+	g.mu.Unlock()
+}
+
+func (g *gaugeFuncVec) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, key := range sortedKeys(g.funcs) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(g.labels, key), formatFloat(g.funcs[key]()))
+	}
+}
+
+type histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+	mu      sync.Mutex
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	return &histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatLabels(names []string, key string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	values := strings.Split(key, labelSeparator)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = name + "=" + strconv.Quote(value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,77 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"musicbot/internal/logger"
+	"sync/atomic"
+	"time"
+)
+
+// cancelRequestTimeout bounds the wait for the downloader to acknowledge a
+// cancellation.
+const cancelRequestTimeout = 5 * time.Second
+
+// ErrDownloadCancelled is reported for songs whose download was stopped by
+// /clear or /leave.
+var ErrDownloadCancelled = errors.New("the download was cancelled")
+
+// trackRequest remembers the downloader request for url so CancelDownloads
+// can stop it. A download that finished before its request ID came back is
+// left alone.
+func (m *Manager) trackRequest(url, requestID string) {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+
+	if _, waiting := m.requestLimits[url]; waiting {
+		m.downloadRequests[url] = requestID
+	}
+}
+
+// CancelDownloads stops every song and playlist download of the guild music
+// belongs to, as /clear and /leave do. The downloader is asked to drop the
+// requests and whatever it still sends for them is ignored.
+func (m *Manager) CancelDownloads() {
+	m.cancelPlaylists(ErrPlaylistCancelled)
+
+	m.downloadMu.Lock()
+	requests := m.downloadRequests
+	m.downloadRequests = make(map[string]string)
+	requestIDs := make([]string, 0, len(requests))
+	var notifiers []RequestNotifier
+	for url, requestID := range requests {
+		requestIDs = append(requestIDs, requestID)
+		if notifier := m.notifiers[url]; notifier != nil {
+			notifiers = append(notifiers, notifier)
+		}
+		delete(m.notifiers, url)
+		delete(m.requestLimits, url)
+		delete(m.downloadAttempts, url)
+		delete(m.duplicateUrls, url)
+		delete(m.playNextUrls, url)
+		delete(m.repairUrls, url)
+	}
+	m.downloadMu.Unlock()
+
+	if len(requests) == 0 {
+		return
+	}
+
+	logger.Info.Printf("Cancelling %d downloads", len(requests))
+	for url := range requests {
+		m.releaseReservation(url, "")
+	}
+	if atomic.AddInt32(&m.pendingDownloads, -int32(len(requests))) < 0 {
+		atomic.StoreInt32(&m.pendingDownloads, 0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelRequestTimeout)
+	defer cancel()
+	if err := m.socketClient.CancelRequest(ctx, requestIDs...); err != nil {
+		logger.Error.Printf("Downloader did not acknowledge cancelling %d downloads: %v", len(requestIDs), err)
+	}
+
+	for _, notifier := range notifiers {
+		notifier.Failed(ErrDownloadCancelled)
+	}
+}
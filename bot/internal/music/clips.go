@@ -0,0 +1,250 @@
+package music
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"musicbot/internal/audio"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// MaxClipLength is the longest clip /clip add accepts.
+	MaxClipLength = 10 * time.Second
+	// MaxClipNameLength is the longest clip name.
+	MaxClipNameLength = 32
+	// clipMaxSizeMB bounds a clip upload; ten seconds of audio in any of
+	// the accepted formats fits well within it.
+	clipMaxSizeMB = 5
+	// clipDir is where clips are stored, under the download directory. The
+	// janitor only looks at files directly in the download directory, so
+	// it leaves them alone.
+	clipDir = "clips"
+	// clipDecodeTimeout bounds decoding a clip before it plays.
+	clipDecodeTimeout = 10 * time.Second
+)
+
+var (
+	ErrClipNotFound    = errors.New("clip not found")
+	ErrInvalidClipName = errors.New("invalid clip name")
+	// ErrClipOverRadio is returned by PlayClip while the radio plays, since
+	// the radio player has no mixer to play the clip through.
+	ErrClipOverRadio = errors.New("clips can't be played over the radio")
+)
+
+// ClipTooLongError is returned by AddClip when the uploaded clip is longer
+// than MaxClipLength.
+type ClipTooLongError struct {
+	Length time.Duration
+}
+
+func (e *ClipTooLongError) Error() string {
+	return fmt.Sprintf("clip is %s long, over the %s limit", e.Length.Round(100*time.Millisecond), MaxClipLength)
+}
+
+var clipNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// NormalizeClipName lowercases name and checks that it is a usable clip
+// name: letters, digits, dashes and underscores, at most MaxClipNameLength
+// long.
+func NormalizeClipName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) > MaxClipNameLength || !clipNamePattern.MatchString(name) {
+		return "", ErrInvalidClipName
+	}
+	return name, nil
+}
+
+// AddClip stores upload as a clip of guildID called name. Uploads longer
+// than MaxClipLength are refused and not kept.
+func (m *Manager) AddClip(guildID, name, addedBy string, upload Upload) (*config.Clip, error) {
+	name, err := NormalizeClipName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := config.DownloadLimits{MaxSizeMB: clipMaxSizeMB}
+	if err := upload.Validate(limits); err != nil {
+		return nil, err
+	}
+
+	// Checked before the download too, so a taken name doesn't cost one.
+	if _, err := m.dbManager.GetClip(guildID, name); err == nil {
+		return nil, config.ErrClipExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	dir := filepath.Join(m.stateManager.GetConfig().DownloadDir, clipDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create clip directory: %w", err)
+	}
+
+	path, _, err := m.storeUpload(ctx, upload, limits, dir, "clip_")
+	if err != nil {
+		return nil, err
+	}
+
+	length := probeLength(ctx, path)
+	if length <= 0 {
+		m.removeClipFile(path)
+		return nil, ErrUnsupportedUpload
+	}
+	if length > MaxClipLength {
+		m.removeClipFile(path)
+		return nil, &ClipTooLongError{Length: length}
+	}
+
+	clip := &config.Clip{
+		GuildID:  guildID,
+		Name:     name,
+		FilePath: path,
+		Duration: length,
+		AddedBy:  addedBy,
+		AddedAt:  time.Now(),
+	}
+	if err := m.dbManager.AddClip(*clip); err != nil {
+		m.removeClipFile(path)
+		return nil, err
+	}
+
+	logger.Info.Printf("Clip %s added to guild %s by %s", name, guildID, addedBy)
+	return clip, nil
+}
+
+// RemoveClip deletes the clip of guildID called name, and its file unless
+// another clip plays the same file.
+func (m *Manager) RemoveClip(guildID, name string) error {
+	clip, err := m.dbManager.GetClip(guildID, strings.ToLower(strings.TrimSpace(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrClipNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	removed, err := m.dbManager.DeleteClip(guildID, clip.Name)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrClipNotFound
+	}
+
+	m.removeClipFile(clip.FilePath)
+	logger.Info.Printf("Clip %s removed from guild %s", clip.Name, guildID)
+	return nil
+}
+
+// removeClipFile deletes a clip's file once no clip plays it. The same
+// upload saved under two names shares one file.
+func (m *Manager) removeClipFile(path string) {
+	inUse, err := m.dbManager.ClipFileInUse(path)
+	if err != nil {
+		logger.Error.Printf("Failed to check whether clip file %s is in use: %v", path, err)
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error.Printf("Failed to remove clip file %s: %v", path, err)
+	}
+}
+
+// PlayClip plays the clip of guildID called name. While one of the guild's
+// songs plays, the clip is mixed into it; otherwise it plays by itself on
+// vc. It returns once the clip has started.
+func (m *Manager) PlayClip(guildID, name string, vc *discordgo.VoiceConnection, radioPlaying bool) (*config.Clip, error) {
+	clip, err := m.dbManager.GetClip(guildID, strings.ToLower(strings.TrimSpace(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClipNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mixing := m.player.IsPlaying() && !m.player.IsPaused()
+	if !mixing && radioPlaying {
+		return nil, ErrClipOverRadio
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clipDecodeTimeout)
+	defer cancel()
+
+	pcm, err := decodeClip(ctx, clip.FilePath, m.stateManager.GetVolume())
+	if err != nil {
+		return nil, err
+	}
+
+	if mixing {
+		err := m.player.Overlay(pcm)
+		if !errors.Is(err, ErrNotPlaying) {
+			return clip, err
+		}
+		// The song ended while the clip was decoded.
+	}
+
+	if err := m.startSoloClip(guildID, vc, pcm); err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// startSoloClip plays pcm on vc while nothing else is playing there, one
+// clip at a time per guild.
+func (m *Manager) startSoloClip(guildID string, vc *discordgo.VoiceConnection, pcm []int16) error {
+	m.clipMu.Lock()
+	defer m.clipMu.Unlock()
+
+	if m.soloClips[guildID] {
+		return audio.ErrOverlayBusy
+	}
+	m.soloClips[guildID] = true
+
+	go func() {
+		defer func() {
+			m.clipMu.Lock()
+			delete(m.soloClips, guildID)
+			m.clipMu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), MaxClipLength+5*time.Second)
+		defer cancel()
+
+		if err := audio.PlayPCM(ctx, vc, pcm); err != nil {
+			logger.Error.Printf("Failed to play clip in guild %s: %v", guildID, err)
+		}
+	}()
+	return nil
+}
+
+// decodeClip decodes up to MaxClipLength of the file at path into
+// interleaved 48kHz stereo samples.
+func decodeClip(ctx context.Context, path string, volume float32) ([]int16, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", decodeArgs(path, 0, MaxClipLength, volume, FilterOff)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode clip: %w", err)
+	}
+
+	pcm := make([]int16, len(out)/2)
+	for k := range pcm {
+		pcm[k] = int16(binary.LittleEndian.Uint16(out[2*k:]))
+	}
+	return pcm, nil
+}
@@ -0,0 +1,24 @@
+package music
+
+import "fmt"
+
+// DuplicateError is returned when a song is already playing or queued.
+// Position is 0 for the current song and counts upcoming songs from 1.
+type DuplicateError struct {
+	Position int
+	Title    string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s is already in the queue at position %d", e.Title, e.Position)
+}
+
+// FindQueued returns a *DuplicateError if a song with rawURL is playing or
+// waiting in the queue.
+func (m *Manager) FindQueued(rawURL string) error {
+	return m.findDuplicate(rawURL, "")
+}
+
+func (m *Manager) findDuplicate(rawURL, filePath string) error {
+	return m.queue.FindDuplicate(rawURL, filePath, m.player.IsPlaying() || m.player.IsPaused())
+}
@@ -0,0 +1,83 @@
+package music
+
+import "time"
+
+// ETA is when an upcoming song is expected to start.
+type ETA struct {
+	// Position is the song's place in the queue, 1 being next.
+	Position int
+	Wait     time.Duration
+
+	// Known is false when a live stream plays first, which has no end to
+	// count from.
+	Known bool
+
+	// Unknown counts the songs before it whose length the downloader
+	// couldn't tell. They are left out of Wait, which makes it the
+	// earliest the song can start.
+	Unknown int
+}
+
+// At is the time the song is expected to start, counted from now.
+func (e ETA) At(now time.Time) time.Time {
+	return now.Add(e.Wait)
+}
+
+// ETA works out when upcoming song n (1 is next) starts: what is left of
+// the current song plus the songs before it, each sped up or slowed down by
+// the filter it plays with. A paused song counts as if it were resumed now.
+func (m *Manager) ETA(n int) (ETA, error) {
+	upcoming := m.queue.GetUpcoming(n)
+	if n < 1 || len(upcoming) < n {
+		return ETA{}, ErrOutOfRange
+	}
+
+	eta := ETA{Position: n, Known: true}
+
+	if current := m.player.GetCurrentSong(); current != nil {
+		if current.IsStream {
+			return ETA{Position: n}, nil
+		}
+		if current.PlayLength() <= 0 {
+			eta.Unknown++
+		}
+		end := time.Duration(current.StartOffset+current.PlayLength()) * time.Second
+		if remaining := end - m.player.Position(); remaining > 0 {
+			eta.Wait += playTime(remaining, m.player.Filter())
+		}
+	}
+
+	for idx := range upcoming[:n-1] {
+		song := &upcoming[idx]
+		if song.IsStream {
+			return ETA{Position: n}, nil
+		}
+		if song.PlayLength() <= 0 {
+			eta.Unknown++
+			continue
+		}
+		eta.Wait += playTime(time.Duration(song.PlayLength())*time.Second, m.player.songFilter(song))
+	}
+
+	return eta, nil
+}
+
+// queuedETA is the ETA of a song that was just queued: next if playNext,
+// otherwise last.
+func (m *Manager) queuedETA(playNext bool) ETA {
+	n := 1
+	if !playNext {
+		n = m.queue.LastTurn()
+	}
+
+	eta, err := m.ETA(n)
+	if err != nil {
+		return ETA{Position: n}
+	}
+	return eta
+}
+
+// playTime is how long d of a file takes to play with filter.
+func playTime(d time.Duration, filter Filter) time.Duration {
+	return time.Duration(float64(d) / filter.Speed())
+}
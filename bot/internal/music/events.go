@@ -0,0 +1,171 @@
+package music
+
+import (
+	"musicbot/internal/state"
+	"sync"
+	"time"
+)
+
+// PlayerEventType says what happened to the song a PlayerEvent is about.
+type PlayerEventType int
+
+const (
+	// TrackStarted is published when a song starts from its beginning. A
+	// song resumed or restarted part way through doesn't start again.
+	TrackStarted PlayerEventType = iota + 1
+	// TrackHalfway is published once half of the song has played.
+	TrackHalfway
+	// TrackEnded is published when a song plays to its end.
+	TrackEnded
+	// TrackStopped is published when a song is stopped before its end.
+	TrackStopped
+	// TrackFailed is published when playback broke off, with the error.
+	TrackFailed
+	// TrackMeasured is published when a song without a known length has
+	// played to its end, with the length it turned out to have.
+	TrackMeasured
+)
+
+func (t PlayerEventType) String() string {
+	switch t {
+	case TrackStarted:
+		return "started"
+	case TrackHalfway:
+		return "halfway"
+	case TrackEnded:
+		return "ended"
+	case TrackStopped:
+		return "stopped"
+	case TrackFailed:
+		return "failed"
+	case TrackMeasured:
+		return "measured"
+	default:
+		return "unknown"
+	}
+}
+
+// PlayerEvent is something that happened to the song a guild's player is
+// playing. Position is how far into the file it happened, or the measured
+// length for TrackMeasured. Error is only set for TrackFailed.
+type PlayerEvent struct {
+	GuildID  string
+	Type     PlayerEventType
+	Song     *state.Song
+	Position time.Duration
+	Error    *TrackError
+}
+
+// subscription forwards a player's events to one subscriber's channel, in
+// the order they happened. Events wait in pending while the subscriber is
+// busy, so a slow subscriber never holds up playback or other subscribers.
+type subscription struct {
+	ch      chan<- PlayerEvent
+	mu      sync.Mutex
+	pending []PlayerEvent
+	wake    chan struct{}
+	done    chan struct{}
+	stop    sync.Once
+}
+
+func newSubscription(ch chan<- PlayerEvent) *subscription {
+	s := &subscription{
+		ch:   ch,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.forward()
+	return s
+}
+
+func (s *subscription) publish(event PlayerEvent) {
+	s.mu.Lock()
+	s.pending = append(s.pending, event)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) forward() {
+	for {
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+
+		for {
+			s.mu.Lock()
+			if len(s.pending) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			event := s.pending[0]
+			s.pending[0] = PlayerEvent{}
+			s.pending = s.pending[1:]
+			s.mu.Unlock()
+
+			select {
+			case s.ch <- event:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *subscription) close() {
+	s.stop.Do(func() { close(s.done) })
+}
+
+// Subscribe sends the player's events to ch until the returned function is
+// called. Every player belongs to one guild, so ch only ever gets that
+// guild's events. ch isn't closed when the subscription ends.
+func (p *Player) Subscribe(ch chan<- PlayerEvent) (unsubscribe func()) {
+	s := newSubscription(ch)
+
+	p.eventsMu.Lock()
+	p.subscribers = append(p.subscribers, s)
+	p.eventsMu.Unlock()
+
+	return func() {
+		p.eventsMu.Lock()
+		for k, sub := range p.subscribers {
+			if sub == s {
+				p.subscribers = append(p.subscribers[:k:k], p.subscribers[k+1:]...)
+				break
+			}
+		}
+		p.eventsMu.Unlock()
+		s.close()
+	}
+}
+
+// publish hands event to every subscriber. Holding eventsMu while doing so
+// keeps events from concurrent publishers in one order for all of them.
+func (p *Player) publish(eventType PlayerEventType, song *state.Song, position time.Duration, err *TrackError) {
+	event := PlayerEvent{
+		Type:     eventType,
+		Song:     song,
+		Position: position,
+		Error:    err,
+	}
+	if guild := p.guild.Load(); guild != nil {
+		event.GuildID = guild.ID()
+	}
+
+	p.eventsMu.Lock()
+	defer p.eventsMu.Unlock()
+	for _, s := range p.subscribers {
+		s.publish(event)
+	}
+}
+
+// Subscribe sends the events of the guild's player to ch until the returned
+// function is called.
+func (m *Manager) Subscribe(ch chan<- PlayerEvent) (unsubscribe func()) {
+	return m.player.Subscribe(ch)
+}
@@ -0,0 +1,161 @@
+package music
+
+import (
+	"musicbot/internal/state"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPlayer(stateManager *state.Manager, guildID string) *Player {
+	p := NewPlayer(stateManager)
+	p.SetGuild(stateManager.Guild(guildID))
+	return p
+}
+
+// receive reads n events from ch, failing the test if they don't arrive.
+func receive(t *testing.T, ch <-chan PlayerEvent, n int) []PlayerEvent {
+	t.Helper()
+	events := make([]PlayerEvent, 0, n)
+	for len(events) < n {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of %d events", len(events), n)
+		}
+	}
+	return events
+}
+
+func TestEventsArriveInOrder(t *testing.T) {
+	stateManager := state.NewManager(state.Config{})
+	p := newTestPlayer(stateManager, "guildA")
+
+	// The subscriber only starts reading once everything is published, so
+	// the player must not wait on it.
+	events := make(chan PlayerEvent)
+	defer p.Subscribe(events)()
+
+	song := &state.Song{Title: "song"}
+	types := []PlayerEventType{TrackStarted, TrackHalfway, TrackMeasured, TrackEnded, TrackStarted, TrackStopped}
+	published := make(chan struct{})
+	go func() {
+		for k, eventType := range types {
+			p.publish(eventType, song, time.Duration(k), nil)
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing blocked on a subscriber that wasn't reading")
+	}
+
+	for k, event := range receive(t, events, len(types)) {
+		if event.Type != types[k] || event.Position != time.Duration(k) {
+			t.Errorf("event %d = %v at %d, want %v at %d", k, event.Type, event.Position, types[k], k)
+		}
+		if event.GuildID != "guildA" || event.Song != song {
+			t.Errorf("event %d is about %q in guild %q, want song in guildA", k, event.Song.Title, event.GuildID)
+		}
+	}
+}
+
+func TestEventsNeverCrossGuilds(t *testing.T) {
+	const perGuild = 500
+	stateManager := state.NewManager(state.Config{})
+	guildIDs := []string{"guildA", "guildB"}
+
+	players := make(map[string]*Player)
+	subscribers := make(map[string]chan PlayerEvent)
+	for _, guildID := range guildIDs {
+		players[guildID] = newTestPlayer(stateManager, guildID)
+		subscribers[guildID] = make(chan PlayerEvent, perGuild)
+		defer players[guildID].Subscribe(subscribers[guildID])()
+	}
+
+	var wg sync.WaitGroup
+	for _, guildID := range guildIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			song := &state.Song{Title: guildID}
+			for k := range perGuild {
+				players[guildID].publish(TrackHalfway, song, time.Duration(k), nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, guildID := range guildIDs {
+		for k, event := range receive(t, subscribers[guildID], perGuild) {
+			if event.GuildID != guildID || event.Song.Title != guildID {
+				t.Fatalf("%s got an event of %s about %s", guildID, event.GuildID, event.Song.Title)
+			}
+			if event.Position != time.Duration(k) {
+				t.Fatalf("%s got event %d as number %d", guildID, event.Position, k)
+			}
+		}
+		select {
+		case event := <-subscribers[guildID]:
+			t.Errorf("%s got an extra event from %s", guildID, event.GuildID)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestUnsubscribeStopsEvents(t *testing.T) {
+	stateManager := state.NewManager(state.Config{})
+	p := newTestPlayer(stateManager, "guildA")
+
+	kept := make(chan PlayerEvent, 4)
+	dropped := make(chan PlayerEvent, 4)
+	defer p.Subscribe(kept)()
+	unsubscribe := p.Subscribe(dropped)
+
+	song := &state.Song{Title: "song"}
+	p.publish(TrackStarted, song, 0, nil)
+	receive(t, kept, 1)
+	receive(t, dropped, 1)
+
+	unsubscribe()
+	p.publish(TrackEnded, song, 0, nil)
+	if event := receive(t, kept, 1)[0]; event.Type != TrackEnded {
+		t.Errorf("kept subscriber got %v, want %v", event.Type, TrackEnded)
+	}
+	select {
+	case event := <-dropped:
+		t.Errorf("got %v after unsubscribing", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestManagerFollowsItsPlayerUntilReleased(t *testing.T) {
+	dm := newTestDatabase(t)
+	stateManager := state.NewManager(state.Config{})
+	m := newTestManager(t, stateManager, dm, "guildA")
+
+	song := testSong("guildA", 0)
+	song.Duration = 0
+	if err := m.queue.Add(song, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The manager keeps the length the player measured.
+	m.player.(*Player).publish(TrackMeasured, song, 90*time.Second, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for m.GetQueue()[0].Song.Duration != 90 {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued song is %ds long, want 90s", m.GetQueue()[0].Song.Duration)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	m.Release()
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done wasn't closed after Release")
+	}
+}
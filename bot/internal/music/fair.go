@@ -0,0 +1,175 @@
+package music
+
+import "musicbot/internal/state"
+
+// QueueMode is how a guild's queue picks the next song.
+type QueueMode string
+
+const (
+	// QueueFIFO plays songs in the order they were queued.
+	QueueFIFO QueueMode = "fifo"
+	// QueueFair lets requesters take turns, so one user queueing a whole
+	// album doesn't hold everyone else back.
+	QueueFair QueueMode = "fair"
+)
+
+// QueueModes lists the modes in the order /queuemode offers them.
+var QueueModes = []QueueMode{QueueFIFO, QueueFair}
+
+// ParseQueueMode returns the mode called name.
+func ParseQueueMode(name string) (QueueMode, bool) {
+	for _, mode := range QueueModes {
+		if string(mode) == name {
+			return mode, true
+		}
+	}
+	return QueueFIFO, false
+}
+
+// FairQueue reports whether requesters take turns in the queue.
+func (m *Manager) FairQueue() bool {
+	return m.queue.IsFair()
+}
+
+// ApplyQueueMode makes the queue follow the mode chosen for the guild, and
+// prebuffers whatever now plays next.
+func (m *Manager) ApplyQueueMode() {
+	mode, _ := ParseQueueMode(m.session.State.GetQueueMode())
+	m.queue.SetFair(mode == QueueFair)
+	m.queueChanged()
+}
+
+// SetFair switches the queue between playing songs in the order they were
+// queued and fair mode, where requesters take turns. Fair mode only changes
+// which upcoming song is picked next; the rest stay where they were queued,
+// so switching back restores their order.
+func (q *Queue) SetFair(fair bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.fair = fair
+}
+
+// IsFair reports whether requesters take turns.
+func (q *Queue) IsFair() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.fair
+}
+
+// orderLocked returns the indices of the upcoming items in the order they
+// will play. The caller must hold q.mu.
+func (q *Queue) orderLocked() []int {
+	start := q.position + 1
+	if start >= len(q.items) {
+		return nil
+	}
+
+	if !q.fair {
+		order := make([]int, len(q.items)-start)
+		for k := range order {
+			order[k] = start + k
+		}
+		return order
+	}
+
+	played := make([]string, start)
+	for k := range played {
+		played[k] = q.items[k].RequestedBy
+	}
+	order := fairOrder(played, q.items[start:], q.pinned)
+	for k := range order {
+		order[k] += start
+	}
+	return order
+}
+
+// turnLocked returns when the item at index plays, 1 being next, or 0 if it
+// isn't upcoming. The caller must hold q.mu.
+func (q *Queue) turnLocked(index int) int {
+	for k, i := range q.orderLocked() {
+		if i == index {
+			return k + 1
+		}
+	}
+	return 0
+}
+
+// pinnedLocked reports whether the item at index is one of the pinned ones.
+// The caller must hold q.mu.
+func (q *Queue) pinnedLocked(index int) bool {
+	return index > q.position && index <= q.position+q.pinned
+}
+
+// moveNextLocked moves the item at index to right after the current one,
+// shifting those in between down by one. The caller must hold q.mu.
+func (q *Queue) moveNextLocked(index int) {
+	next := q.position + 1
+	if index <= next {
+		return
+	}
+
+	item := q.items[index]
+	copy(q.items[next+1:index+1], q.items[next:index])
+	q.items[next] = item
+	for k := next; k <= index; k++ {
+		q.items[k].Position = k + 1
+	}
+}
+
+// fairOrder returns the order, as offsets into upcoming, in which fair mode
+// plays the upcoming items. played holds who requested each item up to and
+// including the current one, most recent last. The first pinned items were
+// put next on purpose and play first; after them the requester whose last
+// turn is longest ago goes next, with those who haven't had one yet first
+// in the order they queued. Each requester's songs keep their own order.
+func fairOrder(played []string, upcoming []state.QueueItem, pinned int) []int {
+	pinned = min(max(pinned, 0), len(upcoming))
+
+	lastTurn := make(map[string]int)
+	for turn, requester := range played {
+		lastTurn[requester] = turn
+	}
+	turn := len(played)
+
+	order := make([]int, 0, len(upcoming))
+	for offset := range upcoming[:pinned] {
+		order = append(order, offset)
+		lastTurn[upcoming[offset].RequestedBy] = turn
+		turn++
+	}
+
+	var requesters []string
+	pending := make(map[string][]int)
+	for offset := pinned; offset < len(upcoming); offset++ {
+		requester := upcoming[offset].RequestedBy
+		if _, ok := pending[requester]; !ok {
+			requesters = append(requesters, requester)
+		}
+		pending[requester] = append(pending[requester], offset)
+	}
+
+	for len(order) < len(upcoming) {
+		next := ""
+		nextTurn := 0
+		for _, requester := range requesters {
+			if len(pending[requester]) == 0 {
+				continue
+			}
+			last, ok := lastTurn[requester]
+			if !ok {
+				last = -1
+			}
+			// Ties go to whoever's next song was queued first.
+			if next == "" || last < nextTurn || (last == nextTurn && pending[requester][0] < pending[next][0]) {
+				next, nextTurn = requester, last
+			}
+		}
+
+		order = append(order, pending[next][0])
+		pending[next] = pending[next][1:]
+		lastTurn[next] = turn
+		turn++
+	}
+
+	return order
+}
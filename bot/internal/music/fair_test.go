@@ -0,0 +1,121 @@
+package music
+
+import (
+	"context"
+	"musicbot/internal/state"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// requestedBy returns queue items requested by each of requesters in turn.
+func requestedBy(requesters ...string) []state.QueueItem {
+	items := make([]state.QueueItem, len(requesters))
+	for k, requester := range requesters {
+		items[k].RequestedBy = requester
+	}
+	return items
+}
+
+func TestFairOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		played   []string
+		upcoming []string
+		pinned   int
+		want     []int
+	}{
+		{"one requester keeps their order", []string{"a"}, []string{"a", "a", "a"}, 0, []int{0, 1, 2}},
+		{"a playlist doesn't hold back singles", []string{"a"}, []string{"a", "a", "a", "b", "c"}, 0, []int{3, 4, 0, 1, 2}},
+		{"requesters take turns", nil, []string{"a", "a", "a", "b", "b", "c"}, 0, []int{0, 3, 5, 1, 4, 2}},
+		{"newcomers go in the order they queued", []string{"a"}, []string{"c", "b", "a"}, 0, []int{0, 1, 2}},
+		{"longest since their last turn goes first", []string{"b", "a"}, []string{"a", "b"}, 0, []int{1, 0}},
+		{"turns count from the whole history", []string{"c", "b", "a"}, []string{"a", "b", "c"}, 0, []int{2, 1, 0}},
+		{"pinned songs play first and use up a turn", []string{"a"}, []string{"b", "a", "a", "b", "c"}, 1, []int{0, 4, 1, 3, 2}},
+		{"pinned beyond the queue", []string{"a"}, []string{"b", "a"}, 5, []int{0, 1}},
+		{"nothing upcoming", []string{"a"}, nil, 0, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fairOrder(tt.played, requestedBy(tt.upcoming...), tt.pinned); !slices.Equal(got, tt.want) {
+				t.Errorf("fairOrder(%v, %v, %d) = %v, want %v", tt.played, tt.upcoming, tt.pinned, got, tt.want)
+			}
+		})
+	}
+}
+
+// newFairQueue queues songs named after whoever requested them: a1 playing,
+// then a2, a3, b1 and c1.
+func newFairQueue(t *testing.T) *Queue {
+	t.Helper()
+	q := NewQueue(newTestDatabase(t), "")
+	t.Cleanup(func() { q.Close(context.Background()) })
+	for _, title := range []string{"a1", "a2", "a3", "b1", "c1"} {
+		song := &state.Song{Title: title, URL: "https://example.com/" + title, Platform: "test", FilePath: title + ".mp3"}
+		if err := q.Add(song, title[:1]); err != nil {
+			t.Fatalf("Add(%s): %v", title, err)
+		}
+	}
+	q.SetFair(true)
+	return q
+}
+
+func titles(songs []state.Song) string {
+	names := make([]string, len(songs))
+	for k, song := range songs {
+		names[k] = song.Title
+	}
+	return strings.Join(names, " ")
+}
+
+func TestFairQueuePlaysInTurns(t *testing.T) {
+	q := newFairQueue(t)
+
+	if got := titles(q.GetUpcoming(10)); got != "b1 c1 a2 a3" {
+		t.Errorf("upcoming = %s, want b1 c1 a2 a3", got)
+	}
+
+	var played []string
+	for q.HasNext() {
+		if next := q.GetNext(); next == nil {
+			t.Fatal("GetNext = nil while songs are upcoming")
+		}
+		song, err := q.Advance()
+		if err != nil {
+			t.Fatalf("Advance: %v", err)
+		}
+		played = append(played, song.Title)
+	}
+	if got := strings.Join(played, " "); got != "b1 c1 a2 a3" {
+		t.Errorf("played %s, want b1 c1 a2 a3", got)
+	}
+}
+
+func TestFairQueueTurnsAfterNewRequests(t *testing.T) {
+	q := newFairQueue(t)
+	if _, err := q.Advance(); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+
+	// b just had a turn, so d, who hasn't, and a, whose turn is longest
+	// ago, go before b's second song.
+	for _, title := range []string{"b2", "d1"} {
+		song := &state.Song{Title: title, URL: "https://example.com/" + title, Platform: "test", FilePath: title + ".mp3"}
+		if err := q.Add(song, title[:1]); err != nil {
+			t.Fatalf("Add(%s): %v", title, err)
+		}
+	}
+	if got := titles(q.GetUpcoming(10)); got != "c1 d1 a2 b2 a3" {
+		t.Errorf("upcoming = %s, want c1 d1 a2 b2 a3", got)
+	}
+}
+
+func TestFairQueueSwitchingBackRestoresOrder(t *testing.T) {
+	q := newFairQueue(t)
+	q.SetFair(false)
+
+	if got := titles(q.GetUpcoming(10)); got != "a2 a3 b1 c1" {
+		t.Errorf("upcoming after switching back = %s, want the order songs were queued in", got)
+	}
+}
@@ -0,0 +1,92 @@
+package music
+
+import (
+	"fmt"
+	"musicbot/internal/state"
+	"time"
+)
+
+// Filter is an audio filter preset applied to the songs a guild plays. Each
+// one adds an ffmpeg filter chain to the decode, which costs CPU on top of
+// decoding: bassboost and karaoke are cheap, while nightcore and vaporwave
+// resample every frame. Hosts that can't spare it set disable_filters.
+type Filter string
+
+const (
+	FilterOff       Filter = "off"
+	FilterBassBoost Filter = "bassboost"
+	FilterNightcore Filter = "nightcore"
+	FilterVaporwave Filter = "vaporwave"
+	FilterKaraoke   Filter = "karaoke"
+)
+
+// Filters lists the presets in the order /filter offers them.
+var Filters = []Filter{FilterOff, FilterBassBoost, FilterNightcore, FilterVaporwave, FilterKaraoke}
+
+// ParseFilter returns the preset called name.
+func ParseFilter(name string) (Filter, bool) {
+	for _, filter := range Filters {
+		if string(filter) == name {
+			return filter, true
+		}
+	}
+	return FilterOff, false
+}
+
+// Speed is how much faster than normal the preset plays a song.
+func (f Filter) Speed() float64 {
+	switch f {
+	case FilterNightcore:
+		return 1.25
+	case FilterVaporwave:
+		return 0.8
+	default:
+		return 1
+	}
+}
+
+// chain is the ffmpeg filter chain of the preset, run before the volume is
+// applied, or "" for none. Nightcore and vaporwave change speed and pitch
+// together by reinterpreting the sample rate and resampling back to 48kHz.
+func (f Filter) chain() string {
+	switch f {
+	case FilterBassBoost:
+		return "bass=g=10:f=110:w=0.6"
+	case FilterNightcore, FilterVaporwave:
+		return fmt.Sprintf("aresample=48000,asetrate=%d,aresample=48000", int(48000*f.Speed()))
+	case FilterKaraoke:
+		// Vocals are usually mixed to the center, so subtracting the
+		// channels from each other mostly leaves the instruments.
+		return "pan=stereo|c0=c0-c1|c1=c1-c0"
+	default:
+		return ""
+	}
+}
+
+// fileTime converts d of played audio to how far through the file it got.
+func (f Filter) fileTime(d time.Duration) time.Duration {
+	if f.Speed() == 1 {
+		return d
+	}
+	return time.Duration(float64(d) * f.Speed())
+}
+
+// audioFilters builds the -af argument for a decode at volume with filter.
+func audioFilters(volume float32, filter Filter) string {
+	volumeFilter := fmt.Sprintf("volume=%f", volume)
+	if chain := filter.chain(); chain != "" {
+		return chain + "," + volumeFilter
+	}
+	return volumeFilter
+}
+
+// songFilter returns the filter song plays with: the guild's preset, except
+// for live streams and when filters are disabled.
+func (p *Player) songFilter(song *state.Song) Filter {
+	guild := p.guild.Load()
+	if song.IsStream || guild == nil || p.stateManager.GetConfig().FiltersDisabled {
+		return FilterOff
+	}
+	filter, _ := ParseFilter(guild.GetFilter())
+	return filter
+}
@@ -0,0 +1,138 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/state"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// historySize is how many finished songs each guild remembers for
+// /previous and /history.
+const historySize = 10
+
+var (
+	ErrNoHistory    = errors.New("no songs have finished playing yet")
+	ErrPreviousGone = errors.New("the previous song's file is gone")
+)
+
+// HistoryEntry is a song that finished playing, or was skipped part way.
+type HistoryEntry struct {
+	Song       state.Song
+	FinishedAt time.Time
+	Skipped    bool
+}
+
+// history is a ring buffer of the songs the guild played last. It lives with
+// the manager, so it outlasts voice reconnects but not a restart.
+type history struct {
+	entries [historySize]HistoryEntry
+	next    int
+	count   int
+}
+
+func (h *history) push(entry HistoryEntry) {
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % historySize
+	if h.count < historySize {
+		h.count++
+	}
+}
+
+// pop removes and returns the most recent entry.
+func (h *history) pop() (HistoryEntry, bool) {
+	if h.count == 0 {
+		return HistoryEntry{}, false
+	}
+	h.next = (h.next + historySize - 1) % historySize
+	h.count--
+	entry := h.entries[h.next]
+	h.entries[h.next] = HistoryEntry{}
+	return entry, true
+}
+
+// list returns the entries, most recent first.
+func (h *history) list() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, h.count)
+	for k := 1; k <= h.count; k++ {
+		entries = append(entries, h.entries[(h.next-k+historySize)%historySize])
+	}
+	return entries
+}
+
+// recordHistory remembers song as finished.
+func (m *Manager) recordHistory(song *state.Song, skipped bool) {
+	if song == nil {
+		return
+	}
+
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	m.history.push(HistoryEntry{Song: *song, FinishedAt: time.Now(), Skipped: skipped})
+}
+
+// History returns the songs the guild played last, most recent first.
+func (m *Manager) History() []HistoryEntry {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	return m.history.list()
+}
+
+// historySongIDs returns the IDs of the songs in the history, so their files
+// are kept for /previous.
+func (m *Manager) historySongIDs() []int64 {
+	var ids []int64
+	for _, entry := range m.History() {
+		ids = append(ids, entry.Song.ID)
+	}
+	return ids
+}
+
+// PlayPrevious plays the most recent song of the history right away. The
+// song playing, if any, goes back to the front of the queue to play next.
+func (m *Manager) PlayPrevious() (*state.Song, error) {
+	if atomic.LoadInt32(&m.clearing) == 1 {
+		return nil, fmt.Errorf("cannot go back while clearing queue")
+	}
+
+	vc := m.getVoiceConnection()
+	if vc == nil {
+		return nil, fmt.Errorf("no voice connection available")
+	}
+
+	m.historyMu.Lock()
+	entry, ok := m.history.pop()
+	m.historyMu.Unlock()
+	if !ok {
+		return nil, ErrNoHistory
+	}
+
+	song := entry.Song
+	if !song.IsStream {
+		if _, err := os.Stat(m.player.filePath(&song)); err != nil {
+			return nil, ErrPreviousGone
+		}
+	}
+
+	m.mu.Lock()
+	playing := m.player.IsPlaying() || m.player.IsPaused()
+	if err := m.queue.InsertCurrent(&song, song.RequesterID, playing); err != nil {
+		m.mu.Unlock()
+		m.historyMu.Lock()
+		m.history.push(entry)
+		m.historyMu.Unlock()
+		return nil, err
+	}
+	m.session.State.SetBotState(state.StateDJ)
+	m.mu.Unlock()
+
+	m.cancelIdle("previous song requested")
+	m.queueChanged()
+
+	if err := m.play(vc, &song, handover{stopRadio: true, stopSong: true}); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
@@ -0,0 +1,281 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"strings"
+	"time"
+)
+
+// reservationGrace keeps a reservation alive while nothing is pending, which
+// covers the gap between a playlist request and the downloader reporting how
+// many tracks it will send.
+const reservationGrace = 30 * time.Second
+
+var ErrQueueFull = errors.New("queue is full")
+
+// LimitError is returned when a request would exceed the queue limits.
+type LimitError struct {
+	Limit   int
+	PerUser bool
+}
+
+func (e *LimitError) Error() string {
+	if e.PerUser {
+		return fmt.Sprintf("user already has %d tracks queued", e.Limit)
+	}
+	return fmt.Sprintf("queue is full (%d tracks)", e.Limit)
+}
+
+// DownloadLimitError is reported when the downloader refuses a track for
+// being longer or larger than the limits it was requested with.
+type DownloadLimitError struct {
+	Limits   config.DownloadLimits
+	TooLarge bool
+	Reason   string
+
+	// Duration is the track's length in seconds when it was known before
+	// the download, or 0.
+	Duration int
+}
+
+func (e *DownloadLimitError) Error() string {
+	return e.Reason
+}
+
+// CheckDuration refuses a track whose length, known from search results or
+// a probe, is over the duration limit, before anything is downloaded. A
+// length of 0 is unknown and left to the downloader.
+func CheckDuration(seconds int, limits config.DownloadLimits) error {
+	if seconds <= 0 || limits.MaxDurationSeconds <= 0 || seconds <= limits.MaxDurationSeconds {
+		return nil
+	}
+	return &DownloadLimitError{
+		Limits:   limits,
+		Duration: seconds,
+		Reason:   fmt.Sprintf("duration %ds exceeds limit of %ds", seconds, limits.MaxDurationSeconds),
+	}
+}
+
+// ProbeDuration asks the downloader for the length of the track at url
+// without downloading it. It returns 0 when the length is unknown, as for a
+// playlist.
+func (m *Manager) ProbeDuration(ctx context.Context, url string) (int, error) {
+	if m.socketClient == nil || !m.socketClient.IsConnected() {
+		return 0, fmt.Errorf("downloader not available")
+	}
+
+	info, err := m.socketClient.GetPlaylistInfo(ctx, url, 1)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsPlaylist {
+		return 0, nil
+	}
+	return info.Duration, nil
+}
+
+// downloadLimitError recognises the downloader's refusals over the duration
+// or size limit, returning nil for any other failure.
+func downloadLimitError(reason string, limits config.DownloadLimits) error {
+	lower := strings.ToLower(reason)
+	switch {
+	case strings.Contains(lower, "duration") && strings.Contains(lower, "exceeds limit"),
+		strings.Contains(lower, "too long"):
+		return &DownloadLimitError{Limits: limits, Reason: reason}
+	case strings.Contains(lower, "size") && strings.Contains(lower, "exceeds limit"),
+		strings.Contains(lower, "too large"):
+		return &DownloadLimitError{Limits: limits, TooLarge: true, Reason: reason}
+	}
+	return nil
+}
+
+// DownloadLimits returns the download limits configured for guildID.
+func (m *Manager) DownloadLimits(guildID string) config.DownloadLimits {
+	limits, err := m.dbManager.GetDownloadLimits(guildID)
+	if err != nil {
+		logger.Error.Printf("Failed to load download limits for guild %s, using defaults: %v", guildID, err)
+		return config.DefaultDownloadLimits()
+	}
+	return limits
+}
+
+// reservation holds queue slots for downloads that have been requested but
+// not added yet, so limits are enforced before anything is downloaded.
+type reservation struct {
+	userID    string
+	remaining int
+	created   time.Time
+}
+
+// userRequest is what a reservation is kept under: a user and the song or
+// playlist URL they asked for. Two users asking for the same URL each hold
+// their own slots.
+type userRequest struct {
+	userID string
+	url    string
+}
+
+// SetQueueLimits sets the limits the guild's queue is held to. A limit of
+// zero, as in a manager without any, is no limit.
+func (m *Manager) SetQueueLimits(limits config.QueueLimits) {
+	m.limitsMu.Lock()
+	m.limits = limits
+	m.limitsMu.Unlock()
+
+	m.queue.SetMaxLength(limits.MaxQueueLength)
+}
+
+func (m *Manager) GetQueueLimits() config.QueueLimits {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	return m.limits
+}
+
+// RemainingCapacity returns how many more tracks userID may request. It
+// returns a *LimitError when the answer is zero.
+func (m *Manager) RemainingCapacity(userID string) (int, error) {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	return m.remainingLocked(userID)
+}
+
+func (m *Manager) remainingLocked(userID string) (int, error) {
+	remainingTotal, remainingUser := m.capacityLocked(userID)
+	if remainingTotal <= 0 {
+		return 0, &LimitError{Limit: m.limits.MaxQueueLength}
+	}
+	if remainingUser <= 0 {
+		return 0, &LimitError{Limit: m.limits.MaxUserQueued, PerUser: true}
+	}
+	return min(remainingTotal, remainingUser), nil
+}
+
+// capacityLocked returns the free slots in the queue and for userID, counting
+// both queued songs and outstanding reservations.
+func (m *Manager) capacityLocked(userID string) (int, int) {
+	total := m.queue.UpcomingCount()
+	byUser := m.queue.UpcomingCountBy(userID)
+	for _, r := range m.reservations {
+		total += r.remaining
+		if r.userID == userID {
+			byUser += r.remaining
+		}
+	}
+
+	return remaining(m.limits.MaxQueueLength, total), remaining(m.limits.MaxUserQueued, byUser)
+}
+
+// remaining is how far used is below limit, where a limit of zero or less
+// never runs out.
+func remaining(limit, used int) int {
+	if limit <= 0 {
+		return math.MaxInt
+	}
+	return limit - used
+}
+
+// reserve holds count slots for userID's request of url.
+func (m *Manager) reserve(url, userID string, count int) error {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+
+	remainingTotal, remainingUser := m.capacityLocked(userID)
+	if count > remainingTotal {
+		return &LimitError{Limit: m.limits.MaxQueueLength}
+	}
+	if count > remainingUser {
+		return &LimitError{Limit: m.limits.MaxUserQueued, PerUser: true}
+	}
+
+	key := userRequest{userID: userID, url: url}
+	if existing, ok := m.reservations[key]; ok {
+		existing.remaining += count
+		return nil
+	}
+
+	m.reservations[key] = &reservation{userID: userID, remaining: count, created: time.Now()}
+	return nil
+}
+
+// oldestReservationLocked finds the earliest reservation made for url, which
+// a download of it is charged to first. It must be called with limitsMu held.
+func (m *Manager) oldestReservationLocked(url string) (userRequest, *reservation) {
+	var oldestKey userRequest
+	var oldest *reservation
+	for key, r := range m.reservations {
+		if key.url == url && (oldest == nil || r.created.Before(oldest.created)) {
+			oldestKey, oldest = key, r
+		}
+	}
+	return oldestKey, oldest
+}
+
+// consumeReservation takes one slot from the oldest reservation for url and
+// returns the user it belonged to.
+func (m *Manager) consumeReservation(url string) string {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+
+	key, r := m.oldestReservationLocked(url)
+	if r == nil {
+		return ""
+	}
+
+	r.remaining--
+	if r.remaining <= 0 {
+		delete(m.reservations, key)
+	}
+	return r.userID
+}
+
+// reservedBy returns the user who made the oldest reservation for url, if
+// any.
+func (m *Manager) reservedBy(url string) string {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+
+	if _, r := m.oldestReservationLocked(url); r != nil {
+		return r.userID
+	}
+	return ""
+}
+
+// releaseReservation drops userID's reservation for url once the request it
+// was made for is over. Without a userID it drops the oldest one for url.
+func (m *Manager) releaseReservation(url, userID string) {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+
+	if userID != "" {
+		delete(m.reservations, userRequest{userID: userID, url: url})
+		return
+	}
+	if key, r := m.oldestReservationLocked(url); r != nil {
+		delete(m.reservations, key)
+	}
+}
+
+// pruneReservations drops reservations that can no longer be filled, such as
+// failed downloads or playlists shorter than requested. It runs once nothing
+// is pending.
+func (m *Manager) pruneReservations() {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+
+	for key, r := range m.reservations {
+		if time.Since(r.created) > reservationGrace {
+			delete(m.reservations, key)
+		}
+	}
+}
+
+func (m *Manager) clearReservations() {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	m.reservations = make(map[userRequest]*reservation)
+}
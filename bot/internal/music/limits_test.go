@@ -0,0 +1,113 @@
+package music
+
+import (
+	"errors"
+	"math"
+	"musicbot/internal/config"
+	"musicbot/internal/state"
+	"testing"
+)
+
+func TestRemainingCapacityBoundaries(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     config.QueueLimits
+		queued     int
+		want       int
+		wantFull   bool
+		wantByUser bool
+	}{
+		{"no limits", config.QueueLimits{}, 3, math.MaxInt, false, false},
+		{"no queue limit", config.QueueLimits{MaxUserQueued: 5}, 3, 2, false, false},
+		{"no user limit", config.QueueLimits{MaxQueueLength: 5}, 3, 2, false, false},
+		{"one slot left in the queue", config.QueueLimits{MaxQueueLength: 4, MaxUserQueued: 10}, 3, 1, false, false},
+		{"queue exactly full", config.QueueLimits{MaxQueueLength: 3, MaxUserQueued: 10}, 3, 0, true, false},
+		{"one slot left for the user", config.QueueLimits{MaxQueueLength: 10, MaxUserQueued: 4}, 3, 1, false, false},
+		{"user exactly at their limit", config.QueueLimits{MaxQueueLength: 10, MaxUserQueued: 3}, 3, 0, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, state.NewManager(state.Config{}), newTestDatabase(t), "guild")
+			m.SetQueueLimits(tt.limits)
+			// The first song is the one playing, which isn't upcoming.
+			if err := m.queue.Add(testSong("guild", 0), "dj"); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			for n := 1; n <= tt.queued; n++ {
+				if err := m.queue.Add(testSong("guild", n), "user"); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+
+			got, err := m.RemainingCapacity("user")
+			if got != tt.want {
+				t.Errorf("RemainingCapacity = %d, want %d", got, tt.want)
+			}
+			var limitErr *LimitError
+			if full := errors.As(err, &limitErr); full != tt.wantFull {
+				t.Fatalf("RemainingCapacity error = %v, want a *LimitError: %v", err, tt.wantFull)
+			}
+			if tt.wantFull && limitErr.PerUser != tt.wantByUser {
+				t.Errorf("PerUser = %v, want %v", limitErr.PerUser, tt.wantByUser)
+			}
+		})
+	}
+}
+
+func TestReserveBoundaries(t *testing.T) {
+	m := newTestManager(t, state.NewManager(state.Config{}), newTestDatabase(t), "guild")
+	m.SetQueueLimits(config.QueueLimits{MaxQueueLength: 5, MaxUserQueued: 3})
+
+	if err := m.reserve("https://example.com/a", "a", 3); err != nil {
+		t.Fatalf("reserving exactly the user limit: %v", err)
+	}
+	var limitErr *LimitError
+	if err := m.reserve("https://example.com/b", "a", 1); !errors.As(err, &limitErr) || !limitErr.PerUser {
+		t.Errorf("reserving past the user limit = %v, want a per-user *LimitError", err)
+	}
+	if err := m.reserve("https://example.com/b", "b", 2); err != nil {
+		t.Fatalf("reserving exactly the queue limit: %v", err)
+	}
+	if err := m.reserve("https://example.com/c", "c", 1); !errors.As(err, &limitErr) || limitErr.PerUser {
+		t.Errorf("reserving past the queue limit = %v, want a queue *LimitError", err)
+	}
+
+	// Without limits nothing is ever full.
+	m.SetQueueLimits(config.QueueLimits{})
+	if err := m.reserve("https://example.com/c", "c", 1000); err != nil {
+		t.Errorf("reserving without limits: %v", err)
+	}
+}
+
+func TestReservationsAreKeptPerUser(t *testing.T) {
+	const url = "https://example.com/song"
+	m := newTestManager(t, state.NewManager(state.Config{}), newTestDatabase(t), "guild")
+	m.SetQueueLimits(config.QueueLimits{MaxQueueLength: 10, MaxUserQueued: 1})
+
+	if err := m.reserve(url, "a", 1); err != nil {
+		t.Fatalf("reserve for a: %v", err)
+	}
+	// b asking for the same song is charged to b, not added to a's slots.
+	if err := m.reserve(url, "b", 1); err != nil {
+		t.Fatalf("reserve for b: %v", err)
+	}
+	for _, user := range []string{"a", "b"} {
+		if _, err := m.RemainingCapacity(user); err == nil {
+			t.Errorf("%s has room left, want their one slot held", user)
+		}
+	}
+
+	// Downloads are charged oldest request first.
+	if got := m.consumeReservation(url); got != "a" {
+		t.Errorf("first download charged to %q, want a", got)
+	}
+	if got := m.reservedBy(url); got != "b" {
+		t.Errorf("reservedBy after a's download = %q, want b", got)
+	}
+
+	m.releaseReservation(url, "b")
+	if remaining, err := m.RemainingCapacity("b"); err != nil || remaining != 1 {
+		t.Errorf("RemainingCapacity(b) after release = %d, %v, want 1", remaining, err)
+	}
+}
@@ -2,12 +2,14 @@ package music
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"musicbot/internal/config"
 	"musicbot/internal/logger"
-	"musicbot/internal/radio"
+	"musicbot/internal/metrics"
 	"musicbot/internal/socket"
 	"musicbot/internal/state"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,40 +17,145 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+// ErrDownloadLost is reported for downloads that were in flight when the
+// downloader connection was reset.
+var ErrDownloadLost = errors.New("the downloader restarted before the song finished")
+
+// musicPlayer is what the manager needs of its Player. Tests stand in for
+// the player through it.
+type musicPlayer interface {
+	SetGuild(guild *state.Guild)
+	Subscribe(ch chan<- PlayerEvent) (unsubscribe func())
+	IsPlaying() bool
+	IsPaused() bool
+	GetCurrentSong() *state.Song
+	Position() time.Duration
+	Filter() Filter
+	Pause()
+	Resume(vc *discordgo.VoiceConnection) error
+	Stop()
+	StopWithoutEvent()
+	Overlay(pcm []int16) error
+	Prebuffer(song *state.Song)
+	InvalidatePrebuffer()
+	Shutdown(ctx context.Context) error
+	checkFile(song *state.Song) error
+	start(vc *discordgo.VoiceConnection, song *state.Song, offset time.Duration, resumed bool) error
+	songFilter(song *state.Song) Filter
+	filePath(song *state.Song) string
+}
+
 type Manager struct {
-	player              *Player
+	player              musicPlayer
 	queue               *Queue
 	stateManager        *state.Manager
 	dbManager           *config.DatabaseManager
 	socketClient        *socket.Client
-	radioManager        *radio.Manager
-	vcGetter            func() *discordgo.VoiceConnection
+	session             *Session
 	activeDownloads     map[string]bool
 	activePlaylistUrls  map[string]bool
+	playNextUrls        map[string]bool
+	duplicateUrls       map[string]bool
+	prefetchUrls        map[string]bool
+	repairUrls          map[string]bool
+	recoveries          map[string]bool
+	stalledURL          string
+	retriedSong         atomic.Pointer[state.Song]
+	playlists           map[string]*playlistProgress
+	notifiers           map[string]RequestNotifier
+	requestLimits       map[string]config.DownloadLimits
+	downloadAttempts    map[string]int
+	downloadRequests    map[string]string
 	pendingDownloads    int32
 	clearing            int32
 	disableAutoHandlers int32
+	limits              config.QueueLimits
+	reservations        map[userRequest]*reservation
+	soloClips           map[string]bool
+	history             history
+	cleared             *clearedQueue
+	shutdownNotice      func(ctx context.Context, guildID string, queued int)
+	skipNotice          func(guildID string, song *state.Song, reason error)
 	mu                  sync.RWMutex
 	downloadMu          sync.RWMutex
+	limitsMu            sync.Mutex
+	clipMu              sync.Mutex
+	historyMu           sync.Mutex
+	undoMu              sync.Mutex
+	done                chan struct{}
+	stopEvents          func()
 }
 
-func NewManager(stateManager *state.Manager, dbManager *config.DatabaseManager, radioManager *radio.Manager, socketClient *socket.Client) *Manager {
+// NewManager creates the music manager of the guild session plays in, with
+// the queue the guild left behind.
+func NewManager(stateManager *state.Manager, dbManager *config.DatabaseManager, socketClient *socket.Client, session *Session) *Manager {
 	manager := &Manager{
 		player:             NewPlayer(stateManager),
-		queue:              NewQueue(dbManager),
+		queue:              NewQueue(dbManager, session.State.ID()),
 		stateManager:       stateManager,
 		dbManager:          dbManager,
-		radioManager:       radioManager,
 		socketClient:       socketClient,
+		session:            session,
 		activeDownloads:    make(map[string]bool),
 		activePlaylistUrls: make(map[string]bool),
+		playNextUrls:       make(map[string]bool),
+		duplicateUrls:      make(map[string]bool),
+		prefetchUrls:       make(map[string]bool),
+		repairUrls:         make(map[string]bool),
+		recoveries:         make(map[string]bool),
+		playlists:          make(map[string]*playlistProgress),
+		notifiers:          make(map[string]RequestNotifier),
+		requestLimits:      make(map[string]config.DownloadLimits),
+		downloadAttempts:   make(map[string]int),
+		downloadRequests:   make(map[string]string),
+		reservations:       make(map[userRequest]*reservation),
+		soloClips:          make(map[string]bool),
+		done:               make(chan struct{}),
 	}
 
-	manager.player.SetOnSongEnd(manager.onSongEnd)
+	manager.player.SetGuild(session.State)
+	manager.queue.SetFair(session.State.GetQueueMode() == string(QueueFair))
+
+	events := make(chan PlayerEvent)
+	unsubscribe := manager.player.Subscribe(events)
+	manager.stopEvents = sync.OnceFunc(func() {
+		unsubscribe()
+		close(manager.done)
+	})
+	go manager.handleEvents(events)
 
 	return manager
 }
 
+// Done is closed once the manager has shut down or was released. Whoever
+// follows its events can stop there.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// handleEvents carries the guild's playback on from what its player reports.
+func (m *Manager) handleEvents(events <-chan PlayerEvent) {
+	for {
+		select {
+		case event := <-events:
+			switch event.Type {
+			case TrackStarted:
+				m.onSongStart(event.Song)
+			case TrackHalfway:
+				m.onHalfway(event.Song)
+			case TrackEnded, TrackStopped:
+				m.onSongEnd(event.Song, event.Type == TrackStopped)
+			case TrackFailed:
+				m.onSongError(event.Song, event.Error)
+			case TrackMeasured:
+				m.onMeasured(event.Song, event.Position)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
 func (m *Manager) EnableAutoHandlers() {
 	atomic.StoreInt32(&m.disableAutoHandlers, 0)
 	logger.Debug.Println("Auto handlers enabled")
@@ -102,7 +209,40 @@ func (m *Manager) IsPaused() bool {
 	return m.player.IsPaused()
 }
 
-func (m *Manager) RequestSong(url, requestedBy string) error {
+// RequestNotifier is told how a song request ended. A download can take longer
+// than the interaction that asked for it stays open, so reaching the requester
+// is left to the notifier. Queued is also told when the song is expected to
+// start.
+type RequestNotifier interface {
+	Queued(song *state.Song, eta ETA)
+	Failed(err error)
+}
+
+// PlaylistNotifier follows a requested playlist: how many of its tracks are
+// through so far, how many were queued and why the others were skipped once
+// it is done, or why it stopped.
+type PlaylistNotifier interface {
+	PlaylistProgress(done, failed, total int)
+	PlaylistDone(added, duplicates int, skipped Skipped)
+	Failed(err error)
+}
+
+// playlistProgress counts the tracks of a playlist as they arrive. cancel
+// stops its download and done is closed once the download has wound down.
+type playlistProgress struct {
+	added       int
+	duplicates  int
+	skipped     Skipped
+	requestedBy string
+	notifier    PlaylistNotifier
+	cancel      context.CancelCauseFunc
+	done        chan struct{}
+}
+
+// RequestSong asks the downloader for url within limits. notifier, if set, is
+// told when the song is queued or the download fails. A song that is already
+// playing or queued is refused with a *DuplicateError.
+func (m *Manager) RequestSong(url, requestedBy string, limits config.DownloadLimits, notifier RequestNotifier) error {
 	if atomic.LoadInt32(&m.clearing) == 1 {
 		logger.Info.Printf("Ignoring song request while clearing queue: %s", url)
 		return nil
@@ -112,6 +252,16 @@ func (m *Manager) RequestSong(url, requestedBy string) error {
 		return fmt.Errorf("downloader not available")
 	}
 
+	m.downloadMu.RLock()
+	allowDuplicate := m.duplicateUrls[url]
+	m.downloadMu.RUnlock()
+
+	if !allowDuplicate {
+		if err := m.findDuplicate(url, ""); err != nil {
+			return err
+		}
+	}
+
 	m.downloadMu.Lock()
 	if m.activeDownloads[url] {
 		m.downloadMu.Unlock()
@@ -121,8 +271,23 @@ func (m *Manager) RequestSong(url, requestedBy string) error {
 	m.activeDownloads[url] = true
 	m.downloadMu.Unlock()
 
+	if err := m.reserve(url, requestedBy, 1); err != nil {
+		m.downloadMu.Lock()
+		delete(m.activeDownloads, url)
+		m.downloadMu.Unlock()
+		return err
+	}
+
+	m.downloadMu.Lock()
+	m.requestLimits[url] = limits
+	if notifier != nil {
+		m.notifiers[url] = notifier
+	}
+	m.downloadMu.Unlock()
+
 	atomic.AddInt32(&m.pendingDownloads, 1)
 	logger.Info.Printf("Requesting download for: %s (pending: %d)", url, atomic.LoadInt32(&m.pendingDownloads))
+	m.cancelIdle("song requested")
 
 	go func() {
 		defer func() {
@@ -131,20 +296,96 @@ func (m *Manager) RequestSong(url, requestedBy string) error {
 			m.downloadMu.Unlock()
 		}()
 
-		err := m.socketClient.SendDownloadRequest(url, requestedBy)
+		requestID, err := m.socketClient.SendDownloadRequest(url, requestedBy, limits)
 		if err != nil {
 			atomic.AddInt32(&m.pendingDownloads, -1)
+			m.releaseReservation(url, requestedBy)
 			logger.Error.Printf("Failed to send download request: %v", err)
+			if notifier := m.takeNotifier(url); notifier != nil {
+				notifier.Failed(err)
+			}
+			return
 		}
+		m.trackRequest(url, requestID)
 	}()
 
 	return nil
 }
 
-func (m *Manager) RequestPlaylist(url, requestedBy string, limit int) error {
+// Prefetch downloads url within limits without queueing it, so a later
+// request for it is served from the cache. Songs already queued or being
+// downloaded are left alone.
+func (m *Manager) Prefetch(url string, limits config.DownloadLimits) error {
+	if m.socketClient == nil || !m.socketClient.IsConnected() {
+		return fmt.Errorf("downloader not available")
+	}
+	if m.FindQueued(url) != nil || m.IsDownloading(url) {
+		return nil
+	}
+	if song, err := m.dbManager.GetSongByURL(url); err == nil && song.FilePath != "" {
+		if _, err := os.Stat(song.FilePath); err == nil {
+			logger.Debug.Printf("Not prefetching %s, it is cached", url)
+			return nil
+		}
+	}
+
+	m.downloadMu.Lock()
+	m.prefetchUrls[url] = true
+	m.downloadMu.Unlock()
+
+	atomic.AddInt32(&m.pendingDownloads, 1)
+	if _, err := m.socketClient.SendDownloadRequest(url, "", limits); err != nil {
+		atomic.AddInt32(&m.pendingDownloads, -1)
+		m.downloadMu.Lock()
+		delete(m.prefetchUrls, url)
+		m.downloadMu.Unlock()
+		return err
+	}
+
+	logger.Info.Printf("Prefetching %s", url)
+	return nil
+}
+
+// RequestSongNext downloads url like RequestSong, but the finished song is
+// inserted right after the current one instead of at the end of the queue.
+func (m *Manager) RequestSongNext(url, requestedBy string, limits config.DownloadLimits, notifier RequestNotifier) error {
+	m.downloadMu.Lock()
+	m.playNextUrls[url] = true
+	m.downloadMu.Unlock()
+
+	err := m.RequestSong(url, requestedBy, limits, notifier)
+	if err != nil {
+		m.downloadMu.Lock()
+		delete(m.playNextUrls, url)
+		m.downloadMu.Unlock()
+	}
+	return err
+}
+
+// RequestSongAllowDuplicate downloads url like RequestSong, but queues it even
+// if the same song is already playing or queued.
+func (m *Manager) RequestSongAllowDuplicate(url, requestedBy string, limits config.DownloadLimits, notifier RequestNotifier) error {
+	m.downloadMu.Lock()
+	m.duplicateUrls[url] = true
+	m.downloadMu.Unlock()
+
+	err := m.RequestSong(url, requestedBy, limits, notifier)
+	if err != nil {
+		m.downloadMu.Lock()
+		delete(m.duplicateUrls, url)
+		m.downloadMu.Unlock()
+	}
+	return err
+}
+
+// RequestPlaylist downloads up to limit tracks of url, several at a time, and
+// queues them in playlist order. Tracks that are already playing or queued
+// are skipped. notifier, if set, follows the progress and is told how many
+// were added once the playlist is done.
+func (m *Manager) RequestPlaylist(url, requestedBy string, limit int, limits config.DownloadLimits, notifier PlaylistNotifier) error {
 	if atomic.LoadInt32(&m.clearing) == 1 {
 		logger.Info.Printf("Ignoring playlist request while clearing queue: %s", url)
-		return nil
+		return ErrPlaylistCancelled
 	}
 
 	if m.socketClient == nil || !m.socketClient.IsConnected() {
@@ -155,116 +396,350 @@ func (m *Manager) RequestPlaylist(url, requestedBy string, limit int) error {
 	if m.activePlaylistUrls[url] {
 		m.downloadMu.Unlock()
 		logger.Info.Printf("Playlist already being downloaded: %s", url)
-		return nil
+		return ErrPlaylistInProgress
 	}
 	m.activePlaylistUrls[url] = true
 	m.downloadMu.Unlock()
 
+	if err := m.reserve(url, requestedBy, limit); err != nil {
+		m.downloadMu.Lock()
+		delete(m.activePlaylistUrls, url)
+		m.downloadMu.Unlock()
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	progress := &playlistProgress{
+		requestedBy: requestedBy,
+		notifier:    notifier,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	m.downloadMu.Lock()
+	m.playlists[url] = progress
+	m.downloadMu.Unlock()
+
 	logger.Info.Printf("Requesting playlist download for: %s (limit: %d)", url, limit)
+	m.cancelIdle("playlist requested")
+
+	go m.downloadPlaylist(ctx, url, limit, limits, progress)
+
+	return nil
+}
+
+// OnDownloadComplete queues a song the downloader finished for this manager.
+func (m *Manager) OnDownloadComplete(song *state.Song) error {
+	return m.completeDownload(song, song.URL)
+}
+
+// Awaits reports whether the manager is waiting on the downloader for url,
+// so a finished or failed download is handed to the guild that asked for it.
+func (m *Manager) Awaits(url string) bool {
+	m.downloadMu.RLock()
+	defer m.downloadMu.RUnlock()
+
+	_, requested := m.requestLimits[url]
+	return requested || m.activeDownloads[url] || m.prefetchUrls[url] || m.repairUrls[url]
+}
+
+// OnDownloadFailed tries a download that failed for a passing reason again,
+// up to the attempts allowed by its limits. Otherwise the failure is reported
+// to whoever requested it, as a *DownloadLimitError for a refusal over the
+// download limits and a *DownloadError for anything else. Either way the
+// failed attempt no longer counts as pending.
+func (m *Manager) OnDownloadFailed(url, reason string) {
+	defer m.completeDownload(nil, "")
+
+	m.downloadMu.Lock()
+	limits, ok := m.requestLimits[url]
+	if !ok && m.prefetchUrls[url] {
+		delete(m.prefetchUrls, url)
+		m.downloadMu.Unlock()
+		logger.Info.Printf("Prefetch of %s failed: %s", url, reason)
+		return
+	}
+	attempts := m.downloadAttempts[url] + 1
+	class := classifyDownloadError(reason)
+	if ok && retryable(class) && attempts < limits.MaxAttempts {
+		m.downloadAttempts[url] = attempts
+		m.downloadMu.Unlock()
+		m.retryDownload(url, reason, class, attempts, limits)
+		return
+	}
+	delete(m.requestLimits, url)
+	delete(m.duplicateUrls, url)
+	delete(m.repairUrls, url)
+	delete(m.downloadAttempts, url)
+	delete(m.downloadRequests, url)
+	m.downloadMu.Unlock()
+
+	m.recordFailure(url, -1, m.reservedBy(url), reason)
+
+	notifier := m.takeNotifier(url)
+	if notifier == nil {
+		return
+	}
+
+	if ok {
+		if err := downloadLimitError(reason, limits); err != nil {
+			notifier.Failed(err)
+			return
+		}
+	}
+	notifier.Failed(downloadError(reason, "", attempts))
+}
+
+// retryDownload sends the request for url again after a backoff. The failed
+// attempt is counted as finished by OnDownloadFailed, so the download is
+// counted as pending once more.
+func (m *Manager) retryDownload(url, reason, class string, attempt int, limits config.DownloadLimits) {
+	delay := retryDelay(attempt)
+	logger.Info.Printf("Download of %s failed (%s, attempt %d of %d), retrying in %v: %s",
+		url, class, attempt, limits.MaxAttempts, delay.Round(time.Millisecond), reason)
+	metrics.DownloadRetried(class)
+	atomic.AddInt32(&m.pendingDownloads, 1)
 
 	go func() {
-		defer func() {
-			m.downloadMu.Lock()
-			delete(m.activePlaylistUrls, url)
-			m.downloadMu.Unlock()
-		}()
+		time.Sleep(delay)
+
+		// A clear or a downloader reset in the meantime drops the download.
+		m.downloadMu.RLock()
+		_, waiting := m.requestLimits[url]
+		m.downloadMu.RUnlock()
+		if !waiting || atomic.LoadInt32(&m.clearing) == 1 {
+			return
+		}
 
-		err := m.socketClient.SendPlaylistRequest(url, requestedBy, limit)
+		requestID, err := m.socketClient.SendDownloadRequest(url, "", limits)
 		if err != nil {
-			logger.Error.Printf("Failed to send playlist request: %v", err)
+			logger.Error.Printf("Failed to send download retry for %s: %v", url, err)
+			m.recordFailure(url, -1, m.reservedBy(url), reason)
+			atomic.AddInt32(&m.pendingDownloads, -1)
+			m.releaseReservation(url, "")
+			m.downloadMu.Lock()
+			delete(m.requestLimits, url)
+			delete(m.downloadAttempts, url)
+			delete(m.downloadRequests, url)
+			m.downloadMu.Unlock()
+			if notifier := m.takeNotifier(url); notifier != nil {
+				notifier.Failed(downloadError(reason, "", attempt))
+			}
+			return
 		}
+		m.trackRequest(url, requestID)
 	}()
-
-	return nil
 }
 
-func (m *Manager) OnPlaylistStart(totalTracks int) {
-	if atomic.LoadInt32(&m.clearing) == 1 {
-		logger.Info.Printf("Ignoring playlist start while clearing queue, tracks: %d", totalTracks)
-		return
-	}
+func (m *Manager) takeNotifier(url string) RequestNotifier {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
 
-	atomic.AddInt32(&m.pendingDownloads, int32(totalTracks))
-	logger.Info.Printf("Playlist started with %d tracks (total pending: %d)", totalTracks, atomic.LoadInt32(&m.pendingDownloads))
+	notifier := m.notifiers[url]
+	delete(m.notifiers, url)
+	return notifier
 }
 
-func (m *Manager) OnDownloadComplete(song *state.Song) error {
-	atomic.AddInt32(&m.pendingDownloads, -1)
+// completeDownload adds a finished download to the queue in the background,
+// charging it to the reservation made under reservationKey when it was
+// requested.
+func (m *Manager) completeDownload(song *state.Song, reservationKey string) error {
+	return m.acceptDownload(song, reservationKey, false)
+}
+
+// acceptDownload is completeDownload, but with inOrder set the song is in the
+// queue before it returns, so consecutive calls queue songs in call order.
+func (m *Manager) acceptDownload(song *state.Song, reservationKey string, inOrder bool) error {
+	if atomic.AddInt32(&m.pendingDownloads, -1) <= 0 {
+		defer m.pruneReservations()
+	}
 
 	if song == nil {
 		logger.Info.Printf("Download failed, decremented pending counter (pending: %d)", atomic.LoadInt32(&m.pendingDownloads))
 		return nil
 	}
 
+	m.downloadMu.Lock()
+	prefetched := m.prefetchUrls[song.URL]
+	delete(m.prefetchUrls, song.URL)
+	m.downloadMu.Unlock()
+	if prefetched {
+		logger.Info.Printf("Prefetched %s", song.Title)
+		return nil
+	}
+
+	if m.takeRepair(song.URL) {
+		m.repaired(song)
+		return nil
+	}
+
 	if atomic.LoadInt32(&m.clearing) == 1 {
 		logger.Info.Printf("Ignoring download completion while clearing queue: %s (pending: %d)", song.Title, atomic.LoadInt32(&m.pendingDownloads))
 		return nil
 	}
 
-	go func() {
-		err := m.queue.Add(song)
+	requestedBy := m.consumeReservation(reservationKey)
+
+	m.downloadMu.Lock()
+	playNext := m.playNextUrls[song.URL]
+	delete(m.playNextUrls, song.URL)
+	allowDuplicate := m.duplicateUrls[song.URL]
+	delete(m.duplicateUrls, song.URL)
+	notifier := m.notifiers[song.URL]
+	delete(m.notifiers, song.URL)
+	delete(m.requestLimits, song.URL)
+	delete(m.downloadAttempts, song.URL)
+	delete(m.downloadRequests, song.URL)
+	playlist := m.playlists[reservationKey]
+	m.downloadMu.Unlock()
+
+	if !allowDuplicate {
+		if err := m.findDuplicate(song.URL, song.FilePath); err != nil {
+			logger.Info.Printf("Not queueing duplicate: %v", err)
+			if playlist != nil {
+				m.downloadMu.Lock()
+				playlist.duplicates++
+				m.downloadMu.Unlock()
+			} else if notifier != nil {
+				notifier.Failed(err)
+			}
+			return nil
+		}
+	}
+
+	if playlist != nil {
+		m.downloadMu.Lock()
+		playlist.added++
+		m.downloadMu.Unlock()
+	}
+
+	add := func() {
+		var err error
+		if playNext {
+			err = m.queue.InsertNext(song, requestedBy)
+		} else {
+			err = m.queue.Add(song, requestedBy)
+		}
 		if err != nil {
 			logger.Error.Printf("Failed to add song to queue: %v", err)
+			if notifier != nil {
+				notifier.Failed(err)
+			}
 			return
 		}
+		m.queueChanged()
+
+		if notifier != nil {
+			notifier.Queued(song, m.queuedETA(playNext))
+		}
 
 		logger.Info.Printf("Song added to queue: %s by %s (pending: %d)", song.Title, song.Artist, atomic.LoadInt32(&m.pendingDownloads))
 
 		if atomic.LoadInt32(&m.clearing) == 0 {
 			m.handleQueueAddition()
 		}
-	}()
+	}
 
-	return nil
-}
+	if inOrder {
+		add()
+	} else {
+		go add()
+	}
 
-func (m *Manager) OnPlaylistItemComplete(playlistUrl string, song *state.Song) error {
-	return m.OnDownloadComplete(song)
+	return nil
 }
 
+// handleQueueAddition starts playback once songs are queued, if nothing is
+// playing yet: the radio gives way, or the next song starts. A song that is
+// playing or paused is never interrupted. Every path that queues songs,
+// downloads, uploads and restoring a cleared queue alike, ends up here.
 func (m *Manager) handleQueueAddition() {
 	if atomic.LoadInt32(&m.clearing) == 1 {
 		return
 	}
 
-	currentState := m.stateManager.GetBotState()
+	guild := m.session.State
+	guild.CancelIdle("song queued")
+
+	currentState := guild.GetBotState()
 
 	if currentState == state.StateDJ && !m.player.IsPlaying() && !m.player.IsPaused() {
 		m.startNextSong()
 	} else if currentState == state.StateRadio || currentState == state.StateIdle {
-		m.radioManager.Stop()
-		time.Sleep(200 * time.Millisecond)
-		m.stateManager.SetBotState(state.StateDJ)
+		m.session.Radio.Stop()
+		guild.SetBotState(state.StateDJ)
 		m.startNextSong()
 	}
 }
 
+// handover is what happens between picking a song under m.mu and starting
+// it. Stopping the radio or the song playing waits for ffmpeg to exit, so it
+// runs with m.mu released, as does the check of the new song's file.
+type handover struct {
+	stopRadio bool
+	stopSong  bool
+	offset    time.Duration
+	resumed   bool
+}
+
+// play hands over to song and starts it. m.mu is taken again to start it,
+// and song is only started if it is still the current song and nothing else
+// started playing meanwhile; whoever moved the queue on starts their own.
+func (m *Manager) play(vc *discordgo.VoiceConnection, song *state.Song, h handover) error {
+	if h.stopRadio {
+		m.session.Radio.Stop()
+	}
+	if h.stopSong {
+		m.player.StopWithoutEvent()
+	}
+	if err := m.player.checkFile(song); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.queue.GetCurrent() != song || m.player.IsPlaying() {
+		logger.Debug.Printf("Not starting %s, playback moved on meanwhile", song.Title)
+		return nil
+	}
+	return m.player.start(vc, song, h.offset, h.resumed)
+}
+
 func (m *Manager) Start(vc *discordgo.VoiceConnection) error {
+	song, err := m.currentToStart()
+	if song == nil || err != nil {
+		return err
+	}
+
+	// The radio has to be gone before the song starts, or both are heard.
+	return m.play(vc, song, handover{stopRadio: true})
+}
+
+// currentToStart returns the current song if nothing is playing, and
+// switches the guild to DJ mode to play it.
+func (m *Manager) currentToStart() (*state.Song, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if atomic.LoadInt32(&m.clearing) == 1 {
-		return fmt.Errorf("cannot start music while clearing queue")
+		return nil, fmt.Errorf("cannot start music while clearing queue")
 	}
 
 	if m.player.IsPlaying() {
-		return nil
+		return nil, nil
 	}
 
 	currentSong := m.queue.GetCurrent()
 	if currentSong == nil {
-		return fmt.Errorf("no songs in queue")
+		return nil, fmt.Errorf("no songs in queue")
 	}
 
-	m.stateManager.SetBotState(state.StateDJ)
-
-	return m.player.Play(vc, currentSong)
+	m.session.State.SetBotState(state.StateDJ)
+	return currentSong, nil
 }
 
 func (m *Manager) Stop() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if !m.player.IsPlaying() && !m.player.IsPaused() {
 		return
 	}
@@ -273,33 +748,161 @@ func (m *Manager) Stop() {
 	m.player.Stop()
 }
 
+// Suspend stops the current song without moving on in the queue and returns
+// how far into it playback was. ok is false if nothing was playing.
+func (m *Manager) Suspend() (position time.Duration, ok bool) {
+	m.mu.Lock()
+	if !m.player.IsPlaying() {
+		m.mu.Unlock()
+		return 0, false
+	}
+	position = m.player.Position()
+	m.mu.Unlock()
+
+	logger.Info.Printf("Suspending music at %s", position.Truncate(time.Second))
+	m.player.StopWithoutEvent()
+	return position, true
+}
+
+// ResumeAt plays the current song from position, e.g. after Suspend.
+func (m *Manager) ResumeAt(vc *discordgo.VoiceConnection, position time.Duration) error {
+	song, err := m.currentToStart()
+	if song == nil || err != nil {
+		return err
+	}
+
+	return m.play(vc, song, handover{stopRadio: true, offset: position, resumed: position > 0})
+}
+
+// ApplyFilter restarts the song playing at its current position, so a change
+// to the guild's filter is heard right away instead of from the next song.
+// Paused songs and live streams are left alone.
+func (m *Manager) ApplyFilter() error {
+	m.mu.Lock()
+	song := m.player.GetCurrentSong()
+	if song == nil || song.IsStream || !m.player.IsPlaying() || m.player.IsPaused() {
+		m.mu.Unlock()
+		return nil
+	}
+	if m.player.Filter() == m.player.songFilter(song) {
+		m.mu.Unlock()
+		return nil
+	}
+	position := m.player.Position()
+	m.mu.Unlock()
+
+	vc := m.getVoiceConnection()
+	if vc == nil {
+		return fmt.Errorf("no voice connection available")
+	}
+
+	if err := m.play(vc, song, handover{stopSong: true, offset: position, resumed: true}); err != nil {
+		return err
+	}
+
+	// The next song's prebuffer was made with the old filter.
+	m.queueChanged()
+	return nil
+}
+
+// ActiveFilter returns the filter the current song is playing with.
+func (m *Manager) ActiveFilter() Filter {
+	return m.player.Filter()
+}
+
+// RestartQueue restarts playback from the current song, dropping songs that
+// have already been played. The player is stopped and started exactly once.
+func (m *Manager) RestartQueue() (int, error) {
+	if atomic.LoadInt32(&m.clearing) == 1 {
+		return 0, fmt.Errorf("cannot restart while clearing queue")
+	}
+
+	vc := m.getVoiceConnection()
+	if vc == nil {
+		return 0, fmt.Errorf("no voice connection available")
+	}
+
+	count, song, err := m.restartToStart()
+	if err != nil {
+		return 0, err
+	}
+	m.queueChanged()
+
+	if err := m.play(vc, song, handover{stopRadio: true, stopSong: true}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// restartToStart restarts the queue and returns how many songs are left in
+// it and the song to play from.
+func (m *Manager) restartToStart() (int, *state.Song, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.queue.IsEmpty() {
+		return 0, nil, fmt.Errorf("no songs in queue")
+	}
+
+	count, err := m.queue.Restart()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	currentSong := m.queue.GetCurrent()
+	if currentSong == nil {
+		return 0, nil, fmt.Errorf("no songs in queue")
+	}
+
+	m.session.State.SetBotState(state.StateDJ)
+	return count, currentSong, nil
+}
+
+// SkipTo jumps to the n-th upcoming song and plays it right away, see
+// Queue.SkipTo. Nothing is stopped if n is out of range.
+func (m *Manager) SkipTo(n int, keepSkipped bool) (*state.Song, error) {
+	if atomic.LoadInt32(&m.clearing) == 1 {
+		return nil, fmt.Errorf("cannot skip while clearing queue")
+	}
+
+	vc := m.getVoiceConnection()
+	if vc == nil {
+		return nil, fmt.Errorf("no voice connection available")
+	}
+
+	m.mu.Lock()
+	skipped := m.player.GetCurrentSong()
+	song, err := m.queue.SkipTo(n, keepSkipped)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.recordHistory(skipped, true)
+	m.mu.Unlock()
+
+	m.queueChanged()
+
+	if err := m.play(vc, song, handover{stopSong: true}); err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
 func (m *Manager) startNextSong() {
 	if atomic.LoadInt32(&m.clearing) == 1 {
 		return
 	}
 
 	go func() {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-
-		if m.stateManager.IsShuttingDown() || atomic.LoadInt32(&m.clearing) == 1 {
-			return
-		}
-
-		currentSong := m.queue.GetCurrent()
+		currentSong, vc := m.songToStart(false)
 		if currentSong == nil {
-			logger.Info.Println("No songs available to play")
-			return
-		}
-
-		vc := m.getVoiceConnection()
-		if vc == nil {
-			logger.Error.Println("No voice connection available for playback")
 			return
 		}
 
-		err := m.player.Play(vc, currentSong)
-		if err != nil {
+		err := m.play(vc, currentSong, handover{})
+		if errors.Is(err, ErrFileMissing) {
+			m.recoverMissing(currentSong)
+		} else if err != nil {
 			logger.Error.Printf("Failed to start playing song: %v", err)
 		}
 	}()
@@ -311,36 +914,109 @@ func (m *Manager) playNext() {
 	}
 
 	go func() {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-
-		if m.stateManager.IsShuttingDown() || atomic.LoadInt32(&m.clearing) == 1 {
+		nextSong, vc := m.songToStart(true)
+		if nextSong == nil {
 			return
 		}
 
-		nextSong, err := m.queue.Advance()
-		if err != nil {
-			logger.Info.Println("No more songs in queue")
-			return
+		err := m.play(vc, nextSong, handover{})
+		if errors.Is(err, ErrFileMissing) {
+			m.recoverMissing(nextSong)
+		} else if err != nil {
+			logger.Error.Printf("Failed to play next song: %v", err)
 		}
+	}()
+}
 
-		vc := m.getVoiceConnection()
-		if vc == nil {
-			logger.Error.Println("No voice connection available for next song")
-			return
-		}
+// songToStart returns the current song, or with advance the one after it,
+// and the connection to play it on. The song is nil if there is nothing to
+// play.
+func (m *Manager) songToStart(advance bool) (*state.Song, *discordgo.VoiceConnection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		time.Sleep(500 * time.Millisecond)
+	if m.stateManager.IsShuttingDown() || atomic.LoadInt32(&m.clearing) == 1 {
+		return nil, nil
+	}
 
-		err = m.player.Play(vc, nextSong)
+	var song *state.Song
+	if advance {
+		next, err := m.queue.Advance()
 		if err != nil {
-			logger.Error.Printf("Failed to play next song: %v", err)
+			logger.Info.Println("No more songs in queue")
+			return nil, nil
 		}
-	}()
+		song = next
+	} else if song = m.queue.GetCurrent(); song == nil {
+		logger.Info.Println("No songs available to play")
+		return nil, nil
+	}
+
+	vc := m.getVoiceConnection()
+	if vc == nil {
+		logger.Error.Println("No voice connection available for playback")
+		return nil, nil
+	}
+	return song, vc
 }
 
-func (m *Manager) onSongEnd() {
-	if m.stateManager.IsShuttingDown() || atomic.LoadInt32(&m.clearing) == 1 {
+func (m *Manager) onSongStart(song *state.Song) {
+	m.forgetRecovery(song.URL)
+	m.retriedSong.Store(nil)
+
+	if song.ID == 0 {
+		return
+	}
+	if err := m.dbManager.MarkSongPlayed(song.ID); err != nil {
+		logger.Error.Printf("Failed to record play for song %d: %v", song.ID, err)
+	}
+}
+
+// onHalfway prebuffers the next song once the current one is half played.
+func (m *Manager) onHalfway(song *state.Song) {
+	if next := m.queue.GetNext(); next != nil {
+		m.player.Prebuffer(next)
+	}
+}
+
+// onMeasured keeps the length a song turned out to have, so the queue, ETAs
+// and later plays of it count with a real length.
+func (m *Manager) onMeasured(song *state.Song, length time.Duration) {
+	seconds := int(length.Round(time.Second) / time.Second)
+	if seconds <= 0 {
+		return
+	}
+
+	updated, err := m.queue.SetDuration(song, seconds)
+	if err != nil {
+		logger.Error.Printf("Failed to store the measured length of %s: %v", song.Title, err)
+		return
+	}
+	logger.Info.Printf("Measured %s at %ds, updating %d queued songs", song.Title, seconds, updated)
+}
+
+// queueChanged keeps the prebuffer in step with the song after the current
+// one when the queue is edited.
+func (m *Manager) queueChanged() {
+	song := m.player.GetCurrentSong()
+	next := m.queue.GetNext()
+	if song == nil || next == nil || song.Duration <= 0 ||
+		m.player.Position() < halfwayPoint(song) {
+		m.player.InvalidatePrebuffer()
+		return
+	}
+
+	m.player.Prebuffer(next)
+}
+
+func (m *Manager) onSongEnd(song *state.Song, stopped bool) {
+	if m.stateManager.IsShuttingDown() {
+		return
+	}
+
+	m.recordHistory(song, stopped)
+
+	if atomic.LoadInt32(&m.clearing) == 1 {
 		return
 	}
 
@@ -349,7 +1025,8 @@ func (m *Manager) onSongEnd() {
 		return
 	}
 
-	if m.stateManager.IsManualOperationActive() {
+	guild := m.session.State
+	if guild.IsManualOperationActive() {
 		logger.Debug.Println("Manual operation active, skipping onSongEnd")
 		return
 	}
@@ -357,43 +1034,86 @@ func (m *Manager) onSongEnd() {
 	if m.queue.HasNext() {
 		m.playNext()
 	} else {
-		logger.Info.Println("Queue finished, no more songs")
-
-		go func() {
-			time.Sleep(1 * time.Second)
-
-			if atomic.LoadInt32(&m.clearing) == 1 {
-				return
-			}
+		m.queueFinished(guild)
+	}
+}
 
-			if !m.AreAutoHandlersEnabled() {
-				return
-			}
+// queueFinished hands the guild back to the radio after the idle delay.
+func (m *Manager) queueFinished(guild *state.Guild) {
+	delay := m.stateManager.GetConfig().IdleDelay
+	if guild.GetAlwaysOnChannel() != "" {
+		// 24/7 mode goes straight back to the radio.
+		delay = 0
+	}
+	logger.Info.Printf("Queue finished, going idle in %v", delay)
+	guild.ScheduleIdle(delay, func() {
+		m.enterIdle(guild)
+	})
+}
 
-			if m.stateManager.IsManualOperationActive() {
-				return
-			}
+// enterIdle hands the guild back to the radio once the idle delay after the
+// queue ended is up, unless music has picked up again in the meantime.
+func (m *Manager) enterIdle(guild *state.Guild) {
+	switch {
+	case m.stateManager.IsShuttingDown():
+		logger.Debug.Println("Not going idle: shutting down")
+		return
+	case atomic.LoadInt32(&m.clearing) == 1:
+		logger.Debug.Println("Not going idle: queue is being cleared")
+		return
+	case !m.AreAutoHandlersEnabled():
+		logger.Debug.Println("Not going idle: auto handlers disabled")
+		return
+	case guild.IsManualOperationActive():
+		logger.Debug.Println("Not going idle: manual operation active")
+		return
+	case m.player.IsPlaying() || m.player.IsPaused():
+		logger.Debug.Println("Not going idle: player is not stopped")
+		return
+	case m.HasActiveDownloads():
+		logger.Debug.Println("Not going idle: downloads are pending")
+		return
+	}
 
-			if m.stateManager.IsInIdleChannel() {
-				m.stateManager.SetBotState(state.StateIdle)
-			} else {
-				m.stateManager.SetBotState(state.StateRadio)
-			}
+	logger.Debug.Printf("Going idle in guild %s", guild.ID())
 
-			time.Sleep(500 * time.Millisecond)
+	if guild.IsInIdleChannel() {
+		guild.SetBotState(state.StateIdle)
+	} else {
+		guild.SetBotState(state.StateRadio)
+	}
 
-			vc := m.getVoiceConnection()
-			if vc != nil && !m.radioManager.IsPlaying() {
-				m.radioManager.Start(vc)
-			}
-		}()
+	radioManager := m.session.Radio
+	vc := m.getVoiceConnection()
+	if vc != nil && !radioManager.IsPlaying() {
+		radioManager.Start(vc)
 	}
 }
 
+// cancelIdle stops the radio from taking over after the queue ended, because
+// of new activity described by reason.
+func (m *Manager) cancelIdle(reason string) {
+	m.session.State.CancelIdle(reason)
+}
+
 func (m *Manager) GetQueue() []state.QueueItem {
 	return m.queue.GetItems()
 }
 
+// QueuedSongIDs returns the IDs of every song in the in-memory queue,
+// including the one playing, and of the songs /previous can go back to.
+func (m *Manager) QueuedSongIDs() []int64 {
+	items := m.queue.GetItems()
+	ids := make([]int64, 0, len(items)+1)
+	for _, item := range items {
+		ids = append(ids, item.SongID)
+	}
+	if song := m.player.GetCurrentSong(); song != nil {
+		ids = append(ids, song.ID)
+	}
+	return append(ids, m.historySongIDs()...)
+}
+
 func (m *Manager) GetUpcoming(limit int) []state.Song {
 	return m.queue.GetUpcoming(limit)
 }
@@ -420,7 +1140,37 @@ func (m *Manager) HasActiveDownloads() bool {
 	return activeRequests || pendingCount > 0
 }
 
+// IsDownloading reports whether url has been requested and isn't done yet.
+func (m *Manager) IsDownloading(url string) bool {
+	m.downloadMu.RLock()
+	defer m.downloadMu.RUnlock()
+
+	_, waiting := m.requestLimits[url]
+	return waiting || m.activeDownloads[url]
+}
+
+// ResetPendingDownloads forgets every download in flight, e.g. after the
+// downloader reconnected, and tells whoever was waiting on one that it failed.
 func (m *Manager) ResetPendingDownloads() {
+	m.cancelPlaylists(ErrDownloadLost)
+	m.clearReservations()
+
+	m.downloadMu.Lock()
+	notifiers := m.notifiers
+	m.notifiers = make(map[string]RequestNotifier)
+	m.duplicateUrls = make(map[string]bool)
+	m.prefetchUrls = make(map[string]bool)
+	m.repairUrls = make(map[string]bool)
+	m.playlists = make(map[string]*playlistProgress)
+	m.requestLimits = make(map[string]config.DownloadLimits)
+	m.downloadAttempts = make(map[string]int)
+	m.downloadRequests = make(map[string]string)
+	m.downloadMu.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier.Failed(ErrDownloadLost)
+	}
+
 	old := atomic.SwapInt32(&m.pendingDownloads, 0)
 	if old > 0 {
 		logger.Info.Printf("Reset pending downloads counter from %d to 0", old)
@@ -432,6 +1182,8 @@ func (m *Manager) GetPendingDownloads() int {
 }
 
 func (m *Manager) ClearQueue() error {
+	m.CancelDownloads()
+
 	if m.HasActiveDownloads() {
 		return fmt.Errorf("cannot clear queue while downloads are in progress")
 	}
@@ -443,12 +1195,16 @@ func (m *Manager) ClearQueue() error {
 
 	time.Sleep(1 * time.Second)
 
+	m.stashCleared()
+
 	err := m.queue.Clear()
 	if err != nil {
 		return err
 	}
+	m.queueChanged()
 
 	atomic.StoreInt32(&m.pendingDownloads, 0)
+	m.clearReservations()
 	logger.Info.Println("Cleared pending downloads counter")
 
 	time.Sleep(500 * time.Millisecond)
@@ -456,24 +1212,99 @@ func (m *Manager) ClearQueue() error {
 	return nil
 }
 
-func (m *Manager) RemoveFromQueue(queueID int64) error {
-	return m.queue.Remove(queueID)
+func (m *Manager) RemoveFromQueue(index int) error {
+	if err := m.queue.Remove(index); err != nil {
+		return err
+	}
+	m.queueChanged()
+	return nil
+}
+
+// RemoveUpcoming drops a range of upcoming songs, see Queue.RemoveUpcoming.
+func (m *Manager) RemoveUpcoming(from, to int, requestedBy, owner string) ([]state.QueueItem, error) {
+	removed, err := m.queue.RemoveUpcoming(from, to, requestedBy, owner)
+	if err != nil || len(removed) == 0 {
+		return nil, err
+	}
+	m.queueChanged()
+	return removed, nil
+}
+
+// UpcomingCountBy returns how many upcoming songs userID requested.
+func (m *Manager) UpcomingCountBy(userID string) int {
+	return m.queue.UpcomingCountBy(userID)
+}
+
+// RemoveRequestedBy drops the upcoming songs userID requested and returns how
+// many there were.
+func (m *Manager) RemoveRequestedBy(userID string) int {
+	removed := m.queue.RemoveRequestedBy(userID)
+	if removed > 0 {
+		m.queueChanged()
+	}
+	return removed
+}
+
+// GetUpcomingItems returns the queue items after the current song.
+func (m *Manager) GetUpcomingItems() []state.QueueItem {
+	return m.queue.GetUpcomingItems()
+}
+
+// TrimUpcoming sets the start and end offsets of the n-th upcoming song.
+func (m *Manager) TrimUpcoming(n int, songID int64, start, end int) error {
+	if err := m.queue.Trim(n, songID, start, end); err != nil {
+		return err
+	}
+	m.queueChanged()
+	return nil
 }
 
 func (m *Manager) getVoiceConnection() *discordgo.VoiceConnection {
-	if m.vcGetter != nil {
-		return m.vcGetter()
+	if m.session.VoiceConnection != nil {
+		return m.session.VoiceConnection()
 	}
 	return nil
 }
 
-func (m *Manager) SetVoiceConnectionGetter(getter func() *discordgo.VoiceConnection) {
-	m.vcGetter = getter
+// SetShutdownNotice sets the function that tells the guild that the bot is
+// shutting down, with the number of songs kept in the
+// queue. It gets the shutdown context and must return when it is done.
+func (m *Manager) SetShutdownNotice(notice func(ctx context.Context, guildID string, queued int)) {
+	m.shutdownNotice = notice
 }
 
+// SetSkipNotice sets the function told when a song is skipped because its
+// file is gone and could not be downloaded again, or because it broke off
+// while playing, even when played again.
+func (m *Manager) SetSkipNotice(notice func(guildID string, song *state.Song, reason error)) {
+	m.skipNotice = notice
+}
+
+// Shutdown stops playback, ending it with silence rather than mid-frame, and
+// flushes the queue to the database. It is registered to shut down before the
+// voice connections and the downloader connection.
 func (m *Manager) Shutdown(ctx context.Context) error {
-	logger.Info.Println("Shutting down music manager...")
-	return m.player.Shutdown(ctx)
+	logger.Info.Printf("Shutting down music of guild %s...", m.GuildID())
+
+	if m.player.IsPlaying() && m.shutdownNotice != nil {
+		m.shutdownNotice(ctx, m.GuildID(), len(m.queue.GetItems()))
+	}
+
+	m.cancelPlaylists(ErrPlaylistCancelled)
+
+	err := m.player.Shutdown(ctx)
+
+	if flushErr := m.queue.Close(ctx); flushErr != nil {
+		logger.Error.Printf("Failed to flush queue on shutdown: %v", flushErr)
+	}
+
+	if pending := atomic.LoadInt32(&m.pendingDownloads); pending > 0 {
+		logger.Info.Printf("Shutting down with %d downloads still pending, they are not queued", pending)
+	}
+
+	m.stopEvents()
+
+	return err
 }
 
 func (m *Manager) Name() string {
@@ -0,0 +1,374 @@
+package music
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/radio"
+	"musicbot/internal/state"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestDatabase opens an in-memory database that is closed with the test.
+func newTestDatabase(t *testing.T) *config.DatabaseManager {
+	t.Helper()
+	dm, err := config.NewMemoryDatabaseManager(strings.ReplaceAll(t.Name(), "/", "_"))
+	if err != nil {
+		t.Fatalf("NewMemoryDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	return dm
+}
+
+// newTestManager creates the music manager of guildID without a voice
+// connection or downloader. It is shut down with the test.
+func newTestManager(t *testing.T, stateManager *state.Manager, dm *config.DatabaseManager, guildID string) *Manager {
+	t.Helper()
+	guildState := stateManager.Guild(guildID)
+	m := NewManager(stateManager, dm, nil, &Session{
+		State: guildState,
+		Radio: radio.NewManager(guildState, radio.NewStreamManager(nil)),
+	})
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+	return m
+}
+
+func testSong(guildID string, n int) *state.Song {
+	url := "https://example.com/" + guildID + "/" + string(rune('a'+n))
+	return &state.Song{Title: url, URL: url, Platform: "test", FilePath: url + ".mp3", Duration: 60}
+}
+
+// requestNotifier records how a request ended.
+type requestNotifier struct {
+	queued chan *state.Song
+	failed chan error
+}
+
+func newRequestNotifier() *requestNotifier {
+	return &requestNotifier{queued: make(chan *state.Song, 1), failed: make(chan error, 1)}
+}
+
+func (n *requestNotifier) Queued(song *state.Song, _ ETA) { n.queued <- song }
+func (n *requestNotifier) Failed(err error)               { n.failed <- err }
+
+// awaitDownload makes m wait on url as if it had been requested.
+func awaitDownload(m *Manager, url string, notifier RequestNotifier) {
+	m.downloadMu.Lock()
+	m.requestLimits[url] = config.DownloadLimits{MaxAttempts: 1}
+	m.notifiers[url] = notifier
+	m.downloadMu.Unlock()
+	atomic.AddInt32(&m.pendingDownloads, 1)
+}
+
+func TestGuildsKeepTheirOwnQueue(t *testing.T) {
+	dm := newTestDatabase(t)
+	stateManager := state.NewManager(state.Config{})
+	a := newTestManager(t, stateManager, dm, "guildA")
+	b := newTestManager(t, stateManager, dm, "guildB")
+
+	for n := range 3 {
+		if err := a.queue.Add(testSong("guildA", n), "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.queue.Add(testSong("guildB", 0), "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(a.GetQueue()); got != 3 {
+		t.Errorf("guildA has %d songs queued, want 3", got)
+	}
+	if got := len(b.GetQueue()); got != 1 {
+		t.Errorf("guildB has %d songs queued, want 1", got)
+	}
+	if a.GetUpcomingItems()[0].Song.URL == b.GetQueue()[0].Song.URL {
+		t.Error("guildB's song showed up in guildA's queue")
+	}
+
+	// A restart picks each guild's queue back up from the database.
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	a = newTestManager(t, stateManager, dm, "guildA")
+	b = newTestManager(t, stateManager, dm, "guildB")
+	if got := len(a.GetQueue()); got != 3 {
+		t.Errorf("guildA reloaded %d songs, want 3", got)
+	}
+	if got := len(b.GetQueue()); got != 1 {
+		t.Errorf("guildB reloaded %d songs, want 1", got)
+	}
+
+	// Removing the bot from guildA empties its queue, not guildB's.
+	a.Release()
+	if items, _ := dm.GetQueue("guildA"); len(items) != 0 {
+		t.Errorf("guildA's saved queue has %d songs after Release, want 0", len(items))
+	}
+	if items, _ := dm.GetQueue("guildB"); len(items) != 1 {
+		t.Errorf("guildB's saved queue has %d songs after guildA's Release, want 1", len(items))
+	}
+}
+
+func TestDownloadsStayWithTheGuildThatAskedForThem(t *testing.T) {
+	dm := newTestDatabase(t)
+	stateManager := state.NewManager(state.Config{})
+	a := newTestManager(t, stateManager, dm, "guildA")
+	b := newTestManager(t, stateManager, dm, "guildB")
+
+	failing := testSong("guildA", 0)
+	failedNotifier := newRequestNotifier()
+	awaitDownload(a, failing.URL, failedNotifier)
+
+	if !a.Awaits(failing.URL) || b.Awaits(failing.URL) {
+		t.Fatalf("Awaits = %v for guildA, %v for guildB; want only guildA", a.Awaits(failing.URL), b.Awaits(failing.URL))
+	}
+
+	a.OnDownloadFailed(failing.URL, "HTTP Error 404: Not Found")
+	select {
+	case <-failedNotifier.failed:
+	case <-time.After(time.Second):
+		t.Fatal("guildA's requester wasn't told the download failed")
+	}
+	if a.Awaits(failing.URL) {
+		t.Error("guildA still waits on a failed download")
+	}
+	if pending := a.GetPendingDownloads(); pending != 0 {
+		t.Errorf("guildA has %d downloads pending after the failure, want 0", pending)
+	}
+
+	song := testSong("guildA", 1)
+	notifier := newRequestNotifier()
+	awaitDownload(a, song.URL, notifier)
+	if err := a.OnDownloadComplete(song); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case queued := <-notifier.queued:
+		if queued.URL != song.URL {
+			t.Errorf("queued %s, want %s", queued.URL, song.URL)
+		}
+	case err := <-notifier.failed:
+		t.Fatalf("download failed to queue: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("guildA's requester wasn't told the song was queued")
+	}
+
+	if got := len(a.GetQueue()); got != 1 {
+		t.Errorf("guildA has %d songs queued, want 1", got)
+	}
+	if got := len(b.GetQueue()); got != 0 {
+		t.Errorf("guildB has %d songs queued, want 0", got)
+	}
+	if pending := b.GetPendingDownloads(); pending != 0 {
+		t.Errorf("guildB has %d downloads pending, want 0", pending)
+	}
+}
+
+// fakePlayer stands in for the player, counting how often songs are started
+// and stopped instead of running ffmpeg.
+type fakePlayer struct {
+	*Player
+	mu      sync.Mutex
+	playing *state.Song
+	paused  bool
+	started []*state.Song
+	stops   int
+}
+
+func newFakePlayer(stateManager *state.Manager) *fakePlayer {
+	return &fakePlayer{Player: NewPlayer(stateManager)}
+}
+
+func (p *fakePlayer) IsPlaying() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing != nil
+}
+
+func (p *fakePlayer) IsPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+func (p *fakePlayer) GetCurrentSong() *state.Song {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+func (p *fakePlayer) Stop() { p.StopWithoutEvent() }
+
+func (p *fakePlayer) StopWithoutEvent() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stops++
+	p.playing = nil
+}
+
+func (p *fakePlayer) checkFile(*state.Song) error { return nil }
+
+func (p *fakePlayer) start(_ *discordgo.VoiceConnection, song *state.Song, _ time.Duration, _ bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = append(p.started, song)
+	p.playing = song
+	return nil
+}
+
+func TestRestartQueueStopsAndStartsOnce(t *testing.T) {
+	stateManager := state.NewManager(state.Config{})
+	m := newTestManager(t, stateManager, newTestDatabase(t), "guild")
+	m.session.VoiceConnection = func() *discordgo.VoiceConnection { return &discordgo.VoiceConnection{} }
+	player := newFakePlayer(stateManager)
+	m.player = player
+
+	for n := range 3 {
+		if err := m.queue.Add(testSong("guild", n), "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current, err := m.queue.Advance()
+	if err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	player.playing = current
+
+	left, err := m.RestartQueue()
+	if err != nil {
+		t.Fatalf("RestartQueue: %v", err)
+	}
+	// The song played before the current one is dropped.
+	if left != 2 {
+		t.Errorf("RestartQueue = %d, want the 2 songs left", left)
+	}
+	if player.stops != 1 {
+		t.Errorf("player stopped %d times, want once", player.stops)
+	}
+	if len(player.started) != 1 || player.started[0] != current {
+		t.Errorf("player started %v, want only %s", player.started, current.Title)
+	}
+	if got := len(m.GetQueue()); got != 2 {
+		t.Errorf("queue holds %d songs after the restart, want 2", got)
+	}
+}
+
+func TestQueueingNeverInterruptsPlayback(t *testing.T) {
+	type player int
+	const (
+		stopped player = iota
+		playing
+		paused
+	)
+	states := []struct {
+		name     string
+		botState state.BotState
+		player   player
+		starts   bool
+	}{
+		{"idle", state.StateIdle, stopped, true},
+		{"radio", state.StateRadio, stopped, true},
+		{"DJ with nothing playing", state.StateDJ, stopped, true},
+		{"DJ playing", state.StateDJ, playing, false},
+		{"DJ paused", state.StateDJ, paused, false},
+		{"transitioning", state.StateTransitioning, stopped, false},
+	}
+	positions := []struct {
+		name     string
+		playNext bool
+		upcoming string
+	}{
+		{"at the end", false, "b new"},
+		{"next", true, "new b"},
+	}
+
+	for _, tt := range states {
+		for _, position := range positions {
+			t.Run(tt.name+", queued "+position.name, func(t *testing.T) {
+				stateManager := state.NewManager(state.Config{})
+				m := newTestManager(t, stateManager, newTestDatabase(t), "guild")
+				m.session.VoiceConnection = func() *discordgo.VoiceConnection { return &discordgo.VoiceConnection{} }
+				player := newFakePlayer(stateManager)
+				m.player = player
+
+				current, upcoming := testSong("guild", 0), testSong("guild", 1)
+				current.Title, upcoming.Title = "a", "b"
+				for _, song := range []*state.Song{current, upcoming} {
+					if err := m.queue.Add(song, "user"); err != nil {
+						t.Fatal(err)
+					}
+				}
+				m.session.State.SetBotState(tt.botState)
+				if tt.player != stopped {
+					player.playing = current
+					player.paused = tt.player == paused
+				}
+
+				song := testSong("guild", 2)
+				song.Title = "new"
+				notifier := newRequestNotifier()
+				awaitDownload(m, song.URL, notifier)
+				if position.playNext {
+					m.downloadMu.Lock()
+					m.playNextUrls[song.URL] = true
+					m.downloadMu.Unlock()
+				}
+				if err := m.OnDownloadComplete(song); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case <-notifier.queued:
+				case err := <-notifier.failed:
+					t.Fatalf("queueing failed: %v", err)
+				case <-time.After(time.Second):
+					t.Fatal("the song wasn't queued")
+				}
+
+				if got := titles(songsOf(m.GetUpcomingItems())); got != position.upcoming {
+					t.Errorf("upcoming = %s, want %s", got, position.upcoming)
+				}
+
+				// Starting happens in the background; give it the time it
+				// would take either way.
+				deadline := time.Now().Add(time.Second)
+				for tt.starts && !player.IsPlaying() && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+				}
+				if !tt.starts {
+					time.Sleep(50 * time.Millisecond)
+				}
+
+				player.mu.Lock()
+				defer player.mu.Unlock()
+				if player.stops != 0 {
+					t.Errorf("the player was stopped %d times, want never", player.stops)
+				}
+				if tt.starts {
+					if len(player.started) != 1 || player.started[0] != current {
+						t.Errorf("started %v, want the current song a", player.started)
+					}
+					if got := m.session.State.GetBotState(); got != state.StateDJ {
+						t.Errorf("bot state = %v, want DJ", got)
+					}
+				} else if len(player.started) != 0 {
+					t.Errorf("started %v while %s, want nothing", player.started, tt.name)
+				}
+			})
+		}
+	}
+}
+
+func songsOf(items []state.QueueItem) []state.Song {
+	songs := make([]state.Song, len(items))
+	for k, item := range items {
+		songs[k] = *item.Song
+	}
+	return songs
+}
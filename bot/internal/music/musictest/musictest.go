@@ -0,0 +1,101 @@
+// Package musictest plays songs in tests without ffmpeg or a voice server.
+package musictest
+
+import (
+	"musicbot/internal/config"
+	"musicbot/internal/state"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// FakeFFmpeg puts an ffmpeg on PATH for the rest of the test that decodes
+// every file to endless silence, so a song plays until it is stopped.
+func FakeFFmpeg(t testing.TB) {
+	t.Helper()
+	TrackFFmpeg(t)
+}
+
+// TrackFFmpeg is FakeFFmpeg, and returns a function that reports how many of
+// the ffmpeg processes started so far are still running.
+func TrackFFmpeg(t testing.TB) (running func() int) {
+	t.Helper()
+	dir := t.TempDir()
+	pids := filepath.Join(dir, "pids")
+	script := "#!/bin/sh\necho $$ >> '" + pids + "'\nexec cat /dev/zero\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatalf("writing fake ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return func() int {
+		data, err := os.ReadFile(pids)
+		if err != nil {
+			return 0
+		}
+		count := 0
+		for _, pid := range strings.Fields(string(data)) {
+			// A process that was waited for is gone from /proc.
+			if _, err := os.Stat(filepath.Join("/proc", pid)); err == nil {
+				count++
+			}
+		}
+		return count
+	}
+}
+
+// VoiceConnection returns a voice connection whose frames are thrown away.
+// It is drained until the test's cleanup, so create it before anything that
+// plays into it and is stopped in a cleanup of its own.
+func VoiceConnection(t testing.TB) *discordgo.VoiceConnection {
+	vc := &discordgo.VoiceConnection{OpusSend: make(chan []byte, 2)}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-vc.OpusSend:
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+	return vc
+}
+
+// Song returns a song of a minute with an empty file of its own.
+func Song(t testing.TB, title string) *state.Song {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), title+".mp3")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("writing song file: %v", err)
+	}
+	return &state.Song{
+		Title:    title,
+		URL:      "https://example.com/" + title,
+		Platform: "test",
+		FilePath: path,
+		Duration: 60,
+	}
+}
+
+// SaveQueue stores songs as the queue guildID left behind, so its music
+// manager starts out with them.
+func SaveQueue(t testing.TB, dbManager *config.DatabaseManager, guildID string, songs ...*state.Song) {
+	t.Helper()
+	entries := make([]config.QueueEntry, 0, len(songs))
+	for _, song := range songs {
+		songID, err := dbManager.AddSong(song)
+		if err != nil {
+			t.Fatalf("adding %s: %v", song.Title, err)
+		}
+		entries = append(entries, config.QueueEntry{SongID: songID, RequestedBy: "user", RequestedAt: time.Now()})
+	}
+	if err := dbManager.SaveQueue(guildID, entries, 0); err != nil {
+		t.Fatalf("saving the queue of %s: %v", guildID, err)
+	}
+}
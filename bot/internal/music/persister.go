@@ -0,0 +1,102 @@
+package music
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"sync"
+	"time"
+)
+
+const persistInterval = 500 * time.Millisecond
+
+// queuePersister coalesces queue mutations into at most one database write per
+// interval. Every flush writes the latest snapshot, so bursts of adds (e.g. a
+// playlist) cost a single rewrite and a stale write can never land last.
+type queuePersister struct {
+	dbManager *config.DatabaseManager
+	snapshot  func() (string, []config.QueueEntry, int)
+	interval  time.Duration
+	dirty     chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	flushMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+func newQueuePersister(dbManager *config.DatabaseManager, snapshot func() (string, []config.QueueEntry, int)) *queuePersister {
+	p := &queuePersister{
+		dbManager: dbManager,
+		snapshot:  snapshot,
+		interval:  persistInterval,
+		dirty:     make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go p.run()
+	return p
+}
+
+func (p *queuePersister) MarkDirty() {
+	select {
+	case p.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (p *queuePersister) run() {
+	defer close(p.done)
+
+	var lastFlush time.Time
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.dirty:
+		}
+
+		if wait := p.interval - time.Since(lastFlush); wait > 0 {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		p.Flush(context.Background())
+		lastFlush = time.Now()
+	}
+}
+
+// Flush writes the current queue snapshot immediately. A queue that doesn't
+// belong to a guild yet isn't written.
+func (p *queuePersister) Flush(ctx context.Context) error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	guildID, entries, position := p.snapshot()
+	if guildID == "" {
+		return nil
+	}
+
+	err := p.dbManager.SaveQueueCtx(ctx, guildID, entries, position)
+	if err != nil {
+		logger.Error.Printf("Failed to persist queue of guild %s: %v", guildID, err)
+		return err
+	}
+
+	logger.Debug.Printf("Persisted queue of guild %s with %d songs, position: %d", guildID, len(entries), position)
+	return nil
+}
+
+// Close stops the background worker and performs a final synchronous flush.
+func (p *queuePersister) Close(ctx context.Context) error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		err = p.Flush(ctx)
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+package music
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/state"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingQueue is a queue whose position counts the changes made to it,
+// with a persister that records how often it took a snapshot.
+type countingQueue struct {
+	mu        sync.Mutex
+	entries   []config.QueueEntry
+	position  int
+	snapshots atomic.Int32
+}
+
+func newCountingQueue(t *testing.T, dm *config.DatabaseManager) *countingQueue {
+	t.Helper()
+	songID, err := dm.AddSong(&state.Song{Title: "song", URL: "https://example.com/song", Platform: "test", FilePath: "song.mp3"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	return &countingQueue{entries: []config.QueueEntry{{SongID: songID}}}
+}
+
+func (q *countingQueue) change() {
+	q.mu.Lock()
+	q.position++
+	q.mu.Unlock()
+}
+
+func (q *countingQueue) snapshot() (string, []config.QueueEntry, int) {
+	q.snapshots.Add(1)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return "guild", q.entries, q.position
+}
+
+func savedPosition(t *testing.T, dm *config.DatabaseManager) int {
+	t.Helper()
+	position, err := dm.GetCurrentQueuePosition("guild")
+	if err != nil {
+		t.Fatalf("GetCurrentQueuePosition: %v", err)
+	}
+	return position
+}
+
+func TestPersisterSavesLastChange(t *testing.T) {
+	const writers, changes = 8, 250
+
+	dm := newTestDatabase(t)
+	q := newCountingQueue(t, dm)
+	p := newQueuePersister(dm, q.snapshot)
+	t.Cleanup(func() { p.Close(context.Background()) })
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := 0; c < changes; c++ {
+				q.change()
+				p.MarkDirty()
+			}
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * persistInterval)
+	for savedPosition(t, dm) != writers*changes {
+		if time.Now().After(deadline) {
+			t.Fatalf("saved position = %d, want the last change's %d", savedPosition(t, dm), writers*changes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// One write for the first change, then at most one per interval.
+	if max := int32(time.Since(start)/persistInterval) + 2; q.snapshots.Load() > max {
+		t.Errorf("wrote the queue %d times, want at most %d", q.snapshots.Load(), max)
+	}
+}
+
+func TestPersisterCloseSavesPendingChange(t *testing.T) {
+	dm := newTestDatabase(t)
+	q := newCountingQueue(t, dm)
+	p := newQueuePersister(dm, q.snapshot)
+
+	// The first write goes out at once; the second waits for the interval.
+	q.change()
+	p.MarkDirty()
+	deadline := time.Now().Add(time.Second)
+	for savedPosition(t, dm) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the first change was never saved")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	q.change()
+	p.MarkDirty()
+
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := savedPosition(t, dm); got != 2 {
+		t.Errorf("saved position after Close = %d, want 2", got)
+	}
+}
@@ -2,28 +2,35 @@ package music
 
 import (
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"musicbot/internal/audio"
+	"musicbot/internal/config"
 	"musicbot/internal/logger"
 	"musicbot/internal/state"
 	"os"
 	"os/exec"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"layeh.com/gopus"
 )
 
-const (
-	frameSize = 960
-	channels  = 2
-	frameRate = 48000
-)
+// ErrNotPlaying is returned by Player.Overlay when no song is playing to mix
+// a clip into.
+var ErrNotPlaying = errors.New("no song is playing")
+
+// ErrRadioPlaying is returned when a song is started while the radio still
+// holds the voice connection.
+var ErrRadioPlaying = errors.New("the radio is still playing")
 
 type Player struct {
 	stateManager *state.Manager
+	guild        atomic.Pointer[state.Guild]
 	stopChan     chan bool
 	pauseChan    chan bool
 	resumeChan   chan bool
@@ -31,10 +38,19 @@ type Player struct {
 	isPlaying    bool
 	isPaused     bool
 	currentSong  *state.Song
-	onSongEnd    func()
+	offset       time.Duration
+	filter       Filter
+	sender       atomic.Pointer[audio.Sender]
+	overlay      audio.Overlay
+	encoder      audio.EncoderSlot
+	next         *prebuffer
+	suppressEnd  bool
+	stopped      bool
 	ctx          context.Context
 	cancel       context.CancelFunc
 	mu           sync.RWMutex
+	subscribers  []*subscription
+	eventsMu     sync.Mutex
 }
 
 func NewPlayer(stateManager *state.Manager) *Player {
@@ -47,13 +63,60 @@ func NewPlayer(stateManager *state.Manager) *Player {
 	}
 }
 
-func (p *Player) SetOnSongEnd(callback func()) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.onSongEnd = callback
+// SetGuild sets the guild whose state the player reports playback to.
+func (p *Player) SetGuild(guild *state.Guild) {
+	p.guild.Store(guild)
+}
+
+func (p *Player) setPlaying(playing bool) {
+	if guild := p.guild.Load(); guild != nil {
+		guild.SetPlaying(playing)
+	}
+}
+
+func (p *Player) setPaused(paused bool) {
+	if guild := p.guild.Load(); guild != nil {
+		guild.SetMusicPaused(paused)
+	}
 }
 
 func (p *Player) Play(vc *discordgo.VoiceConnection, song *state.Song) error {
+	return p.PlayFrom(vc, song, 0)
+}
+
+// PlayFrom starts song at offset. A song resumed part way through is not
+// reported as a new play. Playback never starts before the song's trimmed
+// start.
+func (p *Player) PlayFrom(vc *discordgo.VoiceConnection, song *state.Song, offset time.Duration) error {
+	if err := p.checkFile(song); err != nil {
+		return err
+	}
+	return p.start(vc, song, offset, offset > 0)
+}
+
+// Restart starts song again at offset after it was stopped to change how it
+// is decoded, such as its filter. It is never reported as a new play.
+func (p *Player) Restart(vc *discordgo.VoiceConnection, song *state.Song, offset time.Duration) error {
+	if err := p.checkFile(song); err != nil {
+		return err
+	}
+	return p.start(vc, song, offset, true)
+}
+
+// checkFile returns ErrFileMissing if song's file is gone. It runs before
+// any lock is taken, since the download directory may be on a slow disk.
+func (p *Player) checkFile(song *state.Song) error {
+	if song.IsStream {
+		return nil
+	}
+	if _, err := os.Stat(p.filePath(song)); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrFileMissing, song.FilePath)
+	}
+	return nil
+}
+
+// start starts song, whose file the caller has checked.
+func (p *Player) start(vc *discordgo.VoiceConnection, song *state.Song, offset time.Duration, resumed bool) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -61,8 +124,18 @@ func (p *Player) Play(vc *discordgo.VoiceConnection, song *state.Song) error {
 		return fmt.Errorf("already playing a song")
 	}
 
-	if _, err := os.Stat(song.FilePath); os.IsNotExist(err) {
-		return fmt.Errorf("song file not found: %s", song.FilePath)
+	guild := p.guild.Load()
+	if guild != nil && !guild.AcquireAudio(state.AudioMusic) {
+		return ErrRadioPlaying
+	}
+
+	// A live stream can only be joined where it is now.
+	if song.IsStream {
+		offset = 0
+	}
+
+	if start := time.Duration(song.StartOffset) * time.Second; offset < start {
+		offset = start
 	}
 
 	select {
@@ -87,18 +160,54 @@ func (p *Player) Play(vc *discordgo.VoiceConnection, song *state.Song) error {
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
 	p.currentSong = song
-	p.stateManager.SetPlaying(true)
-	p.stateManager.SetMusicPaused(false)
+	p.offset = offset
+	p.filter = p.songFilter(song)
+	p.sender.Store(nil)
+	p.setPlaying(true)
+	p.setPaused(false)
 	p.isPlaying = true
 	p.isPaused = false
 
-	logger.Info.Printf("Starting playback: %s by %s", song.Title, song.Artist)
+	if resumed {
+		logger.Info.Printf("Resuming playback at %s with filter %s: %s by %s", offset.Truncate(time.Second), p.filter, song.Title, song.Artist)
+	} else {
+		logger.Info.Printf("Starting playback: %s by %s", song.Title, song.Artist)
+	}
 
-	go p.playLoop(vc, song)
+	var pb *prebuffer
+	if !resumed {
+		pb = p.takePrebufferLocked(song)
+	}
+
+	if !resumed {
+		p.publish(TrackStarted, song, offset, nil)
+	}
+
+	go p.playLoop(vc, guild, song, offset, p.filter, pb)
 
 	return nil
 }
 
+// halfwayPoint is the offset into the file halfway through the part of song
+// that plays.
+func halfwayPoint(song *state.Song) time.Duration {
+	return time.Duration(song.StartOffset)*time.Second + time.Duration(song.PlayLength())*time.Second/2
+}
+
+// formatSeconds formats d for ffmpeg's -ss and -t options.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// filePath resolves the song's stored path against the download directory.
+// Live streams store the media URL instead, which is returned as is.
+func (p *Player) filePath(song *state.Song) string {
+	if song.IsStream {
+		return song.FilePath
+	}
+	return config.ResolveTrackPath(p.stateManager.GetConfig().DownloadDir, song.FilePath)
+}
+
 func (p *Player) Pause() {
 	p.mu.Lock()
 	if !p.isPlaying || p.isPaused {
@@ -114,7 +223,7 @@ func (p *Player) Pause() {
 	}
 
 	p.isPaused = true
-	p.stateManager.SetMusicPaused(true)
+	p.setPaused(true)
 	p.mu.Unlock()
 }
 
@@ -138,6 +247,16 @@ func (p *Player) Resume(vc *discordgo.VoiceConnection) error {
 }
 
 func (p *Player) Stop() {
+	p.stop(false)
+}
+
+// StopWithoutEvent stops playback without publishing the song's end, for
+// callers that start the next song themselves.
+func (p *Player) StopWithoutEvent() {
+	p.stop(true)
+}
+
+func (p *Player) stop(suppressEnd bool) {
 	p.mu.Lock()
 	if !p.isPlaying {
 		p.mu.Unlock()
@@ -146,6 +265,9 @@ func (p *Player) Stop() {
 
 	logger.Info.Println("Stopping music player...")
 
+	p.suppressEnd = suppressEnd
+	p.stopped = true
+
 	if p.cancel != nil {
 		p.cancel()
 	}
@@ -172,8 +294,8 @@ func (p *Player) Stop() {
 		p.isPlaying = false
 		p.isPaused = false
 		p.currentSong = nil
-		p.stateManager.SetPlaying(false)
-		p.stateManager.SetMusicPaused(false)
+		p.setPlaying(false)
+		p.setPaused(false)
 	}
 	p.mu.Unlock()
 }
@@ -196,9 +318,68 @@ func (p *Player) GetCurrentSong() *state.Song {
 	return p.currentSong
 }
 
+// Position returns how far into the current song playback is. With a filter
+// that changes speed, this is the position in the file, not the time played.
+func (p *Player) Position() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	played := int64(0)
+	if sender := p.sender.Load(); sender != nil {
+		played = sender.Played()
+	}
+	return p.offset + p.filter.fileTime(time.Duration(played)*audio.FrameDuration)
+}
+
+// Filter returns the filter the current song is playing with.
+func (p *Player) Filter() Filter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.isPlaying {
+		return FilterOff
+	}
+	return p.filter
+}
+
+// Overlay mixes pcm into the song that is playing, ducking the song while
+// the clip plays.
+func (p *Player) Overlay(pcm []int16) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.isPlaying || p.isPaused {
+		return ErrNotPlaying
+	}
+	return p.overlay.Start(pcm)
+}
+
+// interrupted reports whether playback was stopped or paused. A pause is
+// recorded before returning.
+func (p *Player) interrupted() bool {
+	select {
+	case <-p.ctx.Done():
+		return true
+	case <-p.stopChan:
+		return true
+	case <-p.pauseChan:
+		logger.Info.Println("Music paused")
+		p.mu.Lock()
+		p.isPlaying = false
+		p.setPlaying(false)
+		p.mu.Unlock()
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Player) Shutdown(ctx context.Context) error {
 	logger.Info.Println("Gracefully shutting down music player...")
+	wasPlaying := p.IsPlaying()
 	p.Stop()
+	p.InvalidatePrebuffer()
+	if !wasPlaying {
+		return nil
+	}
 
 	select {
 	case <-ctx.Done():
@@ -212,26 +393,44 @@ func (p *Player) Name() string {
 	return "MusicPlayer"
 }
 
-func (p *Player) playLoop(vc *discordgo.VoiceConnection, song *state.Song) {
+func (p *Player) playLoop(vc *discordgo.VoiceConnection, guild *state.Guild, song *state.Song, offset time.Duration, filter Filter, pb *prebuffer) {
+	var trackErr *TrackError
 	defer func() {
+		if guild != nil {
+			guild.ReleaseAudio(state.AudioMusic)
+		}
+
+		position := p.Position()
+
 		p.mu.Lock()
 		doneChan := p.doneChan
-		onSongEnd := p.onSongEnd
 		wasPaused := p.isPaused
+		suppressEnd := p.suppressEnd
+		stopped := p.stopped
 
+		p.suppressEnd = false
+		p.stopped = false
 		p.isPlaying = false
 		p.isPaused = false
 		p.currentSong = nil
-		p.stateManager.SetPlaying(false)
-		p.stateManager.SetMusicPaused(false)
+		p.setPlaying(false)
+		p.setPaused(false)
 		p.mu.Unlock()
 
-		if doneChan != nil {
-			close(doneChan)
+		// The end is published before anyone waiting on the stop can start
+		// the next song, so it always comes before that song's start.
+		switch {
+		case wasPaused || suppressEnd:
+		case trackErr != nil && !stopped:
+			p.publish(TrackFailed, song, trackErr.Position, trackErr)
+		case stopped:
+			p.publish(TrackStopped, song, position, nil)
+		default:
+			p.publish(TrackEnded, song, position, nil)
 		}
 
-		if onSongEnd != nil && !wasPaused {
-			onSongEnd()
+		if doneChan != nil {
+			close(doneChan)
 		}
 
 		logger.Debug.Println("Music playback goroutine finished")
@@ -242,7 +441,10 @@ func (p *Player) playLoop(vc *discordgo.VoiceConnection, song *state.Song) {
 		return
 	}
 
-	err := p.playFile(vc, song)
+	err := p.playFile(vc, song, offset, filter, pb)
+	if errors.As(err, &trackErr) {
+		return
+	}
 	if err != nil {
 		if p.stateManager.IsShuttingDown() {
 			logger.Debug.Printf("Music playback error during shutdown: %v", err)
@@ -252,24 +454,38 @@ func (p *Player) playLoop(vc *discordgo.VoiceConnection, song *state.Song) {
 	}
 }
 
-func (p *Player) playFile(vc *discordgo.VoiceConnection, song *state.Song) error {
-	logger.Debug.Printf("Playing file: %s", song.FilePath)
+// playFile plays song from offset through filter. With a prebuffer, its
+// frames are sent first while ffmpeg starts on the file where the prebuffer
+// ends.
+func (p *Player) playFile(vc *discordgo.VoiceConnection, song *state.Song, offset time.Duration, filter Filter, pb *prebuffer) error {
+	path := p.filePath(song)
+	logger.Debug.Printf("Playing file: %s", path)
 
 	ffmpegCtx, ffmpegCancel := context.WithCancel(p.ctx)
 	defer ffmpegCancel()
 
 	volume := p.stateManager.GetVolume()
 
-	ffmpeg := exec.CommandContext(ffmpegCtx,
-		"ffmpeg",
-		"-i", song.FilePath,
-		"-f", "s16le",
-		"-ar", "48000",
-		"-ac", "2",
-		"-af", fmt.Sprintf("volume=%f", volume),
-		"-loglevel", "error",
-		"pipe:1",
-	)
+	decodeFrom := offset
+	if pb != nil {
+		decodeFrom += filter.fileTime(pb.duration())
+	}
+
+	var limit time.Duration
+	if song.EndOffset > 0 {
+		limit = time.Duration(song.EndOffset)*time.Second - decodeFrom
+		if limit <= 0 {
+			return nil
+		}
+	}
+
+	args := decodeArgs(path, decodeFrom, limit, volume, filter)
+	if song.IsStream {
+		args = streamArgs(path, volume)
+	}
+	ffmpeg := exec.CommandContext(ffmpegCtx, "ffmpeg", args...)
+	stderr := &audio.StderrTail{}
+	ffmpeg.Stderr = stderr
 
 	ffmpegOut, err := ffmpeg.StdoutPipe()
 	if err != nil {
@@ -281,8 +497,9 @@ func (p *Player) playFile(vc *discordgo.VoiceConnection, song *state.Song) error
 		return fmt.Errorf("error starting ffmpeg: %w", err)
 	}
 
+	exited := false
 	defer func() {
-		if ffmpeg.Process != nil {
+		if ffmpeg.Process != nil && !exited {
 			ffmpeg.Process.Signal(os.Interrupt)
 
 			done := make(chan error, 1)
@@ -303,50 +520,111 @@ func (p *Player) playFile(vc *discordgo.VoiceConnection, song *state.Song) error
 
 	vc.Speaking(true)
 	defer vc.Speaking(false)
+	// Runs after the sender has stopped feeding frames, see audio.SendSilence.
+	defer audio.SendSilence(vc)
 
-	encoder, err := gopus.NewEncoder(frameRate, channels, gopus.Audio)
+	encoder, err := p.encoder.Take()
 	if err != nil {
 		return fmt.Errorf("error creating opus encoder: %w", err)
 	}
+	defer p.encoder.Put(encoder)
+
+	sender := audio.NewSender(p.ctx, vc, "music")
+	defer sender.Stop()
+	p.sender.Store(sender)
+
+	// A clip doesn't outlast the song it was mixed into.
+	defer p.overlay.Cancel()
+
+	halfway := halfwayPoint(song)
+	if halfway <= offset {
+		halfway = 0
+	}
+
+	if pb != nil {
+		logger.Debug.Printf("Starting %s from %s of prebuffered audio", song.Title, pb.duration())
+		var decoder *gopus.Decoder
+		for _, frame := range pb.frames {
+			if p.interrupted() {
+				return nil
+			}
+
+			// Prebuffered frames are already encoded, so they are only
+			// decoded again when there is a clip to mix in.
+			if p.overlay.Active() {
+				if decoder == nil {
+					decoder, err = gopus.NewDecoder(audio.FrameRate, audio.Channels)
+					if err != nil {
+						return fmt.Errorf("error creating opus decoder: %w", err)
+					}
+				}
+				pcm, err := decoder.Decode(frame, audio.FrameSize, false)
+				if err != nil {
+					return fmt.Errorf("error decoding prebuffered opus: %w", err)
+				}
+				p.overlay.Mix(pcm)
+				frame, err = encoder.Encode(pcm)
+				if err != nil {
+					return fmt.Errorf("error encoding opus: %w", err)
+				}
+			}
+
+			if err := sender.Send(frame); err != nil {
+				return nil
+			}
+		}
+	}
 
-	audioBuf := make([]int16, frameSize*channels)
-	opusBuffer := make([]byte, 1000)
+	frames := audio.NewFrameReader(ffmpegOut)
+	pcm := audio.GetFrame()
+	defer audio.PutFrame(pcm)
 
 	for {
-		select {
-		case <-p.ctx.Done():
+		if p.interrupted() {
 			return nil
-		case <-p.stopChan:
-			return nil
-		case <-p.pauseChan:
-			logger.Info.Println("Music paused")
-			p.mu.Lock()
-			p.isPlaying = false
-			p.stateManager.SetPlaying(false)
-			p.mu.Unlock()
-			return nil
-		default:
 		}
 
-		err := binary.Read(ffmpegOut, binary.LittleEndian, &audioBuf)
+		if halfway > 0 && offset+filter.fileTime(time.Duration(sender.Played())*audio.FrameDuration) >= halfway {
+			p.publish(TrackHalfway, song, halfway, nil)
+			halfway = 0
+		}
+
+		err := frames.ReadFrame(pcm[:])
 		if err != nil {
-			if err == io.EOF {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				sender.Close()
+				// ffmpeg has closed its output, so it is exiting, on its
+				// own unless playback was stopped. How it exits and how
+				// far it got tell a finished song from a decode error
+				// part way in.
+				exited = true
+				waitErr := ffmpeg.Wait()
+				if p.ctx.Err() != nil {
+					return nil
+				}
+				if trackErr := checkTrackEnd(song, p.Position(), waitErr, stderr); trackErr != nil {
+					return trackErr
+				}
+				// A song the downloader had no length for is measured
+				// here, where it is known to have played to its end.
+				// A trimmed song stops short of it.
+				if song.Duration <= 0 && song.EndOffset == 0 {
+					p.publish(TrackMeasured, song, p.Position(), nil)
+				}
 				logger.Debug.Printf("Finished playing: %s", song.Title)
 				return nil
 			}
 			return fmt.Errorf("error reading audio data: %w", err)
 		}
 
-		opusData, err := encoder.Encode(audioBuf, frameSize, len(opusBuffer))
+		p.overlay.Mix(pcm[:])
+
+		opusData, err := encoder.Encode(pcm[:])
 		if err != nil {
 			return fmt.Errorf("error encoding opus: %w", err)
 		}
 
-		select {
-		case vc.OpusSend <- opusData:
-		case <-time.After(2 * time.Second):
-			return fmt.Errorf("discord send timeout")
-		case <-p.ctx.Done():
+		if err := sender.Send(opusData); err != nil {
 			return nil
 		}
 	}
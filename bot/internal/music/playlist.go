@@ -0,0 +1,305 @@
+package music
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
+	"musicbot/internal/socket"
+	"musicbot/internal/state"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultPlaylistWorkers is how many tracks of a playlist are downloaded
+	// at once unless playlist_workers is configured.
+	defaultPlaylistWorkers = 3
+
+	// playlistItemTimeout bounds a single attempt, so a download the
+	// downloader never answers can't hold up the playlist for good.
+	playlistItemTimeout = 10 * time.Minute
+
+	// playlistCancelWait is how long cancelPlaylists waits for downloads to
+	// wind down.
+	playlistCancelWait = 10 * time.Second
+)
+
+// ErrPlaylistCancelled is reported for playlists stopped by /clear, /leave
+// or a shutdown before all of their tracks were queued.
+var ErrPlaylistCancelled = errors.New("the playlist download was cancelled")
+
+// ErrPlaylistInProgress is returned when a playlist is requested while the
+// same playlist is still downloading.
+var ErrPlaylistInProgress = errors.New("the playlist is already being downloaded")
+
+// SkipReason is why a track of a playlist was skipped.
+type SkipReason int
+
+const (
+	SkipUnavailable SkipReason = iota
+	SkipPrivate
+	SkipRegionBlocked
+	SkipAgeRestricted
+	SkipTooLong
+	SkipTooLarge
+	SkipFailed
+
+	skipReasons
+)
+
+// Skipped counts the skipped tracks of a playlist by SkipReason.
+type Skipped [skipReasons]int
+
+func (s Skipped) Total() int {
+	total := 0
+	for _, count := range s {
+		total += count
+	}
+	return total
+}
+
+// SkipReasonOf tells why the download of a track failed for good.
+func SkipReasonOf(err error) SkipReason {
+	var limitErr *DownloadLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		if limitErr.TooLarge {
+			return SkipTooLarge
+		}
+		return SkipTooLong
+	case errors.Is(err, ErrPrivate):
+		return SkipPrivate
+	case errors.Is(err, ErrRegionBlocked):
+		return SkipRegionBlocked
+	case errors.Is(err, ErrAgeRestricted):
+		return SkipAgeRestricted
+	case errors.Is(err, ErrVideoUnavailable):
+		return SkipUnavailable
+	}
+	return SkipFailed
+}
+
+// playlistItem is the outcome of downloading the track at index.
+type playlistItem struct {
+	index int
+	song  *state.Song
+	err   error
+}
+
+// downloadPlaylist fetches the track list of url, downloads the tracks on a
+// pool of workers and queues them in playlist order as soon as every earlier
+// track is through. Tracks that fail after all attempts are skipped and
+// counted by why.
+func (m *Manager) downloadPlaylist(ctx context.Context, url string, limit int, limits config.DownloadLimits, progress *playlistProgress) {
+	defer close(progress.done)
+	defer func() {
+		m.downloadMu.Lock()
+		delete(m.activePlaylistUrls, url)
+		if m.playlists[url] == progress {
+			delete(m.playlists, url)
+		}
+		m.downloadMu.Unlock()
+		m.releaseReservation(url, progress.requestedBy)
+		progress.cancel(nil)
+	}()
+
+	info, err := m.socketClient.GetPlaylistInfo(ctx, url, limit)
+	if err == nil && !info.IsPlaylist {
+		err = fmt.Errorf("%s is not a playlist", url)
+	}
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			err = cause
+		}
+		logger.Error.Printf("Failed to get playlist info for %s: %v", url, err)
+		if progress.notifier != nil {
+			progress.notifier.Failed(err)
+		}
+		return
+	}
+
+	total := min(info.TotalTracks, limit)
+	if total <= 0 {
+		if progress.notifier != nil {
+			progress.notifier.PlaylistDone(0, 0, Skipped{})
+		}
+		return
+	}
+
+	workers := m.stateManager.GetConfig().PlaylistWorkers
+	if workers <= 0 {
+		workers = defaultPlaylistWorkers
+	}
+	workers = min(workers, total)
+
+	atomic.AddInt32(&m.pendingDownloads, int32(total))
+	logger.Info.Printf("Downloading playlist %q with %d tracks on %d workers (total pending: %d)",
+		info.Title, total, workers, atomic.LoadInt32(&m.pendingDownloads))
+
+	indexes := make(chan int)
+	results := make(chan playlistItem)
+
+	go func() {
+		defer close(indexes)
+		for index := 0; index < total; index++ {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				song, err := m.downloadPlaylistItem(ctx, url, index, limits)
+				results <- playlistItem{index: index, song: song, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Finished tracks wait here until every track before them is through.
+	waiting := make(map[int]playlistItem)
+	next, failed := 0, 0
+	for result := range results {
+		waiting[result.index] = result
+		for {
+			item, ok := waiting[next]
+			if !ok || ctx.Err() != nil {
+				break
+			}
+			delete(waiting, next)
+			next++
+
+			if item.err != nil {
+				logger.Error.Printf("Giving up on track %d of playlist %s: %v", item.index+1, url, item.err)
+				reason := item.err.Error()
+				var downloadErr *DownloadError
+				if errors.As(item.err, &downloadErr) {
+					reason = downloadErr.Reason
+				}
+				m.recordFailure(url, item.index, m.reservedBy(url), reason)
+				failed++
+				m.downloadMu.Lock()
+				progress.skipped[SkipReasonOf(item.err)]++
+				m.downloadMu.Unlock()
+				m.completeDownload(nil, "")
+			} else {
+				m.acceptDownload(item.song, url, true)
+			}
+
+			if progress.notifier != nil {
+				progress.notifier.PlaylistProgress(next, failed, total)
+			}
+		}
+	}
+
+	if next < total {
+		// Cancelled: the tracks that weren't queued are no longer pending.
+		if atomic.AddInt32(&m.pendingDownloads, -int32(total-next)) <= 0 {
+			m.pruneReservations()
+		}
+		logger.Info.Printf("Playlist %s stopped after %d of %d tracks: %v", url, next, total, context.Cause(ctx))
+		if progress.notifier != nil {
+			progress.notifier.Failed(context.Cause(ctx))
+		}
+		return
+	}
+
+	m.downloadMu.Lock()
+	added, duplicates, skipped := progress.added, progress.duplicates, progress.skipped
+	m.downloadMu.Unlock()
+
+	logger.Info.Printf("Playlist finished: %s (%d added, %d duplicates skipped, %d failed)", url, added, duplicates, failed)
+	if progress.notifier != nil {
+		progress.notifier.PlaylistDone(added, duplicates, skipped)
+	}
+}
+
+// downloadPlaylistItem downloads one track. Failures for a passing reason are
+// tried again with a growing pause, up to the attempts allowed by limits.
+func (m *Manager) downloadPlaylistItem(ctx context.Context, url string, index int, limits config.DownloadLimits) (*state.Song, error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, playlistItemTimeout)
+		song, err := m.socketClient.DownloadPlaylistItem(attemptCtx, url, index, limits)
+		cancel()
+
+		if err == nil {
+			return song, nil
+		}
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		if limitErr := downloadLimitError(err.Error(), limits); limitErr != nil {
+			return nil, limitErr
+		}
+
+		// A failure the downloader recognised won't go away on its own.
+		code := ""
+		var downloaderErr *socket.DownloaderError
+		if errors.As(err, &downloaderErr) {
+			code = downloaderErr.Code
+		}
+
+		class := classifyDownloadError(err.Error())
+		if code != "" || !retryable(class) || attempt >= limits.MaxAttempts {
+			return nil, downloadError(err.Error(), code, attempt)
+		}
+
+		delay := retryDelay(attempt)
+		logger.Debug.Printf("Track %d of playlist %s failed (%s, attempt %d), retrying in %v: %v",
+			index+1, url, class, attempt, delay.Round(time.Millisecond), err)
+		metrics.DownloadRetried(class)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// cancelPlaylists stops every playlist download and waits briefly for the
+// downloads to wind down.
+func (m *Manager) cancelPlaylists(cause error) {
+	m.downloadMu.RLock()
+	var running []*playlistProgress
+	for _, progress := range m.playlists {
+		if progress.cancel != nil {
+			running = append(running, progress)
+		}
+	}
+	m.downloadMu.RUnlock()
+
+	if len(running) == 0 {
+		return
+	}
+
+	logger.Info.Printf("Cancelling %d playlist downloads: %v", len(running), cause)
+	for _, progress := range running {
+		progress.cancel(cause)
+	}
+
+	timeout := time.After(playlistCancelWait)
+	for _, progress := range running {
+		select {
+		case <-progress.done:
+		case <-timeout:
+			logger.Error.Println("Playlist downloads did not stop in time")
+			return
+		}
+	}
+}
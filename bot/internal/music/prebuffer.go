@@ -0,0 +1,232 @@
+package music
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/audio"
+	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
+	"musicbot/internal/state"
+	"os/exec"
+	"time"
+)
+
+const (
+	// prebufferLength is how much of the next song is encoded ahead of time,
+	// enough to cover ffmpeg starting up for the rest of it.
+	prebufferLength = 5 * time.Second
+
+	// maxPrebufferBytes caps the memory one prebuffer may hold.
+	maxPrebufferBytes = 256 << 10
+)
+
+// prebuffer holds the opening opus frames of the next song, so playback can
+// start from memory while ffmpeg starts on the rest of the file.
+type prebuffer struct {
+	songID      int64
+	filePath    string
+	startOffset int
+	endOffset   int
+	volume      float32
+	filter      Filter
+	frames      [][]byte
+	bytes       int
+	ready       chan struct{}
+	cancel      context.CancelFunc
+}
+
+func (pb *prebuffer) matches(song *state.Song, volume float32, filter Filter) bool {
+	return pb.songID == song.ID && pb.filePath == song.FilePath &&
+		pb.startOffset == song.StartOffset && pb.endOffset == song.EndOffset &&
+		pb.volume == volume && pb.filter == filter
+}
+
+func (pb *prebuffer) isReady() bool {
+	select {
+	case <-pb.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// duration is how much audio the buffered frames cover.
+func (pb *prebuffer) duration() time.Duration {
+	return time.Duration(len(pb.frames)) * audio.FrameDuration
+}
+
+// Prebuffer starts encoding the opening of song in the background, replacing
+// any prebuffer for a different song.
+func (p *Player) Prebuffer(song *state.Song) {
+	volume := p.stateManager.GetVolume()
+	filter := p.songFilter(song)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A live stream has no opening to buffer; it starts wherever it is.
+	if song.IsStream {
+		p.dropPrebufferLocked()
+		return
+	}
+
+	if p.next != nil {
+		if p.next.matches(song, volume, filter) {
+			return
+		}
+		p.dropPrebufferLocked()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pb := &prebuffer{
+		songID:      song.ID,
+		filePath:    song.FilePath,
+		startOffset: song.StartOffset,
+		endOffset:   song.EndOffset,
+		volume:      volume,
+		filter:      filter,
+		ready:       make(chan struct{}),
+		cancel:      cancel,
+	}
+	p.next = pb
+
+	go p.fillPrebuffer(ctx, pb, p.filePath(song), song.Title)
+}
+
+// InvalidatePrebuffer discards the prebuffer, e.g. when the next song in the
+// queue changes.
+func (p *Player) InvalidatePrebuffer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropPrebufferLocked()
+}
+
+// takePrebufferLocked hands over the prebuffer if it is finished and belongs to
+// song, and discards it otherwise.
+func (p *Player) takePrebufferLocked(song *state.Song) *prebuffer {
+	pb := p.next
+	if pb == nil {
+		return nil
+	}
+
+	if !pb.matches(song, p.stateManager.GetVolume(), p.songFilter(song)) || !pb.isReady() || len(pb.frames) == 0 {
+		p.dropPrebufferLocked()
+		return nil
+	}
+
+	p.next = nil
+	metrics.SetPrebufferBytes(0)
+	return pb
+}
+
+func (p *Player) dropPrebufferLocked() {
+	if p.next == nil {
+		return
+	}
+	p.next.cancel()
+	p.next = nil
+	metrics.SetPrebufferBytes(0)
+}
+
+func (p *Player) fillPrebuffer(ctx context.Context, pb *prebuffer, path, title string) {
+	defer close(pb.ready)
+
+	start := time.Duration(pb.startOffset) * time.Second
+	limit := pb.filter.fileTime(prebufferLength)
+	if pb.endOffset > 0 {
+		limit = min(limit, time.Duration(pb.endOffset)*time.Second-start)
+	}
+
+	ffmpeg := exec.CommandContext(ctx, "ffmpeg", decodeArgs(path, start, limit, pb.volume, pb.filter)...)
+	out, err := ffmpeg.StdoutPipe()
+	if err != nil {
+		logger.Error.Printf("Failed to prebuffer %s: %v", title, err)
+		return
+	}
+	if err := ffmpeg.Start(); err != nil {
+		logger.Error.Printf("Failed to prebuffer %s: %v", title, err)
+		return
+	}
+	defer func() {
+		// ffmpeg may still be writing if the byte cap was hit first.
+		pb.cancel()
+		ffmpeg.Wait()
+	}()
+
+	// Prebuffering runs while the current song plays, so it can't borrow the
+	// player's encoder.
+	encoder, err := audio.NewEncoder()
+	if err != nil {
+		logger.Error.Printf("Failed to prebuffer %s: %v", title, err)
+		return
+	}
+
+	frames := audio.NewFrameReader(out)
+	pcm := audio.GetFrame()
+	defer audio.PutFrame(pcm)
+	for pb.bytes < maxPrebufferBytes {
+		if err := frames.ReadFrame(pcm[:]); err != nil {
+			break
+		}
+
+		frame, err := encoder.Encode(pcm[:])
+		if err != nil {
+			logger.Error.Printf("Failed to prebuffer %s: %v", title, err)
+			pb.frames = nil
+			pb.bytes = 0
+			return
+		}
+
+		pb.frames = append(pb.frames, frame)
+		pb.bytes += len(frame)
+	}
+
+	if ctx.Err() != nil {
+		pb.frames = nil
+		pb.bytes = 0
+		return
+	}
+
+	metrics.SetPrebufferBytes(pb.bytes)
+	logger.Debug.Printf("Prebuffered %s of %s (%d bytes)", pb.duration(), title, pb.bytes)
+}
+
+// decodeArgs builds the ffmpeg arguments that decode path to raw PCM for the
+// opus encoder through filter, starting at offset and stopping after limit if
+// it is set. Both are positions in the file, so they hold for filters that
+// change speed too.
+func decodeArgs(path string, offset, limit time.Duration, volume float32, filter Filter) []string {
+	var args []string
+	if offset > 0 {
+		args = append(args, "-ss", formatSeconds(offset))
+	}
+	if limit > 0 {
+		args = append(args, "-t", formatSeconds(limit))
+	}
+	return append(args,
+		"-i", path,
+		"-f", "s16le",
+		"-ar", "48000",
+		"-ac", "2",
+		"-af", audioFilters(volume, filter),
+		"-loglevel", "error",
+		"pipe:1",
+	)
+}
+
+// streamArgs builds the ffmpeg arguments that decode a live stream from url,
+// reconnecting the way the radio player does if the connection drops.
+func streamArgs(url string, volume float32) []string {
+	return []string{
+		"-reconnect", "1",
+		"-reconnect_streamed", "1",
+		"-reconnect_delay_max", "5",
+		"-i", url,
+		"-f", "s16le",
+		"-ar", "48000",
+		"-ac", "2",
+		"-af", fmt.Sprintf("volume=%f", volume),
+		"-loglevel", "error",
+		"pipe:1",
+	}
+}
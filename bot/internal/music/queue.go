@@ -1,95 +1,316 @@
 package music
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"musicbot/internal/config"
 	"musicbot/internal/logger"
 	"musicbot/internal/state"
+	"musicbot/internal/urlnorm"
 	"sync"
+	"time"
+)
+
+var (
+	ErrOutOfRange = errors.New("queue position out of range")
+	ErrNotOwner   = errors.New("song was requested by someone else")
 )
 
 type Queue struct {
+	// guildID is the guild whose persisted queue this is. A queue without
+	// one is neither loaded nor saved.
+	guildID   string
 	items     []state.QueueItem
 	position  int
+	maxLength int
+	// fair makes requesters take turns; see SetFair.
+	fair bool
+	// pinned counts the items right after the current one that were put
+	// next on purpose, and so play before fair mode's turns.
+	pinned    int
 	dbManager *config.DatabaseManager
+	persister *queuePersister
 	mu        sync.RWMutex
 }
 
-func NewQueue(dbManager *config.DatabaseManager) *Queue {
+func NewQueue(dbManager *config.DatabaseManager, guildID string) *Queue {
 	q := &Queue{
+		guildID:   guildID,
 		items:     make([]state.QueueItem, 0),
 		position:  0,
 		dbManager: dbManager,
 	}
 
-	q.loadFromDatabase()
+	if guildID != "" {
+		q.loadFromDatabase(guildID)
+	}
+	q.persister = newQueuePersister(dbManager, q.snapshot)
 	return q
 }
 
-func (q *Queue) loadFromDatabase() {
-	items, err := q.dbManager.GetQueue()
+func (q *Queue) snapshot() (string, []config.QueueEntry, int) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	entries := make([]config.QueueEntry, len(q.items))
+	for i, item := range q.items {
+		entries[i] = config.QueueEntry{SongID: item.SongID, RequestedBy: item.RequestedBy, RequestedAt: item.RequestedAt}
+		if item.Song != nil {
+			entries[i].StartOffset = item.Song.StartOffset
+			entries[i].EndOffset = item.Song.EndOffset
+		}
+	}
+	return q.guildID, entries, q.position
+}
+
+// SetMaxLength caps the number of upcoming songs. Zero means unlimited.
+func (q *Queue) SetMaxLength(maxLength int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxLength = maxLength
+}
+
+// upcomingLocked returns the number of songs after the current one. The
+// caller must hold q.mu.
+func (q *Queue) upcomingLocked() int {
+	if len(q.items) == 0 {
+		return 0
+	}
+	return len(q.items) - q.position - 1
+}
+
+func (q *Queue) UpcomingCount() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.upcomingLocked()
+}
+
+// LastTurn returns when the last queued song plays, 1 being next, or 0 if
+// nothing is upcoming. Without fair mode that is UpcomingCount.
+func (q *Queue) LastTurn() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.turnLocked(len(q.items) - 1)
+}
+
+// UpcomingCountBy returns how many upcoming songs were requested by userID.
+func (q *Queue) UpcomingCountBy(userID string) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	count := 0
+	for i := q.position + 1; i < len(q.items); i++ {
+		if q.items[i].RequestedBy == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// loadFromDatabase replaces the queue with guildID's persisted one. If it
+// can't be read the queue starts empty.
+func (q *Queue) loadFromDatabase(guildID string) {
+	items, err := q.dbManager.GetQueue(guildID)
 	if err != nil {
-		logger.Error.Printf("Failed to load queue from database: %v", err)
-		return
+		logger.Error.Printf("Failed to load queue of guild %s from database: %v", guildID, err)
+		items = nil
 	}
 
-	position, err := q.dbManager.GetCurrentQueuePosition()
+	position, err := q.dbManager.GetCurrentQueuePosition(guildID)
 	if err != nil {
-		logger.Error.Printf("Failed to load queue position from database: %v", err)
+		logger.Error.Printf("Failed to load queue position of guild %s from database: %v", guildID, err)
 		position = 0
 	}
+	if position >= len(items) {
+		position = max(len(items)-1, 0)
+	}
 
 	q.mu.Lock()
+	q.guildID = guildID
 	q.items = items
+	if q.items == nil {
+		q.items = make([]state.QueueItem, 0)
+	}
 	q.position = position
+	q.pinned = 0
 	q.mu.Unlock()
 
-	logger.Info.Printf("Loaded queue with %d songs, position: %d", len(items), position)
+	logger.Info.Printf("Loaded queue of guild %s with %d songs, position: %d", guildID, len(items), position)
 }
 
-func (q *Queue) Add(song *state.Song) error {
-	var songID int64
-
-	existing, err := q.dbManager.GetSongByURL(song.URL)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to check for existing song: %w", err)
-	}
-
-	if existing != nil {
-		songID = existing.ID
-		song.ID = songID
-		logger.Info.Printf("Using existing song from database: %s (ID: %d)", song.Title, songID)
-	} else {
-		songID, err = q.dbManager.AddSong(song)
-		if err != nil {
-			return fmt.Errorf("failed to add song to database: %w", err)
-		}
-		song.ID = songID
-		logger.Info.Printf("Added new song to database: %s (ID: %d)", song.Title, songID)
+func (q *Queue) Add(song *state.Song, requestedBy string) error {
+	if q.isFull() {
+		return ErrQueueFull
 	}
 
-	err = q.dbManager.AddToQueue(songID)
+	song.RequesterID = requestedBy
+	song.RequestedAt = time.Now()
+	songID, err := q.resolveSongID(song)
 	if err != nil {
-		return fmt.Errorf("failed to add song to queue: %w", err)
+		return err
 	}
 
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	if q.maxLength > 0 && q.upcomingLocked() >= q.maxLength {
+		return ErrQueueFull
+	}
+
 	newPosition := len(q.items) + 1
 	item := state.QueueItem{
-		SongID:   songID,
-		Position: newPosition,
-		Song:     song,
+		SongID:      songID,
+		Position:    newPosition,
+		RequestedBy: requestedBy,
+		RequestedAt: song.RequestedAt,
+		Song:        song,
 	}
 
 	q.items = append(q.items, item)
+	q.persister.MarkDirty()
 
 	logger.Info.Printf("Added song to queue: %s by %s", song.Title, song.Artist)
 	return nil
 }
 
+// InsertNext places song directly after the current item so it plays next.
+func (q *Queue) InsertNext(song *state.Song, requestedBy string) error {
+	if q.isFull() {
+		return ErrQueueFull
+	}
+
+	song.RequesterID = requestedBy
+	song.RequestedAt = time.Now()
+	songID, err := q.resolveSongID(song)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxLength > 0 && q.upcomingLocked() >= q.maxLength {
+		return ErrQueueFull
+	}
+
+	index := q.position + 1
+	if index > len(q.items) {
+		index = len(q.items)
+	}
+
+	item := state.QueueItem{
+		SongID:      songID,
+		RequestedBy: requestedBy,
+		RequestedAt: song.RequestedAt,
+		Song:        song,
+	}
+
+	q.items = append(q.items, state.QueueItem{})
+	copy(q.items[index+1:], q.items[index:])
+	q.items[index] = item
+	if index > q.position {
+		q.pinned++
+	}
+
+	for i := range q.items {
+		q.items[i].Position = i + 1
+	}
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Inserted song as next in queue: %s by %s", song.Title, song.Artist)
+	return nil
+}
+
+// InsertCurrent makes song the current item, to be played right away. With
+// keepCurrent the item playing now moves down to play next; otherwise it
+// stays behind as played. The result is persisted in one write.
+func (q *Queue) InsertCurrent(song *state.Song, requestedBy string, keepCurrent bool) error {
+	song.RequesterID = requestedBy
+	song.RequestedAt = time.Now()
+	songID, err := q.resolveSongID(song)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	index := q.position
+	if len(q.items) > 0 && !keepCurrent {
+		index++
+	}
+	if len(q.items) > 0 && keepCurrent {
+		q.pinned++
+	}
+	if index > len(q.items) {
+		index = len(q.items)
+	}
+
+	q.items = append(q.items, state.QueueItem{})
+	copy(q.items[index+1:], q.items[index:])
+	q.items[index] = state.QueueItem{
+		SongID:      songID,
+		RequestedBy: requestedBy,
+		RequestedAt: song.RequestedAt,
+		Song:        song,
+	}
+	for i := range q.items {
+		q.items[i].Position = i + 1
+	}
+	q.position = index
+	q.mu.Unlock()
+
+	if err := q.persister.Flush(context.Background()); err != nil {
+		return fmt.Errorf("failed to save queue: %w", err)
+	}
+
+	logger.Info.Printf("Inserted song to play now: %s by %s", song.Title, song.Artist)
+	return nil
+}
+
+// isFull is checked before touching the database so a full queue does not
+// register songs it is about to reject.
+func (q *Queue) isFull() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.maxLength > 0 && q.upcomingLocked() >= q.maxLength
+}
+
+func (q *Queue) resolveSongID(song *state.Song) (int64, error) {
+	existing, err := q.dbManager.GetSongByURL(song.URL)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to check for existing song: %w", err)
+	}
+
+	if existing != nil {
+		song.ID = existing.ID
+		logger.Info.Printf("Using existing song from database: %s (ID: %d)", song.Title, song.ID)
+
+		// The downloader stores songs without their upload date and view
+		// count, and leaves them out when it answers from its cache.
+		if song.UploadDate != "" || song.ViewCount > 0 {
+			if err := q.dbManager.UpdateSongMetadata(song.ID, song.UploadDate, song.ViewCount); err != nil {
+				logger.Error.Printf("Failed to store metadata of song %d: %v", song.ID, err)
+			}
+		}
+		if song.UploadDate == "" {
+			song.UploadDate = existing.UploadDate
+		}
+		if song.ViewCount == 0 {
+			song.ViewCount = existing.ViewCount
+		}
+		return song.ID, nil
+	}
+
+	songID, err := q.dbManager.AddSong(song)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add song to database: %w", err)
+	}
+	song.ID = songID
+	logger.Info.Printf("Added new song to database: %s (ID: %d)", song.Title, songID)
+	return songID, nil
+}
+
 func (q *Queue) GetCurrent() *state.Song {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
@@ -105,12 +326,12 @@ func (q *Queue) GetNext() *state.Song {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
-	nextPos := q.position + 1
-	if nextPos >= len(q.items) {
+	order := q.orderLocked()
+	if len(order) == 0 {
 		return nil
 	}
 
-	return q.items[nextPos].Song
+	return q.items[order[0]].Song
 }
 
 func (q *Queue) Advance() (*state.Song, error) {
@@ -121,12 +342,14 @@ func (q *Queue) Advance() (*state.Song, error) {
 		return nil, fmt.Errorf("no more songs in queue")
 	}
 
-	q.position++
-
-	err := q.dbManager.SetCurrentQueuePosition(q.position)
-	if err != nil {
-		logger.Error.Printf("Failed to save queue position: %v", err)
+	// In fair mode the next turn may be further down; it moves up so the
+	// items before position stay in the order they played.
+	q.moveNextLocked(q.orderLocked()[0])
+	if q.pinned > 0 {
+		q.pinned--
 	}
+	q.position++
+	q.persister.MarkDirty()
 
 	logger.Info.Printf("Advanced to next song in queue, position: %d", q.position)
 	return q.items[q.position].Song, nil
@@ -174,14 +397,12 @@ func (q *Queue) GetUpcoming(limit int) []state.Song {
 	defer q.mu.RUnlock()
 
 	upcoming := make([]state.Song, 0)
-	start := q.position + 1
-	end := start + limit
-
-	if end > len(q.items) {
-		end = len(q.items)
+	order := q.orderLocked()
+	if limit < len(order) {
+		order = order[:max(limit, 0)]
 	}
 
-	for i := start; i < end; i++ {
+	for _, i := range order {
 		if q.items[i].Song != nil {
 			upcoming = append(upcoming, *q.items[i].Song)
 		}
@@ -192,28 +413,425 @@ func (q *Queue) GetUpcoming(limit int) []state.Song {
 
 func (q *Queue) Clear() error {
 	q.mu.Lock()
-	defer q.mu.Unlock()
+	q.items = make([]state.QueueItem, 0)
+	q.position = 0
+	q.pinned = 0
+	q.mu.Unlock()
 
-	err := q.dbManager.ClearQueue()
+	err := q.persister.Flush(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to clear queue in database: %w", err)
 	}
 
-	q.items = make([]state.QueueItem, 0)
-	q.position = 0
-
 	logger.Info.Println("Queue cleared")
 	return nil
 }
 
-func (q *Queue) Remove(queueID int64) error {
-	err := q.dbManager.RemoveFromQueue(queueID)
+// Remove drops the item at the given index of the queue. The currently playing
+// item cannot be removed.
+func (q *Queue) Remove(index int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if index < 0 || index >= len(q.items) {
+		return fmt.Errorf("queue index out of range: %d", index)
+	}
+
+	if index == q.position {
+		return fmt.Errorf("cannot remove the current song")
+	}
+
+	if q.pinnedLocked(index) {
+		q.pinned--
+	}
+	removed := q.items[index]
+	q.items = append(q.items[:index], q.items[index+1:]...)
+	for k := index; k < len(q.items); k++ {
+		q.items[k].Position = k + 1
+	}
+	if index < q.position {
+		q.position--
+	}
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Removed song from queue: %d", removed.SongID)
+	return nil
+}
+
+// RemoveRequestedBy drops every upcoming item requested by userID and returns
+// how many were removed.
+func (q *Queue) RemoveRequestedBy(userID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.position+1 >= len(q.items) {
+		return 0
+	}
+
+	kept := q.items[:q.position+1]
+	removed, unpinned := 0, 0
+	for i := len(kept); i < len(q.items); i++ {
+		if q.items[i].RequestedBy == userID {
+			if q.pinnedLocked(i) {
+				unpinned++
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, q.items[i])
+	}
+
+	if removed == 0 {
+		return 0
+	}
+
+	q.items = kept
+	q.pinned -= unpinned
+	for k := range q.items {
+		q.items[k].Position = k + 1
+	}
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Removed %d queued songs requested by %s", removed, userID)
+	return removed
+}
+
+// RemoveURL drops every upcoming item with the same normalized URL as rawURL
+// and returns how many were removed.
+func (q *Queue) RemoveURL(rawURL string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.position+1 >= len(q.items) {
+		return 0
+	}
+
+	normalized := urlnorm.Normalize(rawURL)
+	kept := q.items[:q.position+1]
+	removed, unpinned := 0, 0
+	for i := len(kept); i < len(q.items); i++ {
+		if q.items[i].Song != nil && urlnorm.Normalize(q.items[i].Song.URL) == normalized {
+			if q.pinnedLocked(i) {
+				unpinned++
+			}
+			removed++
+			continue
+		}
+		kept = append(kept, q.items[i])
+	}
+
+	if removed == 0 {
+		return 0
+	}
+
+	q.items = kept
+	q.pinned -= unpinned
+	for k := range q.items {
+		q.items[k].Position = k + 1
+	}
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Removed %d queued songs of %s", removed, rawURL)
+	return removed
+}
+
+// SetDuration stores the measured length of song, which was queued without
+// one, and gives it to every item of the same song still lacking it. It
+// returns how many items were updated.
+func (q *Queue) SetDuration(song *state.Song, seconds int) (int, error) {
+	songID := song.ID
+	if songID == 0 {
+		existing, err := q.dbManager.GetSongByURL(song.URL)
+		if err != nil {
+			return 0, fmt.Errorf("failed to look up song: %w", err)
+		}
+		songID = existing.ID
+	}
+	if err := q.dbManager.UpdateSongDuration(songID, seconds); err != nil {
+		return 0, fmt.Errorf("failed to store the length of song %d: %w", songID, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	normalized := urlnorm.Normalize(song.URL)
+	updated := 0
+	for i := range q.items {
+		item := &q.items[i]
+		if item.Song == nil || item.Song.Duration > 0 || urlnorm.Normalize(item.Song.URL) != normalized {
+			continue
+		}
+
+		measured := *item.Song
+		measured.Duration = seconds
+		item.Song = &measured
+		updated++
+	}
+	if updated > 0 {
+		q.persister.MarkDirty()
+	}
+	return updated, nil
+}
+
+// ReplaceFile points the items from the current one onwards that have the
+// same normalized URL as song at song's file, which was downloaded again
+// after the old one went missing. Trims and requesters are kept. It returns
+// how many items were updated.
+func (q *Queue) ReplaceFile(song *state.Song) (int, error) {
+	downloaded := *song
+	songID, err := q.resolveSongID(&downloaded)
 	if err != nil {
-		return fmt.Errorf("failed to remove from queue in database: %w", err)
+		return 0, err
+	}
+	// The downloader keeps the row of a song it already knew, along with
+	// its old path.
+	if err := q.dbManager.UpdateSongFile(songID, downloaded.FilePath, downloaded.FileSize); err != nil {
+		return 0, fmt.Errorf("failed to store the new file of song %d: %w", songID, err)
 	}
 
-	q.loadFromDatabase()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	normalized := urlnorm.Normalize(song.URL)
+	replaced := 0
+	for i := max(q.position, 0); i < len(q.items); i++ {
+		item := &q.items[i]
+		if item.Song == nil || urlnorm.Normalize(item.Song.URL) != normalized {
+			continue
+		}
+
+		// Like a trim, the new file goes on a copy, since the song may be
+		// shared with the player.
+		updated := *item.Song
+		updated.ID = songID
+		updated.FilePath = downloaded.FilePath
+		updated.FileSize = downloaded.FileSize
+		if updated.Duration <= 0 {
+			updated.Duration = downloaded.Duration
+		}
+		item.Song = &updated
+		item.SongID = songID
+		replaced++
+	}
+	if replaced > 0 {
+		q.persister.MarkDirty()
+	}
+	return replaced, nil
+}
 
-	logger.Info.Printf("Removed song from queue: %d", queueID)
+// FindDuplicate returns a *DuplicateError if an upcoming song, or the current
+// one if includeCurrent is set, has the same normalized URL as rawURL or the
+// same file as filePath. Either may be empty to skip that comparison.
+func (q *Queue) FindDuplicate(rawURL, filePath string, includeCurrent bool) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	normalized := ""
+	if rawURL != "" {
+		normalized = urlnorm.Normalize(rawURL)
+	}
+
+	start := q.position + 1
+	if includeCurrent {
+		start = q.position
+	}
+
+	for i := start; i < len(q.items); i++ {
+		song := q.items[i].Song
+		if song == nil {
+			continue
+		}
+		if (normalized != "" && song.URL != "" && urlnorm.Normalize(song.URL) == normalized) ||
+			(filePath != "" && song.FilePath == filePath) {
+			position := i - q.position
+			if position > 0 {
+				position = q.turnLocked(i)
+			}
+			return &DuplicateError{Position: position, Title: song.Title}
+		}
+	}
 	return nil
 }
+
+// RemoveUpcoming drops upcoming items from through to, counting the next song
+// as 1, in a single change. from 0 selects every upcoming item and to 0 means
+// just from. If requestedBy is set, only that user's items in the range are
+// dropped. If owner is set and any selected item was requested by someone
+// else, nothing is removed and ErrNotOwner is returned.
+func (q *Queue) RemoveUpcoming(from, to int, requestedBy, owner string) ([]state.QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	upcoming := q.upcomingLocked()
+	if from == 0 {
+		from, to = 1, upcoming
+	} else if to == 0 {
+		to = from
+	}
+	if from < 1 || to < from || to > upcoming {
+		return nil, ErrOutOfRange
+	}
+
+	var removed []state.QueueItem
+	selected := make(map[int]bool)
+	unpinned := 0
+	for _, i := range q.orderLocked()[from-1 : to] {
+		item := q.items[i]
+		if requestedBy != "" && item.RequestedBy != requestedBy {
+			continue
+		}
+		if owner != "" && item.RequestedBy != owner {
+			return nil, ErrNotOwner
+		}
+		removed = append(removed, item)
+		selected[i] = true
+		if q.pinnedLocked(i) {
+			unpinned++
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	first := q.position + 1
+	kept := q.items[:first:first]
+	for i := first; i < len(q.items); i++ {
+		if !selected[i] {
+			kept = append(kept, q.items[i])
+		}
+	}
+
+	q.items = kept
+	q.pinned -= unpinned
+	for k := range q.items {
+		q.items[k].Position = k + 1
+	}
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Removed %d songs from the queue", len(removed))
+	return removed, nil
+}
+
+// GetUpcomingItems returns copies of the items after the current one, in
+// the order they will play.
+func (q *Queue) GetUpcomingItems() []state.QueueItem {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	order := q.orderLocked()
+	if len(order) == 0 {
+		return nil
+	}
+
+	items := make([]state.QueueItem, len(order))
+	for k, i := range order {
+		items[k] = q.items[i]
+	}
+	return items
+}
+
+// Trim sets where the n-th upcoming item (0 is next) starts and ends playing,
+// in seconds. Zero clears that side of the trim. songID must match the item,
+// so a queue that moved on since it was read is not trimmed by mistake.
+func (q *Queue) Trim(n int, songID int64, start, end int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	order := q.orderLocked()
+	if n < 0 || n >= len(order) {
+		return fmt.Errorf("queue index out of range: %d", n)
+	}
+
+	item := &q.items[order[n]]
+	if item.SongID != songID || item.Song == nil {
+		return fmt.Errorf("queue changed before the song could be trimmed")
+	}
+
+	// The song may be shared with other entries for the same URL, so the
+	// trim goes on a copy.
+	song := *item.Song
+	song.StartOffset = start
+	song.EndOffset = end
+	item.Song = &song
+	q.persister.MarkDirty()
+
+	logger.Info.Printf("Trimmed queued song %s to %d-%d", song.Title, start, end)
+	return nil
+}
+
+// Restart drops already played songs so the current song becomes the head of
+// the queue, and persists the result in one write. It returns the number of
+// songs left in the queue.
+func (q *Queue) Restart() (int, error) {
+	q.mu.Lock()
+	if q.position > 0 && q.position < len(q.items) {
+		q.items = append([]state.QueueItem(nil), q.items[q.position:]...)
+		for k := range q.items {
+			q.items[k].Position = k + 1
+		}
+	}
+	q.position = 0
+	count := len(q.items)
+	q.mu.Unlock()
+
+	err := q.persister.Flush(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("failed to save restarted queue: %w", err)
+	}
+
+	logger.Info.Printf("Queue restarted with %d songs", count)
+	return count, nil
+}
+
+// SkipTo makes the n-th upcoming item, counting the next song as 1, the
+// current one. The current item and those before the n-th are dropped, or
+// with keepSkipped moved to the end of the queue in order. The result is
+// persisted in one write. It returns the new current song.
+func (q *Queue) SkipTo(n int, keepSkipped bool) (*state.Song, error) {
+	q.mu.Lock()
+	if n < 1 || n > q.upcomingLocked() {
+		q.mu.Unlock()
+		return nil, ErrOutOfRange
+	}
+
+	order := q.orderLocked()
+	target := order[n-1]
+	skipped := []state.QueueItem{q.items[q.position]}
+	dropped := map[int]bool{q.position: true, target: true}
+	for _, i := range order[:n-1] {
+		skipped = append(skipped, q.items[i])
+		dropped[i] = true
+	}
+
+	items := append([]state.QueueItem(nil), q.items[:q.position]...)
+	items = append(items, q.items[target])
+	for i := q.position + 1; i < len(q.items); i++ {
+		if !dropped[i] {
+			items = append(items, q.items[i])
+		}
+	}
+	if keepSkipped {
+		items = append(items, skipped...)
+	}
+	q.pinned = max(q.pinned-n, 0)
+	for k := range items {
+		items[k].Position = k + 1
+	}
+	q.items = items
+	current := q.items[q.position].Song
+	q.mu.Unlock()
+
+	err := q.persister.Flush(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save queue after skipping: %w", err)
+	}
+
+	logger.Info.Printf("Skipped %d songs to queue position %d", len(skipped), n)
+	return current, nil
+}
+
+// Close flushes any pending queue changes to the database.
+func (q *Queue) Close(ctx context.Context) error {
+	return q.persister.Close(ctx)
+}
@@ -0,0 +1,189 @@
+package music
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"musicbot/internal/state"
+	"musicbot/internal/urlnorm"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A queue file holds one track URL per line, so a plain list of links is
+// one too. /exportqueue adds a comment line on top with a JSON header
+// naming the tracks and who requested them; lines starting with # are
+// skipped on import.
+const (
+	MaxQueueFileLines = 100
+	MaxQueueFileSize  = 64 << 10
+
+	queueFileComment = "#"
+	queueFileVersion = 1
+)
+
+var (
+	ErrQueueFileTooLarge = fmt.Errorf("queue file is larger than %d KB", MaxQueueFileSize>>10)
+	ErrQueueFileTooLong  = fmt.Errorf("queue file has more than %d lines", MaxQueueFileLines)
+	ErrQueueFileEmpty    = errors.New("queue file has no tracks")
+)
+
+// QueueFileTrack is a track as described in a queue file's header.
+type QueueFileTrack struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+type queueFileHeader struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Tracks     []QueueFileTrack `json:"tracks"`
+}
+
+// MalformedLine is a line of a queue file that isn't a track URL.
+type MalformedLine struct {
+	Number int
+	Text   string
+}
+
+// QueueFile is a parsed queue file: its track URLs in order, without
+// repeats, and the lines that were skipped.
+type QueueFile struct {
+	URLs       []string
+	Repeats    int
+	Malformed  []MalformedLine
+	TotalLines int
+}
+
+// ExportQueue writes the playing song and the upcoming ones as a queue file.
+// It returns the file, how many tracks are in it, and how many uploaded files
+// were left out since they can't be fetched again by URL.
+func (m *Manager) ExportQueue() ([]byte, int, int) {
+	var tracks []QueueFileTrack
+	skipped := 0
+
+	add := func(song *state.Song, requestedBy string) {
+		if !isTrackURL(song.URL) {
+			skipped++
+			return
+		}
+		tracks = append(tracks, QueueFileTrack{URL: song.URL, Title: song.Title, RequestedBy: requestedBy})
+	}
+
+	if song := m.player.GetCurrentSong(); song != nil {
+		add(song, song.RequesterID)
+	}
+	for _, item := range m.GetUpcomingItems() {
+		if item.Song != nil {
+			add(item.Song, item.RequestedBy)
+		}
+	}
+
+	header, _ := json.Marshal(queueFileHeader{
+		Version:    queueFileVersion,
+		ExportedAt: time.Now().UTC(),
+		Tracks:     tracks,
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\n", queueFileComment, header)
+	for _, track := range tracks {
+		buf.WriteString(track.URL + "\n")
+	}
+	return buf.Bytes(), len(tracks), skipped
+}
+
+// ParseQueueFile reads the track URLs from data. Lines that aren't http(s)
+// URLs are collected in Malformed rather than failing the whole file.
+func ParseQueueFile(data []byte) (*QueueFile, error) {
+	if len(data) > MaxQueueFileSize {
+		return nil, ErrQueueFileTooLarge
+	}
+
+	file := &QueueFile{}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), MaxQueueFileSize)
+
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, queueFileComment) {
+			continue
+		}
+
+		file.TotalLines++
+		if file.TotalLines > MaxQueueFileLines {
+			return nil, ErrQueueFileTooLong
+		}
+
+		if !isTrackURL(line) {
+			file.Malformed = append(file.Malformed, MalformedLine{Number: number, Text: line})
+			continue
+		}
+
+		normalized := urlnorm.Normalize(line)
+		if seen[normalized] {
+			file.Repeats++
+			continue
+		}
+		seen[normalized] = true
+		file.URLs = append(file.URLs, normalized)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queue file: %w", err)
+	}
+
+	if file.TotalLines == 0 {
+		return nil, ErrQueueFileEmpty
+	}
+	return file, nil
+}
+
+// FetchQueueFile downloads a queue file attached to a command.
+func FetchQueueFile(ctx context.Context, fileURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch queue file: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch queue file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch queue file: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxQueueFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch queue file: %w", err)
+	}
+	if len(data) > MaxQueueFileSize {
+		return nil, ErrQueueFileTooLarge
+	}
+	return data, nil
+}
+
+// isTrackURL reports whether s is an absolute http(s) URL, the only kind
+// the downloader takes.
+func isTrackURL(s string) bool {
+	if strings.ContainsAny(s, " \t") {
+		return false
+	}
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,238 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// ErrFileMissing is reported for a queued song whose file is gone,
+	// typically evicted by the janitor while the song waited its turn.
+	ErrFileMissing = errors.New("the song's file is missing")
+
+	ErrRepairPending = errors.New("the song is already being downloaded again")
+)
+
+// FileMissing reports whether song is a download whose file is gone. Live
+// streams have no file to lose.
+func (m *Manager) FileMissing(song *state.Song) bool {
+	if song.IsStream {
+		return false
+	}
+	_, err := os.Stat(m.player.filePath(song))
+	return err != nil
+}
+
+// Redownloadable reports whether song can be downloaded again by its URL.
+// Uploaded files can't.
+func Redownloadable(song *state.Song) bool {
+	return strings.HasPrefix(song.URL, "https://") || strings.HasPrefix(song.URL, "http://")
+}
+
+// MissingUpcoming returns the upcoming songs whose files are gone, once per
+// URL, in queue order.
+func (m *Manager) MissingUpcoming() []state.Song {
+	seen := make(map[string]bool)
+	var missing []state.Song
+	for _, item := range m.queue.GetUpcomingItems() {
+		if item.Song == nil || seen[item.Song.URL] || !m.FileMissing(item.Song) {
+			continue
+		}
+		seen[item.Song.URL] = true
+		missing = append(missing, *item.Song)
+	}
+	return missing
+}
+
+// RemoveMissing drops the upcoming songs of url, whose file could not be
+// brought back, and returns how many were removed.
+func (m *Manager) RemoveMissing(url string) int {
+	removed := m.queue.RemoveURL(url)
+	if removed > 0 {
+		m.queueChanged()
+	}
+	return removed
+}
+
+// RepairSong downloads url again within limits, through the same pipeline
+// and retries as a request, but without queueing it: the queued songs of url
+// are pointed at the new file instead. notifier, if set, is told once the
+// file is back or the download failed for good.
+func (m *Manager) RepairSong(url string, limits config.DownloadLimits, notifier RequestNotifier) error {
+	if atomic.LoadInt32(&m.clearing) == 1 {
+		return fmt.Errorf("cannot repair songs while clearing queue")
+	}
+	if m.socketClient == nil || !m.socketClient.IsConnected() {
+		return fmt.Errorf("downloader not available")
+	}
+
+	m.downloadMu.Lock()
+	if m.repairUrls[url] || m.activeDownloads[url] {
+		m.downloadMu.Unlock()
+		return ErrRepairPending
+	}
+	m.repairUrls[url] = true
+	m.requestLimits[url] = limits
+	if notifier != nil {
+		m.notifiers[url] = notifier
+	}
+	m.downloadMu.Unlock()
+
+	atomic.AddInt32(&m.pendingDownloads, 1)
+	logger.Info.Printf("Downloading missing file again: %s (pending: %d)", url, atomic.LoadInt32(&m.pendingDownloads))
+
+	go func() {
+		requestID, err := m.socketClient.SendDownloadRequest(url, "", limits)
+		if err != nil {
+			atomic.AddInt32(&m.pendingDownloads, -1)
+			m.downloadMu.Lock()
+			delete(m.repairUrls, url)
+			delete(m.requestLimits, url)
+			m.downloadMu.Unlock()
+			logger.Error.Printf("Failed to send repair download request: %v", err)
+			if notifier := m.takeNotifier(url); notifier != nil {
+				notifier.Failed(err)
+			}
+			return
+		}
+		m.trackRequest(url, requestID)
+	}()
+
+	return nil
+}
+
+// takeRepair reports whether a finished download of url was a repair, and
+// forgets it.
+func (m *Manager) takeRepair(url string) bool {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+
+	if !m.repairUrls[url] {
+		return false
+	}
+	delete(m.repairUrls, url)
+	delete(m.requestLimits, url)
+	delete(m.downloadAttempts, url)
+	delete(m.downloadRequests, url)
+	return true
+}
+
+// repaired points the queue at a file that was downloaded again and, if
+// playback stopped on the song because its file was gone, starts it.
+func (m *Manager) repaired(song *state.Song) {
+	notifier := m.takeNotifier(song.URL)
+
+	replaced, err := m.queue.ReplaceFile(song)
+	if err != nil {
+		logger.Error.Printf("Failed to put the new file of %s in the queue: %v", song.Title, err)
+		if notifier != nil {
+			notifier.Failed(err)
+		}
+		return
+	}
+	m.queueChanged()
+	logger.Info.Printf("Downloaded %s again for %d queued songs", song.Title, replaced)
+
+	if notifier != nil {
+		notifier.Queued(song, ETA{})
+	}
+
+	m.downloadMu.Lock()
+	stalled := m.stalledURL == song.URL
+	if stalled {
+		m.stalledURL = ""
+	}
+	m.downloadMu.Unlock()
+	if stalled {
+		m.startNextSong()
+	}
+}
+
+// recoverMissing is called when song can't start because its file is gone.
+// The file is downloaded again, once, and the song started when it is back.
+// If that fails, or was already tried, the song is skipped.
+func (m *Manager) recoverMissing(song *state.Song) {
+	if !Redownloadable(song) {
+		m.skipMissing(song, ErrFileMissing)
+		return
+	}
+
+	m.downloadMu.Lock()
+	attempted := m.recoveries[song.URL]
+	m.recoveries[song.URL] = true
+	m.stalledURL = song.URL
+	m.downloadMu.Unlock()
+	if attempted {
+		m.skipMissing(song, ErrFileMissing)
+		return
+	}
+
+	logger.Info.Printf("File of %s is missing, downloading it again before playing", song.Title)
+	err := m.RepairSong(song.URL, m.DownloadLimits(m.GuildID()), &missingRecovery{manager: m, song: song})
+	if errors.Is(err, ErrRepairPending) {
+		// The download under way starts the song when it is done.
+		return
+	}
+	if err != nil {
+		m.skipMissing(song, err)
+	}
+}
+
+// forgetRecovery lets a later automatic re-download of url happen again once
+// the song played.
+func (m *Manager) forgetRecovery(url string) {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+	delete(m.recoveries, url)
+}
+
+// skipMissing moves past a song whose file is gone and tells the guild why.
+func (m *Manager) skipMissing(song *state.Song, reason error) {
+	m.downloadMu.Lock()
+	delete(m.recoveries, song.URL)
+	if m.stalledURL == song.URL {
+		m.stalledURL = ""
+	}
+	m.downloadMu.Unlock()
+
+	logger.Info.Printf("Skipping %s: %v", song.Title, reason)
+	if m.skipNotice != nil {
+		m.skipNotice(m.GuildID(), song, reason)
+	}
+
+	if m.queue.HasNext() {
+		m.playNext()
+	} else {
+		m.queueFinished(m.session.State)
+	}
+}
+
+// missingRecovery follows the automatic re-download of a song that was
+// about to play. Starting it once the file is back is up to repaired.
+type missingRecovery struct {
+	manager *Manager
+	song    *state.Song
+}
+
+func (r *missingRecovery) Queued(*state.Song, ETA) {}
+
+func (r *missingRecovery) Failed(err error) {
+	if errors.Is(err, ErrDownloadCancelled) {
+		// Cleared or left; there is nothing to skip to.
+		m := r.manager
+		m.downloadMu.Lock()
+		delete(m.recoveries, r.song.URL)
+		if m.stalledURL == r.song.URL {
+			m.stalledURL = ""
+		}
+		m.downloadMu.Unlock()
+		return
+	}
+	r.manager.skipMissing(r.song, err)
+}
@@ -0,0 +1,216 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/socket"
+	"musicbot/internal/state"
+	"strings"
+	"time"
+)
+
+// downloadRetryBase is the pause before the second attempt of a download.
+// Each later attempt waits twice as long, plus up to half again as jitter so
+// retries of a playlist don't hit the site in lockstep.
+const downloadRetryBase = 2 * time.Second
+
+// Download error classes. The first four are worth another attempt.
+const (
+	downloadErrorTimeout     = "timeout"
+	downloadErrorServer      = "server"
+	downloadErrorNetwork     = "network"
+	downloadErrorForbidden   = "forbidden"
+	downloadErrorUnavailable = "unavailable"
+	downloadErrorLimit       = "limit"
+	downloadErrorOther       = "other"
+
+	// downloadErrorPlayback is a downloaded file that broke off while
+	// playing, recorded with the download failures so it can be retried.
+	downloadErrorPlayback = "playback"
+)
+
+// A *DownloadError of a video that can't be downloaded at all matches one of
+// these with errors.Is.
+var (
+	ErrVideoUnavailable = errors.New("the video is unavailable")
+	ErrPrivate          = errors.New("the video is private")
+	ErrRegionBlocked    = errors.New("the video is blocked in this region")
+	ErrAgeRestricted    = errors.New("the video is age-restricted")
+)
+
+// DownloadError is a download that failed for good. Temporary is set when
+// every attempt failed for a passing reason, and Unavailable when the video
+// can't be downloaded at all, e.g. because it is private or region-locked.
+// Code is the downloader's socket.Code for the failure, or the one told
+// from Reason when it sent none.
+type DownloadError struct {
+	Reason      string
+	Attempts    int
+	Temporary   bool
+	Unavailable bool
+	Code        string
+}
+
+func (e *DownloadError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("download failed after %d attempts: %s", e.Attempts, e.Reason)
+	}
+	return "download failed: " + e.Reason
+}
+
+func (e *DownloadError) Unwrap() error {
+	switch e.Code {
+	case "":
+		return nil
+	case socket.CodePrivate:
+		return ErrPrivate
+	case socket.CodeRegionBlocked:
+		return ErrRegionBlocked
+	case socket.CodeAgeRestricted:
+		return ErrAgeRestricted
+	}
+	return ErrVideoUnavailable
+}
+
+// classifyDownloadError sorts a downloader error by its message, the way the
+// radio player sorts stream errors.
+func classifyDownloadError(reason string) string {
+	if downloadLimitError(reason, config.DownloadLimits{}) != nil {
+		return downloadErrorLimit
+	}
+
+	lower := strings.ToLower(reason)
+
+	switch {
+	case strings.Contains(lower, "private"),
+		strings.Contains(lower, "geo") && strings.Contains(lower, "block"),
+		strings.Contains(lower, "country"),
+		strings.Contains(lower, "region"),
+		strings.Contains(lower, "copyright"),
+		strings.Contains(lower, "removed"),
+		strings.Contains(lower, "deleted"),
+		strings.Contains(lower, "age") && (strings.Contains(lower, "restrict") || strings.Contains(lower, "verify")),
+		strings.Contains(lower, "premium"),
+		strings.Contains(lower, "members"):
+		return downloadErrorUnavailable
+
+	case strings.Contains(lower, "timeout"),
+		strings.Contains(lower, "timed out"),
+		strings.Contains(lower, "deadline exceeded"):
+		return downloadErrorTimeout
+
+	case strings.Contains(lower, "http error 5"),
+		strings.Contains(lower, "internal server error"),
+		strings.Contains(lower, "bad gateway"),
+		strings.Contains(lower, "service unavailable"):
+		return downloadErrorServer
+
+	case strings.Contains(lower, "connection"),
+		strings.Contains(lower, "reset"),
+		strings.Contains(lower, "refused"),
+		strings.Contains(lower, "network"):
+		return downloadErrorNetwork
+
+	// YouTube answers 403 now and then for a video that downloads fine on
+	// the next try.
+	case strings.Contains(lower, "403"),
+		strings.Contains(lower, "forbidden"):
+		return downloadErrorForbidden
+	}
+
+	return downloadErrorOther
+}
+
+// downloadErrorCode tells the socket.Code of a failure from its message, for
+// the downloader answers that come without one.
+func downloadErrorCode(reason string) string {
+	lower := strings.ToLower(reason)
+
+	switch {
+	case strings.Contains(lower, "private"):
+		return socket.CodePrivate
+	case strings.Contains(lower, "age") && (strings.Contains(lower, "restrict") || strings.Contains(lower, "verify")):
+		return socket.CodeAgeRestricted
+	case strings.Contains(lower, "geo") && strings.Contains(lower, "block"),
+		strings.Contains(lower, "country"),
+		strings.Contains(lower, "region"):
+		return socket.CodeRegionBlocked
+	case classifyDownloadError(reason) == downloadErrorUnavailable:
+		return socket.CodeUnavailable
+	}
+	return ""
+}
+
+// retryable reports whether a download that failed with class may succeed
+// when tried again.
+func retryable(class string) bool {
+	switch class {
+	case downloadErrorTimeout, downloadErrorServer, downloadErrorNetwork, downloadErrorForbidden:
+		return true
+	}
+	return false
+}
+
+// retryDelay is the pause after the given failed attempt, counting from 1.
+func retryDelay(attempt int) time.Duration {
+	delay := downloadRetryBase << (attempt - 1)
+	return delay + rand.N(delay/2)
+}
+
+// recordFailure keeps a download that failed for good for /failures. It runs
+// in the background and only logs its own errors, so it never changes what
+// the requester is told. track is -1 for a single song.
+func (m *Manager) recordFailure(url string, track int, requestedBy, reason string) {
+	m.storeFailure(config.DownloadFailure{
+		URL:         url,
+		Track:       track,
+		Class:       classifyDownloadError(reason),
+		Error:       reason,
+		RequestedBy: requestedBy,
+	})
+}
+
+// recordPlaybackFailure keeps a song that broke off while playing for
+// /failures, like a failed download.
+func (m *Manager) recordPlaybackFailure(song *state.Song, err error) {
+	m.storeFailure(config.DownloadFailure{
+		URL:         song.URL,
+		Track:       -1,
+		Class:       downloadErrorPlayback,
+		Error:       err.Error(),
+		RequestedBy: song.RequesterID,
+	})
+}
+
+func (m *Manager) storeFailure(failure config.DownloadFailure) {
+	failure.GuildID = m.GuildID()
+	failure.At = time.Now()
+	if failure.GuildID == "" {
+		return
+	}
+
+	go func() {
+		if err := m.dbManager.AddDownloadFailure(failure); err != nil {
+			logger.Error.Printf("Failed to record download failure of %s: %v", failure.URL, err)
+		}
+	}()
+}
+
+// downloadError wraps the reason of the last of attempts into a
+// *DownloadError. code is the downloader's code for it, if it sent one.
+func downloadError(reason, code string, attempts int) *DownloadError {
+	class := classifyDownloadError(reason)
+	if code == "" {
+		code = downloadErrorCode(reason)
+	}
+	return &DownloadError{
+		Reason:      reason,
+		Attempts:    attempts,
+		Temporary:   code == "" && retryable(class),
+		Unavailable: code != "",
+		Code:        code,
+	}
+}
@@ -0,0 +1,57 @@
+package music
+
+import (
+	"context"
+	"musicbot/internal/logger"
+	"musicbot/internal/radio"
+	"musicbot/internal/state"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session ties a music manager to the guild it plays in. Every guild has its
+// own manager, so queues, players and downloads never mix between guilds.
+type Session struct {
+	State           *state.Guild
+	Radio           *radio.Manager
+	VoiceConnection func() *discordgo.VoiceConnection
+}
+
+// GuildID returns the guild the manager plays in.
+func (m *Manager) GuildID() string {
+	return m.session.State.ID()
+}
+
+// Release winds the manager down after the bot was removed from its guild:
+// its downloads are cancelled, the song stops without moving on and the
+// saved queue is emptied. The manager isn't used afterwards.
+func (m *Manager) Release() {
+	guildID := m.GuildID()
+
+	m.undoMu.Lock()
+	if m.cleared != nil {
+		m.cleared.timer.Stop()
+		m.cleared = nil
+	}
+	m.undoMu.Unlock()
+
+	m.cancelPlaylists(ErrPlaylistCancelled)
+	m.CancelDownloads()
+	m.cancelIdle("guild removed")
+
+	m.player.StopWithoutEvent()
+	m.stopEvents()
+
+	if !m.queue.IsEmpty() {
+		if err := m.queue.Clear(); err != nil {
+			logger.Error.Printf("Failed to clear the queue of removed guild %s: %v", guildID, err)
+		}
+	}
+	m.clearReservations()
+
+	if err := m.queue.Close(context.Background()); err != nil {
+		logger.Error.Printf("Failed to save the queue of removed guild %s: %v", guildID, err)
+	}
+
+	logger.Info.Printf("Music released from removed guild %s", guildID)
+}
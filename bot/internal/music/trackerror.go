@@ -0,0 +1,111 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/audio"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// earlyEndTolerance is how much of a song may be missing at the end, as a
+// share of its length, before the end counts as a failure. Durations
+// reported by sites are a little off often enough.
+const earlyEndTolerance = 0.1
+
+// TrackError is a song whose playback broke off: ffmpeg exited with an
+// error, or the audio ran out well before the song's duration, as happens
+// when ffmpeg gives up on a decode error part way into the file.
+type TrackError struct {
+	// Position is how far into the file playback got, and Expected where
+	// it should have ended.
+	Position time.Duration
+	Expected time.Duration
+	ExitCode int
+
+	// Stderr is the end of ffmpeg's error output.
+	Stderr string
+}
+
+func (e *TrackError) Error() string {
+	reason := fmt.Sprintf("playback ended at %s of %s", e.Position.Truncate(time.Second), e.Expected.Truncate(time.Second))
+	if e.ExitCode != 0 {
+		reason += fmt.Sprintf(", ffmpeg exited with code %d", e.ExitCode)
+	}
+	if e.Stderr != "" {
+		reason += ": " + e.Stderr
+	}
+	return reason
+}
+
+// checkTrackEnd tells a clean end of song from a broken off one, once ffmpeg
+// ran out of audio at position. waitErr is how ffmpeg exited. Live streams
+// have no length to compare with and are never reported.
+func checkTrackEnd(song *state.Song, position time.Duration, waitErr error, stderr *audio.StderrTail) *TrackError {
+	if song.IsStream {
+		return nil
+	}
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	var expected, shortfall time.Duration
+	if song.Duration > 0 {
+		expected = time.Duration(song.StartOffset+song.PlayLength()) * time.Second
+		shortfall = expected - position
+	}
+	early := shortfall > time.Duration(float64(song.PlayLength())*earlyEndTolerance*float64(time.Second))
+
+	if exitCode == 0 && !early {
+		return nil
+	}
+	return &TrackError{
+		Position: position,
+		Expected: expected,
+		ExitCode: exitCode,
+		Stderr:   stderr.LastLine(),
+	}
+}
+
+// onSongError handles a song whose playback broke off. It is played once
+// more from where it stopped; if it breaks off again, it is recorded with the
+// download failures, announced, and passed over like a song that ended.
+func (m *Manager) onSongError(song *state.Song, trackErr *TrackError) {
+	if m.stateManager.IsShuttingDown() || atomic.LoadInt32(&m.clearing) == 1 || !m.AreAutoHandlersEnabled() {
+		return
+	}
+
+	logger.Error.Printf("Playback of %s broke off: %v", song.Title, trackErr)
+
+	if m.retriedSong.Swap(song) != song {
+		if vc := m.getVoiceConnection(); vc != nil {
+			logger.Info.Printf("Playing %s again from %s", song.Title, trackErr.Position.Truncate(time.Second))
+			go func() {
+				err := m.play(vc, song, handover{offset: trackErr.Position, resumed: true})
+				if err != nil {
+					logger.Error.Printf("Failed to play %s again: %v", song.Title, err)
+					m.songFailed(song, trackErr)
+				}
+			}()
+			return
+		}
+	}
+
+	m.songFailed(song, trackErr)
+}
+
+// songFailed gives up on a song that broke off twice.
+func (m *Manager) songFailed(song *state.Song, trackErr *TrackError) {
+	m.retriedSong.Store(nil)
+	m.recordPlaybackFailure(song, trackErr)
+	if m.skipNotice != nil {
+		m.skipNotice(m.GuildID(), song, trackErr)
+	}
+	m.onSongEnd(song, false)
+}
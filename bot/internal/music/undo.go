@@ -0,0 +1,116 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ClearUndoWindow is how long the songs taken off by /clear can be put back.
+const ClearUndoWindow = 2 * time.Minute
+
+var ErrNothingToRestore = errors.New("there is no cleared queue to restore")
+
+// clearedQueue is what the queue held when it was cleared, from the
+// song that was playing onwards.
+type clearedQueue struct {
+	items []state.QueueItem
+	timer *time.Timer
+}
+
+// stashCleared keeps the current and upcoming items of the queue until the
+// undo window runs out. A newer clear replaces the older one.
+func (m *Manager) stashCleared() {
+	position := m.queue.GetPosition()
+	items := m.queue.GetItems()
+	if position >= len(items) {
+		return
+	}
+
+	stash := &clearedQueue{}
+	for _, item := range items[position:] {
+		if item.Song == nil {
+			continue
+		}
+		song := *item.Song
+		item.Song = &song
+		stash.items = append(stash.items, item)
+	}
+	if len(stash.items) == 0 {
+		return
+	}
+
+	m.undoMu.Lock()
+	defer m.undoMu.Unlock()
+	if m.cleared != nil {
+		m.cleared.timer.Stop()
+	}
+	stash.timer = time.AfterFunc(ClearUndoWindow, func() {
+		m.undoMu.Lock()
+		defer m.undoMu.Unlock()
+		if m.cleared == stash {
+			m.cleared = nil
+		}
+	})
+	m.cleared = stash
+}
+
+// CanRestoreCleared reports whether there is a cleared queue that can still
+// be put back.
+func (m *Manager) CanRestoreCleared() bool {
+	m.undoMu.Lock()
+	defer m.undoMu.Unlock()
+	return m.cleared != nil
+}
+
+// RestoreCleared puts the songs of the last cleared queue back,
+// after anything queued since, and starts playing if nothing is. Songs
+// whose files were deleted in the meantime are skipped, as is the rest once
+// the queue is full; both count as missing.
+func (m *Manager) RestoreCleared() (restored, missing int, err error) {
+	if atomic.LoadInt32(&m.clearing) == 1 {
+		return 0, 0, fmt.Errorf("cannot restore the queue while it is being cleared")
+	}
+
+	m.undoMu.Lock()
+	stash := m.cleared
+	if stash != nil {
+		stash.timer.Stop()
+		m.cleared = nil
+	}
+	m.undoMu.Unlock()
+	if stash == nil {
+		return 0, 0, ErrNothingToRestore
+	}
+
+	for idx, item := range stash.items {
+		song := item.Song
+		if !song.IsStream {
+			if _, err := os.Stat(m.player.filePath(song)); err != nil {
+				missing++
+				continue
+			}
+		}
+		if err := m.queue.Add(song, item.RequestedBy); err != nil {
+			if errors.Is(err, ErrQueueFull) {
+				missing += len(stash.items) - idx
+				break
+			}
+			logger.Error.Printf("Failed to restore %s to the queue: %v", song.Title, err)
+			missing++
+			continue
+		}
+		restored++
+	}
+
+	if restored > 0 {
+		m.queueChanged()
+		m.handleQueueAddition()
+	}
+	logger.Info.Printf("Restored %d cleared songs (%d missing)", restored, missing)
+	return restored, missing, nil
+}
@@ -0,0 +1,198 @@
+package music
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"musicbot/internal/state"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// uploadTimeout bounds fetching an attachment from Discord's CDN.
+const uploadTimeout = 2 * time.Minute
+
+var ErrUnsupportedUpload = errors.New("unsupported upload file type")
+
+// UploadTooLargeError is returned when an upload is over the guild's size
+// limit.
+type UploadTooLargeError struct {
+	LimitMB int
+}
+
+func (e *UploadTooLargeError) Error() string {
+	return fmt.Sprintf("upload exceeds %d MB", e.LimitMB)
+}
+
+// Upload is an audio file attached to a /playfile command.
+type Upload struct {
+	URL         string
+	Filename    string
+	ContentType string
+	Size        int
+}
+
+// Validate checks the upload's type and reported size before anything is
+// downloaded.
+func (u Upload) Validate(limits config.DownloadLimits) error {
+	if !config.IsTrackFile(u.Filename) {
+		return ErrUnsupportedUpload
+	}
+
+	// Discord leaves the content type empty for some files; the extension
+	// decides then.
+	contentType := strings.ToLower(u.ContentType)
+	if contentType != "" && !strings.HasPrefix(contentType, "audio/") && !strings.HasPrefix(contentType, "video/") {
+		return ErrUnsupportedUpload
+	}
+
+	if u.Size > limits.MaxSizeMB<<20 {
+		return &UploadTooLargeError{LimitMB: limits.MaxSizeMB}
+	}
+	return nil
+}
+
+// QueueUpload stores upload in the download directory under a name derived
+// from its content and queues it for requestedBy. Uploading the same file
+// again reuses the stored copy and its songs row.
+func (m *Manager) QueueUpload(upload Upload, requestedBy, uploaderName string, limits config.DownloadLimits) (*state.Song, error) {
+	if err := upload.Validate(limits); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.RemainingCapacity(requestedBy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	path, hash, err := m.storeUpload(ctx, upload, limits, m.stateManager.GetConfig().DownloadDir, "upload_")
+	if err != nil {
+		return nil, err
+	}
+
+	song := &state.Song{
+		Title:       strings.TrimSuffix(upload.Filename, filepath.Ext(upload.Filename)),
+		Artist:      uploaderName,
+		URL:         "upload:" + hash,
+		Platform:    "upload",
+		FilePath:    path,
+		Duration:    probeDuration(ctx, path),
+		RequesterID: requestedBy,
+	}
+	if info, err := os.Stat(path); err == nil {
+		song.FileSize = info.Size()
+	}
+
+	if err := m.queue.Add(song, requestedBy); err != nil {
+		return nil, err
+	}
+	m.queueChanged()
+
+	logger.Info.Printf("Upload added to queue: %s by %s", song.Title, requestedBy)
+
+	if atomic.LoadInt32(&m.clearing) == 0 {
+		go m.handleQueueAddition()
+	}
+
+	return song, nil
+}
+
+// storeUpload downloads the attachment into dir and moves it to a file named
+// after prefix and its hash, returning the path and the hash.
+func (m *Manager) storeUpload(ctx context.Context, upload Upload, limits config.DownloadLimits, dir, prefix string) (string, string, error) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, upload.URL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to fetch upload: %s", resp.Status)
+	}
+
+	// Dot files are skipped by the janitor, so a partial upload is never
+	// mistaken for an orphan.
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	maxBytes := int64(limits.MaxSizeMB) << 20
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(resp.Body, maxBytes+1))
+	closeErr := tmp.Close()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	if closeErr != nil {
+		return "", "", fmt.Errorf("failed to store upload: %w", closeErr)
+	}
+	if written > maxBytes {
+		return "", "", &UploadTooLargeError{LimitMB: limits.MaxSizeMB}
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	path := filepath.Join(dir, prefix+hash[:16]+strings.ToLower(filepath.Ext(upload.Filename)))
+
+	if _, err := os.Stat(path); err == nil {
+		logger.Info.Printf("Upload already stored: %s", path)
+		return path, hash, nil
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	return path, hash, nil
+}
+
+// probeDuration asks ffprobe how long the file is in whole seconds,
+// returning 0 if it can't tell.
+func probeDuration(ctx context.Context, path string) int {
+	return int(probeLength(ctx, path).Seconds())
+}
+
+// probeLength asks ffprobe how long the file is, returning 0 if it can't
+// tell.
+func probeLength(ctx context.Context, path string) time.Duration {
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		logger.Debug.Printf("Failed to probe duration of %s: %v", path, err)
+		return 0
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -2,25 +2,208 @@ package permissions
 
 import (
 	"fmt"
+	"musicbot/internal/discordapi"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 type Manager struct {
-	config Config
+	defaults Config
+	guilds   map[string]Config
+	roleIDs  map[string]RoleIDs
+	djOnly   map[string]bool
+	owners   map[string]bool
+	mu       sync.RWMutex
+
+	// roleName looks up the current name of a role, for messages.
+	roleName func(guildID, roleID string) (string, bool)
 }
 
-func NewManager(config Config) *Manager {
+// NewManager uses the role names in guilds for the listed guilds and the
+// defaults everywhere else.
+func NewManager(defaults Config, guilds map[string]Config) *Manager {
 	return &Manager{
-		config: config,
+		defaults: defaults,
+		guilds:   copyGuilds(guilds),
+		roleIDs:  make(map[string]RoleIDs),
+		djOnly:   make(map[string]bool),
+		owners:   make(map[string]bool),
+	}
+}
+
+// SetOwners makes userIDs the bot's owners, the only users at LevelOwner.
+func (m *Manager) SetOwners(userIDs []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owners = make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		m.owners[userID] = true
+	}
+}
+
+// Owners returns the bot's owners, sorted.
+func (m *Manager) Owners() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	owners := make([]string, 0, len(m.owners))
+	for userID := range m.owners {
+		owners = append(owners, userID)
+	}
+	slices.Sort(owners)
+	return owners
+}
+
+// IsOwner reports whether userID is one of the bot's owners.
+func (m *Manager) IsOwner(userID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.owners[userID]
+}
+
+// SetRoleLookup sets how role names are looked up for roles stored by ID.
+func (m *Manager) SetRoleLookup(lookup func(guildID, roleID string) (string, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roleName = lookup
+}
+
+// RoleIDs returns the roles guildID picked by ID.
+func (m *Manager) RoleIDs(guildID string) RoleIDs {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.roleIDs[guildID]
+}
+
+// ForgetGuild drops the roles and DJ-only mode guildID set, after its
+// settings were purged. Role names from the config file are kept.
+func (m *Manager) ForgetGuild(guildID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.roleIDs, guildID)
+	delete(m.djOnly, guildID)
+}
+
+// SetRoleID ties level in guildID to roleID. An empty roleID goes back to
+// matching by name.
+func (m *Manager) SetRoleID(guildID string, level Level, roleID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := m.roleIDs[guildID]
+	switch level {
+	case LevelDJ:
+		roles.DJ = roleID
+	case LevelAdmin:
+		roles.Admin = roleID
+	default:
+		return
+	}
+
+	if roles == (RoleIDs{}) {
+		delete(m.roleIDs, guildID)
+	} else {
+		m.roleIDs[guildID] = roles
+	}
+}
+
+// RoleName returns the current name of roleID, if it still exists.
+func (m *Manager) RoleName(guildID, roleID string) (string, bool) {
+	m.mu.RLock()
+	lookup := m.roleName
+	m.mu.RUnlock()
+	if lookup == nil || roleID == "" {
+		return "", false
+	}
+	return lookup(guildID, roleID)
+}
+
+// DJOnly reports whether music commands are limited to the DJ role in
+// guildID.
+func (m *Manager) DJOnly(guildID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.djOnly[guildID]
+}
+
+// SetDJOnly turns DJ-only mode on or off for guildID.
+func (m *Manager) SetDJOnly(guildID string, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled {
+		m.djOnly[guildID] = true
+	} else {
+		delete(m.djOnly, guildID)
 	}
 }
 
-func (m *Manager) HasPermission(session *discordgo.Session, guildID, userID string, requiredLevel Level) (bool, error) {
+// HasDJRole reports whether guild still has the role DJ permission is tied
+// to. Without a DJ role there is nothing to look for.
+func (m *Manager) HasDJRole(guild *discordgo.Guild) bool {
+	if roleID := m.RoleIDs(guild.ID).DJ; roleID != "" {
+		for _, role := range guild.Roles {
+			if role.ID == roleID {
+				return true
+			}
+		}
+		return false
+	}
+
+	name := m.Config(guild.ID).DJRoleName
+	if name == "" {
+		return true
+	}
+	for _, role := range guild.Roles {
+		if strings.EqualFold(role.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns the role names in effect for guildID. Names a guild leaves
+// empty fall back to the defaults.
+func (m *Manager) Config(guildID string) Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config := m.defaults
+	if guild, ok := m.guilds[guildID]; ok {
+		if guild.DJRoleName != "" {
+			config.DJRoleName = guild.DJRoleName
+		}
+		if guild.AdminRoleName != "" {
+			config.AdminRoleName = guild.AdminRoleName
+		}
+	}
+	return config
+}
+
+// UpdateConfig replaces the role names, e.g. after a config reload.
+func (m *Manager) UpdateConfig(defaults Config, guilds map[string]Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaults = defaults
+	m.guilds = copyGuilds(guilds)
+}
+
+func copyGuilds(guilds map[string]Config) map[string]Config {
+	copied := make(map[string]Config, len(guilds))
+	for id, config := range guilds {
+		copied[id] = config
+	}
+	return copied
+}
+
+func (m *Manager) HasPermission(session discordapi.Session, guildID, userID string, requiredLevel Level) (bool, error) {
 	if requiredLevel == LevelUser {
 		return true, nil
 	}
+	if requiredLevel == LevelOwner {
+		return m.IsOwner(userID), nil
+	}
 
 	member, err := session.GuildMember(guildID, userID)
 	if err != nil {
@@ -32,69 +215,78 @@ func (m *Manager) HasPermission(session *discordgo.Session, guildID, userID stri
 		return false, fmt.Errorf("failed to get guild: %w", err)
 	}
 
-	userRoles := make(map[string]bool)
+	userRoles := memberRoles{ids: make(map[string]bool), names: make(map[string]bool)}
 	for _, roleID := range member.Roles {
+		userRoles.ids[roleID] = true
 		for _, guildRole := range guild.Roles {
 			if guildRole.ID == roleID {
-				userRoles[strings.ToLower(guildRole.Name)] = true
+				userRoles.names[strings.ToLower(guildRole.Name)] = true
 				break
 			}
 		}
 	}
 
+	config := m.Config(guildID)
+	roleIDs := m.RoleIDs(guildID)
+
 	switch requiredLevel {
 	case LevelDJ:
-		return m.hasDJPermission(userRoles), nil
+		return hasDJPermission(config, roleIDs, userRoles), nil
 	case LevelAdmin:
-		return m.hasAdminPermission(userRoles), nil
+		return hasAdminPermission(config, roleIDs, userRoles), nil
 	default:
 		return false, fmt.Errorf("unknown permission level: %v", requiredLevel)
 	}
 }
 
-func (m *Manager) hasDJPermission(userRoles map[string]bool) bool {
-	if m.config.DJRoleName != "" && userRoles[strings.ToLower(m.config.DJRoleName)] {
-		return true
-	}
-	return m.hasAdminPermission(userRoles)
+// memberRoles are a member's roles by ID and by lowercased name.
+type memberRoles struct {
+	ids   map[string]bool
+	names map[string]bool
 }
 
-func (m *Manager) hasAdminPermission(userRoles map[string]bool) bool {
-	if m.config.AdminRoleName != "" && userRoles[strings.ToLower(m.config.AdminRoleName)] {
+func hasDJPermission(config Config, roleIDs RoleIDs, userRoles memberRoles) bool {
+	if roleIDs.DJ != "" {
+		if userRoles.ids[roleIDs.DJ] {
+			return true
+		}
+	} else if config.DJRoleName != "" && userRoles.names[strings.ToLower(config.DJRoleName)] {
 		return true
 	}
-	return userRoles["administrator"] || userRoles["admin"]
+	return hasAdminPermission(config, roleIDs, userRoles)
 }
 
-func (m *Manager) CheckPermissionWithResponse(session *discordgo.Session, interaction *discordgo.InteractionCreate, requiredLevel Level) (bool, error) {
-	hasPermission, err := m.HasPermission(session, interaction.GuildID, interaction.Member.User.ID, requiredLevel)
-	if err != nil {
-		return false, err
+// hasAdminPermission checks the admin role. Guilds that picked one by ID
+// get only that; the others keep the name fallbacks.
+func hasAdminPermission(config Config, roleIDs RoleIDs, userRoles memberRoles) bool {
+	if roleIDs.Admin != "" {
+		return userRoles.ids[roleIDs.Admin]
 	}
-
-	if !hasPermission {
-		response := fmt.Sprintf("❌ You need %s permissions to use this command.", requiredLevel.String())
-		_, err = session.InteractionResponseEdit(interaction.Interaction, &discordgo.WebhookEdit{
-			Content: &response,
-		})
-		return false, err
+	if config.AdminRoleName != "" && userRoles.names[strings.ToLower(config.AdminRoleName)] {
+		return true
 	}
-
-	return true, nil
+	return userRoles.names["administrator"] || userRoles.names["admin"]
 }
 
-func (m *Manager) GetRequiredRoleName(level Level) string {
+func (m *Manager) GetRequiredRoleName(guildID string, level Level) string {
+	if name, ok := m.RoleName(guildID, m.RoleIDs(guildID).Get(level)); ok {
+		return name
+	}
+
+	config := m.Config(guildID)
 	switch level {
 	case LevelDJ:
-		if m.config.DJRoleName != "" {
-			return m.config.DJRoleName
+		if config.DJRoleName != "" {
+			return config.DJRoleName
 		}
 		return "DJ"
 	case LevelAdmin:
-		if m.config.AdminRoleName != "" {
-			return m.config.AdminRoleName
+		if config.AdminRoleName != "" {
+			return config.AdminRoleName
 		}
 		return "Admin"
+	case LevelOwner:
+		return "Bot owner"
 	default:
 		return "User"
 	}
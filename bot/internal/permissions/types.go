@@ -6,6 +6,9 @@ const (
 	LevelUser Level = iota
 	LevelDJ
 	LevelAdmin
+	// LevelOwner is for whoever runs the bot, listed in config.json. It
+	// isn't tied to a role, since owners change what every guild gets.
+	LevelOwner
 )
 
 func (l Level) String() string {
@@ -16,6 +19,8 @@ func (l Level) String() string {
 		return "DJ"
 	case LevelAdmin:
 		return "Admin"
+	case LevelOwner:
+		return "Owner"
 	default:
 		return "Unknown"
 	}
@@ -25,3 +30,23 @@ type Config struct {
 	DJRoleName    string
 	AdminRoleName string
 }
+
+// RoleIDs are the roles a guild picked with /setdjrole and /setadminrole.
+// They are matched by ID, so renaming a role doesn't break them. A level
+// without one falls back to matching roles by the name in Config.
+type RoleIDs struct {
+	DJ    string
+	Admin string
+}
+
+// Get returns the role ID for level, or "".
+func (r RoleIDs) Get(level Level) string {
+	switch level {
+	case LevelDJ:
+		return r.DJ
+	case LevelAdmin:
+		return r.Admin
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,252 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/config"
+	"musicbot/internal/guilds"
+	"musicbot/internal/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// RotateInterval is how long each idle template is shown.
+	RotateInterval = 3 * time.Minute
+
+	// maxStatusLength is the longest status Discord shows.
+	maxStatusLength = 128
+)
+
+// Placeholders lists what templates can contain, for /presence to show.
+var Placeholders = []string{"{title}", "{artist}", "{queue}", "{stream}", "{guilds}", "{uptime}"}
+
+// Kind is which template the bot's status is drawn from.
+type Kind string
+
+const (
+	KindPlaying Kind = "playing"
+	KindRadio   Kind = "radio"
+	KindIdle    Kind = "idle"
+)
+
+// Rotator keeps the bot's status in step with what it is doing: the song
+// playing, the radio, or while neither plays, the idle templates in turn.
+// The status is seen in every guild the bot is in, so songs and radio are
+// only shown while it is in a single guild.
+type Rotator struct {
+	session   *discordgo.Session
+	guilds    *guilds.Registry
+	startedAt time.Time
+
+	templates config.PresenceTemplates
+	idleIndex int
+	last      string
+	mu        sync.Mutex
+
+	refresh  chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func New(session *discordgo.Session, guildRegistry *guilds.Registry, templates config.PresenceTemplates) *Rotator {
+	return &Rotator{
+		session:   session,
+		guilds:    guildRegistry,
+		startedAt: time.Now(),
+		templates: templates,
+		refresh:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start updates the status in the background: whenever Refresh is called
+// and every RotateInterval, moving on to the next idle template.
+func (r *Rotator) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(RotateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-r.refresh:
+				r.update(false)
+			case <-ticker.C:
+				r.update(true)
+			}
+		}
+	}()
+}
+
+// Refresh updates the status right away, such as when a song starts.
+func (r *Rotator) Refresh() {
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Rotator) Templates() config.PresenceTemplates {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyTemplates(r.templates)
+}
+
+// SetTemplates replaces the templates and shows the new status.
+func (r *Rotator) SetTemplates(templates config.PresenceTemplates) {
+	r.mu.Lock()
+	r.templates = copyTemplates(templates)
+	r.idleIndex = 0
+	r.mu.Unlock()
+	r.Refresh()
+}
+
+// Render fills in the placeholders of template with what is playing now.
+func (r *Rotator) Render(template string) string {
+	var title, artist, stream string
+	var queued int
+	if guild := r.soleGuild(); guild != nil {
+		if song := guild.Music.GetCurrentSong(); song != nil && guild.Music.IsPlaying() {
+			title, artist = song.Title, song.Artist
+			queued = len(guild.Music.GetUpcomingItems())
+		}
+		if guild.Radio.IsPlaying() {
+			stream = guild.Radio.StreamName()
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		"{title}", title,
+		"{artist}", artist,
+		"{queue}", strconv.Itoa(queued),
+		"{stream}", stream,
+		"{guilds}", strconv.Itoa(len(r.session.State.Guilds)),
+		"{uptime}", formatUptime(time.Since(r.startedAt)),
+	)
+
+	text := strings.TrimSpace(replacer.Replace(template))
+	if runes := []rune(text); len(runes) > maxStatusLength {
+		text = string(runes[:maxStatusLength-1]) + "…"
+	}
+	return text
+}
+
+// soleGuild returns the guild the bot is in, or nil while it is in several,
+// where one guild's song would show up in all the others.
+func (r *Rotator) soleGuild() *guilds.Guild {
+	r.session.State.RLock()
+	var guildID string
+	if len(r.session.State.Guilds) == 1 {
+		guildID = r.session.State.Guilds[0].ID
+	}
+	r.session.State.RUnlock()
+
+	if guildID == "" {
+		return nil
+	}
+	return r.guilds.Get(guildID)
+}
+
+// ShowsPlayback reports whether the status can show what plays, which it
+// only does while the bot is in a single guild.
+func (r *Rotator) ShowsPlayback() bool {
+	return r.soleGuild() != nil
+}
+
+// Current is the kind of status that applies now.
+func (r *Rotator) Current() Kind {
+	guild := r.soleGuild()
+	switch {
+	case guild == nil:
+		return KindIdle
+	case guild.Music.IsPlaying() && guild.Music.GetCurrentSong() != nil:
+		return KindPlaying
+	case guild.Radio.IsPlaying():
+		return KindRadio
+	default:
+		return KindIdle
+	}
+}
+
+// update sets the status, moving on to the next idle template if rotate is
+// set. The status is only sent when it changed, as Discord rate limits it.
+func (r *Rotator) update(rotate bool) {
+	kind := r.Current()
+
+	r.mu.Lock()
+	var template string
+	switch kind {
+	case KindPlaying:
+		template = r.templates.Playing
+	case KindRadio:
+		template = r.templates.Radio
+	default:
+		if len(r.templates.Idle) > 0 {
+			if rotate {
+				r.idleIndex = (r.idleIndex + 1) % len(r.templates.Idle)
+			}
+			template = r.templates.Idle[r.idleIndex%len(r.templates.Idle)]
+		}
+	}
+	r.mu.Unlock()
+
+	status := r.Render(template)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if status == r.last {
+		return
+	}
+
+	if err := r.session.UpdateGameStatus(0, status); err != nil {
+		logger.Error.Printf("Failed to update status: %v", err)
+		return
+	}
+	r.last = status
+}
+
+func (r *Rotator) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Rotator) Name() string {
+	return "PresenceRotator"
+}
+
+func copyTemplates(templates config.PresenceTemplates) config.PresenceTemplates {
+	templates.Idle = append([]string(nil), templates.Idle...)
+	return templates
+}
+
+// formatUptime is a short uptime for a status, such as "3d 4h" or "12m".
+func formatUptime(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d / time.Hour % 24)
+	minutes := int(d / time.Minute % 60)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,65 @@
+package presence
+
+import (
+	"context"
+	"musicbot/internal/config"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/guilds"
+	"musicbot/internal/music/musictest"
+	"musicbot/internal/radio"
+	"musicbot/internal/state"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSongIsOnlyShownInItsOwnGuild(t *testing.T) {
+	musictest.FakeFFmpeg(t)
+	vc := musictest.VoiceConnection(t)
+
+	dm, err := config.NewMemoryDatabaseManager(strings.ReplaceAll(t.Name(), "/", "_"))
+	if err != nil {
+		t.Fatalf("NewMemoryDatabaseManager: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	musictest.SaveQueue(t, dm, "guildA", musictest.Song(t, "songA"))
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	if err := session.State.GuildAdd(&discordgo.Guild{ID: "guildA"}); err != nil {
+		t.Fatal(err)
+	}
+	registry := guilds.NewRegistry(discordapi.New(session), state.NewManager(state.Config{}), radio.NewStreamManager(nil), dm, nil)
+	t.Cleanup(func() { registry.Shutdown(context.Background()) })
+	r := New(session, registry, config.DefaultPresenceTemplates())
+
+	if err := registry.Get("guildA").Music.Start(vc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Current(); got != KindPlaying {
+		t.Errorf("in one guild, Current() = %s, want %s", got, KindPlaying)
+	}
+	if got := r.Render("{title} ({queue})"); got != "songA (0)" {
+		t.Errorf("in one guild, Render = %q, want %q", got, "songA (0)")
+	}
+
+	// Once guildB can see the status, guildA's song is no longer shown.
+	if err := session.State.GuildAdd(&discordgo.Guild{ID: "guildB"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Current(); got != KindIdle {
+		t.Errorf("in two guilds, Current() = %s, want %s", got, KindIdle)
+	}
+	if got := r.Render("{title}|{artist}|{guilds}"); got != "||2" {
+		t.Errorf("in two guilds, Render = %q, want %q", got, "||2")
+	}
+	if r.ShowsPlayback() {
+		t.Error("ShowsPlayback in two guilds")
+	}
+
+	// Give the song time to start sending audio before it is stopped.
+	time.Sleep(100 * time.Millisecond)
+	registry.StopPlayback("guildA")
+}
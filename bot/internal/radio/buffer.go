@@ -0,0 +1,69 @@
+package radio
+
+import (
+	"context"
+	"time"
+
+	"musicbot/internal/audio"
+)
+
+const (
+	// bufferDuration is how much decoded audio the radio keeps ahead of the
+	// encoder, to play through while a stream stalls or reconnects.
+	bufferDuration = 2 * time.Second
+
+	bufferFrames = int(bufferDuration / audio.FrameDuration)
+
+	// refillFrames is how full the buffer has to be again after it ran dry
+	// before playback resumes, so a trickle of data doesn't stutter.
+	refillFrames = bufferFrames / 2
+)
+
+// jitterBuffer holds decoded PCM frames between the stream and the encoder.
+// It is bounded: Push waits while it is full. The frames come from the audio
+// frame pool and whoever takes one out returns it.
+type jitterBuffer struct {
+	frames chan *audio.PCMFrame
+}
+
+func newJitterBuffer() *jitterBuffer {
+	return &jitterBuffer{frames: make(chan *audio.PCMFrame, bufferFrames)}
+}
+
+// Push adds a frame, waiting for room until ctx is cancelled. A frame that
+// didn't make it in stays the caller's.
+func (b *jitterBuffer) Push(ctx context.Context, frame *audio.PCMFrame) error {
+	select {
+	case b.frames <- frame:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pop takes the oldest frame, if there is one.
+func (b *jitterBuffer) Pop() (*audio.PCMFrame, bool) {
+	select {
+	case frame := <-b.frames:
+		return frame, true
+	default:
+		return nil, false
+	}
+}
+
+func (b *jitterBuffer) Len() int {
+	return len(b.frames)
+}
+
+// Reset drops everything buffered, so a new station doesn't start with the
+// tail of the old one.
+func (b *jitterBuffer) Reset() {
+	for {
+		select {
+		case frame := <-b.frames:
+			audio.PutFrame(frame)
+		default:
+			return
+		}
+	}
+}
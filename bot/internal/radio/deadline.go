@@ -0,0 +1,33 @@
+package radio
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// frameReadTimeout is how long the stream may go without producing audio
+// before it counts as stalled.
+const frameReadTimeout = 5 * time.Second
+
+// deadlineReader gives every read from ffmpeg's output a deadline, so a
+// stalled stream fails the read instead of blocking it. A reader that can't
+// take a deadline is read as is.
+type deadlineReader struct {
+	r       io.Reader
+	timeout time.Duration
+}
+
+func newDeadlineReader(r io.Reader, timeout time.Duration) io.Reader {
+	return &deadlineReader{r: r, timeout: timeout}
+}
+
+func (d *deadlineReader) Read(buf []byte) (int, error) {
+	if f, ok := d.r.(*os.File); ok {
+		if err := f.SetReadDeadline(time.Now().Add(d.timeout)); err != nil && !errors.Is(err, os.ErrNoDeadline) {
+			return 0, err
+		}
+	}
+	return d.r.Read(buf)
+}
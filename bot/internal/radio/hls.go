@@ -0,0 +1,26 @@
+package radio
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// hlsContentTypes are the content types HLS playlists are served with.
+var hlsContentTypes = map[string]bool{
+	"application/vnd.apple.mpegurl": true,
+	"application/x-mpegurl":         true,
+	"audio/mpegurl":                 true,
+	"audio/x-mpegurl":               true,
+}
+
+// isHLS reports whether a stream is an HLS playlist, going by its URL and,
+// once the stream has answered, its content type. HLS can't be piped into
+// ffmpeg: it is a playlist of segments that ffmpeg has to fetch itself.
+func isHLS(streamURL, contentType string) bool {
+	if u, err := url.Parse(streamURL); err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".m3u8") {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && hlsContentTypes[strings.ToLower(mediaType)]
+}
@@ -0,0 +1,83 @@
+package radio
+
+import (
+	"io"
+	"strings"
+)
+
+// maxICYMetaInt bounds the metadata interval a server can ask for, so a
+// bogus header can't make the reader buffer huge amounts of audio.
+const maxICYMetaInt = 1 << 20
+
+// icyReader strips the SHOUTcast/Icecast metadata blocks a stream sends
+// every metaInt bytes of audio when asked with Icy-MetaData: 1, passing the
+// stream title of each block to onTitle.
+type icyReader struct {
+	r         io.Reader
+	metaInt   int
+	remaining int
+	onTitle   func(string)
+}
+
+func newICYReader(r io.Reader, metaInt int, onTitle func(string)) *icyReader {
+	return &icyReader{r: r, metaInt: metaInt, remaining: metaInt, onTitle: onTitle}
+}
+
+func (ir *icyReader) Read(p []byte) (int, error) {
+	if ir.remaining == 0 {
+		if err := ir.readMetadata(); err != nil {
+			return 0, err
+		}
+		ir.remaining = ir.metaInt
+	}
+
+	if len(p) > ir.remaining {
+		p = p[:ir.remaining]
+	}
+	n, err := ir.r.Read(p)
+	ir.remaining -= n
+	return n, err
+}
+
+// readMetadata reads one metadata block: a length byte counting 16-byte
+// units, then that much text, which is empty when the title hasn't changed.
+func (ir *icyReader) readMetadata() error {
+	var length [1]byte
+	if _, err := io.ReadFull(ir.r, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+
+	block := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(ir.r, block); err != nil {
+		return err
+	}
+	if title, ok := parseStreamTitle(string(block)); ok {
+		ir.onTitle(title)
+	}
+	return nil
+}
+
+// parseStreamTitle extracts the StreamTitle of a metadata block, which looks
+// like StreamTitle='Artist - Title';StreamUrl=”;
+func parseStreamTitle(metadata string) (string, bool) {
+	const key = "StreamTitle='"
+	start := strings.Index(metadata, key)
+	if start < 0 {
+		return "", false
+	}
+	rest := metadata[start+len(key):]
+
+	// Titles can contain quotes themselves, so the value ends at the
+	// quote before the next field rather than the first quote.
+	end := strings.Index(rest, "';")
+	if end < 0 {
+		end = strings.LastIndex(rest, "'")
+	}
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
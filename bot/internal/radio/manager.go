@@ -12,16 +12,20 @@ import (
 type Manager struct {
 	player        *Player
 	streamManager *StreamManager
-	stateManager  *state.Manager
+	guildState    *state.Guild
 	starting      bool
+	channelID     string
+	notice        func(channelID string, playing bool)
 	mu            sync.RWMutex
 }
 
-func NewManager(stateManager *state.Manager, streams []state.StreamOption) *Manager {
+// NewManager creates the radio for one guild. The stream list is shared by
+// all guilds.
+func NewManager(guildState *state.Guild, streamManager *StreamManager) *Manager {
 	return &Manager{
-		player:        NewPlayer(stateManager),
-		streamManager: NewStreamManager(streams),
-		stateManager:  stateManager,
+		player:        NewPlayer(guildState),
+		streamManager: streamManager,
+		guildState:    guildState,
 	}
 }
 
@@ -44,12 +48,20 @@ func (m *Manager) Start(vc *discordgo.VoiceConnection) error {
 		m.starting = false
 	}()
 
-	logger.Info.Println("Starting radio stream...")
-	m.stateManager.SetRadioPlaying(true)
+	logger.Info.Printf("Starting radio stream in guild %s...", m.guildState.ID())
+	if err := m.player.Start(vc); err != nil {
+		logger.Info.Printf("Not starting radio in guild %s: %v", m.guildState.ID(), err)
+		return err
+	}
 
-	return m.player.Start(vc)
+	m.guildState.SetRadioPlaying(true)
+	m.channelID = vc.ChannelID
+	m.notify(true)
+	return nil
 }
 
+// Stop stops the radio and returns once it has stopped sending audio, so
+// music can take over the voice connection right away.
 func (m *Manager) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -58,9 +70,25 @@ func (m *Manager) Stop() {
 		return
 	}
 
-	logger.Info.Println("Stopping radio stream...")
+	logger.Info.Printf("Stopping radio stream in guild %s...", m.guildState.ID())
 	m.player.Stop()
-	m.stateManager.SetRadioPlaying(false)
+	m.guildState.SetRadioPlaying(false)
+	m.notify(false)
+}
+
+// SetNotice installs a callback told about every start and stop of the radio,
+// with the voice channel it plays or played in.
+func (m *Manager) SetNotice(notice func(channelID string, playing bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notice = notice
+}
+
+// notify must be called with mu held.
+func (m *Manager) notify(playing bool) {
+	if m.notice != nil {
+		m.notice(m.channelID, playing)
+	}
 }
 
 func (m *Manager) ChangeStream(streamName string) error {
@@ -76,11 +104,19 @@ func (m *Manager) ChangeStream(streamName string) error {
 		m.Stop()
 	}
 
-	m.stateManager.SetRadioStream(stream.URL)
+	m.guildState.SetRadioStream(stream.URL)
 
 	return nil
 }
 
+func (m *Manager) GetStream(name string) (state.StreamOption, error) {
+	return m.streamManager.GetStreamByName(name)
+}
+
+func (m *Manager) GetStreams() []state.StreamOption {
+	return m.streamManager.GetStreams()
+}
+
 func (m *Manager) GetStreamNames() []string {
 	return m.streamManager.GetStreamNames()
 }
@@ -89,6 +125,29 @@ func (m *Manager) IsValidStream(name string) bool {
 	return m.streamManager.IsValidStream(name)
 }
 
+// StreamTitle returns what the current stream says it is playing, from its
+// ICY metadata, or "" if it doesn't say.
+func (m *Manager) StreamTitle() string {
+	return m.player.StreamTitle(m.guildState.GetRadioStream())
+}
+
+// StreamName returns the name of the current stream, or its URL if it isn't
+// one of the configured streams.
+func (m *Manager) StreamName() string {
+	streamURL := m.guildState.GetRadioStream()
+	for _, stream := range m.streamManager.GetStreams() {
+		if stream.URL == streamURL {
+			return stream.Name
+		}
+	}
+	return streamURL
+}
+
+// Underruns returns how often the radio's buffer ran dry in this guild.
+func (m *Manager) Underruns() int64 {
+	return m.player.Underruns()
+}
+
 func (m *Manager) IsPlaying() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
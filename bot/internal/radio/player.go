@@ -2,28 +2,25 @@ package radio
 
 import (
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"musicbot/internal/audio"
 	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
 	"musicbot/internal/state"
 
 	"github.com/bwmarrin/discordgo"
-	"layeh.com/gopus"
-)
-
-const (
-	frameSize = 960
-	channels  = 2
-	frameRate = 48000
 )
 
 type ErrorType int
@@ -36,6 +33,24 @@ const (
 	ErrorOther
 )
 
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorEOF:
+		return "eof"
+	case ErrorTimeout:
+		return "timeout"
+	case ErrorRateLimit:
+		return "rate_limit"
+	case ErrorNetwork:
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// ErrAudioBusy is returned by Start while music holds the voice connection.
+var ErrAudioBusy = errors.New("voice connection is in use by music")
+
 type StreamError struct {
 	Type ErrorType
 	Err  error
@@ -46,20 +61,31 @@ func (se StreamError) Error() string {
 }
 
 type Player struct {
-	stateManager *state.Manager
-	stopChan     chan bool
-	doneChan     chan struct{}
-	isPlaying    bool
-	ctx          context.Context
-	cancel       context.CancelFunc
-	mu           sync.RWMutex
+	guildState *state.Guild
+	stopChan   chan bool
+	doneChan   chan struct{}
+	isPlaying  bool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	mu         sync.RWMutex
+
+	// encoder is kept across stations and reconnects.
+	encoder audio.EncoderSlot
+
+	// underruns counts the times the buffer ran dry mid-stream.
+	underruns atomic.Int64
+
+	// icyTitle is the last stream title icyStream announced.
+	icyTitle  string
+	icyStream string
+	icyMu     sync.RWMutex
 }
 
-func NewPlayer(stateManager *state.Manager) *Player {
+func NewPlayer(guildState *state.Guild) *Player {
 	return &Player{
-		stateManager: stateManager,
-		stopChan:     make(chan bool, 1),
-		doneChan:     make(chan struct{}),
+		guildState: guildState,
+		stopChan:   make(chan bool, 1),
+		doneChan:   make(chan struct{}),
 	}
 }
 
@@ -71,6 +97,10 @@ func (p *Player) Start(vc *discordgo.VoiceConnection) error {
 		return nil
 	}
 
+	if !p.guildState.AcquireAudio(state.AudioRadio) {
+		return ErrAudioBusy
+	}
+
 	// Drain any leftover signals from previous stop operations
 	select {
 	case <-p.stopChan:
@@ -82,10 +112,10 @@ func (p *Player) Start(vc *discordgo.VoiceConnection) error {
 	p.doneChan = make(chan struct{})
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
-	p.stateManager.SetStreaming(true)
+	p.guildState.SetStreaming(true)
 	p.isPlaying = true
 
-	go p.streamLoop(vc)
+	go p.streamLoop(p.ctx, p.doneChan, vc)
 
 	return nil
 }
@@ -112,32 +142,68 @@ func (p *Player) Stop() {
 	doneChan := p.doneChan
 	p.mu.Unlock()
 
-	// Wait for goroutine to actually finish
+	// Wait for the goroutine to actually finish, however long it takes: a
+	// stream loop left running would reconnect over whatever plays next.
 	if doneChan != nil {
 		select {
 		case <-doneChan:
-			logger.Debug.Println("Radio player stopped successfully")
 		case <-time.After(3 * time.Second):
-			logger.Error.Println("Timeout waiting for radio player to stop")
+			logger.Error.Println("Radio player is slow to stop, still waiting")
+			<-doneChan
 		}
+		logger.Debug.Println("Radio player stopped successfully")
 	}
 
 	p.mu.Lock()
 	p.isPlaying = false
-	p.stateManager.SetStreaming(false)
-	p.stateManager.SetRadioPlaying(false)
+	p.guildState.SetStreaming(false)
+	p.guildState.SetRadioPlaying(false)
 	p.mu.Unlock()
 }
 
+// Underruns returns how often the stream couldn't keep the buffer filled and
+// silence played instead.
+func (p *Player) Underruns() int64 {
+	return p.underruns.Load()
+}
+
 func (p *Player) IsPlaying() bool {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	return p.isPlaying
 }
 
+// StreamTitle returns the title streamURL last announced in its ICY
+// metadata, or "" if it hasn't announced one.
+func (p *Player) StreamTitle(streamURL string) string {
+	p.icyMu.RLock()
+	defer p.icyMu.RUnlock()
+	if p.icyStream != streamURL {
+		return ""
+	}
+	return p.icyTitle
+}
+
+func (p *Player) setStreamTitle(streamURL, title string) {
+	p.icyMu.Lock()
+	defer p.icyMu.Unlock()
+	if p.icyStream == streamURL && p.icyTitle == title {
+		return
+	}
+	p.icyStream = streamURL
+	p.icyTitle = title
+	if title != "" {
+		logger.Debug.Printf("Radio stream title: %s", title)
+	}
+}
+
 func (p *Player) Shutdown(ctx context.Context) error {
 	logger.Info.Println("Gracefully shutting down radio player...")
+	wasPlaying := p.IsPlaying()
 	p.Stop()
+	if !wasPlaying {
+		return nil
+	}
 
 	select {
 	case <-ctx.Done():
@@ -151,25 +217,42 @@ func (p *Player) Name() string {
 	return "RadioPlayer"
 }
 
-func (p *Player) streamLoop(vc *discordgo.VoiceConnection) {
-	defer func() {
-		// Signal that the goroutine has finished
-		p.mu.RLock()
-		doneChan := p.doneChan
-		p.mu.RUnlock()
-
-		if doneChan != nil {
-			close(doneChan)
-		}
+// streamLoop streams until ctx is cancelled, then closes done. Both belong
+// to the session Start began, so a loop that is slow to notice a stop can't
+// pick up the context of the session started after it. The stream is decoded
+// into a buffer that playout sends on from, which keeps playing across
+// reconnects.
+func (p *Player) streamLoop(ctx context.Context, done chan struct{}, vc *discordgo.VoiceConnection) {
+	buffer := newJitterBuffer()
+	playoutCtx, stopPlayout := context.WithCancel(ctx)
+	playoutDone := make(chan struct{})
 
+	defer func() {
+		stopPlayout()
+		<-playoutDone
+		p.guildState.ReleaseAudio(state.AudioRadio)
+		close(done)
 		logger.Debug.Println("Radio stream goroutine finished")
 	}()
 
+	encoder, err := p.encoder.Take()
+	if err != nil {
+		logger.Error.Printf("Error creating opus encoder: %v", err)
+		close(playoutDone)
+		return
+	}
+	go func() {
+		defer p.encoder.Put(encoder)
+		defer close(playoutDone)
+		p.playout(playoutCtx, vc, encoder, buffer)
+	}()
+
 	consecutiveNetworkErrors := 0
+	lastStreamURL := ""
 
 	for {
 		select {
-		case <-p.ctx.Done():
+		case <-ctx.Done():
 			logger.Info.Println("Radio stream context cancelled")
 			return
 		case <-p.stopChan:
@@ -178,13 +261,18 @@ func (p *Player) streamLoop(vc *discordgo.VoiceConnection) {
 		default:
 		}
 
-		if p.stateManager.IsShuttingDown() {
+		if p.guildState.GetAudioOwner() != state.AudioRadio {
+			logger.Info.Println("Radio lost the voice connection, stopping")
+			return
+		}
+
+		if p.guildState.IsShuttingDown() {
 			logger.Debug.Println("Radio stream stopping due to shutdown")
 			return
 		}
 
-		if !p.stateManager.IsConnected() {
-			if p.stateManager.IsShuttingDown() {
+		if !p.guildState.IsConnected() {
+			if p.guildState.IsShuttingDown() {
 				logger.Debug.Println("Not connected to voice during shutdown, stopping radio")
 			} else {
 				logger.Info.Println("Not connected to voice, stopping radio")
@@ -192,13 +280,18 @@ func (p *Player) streamLoop(vc *discordgo.VoiceConnection) {
 			return
 		}
 
-		streamURL := p.stateManager.GetRadioStream()
-		volume := p.stateManager.GetVolume()
+		streamURL := p.guildState.GetRadioStream()
+		volume := p.guildState.GetVolume()
 
-		err := p.streamAudio(vc, streamURL, volume)
+		if streamURL != lastStreamURL {
+			buffer.Reset()
+			lastStreamURL = streamURL
+		}
+
+		err := p.streamAudio(ctx, buffer, streamURL, volume)
 
 		if err != nil {
-			if p.stateManager.IsShuttingDown() {
+			if p.guildState.IsShuttingDown() {
 				logger.Debug.Printf("Radio stream error during shutdown: %v", err)
 				return
 			}
@@ -208,13 +301,14 @@ func (p *Player) streamLoop(vc *discordgo.VoiceConnection) {
 				streamErr = StreamError{Type: ErrorOther, Err: err}
 			}
 
+			metrics.RadioStreamError(streamErr.Type.String())
 			delay := p.getRetryDelay(streamErr, &consecutiveNetworkErrors)
 
 			p.logError(streamErr, delay)
 
 			if delay > 0 {
 				select {
-				case <-p.ctx.Done():
+				case <-ctx.Done():
 					return
 				case <-time.After(delay):
 					continue
@@ -295,65 +389,98 @@ func (p *Player) logError(streamErr StreamError, delay time.Duration) {
 	}
 }
 
-func (p *Player) streamAudio(vc *discordgo.VoiceConnection, streamURL string, volume float32) error {
-	logger.Debug.Printf("Connecting to stream: %s", streamURL)
+// playout sends the buffered audio to vc at the pace Discord plays it. While
+// the buffer is empty or refilling, silence is sent instead, so the bot keeps
+// speaking rather than flickering through a reconnect.
+func (p *Player) playout(ctx context.Context, vc *discordgo.VoiceConnection, encoder *audio.Encoder, buffer *jitterBuffer) {
+	vc.Speaking(true)
+	defer vc.Speaking(false)
 
-	ctx, cancel := context.WithTimeout(p.ctx, 30*time.Second)
-	defer cancel()
+	sender := audio.NewSender(ctx, vc, "radio")
+	defer sender.Stop()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			IdleConnTimeout:   30 * time.Second,
-			DisableKeepAlives: false,
-			DialContext: (&net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-		},
-	}
+	filling := true
 
-	req, err := http.NewRequestWithContext(ctx, "GET", streamURL, nil)
-	if err != nil {
-		return p.classifyError(fmt.Errorf("error creating request: %w", err))
-	}
+	for {
+		if filling && buffer.Len() >= refillFrames {
+			filling = false
+		}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Discord Bot)")
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Cache-Control", "no-cache")
+		frame := audio.SilenceFrame
+		if !filling {
+			pcm, ok := buffer.Pop()
+			if ok {
+				opusData, err := encoder.Encode(pcm[:])
+				audio.PutFrame(pcm)
+				if err != nil {
+					logger.Error.Printf("Error encoding opus: %v", err)
+				} else {
+					frame = opusData
+				}
+			} else {
+				filling = true
+				p.underruns.Add(1)
+				metrics.RadioBufferUnderrun()
+				logger.Debug.Println("Radio buffer ran dry, playing silence until it refills")
+			}
+		}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return p.classifyError(fmt.Errorf("error requesting stream: %w", err))
+		if err := sender.Send(frame); err != nil {
+			return
+		}
 	}
-	defer func() {
-		if resp.Body != nil {
-			resp.Body.Close()
+}
+
+// streamUserAgent is sent when fetching streams, by the bot and by ffmpeg.
+const streamUserAgent = "Mozilla/5.0 (compatible; Discord Bot)"
+
+// streamAudio decodes streamURL into buffer until the stream ends or fails.
+// Plain streams are fetched here and piped into ffmpeg, which lets the ICY
+// titles be read on the way. HLS streams are left to ffmpeg to fetch, and
+// its errors are classified the same way as the ones fetching here gives.
+func (p *Player) streamAudio(streamCtx context.Context, buffer *jitterBuffer, streamURL string, volume float32) error {
+	logger.Debug.Printf("Connecting to stream: %s", streamURL)
+
+	ctx, cancel := context.WithTimeout(streamCtx, 30*time.Second)
+	defer cancel()
+
+	hls := isHLS(streamURL, "")
+	var stdin io.Reader
+	if !hls {
+		resp, err := p.openStream(ctx, streamURL)
+		if err != nil {
+			return err
 		}
-	}()
+		defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		retryAfter := resp.Header.Get("Retry-After")
-		logger.Error.Printf("Rate limited, Retry-After: %s", retryAfter)
-		return StreamError{Type: ErrorRateLimit, Err: fmt.Errorf("rate limited by server")}
+		if isHLS(streamURL, resp.Header.Get("Content-Type")) {
+			hls = true
+		} else {
+			stdin = resp.Body
+			if metaInt, err := strconv.Atoi(resp.Header.Get("icy-metaint")); err == nil && metaInt > 0 && metaInt <= maxICYMetaInt {
+				stdin = newICYReader(resp.Body, metaInt, func(title string) {
+					p.setStreamTitle(streamURL, title)
+				})
+			}
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
-		return p.classifyError(fmt.Errorf("server returned error: %d %s", resp.StatusCode, resp.Status))
+	input := []string{"-i", "pipe:0"}
+	if hls {
+		logger.Debug.Println("Stream is HLS, letting ffmpeg fetch it")
+		input = []string{
+			"-user_agent", streamUserAgent,
+			"-reconnect", "1",
+			"-reconnect_streamed", "1",
+			"-reconnect_delay_max", "2",
+			"-i", streamURL,
+		}
 	}
 
-	logger.Debug.Println("Successfully connected to stream")
-
-	vc.Speaking(true)
-	defer vc.Speaking(false)
-
-	ffmpegCtx, ffmpegCancel := context.WithCancel(p.ctx)
+	ffmpegCtx, ffmpegCancel := context.WithCancel(streamCtx)
 	defer ffmpegCancel()
 
-	ffmpeg := exec.CommandContext(ffmpegCtx,
-		"ffmpeg",
-		"-i", "pipe:0",
+	ffmpeg := exec.CommandContext(ffmpegCtx, "ffmpeg", append(input,
 		"-f", "s16le",
 		"-ar", "48000",
 		"-ac", "2",
@@ -363,9 +490,13 @@ func (p *Player) streamAudio(vc *discordgo.VoiceConnection, streamURL string, vo
 		"-reconnect_streamed", "1",
 		"-reconnect_delay_max", "2",
 		"pipe:1",
-	)
+	)...)
 
-	ffmpeg.Stdin = resp.Body
+	// When ffmpeg fetches a stream itself, its error output is the only
+	// place its HTTP errors show up.
+	stderr := &audio.StderrTail{}
+	ffmpeg.Stdin = stdin
+	ffmpeg.Stderr = stderr
 	ffmpegOut, err := ffmpeg.StdoutPipe()
 	if err != nil {
 		return p.classifyError(fmt.Errorf("error creating ffmpeg pipe: %w", err))
@@ -396,51 +527,84 @@ func (p *Player) streamAudio(vc *discordgo.VoiceConnection, streamURL string, vo
 		}
 	}()
 
-	encoder, err := gopus.NewEncoder(frameRate, channels, gopus.Audio)
-	if err != nil {
-		return p.classifyError(fmt.Errorf("error creating opus encoder: %w", err))
-	}
-
-	audioBuf := make([]int16, frameSize*channels)
-	opusBuffer := make([]byte, 1000)
-
+	frames := audio.NewFrameReader(newDeadlineReader(ffmpegOut, frameReadTimeout))
 	for {
 		select {
-		case <-p.ctx.Done():
+		case <-streamCtx.Done():
 			return nil
 		case <-p.stopChan:
 			return nil
 		default:
 		}
 
-		readDone := make(chan error, 1)
-		go func() {
-			err := binary.Read(ffmpegOut, binary.LittleEndian, &audioBuf)
-			readDone <- err
-		}()
-
-		select {
-		case err := <-readDone:
-			if err != nil {
-				return p.classifyError(err)
+		// Each frame is handed to the buffer, which gives it back to the
+		// pool once it has been played.
+		pcm := audio.GetFrame()
+		if err := frames.ReadFrame(pcm[:]); err != nil {
+			audio.PutFrame(pcm)
+			switch {
+			case streamCtx.Err() != nil:
+				// Stopping kills ffmpeg, which ends the read.
+				return nil
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				return StreamError{Type: ErrorTimeout, Err: fmt.Errorf("audio read timeout")}
 			}
-		case <-time.After(5 * time.Second):
-			return StreamError{Type: ErrorTimeout, Err: fmt.Errorf("audio read timeout")}
-		case <-p.ctx.Done():
-			return nil
-		}
-
-		opusData, err := encoder.Encode(audioBuf, frameSize, len(opusBuffer))
-		if err != nil {
-			return p.classifyError(fmt.Errorf("error encoding opus: %w", err))
+			if line := stderr.LastLine(); hls && line != "" {
+				return p.classifyError(fmt.Errorf("ffmpeg: %s", line))
+			}
+			return p.classifyError(err)
 		}
 
-		select {
-		case vc.OpusSend <- opusData:
-		case <-time.After(2 * time.Second):
-			return StreamError{Type: ErrorTimeout, Err: fmt.Errorf("discord send timeout")}
-		case <-p.ctx.Done():
+		if err := buffer.Push(streamCtx, pcm); err != nil {
+			audio.PutFrame(pcm)
 			return nil
 		}
 	}
 }
+
+// openStream requests a plain stream, turning the ways it can fail into
+// stream errors.
+func (p *Player) openStream(ctx context.Context, streamURL string) (*http.Response, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			IdleConnTimeout:   30 * time.Second,
+			DisableKeepAlives: false,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", streamURL, nil)
+	if err != nil {
+		return nil, p.classifyError(fmt.Errorf("error creating request: %w", err))
+	}
+
+	req.Header.Set("User-Agent", streamUserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, p.classifyError(fmt.Errorf("error requesting stream: %w", err))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := resp.Header.Get("Retry-After")
+		logger.Error.Printf("Rate limited, Retry-After: %s", retryAfter)
+		resp.Body.Close()
+		return nil, StreamError{Type: ErrorRateLimit, Err: fmt.Errorf("rate limited by server")}
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, p.classifyError(fmt.Errorf("server returned error: %d %s", resp.StatusCode, resp.Status))
+	}
+
+	logger.Debug.Println("Successfully connected to stream")
+	return resp, nil
+}
@@ -3,10 +3,12 @@ package radio
 import (
 	"fmt"
 	"musicbot/internal/state"
+	"sync"
 )
 
 type StreamManager struct {
 	streams []state.StreamOption
+	mu      sync.RWMutex
 }
 
 func NewStreamManager(streams []state.StreamOption) *StreamManager {
@@ -16,10 +18,20 @@ func NewStreamManager(streams []state.StreamOption) *StreamManager {
 }
 
 func (sm *StreamManager) GetStreams() []state.StreamOption {
-	return sm.streams
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return append([]state.StreamOption(nil), sm.streams...)
+}
+
+func (sm *StreamManager) SetStreams(streams []state.StreamOption) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.streams = append([]state.StreamOption(nil), streams...)
 }
 
 func (sm *StreamManager) GetStreamByName(name string) (state.StreamOption, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
 	for _, stream := range sm.streams {
 		if stream.Name == name {
 			return stream, nil
@@ -29,6 +41,8 @@ func (sm *StreamManager) GetStreamByName(name string) (state.StreamOption, error
 }
 
 func (sm *StreamManager) GetStreamNames() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
 	names := make([]string, len(sm.streams))
 	for i, stream := range sm.streams {
 		names[i] = stream.Name
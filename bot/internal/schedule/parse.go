@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadTime is returned by ParseTime for input it doesn't understand.
+var ErrBadTime = errors.New("unrecognized time")
+
+// timeLayouts are the absolute times ParseTime accepts. Layouts without a
+// zone are read as UTC.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseTime reads when a job should run: an ISO 8601 time such as
+// 2025-06-01T20:00+02:00, or a delay from now such as "in 2h", "in 90m" or
+// "in 1d12h".
+func ParseTime(input string, now time.Time) (time.Time, error) {
+	input = strings.TrimSpace(input)
+
+	if rest, ok := strings.CutPrefix(strings.ToLower(input), "in "); ok {
+		delay, err := parseDelay(strings.ReplaceAll(rest, " ", ""))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(delay), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(input), time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrBadTime
+}
+
+// parseDelay is time.ParseDuration with days: a leading "<n>d".
+func parseDelay(s string) (time.Duration, error) {
+	var delay time.Duration
+	if days, rest, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, ErrBadTime
+		}
+		delay = time.Duration(n) * 24 * time.Hour
+		s = rest
+	}
+
+	if s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, ErrBadTime
+		}
+		delay += d
+	}
+
+	if delay <= 0 {
+		return 0, ErrBadTime
+	}
+	return delay, nil
+}
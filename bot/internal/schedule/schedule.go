@@ -0,0 +1,223 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/logger"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAhead is how far ahead playback can be scheduled.
+	MaxAhead = 30 * 24 * time.Hour
+	// prefetchLead is how long before a job its track is downloaded, so it
+	// starts on time.
+	prefetchLead = 10 * time.Minute
+	// missedGrace is how late a job missed while the bot was down may still
+	// run after a restart. Older ones are dropped.
+	missedGrace = 15 * time.Minute
+)
+
+var (
+	ErrInPast  = errors.New("that time has already passed")
+	ErrTooFar  = errors.New("that time is too far ahead")
+	ErrStopped = errors.New("the scheduler has stopped")
+)
+
+// Runner carries out scheduled playback.
+type Runner interface {
+	// Prefetch downloads the job's track ahead of time without queueing it.
+	Prefetch(job config.Schedule)
+	// Run joins the job's channel and queues its URL.
+	Run(job config.Schedule)
+}
+
+// Scheduler keeps a timer for each pending job. Jobs live in the schedules
+// table, so they survive a restart; a job is deleted when it runs.
+type Scheduler struct {
+	dbManager *config.DatabaseManager
+	runner    Runner
+	timers    map[int64][]*time.Timer
+	stopped   bool
+	mu        sync.Mutex
+}
+
+func New(dbManager *config.DatabaseManager) *Scheduler {
+	return &Scheduler{
+		dbManager: dbManager,
+		timers:    make(map[int64][]*time.Timer),
+	}
+}
+
+// SetRunner installs what runs the jobs. It must be set before Start.
+func (s *Scheduler) SetRunner(runner Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runner = runner
+}
+
+// Start loads the pending jobs and arms their timers.
+func (s *Scheduler) Start() error {
+	jobs, err := s.dbManager.GetSchedules("")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	armed := 0
+	for _, job := range jobs {
+		if job.RunAt.Before(now.Add(-missedGrace)) {
+			logger.Info.Printf("Dropping schedule %d of guild %s, missed at %s", job.ID, job.GuildID, job.RunAt.Format(time.RFC3339))
+			if _, err := s.dbManager.DeleteSchedule(job.GuildID, job.ID); err != nil {
+				logger.Error.Printf("Failed to delete missed schedule %d: %v", job.ID, err)
+			}
+			continue
+		}
+		s.arm(job)
+		armed++
+	}
+
+	logger.Info.Printf("Scheduler started with %d pending jobs", armed)
+	return nil
+}
+
+// Add validates and stores job and arms its timer. The stored job, with its
+// ID, is returned.
+func (s *Scheduler) Add(job config.Schedule) (config.Schedule, error) {
+	now := time.Now()
+	if !job.RunAt.After(now) {
+		return job, ErrInPast
+	}
+	if job.RunAt.After(now.Add(MaxAhead)) {
+		return job, ErrTooFar
+	}
+
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+	if stopped {
+		return job, ErrStopped
+	}
+
+	job.CreatedAt = now
+	id, err := s.dbManager.AddSchedule(job)
+	if err != nil {
+		return job, err
+	}
+	job.ID = id
+
+	s.arm(job)
+	logger.Info.Printf("Scheduled %s in guild %s for %s (job %d)", job.URL, job.GuildID, job.RunAt.Format(time.RFC3339), job.ID)
+	return job, nil
+}
+
+// Remove cancels job id of guildID and reports whether there was one.
+func (s *Scheduler) Remove(guildID string, id int64) (bool, error) {
+	removed, err := s.dbManager.DeleteSchedule(guildID, id)
+	if err != nil || !removed {
+		return removed, err
+	}
+
+	s.mu.Lock()
+	s.disarmLocked(id)
+	s.mu.Unlock()
+
+	logger.Info.Printf("Removed schedule %d of guild %s", id, guildID)
+	return true, nil
+}
+
+// List returns the pending jobs of guildID, soonest first.
+func (s *Scheduler) List(guildID string) ([]config.Schedule, error) {
+	return s.dbManager.GetSchedules(guildID)
+}
+
+// arm sets the timers of job: one to prefetch its track and one to run it.
+// A job that is already due runs right away.
+func (s *Scheduler) arm(job config.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	var timers []*time.Timer
+	untilRun := time.Until(job.RunAt)
+
+	// A job closer than the lead is prefetched right away, which still
+	// helps. Playlists are left to download when they run.
+	if !job.Playlist && untilRun > 0 {
+		timers = append(timers, time.AfterFunc(max(untilRun-prefetchLead, 0), func() {
+			if runner := s.getRunner(); runner != nil {
+				runner.Prefetch(job)
+			}
+		}))
+	}
+
+	timers = append(timers, time.AfterFunc(max(untilRun, 0), func() {
+		s.run(job)
+	}))
+
+	s.timers[job.ID] = timers
+}
+
+// run deletes job and hands it to the runner. A job removed in the meantime
+// is skipped.
+func (s *Scheduler) run(job config.Schedule) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.timers, job.ID)
+	runner := s.runner
+	s.mu.Unlock()
+
+	removed, err := s.dbManager.DeleteSchedule(job.GuildID, job.ID)
+	if err != nil {
+		logger.Error.Printf("Failed to delete schedule %d before running it: %v", job.ID, err)
+	} else if !removed {
+		return
+	}
+
+	if runner == nil {
+		logger.Error.Printf("Schedule %d is due but no runner is set", job.ID)
+		return
+	}
+
+	logger.Info.Printf("Running schedule %d in guild %s: %s", job.ID, job.GuildID, job.URL)
+	runner.Run(job)
+}
+
+func (s *Scheduler) getRunner() Runner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runner
+}
+
+// disarmLocked stops the timers of job id. The caller holds s.mu.
+func (s *Scheduler) disarmLocked(id int64) {
+	for _, timer := range s.timers[id] {
+		timer.Stop()
+	}
+	delete(s.timers, id)
+}
+
+// Shutdown stops every timer. Pending jobs stay in the database and are
+// picked up again on the next start.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for id := range s.timers {
+		s.disarmLocked(id)
+	}
+	return nil
+}
+
+func (s *Scheduler) Name() string {
+	return "Scheduler"
+}
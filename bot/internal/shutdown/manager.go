@@ -64,34 +64,25 @@ func (m *Manager) Shutdown(timeout time.Duration) error {
 	copy(components, m.components)
 	m.mu.RUnlock()
 
-	var wg sync.WaitGroup
-	errors := make(chan error, len(components))
-
-	// Shutdown in reverse order (LIFO)
-	for i := len(components) - 1; i >= 0; i-- {
-		component := components[i]
-		wg.Add(1)
+	// Shutdown in reverse order (LIFO), one component at a time, so a
+	// component can rely on the ones registered before it still running.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := len(components) - 1; i >= 0; i-- {
+			comp := components[i]
+			if ctx.Err() != nil {
+				logger.Error.Printf("Skipping shutdown of %s, out of time", comp.Name())
+				continue
+			}
 
-		go func(comp Component) {
-			defer wg.Done()
 			logger.Info.Printf("Shutting down component: %s", comp.Name())
-
 			if err := comp.Shutdown(ctx); err != nil {
 				logger.Error.Printf("Error shutting down %s: %v", comp.Name(), err)
-				errors <- err
 			} else {
 				logger.Info.Printf("Successfully shut down: %s", comp.Name())
 			}
-		}(component)
-
-		// Small delay between component shutdowns
-		time.Sleep(100 * time.Millisecond)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
+		}
 	}()
 
 	select {
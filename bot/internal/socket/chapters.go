@@ -0,0 +1,71 @@
+package socket
+
+import "strings"
+
+var (
+	introTitles = []string{"intro", "opening", "skit"}
+	outroTitles = []string{"outro", "credits", "ending", "end"}
+)
+
+// musicOffsets works out where the music starts and ends in a track from the
+// downloader's "music_offset" hint, or failing that from chapter titles. It
+// returns zeros when there is nothing to skip.
+func musicOffsets(data map[string]interface{}, duration int) (int, int) {
+	if duration <= 0 {
+		return 0, 0
+	}
+
+	switch hint := data["music_offset"].(type) {
+	case float64:
+		return clampOffsets(int(hint), 0, duration)
+	case map[string]interface{}:
+		return clampOffsets(getInt(hint, "start"), getInt(hint, "end"), duration)
+	}
+
+	chapters, ok := data["chapters"].([]interface{})
+	if !ok || len(chapters) < 2 {
+		return 0, 0
+	}
+
+	start, end := 0, 0
+
+	// Only an intro in the first half or an outro in the second counts, so a
+	// mislabelled chapter can't cut away most of the song.
+	if first, ok := chapters[0].(map[string]interface{}); ok && matchesTitle(getString(first, "title"), introTitles) {
+		if chapterEnd := getInt(first, "end_time"); chapterEnd < duration/2 {
+			start = chapterEnd
+		}
+	}
+	if last, ok := chapters[len(chapters)-1].(map[string]interface{}); ok && matchesTitle(getString(last, "title"), outroTitles) {
+		if chapterStart := getInt(last, "start_time"); chapterStart > duration/2 {
+			end = chapterStart
+		}
+	}
+
+	return clampOffsets(start, end, duration)
+}
+
+// clampOffsets drops offsets that fall outside the track or leave nothing to
+// play.
+func clampOffsets(start, end, duration int) (int, int) {
+	if start < 0 || start >= duration {
+		start = 0
+	}
+	if end <= 0 || end >= duration {
+		end = 0
+	}
+	if end > 0 && start >= end {
+		return 0, 0
+	}
+	return start, end
+}
+
+func matchesTitle(title string, names []string) bool {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, name := range names {
+		if title == name || strings.HasPrefix(title, name+" ") || strings.HasSuffix(title, " "+name) {
+			return true
+		}
+	}
+	return false
+}
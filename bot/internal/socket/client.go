@@ -2,15 +2,18 @@ package socket
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/binary"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"musicbot/internal/config"
+	"musicbot/internal/ids"
 	"musicbot/internal/logger"
+	"musicbot/internal/metrics"
 	"musicbot/internal/state"
 	"net"
+	"sort"
 	"sync"
 	"time"
 )
@@ -21,10 +24,59 @@ type DownloadRequest struct {
 	Params  map[string]interface{} `json:"params"`
 }
 
-type SearchRequest struct {
-	Command string                 `json:"command"`
-	ID      string                 `json:"id"`
-	Params  map[string]interface{} `json:"params"`
+// ErrRequestTimeout is returned when the downloader doesn't answer a request
+// in time.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// ErrConnectionLost is returned to requests still waiting for an answer when
+// the connection to the downloader is replaced.
+var ErrConnectionLost = errors.New("downloader connection was lost")
+
+// ErrClientClosed is returned by Reconnect once the client was shut down.
+var ErrClientClosed = errors.New("downloader client was shut down")
+
+// ErrRequestCancelled is returned to requests still waiting for an answer
+// when they are cancelled.
+var ErrRequestCancelled = errors.New("request was cancelled")
+
+// Codes the downloader sends with a download it recognises as impossible.
+const (
+	CodePrivate       = "private"
+	CodeLoginRequired = "login_required"
+	CodeRemoved       = "removed"
+	CodeUnavailable   = "unavailable"
+	CodeCopyright     = "copyright"
+	CodeAgeRestricted = "age_restricted"
+	CodeRegionBlocked = "region_blocked"
+	CodeNotFound      = "not_found"
+)
+
+// DownloaderError is a failure the downloader reported in its answer. Code
+// is one of the Code constants, or empty when it didn't recognise the cause.
+type DownloaderError struct {
+	Code    string
+	Message string
+}
+
+func (e *DownloaderError) Error() string {
+	return e.Message
+}
+
+const (
+	// cancelledRequestTTL is how long the IDs of cancelled requests are kept
+	// to recognise answers the downloader sends anyway.
+	cancelledRequestTTL = 15 * time.Minute
+
+	// cancelTimeout bounds the wait for the downloader to acknowledge a
+	// cancellation the client made on its own.
+	cancelTimeout = 5 * time.Second
+)
+
+// pendingCall is a request made through call, waiting for its answer on ch.
+type pendingCall struct {
+	ch      chan interface{}
+	command string
+	started time.Time
 }
 
 type Client struct {
@@ -32,16 +84,26 @@ type Client struct {
 	conn                 net.Conn
 	connected            bool
 	downloadHandler      func(*state.Song)
+	downloadFailHandler  func(string, string)
 	playlistHandler      func([]state.Song)
-	searchHandler        func([]SearchResult)
 	playlistEventHandler func(string, *state.Song)
 	playlistStartHandler func(int)
+	playlistDoneHandler  func(string)
 	resetPendingHandler  func()
 	mu                   sync.RWMutex
-	pendingRequests      map[string]chan interface{}
+	// writeMu keeps frames from concurrent senders from interleaving on
+	// the connection.
+	writeMu              sync.Mutex
+	pendingRequests      map[string]pendingCall
+	downloadStarts       map[string]time.Time
+	downloadURLs         map[string]string
+	cancelled            map[string]time.Time
+	playlistRequests     map[string]time.Time
+	searches             *searchCache
 	lastDownloaderPing   time.Time
-	pingTicker           *time.Ticker
-	stopPing             chan struct{}
+	connCtx              context.Context
+	connCancel           context.CancelFunc
+	closed               bool
 	reconnectAttempts    int
 	maxReconnectAttempts int
 }
@@ -49,8 +111,12 @@ type Client struct {
 func NewClient(socketPath string) *Client {
 	return &Client{
 		socketPath:           socketPath,
-		pendingRequests:      make(map[string]chan interface{}),
-		stopPing:             make(chan struct{}),
+		pendingRequests:      make(map[string]pendingCall),
+		downloadStarts:       make(map[string]time.Time),
+		downloadURLs:         make(map[string]string),
+		cancelled:            make(map[string]time.Time),
+		playlistRequests:     make(map[string]time.Time),
+		searches:             newSearchCache(),
 		maxReconnectAttempts: 5,
 	}
 }
@@ -63,22 +129,34 @@ func (c *Client) SetPlaylistStartHandler(handler func(int)) {
 	c.playlistStartHandler = handler
 }
 
+// SetPlaylistDoneHandler is called with the playlist URL once the downloader
+// has finished with a playlist, whether or not every track made it.
+func (c *Client) SetPlaylistDoneHandler(handler func(string)) {
+	c.playlistDoneHandler = handler
+}
+
 func (c *Client) SetDownloadHandler(handler func(*state.Song)) {
 	c.downloadHandler = handler
 }
 
-func (c *Client) SetPlaylistHandler(handler func([]state.Song)) {
-	c.playlistHandler = handler
+// SetDownloadFailHandler is called with the URL and the downloader's reason
+// when a single-track download fails. The download handler still gets nil.
+func (c *Client) SetDownloadFailHandler(handler func(string, string)) {
+	c.downloadFailHandler = handler
 }
 
-func (c *Client) SetSearchHandler(handler func([]SearchResult)) {
-	c.searchHandler = handler
+func (c *Client) SetPlaylistHandler(handler func([]state.Song)) {
+	c.playlistHandler = handler
 }
 
 func (c *Client) SetPlaylistEventHandler(handler func(string, *state.Song)) {
 	c.playlistEventHandler = handler
 }
 
+// Connect dials the downloader and starts a new connection generation. The
+// generation's reader and keepalive run until its context is cancelled, which
+// closeConnection does exactly once, so goroutines of an old connection never
+// touch its successor.
 func (c *Client) Connect() error {
 	logger.Info.Printf("Connecting to socket: %s", c.socketPath)
 
@@ -87,143 +165,130 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("failed to connect to socket: %w", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	c.mu.Lock()
+	if c.connCancel != nil {
+		c.connCancel()
+	}
+	oldConn := c.conn
 	c.conn = conn
+	c.connCtx = ctx
+	c.connCancel = cancel
 	c.connected = true
+	c.closed = false
 	c.lastDownloaderPing = time.Now()
 	c.reconnectAttempts = 0
+	// Downloads in flight on the old connection will never be answered.
+	c.downloadStarts = make(map[string]time.Time)
+	c.downloadURLs = make(map[string]string)
+	c.cancelled = make(map[string]time.Time)
+	c.playlistRequests = make(map[string]time.Time)
+	pending := c.pendingRequests
+	c.pendingRequests = make(map[string]pendingCall)
 	c.mu.Unlock()
 
+	if oldConn != nil {
+		oldConn.Close()
+	}
+
+	for _, call := range pending {
+		deliver(call.ch, ErrConnectionLost)
+	}
+
 	if c.resetPendingHandler != nil {
 		c.resetPendingHandler()
 	}
 
-	go c.listenForResponses()
-	c.startKeepaliveRoutine()
+	go c.listenForResponses(ctx, conn)
+	go c.keepalive(ctx)
 
 	logger.Info.Println("Successfully connected to socket")
 	return nil
 }
 
-func (c *Client) startKeepaliveRoutine() {
-	c.mu.Lock()
-	if c.pingTicker != nil {
-		c.pingTicker.Stop()
-	}
-	c.pingTicker = time.NewTicker(90 * time.Second) // Ping every 90 seconds
-	c.mu.Unlock()
-
-	go func() {
-		defer func() {
-			c.mu.Lock()
-			if c.pingTicker != nil {
-				c.pingTicker.Stop()
-				c.pingTicker = nil
-			}
-			c.mu.Unlock()
-		}()
-
-		for {
-			select {
-			case <-c.pingTicker.C:
-				if !c.IsConnected() {
-					logger.Info.Println("Keepalive: Not connected to downloader, stopping keepalive")
+// keepalive pings the downloader every 90 seconds until ctx, the context of
+// its connection generation, is cancelled.
+func (c *Client) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.sendKeepalivePing(ctx); err != nil {
+				if ctx.Err() != nil {
 					return
 				}
-
-				err := c.sendKeepalivePing()
-				if err != nil {
-					logger.Error.Printf("Keepalive ping failed: %v", err)
-					c.handleConnectionError(err)
-					return
-				}
-
-			case <-c.stopPing:
-				logger.Debug.Println("Keepalive routine stopped")
+				logger.Error.Printf("Keepalive ping failed: %v", err)
+				c.closeConnection(ctx, err)
 				return
 			}
+
+		case <-ctx.Done():
+			logger.Debug.Println("Keepalive routine stopped")
+			return
 		}
-	}()
+	}
 }
 
-func (c *Client) sendKeepalivePing() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (c *Client) sendKeepalivePing(connCtx context.Context) error {
+	ctx, cancel := context.WithTimeout(connCtx, 30*time.Second)
 	defer cancel()
 
-	requestID := c.generateRequestID()
-	request := map[string]interface{}{
-		"command": "ping",
-		"id":      requestID,
-		"params": map[string]interface{}{
-			"timestamp": time.Now().Format(time.RFC3339),
-			"keepalive": true,
-		},
+	data, err := c.call(ctx, "ping", map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"keepalive": true,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("keepalive ping timeout")
 	}
-
-	data, err := json.Marshal(request)
-	if err != nil {
-		return fmt.Errorf("failed to marshal keepalive ping: %w", err)
-	}
-
-	responseChan := make(chan interface{}, 1)
-	c.mu.Lock()
-	c.pendingRequests[requestID] = responseChan
-	c.mu.Unlock()
-
-	defer func() {
-		c.mu.Lock()
-		delete(c.pendingRequests, requestID)
-		c.mu.Unlock()
-	}()
-
-	err = c.sendMessage(data)
 	if err != nil {
 		return fmt.Errorf("failed to send keepalive ping: %w", err)
 	}
 
-	select {
-	case response := <-responseChan:
-		if responseData, ok := response.(map[string]interface{}); ok {
-			if msg, exists := responseData["message"].(string); exists && msg == "pong" {
-				c.mu.Lock()
-				c.lastDownloaderPing = time.Now()
-				c.mu.Unlock()
-				logger.Debug.Println("Keepalive pong received")
-				return nil
-			}
-		}
+	if getString(data, "message") != "pong" {
 		return fmt.Errorf("unexpected keepalive response format")
-
-	case <-ctx.Done():
-		return fmt.Errorf("keepalive ping timeout")
 	}
+
+	c.mu.Lock()
+	c.lastDownloaderPing = time.Now()
+	c.mu.Unlock()
+	logger.Debug.Println("Keepalive pong received")
+	return nil
 }
 
+// handleConnectionError closes the current connection after err and starts
+// reconnecting.
 func (c *Client) handleConnectionError(err error) {
+	c.mu.RLock()
+	ctx := c.connCtx
+	c.mu.RUnlock()
+
+	c.closeConnection(ctx, err)
+}
+
+// closeConnection closes the connection generation of ctx and starts
+// reconnecting. It does nothing if that generation is already closed, so a
+// reader or keepalive of an old connection can't take down a newer one.
+func (c *Client) closeConnection(ctx context.Context, err error) {
 	c.mu.Lock()
-	wasConnected := c.connected
+	if ctx == nil || ctx != c.connCtx || !c.connected {
+		c.mu.Unlock()
+		return
+	}
 	c.connected = false
+	c.connCancel()
+	conn := c.conn
+	c.conn = nil
 	c.mu.Unlock()
 
-	if !wasConnected {
-		return // Already handling disconnection
-	}
-
 	logger.Error.Printf("Connection error detected: %v", err)
 
-	// Stop keepalive routine
-	select {
-	case c.stopPing <- struct{}{}:
-	default:
+	if conn != nil {
+		conn.Close()
 	}
 
-	// Close current connection
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
-
-	// Attempt reconnection
 	go c.attemptReconnection()
 }
 
@@ -234,9 +299,22 @@ func (c *Client) attemptReconnection() {
 
 		time.Sleep(delay)
 
+		c.mu.RLock()
+		closed, connected := c.closed, c.connected
+		c.mu.RUnlock()
+		if closed {
+			logger.Info.Println("Socket client was disconnected, not reconnecting")
+			return
+		}
+		if connected {
+			logger.Info.Println("Socket client was reconnected in the meantime")
+			return
+		}
+
 		err := c.Connect()
 		if err == nil {
 			logger.Info.Printf("Reconnection successful after %d attempts", attempt)
+			metrics.SocketReconnected()
 			return
 		}
 
@@ -246,33 +324,23 @@ func (c *Client) attemptReconnection() {
 	logger.Error.Printf("Failed to reconnect after %d attempts", c.maxReconnectAttempts)
 }
 
+// Disconnect closes the connection for good; no reconnection is attempted.
 func (c *Client) Disconnect() error {
 	c.mu.Lock()
+	c.closed = true
 	if !c.connected || c.conn == nil {
 		c.mu.Unlock()
 		return nil
 	}
 	c.connected = false
+	c.connCancel()
+	conn := c.conn
+	c.conn = nil
 	c.mu.Unlock()
 
 	logger.Info.Println("Disconnecting from socket...")
 
-	// Stop keepalive routine
-	select {
-	case c.stopPing <- struct{}{}:
-	default:
-	}
-
-	c.mu.Lock()
-	if c.pingTicker != nil {
-		c.pingTicker.Stop()
-		c.pingTicker = nil
-	}
-	c.mu.Unlock()
-
-	err := c.conn.Close()
-	c.conn = nil
-
+	err := conn.Close()
 	if err != nil {
 		logger.Error.Printf("Error disconnecting from socket: %v", err)
 	} else {
@@ -288,42 +356,193 @@ func (c *Client) IsConnected() bool {
 	return c.connected && c.conn != nil
 }
 
-func (c *Client) generateRequestID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+// Reconnect drops the current connection and connects again right away,
+// rather than waiting for the keepalive to notice that the downloader was
+// restarted. Requests still waiting for an answer fail with
+// ErrConnectionLost.
+func (c *Client) Reconnect() error {
+	c.mu.RLock()
+	closed := c.closed
+	c.mu.RUnlock()
+	if closed {
+		return ErrClientClosed
+	}
+
+	logger.Info.Println("Reconnecting to the downloader on request")
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	metrics.SocketReconnected()
+	return nil
+}
+
+// PendingRequest is a request the downloader hasn't answered yet.
+type PendingRequest struct {
+	ID      string
+	Command string
+
+	// URL is set for single-track downloads.
+	URL     string
+	Started time.Time
+}
+
+// PendingRequests returns the requests waiting for an answer, oldest first.
+func (c *Client) PendingRequests() []PendingRequest {
+	c.mu.RLock()
+	requests := make([]PendingRequest, 0, len(c.pendingRequests)+len(c.downloadStarts)+len(c.playlistRequests))
+	for id, call := range c.pendingRequests {
+		requests = append(requests, PendingRequest{ID: id, Command: call.command, Started: call.started})
+	}
+	for id, started := range c.downloadStarts {
+		requests = append(requests, PendingRequest{ID: id, Command: "download_audio", URL: c.downloadURLs[id], Started: started})
+	}
+	for id, started := range c.playlistRequests {
+		requests = append(requests, PendingRequest{ID: id, Command: "start_playlist_download", Started: started})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(requests, func(a, b int) bool {
+		return requests[a].Started.Before(requests[b].Started)
+	})
+	return requests
+}
+
+// requestPrefixes shortens command names for request IDs, so the requests of
+// one kind are easy to find in the logs.
+var requestPrefixes = map[string]string{
+	"download_audio":          "dl",
+	"download_playlist_item":  "pli",
+	"get_playlist_info":       "plinfo",
+	"start_playlist_download": "pl",
+	"search":                  "srch",
+	"ping":                    "ping",
+	"cancel":                  "cancel",
+}
+
+func (c *Client) generateRequestID(command string) string {
+	prefix, ok := requestPrefixes[command]
+	if !ok {
+		prefix = command
+	}
+	return ids.New(prefix)
 }
 
-func (c *Client) SendDownloadRequest(url, requestedBy string) error {
+// SendDownloadRequest asks the downloader for url within limits and returns
+// the ID of the request, which CancelRequest takes. The song is handed to the
+// download handler once it is done.
+func (c *Client) SendDownloadRequest(url, requestedBy string, limits config.DownloadLimits) (string, error) {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected")
+		return "", fmt.Errorf("not connected")
 	}
 
-	requestID := c.generateRequestID()
+	requestID := c.generateRequestID("download_audio")
+
+	platform := limits.Platform
+	if platform == "" {
+		platform = DetectPlatform(url)
+	}
 
 	request := DownloadRequest{
 		Command: "download_audio",
 		ID:      requestID,
 		Params: map[string]interface{}{
-			"url": url,
+			"url":                  url,
+			"max_duration_seconds": limits.MaxDurationSeconds,
+			"max_size_mb":          limits.MaxSizeMB,
+			"allow_live":           limits.AllowLive,
+			"platform":             platform,
 		},
 	}
 
 	data, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// Registered before sending, so an answer can't arrive for a request
+	// the client doesn't know yet.
+	c.mu.Lock()
+	c.downloadStarts[requestID] = time.Now()
+	c.downloadURLs[requestID] = url
+	c.mu.Unlock()
+
 	err = c.sendMessage(data)
 	if err != nil {
+		c.mu.Lock()
+		delete(c.downloadStarts, requestID)
+		delete(c.downloadURLs, requestID)
+		c.mu.Unlock()
 		c.handleConnectionError(err)
-		return fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	logger.ForRequest(requestID).Info("Sent downloader request", "command", request.Command, "url", url, "requested_by", requestedBy,
+		"max_duration", limits.MaxDurationSeconds, "max_size_mb", limits.MaxSizeMB, "allow_live", limits.AllowLive)
+	return requestID, nil
+}
+
+// CancelRequest asks the downloader to stop the given requests and waits for
+// its acknowledgement. Answers that still arrive for them are dropped, and
+// callers waiting on one of them get ErrRequestCancelled right away.
+func (c *Client) CancelRequest(ctx context.Context, requestIDs ...string) error {
+	if len(requestIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var waiting []chan interface{}
+
+	c.mu.Lock()
+	for id, at := range c.cancelled {
+		if now.Sub(at) > cancelledRequestTTL {
+			delete(c.cancelled, id)
+		}
+	}
+	for _, id := range requestIDs {
+		c.cancelled[id] = now
+		delete(c.downloadStarts, id)
+		delete(c.downloadURLs, id)
+		delete(c.playlistRequests, id)
+		if call, ok := c.pendingRequests[id]; ok {
+			delete(c.pendingRequests, id)
+			waiting = append(waiting, call.ch)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, ch := range waiting {
+		deliver(ch, ErrRequestCancelled)
 	}
 
+	data, err := c.call(ctx, "cancel", map[string]interface{}{
+		"ids": requestIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel requests: %w", err)
+	}
+
+	logger.Info.Printf("Downloader cancelled %d of %d requests", getInt(data, "cancelled"), len(requestIDs))
 	return nil
 }
 
-func (c *Client) SendPlaylistRequest(url, requestedBy string, limit int) error {
+// isCancelled reports whether id belongs to a cancelled request, forgetting
+// it since the downloader answers a request only once.
+func (c *Client) isCancelled(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.cancelled[id]; !ok {
+		return false
+	}
+	delete(c.cancelled, id)
+	return true
+}
+
+func (c *Client) SendPlaylistRequest(url, requestedBy string, limit int, limits config.DownloadLimits) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("not connected")
 	}
@@ -335,15 +554,17 @@ func (c *Client) SendPlaylistRequest(url, requestedBy string, limit int) error {
 		limit = 50
 	}
 
-	requestID := c.generateRequestID()
+	requestID := c.generateRequestID("start_playlist_download")
 
 	request := DownloadRequest{
 		Command: "start_playlist_download",
 		ID:      requestID,
 		Params: map[string]interface{}{
-			"url":       url,
-			"requester": requestedBy,
-			"max_items": limit,
+			"url":                  url,
+			"requester":            requestedBy,
+			"max_items":            limit,
+			"max_duration_seconds": limits.MaxDurationSeconds,
+			"max_size_mb":          limits.MaxSizeMB,
 		},
 	}
 
@@ -352,42 +573,231 @@ func (c *Client) SendPlaylistRequest(url, requestedBy string, limit int) error {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	c.mu.Lock()
+	c.playlistRequests[requestID] = time.Now()
+	c.mu.Unlock()
+
 	err = c.sendMessage(data)
 	if err != nil {
+		c.mu.Lock()
+		delete(c.playlistRequests, requestID)
+		c.mu.Unlock()
 		c.handleConnectionError(err)
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 
+	logger.ForRequest(requestID).Info("Sent downloader request", "command", request.Command, "url", url, "requested_by", requestedBy, "limit", limit)
 	return nil
 }
 
-func (c *Client) SendSearchRequest(query string, platform string, limit int) error {
+// PlaylistInfo describes a playlist before any of it is downloaded. For a
+// single track, Duration is its length in seconds, or 0 if unknown.
+type PlaylistInfo struct {
+	Title       string
+	TotalTracks int
+	IsPlaylist  bool
+	Duration    int
+}
+
+// GetPlaylistInfo asks the downloader how many tracks of the playlist at url
+// it would download with the given limit.
+func (c *Client) GetPlaylistInfo(ctx context.Context, url string, limit int) (PlaylistInfo, error) {
+	data, err := c.call(ctx, "get_playlist_info", map[string]interface{}{
+		"url":       url,
+		"max_items": limit,
+	})
+	if err != nil {
+		return PlaylistInfo{}, err
+	}
+
+	return PlaylistInfo{
+		Title:       getString(data, "playlist_title"),
+		TotalTracks: getInt(data, "total_tracks"),
+		IsPlaylist:  getBool(data, "is_playlist"),
+		Duration:    getInt(data, "duration"),
+	}, nil
+}
+
+// DownloadPlaylistItem downloads the track at index, counting from 0, of the
+// playlist at url within limits.
+func (c *Client) DownloadPlaylistItem(ctx context.Context, url string, index int, limits config.DownloadLimits) (*state.Song, error) {
+	started := time.Now()
+	data, err := c.call(ctx, "download_playlist_item", map[string]interface{}{
+		"url":                  url,
+		"index":                index,
+		"max_duration_seconds": limits.MaxDurationSeconds,
+		"max_size_mb":          limits.MaxSizeMB,
+		"allow_live":           limits.AllowLive,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			metrics.DownloadFinished(metrics.ResultError, time.Since(started))
+		}
+		return nil, err
+	}
+	metrics.DownloadFinished(metrics.ResultSuccess, time.Since(started))
+
+	song := &state.Song{
+		ID:            int64(getInt(data, "id")),
+		Title:         getString(data, "title"),
+		URL:           getString(data, "url"),
+		Platform:      getString(data, "platform"),
+		FilePath:      getString(data, "filename"),
+		Duration:      getInt(data, "duration"),
+		FileSize:      int64(getInt(data, "file_size")),
+		ThumbnailURL:  getString(data, "thumbnail_url"),
+		ThumbnailPath: getString(data, "thumbnail_path"),
+		Artist:        getString(data, "artist"),
+		IsStream:      getBool(data, "is_stream"),
+	}
+	song.StartOffset, song.EndOffset = musicOffsets(data, song.Duration)
+	song.UploadDate, song.ViewCount = trackMetadata(data)
+	return song, nil
+}
+
+// call sends command and waits for the response carrying the same request ID.
+// If ctx ends first the request is cancelled, so the downloader stops working
+// on it and a late answer is dropped rather than taken for a download nobody
+// asked for.
+func (c *Client) call(ctx context.Context, command string, params map[string]interface{}) (map[string]interface{}, error) {
 	if !c.IsConnected() {
-		return fmt.Errorf("not connected")
+		return nil, fmt.Errorf("not connected")
 	}
 
-	request := SearchRequest{
-		Command: "search",
-		ID:      c.generateRequestID(),
-		Params: map[string]interface{}{
-			"query":    query,
-			"platform": platform,
-			"limit":    limit,
-		},
+	requestID := c.generateRequestID(command)
+	request := DownloadRequest{
+		Command: command,
+		ID:      requestID,
+		Params:  params,
 	}
 
 	data, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	responseChan := make(chan interface{}, 1)
+	c.mu.Lock()
+	c.pendingRequests[requestID] = pendingCall{ch: responseChan, command: command, started: time.Now()}
+	c.mu.Unlock()
+
 	err = c.sendMessage(data)
 	if err != nil {
+		c.mu.Lock()
+		delete(c.pendingRequests, requestID)
+		c.mu.Unlock()
 		c.handleConnectionError(err)
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	return nil
+	logger.ForRequest(requestID).Debug("Sent downloader request", "command", command)
+
+	select {
+	case responseData := <-responseChan:
+		switch result := responseData.(type) {
+		case error:
+			return nil, result
+		case map[string]interface{}:
+			// Handlers report some failures as a successful response
+			// carrying an error status.
+			if getString(result, "status") == "error" {
+				return nil, &DownloaderError{
+					Code:    getString(result, "code"),
+					Message: getString(result, "message"),
+				}
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("unexpected response format for %s", command)
+	case <-ctx.Done():
+		if command == "cancel" {
+			c.mu.Lock()
+			delete(c.pendingRequests, requestID)
+			c.mu.Unlock()
+		} else {
+			go func() {
+				cancelCtx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+				defer cancel()
+				if err := c.CancelRequest(cancelCtx, requestID); err != nil {
+					logger.ForRequest(requestID).Debug("Failed to cancel abandoned request", "command", command, "error", err)
+				}
+			}()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Search returns the results of a search, reusing those of an identical
+// search made in the last few minutes. Identical searches made at the same
+// time share one downloader request. A search that takes longer than timeout
+// fails with ErrRequestTimeout.
+func (c *Client) Search(query string, platform string, limit int, timeout time.Duration) ([]SearchResult, error) {
+	key := newSearchKey(query, platform, limit)
+	results, hit, call, leader := c.searches.begin(key)
+	if hit {
+		logger.Debug.Printf("Search for %q on %s answered from the cache", query, platform)
+		return results, nil
+	}
+
+	if !leader {
+		select {
+		case <-call.done:
+			return copyResults(call.results), call.err
+		case <-time.After(timeout):
+			return nil, ErrRequestTimeout
+		}
+	}
+
+	results, err := c.search(query, platform, limit, timeout)
+	c.searches.finish(key, call, results, err)
+	return copyResults(results), err
+}
+
+// SearchCacheStats reports how well the search cache is doing.
+func (c *Client) SearchCacheStats() SearchCacheStats {
+	return c.searches.stats()
+}
+
+// search sends a search request and waits for the response carrying the same
+// request ID, so concurrent searches never see each other's results. A search
+// that takes longer than timeout is cancelled and fails with
+// ErrRequestTimeout.
+func (c *Client) search(query string, platform string, limit int, timeout time.Duration) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Info.Printf("Searching %s for %q", platform, query)
+	data, err := c.call(ctx, "search", map[string]interface{}{
+		"query":    query,
+		"platform": platform,
+		"limit":    limit,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrRequestTimeout
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results, _ := data["results"].([]interface{})
+	return parseSearchResults(results), nil
+}
+
+func parseSearchResults(results []interface{}) []SearchResult {
+	searchResults := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		if resultMap, ok := result.(map[string]interface{}); ok {
+			searchResults = append(searchResults, SearchResult{
+				Title:     getString(resultMap, "title"),
+				URL:       getString(resultMap, "url"),
+				Duration:  getInt(resultMap, "duration"),
+				Uploader:  getString(resultMap, "uploader"),
+				Thumbnail: getString(resultMap, "thumbnail"),
+				Platform:  getString(resultMap, "platform"),
+			})
+		}
+	}
+	return searchResults
 }
 
 func (c *Client) sendMessage(data []byte) error {
@@ -403,34 +813,25 @@ func (c *Client) sendMessage(data []byte) error {
 		return fmt.Errorf("no connection available")
 	}
 
-	messageLen := uint32(len(data))
-	lengthBuf := make([]byte, 4)
-	binary.BigEndian.PutUint32(lengthBuf, messageLen)
+	// The length prefix and the body go out in one write, so a frame is
+	// never split around another sender's.
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
 
-	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 
-	_, err := conn.Write(lengthBuf)
-	if err != nil {
-		return fmt.Errorf("failed to write length: %w", err)
-	}
+	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
 
-	_, err = conn.Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to write data: %w", err)
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Client) readMessage() ([]byte, error) {
-	c.mu.RLock()
-	conn := c.conn
-	c.mu.RUnlock()
-
-	if conn == nil {
-		return nil, fmt.Errorf("no connection available")
-	}
-
+func (c *Client) readMessage(conn net.Conn) ([]byte, error) {
 	conn.SetReadDeadline(time.Now().Add(10 * time.Minute)) // Longer read timeout
 
 	lengthBuf := make([]byte, 4)
@@ -464,20 +865,22 @@ func (c *Client) readMessage() ([]byte, error) {
 	return messageBuf, nil
 }
 
-func (c *Client) listenForResponses() {
+// listenForResponses reads conn until ctx, the context of its connection
+// generation, is cancelled or reading fails.
+func (c *Client) listenForResponses(ctx context.Context, conn net.Conn) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error.Printf("Socket listener panic: %v", r)
+			c.closeConnection(ctx, fmt.Errorf("listener panicked: %v", r))
 		}
-		c.handleConnectionError(fmt.Errorf("listener stopped"))
 	}()
 
-	for c.IsConnected() {
-		data, err := c.readMessage()
+	for ctx.Err() == nil {
+		data, err := c.readMessage(conn)
 		if err != nil {
-			if c.IsConnected() {
+			if ctx.Err() == nil {
 				logger.Error.Printf("Socket read error: %v", err)
-				c.handleConnectionError(err)
+				c.closeConnection(ctx, err)
 			}
 			return
 		}
@@ -521,11 +924,33 @@ func (c *Client) handleResponse(data []byte) {
 		return
 	}
 
+	logger.ForRequest(response.ID).Debug("Received downloader response", "type", response.Type, "status", response.Status)
+
+	if response.Type == "response" && c.isCancelled(response.ID) {
+		logger.ForRequest(response.ID).Debug("Dropping answer to cancelled request", "status", response.Status)
+		return
+	}
+
 	if response.Type == "response" {
 		if response.Status == "success" {
 			c.handleSuccessResponse(response)
 		} else if response.Status == "error" {
-			logger.Error.Printf("Download request failed: %s", response.Error)
+			if c.deliverPending(response.ID, fmt.Errorf("%s", response.Error)) {
+				return
+			}
+			url, ok := c.finishDownload(response.ID, metrics.ResultError)
+			if !ok {
+				if c.takePlaylistRequest(response.ID) {
+					logger.ForRequest(response.ID).Error("Downloader playlist request failed", "error", response.Error)
+					return
+				}
+				c.dropResponse(response)
+				return
+			}
+			logger.ForRequest(response.ID).Error("Downloader request failed", "error", response.Error)
+			if c.downloadFailHandler != nil {
+				c.downloadFailHandler(url, response.Error)
+			}
 			if c.downloadHandler != nil {
 				c.downloadHandler(nil)
 			}
@@ -537,6 +962,78 @@ func (c *Client) handleResponse(data []byte) {
 	}
 }
 
+// finishDownload records the outcome and duration of a single-track download
+// and returns the URL it was requested for. ok is false if id isn't a download
+// the client is waiting for.
+func (c *Client) finishDownload(id, result string) (url string, ok bool) {
+	c.mu.Lock()
+	started, ok := c.downloadStarts[id]
+	url = c.downloadURLs[id]
+	delete(c.downloadStarts, id)
+	delete(c.downloadURLs, id)
+	c.mu.Unlock()
+
+	if ok {
+		metrics.DownloadFinished(result, time.Since(started))
+	}
+	return url, ok
+}
+
+// takePlaylistRequest reports whether id is a playlist request sent by
+// SendPlaylistRequest, forgetting it since it is answered once.
+func (c *Client) takePlaylistRequest(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.playlistRequests[id]; !ok {
+		return false
+	}
+	delete(c.playlistRequests, id)
+	return true
+}
+
+// dropResponse logs an answer to a request the client isn't waiting for,
+// typically one that timed out. Taking it for a fresh download would queue
+// the song a second time.
+func (c *Client) dropResponse(response DownloadResponse) {
+	logger.ForRequest(response.ID).Warn("Dropping downloader response for unknown request",
+		"status", response.Status, "title", getString(response.Data, "title"), "error", response.Error)
+}
+
+// deliver hands value to a caller waiting on ch without ever blocking the
+// read loop. Response channels are buffered for the one answer they get.
+func deliver(ch chan interface{}, value interface{}) bool {
+	select {
+	case ch <- value:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliverPending hands a response to the caller waiting on its request ID.
+func (c *Client) deliverPending(id string, value interface{}) bool {
+	if id == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	call, ok := c.pendingRequests[id]
+	if ok {
+		delete(c.pendingRequests, id)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	if !deliver(call.ch, value) {
+		logger.ForRequest(id).Warn("Dropping downloader response, the caller already has one")
+	}
+	return true
+}
+
 func (c *Client) handleSuccessResponse(response DownloadResponse) {
 	data := response.Data
 	if data == nil {
@@ -544,82 +1041,99 @@ func (c *Client) handleSuccessResponse(response DownloadResponse) {
 	}
 
 	// Check if this is a response to a pending request
-	if response.ID != "" {
+	if c.deliverPending(response.ID, response.Data) {
+		return
+	}
+
+	// Pongs of fire-and-forget pings and of keepalives that timed out still
+	// show the downloader is alive.
+	if getString(data, "message") == "pong" {
 		c.mu.Lock()
-		if ch, ok := c.pendingRequests[response.ID]; ok {
-			ch <- response.Data
-			delete(c.pendingRequests, response.ID)
-			c.mu.Unlock()
-			return
-		}
+		c.lastDownloaderPing = time.Now()
 		c.mu.Unlock()
+		logger.Debug.Println("Received pong from downloader, updated lastDownloaderPing.")
+		return
 	}
 
-	if response.Status == "success" && response.ID != "" && response.Data != nil {
-		if msg, ok := response.Data["message"].(string); ok && msg == "pong" {
-			c.mu.Lock()
-			c.lastDownloaderPing = time.Now()
-			c.mu.Unlock()
-			logger.Debug.Println("Received pong from downloader, updated lastDownloaderPing.")
-		}
+	if c.takePlaylistRequest(response.ID) {
+		c.handlePlaylistResponse(data)
+		return
 	}
 
-	if title, hasTitle := data["title"].(string); hasTitle {
-		song := &state.Song{
-			ID:           int64(getInt(data, "id")),
-			Title:        title,
-			URL:          getString(data, "url"),
-			Platform:     getString(data, "platform"),
-			FilePath:     getString(data, "filename"),
-			Duration:     getInt(data, "duration"),
-			FileSize:     int64(getInt(data, "file_size")),
-			ThumbnailURL: getString(data, "thumbnail_url"),
-			Artist:       getString(data, "artist"),
-			IsStream:     getBool(data, "is_stream"),
-		}
+	title, hasTitle := data["title"].(string)
+	result := metrics.ResultSuccess
+	if !hasTitle {
+		result = metrics.ResultError
+	}
 
+	url, ok := c.finishDownload(response.ID, result)
+	if !ok {
+		c.dropResponse(response)
+		return
+	}
+
+	// A download answered without a song still has to be counted as done.
+	if !hasTitle {
+		reason := getString(data, "message")
+		if reason == "" {
+			reason = "the downloader returned no song"
+		}
+		logger.ForRequest(response.ID).Error("Downloader request failed", "error", reason)
+		if c.downloadFailHandler != nil {
+			c.downloadFailHandler(url, reason)
+		}
 		if c.downloadHandler != nil {
-			c.downloadHandler(song)
+			c.downloadHandler(nil)
 		}
+		return
 	}
 
-	if results, hasResults := data["results"].([]interface{}); hasResults {
-		searchResults := make([]SearchResult, 0)
-		for _, result := range results {
-			if resultMap, ok := result.(map[string]interface{}); ok {
-				searchResult := SearchResult{
-					Title:     getString(resultMap, "title"),
-					URL:       getString(resultMap, "url"),
-					Duration:  getInt(resultMap, "duration"),
-					Uploader:  getString(resultMap, "uploader"),
-					Thumbnail: getString(resultMap, "thumbnail"),
-					Platform:  getString(resultMap, "platform"),
-				}
-				searchResults = append(searchResults, searchResult)
-			}
-		}
+	song := &state.Song{
+		ID:            int64(getInt(data, "id")),
+		Title:         title,
+		URL:           getString(data, "url"),
+		Platform:      getString(data, "platform"),
+		FilePath:      getString(data, "filename"),
+		Duration:      getInt(data, "duration"),
+		FileSize:      int64(getInt(data, "file_size")),
+		ThumbnailURL:  getString(data, "thumbnail_url"),
+		ThumbnailPath: getString(data, "thumbnail_path"),
+		Artist:        getString(data, "artist"),
+		IsStream:      getBool(data, "is_stream"),
+	}
+	song.StartOffset, song.EndOffset = musicOffsets(data, song.Duration)
+	song.UploadDate, song.ViewCount = trackMetadata(data)
 
-		if c.searchHandler != nil {
-			c.searchHandler(searchResults)
-		}
+	logger.ForRequest(response.ID).Info("Download completed", "title", song.Title, "url", song.URL)
+
+	if c.downloadHandler != nil {
+		c.downloadHandler(song)
 	}
+}
 
+// handlePlaylistResponse handles the answer to a SendPlaylistRequest: the
+// downloaded tracks, or the start of a download that reports its tracks as
+// events.
+func (c *Client) handlePlaylistResponse(data map[string]interface{}) {
 	if items, hasItems := data["items"].([]interface{}); hasItems {
 		songs := make([]state.Song, 0)
 		for _, item := range items {
 			if itemMap, ok := item.(map[string]interface{}); ok {
 				song := state.Song{
-					ID:           int64(getInt(itemMap, "id")),
-					Title:        getString(itemMap, "title"),
-					URL:          getString(itemMap, "url"),
-					Platform:     getString(itemMap, "platform"),
-					FilePath:     getString(itemMap, "filename"),
-					Duration:     getInt(itemMap, "duration"),
-					FileSize:     int64(getInt(itemMap, "file_size")),
-					ThumbnailURL: getString(itemMap, "thumbnail_url"),
-					Artist:       getString(itemMap, "artist"),
-					IsStream:     getBool(itemMap, "is_stream"),
+					ID:            int64(getInt(itemMap, "id")),
+					Title:         getString(itemMap, "title"),
+					URL:           getString(itemMap, "url"),
+					Platform:      getString(itemMap, "platform"),
+					FilePath:      getString(itemMap, "filename"),
+					Duration:      getInt(itemMap, "duration"),
+					FileSize:      int64(getInt(itemMap, "file_size")),
+					ThumbnailURL:  getString(itemMap, "thumbnail_url"),
+					ThumbnailPath: getString(itemMap, "thumbnail_path"),
+					Artist:        getString(itemMap, "artist"),
+					IsStream:      getBool(itemMap, "is_stream"),
 				}
+				song.StartOffset, song.EndOffset = musicOffsets(itemMap, song.Duration)
+				song.UploadDate, song.ViewCount = trackMetadata(itemMap)
 				songs = append(songs, song)
 			}
 		}
@@ -645,17 +1159,20 @@ func (c *Client) handleEventResponse(response DownloadResponse) {
 
 		if trackData, hasTrack := data["track"].(map[string]interface{}); hasTrack {
 			song := &state.Song{
-				ID:           int64(getInt(trackData, "id")),
-				Title:        getString(trackData, "title"),
-				URL:          getString(trackData, "url"),
-				Platform:     getString(trackData, "platform"),
-				FilePath:     getString(trackData, "filename"),
-				Duration:     getInt(trackData, "duration"),
-				FileSize:     int64(getInt(trackData, "file_size")),
-				ThumbnailURL: getString(trackData, "thumbnail_url"),
-				Artist:       getString(trackData, "artist"),
-				IsStream:     getBool(trackData, "is_stream"),
+				ID:            int64(getInt(trackData, "id")),
+				Title:         getString(trackData, "title"),
+				URL:           getString(trackData, "url"),
+				Platform:      getString(trackData, "platform"),
+				FilePath:      getString(trackData, "filename"),
+				Duration:      getInt(trackData, "duration"),
+				FileSize:      int64(getInt(trackData, "file_size")),
+				ThumbnailURL:  getString(trackData, "thumbnail_url"),
+				ThumbnailPath: getString(trackData, "thumbnail_path"),
+				Artist:        getString(trackData, "artist"),
+				IsStream:      getBool(trackData, "is_stream"),
 			}
+			song.StartOffset, song.EndOffset = musicOffsets(trackData, song.Duration)
+			song.UploadDate, song.ViewCount = trackMetadata(trackData)
 
 			var playlistID string
 			if playlistData, hasPlaylist := data["playlist"].(map[string]interface{}); hasPlaylist {
@@ -668,6 +1185,13 @@ func (c *Client) handleEventResponse(response DownloadResponse) {
 				c.downloadHandler(song)
 			}
 		}
+	} else if response.Event == "playlist_download_completed" || response.Event == "playlist_download_error" {
+		playlistURL := getString(response.Data, "playlist_url")
+		logger.Info.Printf("Received event: %s for %s", response.Event, playlistURL)
+
+		if playlistURL != "" && c.playlistDoneHandler != nil {
+			c.playlistDoneHandler(playlistURL)
+		}
 	} else {
 		logger.Info.Printf("Received event: %s", response.Event)
 	}
@@ -701,7 +1225,7 @@ func (c *Client) Ping() error {
 
 	request := map[string]interface{}{
 		"command": "ping",
-		"id":      c.generateRequestID(),
+		"id":      c.generateRequestID("ping"),
 		"params":  map[string]interface{}{},
 	}
 
@@ -733,46 +1257,22 @@ func (c *Client) SendPingWithResponse() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("not connected to downloader")
 	}
 
-	requestID := c.generateRequestID()
-	request := map[string]interface{}{
-		"command": "ping",
-		"id":      requestID,
-		"params": map[string]interface{}{
-			"timestamp": time.Now().Format(time.RFC3339),
-		},
-	}
-
-	data, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal ping request: %w", err)
-	}
-
-	responseChan := make(chan interface{})
-	c.mu.Lock()
-	c.pendingRequests[requestID] = responseChan
-	c.mu.Unlock()
-
-	err = c.sendMessage(data)
-	if err != nil {
-		c.mu.Lock()
-		delete(c.pendingRequests, requestID)
-		c.mu.Unlock()
-		c.handleConnectionError(err)
-		return nil, fmt.Errorf("failed to send ping request: %w", err)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	select {
-	case responseData := <-responseChan:
-		if result, ok := responseData.(map[string]interface{}); ok {
-			return result, nil
-		}
-		return nil, fmt.Errorf("unexpected response format for ping")
-	case <-time.After(5 * time.Second):
-		c.mu.Lock()
-		delete(c.pendingRequests, requestID)
-		c.mu.Unlock()
+	data, err := c.call(ctx, "ping", map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
 		return nil, fmt.Errorf("ping response timed out")
 	}
+	return data, err
+}
+
+func (c *Client) LastDownloaderPing() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastDownloaderPing
 }
 
 func (c *Client) GetDownloaderStatus() string {
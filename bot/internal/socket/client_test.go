@@ -0,0 +1,352 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"musicbot/internal/config"
+	"musicbot/internal/socket/sockettest"
+	"musicbot/internal/state"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// newTestClient connects a client to a fake downloader. Both are shut down
+// when the test ends.
+func newTestClient(t *testing.T) (*Client, *sockettest.Server) {
+	t.Helper()
+	server := sockettest.NewServer(t)
+	client := NewClient(server.Path())
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	// Events are only sent on connections the server has accepted.
+	eventually(t, "the server to accept the client", func() bool {
+		return server.Connections() == 1
+	})
+	return client, server
+}
+
+// eventually fails the test if cond doesn't hold within five seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// track is how the downloader describes a downloaded track.
+func track(title string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       1,
+		"title":    title,
+		"url":      "https://soundcloud.com/artist/" + title,
+		"platform": "soundcloud",
+		"filename": title + ".mp3",
+		"duration": 180,
+	}
+}
+
+func TestCallAnswers(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Handle("get_playlist_info", func(request sockettest.Request) []sockettest.Response {
+		if request.Params["url"] == "https://soundcloud.com/artist/sets/broken" {
+			return []sockettest.Response{sockettest.Failure(request, "playlist is private")}
+		}
+		return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+			"playlist_title": "Mix",
+			"total_tracks":   12,
+			"is_playlist":    true,
+		})}
+	})
+
+	info, err := client.GetPlaylistInfo(context.Background(), "https://soundcloud.com/artist/sets/mix", 20)
+	if err != nil {
+		t.Fatalf("GetPlaylistInfo: %v", err)
+	}
+	if info != (PlaylistInfo{Title: "Mix", TotalTracks: 12, IsPlaylist: true}) {
+		t.Errorf("GetPlaylistInfo = %+v", info)
+	}
+
+	_, err = client.GetPlaylistInfo(context.Background(), "https://soundcloud.com/artist/sets/broken", 20)
+	if err == nil || err.Error() != "playlist is private" {
+		t.Errorf("GetPlaylistInfo of a private playlist = %v, want the downloader's error", err)
+	}
+}
+
+func TestDownloadRequest(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Handle("download_audio", func(request sockettest.Request) []sockettest.Response {
+		return []sockettest.Response{sockettest.Success(request, track("song"))}
+	})
+	songs := make(chan *state.Song, 1)
+	client.SetDownloadHandler(func(song *state.Song) { songs <- song })
+
+	if _, err := client.SendDownloadRequest("https://soundcloud.com/artist/song", "user", config.DownloadLimits{}); err != nil {
+		t.Fatalf("SendDownloadRequest: %v", err)
+	}
+	select {
+	case song := <-songs:
+		if song == nil || song.Title != "song" || song.FilePath != "song.mp3" {
+			t.Errorf("download handler got %+v", song)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the download never reached the download handler")
+	}
+	if pending := client.PendingRequests(); len(pending) != 0 {
+		t.Errorf("requests still pending after the answer: %+v", pending)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	client, server := newTestClient(t)
+	type item struct {
+		playlist string
+		song     *state.Song
+	}
+	items := make(chan item, 1)
+	done := make(chan string, 1)
+	client.SetPlaylistEventHandler(func(playlist string, song *state.Song) { items <- item{playlist, song} })
+	client.SetPlaylistDoneHandler(func(playlist string) { done <- playlist })
+
+	const playlist = "https://soundcloud.com/artist/sets/mix"
+	server.Send(sockettest.Event("playlist_item_downloaded", map[string]interface{}{
+		"track":    track("first"),
+		"playlist": map[string]interface{}{"url": playlist},
+	}))
+	select {
+	case got := <-items:
+		if got.playlist != playlist || got.song.Title != "first" {
+			t.Errorf("playlist event handler got %s, %+v", got.playlist, got.song)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the downloaded item never reached the playlist event handler")
+	}
+
+	server.Send(sockettest.Event("playlist_download_completed", map[string]interface{}{"playlist_url": playlist}))
+	select {
+	case got := <-done:
+		if got != playlist {
+			t.Errorf("playlist done handler got %q, want %q", got, playlist)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the completed playlist never reached the playlist done handler")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	client, server := newTestClient(t)
+
+	// get_playlist_info is left unanswered, so the call is still waiting
+	// when the downloader goes away.
+	failed := make(chan error, 1)
+	go func() {
+		_, err := client.GetPlaylistInfo(context.Background(), "https://soundcloud.com/artist/sets/mix", 20)
+		failed <- err
+	}()
+	server.Await("get_playlist_info", 1)
+
+	server.CloseConnections()
+	eventually(t, "the client to reconnect", func() bool {
+		return server.Accepted() == 2 && client.IsConnected()
+	})
+
+	select {
+	case err := <-failed:
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("call waiting across the reconnect got %v, want ErrConnectionLost", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call waiting across the reconnect never returned")
+	}
+
+	data, err := client.SendPingWithResponse()
+	if err != nil || getString(data, "message") != "pong" {
+		t.Errorf("ping after reconnecting = %v, %v", data, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	client, server := newTestClient(t)
+
+	// search is left unanswered.
+	_, err := client.Search("lofi", "soundcloud", 3, 100*time.Millisecond)
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("Search = %v, want ErrRequestTimeout", err)
+	}
+
+	search := server.Requests("search")
+	if len(search) != 1 {
+		t.Fatalf("got %d search requests, want 1", len(search))
+	}
+	cancel := server.Await("cancel", 1)
+	ids, _ := cancel[0].Params["ids"].([]interface{})
+	if len(ids) != 1 || ids[0] != search[0].ID {
+		t.Errorf("cancelled %v, want the search %s", ids, search[0].ID)
+	}
+	eventually(t, "the search to stop pending", func() bool {
+		return len(client.PendingRequests()) == 0
+	})
+}
+
+func TestConcurrentCallsGetTheirOwnAnswers(t *testing.T) {
+	client, server := newTestClient(t)
+
+	urls := []string{"https://soundcloud.com/artist/sets/a", "https://soundcloud.com/artist/sets/b"}
+	titles := make(chan [2]string, len(urls))
+	for _, url := range urls {
+		go func() {
+			info, err := client.GetPlaylistInfo(context.Background(), url, 20)
+			if err != nil {
+				t.Errorf("GetPlaylistInfo(%s): %v", url, err)
+			}
+			titles <- [2]string{url, info.Title}
+		}()
+	}
+
+	// Answer the requests in the opposite order they were made.
+	requests := server.Await("get_playlist_info", len(urls))
+	for k := len(requests) - 1; k >= 0; k-- {
+		server.Send(sockettest.Success(requests[k], map[string]interface{}{"playlist_title": requests[k].Params["url"]}))
+	}
+
+	for range urls {
+		select {
+		case got := <-titles:
+			if got[0] != got[1] {
+				t.Errorf("GetPlaylistInfo(%s) got the answer for %s", got[0], got[1])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a call never got its answer")
+		}
+	}
+}
+
+// TestLateAnswersAreNotQueued checks that answers to requests the client
+// gave up on never reach the download handler, where they would queue the
+// song a second time.
+func TestLateAnswersAreNotQueued(t *testing.T) {
+	client, server := newTestClient(t)
+	songs := make(chan *state.Song, 4)
+	client.SetDownloadHandler(func(song *state.Song) { songs <- song })
+
+	// download_playlist_item is left unanswered until the call times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.DownloadPlaylistItem(ctx, "https://soundcloud.com/artist/sets/mix", 0, config.DownloadLimits{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DownloadPlaylistItem = %v, want a timeout", err)
+	}
+	server.Await("cancel", 1)
+
+	timedOut := server.Requests("download_playlist_item")[0]
+	server.Send(sockettest.Success(timedOut, track("late")))
+	server.Send(sockettest.Success(sockettest.Request{ID: "dl_unknown"}, track("unknown")))
+
+	server.Handle("download_audio", func(request sockettest.Request) []sockettest.Response {
+		return []sockettest.Response{sockettest.Success(request, track("fresh"))}
+	})
+	if _, err := client.SendDownloadRequest("https://soundcloud.com/artist/fresh", "user", config.DownloadLimits{}); err != nil {
+		t.Fatalf("SendDownloadRequest: %v", err)
+	}
+
+	select {
+	case song := <-songs:
+		if song.Title != "fresh" {
+			t.Fatalf("download handler got %q, want only the fresh download", song.Title)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fresh download never reached the download handler")
+	}
+
+	// Answers are handled concurrently, so the dropped ones get a moment
+	// past the fresh download to show up.
+	select {
+	case song := <-songs:
+		t.Errorf("download handler got %q, an answer to a request nobody waits for", song.Title)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDuplicateAnswersAreDropped(t *testing.T) {
+	client, server := newTestClient(t)
+	songs := make(chan *state.Song, 2)
+	client.SetDownloadHandler(func(song *state.Song) { songs <- song })
+	twice := func(request sockettest.Request) []sockettest.Response {
+		answer := sockettest.Success(request, track("song"))
+		return []sockettest.Response{answer, answer}
+	}
+	server.Handle("download_audio", twice)
+	server.Handle("get_playlist_info", twice)
+
+	if _, err := client.SendDownloadRequest("https://soundcloud.com/artist/song", "user", config.DownloadLimits{}); err != nil {
+		t.Fatalf("SendDownloadRequest: %v", err)
+	}
+	select {
+	case <-songs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the download never reached the download handler")
+	}
+
+	// A call answered twice must neither block the read loop nor pass the
+	// second answer on as a download.
+	if _, err := client.GetPlaylistInfo(context.Background(), "https://soundcloud.com/artist/sets/mix", 20); err != nil {
+		t.Fatalf("GetPlaylistInfo: %v", err)
+	}
+	if _, err := client.SendPingWithResponse(); err != nil {
+		t.Fatalf("ping after duplicate answers: %v", err)
+	}
+
+	select {
+	case song := <-songs:
+		t.Errorf("download handler got %q a second time", song.Title)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// TestFlappingConnection drops the connection from the downloader's side and
+// reconnects 100 times, and checks that every generation's reader and
+// keepalive stop rather than piling up.
+func TestFlappingConnection(t *testing.T) {
+	const flaps = 100
+
+	client, server := newTestClient(t)
+	baseline := runtime.NumGoroutine()
+
+	for n := 0; n < flaps; n++ {
+		eventually(t, "the server to accept the new connection", func() bool {
+			return server.Accepted() > n && server.Connections() == 1
+		})
+		client.mu.RLock()
+		generation := client.connCtx
+		client.mu.RUnlock()
+
+		server.CloseConnections()
+		if n%2 == 0 {
+			// Let the reader notice, so it closes its generation and starts
+			// reconnecting; otherwise Reconnect gets there first.
+			eventually(t, "the client to notice the dropped connection", func() bool {
+				return generation.Err() != nil
+			})
+		}
+		if err := client.Reconnect(); err != nil {
+			t.Fatalf("Reconnect %d: %v", n, err)
+		}
+	}
+
+	// Readers that saw their connection drop start a reconnection that
+	// waits a second before noticing the client is connected again.
+	eventually(t, "the goroutines of old connections to stop", func() bool {
+		return runtime.NumGoroutine() <= baseline+2
+	})
+	eventually(t, "one connection to stay open", func() bool {
+		return server.Connections() == 1
+	})
+	if _, err := client.SendPingWithResponse(); err != nil {
+		t.Errorf("ping after flapping: %v", err)
+	}
+}
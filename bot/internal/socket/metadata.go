@@ -0,0 +1,46 @@
+package socket
+
+import (
+	"fmt"
+	"time"
+)
+
+// trackMetadata reads the optional upload date and view count of a track.
+// The downloader passes them on from yt-dlp as "upload_date", a YYYYMMDD
+// string, and "view_count". Some extractors only fill "release_date" (also
+// YYYYMMDD), "timestamp" (Unix seconds) or "release_year", and SoundCloud
+// reports plays as "play_count"; those are tried in that order. Tracks the
+// downloader answers from its cache come without either, which leaves the
+// date empty and the count zero.
+func trackMetadata(data map[string]interface{}) (uploadDate string, viewCount int64) {
+	for _, key := range []string{"upload_date", "release_date"} {
+		if date := isoDate(getString(data, key)); date != "" {
+			uploadDate = date
+			break
+		}
+	}
+	if uploadDate == "" {
+		if timestamp := getInt(data, "timestamp"); timestamp > 0 {
+			uploadDate = time.Unix(int64(timestamp), 0).UTC().Format("2006-01-02")
+		} else if year := getInt(data, "release_year"); year > 0 {
+			uploadDate = fmt.Sprintf("%04d", year)
+		}
+	}
+
+	for _, key := range []string{"view_count", "play_count"} {
+		if count, ok := data[key].(float64); ok && count > 0 {
+			viewCount = int64(count)
+			break
+		}
+	}
+	return uploadDate, viewCount
+}
+
+// isoDate turns yt-dlp's YYYYMMDD into YYYY-MM-DD, or "" if date isn't one.
+func isoDate(date string) string {
+	parsed, err := time.Parse("20060102", date)
+	if err != nil {
+		return ""
+	}
+	return parsed.Format("2006-01-02")
+}
@@ -0,0 +1,39 @@
+package socket
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Platforms a download request can name, so the downloader uses the right
+// extractor. Anything else is left to the downloader to tell from the URL.
+const (
+	PlatformYouTube      = "youtube"
+	PlatformYouTubeMusic = "ytmusic"
+	PlatformSoundCloud   = "soundcloud"
+	PlatformBandcamp     = "bandcamp"
+)
+
+// DetectPlatform tells the platform of a track from its URL, or returns ""
+// when it is none of the known ones.
+func DetectPlatform(rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	switch {
+	case host == "music.youtube.com":
+		return PlatformYouTubeMusic
+	case host == "youtube.com", host == "youtu.be", strings.HasSuffix(host, ".youtube.com"):
+		return PlatformYouTube
+	case host == "soundcloud.com", strings.HasSuffix(host, ".soundcloud.com"):
+		return PlatformSoundCloud
+	case host == "bandcamp.com", strings.HasSuffix(host, ".bandcamp.com"):
+		return PlatformBandcamp
+	}
+	return ""
+}
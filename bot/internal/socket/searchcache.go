@@ -0,0 +1,142 @@
+package socket
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// searchCacheTTL is how long search results are reused, long enough to
+	// catch several people looking up the same song.
+	searchCacheTTL = 5 * time.Minute
+
+	// searchCacheSize bounds the cache; the least recently used results go
+	// first.
+	searchCacheSize = 200
+)
+
+// searchKey is what the downloader is asked for in a search.
+type searchKey struct {
+	query    string
+	platform string
+	limit    int
+}
+
+func newSearchKey(query, platform string, limit int) searchKey {
+	return searchKey{
+		query:    strings.ToLower(strings.TrimSpace(query)),
+		platform: platform,
+		limit:    limit,
+	}
+}
+
+type searchEntry struct {
+	key     searchKey
+	results []SearchResult
+	stored  time.Time
+}
+
+// searchCall is a search being sent to the downloader. Identical searches
+// made meanwhile wait for it instead of sending their own.
+type searchCall struct {
+	done    chan struct{}
+	results []SearchResult
+	err     error
+}
+
+// SearchCacheStats counts the searches answered without the downloader,
+// including those that waited for an identical one, and those sent to it.
+type SearchCacheStats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+}
+
+// searchCache holds recent search results, shared by every guild, and the
+// searches still running.
+type searchCache struct {
+	entries  map[searchKey]*list.Element
+	order    *list.List
+	inflight map[searchKey]*searchCall
+	hits     int64
+	misses   int64
+	mu       sync.Mutex
+}
+
+func newSearchCache() *searchCache {
+	return &searchCache{
+		entries:  make(map[searchKey]*list.Element),
+		order:    list.New(),
+		inflight: make(map[searchKey]*searchCall),
+	}
+}
+
+// begin looks key up. It returns the cached results on a hit. Otherwise it
+// returns the running call for key, with leader set when the caller started
+// it and has to send the search and finish the call.
+func (sc *searchCache) begin(key searchKey) (results []SearchResult, hit bool, call *searchCall, leader bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if elem, ok := sc.entries[key]; ok {
+		entry := elem.Value.(*searchEntry)
+		if time.Since(entry.stored) < searchCacheTTL {
+			sc.order.MoveToFront(elem)
+			sc.hits++
+			return copyResults(entry.results), true, nil, false
+		}
+		sc.order.Remove(elem)
+		delete(sc.entries, key)
+	}
+
+	if call, ok := sc.inflight[key]; ok {
+		sc.hits++
+		return nil, false, call, false
+	}
+
+	sc.misses++
+	call = &searchCall{done: make(chan struct{})}
+	sc.inflight[key] = call
+	return nil, false, call, true
+}
+
+// finish hands the outcome of call to the searches waiting for it and keeps
+// successful results.
+func (sc *searchCache) finish(key searchKey, call *searchCall, results []SearchResult, err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	call.results, call.err = results, err
+	close(call.done)
+	delete(sc.inflight, key)
+
+	if err != nil {
+		return
+	}
+	if elem, ok := sc.entries[key]; ok {
+		sc.order.Remove(elem)
+	}
+	sc.entries[key] = sc.order.PushFront(&searchEntry{key: key, results: copyResults(results), stored: time.Now()})
+	for sc.order.Len() > searchCacheSize {
+		oldest := sc.order.Back()
+		sc.order.Remove(oldest)
+		delete(sc.entries, oldest.Value.(*searchEntry).key)
+	}
+}
+
+func (sc *searchCache) stats() SearchCacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return SearchCacheStats{Hits: sc.hits, Misses: sc.misses, Entries: sc.order.Len()}
+}
+
+// copyResults gives every caller its own results, so changing one never
+// reaches the cache or another caller.
+func copyResults(results []SearchResult) []SearchResult {
+	if results == nil {
+		return nil
+	}
+	return append([]SearchResult(nil), results...)
+}
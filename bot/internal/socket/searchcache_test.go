@@ -0,0 +1,117 @@
+package socket
+
+import (
+	"fmt"
+	"musicbot/internal/socket/sockettest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParallelSearchesShareOneRequest(t *testing.T) {
+	const searches = 20
+
+	client, server := newTestClient(t)
+	release := make(chan struct{})
+	server.Handle("search", func(request sockettest.Request) []sockettest.Response {
+		<-release
+		return []sockettest.Response{sockettest.Success(request, map[string]interface{}{
+			"results": []interface{}{map[string]interface{}{"title": "Lofi", "url": "https://soundcloud.com/artist/lofi"}},
+		})}
+	})
+
+	results := make(chan []SearchResult, searches)
+	var wg sync.WaitGroup
+	for n := 0; n < searches; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Case and surrounding spaces don't make a search different.
+			query := "lofi"
+			if n%2 == 1 {
+				query = " LoFi "
+			}
+			found, err := client.Search(query, "soundcloud", 3, 5*time.Second)
+			if err != nil {
+				t.Errorf("Search: %v", err)
+			}
+			results <- found
+		}()
+	}
+
+	// Hold the answer back until every search has either started the
+	// request or joined it.
+	eventually(t, "every search to start", func() bool {
+		stats := client.SearchCacheStats()
+		return stats.Hits+stats.Misses == searches
+	})
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := len(server.Requests("search")); n != 1 {
+		t.Errorf("downloader searched %d times, want once", n)
+	}
+	if stats := client.SearchCacheStats(); stats.Misses != 1 || stats.Hits != searches-1 {
+		t.Errorf("stats = %+v, want 1 miss and %d hits", stats, searches-1)
+	}
+
+	// Every caller gets results of its own to change.
+	for found := range results {
+		if len(found) != 1 || found[0].Title != "Lofi" {
+			t.Fatalf("Search = %+v", found)
+		}
+		found[0].Title = "changed"
+	}
+	cached, err := client.Search("lofi", "soundcloud", 3, time.Second)
+	if err != nil || len(cached) != 1 || cached[0].Title != "Lofi" {
+		t.Errorf("cached Search = %+v, %v, want the results as the downloader sent them", cached, err)
+	}
+}
+
+func TestFailedSearchIsNotCached(t *testing.T) {
+	client, server := newTestClient(t)
+	server.Handle("search", func(request sockettest.Request) []sockettest.Response {
+		return []sockettest.Response{sockettest.Failure(request, "search failed")}
+	})
+
+	for n := 1; n <= 2; n++ {
+		if _, err := client.Search("lofi", "soundcloud", 3, time.Second); err == nil {
+			t.Fatalf("Search %d succeeded, want the downloader's error", n)
+		}
+		if got := len(server.Requests("search")); got != n {
+			t.Errorf("after %d failed searches the downloader searched %d times", n, got)
+		}
+	}
+}
+
+func TestSearchCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	sc := newSearchCache()
+	store := func(key searchKey) {
+		_, _, call, leader := sc.begin(key)
+		if !leader {
+			t.Fatalf("%v was already cached or running", key)
+		}
+		sc.finish(key, call, []SearchResult{{Title: key.query}}, nil)
+	}
+
+	first := newSearchKey("query 0", "soundcloud", 3)
+	for n := 0; n < searchCacheSize; n++ {
+		store(newSearchKey(fmt.Sprintf("query %d", n), "soundcloud", 3))
+	}
+	// Using the first keeps it; the second becomes the oldest.
+	if _, hit, _, _ := sc.begin(first); !hit {
+		t.Fatal("first search isn't cached")
+	}
+	store(newSearchKey("one too many", "soundcloud", 3))
+
+	if entries := sc.stats().Entries; entries != searchCacheSize {
+		t.Errorf("cache holds %d entries, want %d", entries, searchCacheSize)
+	}
+	if _, hit, _, _ := sc.begin(first); !hit {
+		t.Error("the recently used search was evicted")
+	}
+	if _, hit, _, _ := sc.begin(newSearchKey("query 1", "soundcloud", 3)); hit {
+		t.Error("the least recently used search is still cached")
+	}
+}
@@ -0,0 +1,280 @@
+// Package sockettest runs a fake downloader on a unix socket, speaking the
+// length-prefixed JSON protocol of the real one, for tests of the client and
+// of everything that downloads through it.
+package sockettest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Request is a request the fake downloader received.
+type Request struct {
+	Command string                 `json:"command"`
+	ID      string                 `json:"id"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+// Response is a frame the fake downloader sends: the answer to a request or,
+// with Type "event", something it reports on its own.
+type Response struct {
+	Type   string                 `json:"type"`
+	Status string                 `json:"status"`
+	ID     string                 `json:"id"`
+	Event  string                 `json:"event,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// Success answers request with data.
+func Success(request Request, data map[string]interface{}) Response {
+	return Response{Type: "response", Status: "success", ID: request.ID, Data: data}
+}
+
+// Failure answers request with an error.
+func Failure(request Request, message string) Response {
+	return Response{Type: "response", Status: "error", ID: request.ID, Error: message}
+}
+
+// Event is an event the downloader sends without being asked.
+func Event(name string, data map[string]interface{}) Response {
+	return Response{Type: "event", Status: "success", Event: name, Data: data}
+}
+
+// Handler answers a request with the frames it returns, in order. Returning
+// none leaves the request unanswered. Handlers run concurrently, each request
+// in a goroutine of its own, so one may block to hold its answer back.
+type Handler func(request Request) []Response
+
+// Server is a fake downloader. Until told otherwise it answers ping with a
+// pong and cancel with how many requests it cancelled, and leaves every other
+// command unanswered.
+type Server struct {
+	t        testing.TB
+	path     string
+	listener net.Listener
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	requests []Request
+	conns    map[*conn]bool
+	accepted int
+	received chan struct{}
+}
+
+type conn struct {
+	net.Conn
+	writeMu sync.Mutex
+}
+
+// NewServer starts a fake downloader that is stopped when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	// Unix socket paths are limited to about a hundred bytes, which the
+	// test's own temp directory can exceed.
+	dir, err := os.MkdirTemp("", "sockettest")
+	if err != nil {
+		t.Fatalf("creating socket directory: %v", err)
+	}
+	path := filepath.Join(dir, "downloader.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("listening on %s: %v", path, err)
+	}
+
+	s := &Server{
+		t:        t,
+		path:     path,
+		listener: listener,
+		handlers: make(map[string]Handler),
+		conns:    make(map[*conn]bool),
+		received: make(chan struct{}),
+	}
+	s.Handle("ping", func(request Request) []Response {
+		return []Response{Success(request, map[string]interface{}{"message": "pong"})}
+	})
+	s.Handle("cancel", func(request Request) []Response {
+		ids, _ := request.Params["ids"].([]interface{})
+		return []Response{Success(request, map[string]interface{}{"cancelled": len(ids)})}
+	})
+
+	go s.accept()
+	t.Cleanup(func() {
+		listener.Close()
+		s.CloseConnections()
+		os.RemoveAll(dir)
+	})
+	return s
+}
+
+// Path is the socket to connect the client to.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Handle makes handler answer every later request for command.
+func (s *Server) Handle(command string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+}
+
+// Requests returns the requests received for command so far, oldest first.
+func (s *Server) Requests(command string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []Request
+	for _, request := range s.requests {
+		if request.Command == command {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// Await waits until n requests for command have been received and returns
+// them, failing the test if they don't arrive within five seconds.
+func (s *Server) Await(command string, n int) []Request {
+	s.t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		s.mu.Lock()
+		received := s.received
+		s.mu.Unlock()
+
+		if requests := s.Requests(command); len(requests) >= n {
+			return requests
+		}
+		select {
+		case <-received:
+		case <-deadline:
+			s.t.Fatalf("got %d %s requests, want %d", len(s.Requests(command)), command, n)
+			return nil
+		}
+	}
+}
+
+// Send sends response on every open connection, as the downloader does with
+// events and with answers that come late.
+func (s *Server) Send(response Response) {
+	s.t.Helper()
+	for _, c := range s.openConns() {
+		if err := c.write(response); err != nil {
+			s.t.Errorf("sending %s: %v", response.ID, err)
+		}
+	}
+}
+
+// CloseConnections drops every open connection, as a restart of the
+// downloader would. The server keeps accepting new ones.
+func (s *Server) CloseConnections() {
+	for _, c := range s.openConns() {
+		c.Close()
+	}
+}
+
+// Connections returns how many connections are open.
+func (s *Server) Connections() int {
+	return len(s.openConns())
+}
+
+// Accepted returns how many connections were accepted in all.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+func (s *Server) openConns() []*conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (s *Server) accept() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{Conn: netConn}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.accepted++
+		s.mu.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c *conn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(c, length[:]); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(c, body); err != nil {
+			return
+		}
+
+		var request Request
+		if err := json.Unmarshal(body, &request); err != nil {
+			s.t.Errorf("fake downloader got a frame that isn't a request: %q", body)
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, request)
+		handler := s.handlers[request.Command]
+		close(s.received)
+		s.received = make(chan struct{})
+		s.mu.Unlock()
+
+		if handler == nil {
+			continue
+		}
+		go func() {
+			for _, response := range handler(request) {
+				if err := c.write(response); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (c *conn) write(response Response) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.Write(frame)
+	return err
+}
@@ -0,0 +1,481 @@
+package state
+
+import (
+	"musicbot/internal/logger"
+	"sync"
+	"time"
+)
+
+// Guild is the voice, radio and music state of one guild. Settings shared by
+// all guilds, such as the volume and radio stream, are read from the Manager.
+type Guild struct {
+	id             string
+	manager        *Manager
+	botState       BotState
+	opState        OperationState
+	voiceState     VoiceState
+	radioState     RadioState
+	musicState     MusicState
+	announceChan   string
+	auditChan      string
+	followedUser   string
+	filter         string
+	queueMode      string
+	requestChannel string
+	lastActivity   time.Time
+	manualOpActive bool
+	idleTimer      *time.Timer
+	audioOwner     AudioOwner
+	mu             sync.RWMutex
+}
+
+func newGuild(id string, manager *Manager) *Guild {
+	return &Guild{
+		id:           id,
+		manager:      manager,
+		botState:     StateIdle,
+		lastActivity: time.Now(),
+	}
+}
+
+func (g *Guild) ID() string {
+	return g.id
+}
+
+func (g *Guild) GetBotState() BotState {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.botState
+}
+
+func (g *Guild) SetBotState(state BotState) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.botState = state
+	g.lastActivity = time.Now()
+}
+
+func (g *Guild) IsShuttingDown() bool {
+	return g.manager.IsShuttingDown()
+}
+
+func (g *Guild) IsManualOperationActive() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.manualOpActive
+}
+
+// SetManualOperationActive marks a command working on the voice connection.
+// Starting one cancels a pending idle transition, since the command decides
+// what plays next.
+func (g *Guild) SetManualOperationActive(active bool) {
+	if active {
+		g.CancelIdle("manual operation")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.manualOpActive = active
+}
+
+// ScheduleIdle runs fn after delay unless CancelIdle is called first. An
+// idle transition that is already scheduled is replaced.
+func (g *Guild) ScheduleIdle(delay time.Duration, fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.idleTimer != nil {
+		g.idleTimer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		g.mu.Lock()
+		current := g.idleTimer == timer
+		if current {
+			g.idleTimer = nil
+		}
+		g.mu.Unlock()
+
+		if current {
+			fn()
+		}
+	})
+	g.idleTimer = timer
+}
+
+// CancelIdle stops a pending idle transition and reports whether there was
+// one. reason is logged to trace why the radio didn't start.
+func (g *Guild) CancelIdle(reason string) bool {
+	g.mu.Lock()
+	timer := g.idleTimer
+	g.idleTimer = nil
+	g.mu.Unlock()
+
+	if timer == nil {
+		return false
+	}
+
+	timer.Stop()
+	logger.Debug.Printf("Idle transition in guild %s cancelled: %s", g.id, reason)
+	return true
+}
+
+// Reset forgets what the guild was doing, after the bot was removed from it:
+// the connection, what played and any pending idle transition. Its settings,
+// such as the idle channel and filter, are kept.
+func (g *Guild) Reset() {
+	g.CancelIdle("guild removed")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.botState = StateIdle
+	g.opState = OperationState{}
+	g.voiceState = VoiceState{
+		IdleChannel:     g.voiceState.IdleChannel,
+		AlwaysOnChannel: g.voiceState.AlwaysOnChannel,
+	}
+	g.radioState = RadioState{}
+	g.musicState = MusicState{}
+	g.followedUser = ""
+	g.manualOpActive = false
+	g.audioOwner = AudioNone
+	g.lastActivity = time.Now()
+}
+
+func (g *Guild) IsOperationInProgress() bool {
+	if g.IsShuttingDown() {
+		return false
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.opState.IsJoining || g.opState.IsLeaving || g.opState.IsStreaming || g.opState.IsPlaying
+}
+
+func (g *Guild) SetJoining(joining bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.opState.IsJoining = joining
+}
+
+func (g *Guild) SetLeaving(leaving bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.opState.IsLeaving = leaving
+}
+
+func (g *Guild) SetStreaming(streaming bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.opState.IsStreaming = streaming
+}
+
+func (g *Guild) SetPlaying(playing bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.opState.IsPlaying = playing
+	g.musicState.IsPlaying = playing
+}
+
+func (g *Guild) GetCurrentChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.voiceState.CurrentChannel
+}
+
+func (g *Guild) SetCurrentChannel(channel string) {
+	shuttingDown := g.IsShuttingDown()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.voiceState.CurrentChannel = channel
+	if !shuttingDown {
+		g.lastActivity = time.Now()
+	}
+}
+
+func (g *Guild) GetIdleChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.voiceState.IdleChannel
+}
+
+func (g *Guild) SetIdleChannel(channel string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.voiceState.IdleChannel = channel
+}
+
+// GetAlwaysOnChannel returns the channel 24/7 mode keeps the bot in, or "" if
+// 24/7 mode is off.
+func (g *Guild) GetAlwaysOnChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.voiceState.AlwaysOnChannel
+}
+
+func (g *Guild) SetAlwaysOnChannel(channel string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.voiceState.AlwaysOnChannel = channel
+}
+
+// HomeChannel returns where the bot goes when it is done in a channel: the
+// 24/7 channel if 24/7 mode is on, otherwise the idle channel.
+func (g *Guild) HomeChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.voiceState.AlwaysOnChannel != "" {
+		return g.voiceState.AlwaysOnChannel
+	}
+	return g.voiceState.IdleChannel
+}
+
+func (g *Guild) IsHome() bool {
+	return g.GetCurrentChannel() == g.HomeChannel()
+}
+
+// GetAnnounceChannel returns the text channel for bot notices, or "" if the
+// guild has none.
+func (g *Guild) GetAnnounceChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.announceChan
+}
+
+func (g *Guild) SetAnnounceChannel(channel string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.announceChan = channel
+}
+
+// GetAuditChannel returns the moderation channel audit lines are mirrored to,
+// or "" if the guild has none.
+func (g *Guild) GetAuditChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.auditChan
+}
+
+func (g *Guild) SetAuditChannel(channel string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.auditChan = channel
+}
+
+// GetFilter returns the name of the audio filter preset songs play with, or
+// "" if none was chosen.
+func (g *Guild) GetFilter() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.filter
+}
+
+func (g *Guild) SetFilter(filter string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.filter = filter
+}
+
+// GetRequestChannel returns the text channel whose messages are taken as
+// song requests, or "" if there is none.
+func (g *Guild) GetRequestChannel() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.requestChannel
+}
+
+func (g *Guild) SetRequestChannel(channel string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requestChannel = channel
+}
+
+// GetQueueMode returns the name of the mode the queue picks the next song
+// with, or "" if none was chosen.
+func (g *Guild) GetQueueMode() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.queueMode
+}
+
+func (g *Guild) SetQueueMode(mode string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queueMode = mode
+}
+
+// GetFollowedUser returns the DJ the bot moves between voice channels with,
+// or "" if follow mode is off.
+func (g *Guild) GetFollowedUser() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.followedUser
+}
+
+func (g *Guild) SetFollowedUser(userID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.followedUser = userID
+}
+
+func (g *Guild) IsInIdleChannel() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.voiceState.CurrentChannel == g.voiceState.IdleChannel
+}
+
+func (g *Guild) IsConnected() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.voiceState.IsConnected
+}
+
+func (g *Guild) SetConnected(connected bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.voiceState.IsConnected = connected
+}
+
+func (g *Guild) GetRadioStream() string {
+	return g.manager.GetRadioStream()
+}
+
+func (g *Guild) SetRadioStream(stream string) {
+	g.manager.SetRadioStream(stream)
+	g.touch()
+}
+
+func (g *Guild) GetVolume() float32 {
+	return g.manager.GetVolume()
+}
+
+func (g *Guild) SetVolume(volume float32) {
+	g.manager.SetVolume(volume)
+	g.touch()
+}
+
+func (g *Guild) IsRadioPlaying() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.radioState.IsPlaying
+}
+
+func (g *Guild) SetRadioPlaying(playing bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.radioState.IsPlaying = playing
+}
+
+// AcquireAudio makes owner the one sending audio to the voice connection.
+// It fails while another owner holds it; acquiring it again is a no-op.
+func (g *Guild) AcquireAudio(owner AudioOwner) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.audioOwner != AudioNone && g.audioOwner != owner {
+		return false
+	}
+	g.audioOwner = owner
+	return true
+}
+
+// ReleaseAudio gives the voice connection up, if owner still holds it.
+func (g *Guild) ReleaseAudio(owner AudioOwner) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.audioOwner == owner {
+		g.audioOwner = AudioNone
+	}
+}
+
+func (g *Guild) GetAudioOwner() AudioOwner {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.audioOwner
+}
+
+func (g *Guild) GetCurrentSong() *Song {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.musicState.CurrentSong
+}
+
+func (g *Guild) SetCurrentSong(song *Song) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.musicState.CurrentSong = song
+	g.lastActivity = time.Now()
+}
+
+func (g *Guild) IsMusicPlaying() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.musicState.IsPlaying
+}
+
+func (g *Guild) IsMusicPaused() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.musicState.IsPaused
+}
+
+func (g *Guild) SetMusicPaused(paused bool) {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.musicState.IsPaused = paused
+	if !paused {
+		g.lastActivity = time.Now()
+	}
+}
+
+func (g *Guild) GetQueuePosition() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.musicState.QueuePosition
+}
+
+func (g *Guild) SetQueuePosition(position int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.musicState.QueuePosition = position
+	g.lastActivity = time.Now()
+}
+
+func (g *Guild) GetConfig() Config {
+	return g.manager.GetConfig()
+}
+
+func (g *Guild) touch() {
+	if g.IsShuttingDown() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastActivity = time.Now()
+}
@@ -0,0 +1,49 @@
+package state
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGuildStatesChangeIndependently(t *testing.T) {
+	m := NewManager(Config{})
+	a := m.AddGuild("guildA", "idleA")
+	b := m.AddGuild("guildB", "idleB")
+
+	var wg sync.WaitGroup
+	run := func(guild *Guild, channel string, final BotState) {
+		defer wg.Done()
+		for range 1000 {
+			guild.SetBotState(StateRadio)
+			guild.SetCurrentChannel(channel)
+			guild.SetBotState(StateDJ)
+			guild.SetCurrentChannel(guild.GetIdleChannel())
+		}
+		guild.SetCurrentChannel(channel)
+		guild.SetBotState(final)
+	}
+
+	wg.Add(2)
+	go run(a, "musicA", StateDJ)
+	go run(b, "musicB", StateRadio)
+	wg.Wait()
+
+	if got := a.GetBotState(); got != StateDJ {
+		t.Errorf("guildA state = %v, want %v", got, StateDJ)
+	}
+	if got := b.GetBotState(); got != StateRadio {
+		t.Errorf("guildB state = %v, want %v", got, StateRadio)
+	}
+	if got := a.GetCurrentChannel(); got != "musicA" {
+		t.Errorf("guildA channel = %q, want musicA", got)
+	}
+	if got := b.GetCurrentChannel(); got != "musicB" {
+		t.Errorf("guildB channel = %q, want musicB", got)
+	}
+	if a.GetIdleChannel() != "idleA" || b.GetIdleChannel() != "idleB" {
+		t.Errorf("idle channels = %q, %q; want idleA, idleB", a.GetIdleChannel(), b.GetIdleChannel())
+	}
+	if m.Guild("guildA") != a || m.Guild("guildB") != b {
+		t.Error("Guild returned a different state than AddGuild")
+	}
+}
@@ -1,5 +1,7 @@
 package state
 
+import "time"
+
 type BotState int
 
 const (
@@ -9,6 +11,16 @@ const (
 	StateTransitioning
 )
 
+// AudioOwner is what sends audio over a guild's voice connection. Only one
+// owner at a time may, or the radio and music play over each other.
+type AudioOwner string
+
+const (
+	AudioNone  AudioOwner = ""
+	AudioRadio AudioOwner = "radio"
+	AudioMusic AudioOwner = "music"
+)
+
 type OperationState struct {
 	IsJoining   bool
 	IsLeaving   bool
@@ -17,15 +29,14 @@ type OperationState struct {
 }
 
 type VoiceState struct {
-	CurrentChannel string
-	IdleChannel    string
-	IsConnected    bool
+	CurrentChannel  string
+	IdleChannel     string
+	AlwaysOnChannel string
+	IsConnected     bool
 }
 
 type RadioState struct {
-	CurrentStream string
-	Volume        float32
-	IsPlaying     bool
+	IsPlaying bool
 }
 
 type MusicState struct {
@@ -38,10 +49,33 @@ type MusicState struct {
 type Config struct {
 	Token       string
 	UDSPath     string
-	IdleChannel string
+	DownloadDir string
 	Volume      float32
 	Stream      string
 	Streams     []StreamOption
+
+	// PlaylistWorkers is how many tracks of a playlist download at once.
+	PlaylistWorkers int
+
+	// IdleDelay is how long the bot waits after the queue ends before it
+	// goes back to the radio.
+	IdleDelay time.Duration
+
+	// FiltersDisabled turns audio filter presets off on hosts without the
+	// CPU to spare for them.
+	FiltersDisabled bool
+
+	// PurgeOnLeave deletes a guild's settings when the bot is removed from
+	// it.
+	PurgeOnLeave bool
+
+	// RadioAutoResume moves the radio back to where it was playing after a
+	// restart.
+	RadioAutoResume bool
+
+	// RequestChannels lets guilds take song requests as plain messages in
+	// a text channel. It needs the message content intent.
+	RequestChannels bool
 }
 
 type StreamOption struct {
@@ -60,11 +94,43 @@ type Song struct {
 	FileSize     int64  `json:"file_size"`
 	ThumbnailURL string `json:"thumbnail_url"`
 	IsStream     bool   `json:"is_stream"`
+	RequesterID  string `json:"requester_id,omitempty"`
+
+	// ThumbnailPath is the thumbnail the downloader saved next to the file,
+	// shown when ThumbnailURL no longer loads. "" when there is none.
+	ThumbnailPath string `json:"thumbnail_path,omitempty"`
+
+	// RequestedAt is when the song was queued, or zero when unknown.
+	RequestedAt time.Time `json:"requested_at,omitempty"`
+
+	// UploadDate (YYYY-MM-DD, or just the year) and ViewCount are known for
+	// some platforms only. Zero values mean unknown.
+	UploadDate string `json:"upload_date,omitempty"`
+	ViewCount  int64  `json:"view_count,omitempty"`
+
+	// StartOffset and EndOffset trim an intro or outro, in seconds into the
+	// file. Zero means play from the start or to the end.
+	StartOffset int `json:"start_offset,omitempty"`
+	EndOffset   int `json:"end_offset,omitempty"`
+}
+
+// PlayLength is how many seconds of the song play once it is trimmed.
+func (s *Song) PlayLength() int {
+	end := s.Duration
+	if s.EndOffset > 0 && s.EndOffset < end {
+		end = s.EndOffset
+	}
+	if s.StartOffset >= end {
+		return end
+	}
+	return end - s.StartOffset
 }
 
 type QueueItem struct {
-	ID       int64 `json:"id"`
-	SongID   int64 `json:"song_id"`
-	Position int   `json:"position"`
-	Song     *Song `json:"song,omitempty"`
+	ID          int64     `json:"id"`
+	SongID      int64     `json:"song_id"`
+	Position    int       `json:"position"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	RequestedAt time.Time `json:"requested_at,omitempty"`
+	Song        *Song     `json:"song,omitempty"`
 }
@@ -0,0 +1,91 @@
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that say how a link was shared, or
+// where to start playing, rather than what it points to.
+var trackingParams = map[string]bool{
+	"t":           true,
+	"start":       true,
+	"si":          true,
+	"feature":     true,
+	"pp":          true,
+	"ref":         true,
+	"index":       true,
+	"list":        true,
+	"start_radio": true,
+	"fbclid":      true,
+	"gclid":       true,
+	"in":          true,
+}
+
+// Normalize returns the canonical form of a link to a single track, so that
+// every way of sharing the same song maps to one URL:
+//
+//   - YouTube links (youtu.be, m., music., shorts) become
+//     https://www.youtube.com/watch?v=ID
+//   - SoundCloud and other links lose tracking parameters, start offsets and
+//     fragments, and get a lowercase host without www. or m.
+//
+// Playlist URLs must not be passed in, since their list parameter is dropped.
+// Anything that isn't an absolute http(s) URL is returned trimmed.
+func Normalize(rawURL string) string {
+	trimmed := strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(trimmed)
+	if err != nil || parsed.Host == "" {
+		return trimmed
+	}
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+		return trimmed
+	}
+
+	if id := youtubeID(parsed); id != "" {
+		return "https://www.youtube.com/watch?v=" + id
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	query := parsed.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if trackingParams[lower] || strings.HasPrefix(lower, "utm_") {
+			query.Del(key)
+		}
+	}
+
+	normalized := url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     strings.TrimSuffix(parsed.Path, "/"),
+		RawQuery: query.Encode(),
+	}
+	return normalized.String()
+}
+
+// youtubeID returns the video ID of a YouTube link, or "" for anything else.
+func youtubeID(parsed *url.URL) string {
+	host := strings.ToLower(parsed.Hostname())
+	for _, prefix := range []string{"www.", "m.", "music."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	switch host {
+	case "youtu.be":
+		return strings.Trim(parsed.Path, "/")
+	case "youtube.com", "youtube-nocookie.com":
+		if id := parsed.Query().Get("v"); id != "" {
+			return id
+		}
+		for _, prefix := range []string{"/shorts/", "/embed/", "/live/"} {
+			if id, ok := strings.CutPrefix(parsed.Path, prefix); ok {
+				return strings.Trim(id, "/")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package urlnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	const video = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"canonical youtube", video, video},
+		{"youtube start offset", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s", video},
+		{"youtube share link", "https://youtu.be/dQw4w9WgXcQ?si=abc123", video},
+		{"youtube music", "https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share", video},
+		{"youtube mobile playlist item", "http://m.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123&index=4", video},
+		{"youtube shorts", "https://www.youtube.com/shorts/dQw4w9WgXcQ/", video},
+		{"youtube embed", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", video},
+		{"soundcloud tracking", "https://soundcloud.com/artist/song?utm_source=clipboard&utm_medium=text&in=artist/sets/mix", "https://soundcloud.com/artist/song"},
+		{"soundcloud host and slash", "https://M.SoundCloud.com/artist/song/#comments", "https://soundcloud.com/artist/song"},
+		{"other query kept", "https://www.example.com/track?id=7&ref=home", "https://example.com/track?id=7"},
+		{"surrounding space", "  https://soundcloud.com/artist/song  ", "https://soundcloud.com/artist/song"},
+		{"search query", " lofi beats ", "lofi beats"},
+		{"not http", "ftp://example.com/song.mp3", "ftp://example.com/song.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeIsStable(t *testing.T) {
+	for _, in := range []string{
+		"https://youtu.be/dQw4w9WgXcQ?t=10",
+		"https://soundcloud.com/artist/song?utm_source=x",
+		"https://example.com/a?b=1&c=2",
+	} {
+		once := Normalize(in)
+		if twice := Normalize(once); twice != once {
+			t.Errorf("Normalize(%q) = %q, but normalizing that gives %q", in, once, twice)
+		}
+	}
+}
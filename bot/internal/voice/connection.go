@@ -2,9 +2,12 @@ package voice
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"musicbot/internal/discordapi"
 	"musicbot/internal/logger"
 	"musicbot/internal/state"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,49 +16,66 @@ import (
 const (
 	maxJoinRetries = 3
 	joinRetryDelay = 2 * time.Second
+
+	maxReconnectAttempts = 5
+	reconnectBaseDelay   = time.Second
+
+	readyTimeout = 10 * time.Second
+
+	// leaveIntentWindow is how long after the bot leaves on purpose a
+	// disconnect event is still treated as expected.
+	leaveIntentWindow = 10 * time.Second
 )
 
+// ErrReconnectAborted is returned by Reconnect when shutdown or a deliberate
+// voice operation makes reconnecting pointless.
+var ErrReconnectAborted = errors.New("reconnect aborted")
+
 type Connection struct {
-	session      *discordgo.Session
-	stateManager *state.Manager
-	connection   *discordgo.VoiceConnection
+	session     discordapi.Session
+	guildState  *state.Guild
+	connection  *discordgo.VoiceConnection
+	lastChannel string
+	leftAt      atomic.Int64
 }
 
-func NewConnection(session *discordgo.Session, stateManager *state.Manager) *Connection {
+func NewConnection(session discordapi.Session, guildState *state.Guild) *Connection {
 	return &Connection{
-		session:      session,
-		stateManager: stateManager,
+		session:    session,
+		guildState: guildState,
 	}
 }
 
 func (c *Connection) Join(guildID, channelID string) error {
-	if c.stateManager.IsShuttingDown() {
+	if c.guildState.IsShuttingDown() {
 		logger.Debug.Println("Ignoring join request during shutdown")
 		return fmt.Errorf("bot is shutting down")
 	}
 
-	if c.stateManager.IsOperationInProgress() {
-		return fmt.Errorf("operation already in progress")
-	}
-
-	c.stateManager.SetJoining(true)
-	defer c.stateManager.SetJoining(false)
+	c.guildState.SetJoining(true)
+	defer c.guildState.SetJoining(false)
 
 	if c.connection != nil && c.connection.ChannelID == channelID {
 		logger.Info.Printf("Already connected to channel %s", channelID)
 		return nil
 	}
 
+	// Checked before leaving the current channel, so a refused join
+	// doesn't leave the bot nowhere.
+	if err := CheckJoin(c.session, guildID, channelID); err != nil {
+		return err
+	}
+
 	if c.connection != nil {
 		logger.Info.Println("Disconnecting from current channel...")
-		c.connection.Disconnect()
+		c.markLeaving()
+		c.session.ChannelVoiceLeave(c.connection)
 		c.connection = nil
-		time.Sleep(500 * time.Millisecond)
 	}
 
 	var lastErr error
 	for attempt := 1; attempt <= maxJoinRetries; attempt++ {
-		if c.stateManager.IsShuttingDown() {
+		if c.guildState.IsShuttingDown() {
 			logger.Debug.Printf("Aborting join attempt %d due to shutdown", attempt)
 			return fmt.Errorf("bot is shutting down")
 		}
@@ -63,21 +83,21 @@ func (c *Connection) Join(guildID, channelID string) error {
 		logger.Info.Printf("Joining voice channel %s (attempt %d/%d)", channelID, attempt, maxJoinRetries)
 
 		vc, err := c.session.ChannelVoiceJoin(guildID, channelID, false, true)
+		if err == nil {
+			err = waitReady(vc)
+		}
 		if err != nil {
 			lastErr = err
 			logger.Error.Printf("Join attempt %d failed: %v", attempt, err)
 
-			if attempt < maxJoinRetries && !c.stateManager.IsShuttingDown() {
+			if attempt < maxJoinRetries && !c.guildState.IsShuttingDown() {
 				time.Sleep(joinRetryDelay * time.Duration(attempt))
 				continue
 			}
 		} else {
-			c.connection = vc
-			c.stateManager.SetCurrentChannel(channelID)
-			c.stateManager.SetConnected(true)
+			c.connected(vc, channelID)
 
 			logger.Info.Printf("Successfully joined voice channel %s", channelID)
-			time.Sleep(300 * time.Millisecond)
 			return nil
 		}
 	}
@@ -85,14 +105,74 @@ func (c *Connection) Join(guildID, channelID string) error {
 	return fmt.Errorf("failed to join voice channel after %d attempts: %w", maxJoinRetries, lastErr)
 }
 
-func (c *Connection) Leave() error {
-	if c.stateManager.IsOperationInProgress() && !c.stateManager.IsShuttingDown() {
-		return fmt.Errorf("operation already in progress")
+// LeaveMark identifies the last deliberate leave. Rejoin compares it to spot
+// a leave or join that happened after the connection dropped.
+func (c *Connection) LeaveMark() int64 {
+	return c.leftAt.Load()
+}
+
+// Rejoin makes one attempt to get back into the last channel after Discord
+// dropped the connection. It returns ErrReconnectAborted if the bot is
+// shutting down or has left or joined a channel on purpose since leaveMark
+// was taken.
+func (c *Connection) Rejoin(guildID string, leaveMark int64) error {
+	if c.guildState.IsShuttingDown() || c.leftAt.Load() != leaveMark || c.connection != nil {
+		return ErrReconnectAborted
+	}
+
+	channelID := c.lastChannel
+	if channelID == "" {
+		return fmt.Errorf("no channel to reconnect to")
+	}
+
+	if err := CheckJoin(c.session, guildID, channelID); err != nil {
+		return err
 	}
 
-	if !c.stateManager.IsShuttingDown() {
-		c.stateManager.SetLeaving(true)
-		defer c.stateManager.SetLeaving(false)
+	c.guildState.SetJoining(true)
+	defer c.guildState.SetJoining(false)
+
+	vc, err := c.session.ChannelVoiceJoin(guildID, channelID, false, true)
+	if err != nil {
+		return err
+	}
+	if err := waitReady(vc); err != nil {
+		return err
+	}
+
+	c.connected(vc, channelID)
+	logger.Info.Printf("Reconnected to voice channel %s", channelID)
+	return nil
+}
+
+func (c *Connection) connected(vc *discordgo.VoiceConnection, channelID string) {
+	c.connection = vc
+	c.lastChannel = channelID
+	c.guildState.SetCurrentChannel(channelID)
+	c.guildState.SetConnected(true)
+}
+
+// markLeaving records that the bot is about to leave voice on purpose, so the
+// disconnect event that follows is not mistaken for a drop.
+func (c *Connection) markLeaving() {
+	c.leftAt.Store(time.Now().UnixNano())
+}
+
+// ConsumeLeaveIntent reports whether the bot left voice on purpose within the
+// last few seconds, and clears the record so only one disconnect event
+// matches it.
+func (c *Connection) ConsumeLeaveIntent() bool {
+	leftAt := c.leftAt.Load()
+	if leftAt == 0 || time.Since(time.Unix(0, leftAt)) >= leaveIntentWindow {
+		return false
+	}
+	return c.leftAt.CompareAndSwap(leftAt, 0)
+}
+
+func (c *Connection) Leave() error {
+	if !c.guildState.IsShuttingDown() {
+		c.guildState.SetLeaving(true)
+		defer c.guildState.SetLeaving(false)
 	}
 
 	if c.connection == nil {
@@ -103,10 +183,11 @@ func (c *Connection) Leave() error {
 	channelID := c.connection.ChannelID
 	logger.Info.Printf("Leaving voice channel %s", channelID)
 
-	err := c.connection.Disconnect()
+	c.markLeaving()
+	err := c.session.ChannelVoiceLeave(c.connection)
 	c.connection = nil
-	c.stateManager.SetCurrentChannel("")
-	c.stateManager.SetConnected(false)
+	c.guildState.SetCurrentChannel("")
+	c.guildState.SetConnected(false)
 
 	if err != nil {
 		logger.Error.Printf("Error disconnecting from voice: %v", err)
@@ -129,33 +210,55 @@ func (c *Connection) IsConnectedTo(channelID string) bool {
 }
 
 func (c *Connection) HandleDisconnect() {
-	if c.stateManager.IsShuttingDown() {
+	if c.guildState.IsShuttingDown() {
 		logger.Info.Println("Expected voice disconnection during shutdown")
 	} else {
 		logger.Info.Println("Handling unexpected voice disconnection")
 	}
 
+	// Close the dropped connection so it doesn't linger as a ghost still
+	// marked as speaking; rejoining opens it again.
+	if c.connection != nil {
+		c.connection.Close()
+	}
 	c.connection = nil
-	c.stateManager.SetCurrentChannel("")
-	c.stateManager.SetConnected(false)
+	c.guildState.SetCurrentChannel("")
+	c.guildState.SetConnected(false)
 }
 
 func (c *Connection) Shutdown(ctx context.Context) error {
 	logger.Info.Println("Shutting down voice connection...")
 
-	c.stateManager.SetShuttingDown(true)
-
 	if c.connection != nil {
-		err := c.connection.Disconnect()
+		err := c.session.ChannelVoiceLeave(c.connection)
 		c.connection = nil
-		c.stateManager.SetCurrentChannel("")
-		c.stateManager.SetConnected(false)
+		c.guildState.SetCurrentChannel("")
+		c.guildState.SetConnected(false)
 		return err
 	}
 
 	return nil
 }
 
+// waitReady waits for discordgo to report the voice connection as ready to
+// send audio.
+func waitReady(vc *discordgo.VoiceConnection) error {
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		vc.RLock()
+		ready := vc.Ready
+		vc.RUnlock()
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for voice connection to be ready")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func (c *Connection) Name() string {
 	return "VoiceConnection"
 }
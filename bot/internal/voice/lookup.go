@@ -0,0 +1,99 @@
+package voice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/logger"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// lookupTTL is how long a voice state fetched over REST is trusted. The
+// gateway keeps the state cache current once it has the user, so this only
+// has to cover the gap right after startup or on a cache miss.
+const lookupTTL = 15 * time.Second
+
+var ErrNotInVoice = errors.New("user not in voice channel")
+
+type lookupEntry struct {
+	channelID string
+	fetched   time.Time
+}
+
+var lookups = struct {
+	entries map[string]lookupEntry
+	mu      sync.Mutex
+}{entries: make(map[string]lookupEntry)}
+
+// UserVoiceChannel returns the voice channel userID is connected to in
+// guildID. The session's state cache is checked first; when it doesn't know
+// the user, which happens right after startup and in large guilds, the voice
+// state is fetched from the REST API and kept for a short while.
+func UserVoiceChannel(session discordapi.Session, guildID, userID string) (string, error) {
+	vs, err := session.Cache().VoiceState(guildID, userID)
+	if err == nil && vs != nil {
+		if vs.ChannelID == "" {
+			return "", ErrNotInVoice
+		}
+		return vs.ChannelID, nil
+	}
+
+	key := guildID + ":" + userID
+
+	lookups.mu.Lock()
+	entry, ok := lookups.entries[key]
+	lookups.mu.Unlock()
+
+	if !ok || time.Since(entry.fetched) > lookupTTL {
+		channelID, err := fetchVoiceChannel(session, guildID, userID)
+		if err != nil {
+			return "", err
+		}
+
+		entry = lookupEntry{channelID: channelID, fetched: time.Now()}
+		lookups.mu.Lock()
+		pruneLookups()
+		lookups.entries[key] = entry
+		lookups.mu.Unlock()
+	}
+
+	if entry.channelID == "" {
+		return "", ErrNotInVoice
+	}
+	return entry.channelID, nil
+}
+
+// fetchVoiceChannel asks Discord for the user's voice state. A user who isn't
+// in voice has no voice state, which the API reports as not found.
+func fetchVoiceChannel(session discordapi.Session, guildID, userID string) (string, error) {
+	endpoint := discordgo.EndpointGuild(guildID) + "/voice-states/" + userID
+	body, err := session.RequestWithBucketID(http.MethodGet, endpoint, nil, discordgo.EndpointGuild(guildID)+"/voice-states/")
+	if err != nil {
+		var restErr *discordgo.RESTError
+		if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+		logger.Error.Printf("Failed to fetch voice state of %s in guild %s: %v", userID, guildID, err)
+		return "", ErrNotInVoice
+	}
+
+	var vs discordgo.VoiceState
+	if err := json.Unmarshal(body, &vs); err != nil {
+		return "", fmt.Errorf("failed to decode voice state: %w", err)
+	}
+	return vs.ChannelID, nil
+}
+
+// pruneLookups drops expired entries. The caller holds lookups.mu.
+func pruneLookups() {
+	for key, entry := range lookups.entries {
+		if time.Since(entry.fetched) > lookupTTL {
+			delete(lookups.entries, key)
+		}
+	}
+}
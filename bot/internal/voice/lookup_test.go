@@ -0,0 +1,129 @@
+package voice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// restSession answers voice state requests from channels, keyed by user ID,
+// as Discord's REST API would. A user without an entry gets a 404.
+type restSession struct {
+	*fakeSession
+	channels map[string]string
+	failWith error
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (s *restSession) RequestWithBucketID(method, urlStr string, data interface{}, bucketID string, options ...discordgo.RequestOption) ([]byte, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, urlStr)
+	s.mu.Unlock()
+
+	if s.failWith != nil {
+		return nil, s.failWith
+	}
+	userID := urlStr[strings.LastIndex(urlStr, "/")+1:]
+	channelID, ok := s.channels[userID]
+	if !ok {
+		return nil, &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	}
+	return json.Marshal(discordgo.VoiceState{UserID: userID, ChannelID: channelID})
+}
+
+func (s *restSession) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// forgetLookups empties the voice states fetched so far when the test ends.
+func forgetLookups(t *testing.T) {
+	t.Cleanup(func() {
+		lookups.mu.Lock()
+		defer lookups.mu.Unlock()
+		lookups.entries = make(map[string]lookupEntry)
+	})
+}
+
+func TestUserVoiceChannelPrefersTheCache(t *testing.T) {
+	forgetLookups(t)
+	session := &restSession{
+		fakeSession: newFakeSession(t, "guild", &discordgo.VoiceState{GuildID: "guild", UserID: "user", ChannelID: "cached"}),
+		channels:    map[string]string{"user": "fetched"},
+	}
+
+	channelID, err := UserVoiceChannel(session, "guild", "user")
+	if err != nil || channelID != "cached" {
+		t.Fatalf("UserVoiceChannel = %q, %v, want cached", channelID, err)
+	}
+	if n := session.requestCount(); n != 0 {
+		t.Errorf("made %d REST requests for a user in the cache", n)
+	}
+}
+
+func TestUserVoiceChannelFallsBackToREST(t *testing.T) {
+	forgetLookups(t)
+	session := &restSession{fakeSession: newFakeSession(t, "guild"), channels: map[string]string{"user": "voice"}}
+
+	for n := 1; n <= 2; n++ {
+		channelID, err := UserVoiceChannel(session, "guild", "user")
+		if err != nil || channelID != "voice" {
+			t.Fatalf("lookup %d = %q, %v, want voice", n, channelID, err)
+		}
+	}
+	if n := session.requestCount(); n != 1 {
+		t.Errorf("made %d REST requests, want the second lookup answered from the first", n)
+	}
+	if want := discordgo.EndpointGuild("guild") + "/voice-states/user"; session.requests[0] != want {
+		t.Errorf("requested %s, want %s", session.requests[0], want)
+	}
+
+	// Once the fetched state is too old it is asked for again.
+	lookups.mu.Lock()
+	lookups.entries["guild:user"] = lookupEntry{channelID: "voice", fetched: time.Now().Add(-lookupTTL - time.Second)}
+	lookups.mu.Unlock()
+	session.channels["user"] = "moved"
+	if channelID, err := UserVoiceChannel(session, "guild", "user"); err != nil || channelID != "moved" {
+		t.Errorf("lookup after the TTL = %q, %v, want moved", channelID, err)
+	}
+}
+
+func TestUserVoiceChannelNotInVoice(t *testing.T) {
+	forgetLookups(t)
+	session := &restSession{fakeSession: newFakeSession(t, "guild"), channels: map[string]string{}}
+
+	// Discord answers 404 for a user who isn't in voice, which is kept like
+	// any other answer.
+	for n := 1; n <= 2; n++ {
+		if _, err := UserVoiceChannel(session, "guild", "user"); !errors.Is(err, ErrNotInVoice) {
+			t.Fatalf("lookup %d = %v, want ErrNotInVoice", n, err)
+		}
+	}
+	if n := session.requestCount(); n != 1 {
+		t.Errorf("made %d REST requests, want 1", n)
+	}
+}
+
+func TestUserVoiceChannelRequestFailure(t *testing.T) {
+	forgetLookups(t)
+	session := &restSession{fakeSession: newFakeSession(t, "guild"), failWith: errors.New("connection reset")}
+
+	// A failed request isn't an answer, so it isn't kept.
+	for n := 1; n <= 2; n++ {
+		if _, err := UserVoiceChannel(session, "guild", "user"); !errors.Is(err, ErrNotInVoice) {
+			t.Fatalf("lookup %d = %v, want ErrNotInVoice", n, err)
+		}
+	}
+	if n := session.requestCount(); n != 2 {
+		t.Errorf("made %d REST requests, want 2", n)
+	}
+}
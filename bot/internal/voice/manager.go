@@ -2,61 +2,130 @@ package voice
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"musicbot/internal/discordapi"
 	"musicbot/internal/logger"
 	"musicbot/internal/state"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// ErrManagerClosed is returned for voice requests made after Shutdown.
+var ErrManagerClosed = errors.New("voice manager is shut down")
+
+// Manager owns one guild's voice connection. Joins, leaves and moves are
+// queued and run one at a time by a single goroutine, so concurrent commands
+// and events can't race each other into duplicate or ghost connections.
 type Manager struct {
-	operations   *Operations
-	stateManager *state.Manager
+	operations *Operations
+	guildState *state.Guild
+	requests   chan request
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+type request struct {
+	run    func() error
+	result chan error
 }
 
-func NewManager(session *discordgo.Session, stateManager *state.Manager) *Manager {
-	return &Manager{
-		operations:   NewOperations(session, stateManager),
-		stateManager: stateManager,
+func NewManager(session discordapi.Session, guildState *state.Guild) *Manager {
+	m := &Manager{
+		operations: NewOperations(session, guildState),
+		guildState: guildState,
+		requests:   make(chan request),
+		done:       make(chan struct{}),
 	}
+
+	go m.run()
+
+	return m
+}
+
+func (m *Manager) run() {
+	for {
+		select {
+		case req := <-m.requests:
+			req.result <- req.run()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// do queues fn behind any voice operation already running and waits for it.
+func (m *Manager) do(fn func() error) error {
+	req := request{run: fn, result: make(chan error, 1)}
+
+	select {
+	case m.requests <- req:
+	case <-m.done:
+		return ErrManagerClosed
+	}
+
+	return <-req.result
 }
 
 func (m *Manager) JoinUser(guildID, userID string) error {
-	if m.stateManager.IsShuttingDown() {
+	if m.guildState.IsShuttingDown() {
 		logger.Debug.Println("Ignoring join user request during shutdown")
 		return nil
 	}
 
 	logger.Info.Printf("Attempting to join user %s in guild %s", userID, guildID)
-	return m.operations.JoinUserChannel(guildID, userID)
+	return m.do(func() error {
+		return m.operations.JoinUserChannel(guildID, userID)
+	})
 }
 
 func (m *Manager) LeaveToIdle(guildID string) error {
-	if m.stateManager.IsShuttingDown() {
+	if m.guildState.IsShuttingDown() {
 		logger.Debug.Println("Ignoring leave to idle request during shutdown")
 		return nil
 	}
 
 	logger.Info.Printf("Leaving to idle channel in guild %s", guildID)
-	return m.operations.LeaveToIdle(guildID)
+	return m.do(func() error {
+		return m.operations.LeaveToIdle(guildID)
+	})
 }
 
 func (m *Manager) ReturnToIdle(guildID string) error {
-	if m.stateManager.IsShuttingDown() {
+	if m.guildState.IsShuttingDown() {
 		logger.Debug.Println("Ignoring return to idle request during shutdown")
 		return nil
 	}
 
 	logger.Info.Printf("Returning to idle channel in guild %s", guildID)
-	return m.operations.ReturnToIdle(guildID)
+	return m.do(func() error {
+		return m.operations.ReturnToIdle(guildID)
+	})
+}
+
+// MoveTo moves the bot into channelID, as follow mode does when the followed
+// DJ changes channels.
+func (m *Manager) MoveTo(guildID, channelID string) error {
+	if m.guildState.IsShuttingDown() {
+		logger.Debug.Println("Ignoring move request during shutdown")
+		return nil
+	}
+
+	logger.Info.Printf("Moving to voice channel %s in guild %s", channelID, guildID)
+	return m.do(func() error {
+		return m.operations.GetConnection().Join(guildID, channelID)
+	})
 }
 
 func (m *Manager) HandleUserLeft(guildID, channelID string) error {
-	if m.stateManager.IsShuttingDown() {
+	if m.guildState.IsShuttingDown() {
 		logger.Debug.Println("Ignoring user left event during shutdown")
 		return nil
 	}
 
-	if m.stateManager.IsInIdleChannel() {
+	if m.guildState.IsHome() {
 		logger.Info.Println("Already in idle channel, no action needed")
 		return nil
 	}
@@ -71,30 +140,85 @@ func (m *Manager) HandleUserLeft(guildID, channelID string) error {
 
 	if userCount == 0 {
 		logger.Info.Println("Channel is empty, returning to idle")
-		return m.operations.ReturnToIdle(guildID)
+		return m.do(func() error {
+			return m.operations.ReturnToIdle(guildID)
+		})
 	}
 
 	return nil
 }
 
-func (m *Manager) HandleDisconnect(guildID string) error {
-	if m.stateManager.IsShuttingDown() {
-		logger.Info.Println("Expected disconnect during shutdown, not reconnecting")
-		m.operations.GetConnection().HandleDisconnect()
+// HandleDisconnect records that the bot is no longer in voice. It returns
+// true if the disconnect was expected, either because the bot is shutting
+// down or because it left on purpose; only unexpected drops need Reconnect.
+func (m *Manager) HandleDisconnect(guildID string) bool {
+	connection := m.operations.GetConnection()
+
+	var expected bool
+	err := m.do(func() error {
+		if m.guildState.IsShuttingDown() {
+			logger.Info.Println("Expected disconnect during shutdown, not reconnecting")
+			connection.HandleDisconnect()
+			expected = true
+			return nil
+		}
+
+		if connection.ConsumeLeaveIntent() {
+			logger.Debug.Printf("Ignoring disconnect the bot asked for in guild %s", guildID)
+			expected = true
+			return nil
+		}
+
+		logger.Info.Printf("Handling unexpected disconnect in guild %s", guildID)
+		connection.HandleDisconnect()
 		return nil
+	})
+	if errors.Is(err, ErrManagerClosed) {
+		return true
 	}
 
-	logger.Info.Printf("Handling unexpected disconnect in guild %s", guildID)
+	return expected
+}
 
-	m.operations.GetConnection().HandleDisconnect()
+// Reconnect rejoins the channel the bot was in before an unexpected
+// disconnect, backing off between attempts. Each attempt is queued on its
+// own, so a deliberate join or leave in the meantime runs first and cancels
+// the reconnect.
+func (m *Manager) Reconnect(guildID string) error {
+	connection := m.operations.GetConnection()
+	leaveMark := connection.LeaveMark()
+	delay := reconnectBaseDelay
 
-	idleChannel := m.stateManager.GetIdleChannel()
-	if idleChannel == "" {
-		logger.Error.Println("No idle channel configured")
-		return nil
+	var lastErr error
+	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
+		logger.Info.Printf("Reconnecting to voice in guild %s (attempt %d/%d)", guildID, attempt, maxReconnectAttempts)
+
+		err := m.do(func() error {
+			return connection.Rejoin(guildID, leaveMark)
+		})
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrReconnectAborted) || errors.Is(err, ErrManagerClosed) {
+			return ErrReconnectAborted
+		}
+
+		// Retrying won't grant a permission or free up a seat.
+		var channelErr *ChannelError
+		if errors.As(err, &channelErr) {
+			return fmt.Errorf("failed to reconnect: %w", err)
+		}
+
+		lastErr = err
+		logger.Error.Printf("Reconnect attempt %d failed: %v", attempt, err)
+
+		if attempt < maxReconnectAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
 	}
 
-	return m.operations.GetConnection().Join(guildID, idleChannel)
+	return fmt.Errorf("failed to reconnect after %d attempts: %w", maxReconnectAttempts, lastErr)
 }
 
 func (m *Manager) GetVoiceConnection() *discordgo.VoiceConnection {
@@ -105,9 +229,25 @@ func (m *Manager) IsConnectedTo(channelID string) bool {
 	return m.operations.GetConnection().IsConnectedTo(channelID)
 }
 
+// Shutdown disconnects once the running operation finishes, or straight away
+// if ctx expires first, and then stops accepting requests.
 func (m *Manager) Shutdown(ctx context.Context) error {
 	logger.Info.Println("Shutting down voice manager...")
-	return m.operations.GetConnection().Shutdown(ctx)
+	defer m.closeOnce.Do(func() { close(m.done) })
+
+	result := make(chan error, 1)
+	go func() {
+		result <- m.do(func() error {
+			return m.operations.GetConnection().Shutdown(ctx)
+		})
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return m.operations.GetConnection().Shutdown(ctx)
+	}
 }
 
 func (m *Manager) Name() string {
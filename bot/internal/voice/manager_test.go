@@ -0,0 +1,121 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/state"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeSession hands out voice connections that are ready straight away and
+// keeps track of which are still open. Lookups are answered from its cache.
+type fakeSession struct {
+	discordapi.Session
+
+	cache *discordgo.State
+
+	mu   sync.Mutex
+	open map[*discordgo.VoiceConnection]bool
+}
+
+func newFakeSession(t *testing.T, guildID string, voiceStates ...*discordgo.VoiceState) *fakeSession {
+	t.Helper()
+	cache := discordgo.NewState()
+	cache.User = &discordgo.User{ID: "bot"}
+	if err := cache.GuildAdd(&discordgo.Guild{ID: guildID, VoiceStates: voiceStates}); err != nil {
+		t.Fatal(err)
+	}
+	return &fakeSession{cache: cache, open: make(map[*discordgo.VoiceConnection]bool)}
+}
+
+func (s *fakeSession) ChannelVoiceJoin(guildID, channelID string, mute, deaf bool) (*discordgo.VoiceConnection, error) {
+	// Joining takes a moment, which is when overlapping joins would race.
+	time.Sleep(time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for vc := range s.open {
+		if vc.GuildID == guildID {
+			return nil, fmt.Errorf("already connected to %s", vc.ChannelID)
+		}
+	}
+	vc := &discordgo.VoiceConnection{GuildID: guildID, ChannelID: channelID, Ready: true}
+	s.open[vc] = true
+	return vc, nil
+}
+
+func (s *fakeSession) ChannelVoiceLeave(vc *discordgo.VoiceConnection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.open[vc] {
+		return fmt.Errorf("connection to %s is not open", vc.ChannelID)
+	}
+	delete(s.open, vc)
+	return nil
+}
+
+func (s *fakeSession) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	return discordgo.PermissionAll, nil
+}
+
+func (s *fakeSession) Cache() *discordgo.State {
+	return s.cache
+}
+
+func (s *fakeSession) openConnections() []*discordgo.VoiceConnection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var open []*discordgo.VoiceConnection
+	for vc := range s.open {
+		open = append(open, vc)
+	}
+	return open
+}
+
+func TestConcurrentJoinsAndLeavesLeaveOneConnection(t *testing.T) {
+	const guildID = "guild"
+
+	session := newFakeSession(t, guildID,
+		&discordgo.VoiceState{GuildID: guildID, UserID: "a", ChannelID: "channel-a"},
+		&discordgo.VoiceState{GuildID: guildID, UserID: "b", ChannelID: "channel-b"},
+	)
+	guildState := state.NewManager(state.Config{}).Guild(guildID)
+	guildState.SetIdleChannel("idle")
+	m := NewManager(session, guildState)
+	t.Cleanup(func() { m.Shutdown(context.Background()) })
+
+	var wg sync.WaitGroup
+	for n := 0; n < 50; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Errors such as "already in user's channel" are expected; what
+			// matters is the connection they leave behind.
+			switch n % 3 {
+			case 0:
+				m.JoinUser(guildID, "a")
+			case 1:
+				m.JoinUser(guildID, "b")
+			default:
+				m.ReturnToIdle(guildID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	open := session.openConnections()
+	if len(open) != 1 {
+		t.Fatalf("%d connections are open, want 1", len(open))
+	}
+	if vc := m.GetVoiceConnection(); vc != open[0] {
+		t.Errorf("the manager holds %v, want the open connection to %s", vc, open[0].ChannelID)
+	}
+	if current := guildState.GetCurrentChannel(); current != open[0].ChannelID {
+		t.Errorf("current channel = %q, want %q", current, open[0].ChannelID)
+	}
+}
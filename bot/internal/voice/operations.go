@@ -2,22 +2,21 @@ package voice
 
 import (
 	"fmt"
+	"musicbot/internal/discordapi"
 	"musicbot/internal/state"
-
-	"github.com/bwmarrin/discordgo"
 )
 
 type Operations struct {
-	connection   *Connection
-	stateManager *state.Manager
-	session      *discordgo.Session
+	connection *Connection
+	guildState *state.Guild
+	session    discordapi.Session
 }
 
-func NewOperations(session *discordgo.Session, stateManager *state.Manager) *Operations {
+func NewOperations(session discordapi.Session, guildState *state.Guild) *Operations {
 	return &Operations{
-		connection:   NewConnection(session, stateManager),
-		stateManager: stateManager,
-		session:      session,
+		connection: NewConnection(session, guildState),
+		guildState: guildState,
+		session:    session,
 	}
 }
 
@@ -27,7 +26,7 @@ func (o *Operations) JoinUserChannel(guildID, userID string) error {
 		return fmt.Errorf("user not in voice channel")
 	}
 
-	currentChannel := o.stateManager.GetCurrentChannel()
+	currentChannel := o.guildState.GetCurrentChannel()
 	if currentChannel == userChannel {
 		return fmt.Errorf("already in user's channel")
 	}
@@ -35,11 +34,12 @@ func (o *Operations) JoinUserChannel(guildID, userID string) error {
 	return o.connection.Join(guildID, userChannel)
 }
 
+// LeaveToIdle and ReturnToIdle take the bot to its home channel, which is
+// the 24/7 channel rather than the idle channel while 24/7 mode is on.
 func (o *Operations) LeaveToIdle(guildID string) error {
-	idleChannel := o.stateManager.GetIdleChannel()
-	currentChannel := o.stateManager.GetCurrentChannel()
+	homeChannel := o.guildState.HomeChannel()
 
-	if currentChannel == idleChannel {
+	if o.guildState.IsHome() {
 		return fmt.Errorf("already in idle channel")
 	}
 
@@ -47,13 +47,13 @@ func (o *Operations) LeaveToIdle(guildID string) error {
 		return err
 	}
 
-	return o.connection.Join(guildID, idleChannel)
+	return o.connection.Join(guildID, homeChannel)
 }
 
 func (o *Operations) ReturnToIdle(guildID string) error {
-	idleChannel := o.stateManager.GetIdleChannel()
+	homeChannel := o.guildState.HomeChannel()
 
-	if o.stateManager.IsInIdleChannel() {
+	if o.guildState.IsHome() {
 		return nil
 	}
 
@@ -61,7 +61,7 @@ func (o *Operations) ReturnToIdle(guildID string) error {
 		return err
 	}
 
-	return o.connection.Join(guildID, idleChannel)
+	return o.connection.Join(guildID, homeChannel)
 }
 
 func (o *Operations) GetConnection() *Connection {
@@ -69,12 +69,12 @@ func (o *Operations) GetConnection() *Connection {
 }
 
 func (o *Operations) CheckChannelUsers(guildID, channelID string) (int, error) {
-	guild, err := o.session.State.Guild(guildID)
+	guild, err := o.session.Cache().Guild(guildID)
 	if err != nil {
 		return 0, err
 	}
 
-	botID := o.session.State.User.ID
+	botID := o.session.Cache().User.ID
 	userCount := 0
 
 	for _, vs := range guild.VoiceStates {
@@ -87,10 +87,5 @@ func (o *Operations) CheckChannelUsers(guildID, channelID string) (int, error) {
 }
 
 func (o *Operations) getUserVoiceChannel(guildID, userID string) (string, error) {
-	vs, err := o.session.State.VoiceState(guildID, userID)
-	if err != nil || vs == nil || vs.ChannelID == "" {
-		return "", fmt.Errorf("user not in voice channel")
-	}
-
-	return vs.ChannelID, nil
+	return UserVoiceChannel(o.session, guildID, userID)
 }
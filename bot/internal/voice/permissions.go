@@ -0,0 +1,117 @@
+package voice
+
+import (
+	"errors"
+	"fmt"
+	"musicbot/internal/discordapi"
+	"musicbot/internal/logger"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Reasons CheckJoin gives for not trying to join a channel. Trying anyway
+// only ends in a timeout that doesn't say why.
+var (
+	ErrMissingConnect = errors.New("missing the Connect permission")
+	ErrMissingSpeak   = errors.New("missing the Speak permission")
+	ErrChannelFull    = errors.New("voice channel is full")
+)
+
+// ErrNoFallbackChannel is returned by FallbackChannel when the bot can't join
+// any voice channel of the guild.
+var ErrNoFallbackChannel = errors.New("no joinable voice channel")
+
+// ChannelError is a join refused by CheckJoin. Err is one of
+// ErrMissingConnect, ErrMissingSpeak and ErrChannelFull.
+type ChannelError struct {
+	ChannelID string
+	Err       error
+}
+
+func (e *ChannelError) Error() string {
+	return fmt.Sprintf("cannot join voice channel %s: %v", e.ChannelID, e.Err)
+}
+
+func (e *ChannelError) Unwrap() error {
+	return e.Err
+}
+
+// CheckJoin returns a *ChannelError if the bot can't connect and speak in
+// channelID, or if the channel's user limit is reached and the bot can't move
+// members past it. When the permissions can't be worked out, the join is left
+// to find out for itself.
+func CheckJoin(session discordapi.Session, guildID, channelID string) error {
+	botID := session.Cache().User.ID
+
+	perms, err := session.UserChannelPermissions(botID, channelID)
+	if err != nil {
+		logger.Debug.Printf("Could not check permissions for voice channel %s: %v", channelID, err)
+		return nil
+	}
+
+	if perms&discordgo.PermissionVoiceConnect == 0 {
+		return &ChannelError{ChannelID: channelID, Err: ErrMissingConnect}
+	}
+	if perms&discordgo.PermissionVoiceSpeak == 0 {
+		return &ChannelError{ChannelID: channelID, Err: ErrMissingSpeak}
+	}
+
+	if perms&discordgo.PermissionVoiceMoveMembers != 0 {
+		return nil
+	}
+
+	channel, err := session.Cache().Channel(channelID)
+	if err != nil || channel.UserLimit == 0 {
+		return nil
+	}
+
+	guild, err := session.Cache().Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	occupants := 0
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID == channelID && vs.UserID != botID {
+			occupants++
+		}
+	}
+	if occupants >= channel.UserLimit {
+		return &ChannelError{ChannelID: channelID, Err: ErrChannelFull}
+	}
+
+	return nil
+}
+
+// FallbackChannel picks a voice channel to idle in when the configured idle
+// channel is gone: the guild's AFK channel if the bot can join it, otherwise
+// the first joinable voice channel in channel list order.
+func FallbackChannel(session discordapi.Session, guildID string) (string, error) {
+	guild, err := session.Cache().Guild(guildID)
+	if err != nil {
+		return "", err
+	}
+
+	if guild.AfkChannelID != "" && CheckJoin(session, guildID, guild.AfkChannelID) == nil {
+		return guild.AfkChannelID, nil
+	}
+
+	var channels []*discordgo.Channel
+	for _, channel := range guild.Channels {
+		if channel.Type == discordgo.ChannelTypeGuildVoice {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Slice(channels, func(a, b int) bool {
+		return channels[a].Position < channels[b].Position
+	})
+
+	for _, channel := range channels {
+		if CheckJoin(session, guildID, channel.ID) == nil {
+			return channel.ID, nil
+		}
+	}
+
+	return "", ErrNoFallbackChannel
+}
@@ -191,6 +191,13 @@ class Database:
             print(f"Error in add_song_to_playlist: {e}")
             raise
     
+    def update_file_path(self, song_id, file_path):
+        try:
+            self.execute("UPDATE songs SET file_path = ? WHERE id = ?", (file_path, song_id))
+        except Exception as e:
+            print(f"Error in update_file_path: {e}")
+            raise
+    
     def increment_play_count(self, song_id):
         try:
             current_time = int(time.time())
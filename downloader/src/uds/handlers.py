@@ -105,13 +105,15 @@ def handle_download_audio(params, config):
     max_duration = params.get("max_duration_seconds")
     max_size = params.get("max_size_mb")
     allow_live = params.get("allow_live", False)
+    platform = params.get("platform")
     
-    print(f"UDS: Downloading audio from URL: {url}")
+    print(f"UDS: Downloading audio from URL: {url} (platform: {platform or 'auto'})")
     result = ytdlp_handler.download_audio(
         url, 
         max_duration_seconds=max_duration, 
         max_size_mb=max_size, 
-        allow_live=allow_live
+        allow_live=allow_live,
+        extractor=platform
     )
     
     if not result:
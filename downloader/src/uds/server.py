@@ -5,6 +5,7 @@ import time
 import traceback
 import uuid
 from uds import handlers, utils, protocol
+from ytdlp import utils as ytdlp_utils
 import logger
 
 _socket = None
@@ -96,7 +97,11 @@ def _server_loop():
                 'socket': client,
                 'connected': True,
                 'last_activity': time.time(),
-                'last_keepalive': time.time()
+                'last_keepalive': time.time(),
+                'send_lock': threading.Lock(),
+                # Cancel events of the requests still being worked on
+                'requests': {},
+                'requests_lock': threading.Lock()
             }
             
             client_thread = threading.Thread(
@@ -142,7 +147,7 @@ def _handle_client(client_socket, client_id):
                 if not request:
                     logger.logger.warning(f"Invalid request format from client {client_id}")
                     error_response = protocol.create_error_response("Invalid request format")
-                    utils.send_json_message(client_socket, error_response)
+                    _send(client_id, error_response)
                     continue
                 
                 command = request.get("command", "unknown")
@@ -165,16 +170,27 @@ def _handle_client(client_socket, client_id):
                             "server_time": time.time(),
                             "keepalive": True
                         })
-                        utils.send_json_message(client_socket, keepalive_response)
+                        _send(client_id, keepalive_response)
                         continue
                 
+                if command == "cancel":
+                    _handle_cancel(client_id, request)
+                    continue
+                
                 logger.logger.info(f"Handling request from client {client_id} - Command: {command}, ID: {request_id}")
                 
-                response = handlers.process_request(request, _config)
+                with _clients[client_id]['requests_lock']:
+                    _clients[client_id]['requests'][request_id] = threading.Event()
                 
-                logger.logger.info(f"Sending response for {command}, ID: {request_id} to client {client_id}")
-                utils.send_json_message(client_socket, response)
-                logger.logger.info(f"Request handled - Command: {command}, ID: {request_id}, Client: {client_id}")
+                # Requests run on their own thread so that a slow download
+                # doesn't hold up the ones after it, e.g. the items of a
+                # playlist the bot downloads in parallel.
+                request_thread = threading.Thread(
+                    target=_process_request,
+                    args=(client_id, request),
+                    daemon=True
+                )
+                request_thread.start()
                 
                 # Check if we should adjust timeout based on recent activity
                 time_since_keepalive = current_time - _clients[client_id]['last_keepalive']
@@ -208,7 +224,7 @@ def _handle_client(client_socket, client_id):
                 logger.logger.debug(f"Traceback: {traceback.format_exc()}")
                 try:
                     error_response = protocol.create_error_response(f"Server error: {str(e)}")
-                    utils.send_json_message(client_socket, error_response)
+                    _send(client_id, error_response)
                 except Exception as e2:
                     logger.logger.error(f"Failed to send error response to client {client_id}: {e2}")
                 break
@@ -226,6 +242,83 @@ def _handle_client(client_socket, client_id):
         
         logger.logger.info(f"Client {client_id} connection closed")
 
+def _process_request(client_id, request):
+    command = request.get("command", "unknown")
+    request_id = request.get("id", "unknown")
+    
+    cancel_event = _request_event(client_id, request_id)
+    if cancel_event is not None and cancel_event.is_set():
+        _finish_request(client_id, request_id)
+        logger.logger.info(f"Skipping cancelled request - Command: {command}, ID: {request_id}")
+        return
+    
+    ytdlp_utils.set_cancel_event(cancel_event)
+    try:
+        response = handlers.process_request(request, _config)
+    finally:
+        ytdlp_utils.set_cancel_event(None)
+        _finish_request(client_id, request_id)
+    
+    # The client has already given up on a cancelled request.
+    if cancel_event is not None and cancel_event.is_set():
+        logger.logger.info(f"Dropping result of cancelled request - Command: {command}, ID: {request_id}")
+        return
+    
+    logger.logger.info(f"Sending response for {command}, ID: {request_id} to client {client_id}")
+    if _send(client_id, response):
+        logger.logger.info(f"Request handled - Command: {command}, ID: {request_id}, Client: {client_id}")
+
+def _request_event(client_id, request_id):
+    client_data = _clients.get(client_id)
+    if not client_data:
+        return None
+    with client_data['requests_lock']:
+        return client_data['requests'].get(request_id)
+
+def _finish_request(client_id, request_id):
+    client_data = _clients.get(client_id)
+    if not client_data:
+        return
+    with client_data['requests_lock']:
+        client_data['requests'].pop(request_id, None)
+
+def _handle_cancel(client_id, request):
+    """Cancel the requests listed in params.ids. Requests that haven't started
+    are skipped, running downloads stop at their next progress update, and
+    results of cancelled requests are never sent. The acknowledgement counts
+    the requests that were still being worked on."""
+    request_id = request.get("id", "unknown")
+    ids = request.get("params", {}).get("ids", [])
+    
+    cancelled = []
+    client_data = _clients.get(client_id)
+    if client_data and isinstance(ids, list):
+        with client_data['requests_lock']:
+            for cancel_id in ids:
+                event = client_data['requests'].get(cancel_id)
+                if event is not None:
+                    event.set()
+                    cancelled.append(cancel_id)
+    
+    logger.logger.info(f"Cancelled {len(cancelled)} of {len(ids)} requests for client {client_id}")
+    
+    response = protocol.create_success_response(request_id, {
+        "cancelled": len(cancelled),
+        "ids": cancelled
+    })
+    _send(client_id, response)
+
+def _send(client_id, message):
+    """Send a message to a client. Responses and events are sent from several
+    threads, so writes to one socket are serialized by its send lock."""
+    client_data = _clients.get(client_id)
+    if not client_data or not client_data['connected']:
+        logger.logger.debug(f"Not sending to disconnected client {client_id}")
+        return False
+    
+    with client_data['send_lock']:
+        return utils.send_json_message(client_data['socket'], message)
+
 def handle_event(event_type, event_data):
     global _clients
     
@@ -237,7 +330,7 @@ def handle_event(event_type, event_data):
         try:
             if client_data['connected']:
                 logger.logger.debug(f"Sending event {event_type} to client {client_id}")
-                utils.send_json_message(client_data['socket'], event_message)
+                _send(client_id, event_message)
             else:
                 logger.logger.debug(f"Skipping disconnected client {client_id}")
         except Exception as e:
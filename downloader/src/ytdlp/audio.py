@@ -22,9 +22,67 @@ def match_filter_func(info, max_duration_seconds=None, max_size_mb=None, allow_l
     
     return None
 
-def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None, allow_live=False):
+def resolve_live_stream(url, platform, db):
+    """Resolves a live stream to a media URL instead of downloading it.
+
+    The media URL is stored as the song's file path and refreshed every time
+    the stream is requested, since it expires after a few hours.
+    """
+    with yt_dlp.YoutubeDL({
+        'format': 'bestaudio/best',
+        'skip_download': True,
+        'quiet': True,
+        'socket_timeout': 15
+    }) as ydl:
+        info = ydl.extract_info(url, download=False)
+    
+    stream_url = info.get('url') if info else None
+    if not stream_url:
+        error_msg = "Could not resolve a playable URL for the live stream"
+        print(f"{error_msg}: {url}")
+        return {'status': 'error', 'message': error_msg}
+    
+    thumbnail = info.get('thumbnail', '')
+    if isinstance(thumbnail, dict) and 'url' in thumbnail:
+        thumbnail = thumbnail['url']
+    
+    artist = info.get('artist', info.get('uploader', info.get('channel', 'Unknown')))
+    
+    existing_song = db.get_song_by_url(url)
+    if existing_song:
+        song_id = existing_song['id']
+        db.update_file_path(song_id, stream_url)
+    else:
+        song_id = db.add_song(
+            title=info.get('title', 'Unknown'),
+            url=url,
+            platform=platform,
+            file_path=stream_url,
+            duration=0,
+            file_size=0,
+            thumbnail_url=thumbnail,
+            artist=artist,
+            is_stream=True
+        )
+    print(f"Resolved live stream: {info.get('title', 'Unknown')}")
+    
+    return {
+        'id': song_id,
+        'title': info.get('title', 'Unknown'),
+        'filename': stream_url,
+        'duration': 0,
+        'file_size': 0,
+        'platform': platform,
+        'artist': artist,
+        'thumbnail_url': thumbnail,
+        'is_stream': True,
+        'skipped': False
+    }
+
+def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None, allow_live=False, extractor=None):
     platform = utils.get_platform(url)
     platform_prefix = utils.get_platform_prefix(platform)
+    extractor_opts = utils.extractor_options(extractor)
     
     try:
         song = db.get_song_by_url(url)
@@ -39,6 +97,7 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
                 'platform': song['platform'],
                 'artist': song['artist'] if 'artist' in song else '',
                 'thumbnail_url': song['thumbnail_url'] if 'thumbnail_url' in song else '',
+                'thumbnail_path': utils.thumbnail_path(song['file_path']),
                 'is_stream': bool(song['is_stream']) if 'is_stream' in song else False,
                 'skipped': True
             }
@@ -46,7 +105,8 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
         with yt_dlp.YoutubeDL({
             'skip_download': True, 
             'quiet': True,
-            'socket_timeout': 15
+            'socket_timeout': 15,
+            **extractor_opts
         }) as ydl:
             info = ydl.extract_info(url, download=False)
             
@@ -54,10 +114,12 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
                 print(f"No info found for URL: {url}")
                 return None
             
-            if not allow_live and info.get('duration') is None:
-                error_msg = "Content is a live stream (duration is None)"
-                print(f"Skipping: {error_msg}")
-                return {'status': 'error', 'message': error_msg}
+            if info.get('is_live') or info.get('duration') is None:
+                if not allow_live:
+                    error_msg = "Content is a live stream (duration is None)"
+                    print(f"Skipping: {error_msg}")
+                    return {'status': 'error', 'message': error_msg}
+                return resolve_live_stream(url, platform, db)
                 
             if max_duration_seconds is not None and info.get('duration', 0) > max_duration_seconds:
                 error_msg = f"Duration ({info.get('duration')}s) exceeds limit ({max_duration_seconds}s)"
@@ -102,7 +164,8 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
                 'socket_timeout': 30,
                 'retries': 3,
                 'fragment_retries': 3,
-                'extractor_retries': 3
+                'extractor_retries': 3,
+                **extractor_opts
             }
             
             if max_duration_seconds is not None or max_size_mb is not None:
@@ -137,7 +200,8 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
         if file_exists:
             with yt_dlp.YoutubeDL({
                 'skip_download': True, 
-                'quiet': True
+                'quiet': True,
+                **extractor_opts
             }) as ydl:
                 info = ydl.extract_info(url, download=False)
                 
@@ -169,6 +233,11 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
                 )
                 print(f"Added song to database with ID: {song_id}")
             
+            # The thumbnail is kept next to the audio as a jpg, for the bot to
+            # attach when the thumbnail URL stops working. It is saved only
+            # now the song is in the database, so it is never an orphan.
+            utils.save_thumbnail(thumbnail, full_path)
+            
             time.sleep(0.2)
             
             return {
@@ -180,7 +249,11 @@ def download(url, download_path, db, max_duration_seconds=None, max_size_mb=None
                 'platform': platform,
                 'artist': artist,
                 'thumbnail_url': thumbnail,
+                'thumbnail_path': utils.thumbnail_path(full_path),
                 'is_stream': info.get('is_live', False),
+                'chapters': info.get('chapters') or [],
+                'upload_date': info.get('upload_date'),
+                'view_count': info.get('view_count'),
                 'skipped': False
             }
         else:
@@ -1,8 +1,15 @@
 import os
 import re
+import subprocess
+import threading
+import yt_dlp
 
 config = {}
 
+# The request a download thread works for. The UDS server sets a cancel event
+# per request so a cancelled download stops at its next progress update.
+_request = threading.local()
+
 def init(cfg):
     global config
     config.update(cfg)
@@ -36,6 +43,26 @@ def get_platform(url):
     
     return "unknown"
 
+# Platforms a download request may name, with the yt-dlp extractors that
+# handle them. Requests without one leave yt-dlp to pick from the URL.
+PLATFORM_EXTRACTORS = {
+    'youtube': ['youtube.*'],
+    'ytmusic': ['youtube.*'],
+    'soundcloud': ['soundcloud.*'],
+    'bandcamp': ['bandcamp.*'],
+}
+
+def extractor_options(platform):
+    """Returns the yt-dlp options that route a download to the extractors of
+    platform. An unknown platform falls back to generic extraction."""
+    if not platform:
+        return {}
+    extractors = PLATFORM_EXTRACTORS.get(platform.lower())
+    if extractors is None:
+        print(f"Warning: unknown platform '{platform}', using generic extraction")
+        return {}
+    return {'allowed_extractors': extractors}
+
 def get_platform_prefix(platform):
     if 'youtube.com' in platform or 'youtu.be' in platform:
         return 'youtube'
@@ -76,6 +103,37 @@ def ensure_directory(directory_path):
     os.makedirs(directory_path, exist_ok=True)
     return directory_path
 
+def thumbnail_path(audio_path):
+    """Returns the thumbnail saved next to audio_path, or '' if there is none."""
+    if not audio_path:
+        return ''
+    path = os.path.splitext(audio_path)[0] + '.jpg'
+    return path if os.path.isfile(path) else ''
+
+def save_thumbnail(thumbnail_url, audio_path):
+    """Saves the thumbnail at thumbnail_url next to audio_path as a jpg.
+
+    Called once the song is in the database, so the bot's janitor never sees
+    the thumbnail without the song it belongs to. The image is written under a
+    temporary name and renamed into place. Failures are logged and ignored;
+    the song just has no thumbnail to fall back on.
+    """
+    if not thumbnail_url or not audio_path or thumbnail_path(audio_path):
+        return
+    path = os.path.splitext(audio_path)[0] + '.jpg'
+    partial = path + '.part'
+    try:
+        subprocess.run(
+            ['ffmpeg', '-y', '-loglevel', 'error', '-i', thumbnail_url,
+             '-frames:v', '1', '-f', 'mjpeg', partial],
+            check=True, capture_output=True, timeout=30,
+        )
+        os.replace(partial, path)
+    except (OSError, subprocess.SubprocessError) as e:
+        print(f"Failed to save thumbnail for {audio_path}: {e}")
+        if os.path.exists(partial):
+            os.remove(partial)
+
 def match_filter_func(info, max_duration_seconds=None, max_size_mb=None, allow_live=False):
     if not allow_live and info.get('duration') is None:
         return "Video is a live stream (duration is None)"
@@ -95,7 +153,14 @@ def match_filter_func(info, max_duration_seconds=None, max_size_mb=None, allow_l
     
     return None
 
+def set_cancel_event(event):
+    _request.cancel_event = event
+
 def progress_hook(d):
+    cancel_event = getattr(_request, 'cancel_event', None)
+    if cancel_event is not None and cancel_event.is_set():
+        raise yt_dlp.utils.DownloadCancelled("Request cancelled")
+    
     if d['status'] == 'downloading':
         percent = d.get('_percent_str', 'N/A')
         speed = d.get('_speed_str', 'N/A')
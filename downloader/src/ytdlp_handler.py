@@ -45,7 +45,7 @@ def fire_event(event_type, event_data):
             logger.logger.error(f"Error in event callback: {e}")
             logger.logger.debug(f"Traceback: {traceback.format_exc()}")
 
-def download_audio(url, max_duration_seconds=None, max_size_mb=None, allow_live=False):
+def download_audio(url, max_duration_seconds=None, max_size_mb=None, allow_live=False, extractor=None):
     logger.logger.info(f"Starting download_audio for URL: {url}")
     start_time = time.time()
     
@@ -74,6 +74,7 @@ def download_audio(url, max_duration_seconds=None, max_size_mb=None, allow_live=
                 "platform": song['platform'],
                 "artist": artist,
                 "thumbnail_url": thumbnail_url,
+                "thumbnail_path": utils.thumbnail_path(song['file_path']),
                 "is_stream": is_stream,
                 "id": song['id'],
                 "skipped": True
@@ -90,7 +91,8 @@ def download_audio(url, max_duration_seconds=None, max_size_mb=None, allow_live=
             db,
             max_duration_seconds=max_duration_seconds, 
             max_size_mb=max_size_mb, 
-            allow_live=allow_live
+            allow_live=allow_live,
+            extractor=extractor
         )
         
         elapsed = time.time() - start_time
@@ -123,7 +125,11 @@ def download_audio(url, max_duration_seconds=None, max_size_mb=None, allow_live=
                     "platform": song['platform'],
                     "artist": artist,
                     "thumbnail_url": thumbnail_url,
+                    "thumbnail_path": utils.thumbnail_path(song['file_path']),
                     "is_stream": is_stream,
+                    "chapters": result.get('chapters', []),
+                    "upload_date": result.get('upload_date'),
+                    "view_count": result.get('view_count'),
                     "id": song['id'],
                     "skipped": False
                 }
@@ -239,13 +245,15 @@ def get_playlist_info(url, max_items=None):
             
             # Check if this is a playlist or a single video
             if 'entries' not in info:
-                # This is a single video, not a playlist
+                # This is a single video, not a playlist. Its duration lets
+                # the bot refuse it over the limit before downloading it.
                 return {
                     "status": "success",
                     "playlist_title": info.get('title', 'Single Video'),
                     "playlist_url": url,
                     "total_tracks": 1,
-                    "is_playlist": False
+                    "is_playlist": False,
+                    "duration": int(info.get('duration') or 0)
                 }
             
             entries = list(info.get('entries', []))
@@ -324,7 +332,7 @@ def download_playlist_item(url, index, max_duration_seconds=None, max_size_mb=No
             
             entry = entries[0]
             if not entry or entry.get('id') is None:
-                return {"status": "error", "message": f"Item at index {index} is unavailable"}
+                return {"status": "error", "message": f"Item at index {index} is unavailable", "code": "unavailable"}
             
             video_id = entry.get('id')
             video_title = entry.get('title', f'Unknown Track {index}')
@@ -376,13 +384,18 @@ def download_playlist_item(url, index, max_duration_seconds=None, max_size_mb=No
             return {
                 "status": "success",
                 "title": result.get('title', video_title),
+                "url": result.get('url', video_url),
                 "filename": result.get('filename', ''),
                 "duration": result.get('duration'),
                 "file_size": result.get('file_size'),
                 "platform": result.get('platform', platform),
                 "artist": result.get('artist', ''),
                 "thumbnail_url": result.get('thumbnail_url', ''),
+                "thumbnail_path": result.get('thumbnail_path', ''),
                 "is_stream": result.get('is_stream', False),
+                "chapters": result.get('chapters', []),
+                "upload_date": result.get('upload_date'),
+                "view_count": result.get('view_count'),
                 "id": result.get('id'),
                 "index": index
             }
@@ -390,31 +403,32 @@ def download_playlist_item(url, index, max_duration_seconds=None, max_size_mb=No
         elapsed = time.time() - start_time
         error_msg = str(e).lower()
         
-        # Provide detailed error message based on the type of error
+        # Provide detailed error message based on the type of error. The
+        # code lets the bot tell the failures apart without parsing the message
         if "private" in error_msg:
             logger.logger.error(f"Download error: This video is private")
-            return {"status": "error", "message": "This video is private"}
+            return {"status": "error", "message": "This video is private", "code": "private"}
         elif any(term in error_msg for term in ["premium", "paywall", "subscribe", "login", "member", "paid"]):
             logger.logger.error(f"Download error: This content requires a premium account or login")
-            return {"status": "error", "message": "This content requires a premium account or login"}
+            return {"status": "error", "message": "This content requires a premium account or login", "code": "login_required"}
         elif any(term in error_msg for term in ["removed", "deleted", "taken down"]):
             logger.logger.error(f"Download error: This video has been removed or deleted")
-            return {"status": "error", "message": "This video has been removed or deleted"}
+            return {"status": "error", "message": "This video has been removed or deleted", "code": "removed"}
         elif "unavailable" in error_msg:
             logger.logger.error(f"Download error: This video is unavailable")
-            return {"status": "error", "message": "This video is unavailable"}
+            return {"status": "error", "message": "This video is unavailable", "code": "unavailable"}
         elif "copyright" in error_msg:
             logger.logger.error(f"Download error: This video is blocked due to copyright issues")
-            return {"status": "error", "message": "This video is blocked due to copyright issues"}
+            return {"status": "error", "message": "This video is blocked due to copyright issues", "code": "copyright"}
         elif "age" in error_msg and ("restrict" in error_msg or "verify" in error_msg):
             logger.logger.error(f"Download error: This video is age-restricted")
-            return {"status": "error", "message": "This video is age-restricted"}
+            return {"status": "error", "message": "This video is age-restricted", "code": "age_restricted"}
         elif ("geo" in error_msg and "block" in error_msg) or "country" in error_msg:
             logger.logger.error(f"Download error: This video is not available in your country")
-            return {"status": "error", "message": "This video is not available in your country"}
+            return {"status": "error", "message": "This video is not available in your country", "code": "region_blocked"}
         elif "not exist" in error_msg or "no longer" in error_msg or "not found" in error_msg:
             logger.logger.error(f"Download error: This video does not exist or could not be found")
-            return {"status": "error", "message": "This video does not exist or could not be found"}
+            return {"status": "error", "message": "This video does not exist or could not be found", "code": "not_found"}
         else:
             logger.logger.error(f"Download error after {elapsed:.2f} seconds: {e}")
             return {"status": "error", "message": str(e)}
@@ -519,6 +533,7 @@ def _background_playlist_download(playlist_id, url, max_items, max_duration_seco
         # Send a final event when the playlist is complete
         fire_event("playlist_download_completed", {
             "playlist_id": playlist_id,
+            "playlist_url": url,
             "result": result
         })
         
@@ -529,5 +544,6 @@ def _background_playlist_download(playlist_id, url, max_items, max_duration_seco
         # Send an error event
         fire_event("playlist_download_error", {
             "playlist_id": playlist_id,
+            "playlist_url": url,
             "error": str(e)
         })
\ No newline at end of file